@@ -1,11 +1,15 @@
 package agent
 
 import (
+	"context"
 	"crypto/sha1"
+	"encoding/binary"
 	"encoding/json"
+	"io"
 	"math/rand"
 	"net"
-	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
@@ -15,10 +19,23 @@ import (
 	log "github.com/lilymona/gog/logging"
 	"github.com/lilymona/gog/message"
 	"github.com/lilymona/gog/node"
+	"github.com/lilymona/gog/transport"
 )
 
+// Message is a user message delivered to the MessageHandler.
+type Message struct {
+	// The id of the agent that broadcast the message.
+	SenderID uint64
+	// The user payload.
+	Payload []byte
+	// The broadcast time, in unix nanoseconds.
+	Timestamp int64
+	// The topic, empty for the default topic.
+	Topic string
+}
+
 // MessageHandler is the message handler.
-type MessageHandler func([]byte)
+type MessageHandler func(Message)
 
 // Agent describes the interface of an agent.
 type Agent interface {
@@ -27,51 +44,243 @@ type Agent interface {
 	Serve() error
 	// Join joins the peers.
 	Join(peerAddrs ...string) error
-	// Leave causes the agent to leave the cluster.
-	Leave()
+	// Leave causes the agent to leave the cluster,
+	// and shuts down the agent.
+	Leave() error
+	// Close shuts down the agent without notifying
+	// the peers.
+	Close() error
+	// Shutdown gracefully shuts down the agent, waiting for
+	// the user callbacks in flight until the context is done.
+	Shutdown(ctx context.Context) error
 	// Broadcast broadcasts a message to the cluster.
 	Broadcast(msg []byte) error
+	// BroadcastTopic broadcasts a message of the topic to the cluster.
+	BroadcastTopic(topic string, msg []byte) error
+	// BroadcastWithOptions broadcasts a message with the options,
+	// e.g. to the nodes within a number of hops.
+	BroadcastWithOptions(msg []byte, opts BroadcastOptions) error
 	// RegisterMessageHandler registers a user provided callback.
 	RegisterMessageHandler(mh MessageHandler)
+	// RegisterTopicHandler registers a user provided callback
+	// for the messages of the topic.
+	RegisterTopicHandler(topic string, mh MessageHandler)
+	// RegisterDeliveredMessageHandler registers a user provided
+	// callback for the messages with their signature verification.
+	RegisterDeliveredMessageHandler(dh DeliveredMessageHandler)
+	// Request broadcasts a request to the cluster, and
+	// returns the first reply.
+	Request(payload []byte, timeout time.Duration) ([]byte, error)
+	// Config returns the current configuration,
+	// which must not be modified.
+	Config() *config.Config
+	// Reconfigure replaces the configuration at runtime,
+	// of which only the tunables take effect.
+	Reconfigure(cfg *config.Config) error
+	// RequestNode sends a request to the node of the id,
+	// and returns its reply.
+	RequestNode(id uint64, payload []byte, timeout time.Duration) ([]byte, error)
+	// RegisterRequestHandler registers a user provided
+	// callback to reply the requests.
+	RegisterRequestHandler(rh RequestHandler)
+	// RegisterTracer registers a tracer for the user messages.
+	RegisterTracer(t Tracer)
+	// RegisterNeighborUp registers a user provided callback
+	// for the nodes added to the active view.
+	RegisterNeighborUp(h NeighborHandler)
+	// RegisterNeighborDown registers a user provided callback
+	// for the nodes removed from the active view.
+	RegisterNeighborDown(h NeighborHandler)
+	// RegisterDeliveryFailure registers a user provided callback
+	// for the messages not acknowledged in the reliable mode.
+	RegisterDeliveryFailure(h DeliveryFailureHandler)
+	// RegisterJoinAbandoned registers a user provided callback
+	// for giving up joining the peers again.
+	RegisterJoinAbandoned(h JoinAbandonedHandler)
+	// RegisterPartitionSuspected registers a user provided
+	// callback for suspecting a partition of the overlay.
+	RegisterPartitionSuspected(h PartitionHandler)
 	// List prints the infomation in two views.
 	List() ([]byte, error)
+	// Peers returns the addresses of the nodes in the
+	// active view, the passive view, or both.
+	Peers(active, passive bool) []string
+	// Health returns the status of the agent in the overlay.
+	Health() *Health
+	// Stats returns the runtime counters and gauges.
+	Stats() *Stats
+	// Debug returns the internal state of the agent.
+	Debug() *DebugInfo
+	// NodesWithLabel returns copies of the nodes in the
+	// views that have the label.
+	NodesWithLabel(key, value string) []*node.Node
+	// SetLabels replaces the labels of the agent, which
+	// are advertised in the later membership messages.
+	SetLabels(labels map[string]string)
+	// Evict removes the node from the views, disconnects
+	// it, and bans it for a while.
+	Evict(id uint64) error
+	// Topology crawls the overlay within ttl hops, up to MaxTopologyTTL,
+	// and returns the graph of the nodes that answered in time.
+	Topology(ttl int, timeout time.Duration) (*Topology, error)
+	// Settings returns the cluster settings.
+	Settings() map[string]string
+	// SetSettings changes and applies the cluster settings,
+	// which gossip to the rest of the cluster.
+	SetSettings(settings map[string]string) error
+	// RegisterSettingsHandler registers another user provided
+	// callback for the cluster settings that change.
+	RegisterSettingsHandler(h SettingsHandler)
 }
 
 // agent implements the Agent interface.
 type agent struct {
 	// The id of the agent.
 	id uint64
-	// Configuration.
-	cfg *config.Config
+	// Configuration, the *config.Config, which is replaced
+	// as a whole by Reconfigure, see config().
+	cfg atomic.Value
+	// cfgMu serializes the replacements of the configuration.
+	cfgMu sync.Mutex
+	// The address advertised to the peers.
+	addr string
+	// viewMu guards both the views, so the membership changes that
+	// move the nodes between them are atomic, and there is no lock
+	// order to get wrong. The maps only lock themselves in each call.
+	// It is not held while talking to the peers.
+	viewMu sync.RWMutex
 	// Active View.
-	aView *arraymap.ArrayMap
+	aView *arraymap.OrderedMap[uint64, *node.Node]
 	// Passive View.
-	pView *arraymap.ArrayMap
-	// TCP listener.
-	ln *net.TCPListener
+	pView *arraymap.OrderedMap[uint64, *node.Node]
+	// bans are the evicted nodes and the time until which
+	// they are banned, guarded by viewMu.
+	bans map[uint64]time.Time
+	// The logger, and the logger sampling the logs of the messages
+	// from the peers.
+	logger        log.Logger
+	sampledLogger log.Logger
+	// The transport, and the listeners of the listen
+	// address and the extra addresses.
+	transport transport.Transport
+	lns       []net.Listener
+	lnMu      sync.Mutex
+	// The connection of the datagrams, if the config asks to.
+	pc net.PacketConn
 	// The codec.
 	codec codec.Codec
-	// Message buffer.
-	msgBuffer *arraymap.ArrayMap
+	// The hashes of the received messages.
+	msgCache *msgCache
+	// The sequence numbers of the sent and the received messages.
+	replay *replay
+	// The user messages buffered to deliver in order.
+	fifo *fifo
 	// FaildMessage buffer.
-	failmsgBuffer *arraymap.ArrayMap
+	failmsgBuffer *arraymap.OrderedMap[[sha1.Size]byte, *message.UserMessage]
+	// spoolc wakes up the spool loop to save the failed messages,
+	// and spoolMu serializes the saves.
+	spoolc  chan struct{}
+	spoolMu sync.Mutex
 	// The user message callback.
 	msgHandler MessageHandler
+	// The user message callbacks of the topics.
+	topicHandlers   map[string]MessageHandler
+	topicHandlersMu sync.RWMutex
+	// The user callback of the messages with their signature
+	// verification, instead of the ones above if set.
+	deliveredHandler DeliveredMessageHandler
+	// The trusted keys to verify the signed messages.
+	keys keyring
+	// The tracer of the user messages.
+	tracer Tracer
+	// The user request callback.
+	reqHandler RequestHandler
+	// The requests waiting for the replies.
+	requests *pendingRequests
+	// The crawls of the topology waiting for the replies.
+	topologies *pendingTopologies
+	// The cluster settings, and the callbacks of their changes.
+	settings *settings
+	// Invokes the callback in order for each source,
+	// if the config asks to.
+	dispatcher *dispatcher
+	// The active view, delivery failure, join abandon and
+	// partition callbacks, and the queue of their events.
+	upHandler        NeighborHandler
+	downHandler      NeighborHandler
+	failureHandler   DeliveryFailureHandler
+	abandonHandler   JoinAbandonedHandler
+	partitionHandler PartitionHandler
+	events           *dispatcher
+	// The state of joining the peers again after losing all of them.
+	rejoiner rejoiner
+	// The state of the partition detection.
+	partition *partition
+	// The number of new candidates learned from shuffles
+	// since the last shuffle.
+	learned int32
+	// The eager and lazy peers in the plumtree mode.
+	plumtree *plumtree
+	// The messages waiting for the acks in the reliable mode.
+	reliable *reliable
+	// The idle connections of the control messages.
+	pool *connPool
+	// The liveness probes of the neighbors.
+	pinger *pinger
+	// The shuffles waiting for the replies over UDP.
+	shuffles *pendingShuffles
+	// The queues of the user messages to the neighbors.
+	sendq *sendQueue
+	// The chunked payloads being reassembled.
+	chunks *chunks
+	// The rate limits of the user messages to the neighbors.
+	limiter *rateLimiter
+	// The labels advertised to the peers, initially the
+	// configured ones.
+	labelsMu sync.RWMutex
+	labels   map[string]string
+	// The random number generator of the random choices.
+	rng *rand.Rand
+	// The runtime gauges.
+	stats *stats
+	// Limits the rate of the outbound forward joins.
+	fjThrottle *throttle
+	// Closed when the agent is shut down.
+	stopc     chan struct{}
+	closeOnce sync.Once
 }
 
 // view is a struct that encapsulates the active and passive
 // view. It is for creating json files.
 type view struct {
 	// Active View.
-	AView *arraymap.ArrayMap `json:"active_view"`
+	AView *arraymap.OrderedMap[uint64, *node.Node] `json:"active_view"`
 	// Passive View.
-	PView *arraymap.ArrayMap `json:"passive_view"`
+	PView *arraymap.OrderedMap[uint64, *node.Node] `json:"passive_view"`
 }
 
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
+// lockedSource is a rand.Source that is safe for concurrent use.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
 func GenID() (n uint64) {
 	for n == 0 {
 		n = uint64(rand.Int63())
@@ -79,11 +288,44 @@ func GenID() (n uint64) {
 	return
 }
 
+// randSource() returns the random source of the config,
+// or a source seeded with the time if it has none.
+func randSource(cfg *config.Config) rand.Source {
+	if cfg.Rand != nil {
+		return cfg.Rand
+	}
+	return rand.NewSource(time.Now().UnixNano())
+}
+
 // NewAgent creates a new agent.
 func NewAgent(cfg *config.Config) Agent {
+	return newAgent(cfg, randSource(cfg))
+}
+
+// Start creates a new agent and serves it in the background, so the
+// agent can be embedded in a process without the REST server. It returns
+// once the agent listens, or the error if it cannot listen. The agent
+// is shut down by Leave or Close.
+func Start(cfg *config.Config) (Agent, error) {
+	ag := newAgent(cfg, randSource(cfg))
+	if err := ag.start(); err != nil {
+		return nil, err
+	}
+	go ag.serve()
+	return ag, nil
+}
+
+// newAgent() creates a new agent, which makes the random
+// choices with the source, e.g. a fixed-seed source in tests.
+func newAgent(cfg *config.Config, src rand.Source) *agent {
+	addr := advertiseAddr(cfg)
+	id := nodeID(cfg, addr)
+	logger := newLogger(cfg).WithFields(log.Fields{"agent": id})
+
 	// Create a codec and register messages.
-	codec := codec.NewProtobufCodec()
-	codec.Register(&message.UserMessage{})
+	codec := newCodec(cfg.Codec)
+	codec.SetLogger(logger)
+	codec.RegisterCompressible(&message.UserMessage{})
 	codec.Register(&message.Join{})
 	codec.Register(&message.JoinReply{})
 	codec.Register(&message.ForwardJoin{})
@@ -92,52 +334,280 @@ func NewAgent(cfg *config.Config) Agent {
 	codec.Register(&message.Disconnect{})
 	codec.Register(&message.Shuffle{})
 	codec.Register(&message.ShuffleReply{})
+	codec.Register(&message.Request{})
+	codec.Register(&message.Reply{})
+	codec.Register(&message.IHave{})
+	codec.Register(&message.Graft{})
+	codec.Register(&message.Prune{})
+	codec.Register(&message.Ack{})
+	codec.Register(&message.Ping{})
+	codec.Register(&message.Pong{})
+	codec.RegisterCompressible(&message.Batch{})
+	codec.Register(&message.Digest{})
+	codec.Register(&message.TopologyRequest{})
+	codec.Register(&message.TopologyReply{})
+	codec.Register(&message.Settings{})
+	codec.SetCompressThreshold(cfg.CompressThreshold)
+	if cfg.MaxMessageSize > 0 {
+		codec.SetMaxMessageSize(cfg.MaxMessageSize)
+	}
 
-	return &agent{
-		id:            GenID(),
-		cfg:           cfg,
+	ag := &agent{
+		id:            id,
+		addr:          addr,
+		logger:        logger,
+		sampledLogger: log.Sampled(logger),
 		codec:         codec,
-		aView:         arraymap.NewArrayMap(),
-		pView:         arraymap.NewArrayMap(),
-		msgBuffer:     arraymap.NewArrayMap(),
-		failmsgBuffer: arraymap.NewArrayMap(),
+		aView:         arraymap.NewOrderedMap[uint64, *node.Node](),
+		pView:         arraymap.NewOrderedMap[uint64, *node.Node](),
+		bans:          make(map[uint64]time.Time),
+		msgCache:      newMsgCache(cfg.MessageCacheSize),
+		replay:        newReplay(),
+		fifo:          newFIFO(),
+		failmsgBuffer: arraymap.NewOrderedMap[[sha1.Size]byte, *message.UserMessage](),
+		spoolc:        make(chan struct{}, 1),
+		dispatcher:    newDispatcher(),
+		topicHandlers: make(map[string]MessageHandler),
+		events:        newDispatcher(),
+		stats:         &stats{},
+		tracer:        noopTracer{},
+		requests:      newPendingRequests(),
+		topologies:    newPendingTopologies(),
+		settings:      newSettings(),
+		fjThrottle:    newThrottle(cfg.ForwardJoinRate, cfg.ForwardJoinBurst),
+		plumtree:      newPlumtree(),
+		reliable:      newReliable(),
+		pinger:        newPinger(),
+		shuffles:      newPendingShuffles(),
+		sendq:         newSendQueue(),
+		chunks:        newChunks(),
+		limiter:       newRateLimiter(cfg),
+		partition:     newPartition(),
+		labels:        copyLabels(cfg.Labels),
+		keys:          newKeyring(cfg),
+		pool:          newConnPool(cfg.ConnPoolSize, time.Duration(cfg.ConnIdleTimeout)*time.Second),
+		rng:           rand.New(&lockedSource{src: src}),
+		stopc:         make(chan struct{}),
+	}
+	ag.cfg.Store(cfg)
+	ag.transport = cfg.Transport
+	if ag.transport == nil {
+		ag.transport = &transport.TCP{Net: cfg.Net, Control: ag.control, TLSConfig: cfg.TLSConfig}
+	}
+	ag.loadState()
+	ag.loadSpool()
+	return ag
+}
+
+// newLogger() returns the logger of the
+// config, or the default logger if none.
+func newLogger(cfg *config.Config) log.Logger {
+	if cfg.Logger != nil {
+		return cfg.Logger
 	}
+	return log.Default()
+}
+
+// newCodec() creates the codec of the name, which
+// is the protobuf codec by default.
+func newCodec(name string) *codec.ProtobufCodec {
+	switch name {
+	case config.CodecJSON:
+		return codec.NewJSONCodec().ProtobufCodec
+	case config.CodecMsgpack:
+		return codec.NewMsgpackCodec().ProtobufCodec
+	}
+	return codec.NewProtobufCodec()
 }
 
 // Serve starts a standalone agent, waiting for
 // incoming connections.
 func (ag *agent) Serve() error {
-	ln, err := net.ListenTCP(ag.cfg.Net, ag.cfg.LocalTCPAddr)
+	if err := ag.start(); err == ErrAgentClosed {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	ag.serve()
+	return nil
+}
+
+// start() creates the listener and starts the background loops.
+// If the agent is closed already, it returns ErrAgentClosed.
+func (ag *agent) start() error {
+	lns, err := ag.listen()
 	if err != nil {
-		log.Errorf("Serve() Cannot listen %v\n", err)
+		ag.logger.Errorf("Serve() Cannot listen %v\n", err)
 		return err
 	}
+	// The datagrams are read on the address of the first listener,
+	// which has the port assigned if the configured one is 0.
+	var pc net.PacketConn
+	if ag.config().UDP {
+		if pc, err = ag.listenPacket(lns[0].Addr().String()); err != nil {
+			ag.logger.Errorf("Serve() Cannot listen for the datagrams %v\n", err)
+			closeListeners(lns)
+			return err
+		}
+	}
+	ag.lnMu.Lock()
+	if ag.stopped() {
+		ag.lnMu.Unlock()
+		closeListeners(lns)
+		if pc != nil {
+			pc.Close()
+		}
+		return ErrAgentClosed
+	}
+	ag.lns, ag.pc = lns, pc
+	ag.lnMu.Unlock()
+
+	atomic.StoreInt64(&ag.stats.started, time.Now().UnixNano())
+	if pc != nil {
+		go ag.servePackets(pc)
+	}
+
 	go ag.healLoop()
 	go ag.shuffleLoop()
-	ag.ln = ln
-	ag.serve()
+	go ag.checkLoop()
+	go ag.purgeLoop()
+	go ag.lazyLoop()
+	go ag.stateLoop()
+	go ag.spoolLoop()
+	go ag.poolLoop()
+	go ag.pingLoop()
+	go ag.partitionLoop()
+	go ag.restore()
 	return nil
 }
 
-// serve listens on the TCP listener, waits for incoming connections.
+// listen() creates the listeners of the transport on the local address
+// and the extra addresses, which are the resolved TCP addresses if there
+// are any. The first listener is the one of the local address.
+func (ag *agent) listen() ([]net.Listener, error) {
+	cfg := ag.config()
+	addrs := append([]string{cfg.AddrStr}, cfg.ExtraAddrs...)
+	if cfg.LocalTCPAddr != nil {
+		addrs = []string{cfg.LocalTCPAddr.String()}
+		for _, addr := range cfg.ExtraTCPAddrs {
+			addrs = append(addrs, addr.String())
+		}
+	}
+
+	var lns []net.Listener
+	for _, addr := range addrs {
+		ln, err := ag.transport.Listen(addr)
+		if err != nil {
+			closeListeners(lns)
+			return nil, err
+		}
+		lns = append(lns, ln)
+	}
+	return lns, nil
+}
+
+// closeListeners() closes the listeners, and
+// returns the first error if there is any.
+func closeListeners(lns []net.Listener) error {
+	var err error
+	for _, ln := range lns {
+		if e := ln.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// serve listens on the listeners, waits for incoming connections.
+// The accepted connections are queued for a pool of handlers, if the
+// config asks to.
 func (ag *agent) serve() {
+	var connc chan net.Conn
+	if ag.config().ConnHandlers > 0 {
+		connc = make(chan net.Conn, ag.config().ConnQueueSize)
+		defer close(connc)
+		for i := 0; i < ag.config().ConnHandlers; i++ {
+			go ag.handleConns(connc)
+		}
+	}
+
+	ag.lnMu.Lock()
+	lns := ag.lns
+	ag.lnMu.Unlock()
+	if len(lns) == 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, ln := range lns[1:] {
+		wg.Add(1)
+		go func(ln net.Listener) {
+			defer wg.Done()
+			ag.accept(ln, connc)
+		}(ln)
+	}
+	ag.accept(lns[0], connc)
+	wg.Wait()
+}
+
+// accept() waits for incoming connections on the listener, and serves
+// them, or queues them for the handlers if connc is not nil.
+func (ag *agent) accept(ln net.Listener, connc chan<- net.Conn) {
 	for {
-		conn, err := ag.ln.AcceptTCP()
+		conn, err := ln.Accept()
 		if err != nil {
-			log.Errorf("Agent.serve(): Failed to accept\n")
+			if ag.stopped() {
+				return
+			}
+			ag.logger.Errorf("Agent.accept(): Failed to accept\n")
 			continue
 		}
-		// TODO(Yifan): Set read time ount.
-		go ag.serveConn(conn)
+		conn = ag.wrapConn(conn)
+		if connc == nil {
+			go ag.serveConn(conn)
+			continue
+		}
+
+		atomic.AddInt32(&ag.stats.queuedConns, 1)
+		select {
+		case connc <- conn:
+		case <-ag.stopc:
+			atomic.AddInt32(&ag.stats.queuedConns, -1)
+			conn.Close()
+			return
+		}
+	}
+}
+
+// handleConns() serves the queued connections one by one.
+func (ag *agent) handleConns(connc <-chan net.Conn) {
+	for conn := range connc {
+		atomic.AddInt32(&ag.stats.queuedConns, -1)
+		atomic.AddInt32(&ag.stats.handlingConns, 1)
+		ag.serveConn(conn)
+		atomic.AddInt32(&ag.stats.handlingConns, -1)
 	}
 }
 
-// serveConn() serves a connection.
-func (ag *agent) serveConn(conn *net.TCPConn) {
+// serveConn() serves a connection, after the handshake, which
+// authenticates the peer if a cluster secret is configured.
+func (ag *agent) serveConn(conn net.Conn) {
+	pc, err := ag.handshake(conn, false)
+	if err != nil {
+		ag.logger.Errorf("Agent.serveConn(): Handshake with %v failed: %v\n", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	conn = pc
 	for {
-		msg, err := ag.codec.ReadMsg(conn)
+		msg, err := ag.readMsg(conn)
 		if err != nil {
-			log.Errorf("Agent.serveConn(): Failed to decode message: %v\n", err)
+			if isTimeout(err) {
+				ag.logger.Warningf("Agent.serveConn(): Connection from %v timed out\n", conn.RemoteAddr())
+			} else {
+				ag.logger.Errorf("Agent.serveConn(): Failed to decode message: %v\n", err)
+			}
+			conn.Close()
 			return
 		}
 		// Dispatch messages.
@@ -152,8 +622,16 @@ func (ag *agent) serveConn(conn *net.TCPConn) {
 			}
 		case *message.ShuffleReply:
 			ag.handleShuffleReply(msg.(*message.ShuffleReply))
+		case *message.Reply:
+			ag.handleReply(msg.(*message.Reply))
+		case *message.Request:
+			if !ag.handleDirectRequest(conn, msg.(*message.Request)) {
+				conn.Close()
+				return
+			}
 		default:
-			log.Errorf("Agent.serveConn(): Unexpected message type: %T\n", t)
+			ag.logger.Errorf("Agent.serveConn(): Unexpected message type: %T\n", t)
+			conn.Close()
 			return
 		}
 	}
@@ -162,9 +640,13 @@ func (ag *agent) serveConn(conn *net.TCPConn) {
 // serveNode() serves a node's connection.
 func (ag *agent) serveNode(node *node.Node) {
 	for {
-		msg, err := ag.codec.ReadMsg(node.Conn)
+		msg, err := ag.readMsg(node)
 		if err != nil {
-			log.Errorf("Agent.serveNode(): Failed to decode message: %v\n", err)
+			if isTimeout(err) {
+				ag.logger.Warningf("Agent.serveNode(): Node %v timed out\n", node)
+			} else {
+				ag.logger.Errorf("Agent.serveNode(): Failed to decode message: %v\n", err)
+			}
 			ag.replaceActiveNode(node)
 			return
 		}
@@ -177,225 +659,506 @@ func (ag *agent) serveNode(node *node.Node) {
 			return
 		case *message.Shuffle:
 			ag.handleShuffle(msg.(*message.Shuffle))
+		case *message.ShuffleReply:
+			ag.handleShuffleReply(msg.(*message.ShuffleReply))
 		case *message.UserMessage:
 			ag.handleUserMessage(node, msg.(*message.UserMessage))
+		case *message.Batch:
+			for _, umsg := range msg.(*message.Batch).GetMessages() {
+				ag.handleUserMessage(node, umsg)
+			}
+		case *message.Request:
+			ag.handleRequest(node, msg.(*message.Request))
+		case *message.IHave:
+			ag.handleIHave(node, msg.(*message.IHave))
+		case *message.Graft:
+			ag.handleGraft(node, msg.(*message.Graft))
+		case *message.Prune:
+			ag.handlePrune(node)
+		case *message.Ack:
+			ag.handleAck(node, msg.(*message.Ack))
+		case *message.Ping:
+			ag.handlePing(node, msg.(*message.Ping))
+		case *message.Pong:
+			ag.handlePong(node, msg.(*message.Pong))
+		case *message.Digest:
+			ag.handleDigest(node, msg.(*message.Digest))
+		case *message.TopologyRequest:
+			ag.handleTopologyRequest(node, msg.(*message.TopologyRequest))
+		case *message.TopologyReply:
+			ag.handleTopologyReply(msg.(*message.TopologyReply))
+		case *message.Settings:
+			ag.handleSettings(node, msg.(*message.Settings))
 		default:
-			log.Errorf("Agent.serveNode(): Unexpected message type: %T\n", t)
+			ag.logger.Errorf("Agent.serveNode(): Unexpected message type: %T\n", t)
 			ag.replaceActiveNode(node)
 			return
 		}
 	}
 }
 
+// deadlineReader is a connection, or a node, to read the messages from.
+type deadlineReader interface {
+	io.Reader
+	SetReadDeadline(t time.Time) error
+}
+
+// readMsg() reads a message from the connection. It gives up
+// if nothing arrives within the read timeout.
+func (ag *agent) readMsg(conn deadlineReader) (proto.Message, error) {
+	if ag.config().ReadTimeout <= 0 {
+		return ag.codec.ReadMsg(conn)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Duration(ag.config().ReadTimeout) * time.Second))
+	msg, err := ag.codec.ReadMsg(conn)
+	if err == nil {
+		conn.SetReadDeadline(time.Time{})
+	}
+	return msg, err
+}
+
+// maxHealAttempts is the maximum number of passive nodes
+// that healLoop() tries to promote on each tick.
+const maxHealAttempts = 3
+
+// healEscalation is the number of the heal ticks with the active view
+// under the minimum size, after which the passive nodes are asked with
+// high priority Neighbor requests, which they cannot refuse.
+const healEscalation = 3
+
 func (ag *agent) healLoop() {
-	ticker := time.NewTicker(time.Duration(ag.cfg.HealDuration) * time.Second)
-	defer ticker.Stop()
-	for t := range ticker.C {
-		// ag.aView.Lock()
-		// ag.pView.Lock()
-		// if ag.aView.Len() < ag.cfg.AViewMinSize {
-		// 	if ag.pView.Len() > 0 {
-		// 		nd := chooseRandomNode(ag.pView, 0)
-		// 		ag.neighbor(nd, message.Neighbor_Low)
-		// 	}
-		// }
-		// ag.aView.Unlock()
-		// ag.pView.Unlock()
-
-		ag.aView.RLock()
+	if ag.config().HealDuration <= 0 {
+		return
+	}
+	// The number of the consecutive ticks under the minimum size.
+	under := 0
+	for {
+		select {
+		case <-time.After(ag.healInterval()):
+		case <-ag.stopc:
+			return
+		}
+
+		ag.viewMu.RLock()
 		len := ag.aView.Len()
-		ag.aView.RUnlock()
+		ag.viewMu.RUnlock()
 		if len == 0 {
-			log.Warningf("Lost all peers! Join again\n")
-			if err := ag.Join(ag.cfg.ShufflePeers()...); err != nil {
-				log.Warningf("No available peers, need a new list!")
-			}
+			ag.rejoinCluster()
+			continue
+		}
+		ag.resetRejoin()
+		if len >= ag.config().AViewMinSize {
+			under = 0
+			continue
+		}
+		under++
+		priority := message.Neighbor_Low
+		if under > healEscalation {
+			priority = message.Neighbor_High
+		}
+		ag.promotePassiveNodes(priority)
+	}
+}
+
+// healInterval() returns the heal duration with a random jitter of up
+// to a quarter of it either way, so the agents that lost the same node
+// do not ask the same passive nodes at once.
+func (ag *agent) healInterval() time.Duration {
+	d := time.Duration(ag.config().HealDuration) * time.Second
+	if jitter := int64(d / 4); jitter > 0 {
+		d += time.Duration(ag.rng.Int63n(2*jitter+1) - jitter)
+	}
+	return d
+}
+
+// promotePassiveNodes() sends Neighbor requests of the priority to random
+// nodes in the passive view, until the active view reaches the minimum
+// size, or maxHealAttempts requests are sent, or every passive node is
+// tried. The unreachable nodes are dropped from the passive view, while
+// the nodes that refuse stay there, to be asked again later.
+// The view lock is not held while talking to the nodes, as the nodes
+// might be sending requests to this agent at the same time.
+func (ag *agent) promotePassiveNodes(priority message.Neighbor_Priority) {
+	tried := make(map[uint64]bool)
+	for i := 0; i < maxHealAttempts; i++ {
+		ag.viewMu.RLock()
+		full := ag.aView.Len() >= ag.config().AViewMinSize
+		nd := chooseUntriedNode(ag.rng, ag.pView, tried)
+		ag.viewMu.RUnlock()
+		if full || nd == nil {
+			return
+		}
+		tried[nd.Id] = true
+
+		candidate := &node.Node{Id: nd.Id, Addr: nd.Addr, Labels: nd.Labels}
+		if err := candidate.Dial(ag.dial); err != nil {
+			ag.sampledLogger.Errorf("Agent.promotePassiveNodes(): Failed to connect %s: %v\n", candidate.Addr, err)
+			ag.viewMu.Lock()
+			ag.pView.Remove(candidate.Id)
+			ag.viewMu.Unlock()
+			continue
+		}
+		accepted, err := ag.neighbor(candidate, priority)
+		if err != nil {
+			ag.sampledLogger.Errorf("Agent.promotePassiveNodes(): Failed to neighbor: %v\n", err)
+			candidate.Close()
+			continue
 		}
+		if !accepted {
+			// The peer keeps serving the connection after refusing.
+			ag.pool.put(candidate.Addr, candidate.Conn())
+			continue
+		}
+		ag.viewMu.Lock()
+		ag.addNodeActiveView(candidate)
+		ag.viewMu.Unlock()
 	}
 }
 
 func (ag *agent) shuffleLoop() {
-	tick := time.Tick(time.Duration(ag.cfg.ShuffleDuration) * time.Second)
+	duration := ag.config().ShuffleDuration
+	interval := time.Duration(duration) * time.Second
 	for {
 		select {
-		case <-tick:
-			ag.aView.RLock()
-			ag.pView.RLock()
-			if ag.aView.Len() == 0 {
-				ag.aView.RUnlock()
-				ag.pView.RUnlock()
-				continue
-			}
-			node := chooseRandomNode(ag.aView, 0)
-			if node == nil {
-				continue
+		case <-ag.stopc:
+			return
+		case <-time.After(interval):
+			// The interval restarts from the reconfigured duration.
+			if d := ag.config().ShuffleDuration; d != duration {
+				duration, interval = d, time.Duration(d)*time.Second
 			}
-			list := ag.makeShuffleList()
-			ag.aView.RUnlock()
-			ag.pView.RUnlock()
-			go ag.shuffle(node, list)
+			interval = ag.nextShuffleInterval(interval, int(atomic.SwapInt32(&ag.learned, 0)))
+			ag.shuffleOnce()
 		}
 	}
 }
 
+// shuffleOnce() sends a shuffle to a random node
+// in the active view, if there is any.
+func (ag *agent) shuffleOnce() {
+	ag.viewMu.RLock()
+	node := chooseRandomNode(ag.rng, ag.aView, 0)
+	if node == nil {
+		ag.viewMu.RUnlock()
+		return
+	}
+	list := ag.makeShuffleList()
+	ag.viewMu.RUnlock()
+	atomic.AddUint64(&ag.stats.shuffles, 1)
+	go ag.shuffle(node, list)
+}
+
+// nextShuffleInterval() adapts the shuffle interval to the view churn.
+// If the last shuffles learned no new candidates, the view is considered
+// stable and the interval is doubled. If most of the exchanged candidates
+// were new, the interval is halved. The result is bounded by the min/max
+// shuffle durations. If they are not set, the interval is kept as is.
+func (ag *agent) nextShuffleInterval(interval time.Duration, learned int) time.Duration {
+	min := time.Duration(ag.config().MinShuffleDuration) * time.Second
+	max := time.Duration(ag.config().MaxShuffleDuration) * time.Second
+	if min <= 0 || max < min {
+		return interval
+	}
+
+	switch {
+	case learned == 0:
+		interval *= 2
+	case learned > (ag.config().Ka+ag.config().Kp)/2:
+		interval /= 2
+	}
+	if interval < min {
+		interval = min
+	}
+	if interval > max {
+		interval = max
+	}
+	return interval
+}
+
 func (ag *agent) makeShuffleList() []*message.Candidate {
-	candidates := make([]*message.Candidate, 0, 1+ag.cfg.Ka+ag.cfg.Kp)
+	candidates := make([]*message.Candidate, 0, 1+ag.config().Ka+ag.config().Kp)
 	self := &message.Candidate{
-		Id:   proto.Uint64(ag.id),
-		Addr: proto.String(ag.cfg.AddrStr),
+		Id:     proto.Uint64(ag.id),
+		Addr:   proto.String(ag.addr),
+		Labels: ag.encodedLabels(),
 	}
 	candidates = append(candidates, self)
-	candidates = append(candidates, chooseRandomCandidates(ag.aView, ag.cfg.Ka)...)
-	candidates = append(candidates, chooseRandomCandidates(ag.pView, ag.cfg.Kp)...)
+	candidates = append(candidates, chooseRandomCandidates(ag.rng, ag.aView, ag.config().Ka)...)
+	candidates = append(candidates, chooseRandomCandidates(ag.rng, ag.pView, ag.config().Kp)...)
 	return candidates
 }
 
-// addNodeActiveView() adds the node to the active view. If
-// the active view is full, it will move one node from the active
-// view to the passive view before adding the node.
+// addNodeActiveView() adds the node to the active view, and removes it
+// from the passive view. If the active view is full, it will move one
+// node from the active view to the passive view before adding the node.
 // If the passive view is also full, it will drop a random node
 // in the passive view.
-func (ag *agent) addNodeActiveView(node *node.Node) {
-	if !ag.aView.Has(node.Id) {
-		for ag.aView.Len() >= ag.cfg.AViewMaxSize {
-			n := chooseRandomNode(ag.aView, 0)
+func (ag *agent) addNodeActiveView(nd *node.Node) {
+	// The views have been cleared by Close.
+	if ag.stopped() {
+		nd.Close()
+		return
+	}
+	ag.pView.Remove(nd.Id)
+	if !ag.aView.Has(nd.Id) {
+		for ag.aView.Len() >= ag.config().AViewMaxSize {
+			n := chooseRandomNode(ag.rng, ag.aView, 0)
 			ag.aView.Remove(n.Id)
+			ag.neighborDown(n)
 			go ag.disconnect(n)
 			ag.addNodePassiveView(n)
 			//ag.pView.Add(n.Id, n)
 		}
+		ag.neighborUp(nd)
+	}
+	go ag.serveNode(nd)
+	if old := ag.aView.Add(nd.Id, nd); old != nil {
+		old.Close()
 	}
-	go ag.serveNode(node)
-	if old := ag.aView.Add(node.Id, node); old != nil {
-		old.(*node.Node).Conn.Close()
+	ag.syncSettings(nd)
+	// The overlay heals, resend the failed messages.
+	if ag.failmsgBuffer.Len() > 0 {
+		go ag.resendFailedMessages()
 	}
 }
 
 // addNodePassiveView() adds a node to the passive view. If
 // the passive view is full, it will drop a random node.
-func (ag *agent) addNodePassiveView(node *node.Node) {
-	if node.Id == ag.id || ag.aView.Has(node.Id) || ag.pView.Has(node.Id) {
-		return
+// It returns true if the node is new to the views.
+func (ag *agent) addNodePassiveView(node *node.Node) bool {
+	if node.Id == ag.id || ag.aView.Has(node.Id) || ag.pView.Has(node.Id) || ag.banned(node.Id) {
+		return false
 	}
-	for ag.pView.Len() >= ag.cfg.PViewSize {
-		n := chooseRandomNode(ag.pView, 0)
+	for ag.pView.Len() >= ag.config().PViewSize {
+		n := chooseRandomNode(ag.rng, ag.pView, 0)
 		ag.pView.Remove(n.Id)
 	}
 	ag.pView.Add(node.Id, node)
+	return true
 }
 
 // replaceActiveNode() replaces a "dead" node in the active
 // view with a node randomly chosen from the passive view.
-func (ag *agent) replaceActiveNode(node *node.Node) {
-	// TODO add the node to passive view instead of removing.
-	ag.aView.Lock()
-	if !ag.aView.Remove(node.Id) {
-		ag.aView.Unlock()
+func (ag *agent) replaceActiveNode(lost *node.Node) {
+	if ag.stopped() {
+		return
+	}
+
+	// The node might have been replaced by a new connection
+	// of the same id, which is not lost.
+	ag.viewMu.Lock()
+	if !ag.aView.Has(lost.Id) || ag.aView.GetValueOf(lost.Id) != lost {
+		ag.viewMu.Unlock()
 		return
 	}
-	ag.aView.Unlock()
-	node.Conn.Close()
+	ag.aView.Remove(lost.Id)
+	ag.neighborDown(lost)
+	ag.viewMu.Unlock()
+	lost.Close()
+
+	ag.refillActiveView(lost)
+}
 
-	ag.pView.RLock()
+// refillActiveView() adds a node randomly chosen from the passive view to
+// the active view, after the lost node is removed from it. The lost node
+// is kept in the passive view unless it is nil.
+func (ag *agent) refillActiveView(lost *node.Node) {
+	if ag.stopped() {
+		return
+	}
+
+	// The view lock is not held while talking to the passive nodes.
+	// The nodes that refuse stay in the passive view, but are not
+	// tried again for this replacement.
+	tried := make(map[uint64]bool)
+	depleted := false
 	for {
-		nd := chooseRandomNode(ag.pView, 0)
-		ag.pView.RUnlock()
+		ag.viewMu.RLock()
+		priority := message.Neighbor_Low
+		if ag.aView.Len() == 0 {
+			priority = message.Neighbor_High
+		}
+		nd := chooseUntriedNode(ag.rng, ag.pView, tried)
+		ag.viewMu.RUnlock()
 		if nd == nil {
-			log.Warningf("No nodes in passive view\n")
+			ag.sampledLogger.Warningf("No nodes in passive view\n")
+			depleted = true
 			break
 		}
-
-		if conn, err := ag.connect(nd.Addr); err != nil {
-			log.Errorf("Agent.replaceActiveNode(): Failed to connect %s: %v, drop from passive view.", nd.Addr(), err)
-			ag.pView.Lock()
-			ag.pView.Remove(nd.Id)
-			ag.pView.Unlock()
+		tried[nd.Id] = true
+
+		candidate := &node.Node{Id: nd.Id, Addr: nd.Addr, Labels: nd.Labels}
+		if err := candidate.Dial(ag.dial); err != nil {
+			ag.sampledLogger.Errorf("Agent.replaceActiveNode(): Failed to connect %s: %v, drop from passive view.\n", candidate.Addr, err)
+			ag.viewMu.Lock()
+			ag.pView.Remove(candidate.Id)
+			ag.viewMu.Unlock()
 			continue
-		} else {
-			nd.Conn = conn
 		}
-
-		priority := message.Neighbor_Low
-		if ag.aView.Len() == 0 {
-			message.Neighbor_High
-		}
-		if accepted, err := ag.neighbor(nd, priority); err != nil {
-			log.Errorf("Agent.replaceActiveNode(): Failed to neighbor: %v\n", err)
-			nd.Conn.Close()
-		} else if accpeted {
-			ag.aView.Lock()
-			ag.pView.Lock()
-			ag.pView.Remove(nd.Id)
-			ag.addNodeActiveView(nd)
-			ag.aView.Unlock()
-			ag.pView.Unlock()
-			break
+		accepted, err := ag.neighbor(candidate, priority)
+		if err != nil {
+			ag.sampledLogger.Errorf("Agent.replaceActiveNode(): Failed to neighbor: %v\n", err)
+			candidate.Close()
+			continue
+		}
+		if !accepted {
+			// The peer keeps serving the connection after refusing.
+			ag.pool.put(candidate.Addr, candidate.Conn())
+			continue
 		}
+		ag.viewMu.Lock()
+		ag.addNodeActiveView(candidate)
+		ag.viewMu.Unlock()
+		break
 	}
 
-	ag.aView.RLock()
-	ag.pView.Lock()
-	ag.addNodePassiveView(node)
-	ag.pView.Unlock()
-	ag.aView.RunLock()
+	// Keep the dead node in the passive view, it might come back.
+	if lost != nil {
+		ag.viewMu.Lock()
+		ag.addNodePassiveView(lost)
+		ag.viewMu.Unlock()
+	}
+
+	if depleted {
+		ag.rejoin()
+	}
 
 	ag.resendFailedMessages()
 }
 
-// Resend failed messages if any.
-// NOTE: The view locks should already be held when invoking this function.
-func (ag *agent) resendFailedMessages() {
+// rejoin() joins the seed peers right away if the active view
+// is empty, instead of waiting for the heal loop.
+func (ag *agent) rejoin() {
+	ag.viewMu.RLock()
+	len := ag.aView.Len()
+	ag.viewMu.RUnlock()
+	if len > 0 || ag.stopped() {
+		return
+	}
+
+	ag.logger.Warningf("Agent.rejoin(): Both views are depleted! Join the seed peers again\n")
+	ag.rejoinCluster()
+}
 
-	// Should not use defer unlock to prevent deadlock,
-	// because in userMessage() we will probably lock again.
-	ag.failmsgBuffer.Lock()
-	values := ag.failmsgBuffer.Values()
-	ag.failmsgBuffer.RemoveAll()
-	ag.failmsgBuffer.Unlock()
+// Resend failed messages if any. They are kept
+// until there is a neighbor to resend them to.
+// NOTE: The view lock must not be held when invoking this function.
+func (ag *agent) resendFailedMessages() {
+	if ag.failmsgBuffer.Len() == 0 {
+		return
+	}
+	nodes := ag.activeNodes()
+	if len(nodes) == 0 {
+		return
+	}
+	msgs := ag.failmsgBuffer.RemoveAll()
+	if len(msgs) == 0 {
+		return
+	}
+	defer ag.spoolChanged()
 
-	// We have already lock the view, so do not need locks here.
-	for _, v := range values {
-		log.Debugf("Resending message %v\n", v)
-		msg := v.(*message.UserMessage)
-		for _, vv := range ag.aView.Values() {
-			nd := vv.(*node.Node)
-			go ag.userMessage(nd, msg)
+	now := time.Now().UnixNano()
+	for _, msg := range msgs {
+		if now >= ag.messageDeadline(msg) {
+			ag.logger.Debugf("Dropping expired message %v\n", msg)
+			continue
+		}
+		ag.logger.Debugf("Resending message %v\n", msg)
+		atomic.AddUint64(&ag.stats.resent, 1)
+		for _, nd := range nodes {
+			ag.userMessage(nd, msg)
 		}
 	}
 	return
 }
 
+// bufferFailedMessage() records the message to resend later. If the
+// buffer is full, the oldest message is dropped.
+func (ag *agent) bufferFailedMessage(msg *message.UserMessage) {
+	hash := hashUserMessage(msg)
+
+	if max := ag.config().FailedMessageBufferSize; max > 0 && !ag.failmsgBuffer.Has(hash) {
+		for ag.failmsgBuffer.Len() >= max {
+			var oldest *message.UserMessage
+			ag.failmsgBuffer.Range(func(_ [sha1.Size]byte, m *message.UserMessage) bool {
+				if oldest == nil || m.GetTs() < oldest.GetTs() {
+					oldest = m
+				}
+				return true
+			})
+			if oldest == nil {
+				break
+			}
+			ag.failmsgBuffer.Remove(hashUserMessage(oldest))
+		}
+	}
+	ag.failmsgBuffer.Append(hash, msg)
+	ag.spoolChanged()
+}
+
+// maxMessageLife is the longest lifetime of a message in milliseconds,
+// so a message cannot stay in the caches and be forwarded indefinitely.
+const maxMessageLife = int64(time.Hour / time.Millisecond)
+
+// messageDeadline() returns the time in nanoseconds after which the
+// message is dead, after its own lifetime if it has one, up to
+// maxMessageLife, or MLife.
+func (ag *agent) messageDeadline(msg *message.UserMessage) int64 {
+	if msg.Life != nil {
+		life := msg.GetLife()
+		if life > maxMessageLife {
+			life = maxMessageLife
+		}
+		return msg.GetTs() + time.Millisecond.Nanoseconds()*life
+	}
+	return msg.GetTs() + time.Millisecond.Nanoseconds()*int64(ag.config().MLife)
+}
+
+// purgeDeadline() returns the time in nanoseconds until which the message
+// is kept in the cache, after PurgeDuration, but not before it is dead,
+// so it is not received again while it lives.
+func (ag *agent) purgeDeadline(msg *message.UserMessage, now int64) int64 {
+	purgeDeadline := now + time.Millisecond.Nanoseconds()*int64(ag.config().PurgeDuration)
+	if deadline := ag.messageDeadline(msg); deadline > purgeDeadline {
+		return deadline
+	}
+	return purgeDeadline
+}
+
 // handleJoin() handles Join message. If it accepts the request, it will add
 // the node in the active view. As specified by the protocol, a node should
 // always accept Join requests.
-func (ag *agent) handleJoin(conn *net.TCPConn, msg *message.Join) (accept bool) {
-	newNode := &node.Node{
-		Id:   msg.GetId(),
-		Addr: msg.GetAddr(),
-		Conn: conn,
-	}
+func (ag *agent) handleJoin(conn net.Conn, msg *message.Join) (accept bool) {
+	newNode := node.New(msg.GetId(), msg.GetAddr(), conn)
+	newNode.Labels = decodeLabels(msg.GetLabels())
+	newNode.Caps = nodeCaps(msg.Caps, conn)
 
-	ag.aView.Lock()
-	ag.pView.Lock()
-	defer ag.aView.Unlock()
-	defer ag.pView.Unlock()
+	ag.viewMu.Lock()
+	defer ag.viewMu.Unlock()
 
-	accept = newNode.Id != ag.id && !ag.aView.Has(newNode.Id)
+	// The joins from other clusters, or with the id
+	// of another node, are rejected.
+	accept = ag.sameCluster(msg.GetCluster(), newNode.Addr) && !ag.collides(newNode) && newNode.Id != ag.id && !ag.aView.Has(newNode.Id) && !ag.banned(newNode.Id)
 
 	if err := ag.replyJoin(newNode, accept); err != nil {
-		log.Errorf("Agent.handleJoin(): Failed to reply join: %v", err)
-		node.Conn.Close()
+		ag.sampledLogger.Errorf("Agent.handleJoin(): Failed to reply join: %v", err)
+		newNode.Close()
 		return false
 	}
 
 	if accept {
 		ag.addNodeActiveView(newNode)
 
+		// An observer only wants to receive messages, so do not
+		// advertise it to the rest of the cluster.
+		if msg.GetObserve() {
+			return
+		}
+
 		// Send ForwardJoin message to all other the nodes in the active view.
-		for _, v := range ag.aView.Values() {
-			nd := v.(*node.Node)
+		for _, nd := range ag.aView.Values() {
 			if nd != newNode {
-				go ag.forwardJoin(nd, newNode, uint32(rand.Intn(ag.cfg.ARWL)))
+				go ag.forwardJoin(nd, newNode, uint32(ag.rng.Intn(ag.config().ARWL)))
 			}
 		}
 	}
@@ -406,23 +1169,19 @@ func (ag *agent) handleJoin(conn *net.TCPConn, msg *message.Join) (accept bool)
 // the receiver will always accept the request and add the node to its active view.
 // If the request is low priority, then the request will only be accepted when
 // there are empty slot in the active view.
-func (ag *agent) handleNeighbor(conn *net.TCPConn, msg *message.Neighbor) (accept bool) {
-	newNode := &node.Node{
-		Id:   msg.GetId(),
-		Addr: msg.GetAddr(),
-		Conn: conn,
-	}
+func (ag *agent) handleNeighbor(conn net.Conn, msg *message.Neighbor) (accept bool) {
+	newNode := node.New(msg.GetId(), msg.GetAddr(), conn)
+	newNode.Labels = decodeLabels(msg.GetLabels())
+	newNode.Caps = nodeCaps(msg.Caps, conn)
 
-	ag.aView.Lock()
-	ag.pView.Lock()
-	defer ag.aView.Unlock()
-	defer ag.pView.Unlock()
+	ag.viewMu.Lock()
+	defer ag.viewMu.Unlock()
 
-	accept = newNode.Id != ag.id && !ag.aView.Has(newNode.Id) && (msg.GetPriority() == message.Neighbor_High || ag.aView.Len() < ag.cfg.AViewMaxSize)
+	accept = ag.sameCluster(msg.GetCluster(), newNode.Addr) && !ag.collides(newNode) && newNode.Id != ag.id && !ag.aView.Has(newNode.Id) && !ag.banned(newNode.Id) && (msg.GetPriority() == message.Neighbor_High || ag.aView.Len() < ag.config().AViewMaxSize)
 
 	if err := ag.replyNeighbor(newNode, accept); err != nil {
-		log.Errorf("Agent.handleNeighbor(): Failed to reply neighbor: %v", err)
-		node.Conn.Close()
+		ag.sampledLogger.Errorf("Agent.handleNeighbor(): Failed to reply neighbor: %v", err)
+		newNode.Close()
 		return false
 	}
 	if accept {
@@ -436,94 +1195,135 @@ func (ag *agent) handleNeighbor(conn *net.TCPConn, msg *message.Neighbor) (accep
 func (ag *agent) handleForwardJoin(msg *message.ForwardJoin) {
 	ttl := msg.GetTtl()
 	newNode := &node.Node{
-		Id:   msg.GetSourceId(),
-		Addr: msg.GetSourceAddr(),
+		Id:     msg.GetSourceId(),
+		Addr:   msg.GetSourceAddr(),
+		Labels: decodeLabels(msg.GetSourceLabels()),
 	}
 
-	ag.aView.Lock()
-	ag.pView.Lock()
-	defer ag.aView.Unlock()
-	defer ag.pView.Unlock()
-
+	ag.viewMu.Lock()
 	if ttl == 0 || ag.aView.Len() <= 1 { // TODO(yifan): Loose this?
-		if ag.id != newNode.Id && !ag.aView.Has(newNode.Id) {
-			if conn, err := ag.connect(newNode.Addr); err != nil {
-				log.Errorf("Agent.handleForwardJoin(): Failed to connect %s: %v.", newNode.Addr(), err)
-			} else {
-				newNode.Conn = conn
-				if _, err = ag.neighbor(newNode, message.Neighbor_High); err != nil {
-					log.Errorf("Agent.handleForwardJoin(): Failed to neighbor: %v", err)
-				}
-			}
+		accept := ag.id != newNode.Id && !ag.aView.Has(newNode.Id) && !ag.banned(newNode.Id)
+		ag.viewMu.Unlock()
+		if accept {
+			ag.acceptForwardJoin(newNode)
 		}
 		return
 	}
-	if ttl == uint32(ag.cfg.PRWL) {
+	defer ag.viewMu.Unlock()
+
+	if ttl == uint32(ag.config().PRWL) {
 		ag.addNodePassiveView(newNode)
 	}
-	if node := chooseRandomNode(ag.aView, msg.GetId()); node != nil {
+	if node := chooseRandomNode(ag.rng, ag.aView, msg.GetId()); node != nil {
 		go ag.forwardJoin(node, newNode, ttl-1)
 	}
 	return
 }
 
+// acceptForwardJoin() sends a high priority Neighbor request to the
+// source of a forward join, and adds it to the active view if accepted.
+// The view lock is not held while talking to the source, as the source
+// might be sending requests to this agent at the same time, so the views
+// are checked again before adding it.
+func (ag *agent) acceptForwardJoin(newNode *node.Node) {
+	err := newNode.Dial(ag.connect)
+	if err == ErrSelfConnect {
+		ag.logger.Debugf("Agent.handleForwardJoin(): Skip self address %s\n", newNode.Addr)
+		return
+	} else if err != nil {
+		ag.sampledLogger.Errorf("Agent.handleForwardJoin(): Failed to connect %s: %v.", newNode.Addr, err)
+		return
+	}
+	accepted, err := ag.neighbor(newNode, message.Neighbor_High)
+	if err != nil {
+		ag.sampledLogger.Errorf("Agent.handleForwardJoin(): Failed to neighbor: %v", err)
+	}
+	if !accepted {
+		newNode.Close()
+		return
+	}
+
+	ag.viewMu.Lock()
+	defer ag.viewMu.Unlock()
+	// The source has become a neighbor over another connection,
+	// or has been banned, meanwhile.
+	if ag.aView.Has(newNode.Id) || ag.banned(newNode.Id) {
+		go ag.disconnect(newNode)
+		return
+	}
+	ag.addNodeActiveView(newNode)
+}
+
 // handleShuffle() handles Shuffle message. It will send back a ShuffleReply
 // message and update it's views.
 func (ag *agent) handleShuffle(msg *message.Shuffle) {
-	ag.aView.Lock()
-	ag.pView.Lock()
-	defer ag.aView.Unlock()
-	defer ag.pView.Unlock()
+	// The candidates of other clusters are not learned.
+	if !ag.sameCluster(msg.GetCluster(), msg.GetAddr()) {
+		return
+	}
+
+	ag.viewMu.Lock()
+	defer ag.viewMu.Unlock()
 
 	ttl := msg.GetTtl()
 	if ttl > 0 && ag.aView.Len() > 1 {
-		node := chooseRandomNode(ag.aView, msg.GetId())
+		node := chooseRandomNode(ag.rng, ag.aView, msg.GetId())
 		msg.Ttl = proto.Uint32(ttl - 1)
 		go ag.forwardShuffle(node, msg)
 		return
 	}
 
+	// Reply over the existing connection if the source is a neighbor.
+	var source *node.Node
+	if ag.aView.Has(msg.GetSourceId()) {
+		source = ag.aView.GetValueOf(msg.GetSourceId())
+	}
 	candidates := msg.GetCandidates()
-	replyCandidates := chooseRandomCandidates(ag.pView, len(candidates))
-	go ag.shuffleReply(msg, replyCandidates)
+	replyCandidates := chooseRandomCandidates(ag.rng, ag.pView, len(candidates))
+	go ag.shuffleReply(source, msg, replyCandidates)
 	i := 0
 	for _, candidate := range candidates {
 		node := &node.Node{
-			Id:   candidate.GetId(),
-			Addr: candidate.GetAddr(),
+			Id:     candidate.GetId(),
+			Addr:   candidate.GetAddr(),
+			Labels: decodeLabels(candidate.GetLabels()),
 		}
 		//ag.addNodePassiveView(node)
-		if node.Id == ag.id || ag.aView.Has(node.Id) || ag.pView.Has(node.Id) {
+		if node.Id == ag.id || ag.aView.Has(node.Id) || ag.pView.Has(node.Id) || ag.banned(node.Id) {
 			continue
 		}
-		for ag.pView.Len() >= ag.cfg.PViewSize {
+		for ag.pView.Len() >= ag.config().PViewSize {
 			if i < len(replyCandidates) {
 				ag.pView.Remove(replyCandidates[i].GetId())
 				i++
 			} else {
-				n := chooseRandomNode(ag.pView, 0)
+				n := chooseRandomNode(ag.rng, ag.pView, 0)
 				ag.pView.Remove(n.Id)
 			}
 		}
 		ag.pView.Add(node.Id, node)
+		atomic.AddInt32(&ag.learned, 1)
 	}
 	return
 }
 
 // handleShuffleReply() handles ShuffleReply message. It will update it's views.
 func (ag *agent) handleShuffleReply(msg *message.ShuffleReply) {
-	ag.aView.Lock()
-	ag.pView.Lock()
-	defer ag.aView.Unlock()
-	defer ag.pView.Unlock()
+	atomic.StoreInt64(&ag.stats.lastShuffle, time.Now().UnixNano())
+
+	ag.viewMu.Lock()
+	defer ag.viewMu.Unlock()
 
 	candidates := msg.GetCandidates()
 	for _, candidate := range candidates {
 		node := &node.Node{
-			Id:   candidate.GetId(),
-			Addr: candidate.GetAddr(),
+			Id:     candidate.GetId(),
+			Addr:   candidate.GetAddr(),
+			Labels: decodeLabels(candidate.GetLabels()),
+		}
+		if ag.addNodePassiveView(node) {
+			atomic.AddInt32(&ag.learned, 1)
 		}
-		ag.addNodePassiveView(node)
 	}
 	return
 }
@@ -531,112 +1331,488 @@ func (ag *agent) handleShuffleReply(msg *message.ShuffleReply) {
 // handleUserMessage() handles user defined messages. It will forward the message
 // to the nodes in its active view.
 func (ag *agent) handleUserMessage(from *node.Node, msg *message.UserMessage) {
+	// The message is neither delivered nor forwarded
+	// if the agent is shutting down.
+	if ag.stopped() {
+		return
+	}
+
+	// Acknowledge the message, even if it is stale or
+	// duplicate, so the sender will not send it again.
+	if msg.Seq != nil {
+		go ag.ack(from, msg.GetSeq())
+	}
+
 	// Test if the message is stale.
-	deadline := msg.GetTs() + time.Millisecond.Nanoseconds()*int64(ag.cfg.MLife)
+	deadline := ag.messageDeadline(msg)
 	now := time.Now().UnixNano()
+	atomic.AddUint64(&ag.stats.received, 1)
 	if now >= deadline {
-		log.Debugf("Message is too old, deadline: %v, now %v\n", deadline, now)
+		ag.logger.Debugf("Message is too old, deadline: %v, now %v\n", deadline, now)
+		atomic.AddUint64(&ag.stats.stale, 1)
 		return
 	}
 
-	// Test if the message has been already received.
-	hash := hashMessage(msg.GetPayload())
+	// The message is neither delivered nor forwarded if it is not
+	// signed by a trusted sender. It is verified before it is cached,
+	// so a forged copy does not shadow the genuine message.
+	status := ag.verify(msg)
+	if status != Verified && ag.config().RequireSigned {
+		ag.sampledLogger.Warningf("Agent.handleUserMessage(): Drop the message from %d: %v\n", msg.GetId(), status)
+		atomic.AddUint64(&ag.stats.unverified, 1)
+		return
+	}
 
-	ag.msgBuffer.Lock()
-	defer ag.msgBuffer.Unlock()
+	// Test if the message has been already received,
+	// unless the purge deadline of the entry has passed.
+	hash := hashUserMessage(msg)
+	purgeDeadline := ag.purgeDeadline(msg, now)
+	if !ag.msgCache.addIfAbsent(hash, purgeDeadline, now) {
+		ag.logger.Debugf("Message is alread received, and with purge deadline, hash: %v\n", hash)
+		atomic.AddUint64(&ag.stats.duplicates, 1)
+		if ag.config().Plumtree {
+			// The sender is redundant in the tree.
+			ag.plumtree.setLazy(from.Id, true)
+			go ag.prune(from)
+		}
+		return
+	}
 
-	if ag.msgBuffer.Has(hash) {
-		purgeDeadline := ag.msgBuffer.GetValueOf(hash)
-		if purgeDeadline.(int64) >= now {
-			log.Debugf("Message is alread received, and with purge deadline, hash: %v\n", hash)
+	// The message is dropped if the sequence number of the sender is
+	// received already. Only the verified messages are tracked, as the
+	// others could forge the numbers, and advance the window past the
+	// genuine messages.
+	if w := ag.config().ReplayWindow; w > 0 && msg.OriginSeq != nil && status == Verified {
+		if !ag.replay.accept(msg.GetId(), msg.GetOriginEpoch(), msg.GetOriginSeq(), w, deadline) {
+			ag.sampledLogger.Warningf("Agent.handleUserMessage(): Drop the replayed message %d from %d\n", msg.GetOriginSeq(), msg.GetId())
+			atomic.AddUint64(&ag.stats.replayed, 1)
 			return
 		}
-		ag.msgBuffer.Remove(hash)
 	}
 
-	purgeDeadline := now + time.Millisecond.Nanoseconds()*int64(ag.cfg.PurgeDuration)
-	ag.msgBuffer.Append(hash, purgeDeadline)
+	span := ag.tracer.StartSpan("receive", msg.GetTrace())
+	defer span.End()
 
-	// Invoke user's message handler.
-	go ag.msgHandler(msg.GetPayload())
+	// A chunk is forwarded on its own, while the payload is
+	// delivered only once all of its chunks are received.
+	payload, complete := msg.GetPayload(), true
+	if msg.ChunkCount != nil {
+		payload, status, complete = ag.reassemble(msg, status)
+	}
+	var deliver func()
+	if complete {
+		atomic.StoreInt64(&ag.stats.lastDelivery, now)
+
+		// Invoke user's message handler.
+		um := Message{
+			SenderID:  msg.GetId(),
+			Payload:   payload,
+			Timestamp: msg.GetTs(),
+			Topic:     msg.GetTopic(),
+		}
+		atomic.AddInt32(&ag.stats.handlers, 1)
+		deliver = func() {
+			defer atomic.AddInt32(&ag.stats.handlers, -1)
+			span := ag.tracer.StartSpan("deliver", span.Context())
+			defer span.End()
+			if dh := ag.deliveredHandler; dh != nil {
+				dh(DeliveredMessage{Message: um, KeyFingerprint: msg.GetKey(), Signature: status})
+			} else if mh := ag.messageHandler(um.Topic); mh != nil {
+				mh(um)
+			}
+		}
+	}
+	switch {
+	case ag.config().FIFOWindow > 0 && msg.OriginSeq != nil:
+		// The chunks advance the sequence of the sender too.
+		ag.deliverInOrder(msg.GetId(), msg.GetOriginEpoch(), msg.GetOriginSeq(), deliver, deadline)
+	case deliver == nil:
+	case ag.config().SerializeHandler:
+		ag.dispatcher.dispatch(msg.GetId(), deliver)
+	default:
+		go deliver()
+	}
 
-	ag.aView.Lock()
-	defer ag.aView.Unlock()
+	forward := ag.tracer.StartSpan("forward", span.Context())
+	defer forward.End()
+	fmsg := &message.UserMessage{
+		Id:      msg.Id,
+		Payload: msg.Payload,
+		Ts:      msg.Ts,
+		Trace:   forward.Context(),
+		Topic:   msg.Topic,
+		Life:    msg.Life,
+		Key:     msg.Key,
+		Sig:     msg.Sig,
+
+		ChunkId:     msg.ChunkId,
+		ChunkIndex:  msg.ChunkIndex,
+		ChunkCount:  msg.ChunkCount,
+		ChunkHash:   msg.ChunkHash,
+		OriginSeq:   msg.OriginSeq,
+		OriginEpoch: msg.OriginEpoch,
+	}
+	// The message is not forwarded after its last hop.
+	last := false
+	if msg.Hops != nil {
+		hops := msg.GetHops()
+		if hops > 0 {
+			hops--
+		}
+		fmsg.Hops = proto.Uint32(hops)
+		last = hops == 0
+	}
 
-	for _, v := range ag.aView.Values() {
-		nd := v.(*node.Node)
+	if ag.config().Plumtree {
+		// The sender is on the tree of the message.
+		ag.plumtree.setLazy(from.Id, false)
+		ag.plumtree.received(hash, fmsg, purgeDeadline)
+		if !last {
+			ag.pushMessage(from, hash, fmsg)
+		}
+		return
+	}
+	if last {
+		return
+	}
+	for _, nd := range ag.activeNodes() {
 		if nd.Id != from.Id {
-			go ag.userMessage(nd, msg)
+			ag.userMessage(nd, fmsg)
 		}
 	}
 	return
 }
 
-func (ag *agent) connect(peerAddr string) (*net.TCPConn, error) {
-	addr, err := net.ResolveTCPAddr(ag.cfg.Net, peerAddr)
+// activeNodes() returns a copy of the active view, taken under the view
+// lock, so the messages are queued to the nodes after releasing it, as
+// the senders might block on the full send queues.
+func (ag *agent) activeNodes() []*node.Node {
+	ag.viewMu.RLock()
+	defer ag.viewMu.RUnlock()
+	return ag.aView.Snapshot()
+}
+
+// connect() connects the peer with the transport.
+func (ag *agent) connect(peerAddr string) (net.Conn, error) {
+	if peerAddr == ag.addr {
+		return nil, ErrSelfConnect
+	}
+	if ag.config().Transport == nil {
+		addr, err := net.ResolveTCPAddr(ag.config().Net, peerAddr)
+		if err != nil {
+			// TODO(yifan) log.
+			return nil, err
+		}
+		if ag.isSelf(addr) {
+			return nil, ErrSelfConnect
+		}
+	}
+	conn, err := ag.transport.Dial(peerAddr)
 	if err != nil {
-		// TODO(yifan) log.
 		return nil, err
 	}
-	conn, err := net.DialTCP(ag.cfg.Net, nil, addr)
+	conn = ag.wrapConn(conn)
+	pc, err := ag.handshake(conn, true)
 	if err != nil {
-		// TODO(yifan) log.
+		conn.Close()
 		return nil, err
 	}
-	return conn, nil
+	return pc, nil
 }
 
+// maxConcurrentJoins is the maximum number of peers
+// that Join() contacts at the same time.
+const maxConcurrentJoins = 4
+
 // Join joins the node to the cluster by contacting the nodes provied in the
-// list.
+// list. The peers are tried concurrently, and the first one that accepts the
+// join is added to the active view. If no peer accepts, it retries the peers
+// with exponential backoff and a random jitter, as many times as configured.
+// The hostnames in the list are resolved again on each attempt.
 func (ag *agent) Join(peerAddrs ...string) error {
 	// Append the peer list.
-	ag.cfg.Peers = append(ag.cfg.Peers, peerAddrs...)
-
-	for _, peerAddr := range peerAddrs {
-		log.Infof("Agent.Join(): Trying to join %s...\n", peerAddr)
+	ag.addPeers(peerAddrs...)
+	ag.resetRejoin()
+	return ag.joinCluster(peerAddrs)
+}
 
-		conn, err := ag.connect(peerAddr)
-		if err != nil {
-			log.Errorf("Agent.Join(): Failed to connect %s: %v\n", peerAddr, err)
+// joinCluster() joins the first peer that accepts, and
+// retries with exponential backoff if none accepts.
+func (ag *agent) joinCluster(peerAddrs []string) error {
+	for retry := 0; ; retry++ {
+		if nd := ag.joinAny(peerAddrs); nd != nil {
+			// Successfully Joined.
+			ag.logger.Infof("Successfully join node %s\n", nd.Addr)
+			ag.viewMu.Lock()
+			ag.addNodeActiveView(nd)
+			ag.viewMu.Unlock()
+			return nil
+		}
+		if retry >= ag.config().JoinRetries {
+			return ErrNoAvailablePeers
 		}
-		node := &node.Node{Addr: peerAddr, Conn: conn}
+		backoff := ag.joinBackoff(retry)
+		ag.logger.Warningf("Agent.Join(): No peer accepted, retry in %v\n", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ag.stopc:
+			return ErrNoAvailablePeers
+		}
+	}
+}
 
-		if accepted, err := ag.join(node); err != nil || !accepted {
-			log.Errorf("Agent.Join(): Failed to join: accepted:%v, err:%v\n", accepted, err)
-			node.Conn.Close()
-			continue
+// joinAny() joins the peers, at most maxConcurrentJoins at a time, and
+// returns the first node that accepts the join, or nil if none accepts.
+// No more peers are tried once a node accepts, and the nodes that accept
+// later are disconnected, so they do not keep the agent in their views.
+func (ag *agent) joinAny(peerAddrs []string) *node.Node {
+	done := make(chan struct{})
+	defer close(done)
+
+	// Every peer sends exactly one result, nil if it is not joined.
+	results := make(chan *node.Node, len(peerAddrs))
+	go func() {
+		sem := make(chan struct{}, maxConcurrentJoins)
+		for _, peerAddr := range peerAddrs {
+			select {
+			case sem <- struct{}{}:
+			case <-done:
+				results <- nil
+				continue
+			}
+			go func(peerAddr string) {
+				results <- ag.joinPeer(peerAddr)
+				<-sem
+			}(peerAddr)
+		}
+	}()
+
+	for i := range peerAddrs {
+		if nd := <-results; nd != nil {
+			go func(n int) {
+				for ; n > 0; n-- {
+					if nd := <-results; nd != nil {
+						ag.disconnect(nd)
+					}
+				}
+			}(len(peerAddrs) - i - 1)
+			return nd
 		}
-		// Successfully Joined.
-		log.Infof("Successfully join node %s\n", peerAddr)
-		ag.aView.Lock()
-		ag.pView.Lock()
-		defer ag.aView.Unlock()
-		defer ag.pView.Unlock()
-		ag.addNodeActiveView(node)
+	}
+	return nil
+}
+
+// joinPeer() connects and joins the peer, and returns
+// the node if the peer accepts the join.
+func (ag *agent) joinPeer(peerAddr string) *node.Node {
+	ag.logger.Infof("Agent.Join(): Trying to join %s...\n", peerAddr)
+	atomic.AddUint64(&ag.stats.joins, 1)
+
+	nd := &node.Node{Addr: peerAddr}
+	err := nd.Dial(ag.connect)
+	if err == ErrSelfConnect {
+		ag.logger.Debugf("Agent.Join(): Skip self address %s\n", peerAddr)
+		return nil
+	}
+	if err != nil {
+		ag.logger.Errorf("Agent.Join(): Failed to connect %s: %v\n", peerAddr, err)
 		return nil
 	}
-	return ErrNoAvailablePeers
+	if accepted, err := ag.join(nd); err != nil || !accepted {
+		ag.logger.Errorf("Agent.Join(): Failed to join: accepted:%v, err:%v\n", accepted, err)
+		nd.Close()
+		return nil
+	}
+	ag.viewMu.RLock()
+	banned := ag.banned(nd.Id)
+	ag.viewMu.RUnlock()
+	if banned {
+		ag.logger.Warningf("Agent.Join(): Node %d at %s is banned\n", nd.Id, peerAddr)
+		ag.disconnect(nd)
+		return nil
+	}
+	return nd
+}
+
+// Leave causes the agent to leave the cluster. It sends Disconnect
+// messages to the nodes in the active view, and then shuts down the agent.
+func (ag *agent) Leave() error {
+	ag.logger.Infof("Agent is leaving...\n")
+	// Stop first, so the disconnected nodes will not be replaced.
+	ag.stop()
+	ag.disconnectAll(context.Background())
+	return ag.Close()
+}
+
+// shutdownPollInterval is the interval that Shutdown()
+// checks if the user callbacks in flight have returned.
+const shutdownPollInterval = 10 * time.Millisecond
+
+// Shutdown gracefully shuts down the agent. It stops accepting connections
+// and delivering the user messages, waits for the user callbacks in flight,
+// then sends Disconnect messages to the nodes in the active view and closes
+// all the connections. If the context is done before the callbacks return,
+// or before the Disconnect messages are written, the agent is closed without
+// waiting, and the context error is returned.
+func (ag *agent) Shutdown(ctx context.Context) error {
+	ag.logger.Infof("Agent is shutting down...\n")
+	ag.stop()
+	err := ag.closeListeners()
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for atomic.LoadInt32(&ag.stats.handlers) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			ag.Close()
+			return ctx.Err()
+		}
+	}
+
+	if e := ag.disconnectAll(ctx); e != nil {
+		ag.Close()
+		return e
+	}
+	if e := ag.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// Close shuts down the agent without notifying the peers. It stops
+// accepting connections and closes the connections in the active view.
+func (ag *agent) Close() error {
+	ag.stop()
+
+	err := ag.closeListeners()
+	ag.pool.close()
+
+	// Save the views before they are cleared, so the agent
+	// rejoins through the same nodes after restart.
+	if err := ag.saveState(); err != nil {
+		ag.logger.Errorf("Agent.Close(): Failed to save state: %v\n", err)
+	}
+	if err := ag.saveSpool(); err != nil {
+		ag.logger.Errorf("Agent.Close(): Failed to save spool: %v\n", err)
+	}
+
+	ag.viewMu.Lock()
+	defer ag.viewMu.Unlock()
+
+	for _, nd := range ag.aView.Values() {
+		nd.Close()
+	}
+	ag.aView.RemoveAll()
+	ag.pView.RemoveAll()
+	return err
+}
+
+// closeListeners() closes the listeners and the connection
+// of the datagrams, if they are not closed yet.
+func (ag *agent) closeListeners() error {
+	ag.lnMu.Lock()
+	defer ag.lnMu.Unlock()
+	err := closeListeners(ag.lns)
+	if ag.pc != nil {
+		if e := ag.pc.Close(); err == nil {
+			err = e
+		}
+	}
+	ag.lns, ag.pc = nil, nil
+	return err
 }
 
-// Leave causes the agent to leave the cluster.
-func (ag *agent) Leave() {
-	log.Infof("Agent is leaving...\n")
-	os.Exit(0)
+// stop() stops the agent, and wakes up the senders blocked
+// on the full send queues, which drop their messages.
+func (ag *agent) stop() {
+	ag.closeOnce.Do(func() { close(ag.stopc) })
+	ag.sendq.wakeup()
+}
+
+// stopped() returns true if the agent has been closed.
+func (ag *agent) stopped() bool {
+	select {
+	case <-ag.stopc:
+		return true
+	default:
+		return false
+	}
 }
 
 // Broadcast broadcasts a message to the cluster.
 func (ag *agent) Broadcast(payload []byte) error {
+	return ag.BroadcastTopic("", payload)
+}
+
+// BroadcastTopic broadcasts a message of the topic to the cluster.
+// The empty topic is the default topic, as Broadcast.
+func (ag *agent) BroadcastTopic(topic string, payload []byte) error {
+	return ag.BroadcastWithOptions(payload, BroadcastOptions{Topic: topic})
+}
+
+// BroadcastOptions are the options of a broadcast message.
+type BroadcastOptions struct {
+	// The topic, empty for the default topic.
+	Topic string
+	// Hops is the maximum number of hops the message travels, e.g. 1
+	// for the neighbors only, 0 for unlimited. The agents relaying
+	// the message decrement it.
+	Hops int
+	// Life is the time for which the message lives, instead of
+	// the MLife of the receivers, 0 for the MLife. The receivers
+	// cap it at an hour.
+	Life time.Duration
+}
+
+// BroadcastWithOptions broadcasts a message with the options.
+func (ag *agent) BroadcastWithOptions(payload []byte, opts BroadcastOptions) error {
+	span := ag.tracer.StartSpan("broadcast", nil)
+	defer span.End()
+	atomic.AddUint64(&ag.stats.broadcasts, 1)
+
 	msg := &message.UserMessage{
 		Id:      proto.Uint64(ag.id),
 		Payload: payload,
 		Ts:      proto.Int64(time.Now().UnixNano()),
+		Trace:   span.Context(),
+	}
+	if opts.Topic != "" {
+		msg.Topic = proto.String(opts.Topic)
+	}
+	if opts.Hops > 0 {
+		msg.Hops = proto.Uint32(uint32(opts.Hops))
+	}
+	if opts.Life > 0 {
+		msg.Life = proto.Int64(int64(opts.Life / time.Millisecond))
+	}
+	msgs := ag.chunk(msg)
+	for _, msg := range msgs {
+		msg.OriginSeq = proto.Uint64(ag.replay.next())
+		msg.OriginEpoch = proto.Uint64(ag.replay.epoch)
+		ag.sign(msg)
+	}
+
+	// The messages are resent once the agent has a neighbor.
+	nodes := ag.activeNodes()
+	if len(nodes) == 0 {
+		for _, msg := range msgs {
+			ag.bufferFailedMessage(msg)
+		}
+		return nil
 	}
 
-	ag.aView.Lock()
-	defer ag.aView.Unlock()
-	for _, v := range ag.aView.Values() {
-		nd := v.(*node.Node)
-		ag.userMessage(nd, msg)
+	if ag.config().Plumtree {
+		for _, msg := range msgs {
+			ag.broadcastPlumtree(msg)
+		}
+		return nil
+	}
+
+	for _, msg := range msgs {
+		for _, nd := range nodes {
+			ag.userMessage(nd, msg)
+		}
 	}
 	return nil
 }
@@ -647,53 +1823,146 @@ func (ag *agent) RegisterMessageHandler(mh MessageHandler) {
 	ag.msgHandler = mh
 }
 
+// RegisterTopicHandler registers a user provided message callback
+// to handle the messages of the topic. The messages of the topics
+// without a handler are handled by the default message handler.
+func (ag *agent) RegisterTopicHandler(topic string, mh MessageHandler) {
+	ag.topicHandlersMu.Lock()
+	defer ag.topicHandlersMu.Unlock()
+	ag.topicHandlers[topic] = mh
+}
+
+// messageHandler() returns the handler of the topic, or
+// the default handler if the topic has no handler.
+func (ag *agent) messageHandler(topic string) MessageHandler {
+	if topic != "" {
+		ag.topicHandlersMu.RLock()
+		mh, ok := ag.topicHandlers[topic]
+		ag.topicHandlersMu.RUnlock()
+		if ok {
+			return mh
+		}
+	}
+	return ag.msgHandler
+}
+
+// RegisterTracer registers a tracer to create the spans
+// of the user messages.
+func (ag *agent) RegisterTracer(t Tracer) {
+	ag.tracer = t
+}
+
 // List() lists the active view and passive view.
 func (ag *agent) List() ([]byte, error) {
-	ag.aView.Lock()
-	ag.pView.Lock()
-	defer ag.aView.Unlock()
-	defer ag.pView.Unlock()
+	ag.viewMu.RLock()
+	defer ag.viewMu.RUnlock()
 
-	log.Debugf("AView:\n")
-	for _, v := range ag.aView.Values() {
-		log.Debugf("%v\n", v.(*node.Node))
+	ag.logger.Debugf("AView:\n")
+	for _, nd := range ag.aView.Values() {
+		ag.logger.Debugf("%v\n", nd)
 	}
-	log.Debugf("PView:\n")
-	for _, v := range ag.pView.Values() {
-		log.Debugf("%v\n", v.(*node.Node))
+	ag.logger.Debugf("PView:\n")
+	for _, nd := range ag.pView.Values() {
+		ag.logger.Debugf("%v\n", nd)
 	}
 
 	view := &view{ag.aView, ag.pView}
 	return json.Marshal(view)
 }
 
+// Peers returns the addresses of the nodes in the active view,
+// the passive view, or both, taken under the view lock.
+func (ag *agent) Peers(active, passive bool) []string {
+	ag.viewMu.RLock()
+	defer ag.viewMu.RUnlock()
+
+	peers := make([]string, 0)
+	if active {
+		for _, nd := range ag.aView.Values() {
+			peers = append(peers, nd.Addr)
+		}
+	}
+	if passive {
+		for _, nd := range ag.pView.Values() {
+			peers = append(peers, nd.Addr)
+		}
+	}
+	return peers
+}
+
 // Helpers
 
+// isTimeout() returns true if the error is caused by a deadline.
+func isTimeout(err error) bool {
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
+
 // hashMessage() returns the hash of a user message.
 func hashMessage(msg []byte) [sha1.Size]byte {
 	return sha1.Sum(msg)
 }
 
+// hashUserMessage() returns the hash of the payload and the topic of
+// a user message, so the same payload of different topics are different
+// messages. The hash of the default topic is the hash of the payload.
+// The messages with the sequence numbers of their senders are hashed
+// with them too, and their epochs, so the same payload broadcast twice,
+// by different senders, or by a restarted sender, are different messages.
+func hashUserMessage(msg *message.UserMessage) [sha1.Size]byte {
+	if msg.GetTopic() == "" && msg.ChunkCount == nil && msg.OriginSeq == nil {
+		return hashMessage(msg.GetPayload())
+	}
+	h := sha1.New()
+	h.Write([]byte(msg.GetTopic()))
+	h.Write([]byte{0})
+	if msg.OriginSeq != nil {
+		var b [24]byte
+		binary.BigEndian.PutUint64(b[:], msg.GetId())
+		binary.BigEndian.PutUint64(b[8:], msg.GetOriginSeq())
+		binary.BigEndian.PutUint64(b[16:], msg.GetOriginEpoch())
+		h.Write(b[:])
+	}
+	// The chunks of the same content in different payloads
+	// are different messages.
+	if msg.ChunkCount != nil {
+		var b [16]byte
+		binary.BigEndian.PutUint64(b[:], msg.GetChunkId())
+		binary.BigEndian.PutUint32(b[8:], msg.GetChunkIndex())
+		binary.BigEndian.PutUint32(b[12:], msg.GetChunkCount())
+		h.Write(b[:])
+	}
+	h.Write(msg.GetPayload())
+	var hash [sha1.Size]byte
+	copy(hash[:], h.Sum(nil))
+	return hash
+}
+
 // chooseRandomNode() chooses a random node from the active view
 // or passive view.
-func chooseRandomNode(view *arraymap.ArrayMap, excludeId uint64) *node.Node {
-	if view.Len() == 0 {
-		return nil
-	}
-	index := rand.Intn(view.Len())
-	nd := view.GetValueAt(index).(*node.Node)
-	if nd.Id == excludeId {
-		if view.Len() == 1 {
-			return nil
+func chooseRandomNode(r *rand.Rand, view *arraymap.OrderedMap[uint64, *node.Node], excludeId uint64) *node.Node {
+	nd, _ := view.RandomValue(r, excludeId)
+	return nd
+}
+
+// chooseUntriedNode() chooses a random node from the view
+// that is not in the tried set.
+func chooseUntriedNode(r *rand.Rand, view *arraymap.OrderedMap[uint64, *node.Node], tried map[uint64]bool) *node.Node {
+	var nodes []*node.Node
+	for _, nd := range view.Values() {
+		if !tried[nd.Id] {
+			nodes = append(nodes, nd)
 		}
-		nd = view.GetValueAt((index + 1) % view.Len()).(*node.Node)
 	}
-	return nd
+	if len(nodes) == 0 {
+		return nil
+	}
+	return nodes[r.Intn(len(nodes))]
 }
 
 // chooseRandomCandidates() selects n random nodes from the active view
 // or passive view. If n > the size of the view, then all nodes are returned.
-func chooseRandomCandidates(view *arraymap.ArrayMap, n int) []*message.Candidate {
+func chooseRandomCandidates(r *rand.Rand, view *arraymap.OrderedMap[uint64, *node.Node], n int) []*message.Candidate {
 	if view.Len() == 0 {
 		return nil
 	}
@@ -701,12 +1970,13 @@ func chooseRandomCandidates(view *arraymap.ArrayMap, n int) []*message.Candidate
 		n = view.Len()
 	}
 	candidates := make([]*message.Candidate, n)
-	index := rand.Intn(view.Len())
+	index := r.Intn(view.Len())
 	for i := 0; i < n; i++ {
-		nd := view.GetValueAt((index + i) % view.Len()).(*node.Node)
+		nd := view.GetValueAt((index + i) % view.Len())
 		candidates[i] = &message.Candidate{
-			Id:   proto.Uint64(nd.Id),
-			Addr: proto.String(nd.Addr),
+			Id:     proto.Uint64(nd.Id),
+			Addr:   proto.String(nd.Addr),
+			Labels: encodeLabels(nd.Labels),
 		}
 	}
 	return candidates
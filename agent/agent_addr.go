@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"net"
+
+	"github.com/lilymona/gog/config"
+	log "github.com/lilymona/gog/logging"
+)
+
+// interfaceAddrs returns the addresses of the interfaces,
+// it is replaced in the tests.
+var interfaceAddrs = net.InterfaceAddrs
+
+// advertiseAddr() returns the address advertised to the peers. It is the
+// AdvertiseAddr if set, otherwise the listen address, whose host is replaced
+// by an address of the interfaces if it is empty or unspecified, as the
+// peers cannot dial back ":8424" or "0.0.0.0:8424".
+func advertiseAddr(cfg *config.Config) string {
+	if cfg.AdvertiseAddr != "" {
+		return cfg.AdvertiseAddr
+	}
+	host, port, err := net.SplitHostPort(cfg.AddrStr)
+	if err != nil {
+		return cfg.AddrStr
+	}
+	ip := net.ParseIP(host)
+	if host != "" && (ip == nil || !ip.IsUnspecified()) {
+		return cfg.AddrStr
+	}
+
+	// Prefer IPv6 only if the agent listens on "::".
+	logger := newLogger(cfg)
+	ifaceIP := interfaceIP(logger, ip != nil && ip.To4() == nil)
+	if ifaceIP == nil {
+		logger.Warningf("Cannot find an address to advertise, use %s\n", cfg.AddrStr)
+		return cfg.AddrStr
+	}
+	return net.JoinHostPort(ifaceIP.String(), port)
+}
+
+// isSelf() returns true if the address is the advertised address or one
+// of the listen addresses of the agent.
+func (ag *agent) isSelf(addr *net.TCPAddr) bool {
+	if adv, err := net.ResolveTCPAddr(ag.config().Net, ag.addr); err == nil {
+		if adv.Port == addr.Port && adv.IP.Equal(addr.IP) {
+			return true
+		}
+	}
+	if listensOn(ag.config().LocalTCPAddr, addr) {
+		return true
+	}
+	for _, local := range ag.config().ExtraTCPAddrs {
+		if listensOn(local, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// listensOn() returns true if the listen address is the address. If the
+// listen address is on all the interfaces, any address of the interfaces
+// with the listen port is the listen address.
+func listensOn(local, addr *net.TCPAddr) bool {
+	if local == nil || local.Port != addr.Port {
+		return false
+	}
+	if local.IP != nil && !local.IP.IsUnspecified() {
+		return local.IP.Equal(addr.IP)
+	}
+	if addr.IP.IsLoopback() || addr.IP.IsUnspecified() {
+		return true
+	}
+	addrs, err := interfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.Equal(addr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// interfaceIP() returns a global unicast address of the interfaces, in
+// the preferred family if there is any, or the loopback address if there
+// is none, or nil if there is no address at all.
+func interfaceIP(logger log.Logger, preferIPv6 bool) net.IP {
+	addrs, err := interfaceAddrs()
+	if err != nil {
+		logger.Errorf("Agent.interfaceIP(): Failed to get interface addresses: %v\n", err)
+		return nil
+	}
+
+	var v4, v6, loopback net.IP
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		switch ip := ipnet.IP; {
+		case ip.IsLoopback():
+			if loopback == nil {
+				loopback = ip
+			}
+		case !ip.IsGlobalUnicast():
+		case ip.To4() != nil:
+			if v4 == nil {
+				v4 = ip
+			}
+		default:
+			if v6 == nil {
+				v6 = ip
+			}
+		}
+	}
+	if preferIPv6 && v6 != nil {
+		return v6
+	}
+	if v4 != nil {
+		return v4
+	}
+	if v6 != nil {
+		return v6
+	}
+	return loopback
+}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"net"
+	"sync/atomic"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// nonceSize is the size of the nonces of the challenges.
+const nonceSize = 16
+
+// proof() returns the HMAC of the role and the nonces of the dialer and
+// the other end, in this order, with the cluster secret.
+func (ag *agent) proof(role string, dialerNonce, nonce []byte) []byte {
+	h := hmac.New(sha256.New, []byte(ag.config().ClusterSecret))
+	h.Write([]byte(role))
+	h.Write(dialerNonce)
+	h.Write(nonce)
+	return h.Sum(nil)
+}
+
+// challenge() authenticates both ends of a new connection with the
+// cluster secret, after the preambles. Each end sends a random nonce, and
+// then proves that it knows the secret with the HMAC of both nonces, so
+// a proof cannot be replayed on another connection. The dialer proves
+// first, and the other end only proves it to a dialer that knows the
+// secret.
+func (ag *agent) challenge(conn net.Conn, dialer bool) error {
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	peerNonce := make([]byte, nonceSize)
+	proof := make([]byte, sha256.Size)
+	if dialer {
+		if _, err := conn.Write(nonce); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, peerNonce); err != nil {
+			return err
+		}
+		if _, err := conn.Write(ag.proof("dial", nonce, peerNonce)); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, proof); err != nil {
+			return err
+		}
+		if !hmac.Equal(proof, ag.proof("accept", nonce, peerNonce)) {
+			return ag.authFailed(conn)
+		}
+		return nil
+	}
+
+	if _, err := io.ReadFull(conn, peerNonce); err != nil {
+		return err
+	}
+	if _, err := conn.Write(nonce); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(conn, proof); err != nil {
+		return err
+	}
+	if !hmac.Equal(proof, ag.proof("dial", peerNonce, nonce)) {
+		return ag.authFailed(conn)
+	}
+	_, err := conn.Write(ag.proof("accept", peerNonce, nonce))
+	return err
+}
+
+// authFailed() counts and logs the peer that failed
+// to authenticate, and returns ErrUnauthenticated.
+func (ag *agent) authFailed(conn net.Conn) error {
+	atomic.AddUint64(&ag.stats.authFailures, 1)
+	ag.sampledLogger.Warningf("Agent.authFailed(): Reject the unauthenticated peer %v\n", conn.RemoteAddr())
+	return ErrUnauthenticated
+}
+
+// cluster() returns the cluster name sent in the Join, Neighbor
+// and Shuffle messages, nil for the default cluster.
+func (ag *agent) cluster() *string {
+	if name := ag.config().ClusterName; name != "" {
+		return proto.String(name)
+	}
+	return nil
+}
+
+// sameCluster() returns true if the cluster name of the message from
+// the node is the one of the agent. Otherwise the message is rejected,
+// which is logged and counted.
+func (ag *agent) sameCluster(cluster, addr string) bool {
+	if cluster == ag.config().ClusterName {
+		return true
+	}
+	atomic.AddUint64(&ag.stats.clusterMismatches, 1)
+	ag.sampledLogger.Warningf("Agent.sameCluster(): Reject node %s of cluster %q\n", addr, cluster)
+	return false
+}
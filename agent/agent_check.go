@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"crypto/tls"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/lilymona/gog/node"
+)
+
+// checkLoop() periodically checks the views, as a safety net
+// for the bugs that break the view invariants.
+func (ag *agent) checkLoop() {
+	if ag.config().CheckDuration <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(ag.config().CheckDuration) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ag.checkViews()
+		case <-ag.stopc:
+			return
+		}
+	}
+}
+
+// checkViews() detects and repairs the violations of the view invariants:
+// the agent itself must not be in any view, a node must not be in both
+// views, and the nodes in the active view must have open connections.
+// It returns the number of repairs.
+func (ag *agent) checkViews() int {
+	var dead []*node.Node
+	repairs := 0
+
+	ag.viewMu.Lock()
+
+	if ag.aView.Remove(ag.id) {
+		ag.logger.Warningf("Agent.checkViews(): Removed self from active view\n")
+		repairs++
+	}
+	if ag.pView.Remove(ag.id) {
+		ag.logger.Warningf("Agent.checkViews(): Removed self from passive view\n")
+		repairs++
+	}
+	for _, nd := range ag.aView.Values() {
+		if ag.pView.Remove(nd.Id) {
+			ag.logger.Warningf("Agent.checkViews(): Removed %v from passive view, it is in active view\n", nd)
+			repairs++
+		}
+		if nd.State() == node.StateClosed || isClosed(nd.Conn()) {
+			dead = append(dead, nd)
+		}
+	}
+
+	ag.viewMu.Unlock()
+
+	// replaceActiveNode() acquires the locks itself.
+	for _, nd := range dead {
+		ag.logger.Warningf("Agent.checkViews(): Replacing %v in active view, its connection is closed\n", nd)
+		ag.replaceActiveNode(nd)
+		repairs++
+	}
+	return repairs
+}
+
+// isClosed() returns true if the connection is nil or has been
+// closed locally. A TLS connection is checked by its TCP connection.
+func isClosed(conn net.Conn) bool {
+	if conn == nil {
+		return true
+	}
+	if pc, ok := conn.(*peerConn); ok {
+		conn = pc.Conn
+	}
+	if tc, ok := conn.(*timeoutConn); ok {
+		conn = tc.Conn
+	}
+	if cc, ok := conn.(*countingConn); ok {
+		conn = cc.Conn
+	}
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return false
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return true
+	}
+	return rc.Control(func(uintptr) {}) != nil
+}
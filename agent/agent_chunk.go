@@ -0,0 +1,240 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/lilymona/gog/message"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+const (
+	// maxChunks is the maximum number of the chunks of a payload.
+	maxChunks = 1 << 16
+	// maxPartials and maxSenderPartials bound the payloads being
+	// reassembled, in total and from a sender.
+	maxPartials       = 1024
+	maxSenderPartials = 16
+	// partialOverhead is the size in bytes charged for a payload being
+	// reassembled besides its chunks, e.g. for its timer.
+	partialOverhead = 256
+)
+
+// chunks is the state of the reassembly of the chunked payloads. The
+// chunks are broadcast as separate user messages, so no hop holds more
+// than a chunk of the payload, and the receivers buffer the chunks until
+// all of them are received to deliver the payload.
+type chunks struct {
+	sync.Mutex
+	// The payloads being reassembled.
+	partial map[chunkKey]*partialPayload
+	// The numbers of the payloads being reassembled of the senders.
+	senders map[uint64]int
+	// The total size in bytes of the buffered chunks,
+	// and of the payloads they are in, see partialCost().
+	size int
+}
+
+// chunkKey is the sender and the chunk id of a chunked payload.
+type chunkKey struct {
+	sender uint64
+	id     uint64
+}
+
+// partialPayload is a chunked payload being reassembled.
+type partialPayload struct {
+	parts    [][]byte
+	received int
+	// The size of the received chunks, and the size
+	// charged for the payload besides them.
+	size     int
+	overhead int
+	started  time.Time
+	// The worst signature status of the chunks.
+	status SignatureStatus
+	// The hash of the whole payload in the chunks.
+	hash  []byte
+	timer *time.Timer
+}
+
+func newChunks() *chunks {
+	return &chunks{partial: make(map[chunkKey]*partialPayload), senders: make(map[uint64]int)}
+}
+
+// partialCost() returns the size in bytes charged for a payload
+// of the number of chunks being reassembled, besides its chunks.
+func partialCost(count int) int {
+	return count*int(unsafe.Sizeof([]byte(nil))) + partialOverhead
+}
+
+// remove() removes the payload, and stops its timer.
+// The chunks must be locked.
+func (c *chunks) remove(key chunkKey) *partialPayload {
+	p, ok := c.partial[key]
+	if !ok {
+		return nil
+	}
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	c.size -= p.size + p.overhead
+	delete(c.partial, key)
+	if c.senders[key.sender]--; c.senders[key.sender] <= 0 {
+		delete(c.senders, key.sender)
+	}
+	return p
+}
+
+// oldest() returns the key of the oldest payload, or of the oldest
+// payload of the sender if bySender. The chunks must be locked.
+func (c *chunks) oldest(sender uint64, bySender bool) chunkKey {
+	var key chunkKey
+	var started time.Time
+	for k, p := range c.partial {
+		if bySender && k.sender != sender {
+			continue
+		}
+		if started.IsZero() || p.started.Before(started) {
+			key, started = k, p.started
+		}
+	}
+	return key
+}
+
+// chunk() splits the message into the messages of the chunks of the
+// payload, if the payload is larger than the chunk size.
+func (ag *agent) chunk(msg *message.UserMessage) []*message.UserMessage {
+	size := ag.config().ChunkSize
+	payload := msg.GetPayload()
+	if size <= 0 || len(payload) <= size {
+		return []*message.UserMessage{msg}
+	}
+
+	count := (len(payload) + size - 1) / size
+	id := ag.rng.Uint64()
+	hash := sha256.Sum256(payload)
+	msgs := make([]*message.UserMessage, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+		if end > len(payload) {
+			end = len(payload)
+		}
+		msgs = append(msgs, &message.UserMessage{
+			Id:         msg.Id,
+			Payload:    payload[i*size : end],
+			Ts:         msg.Ts,
+			Trace:      msg.Trace,
+			Topic:      msg.Topic,
+			Hops:       msg.Hops,
+			Life:       msg.Life,
+			ChunkId:    proto.Uint64(id),
+			ChunkIndex: proto.Uint32(uint32(i)),
+			ChunkCount: proto.Uint32(uint32(count)),
+			ChunkHash:  hash[:],
+		})
+	}
+	return msgs
+}
+
+// reassemble() buffers the chunk, and returns the payload and its
+// signature status when all the chunks of the payload are received.
+// The payload is dropped if its hash is not the one in its chunks,
+// which are signed with it, so the chunks of a signed payload cannot
+// be swapped with those of another one. The buffer is charged for the
+// payloads being reassembled too, and their numbers are bounded, so
+// the announced counts of the chunks cannot fill the memory.
+func (ag *agent) reassemble(msg *message.UserMessage, status SignatureStatus) ([]byte, SignatureStatus, bool) {
+	max := ag.config().ChunkBufferSize
+	index, count := int(msg.GetChunkIndex()), int(msg.GetChunkCount())
+	size := len(msg.GetPayload())
+	if count > maxChunks || index >= count || size == 0 || partialCost(count)+size > max {
+		ag.sampledLogger.Warningf("Agent.reassemble(): Drop the chunk %d/%d of %d bytes from %d\n", index, count, len(msg.GetPayload()), msg.GetId())
+		atomic.AddUint64(&ag.stats.chunksDropped, 1)
+		return nil, status, false
+	}
+
+	c := ag.chunks
+	c.Lock()
+	defer c.Unlock()
+
+	key := chunkKey{msg.GetId(), msg.GetChunkId()}
+	p, ok := c.partial[key]
+	if ok {
+		if len(p.parts) != count || p.parts[index] != nil {
+			return nil, status, false
+		}
+		if !bytes.Equal(p.hash, msg.GetChunkHash()) {
+			ag.sampledLogger.Warningf("Agent.reassemble(): Drop the chunk %d/%d of the payload %d from %d, its hash differs\n", index, count, key.id, key.sender)
+			atomic.AddUint64(&ag.stats.chunksDropped, 1)
+			return nil, status, false
+		}
+	}
+
+	// Make room for the chunk, and the new payload, by dropping the
+	// oldest payloads, of the sender if it has too many of them.
+	need := size
+	if !ok {
+		need += partialCost(count)
+	}
+	for len(c.partial) > 0 {
+		bySender := !ok && c.senders[key.sender] >= maxSenderPartials
+		if !bySender && (ok || len(c.partial) < maxPartials) && c.size+need <= max {
+			break
+		}
+		oldest := c.oldest(key.sender, bySender)
+		c.remove(oldest)
+		ag.sampledLogger.Warningf("Agent.reassemble(): Chunk buffer is full, drop the payload %d from %d\n", oldest.id, oldest.sender)
+		atomic.AddUint64(&ag.stats.chunksDropped, 1)
+		if oldest == key {
+			return nil, status, false
+		}
+	}
+
+	if !ok {
+		p = &partialPayload{parts: make([][]byte, count), overhead: partialCost(count), started: time.Now(), status: status, hash: msg.GetChunkHash()}
+		if timeout := ag.config().ChunkTimeout; timeout > 0 {
+			p.timer = time.AfterFunc(time.Duration(timeout)*time.Millisecond, func() { ag.expireChunks(key) })
+		}
+		c.partial[key] = p
+		c.senders[key.sender]++
+		c.size += p.overhead
+	}
+	p.parts[index] = msg.GetPayload()
+	p.received++
+	p.size += size
+	c.size += size
+	if status != Verified {
+		p.status = status
+	}
+	if p.received < count {
+		return nil, status, false
+	}
+
+	c.remove(key)
+	payload := make([]byte, 0, p.size)
+	for _, part := range p.parts {
+		payload = append(payload, part...)
+	}
+	if hash := sha256.Sum256(payload); p.hash != nil && !bytes.Equal(p.hash, hash[:]) {
+		ag.sampledLogger.Warningf("Agent.reassemble(): Drop the payload %d from %d, its hash differs\n", key.id, key.sender)
+		atomic.AddUint64(&ag.stats.chunksDropped, 1)
+		return nil, status, false
+	}
+	return payload, p.status, true
+}
+
+// expireChunks() drops the payload that is not complete in time.
+func (ag *agent) expireChunks(key chunkKey) {
+	ag.chunks.Lock()
+	p := ag.chunks.remove(key)
+	ag.chunks.Unlock()
+	if p != nil {
+		ag.sampledLogger.Warningf("Agent.expireChunks(): Payload %d from %d is not complete in time, %d/%d chunks\n", key.id, key.sender, p.received, len(p.parts))
+		atomic.AddUint64(&ag.stats.chunksDropped, 1)
+	}
+}
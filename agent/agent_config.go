@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"github.com/lilymona/gog/config"
+	"github.com/lilymona/gog/node"
+)
+
+// config() returns the current configuration.
+func (ag *agent) config() *config.Config {
+	return ag.cfg.Load().(*config.Config)
+}
+
+// Config returns the current configuration, which must not be modified.
+func (ag *agent) Config() *config.Config {
+	return ag.config()
+}
+
+// Reconfigure copies the tunables of the configuration, e.g. the view
+// sizes, the message life, the intervals and the send rates, onto the
+// current one, and replaces it with the copy at once, so every reader
+// sees either the old or the new configuration. They take effect from
+// then on, and the views are trimmed to their new sizes. The others,
+// e.g. the addresses, the TLS and the transport, are only read when the
+// agent starts, so they are kept, as are the peers added since.
+func (ag *agent) Reconfigure(cfg *config.Config) error {
+	ag.cfgMu.Lock()
+	c, err := ag.config().WithTunables(cfg)
+	if err == nil {
+		err = c.Validate()
+	}
+	if err != nil {
+		ag.cfgMu.Unlock()
+		return err
+	}
+	ag.cfg.Store(c)
+	ag.cfgMu.Unlock()
+	ag.logger.Infof("Agent.Reconfigure(): Configuration is reloaded\n")
+	ag.limiter.setRates(c)
+	ag.trimViews()
+	return nil
+}
+
+// addPeers() appends the peers to the peer list of a copy of the
+// configuration, and replaces the current one with it, as the list
+// is read concurrently, e.g. by the rejoins.
+func (ag *agent) addPeers(peers ...string) {
+	ag.cfgMu.Lock()
+	defer ag.cfgMu.Unlock()
+	c := *ag.config()
+	c.Peers = append([]string(nil), c.Peers...)
+	c.AddPeers(peers...)
+	ag.cfg.Store(&c)
+}
+
+// trimViews() moves the random nodes exceeding the maximum size of the
+// active view to the passive view, and drops the random nodes exceeding
+// the size of the passive view.
+func (ag *agent) trimViews() {
+	cfg := ag.config()
+	var dropped []*node.Node
+
+	ag.viewMu.Lock()
+	for ag.aView.Len() > cfg.AViewMaxSize {
+		nd := chooseRandomNode(ag.rng, ag.aView, 0)
+		ag.aView.Remove(nd.Id)
+		ag.neighborDown(nd)
+		ag.addNodePassiveView(nd)
+		dropped = append(dropped, nd)
+	}
+	for ag.pView.Len() > cfg.PViewSize {
+		nd := chooseRandomNode(ag.rng, ag.pView, 0)
+		ag.pView.Remove(nd.Id)
+	}
+	ag.viewMu.Unlock()
+
+	for _, nd := range dropped {
+		ag.disconnect(nd)
+	}
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// DebugInfo is the internal state of the agent, to diagnose
+// an agent that is stuck or leaks memory.
+type DebugInfo struct {
+	// The number of the goroutines of the process.
+	Goroutines int `json:"goroutines"`
+	// The sizes of the views.
+	ActiveView  int `json:"active_view"`
+	PassiveView int `json:"passive_view"`
+	// The connections of the neighbors in the active view.
+	Neighbors []NeighborInfo `json:"neighbors"`
+	// The number of the user messages queued to each neighbor,
+	// keyed by the node id, for the neighbors with a send loop.
+	SendQueues map[uint64]int `json:"send_queues"`
+	// The number of the hashes in the message cache, including
+	// the expired ones that are not purged yet.
+	MessageCache int `json:"message_cache"`
+	// The number of the failed messages buffered to resend.
+	FailedMessages int `json:"failed_messages"`
+	// The number of the messages waiting for the acks.
+	PendingAcks int `json:"pending_acks"`
+	// The number of the chunked payloads being reassembled,
+	// and the size in bytes of their chunks.
+	PartialPayloads int `json:"partial_payloads"`
+	ChunkBuffer     int `json:"chunk_buffer"`
+	// The number of the idle connections in the pool.
+	IdleConns int `json:"idle_conns"`
+	// The numbers of the accepted connections waiting for a handler,
+	// of the connections being handled, and of the user callbacks
+	// being run.
+	QueuedConns   int32 `json:"queued_conns"`
+	HandlingConns int32 `json:"handling_conns"`
+	Handlers      int32 `json:"handlers"`
+}
+
+// NeighborInfo is the state of the connection of a neighbor.
+type NeighborInfo struct {
+	ID    uint64 `json:"id"`
+	State string `json:"state"`
+	// The times of the last read from the neighbor and of the
+	// last write to it, zero if there has been none.
+	LastSeen time.Time `json:"last_seen"`
+	LastSent time.Time `json:"last_sent"`
+	// The number of the failed dials and writes.
+	Failures uint64 `json:"failures"`
+}
+
+// Debug returns the internal state of the agent.
+func (ag *agent) Debug() *DebugInfo {
+	d := &DebugInfo{
+		Goroutines:     runtime.NumGoroutine(),
+		Neighbors:      []NeighborInfo{},
+		SendQueues:     make(map[uint64]int),
+		MessageCache:   ag.msgCache.len(),
+		FailedMessages: ag.failmsgBuffer.Len(),
+		IdleConns:      ag.pool.len(),
+		QueuedConns:    atomic.LoadInt32(&ag.stats.queuedConns),
+		HandlingConns:  atomic.LoadInt32(&ag.stats.handlingConns),
+		Handlers:       atomic.LoadInt32(&ag.stats.handlers),
+	}
+
+	ag.viewMu.RLock()
+	d.ActiveView = ag.aView.Len()
+	d.PassiveView = ag.pView.Len()
+	for _, nd := range ag.aView.Values() {
+		d.Neighbors = append(d.Neighbors, NeighborInfo{
+			ID:       nd.Id,
+			State:    nd.State().String(),
+			LastSeen: nd.LastSeen(),
+			LastSent: nd.LastSent(),
+			Failures: nd.Failures(),
+		})
+	}
+	ag.viewMu.RUnlock()
+
+	ag.sendq.mu.Lock()
+	for nd, q := range ag.sendq.queues {
+		d.SendQueues[nd.Id] += len(q.control) + len(q.user)
+	}
+	ag.sendq.mu.Unlock()
+
+	ag.reliable.Lock()
+	d.PendingAcks = len(ag.reliable.pending)
+	ag.reliable.Unlock()
+
+	ag.chunks.Lock()
+	d.PartialPayloads = len(ag.chunks.partial)
+	d.ChunkBuffer = ag.chunks.size
+	ag.chunks.Unlock()
+	return d
+}
@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"sync"
+)
+
+// dispatcher runs the calls of each source in order, while the calls
+// of different sources run concurrently.
+type dispatcher struct {
+	mu sync.Mutex
+	// The pending calls of each source. A source is in the
+	// map as long as a goroutine is running its calls.
+	queues map[uint64][]func()
+}
+
+func newDispatcher() *dispatcher {
+	return &dispatcher{queues: make(map[uint64][]func())}
+}
+
+// dispatch() queues the call after the pending calls of the source.
+func (d *dispatcher) dispatch(source uint64, f func()) {
+	d.mu.Lock()
+	q, running := d.queues[source]
+	d.queues[source] = append(q, f)
+	d.mu.Unlock()
+
+	if !running {
+		go d.run(source)
+	}
+}
+
+// run() runs the calls of the source until there is none left.
+func (d *dispatcher) run(source uint64) {
+	for {
+		d.mu.Lock()
+		q := d.queues[source]
+		if len(q) == 0 {
+			delete(d.queues, source)
+			d.mu.Unlock()
+			return
+		}
+		f := q[0]
+		d.queues[source] = q[1:]
+		d.mu.Unlock()
+
+		f()
+	}
+}
@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"github.com/lilymona/gog/node"
+)
+
+// NeighborHandler is the callback of the changes of the active view.
+type NeighborHandler func(id uint64, addr string)
+
+// RegisterNeighborUp registers a user provided callback, which is
+// invoked when a node is added to the active view.
+func (ag *agent) RegisterNeighborUp(h NeighborHandler) {
+	ag.upHandler = h
+}
+
+// RegisterNeighborDown registers a user provided callback, which is
+// invoked when a node is removed from the active view.
+func (ag *agent) RegisterNeighborDown(h NeighborHandler) {
+	ag.downHandler = h
+}
+
+// neighborUp() queues the NeighborUp event of the node.
+func (ag *agent) neighborUp(nd *node.Node) {
+	ag.notify(ag.upHandler, nd)
+}
+
+// neighborDown() queues the NeighborDown event of the node.
+func (ag *agent) neighborDown(nd *node.Node) {
+	ag.plumtree.remove(nd.Id)
+	ag.limiter.remove(nd.Id)
+	ag.notify(ag.downHandler, nd)
+}
+
+// notify() queues the event. The events are called in order in another
+// goroutine, as the view lock is usually held here, and the callbacks
+// might call back into the agent.
+func (ag *agent) notify(h NeighborHandler, nd *node.Node) {
+	if h == nil {
+		return
+	}
+	id, addr := nd.Id, nd.Addr
+	ag.events.dispatch(0, func() { h(id, addr) })
+}
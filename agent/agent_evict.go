@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lilymona/gog/node"
+)
+
+var ErrNodeNotFound = errors.New("Node not found")
+
+// Evict removes the node from the views, and disconnects it if it is a
+// neighbor, which is replaced by a passive node in the background. The
+// node is banned for BanDuration seconds, so it is refused when it joins
+// or becomes a neighbor again, and not learned from the shuffles and the
+// forward joins.
+func (ag *agent) Evict(id uint64) error {
+	ag.viewMu.Lock()
+	var nd *node.Node
+	if ag.aView.Has(id) {
+		nd = ag.aView.GetValueOf(id)
+		ag.aView.Remove(id)
+		ag.neighborDown(nd)
+	}
+	found := ag.pView.Remove(id) || nd != nil
+	if found && ag.config().BanDuration > 0 {
+		ag.ban(id, time.Now().Add(time.Duration(ag.config().BanDuration)*time.Second))
+	}
+	ag.viewMu.Unlock()
+	if !found {
+		return ErrNodeNotFound
+	}
+
+	ag.logger.Infof("Agent.Evict(): Evicted node %d\n", id)
+	if nd != nil {
+		ag.disconnect(nd)
+		go ag.refillActiveView(nil)
+	}
+	return nil
+}
+
+// ban() bans the node until the time, and forgets the expired bans.
+// It must be called with viewMu held.
+func (ag *agent) ban(id uint64, until time.Time) {
+	now := time.Now()
+	for id, t := range ag.bans {
+		if !now.Before(t) {
+			delete(ag.bans, id)
+		}
+	}
+	ag.bans[id] = until
+}
+
+// banned() returns true if the node is banned.
+// It must be called with viewMu held.
+func (ag *agent) banned(id uint64) bool {
+	until, ok := ag.bans[id]
+	return ok && time.Now().Before(until)
+}
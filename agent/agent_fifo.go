@@ -0,0 +1,174 @@
+package agent
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// fifo is the state of delivering the user messages of each sender in the
+// order of their sequence numbers. The messages arriving before the ones
+// preceding them are buffered, until the missing ones arrive, the buffer
+// of the sender is full, or the gap is not filled in time, in which case
+// the missing ones are skipped, and dropped if they arrive later.
+type fifo struct {
+	sync.Mutex
+	// The queues of the senders, keyed by the node id and the epoch,
+	// as a restarted sender starts its sequence over.
+	senders map[fifoKey]*fifoQueue
+}
+
+// fifoKey is the sender of a sequence, see UserMessage.origin_epoch.
+type fifoKey struct {
+	sender uint64
+	epoch  uint64
+}
+
+// fifoQueue is the buffered messages of a sender.
+type fifoQueue struct {
+	// The sequence number of the next message to deliver.
+	next uint64
+	// The deliveries of the buffered messages, keyed by the
+	// sequence number, nil for the ones not delivered, e.g. the chunks.
+	pending map[uint64]func()
+	// The timer to skip the gap before the buffered messages.
+	timer *time.Timer
+	// The latest deadline in nanoseconds of the messages
+	// accepted, see messageDeadline().
+	deadline int64
+}
+
+func newFIFO() *fifo {
+	return &fifo{senders: make(map[fifoKey]*fifoQueue)}
+}
+
+// deliverInOrder() delivers the message of the sequence number from the
+// sender after the ones before it. The delivery is nil for the messages
+// that only advance the sequence, e.g. the chunks. The first message of
+// a sender in an epoch starts its sequence. The queue is kept until the
+// deadline of the message at least, unless the message is late.
+func (ag *agent) deliverInOrder(sender, epoch, seq uint64, deliver func(), deadline int64) {
+	f := ag.fifo
+	f.Lock()
+	defer f.Unlock()
+	key := fifoKey{sender: sender, epoch: epoch}
+	q, ok := f.senders[key]
+	if !ok {
+		q = &fifoQueue{next: seq, pending: make(map[uint64]func())}
+		f.senders[key] = q
+	}
+
+	if seq < q.next {
+		ag.sampledLogger.Warningf("Agent.deliverInOrder(): Drop the message %d from %d, arrived after it is skipped\n", seq, sender)
+		atomic.AddUint64(&ag.stats.fifoLate, 1)
+		if deliver != nil {
+			atomic.AddInt32(&ag.stats.handlers, -1)
+		}
+		return
+	}
+	if deadline > q.deadline {
+		q.deadline = deadline
+	}
+	if seq != q.next {
+		atomic.AddUint64(&ag.stats.fifoBuffered, 1)
+	}
+	q.pending[seq] = deliver
+
+	// Skip the gap if the window of the sender is full.
+	to := q.next
+	if window := uint64(ag.config().FIFOWindow); seq-q.next >= window {
+		to = seq - window + 1
+	}
+	ag.advance(key, q, to)
+}
+
+// advance() delivers the buffered messages of the sender before the
+// sequence number, skipping the missing ones, and then the consecutive
+// ones from there. The gap timer is started for the messages left
+// buffered. The lock of the fifo must be held.
+func (ag *agent) advance(key fifoKey, q *fifoQueue, to uint64) {
+	sender := key.sender
+	next := q.next
+	if to > q.next {
+		var seqs []uint64
+		for s := range q.pending {
+			if s < to {
+				seqs = append(seqs, s)
+			}
+		}
+		sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+		for _, s := range seqs {
+			ag.deliverNext(sender, q.pending[s])
+			delete(q.pending, s)
+		}
+		skipped := to - q.next - uint64(len(seqs))
+		ag.sampledLogger.Warningf("Agent.advance(): Skip %d messages from %d before %d\n", skipped, sender, to)
+		atomic.AddUint64(&ag.stats.fifoSkipped, skipped)
+		q.next = to
+	}
+	for {
+		deliver, ok := q.pending[q.next]
+		if !ok {
+			break
+		}
+		ag.deliverNext(sender, deliver)
+		delete(q.pending, q.next)
+		q.next++
+	}
+
+	// The gap timer restarts when the sequence advances.
+	if q.timer != nil && (q.next != next || len(q.pending) == 0) {
+		q.timer.Stop()
+		q.timer = nil
+	}
+	timeout := time.Duration(ag.config().FIFOGapTimeout) * time.Millisecond
+	if q.timer == nil && len(q.pending) > 0 && timeout > 0 {
+		var t *time.Timer
+		t = time.AfterFunc(timeout, func() { ag.skipGap(key, q, t) })
+		q.timer = t
+	}
+}
+
+// skipGap() delivers the buffered messages of the sender from the lowest
+// sequence number, skipping the missing ones before it, as they are not
+// received before the timer fired.
+func (ag *agent) skipGap(key fifoKey, q *fifoQueue, t *time.Timer) {
+	f := ag.fifo
+	f.Lock()
+	defer f.Unlock()
+	if ag.stopped() || f.senders[key] != q || q.timer != t {
+		return
+	}
+	q.timer = nil
+	to := uint64(0)
+	for s := range q.pending {
+		if to == 0 || s < to {
+			to = s
+		}
+	}
+	if to != 0 {
+		ag.advance(key, q, to)
+	}
+}
+
+// deliverNext() invokes the handler with the message of the sender after
+// the previous ones, which the dispatcher runs in order.
+func (ag *agent) deliverNext(sender uint64, deliver func()) {
+	if deliver != nil {
+		ag.dispatcher.dispatch(sender, deliver)
+	}
+}
+
+// purgeFIFO() forgets the senders with no message buffered,
+// whose messages accepted are all dead before now.
+func (ag *agent) purgeFIFO(now int64) {
+	f := ag.fifo
+	f.Lock()
+	defer f.Unlock()
+	for key, q := range f.senders {
+		if len(q.pending) == 0 && q.deadline < now {
+			delete(f.senders, key)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package agent
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/lilymona/gog/config"
+)
+
+// The version of the protocol spoken by the agent, and the oldest
+// version it interoperates with. The version is bumped on the protocol
+// changes that the older agents cannot handle.
+const (
+	protocolVersion    = 1
+	minProtocolVersion = 1
+)
+
+// protocolMagic starts the preamble, so a peer that is not an agent,
+// or an agent that predates the handshake, is told apart clearly.
+var protocolMagic = [3]byte{'G', 'O', 'G'}
+
+// The size of the preamble: the magic, the version, the codec,
+// and the capabilities in big-endian.
+const preambleSize = len(protocolMagic) + 1 + 1 + 4
+
+// The codecs in the preamble, the agents must use the same codec.
+var codecIDs = map[string]byte{
+	config.CodecProtobuf: 0,
+	config.CodecJSON:     1,
+	config.CodecMsgpack:  2,
+}
+
+var (
+	ErrIncompatibleProtocol = errors.New("Incompatible protocol version")
+	ErrIncompatibleCodec    = errors.New("Incompatible codec")
+	ErrUnauthenticated      = errors.New("Unauthenticated peer")
+)
+
+// preamble is the first frame written by both ends of a connection,
+// before any message. The capabilities are those of the nodes, see
+// localCaps(), so they are known from the start of the connection,
+// and those of the connection itself, e.g. capAuth, which are
+// negotiated per connection. The Caps of Join and Neighbor, and of
+// their replies, still override the former.
+type preamble struct {
+	version uint8
+	codec   uint8
+	caps    uint32
+}
+
+// marshal() encodes the preamble.
+func (p *preamble) marshal() []byte {
+	b := make([]byte, 0, preambleSize)
+	b = append(b, protocolMagic[:]...)
+	b = append(b, p.version, p.codec)
+	return binary.BigEndian.AppendUint32(b, p.caps)
+}
+
+// readPreamble() reads the preamble of the peer.
+func readPreamble(r io.Reader) (*preamble, error) {
+	b := make([]byte, preambleSize)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	n := len(protocolMagic)
+	if [3]byte(b[:n]) != protocolMagic {
+		return nil, fmt.Errorf("%w: no handshake from the peer, which might predate version %d", ErrIncompatibleProtocol, minProtocolVersion)
+	}
+	return &preamble{
+		version: b[n],
+		codec:   b[n+1],
+		caps:    binary.BigEndian.Uint32(b[n+2:]),
+	}, nil
+}
+
+// capAuth is set in the preamble by the agents with a cluster secret,
+// which authenticate each other right after the preambles, see
+// challenge(). The agents without it cannot connect to them. It is
+// the highest bit, apart from the capabilities of the nodes.
+const capAuth uint32 = 1 << 31
+
+// handshakeTimeout is the time for the peer to complete the handshake,
+// whatever the read timeout, so a silent peer is given up. It is a
+// variable to be replaced in tests.
+var handshakeTimeout = 10 * time.Second
+
+// localPreamble() returns the preamble of the agent.
+func (ag *agent) localPreamble() *preamble {
+	p := &preamble{
+		version: protocolVersion,
+		codec:   codecIDs[ag.config().Codec],
+		caps:    ag.localCaps(),
+	}
+	if ag.config().ClusterSecret != "" {
+		p.caps |= capAuth
+	}
+	return p
+}
+
+// handshake() exchanges the preambles on a new connection, and returns the
+// connection with the capabilities of the peer, see peerCaps(), or an error
+// if the peer is incompatible, or does not complete it in time. The dialer
+// writes first, while the other end reads first, and replies even if the
+// peer is incompatible, so both ends can tell why the connection fails.
+// Then both ends authenticate each other if they have a cluster secret,
+// so no message is read from, or written to, an unauthenticated peer.
+func (ag *agent) handshake(conn net.Conn, dialer bool) (net.Conn, error) {
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	local := ag.localPreamble()
+	if dialer {
+		if _, err := conn.Write(local.marshal()); err != nil {
+			return nil, err
+		}
+	}
+	peer, err := readPreamble(conn)
+	if !dialer {
+		if _, werr := conn.Write(local.marshal()); err == nil && werr != nil {
+			return nil, werr
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case peer.version < minProtocolVersion:
+		return nil, fmt.Errorf("%w: %d < %d", ErrIncompatibleProtocol, peer.version, minProtocolVersion)
+	case peer.codec != local.codec:
+		return nil, fmt.Errorf("%w: %d != %d", ErrIncompatibleCodec, peer.codec, local.codec)
+	case peer.caps&capAuth != local.caps&capAuth:
+		return nil, ag.authFailed(conn)
+	}
+	if local.caps&capAuth != 0 {
+		if err := ag.challenge(conn, dialer); err != nil {
+			return nil, err
+		}
+	}
+	return &peerConn{Conn: conn, caps: peer.caps &^ capAuth}, nil
+}
+
+// peerConn is a connection after the handshake, with the
+// capabilities of the nodes in the preamble of the peer.
+type peerConn struct {
+	net.Conn
+	caps uint32
+}
+
+// peerCaps() returns the capabilities in the preamble
+// of the peer of the connection, 0 if it is unknown.
+func peerCaps(conn net.Conn) uint32 {
+	if pc, ok := conn.(*peerConn); ok {
+		return pc.caps
+	}
+	return 0
+}
+
+// nodeCaps() returns the capabilities in a Join or Neighbor message,
+// or in their replies, or those in the preamble of the connection of
+// the node if the message leaves them out.
+func nodeCaps(caps *uint32, conn net.Conn) uint32 {
+	if caps != nil {
+		return *caps
+	}
+	return peerCaps(conn)
+}
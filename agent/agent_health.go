@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Health is the status of the agent in the overlay.
+type Health struct {
+	// Listening is true if the agent accepts connections.
+	Listening bool `json:"listening"`
+	// The size of the active view, and its minimum size.
+	ActiveView    int `json:"active_view"`
+	ActiveViewMin int `json:"active_view_min"`
+	// The milliseconds since the last shuffle reply, and since the
+	// last user message delivered, -1 if there has been none.
+	SinceShuffle  int64 `json:"since_shuffle_ms"`
+	SinceDelivery int64 `json:"since_delivery_ms"`
+}
+
+// Degraded returns true if the agent does not accept connections,
+// or the active view is smaller than the minimum size.
+func (h *Health) Degraded() bool {
+	return !h.Listening || h.ActiveView < h.ActiveViewMin
+}
+
+// Ready returns true if the agent accepts connections,
+// and has joined the overlay.
+func (h *Health) Ready() bool {
+	return h.Listening && h.ActiveView > 0
+}
+
+// Health returns the status of the agent in the overlay.
+func (ag *agent) Health() *Health {
+	ag.lnMu.Lock()
+	listening := len(ag.lns) > 0 && !ag.stopped()
+	ag.lnMu.Unlock()
+
+	ag.viewMu.RLock()
+	active := ag.aView.Len()
+	ag.viewMu.RUnlock()
+
+	now := time.Now()
+	return &Health{
+		Listening:     listening,
+		ActiveView:    active,
+		ActiveViewMin: ag.config().AViewMinSize,
+		SinceShuffle:  since(now, atomic.LoadInt64(&ag.stats.lastShuffle)),
+		SinceDelivery: since(now, atomic.LoadInt64(&ag.stats.lastDelivery)),
+	}
+}
+
+// since() returns the milliseconds since the time in
+// unix nanoseconds, or -1 if the time is zero.
+func since(now time.Time, t int64) int64 {
+	if t == 0 {
+		return -1
+	}
+	return int64(now.Sub(time.Unix(0, t)) / time.Millisecond)
+}
@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"sync/atomic"
+
+	"github.com/lilymona/gog/config"
+	"github.com/lilymona/gog/node"
+)
+
+// nodeID() returns the id of the agent advertising the address. It is
+// the id of the config if set, otherwise it is got by the strategy of
+// the config, and a random one if the strategy fails.
+func nodeID(cfg *config.Config, addr string) uint64 {
+	if cfg.ID != 0 {
+		return cfg.ID
+	}
+	switch cfg.IDStrategy {
+	case config.IDAddr:
+		return addrID(addr)
+	case config.IDFile:
+		id, err := config.LoadNodeID(cfg.IDFile)
+		if err == nil {
+			return id
+		}
+		newLogger(cfg).Errorf("Cannot load node id, use a random one: %v\n", err)
+	}
+	return config.RandomNodeID()
+}
+
+// addrID() derives a non-zero id of 63 bits from the hash of the address.
+func addrID(addr string) uint64 {
+	h := sha1.Sum([]byte(addr))
+	if id := binary.BigEndian.Uint64(h[:]) &^ (1 << 63); id != 0 {
+		return id
+	}
+	return 1
+}
+
+// collides() returns true if the node has the id of the agent, or of
+// a neighbor, with a different address, which is logged and counted,
+// as two agents got the same id. The view lock must be held.
+func (ag *agent) collides(nd *node.Node) bool {
+	addr := ag.addr
+	if nd.Id != ag.id {
+		if !ag.aView.Has(nd.Id) {
+			return false
+		}
+		addr = ag.aView.GetValueOf(nd.Id).Addr
+	}
+	if addr == nd.Addr {
+		return false
+	}
+	ag.sampledLogger.Errorf("Agent.collides(): Node %s has the id %d of node %s\n", nd.Addr, nd.Id, addr)
+	atomic.AddUint64(&ag.stats.idCollisions, 1)
+	return true
+}
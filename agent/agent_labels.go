@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"github.com/lilymona/gog/arraymap"
+	"github.com/lilymona/gog/message"
+	"github.com/lilymona/gog/node"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// NodesWithLabel returns the nodes in the views that have the label.
+// They are copies of the id, the address and the labels, without the
+// connections, so the caller can keep and change them.
+func (ag *agent) NodesWithLabel(key, value string) []*node.Node {
+	ag.viewMu.RLock()
+	defer ag.viewMu.RUnlock()
+
+	var nodes []*node.Node
+	for _, view := range []*arraymap.OrderedMap[uint64, *node.Node]{ag.aView, ag.pView} {
+		for _, nd := range view.Values() {
+			if l, ok := nd.Labels[key]; ok && l == value {
+				nodes = append(nodes, &node.Node{
+					Id:     nd.Id,
+					Addr:   nd.Addr,
+					Labels: copyLabels(nd.Labels),
+				})
+			}
+		}
+	}
+	return nodes
+}
+
+// SetLabels replaces the labels of the agent. The peers learn them from
+// the later joins, neighbor requests and shuffles of the agent.
+func (ag *agent) SetLabels(labels map[string]string) {
+	labels = copyLabels(labels)
+	ag.labelsMu.Lock()
+	defer ag.labelsMu.Unlock()
+	ag.labels = labels
+}
+
+// encodedLabels() returns the labels of the agent as the messages.
+func (ag *agent) encodedLabels() []*message.Label {
+	ag.labelsMu.RLock()
+	defer ag.labelsMu.RUnlock()
+	return encodeLabels(ag.labels)
+}
+
+// copyLabels() returns a copy of the labels, so they
+// are not changed by the caller afterwards.
+func copyLabels(labels map[string]string) map[string]string {
+	if labels == nil {
+		return nil
+	}
+	c := make(map[string]string, len(labels))
+	for k, v := range labels {
+		c[k] = v
+	}
+	return c
+}
+
+// encodeLabels() converts the labels to the messages.
+func encodeLabels(labels map[string]string) []*message.Label {
+	if len(labels) == 0 {
+		return nil
+	}
+	msgs := make([]*message.Label, 0, len(labels))
+	for k, v := range labels {
+		msgs = append(msgs, &message.Label{
+			Key:   proto.String(k),
+			Value: proto.String(v),
+		})
+	}
+	return msgs
+}
+
+// decodeLabels() converts the messages to the labels.
+func decodeLabels(msgs []*message.Label) map[string]string {
+	if len(msgs) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(msgs))
+	for _, m := range msgs {
+		labels[m.GetKey()] = m.GetValue()
+	}
+	return labels
+}
@@ -1,9 +1,13 @@
 package agent
 
 import (
+	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	log "github.com/lilymona/gog/logging"
+	"github.com/lilymona/gog/codec"
 	"github.com/lilymona/gog/message"
 	"github.com/lilymona/gog/node"
 
@@ -11,52 +15,111 @@ import (
 )
 
 var (
-	ErrInvalidMessageType = errors.New("Invalid message type")
-	ErrNoAvailablePeers   = errors.New("No available peers")
+	ErrInvalidMessageType      = errors.New("Invalid message type")
+	ErrNoAvailablePeers        = errors.New("No available peers")
+	ErrRequestTimeout          = errors.New("Request timeout")
+	ErrNoReply                 = errors.New("No reply")
+	ErrSocketOptionUnsupported = errors.New("Socket option not supported")
+	ErrSelfConnect             = errors.New("Cannot connect to self")
+	ErrAgentClosed             = errors.New("Agent is closed")
 )
 
-// disconnect() sends a Disconnect message to the node and close the connection.
+// caps are the capabilities advertised to the peers.
+const caps = node.CapCompress | node.CapPing | node.CapBatch | node.CapDigest | node.CapSettings
+
+// disconnect() sends a Disconnect message to the node ahead of the queued
+// user messages, and close the connection once it is written.
 // TODO(yifan): cache the connection.
 func (ag *agent) disconnect(node *node.Node) {
 	msg := &message.Disconnect{Id: proto.Uint64(ag.id)}
-	ag.codec.WriteMsg(msg, node.Conn) // TODO record err log.
-	node.Conn.Close()
+	<-ag.controlMessage(node, msg)
+	node.Close()
+}
+
+// disconnectAll() disconnects the nodes in the active view concurrently,
+// outside the view lock, so a slow neighbor neither delays the others nor
+// holds the view changes. It returns the context error if the context is
+// done before the Disconnect messages are written.
+func (ag *agent) disconnectAll(ctx context.Context) error {
+	ag.viewMu.RLock()
+	nodes := ag.aView.Snapshot()
+	ag.viewMu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, nd := range nodes {
+		wg.Add(1)
+		go func(nd *node.Node) {
+			defer wg.Done()
+			ag.disconnect(nd)
+		}(nd)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // forwardJoin() sends a ForwardJoin message to the node. The message
 // will include the Id and Addr of the source node, as the receiver might
 // use these information to establish a connection.
 func (ag *agent) forwardJoin(node, newNode *node.Node, ttl uint32) {
-	msg := &message.ForwardJoin{
-		Id:         proto.Uint64(ag.id),
-		SourceId:   proto.Uint64(newNode.Id),
-		SourceAddr: proto.String(newNode.Addr),
-		Ttl:        proto.Uint32(ttl),
+	// Do not flood the links when lots of nodes are joining,
+	// the dropped nodes will still be learned by shuffles.
+	if !ag.fjThrottle.allow() {
+		ag.sampledLogger.Warningf("Agent.forwardJoin(): Throttled, drop the forward join of %s\n", newNode.Addr)
+		return
 	}
-	if err := ag.codec.WriteMsg(msg, node.Conn); err != nil {
-		node.Conn.Close()
+	msg := &message.ForwardJoin{
+		Id:           proto.Uint64(ag.id),
+		SourceId:     proto.Uint64(newNode.Id),
+		SourceAddr:   proto.String(newNode.Addr),
+		Ttl:          proto.Uint32(ttl),
+		SourceLabels: encodeLabels(newNode.Labels),
 	}
+	ag.controlMessage(node, msg)
 }
 
-// join() sends a Join message, and wait for the reply.
+// joinTimeout is the time to wait for the reply of a Join,
+// replaced in tests.
+var joinTimeout = 10 * time.Second
+
+// join() sends a Join message, and wait for the reply
+// for at most joinTimeout.
 func (ag *agent) join(node *node.Node) (bool, error) {
 	msg := &message.Join{
-		Id:   proto.Uint64(ag.id),
-		Addr: proto.String(ag.cfg.AddrStr),
+		Id:      proto.Uint64(ag.id),
+		Addr:    proto.String(ag.addr),
+		Labels:  ag.encodedLabels(),
+		Caps:    proto.Uint32(ag.localCaps()),
+		Cluster: ag.cluster(),
 	}
-	if err := ag.codec.WriteMsg(msg, node.Conn); err != nil {
+	if ag.config().Observe {
+		msg.Observe = proto.Bool(true)
+	}
+	if err := ag.codec.WriteMsg(msg, node); err != nil {
 		return false, err
 	}
-	recvMsg, err := ag.codec.ReadMsg(node.Conn)
+	node.SetReadDeadline(time.Now().Add(joinTimeout))
+	recvMsg, err := ag.codec.ReadMsg(node)
 	if err != nil {
 		// TODO(yifan) log.
 		return false, err
 	}
+	node.SetReadDeadline(time.Time{})
 	reply, ok := recvMsg.(*message.JoinReply)
 	if !ok {
 		return false, ErrInvalidMessageType
 	}
 	node.Id = reply.GetId()
+	node.Labels = decodeLabels(reply.GetLabels())
+	node.Caps = nodeCaps(reply.Caps, node.Conn())
 	return reply.GetAccept(), nil
 }
 
@@ -65,22 +128,27 @@ func (ag *agent) replyJoin(node *node.Node, accept bool) error {
 	msg := &message.JoinReply{
 		Id:     proto.Uint64(ag.id),
 		Accept: proto.Bool(accept),
+		Labels: ag.encodedLabels(),
+		Caps:   proto.Uint32(ag.localCaps()),
 	}
-	return ag.codec.WriteMsg(msg, node.Conn)
+	return ag.codec.WriteMsg(msg, node)
 }
 
 // neighbor() sends a Neighbor message, and wait for the reply.
 func (ag *agent) neighbor(node *node.Node, priority message.Neighbor_Priority) (bool, error) {
 	msg := &message.Neighbor{
 		Id:       proto.Uint64(ag.id),
-		Addr:     proto.String(ag.cfg.AddrStr),
+		Addr:     proto.String(ag.addr),
 		Priority: priority.Enum(),
+		Labels:   ag.encodedLabels(),
+		Caps:     proto.Uint32(ag.localCaps()),
+		Cluster:  ag.cluster(),
 	}
-	if err := ag.codec.WriteMsg(msg, node.Conn); err != nil {
+	if err := ag.codec.WriteMsg(msg, node); err != nil {
 		// TODO(yifan) log.
 		return false, err
 	}
-	recvMsg, err := ag.codec.ReadMsg(node.Conn)
+	recvMsg, err := ag.codec.ReadMsg(node)
 	if err != nil {
 		// TODO(yifan) log.
 		return false, err
@@ -89,6 +157,8 @@ func (ag *agent) neighbor(node *node.Node, priority message.Neighbor_Priority) (
 	if !ok {
 		return false, ErrInvalidMessageType
 	}
+	node.Labels = decodeLabels(reply.GetLabels())
+	node.Caps = nodeCaps(reply.Caps, node.Conn())
 
 	return reply.GetAccept(), nil
 }
@@ -98,49 +168,129 @@ func (ag *agent) replyNeighbor(node *node.Node, accept bool) error {
 	msg := &message.NeighborReply{
 		Id:     proto.Uint64(ag.id),
 		Accept: proto.Bool(accept),
+		Labels: ag.encodedLabels(),
+		Caps:   proto.Uint32(ag.localCaps()),
 	}
-	return ag.codec.WriteMsg(msg, node.Conn)
+	return ag.codec.WriteMsg(msg, node)
 }
 
-// userMessage() sends a user message to the node.
-func (ag *agent) userMessage(node *node.Node, msg proto.Message) {
-	if err := ag.codec.WriteMsg(msg, node.Conn); err != nil {
-		log.Errorf("Agent.userMessage(): Write msg error: %v", err)
+// writeUserMessage() sends a user message to the node.
+func (ag *agent) writeUserMessage(node *node.Node, msg proto.Message) {
+	atomic.AddUint64(&ag.stats.payloadSends, 1)
+	var key pendingKey
+	if ag.config().Reliable {
+		msg, key = ag.sequence(node, msg.(*message.UserMessage))
+	}
+	if err := ag.codec.WriteMsg(msg, node); err != nil {
+		ag.logger.Errorf("Agent.writeUserMessage(): Write msg error: %v\n", err)
+		atomic.AddUint64(&ag.stats.failedSends, 1)
+		if ag.config().Reliable {
+			// It is resent from the failed message buffer.
+			ag.reliable.remove(key)
+		}
 		// Record this message, so we can resend it later.
-		umsg := msg.(*message.UserMessage)
-		hash := hashMessage(umsg.GetPayload())
+		ag.bufferFailedMessage(msg.(*message.UserMessage))
+
+		node.Close()
+	}
+}
 
-		ag.failmsgBuffer.Lock()
-		ag.failmsgBuffer.Append(hash, msg)
-		ag.failmsgBuffer.Unlock()
+// batchItemOverhead is the room kept for each message of a Batch beyond
+// its encoded size, for its field tag, its length and its sequence number.
+const batchItemOverhead = 32
 
-		node.Conn.Close()
+// writeBatches() sends the user messages to the node in the Batches whose
+// encoded size stays below the maximum size of the messages the peers read,
+// so a batch of large messages is split. A message left alone is sent by
+// itself.
+func (ag *agent) writeBatches(node *node.Node, msgs []proto.Message) {
+	max := ag.config().MaxMessageSize
+	if max <= 0 {
+		max = codec.DefaultMaxMessageSize
+	}
+	for len(msgs) > 0 {
+		n, size := 0, 1
+		for n < len(msgs) {
+			size += proto.Size(msgs[n]) + batchItemOverhead
+			if n > 0 && size >= max {
+				break
+			}
+			n++
+		}
+		if n == 1 {
+			ag.writeUserMessage(node, msgs[0])
+		} else {
+			ag.writeBatch(node, msgs[:n])
+		}
+		msgs = msgs[n:]
+	}
+}
+
+// writeBatch() sends the user messages to the node in a Batch.
+func (ag *agent) writeBatch(node *node.Node, msgs []proto.Message) {
+	atomic.AddUint64(&ag.stats.payloadSends, uint64(len(msgs)))
+	atomic.AddUint64(&ag.stats.batches, 1)
+	batch := &message.Batch{Messages: make([]*message.UserMessage, len(msgs))}
+	keys := make([]pendingKey, len(msgs))
+	for i, msg := range msgs {
+		umsg := msg.(*message.UserMessage)
+		if ag.config().Reliable {
+			umsg, keys[i] = ag.sequence(node, umsg)
+		}
+		batch.Messages[i] = umsg
+	}
+	if err := ag.codec.WriteMsg(batch, node); err != nil {
+		ag.logger.Errorf("Agent.writeBatch(): Write msg error: %v\n", err)
+		atomic.AddUint64(&ag.stats.failedSends, uint64(len(msgs)))
+		for i, umsg := range batch.Messages {
+			if ag.config().Reliable {
+				ag.reliable.remove(keys[i])
+			}
+			ag.bufferFailedMessage(umsg)
+		}
+		node.Close()
 	}
 }
 
 func (ag *agent) forwardShuffle(node *node.Node, msg *message.Shuffle) {
 	msg.Id = proto.Uint64(ag.id)
-	if err := ag.codec.WriteMsg(msg, node.Conn); err != nil {
-		node.Conn.Close()
-	}
+	ag.writeControl(msg, node)
 }
 
-func (ag *agent) shuffleReply(msg *message.Shuffle, candidates []*message.Candidate) error {
-	// TODO use existing tcp.
-	conn, err := ag.connect(msg.GetAddr())
-	if err != nil {
-		log.Errorf("Agent.shuffleReply(): Failed to connect %s: %v", msg.GetAddr(), err)
-		return err
-	}
-	defer conn.Close()
+// shuffleReply() sends the ShuffleReply message to the source of the
+// shuffle, in a datagram if both of them read the datagrams. Otherwise
+// it uses the connection of the source if the source is in the active
+// view, which is nil otherwise, and an idle connection from the pool
+// or a new one to the source address then.
+func (ag *agent) shuffleReply(source *node.Node, msg *message.Shuffle, candidates []*message.Candidate) error {
 	reply := &message.ShuffleReply{
 		Id:         proto.Uint64(ag.id),
 		Candidates: candidates,
+		Nonce:      msg.Nonce,
+	}
+	if source != nil {
+		ag.writeControl(reply, source)
+		return nil
+	}
+	if msg.GetUdp() && ag.packetConn() != nil {
+		err := ag.writePacketTo(reply, msg.GetAddr())
+		if err == nil {
+			return nil
+		}
+		ag.logger.Debugf("Agent.shuffleReply(): Reply to %s over TCP: %v\n", msg.GetAddr(), err)
+	}
+
+	conn, err := ag.dial(msg.GetAddr())
+	if err != nil {
+		ag.logger.Errorf("Agent.shuffleReply(): Failed to connect %s: %v\n", msg.GetAddr(), err)
+		return err
 	}
 	if err := ag.codec.WriteMsg(reply, conn); err != nil {
-		// TODO log
+		ag.logger.Errorf("Agent.shuffleReply(): Write msg error: %v\n", err)
+		conn.Close()
 		return err
 	}
+	ag.pool.put(msg.GetAddr(), conn)
 	return nil
 }
 
@@ -148,11 +298,19 @@ func (ag *agent) shuffle(node *node.Node, candidates []*message.Candidate) {
 	msg := &message.Shuffle{
 		Id:         proto.Uint64(ag.id),
 		SourceId:   proto.Uint64(ag.id),
-		Addr:       proto.String(ag.cfg.AddrStr),
+		Addr:       proto.String(ag.addr),
 		Candidates: candidates,
-		Ttl:        proto.Uint32(uint32(ag.cfg.SRWL)),
+		Ttl:        proto.Uint32(uint32(ag.config().SRWL)),
+		Cluster:    ag.cluster(),
 	}
-	if err := ag.codec.WriteMsg(msg, node.Conn); err != nil {
-		node.Conn.Close()
+	if ag.packetConn() != nil {
+		nonce, err := ag.shuffles.add(time.Now())
+		if err != nil {
+			ag.logger.Errorf("Agent.shuffle(): Failed to make the nonce: %v\n", err)
+			return
+		}
+		msg.Udp = proto.Bool(true)
+		msg.Nonce = proto.Uint64(nonce)
 	}
+	ag.writeControl(msg, node)
 }
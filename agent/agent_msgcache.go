@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"sync"
+	"sync/atomic"
+)
+
+// msgCache is the cache of the hashes of the received messages, which
+// suppresses the duplicates until their purge deadlines. It is an LRU of
+// up to max entries, 0 for unbounded, so it is bounded in memory even if
+// the messages come faster than they are purged. The lookups and inserts
+// are O(1), and the expired entries are removed by the purge loop from
+// the least recently used end.
+type msgCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[[sha1.Size]byte]*list.Element
+	// The entries, the most recently used first.
+	lru *list.List
+	// The numbers of the lookups that found an entry which is not
+	// expired, and of the ones that did not, and of the entries
+	// evicted before their purge deadlines.
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+// cacheEntry is an entry of the msgCache.
+type cacheEntry struct {
+	hash     [sha1.Size]byte
+	deadline int64
+}
+
+func newMsgCache(max int) *msgCache {
+	return &msgCache{
+		max:     max,
+		entries: make(map[[sha1.Size]byte]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// len() returns the number of the entries, including
+// the expired ones that are not purged yet.
+func (c *msgCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// addIfAbsent() adds the hash with the purge deadline, and returns true,
+// unless it is in the cache and its deadline is not before now, which is
+// a hit of a duplicate.
+func (c *msgCache) addIfAbsent(hash [sha1.Size]byte, deadline, now int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[hash]; ok {
+		c.lru.MoveToFront(e)
+		entry := e.Value.(*cacheEntry)
+		if entry.deadline >= now {
+			atomic.AddUint64(&c.hits, 1)
+			return false
+		}
+		atomic.AddUint64(&c.misses, 1)
+		entry.deadline = deadline
+		return true
+	}
+	atomic.AddUint64(&c.misses, 1)
+	c.insert(hash, deadline)
+	return true
+}
+
+// add() sets the purge deadline of the hash.
+func (c *msgCache) add(hash [sha1.Size]byte, deadline int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[hash]; ok {
+		c.lru.MoveToFront(e)
+		e.Value.(*cacheEntry).deadline = deadline
+		return
+	}
+	c.insert(hash, deadline)
+}
+
+// has() returns true if the hash is in the cache,
+// and its deadline is not before now.
+func (c *msgCache) has(hash [sha1.Size]byte, now int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[hash]
+	return ok && e.Value.(*cacheEntry).deadline >= now
+}
+
+// insert() adds a new entry, and evicts the least recently
+// used ones if the cache is full. The cache must be locked.
+func (c *msgCache) insert(hash [sha1.Size]byte, deadline int64) {
+	for c.max > 0 && c.lru.Len() >= c.max {
+		c.remove(c.lru.Back())
+		atomic.AddUint64(&c.evictions, 1)
+	}
+	c.entries[hash] = c.lru.PushFront(&cacheEntry{hash: hash, deadline: deadline})
+}
+
+// remove() removes the entry. The cache must be locked.
+func (c *msgCache) remove(e *list.Element) {
+	delete(c.entries, e.Value.(*cacheEntry).hash)
+	c.lru.Remove(e)
+}
+
+// purge() removes the entries whose purge deadlines are before now,
+// from the least recently used end up to the first one that is not
+// expired, and returns the number of the removed entries. The expired
+// entries behind it, which were used since they were added, are purged
+// later, and are not hits meanwhile.
+func (c *msgCache) purge(now int64) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for e := c.lru.Back(); e != nil && e.Value.(*cacheEntry).deadline < now; e = c.lru.Back() {
+		c.remove(e)
+		n++
+	}
+	return n
+}
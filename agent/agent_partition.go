@@ -0,0 +1,291 @@
+package agent
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lilymona/gog/config"
+	"github.com/lilymona/gog/message"
+	"github.com/lilymona/gog/node"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+const (
+	// partitionWindow is the number of the partition intervals
+	// for which a node seen is counted as reached.
+	partitionWindow = 3
+	// digestSampleSize is the maximum number of the
+	// nodes reached that a digest carries.
+	digestSampleSize = 16
+	// minPartitionPeak is the number of the nodes reached under which no
+	// partition is suspected, as losing the peers of a tiny cluster is
+	// left to the heal loop.
+	minPartitionPeak = 3
+	// maxMergePeers is the maximum number of the lost nodes, and of the
+	// seed peers, joined in a round to merge a partition.
+	maxMergePeers = 3
+)
+
+// PartitionHandler is the callback of suspecting a partition, with the
+// number of the nodes reached recently, and the most reached before.
+type PartitionHandler func(reachable, peak int)
+
+// partition is the state of the partition detection. The neighbors
+// exchange the digests of the nodes they have seen recently, with the
+// time since then, so the nodes seen spread through the overlay. The
+// number of the nodes reached is estimated as the most of its own count
+// and the counts reported by the neighbors. A partition is suspected when
+// the estimate falls under a fraction of the highest one in the history.
+type partition struct {
+	sync.Mutex
+	// The nodes seen in the history, keyed by the node id.
+	seen map[uint64]*seenNode
+	// The last number of the nodes reached reported by
+	// each neighbor, keyed by the node id.
+	reports map[uint64]partitionReport
+	// The estimates of the checks in the history, the oldest first.
+	estimates []int
+	// Whether a partition is suspected, and whether
+	// a round of joins to merge it is in progress.
+	suspected bool
+	merging   bool
+}
+
+// seenNode is the address of a node, and the last time it was seen.
+type seenNode struct {
+	addr string
+	at   time.Time
+}
+
+// partitionReport is the number of the nodes reached
+// reported by a neighbor, and when it was reported.
+type partitionReport struct {
+	reachable int
+	at        time.Time
+}
+
+func newPartition() *partition {
+	return &partition{
+		seen:    make(map[uint64]*seenNode),
+		reports: make(map[uint64]partitionReport),
+	}
+}
+
+// see() records that the node was seen at the time,
+// unless it was seen later. The partition must be locked.
+func (p *partition) see(id uint64, addr string, at time.Time) {
+	if n, ok := p.seen[id]; ok && !n.at.Before(at) {
+		return
+	}
+	p.seen[id] = &seenNode{addr: addr, at: at}
+}
+
+// RegisterPartitionSuspected registers a user provided callback,
+// which is invoked when the agent suspects a partition.
+func (ag *agent) RegisterPartitionSuspected(h PartitionHandler) {
+	ag.partitionHandler = h
+}
+
+// partitionLoop() periodically checks for a partition,
+// and sends the digests to the neighbors.
+func (ag *agent) partitionLoop() {
+	if ag.config().PartitionInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(ag.config().PartitionInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ag.checkPartition(time.Now())
+		case <-ag.stopc:
+			return
+		}
+	}
+}
+
+// checkPartition() estimates the number of the nodes reached, reports a
+// new partition, or joins the nodes lost and the seeds while one is
+// suspected, and sends the digest to the neighbors.
+func (ag *agent) checkPartition(now time.Time) {
+	interval := time.Duration(ag.config().PartitionInterval) * time.Second
+	window := partitionWindow * interval
+	history := time.Duration(ag.config().PartitionHistory) * time.Second
+
+	neighbors := ag.activeNodes()
+
+	p := ag.partition
+	p.Lock()
+	for _, nd := range neighbors {
+		p.see(nd.Id, nd.Addr, now)
+	}
+	var reached []*message.DigestEntry
+	var lost []string
+	for id, n := range p.seen {
+		switch age := now.Sub(n.at); {
+		case age > history:
+			delete(p.seen, id)
+		case age <= window:
+			reached = append(reached, &message.DigestEntry{
+				Id:   proto.Uint64(id),
+				Addr: proto.String(n.addr),
+				Age:  proto.Int64(int64(age / time.Millisecond)),
+			})
+		default:
+			lost = append(lost, n.addr)
+		}
+	}
+
+	// The agent reaches itself too.
+	reachable := len(reached) + 1
+	estimate := reachable
+	for id, r := range p.reports {
+		if now.Sub(r.at) > window {
+			delete(p.reports, id)
+		} else if r.reachable > estimate {
+			estimate = r.reachable
+		}
+	}
+	p.estimates = append(p.estimates, estimate)
+	if n := int(history / interval); n > 0 && len(p.estimates) > n {
+		p.estimates = p.estimates[len(p.estimates)-n:]
+	}
+	peak := 0
+	for _, e := range p.estimates {
+		if e > peak {
+			peak = e
+		}
+	}
+
+	suspected := peak >= minPartitionPeak && float64(estimate) < ag.config().PartitionThreshold*float64(peak)
+	suspect, healed := suspected && !p.suspected, !suspected && p.suspected
+	p.suspected = suspected
+	merge := suspected && !p.merging
+	if merge {
+		p.merging = true
+	}
+	p.Unlock()
+
+	switch {
+	case suspect:
+		ag.partitionSuspected(estimate, peak)
+	case healed:
+		ag.logger.Infof("Agent.checkPartition(): Partition healed, %d of %d nodes reached\n", estimate, peak)
+	}
+	if merge {
+		go ag.mergePartition(lost)
+	}
+
+	ag.rng.Shuffle(len(reached), func(i, j int) { reached[i], reached[j] = reached[j], reached[i] })
+	if len(reached) > digestSampleSize {
+		reached = reached[:digestSampleSize]
+	}
+	msg := &message.Digest{
+		Id:        proto.Uint64(ag.id),
+		Reachable: proto.Uint32(uint32(reachable)),
+		Sample:    reached,
+	}
+	for _, nd := range neighbors {
+		if nd.Caps&node.CapDigest != 0 {
+			ag.digest(nd, msg)
+		}
+	}
+}
+
+// digest() sends the Digest message to the node.
+func (ag *agent) digest(node *node.Node, msg *message.Digest) {
+	if err := ag.codec.WriteMsg(msg, node); err != nil {
+		ag.logger.Errorf("Agent.digest(): Write msg error: %v\n", err)
+		node.Close()
+	}
+}
+
+// handleDigest() records the nodes reached by the neighbor. The ages in
+// the future are taken as now, the nodes older than the history are
+// ignored, and the number reported is bounded by config.MaxPeers, so a
+// neighbor cannot keep the nodes, nor lift the estimate, forever.
+func (ag *agent) handleDigest(from *node.Node, msg *message.Digest) {
+	now := time.Now()
+	history := time.Duration(ag.config().PartitionHistory) * time.Second
+	reachable := int(msg.GetReachable())
+	if reachable > config.MaxPeers {
+		reachable = config.MaxPeers
+	}
+
+	p := ag.partition
+	p.Lock()
+	defer p.Unlock()
+	p.see(from.Id, from.Addr, now)
+	p.reports[from.Id] = partitionReport{reachable: reachable, at: now}
+	for _, e := range msg.GetSample() {
+		// The age is compared in milliseconds, so it cannot overflow.
+		age := e.GetAge()
+		if age < 0 {
+			age = 0
+		}
+		if e.GetId() == ag.id || age > int64(history/time.Millisecond) {
+			continue
+		}
+		p.see(e.GetId(), e.GetAddr(), now.Add(-time.Duration(age)*time.Millisecond))
+	}
+}
+
+// partitionSuspected() reports suspecting a partition.
+func (ag *agent) partitionSuspected(reachable, peak int) {
+	ag.logger.Errorf("Agent.partitionSuspected(): Partition suspected, %d of %d nodes reached\n", reachable, peak)
+	atomic.AddUint64(&ag.stats.partitionsSuspected, 1)
+	h := ag.partitionHandler
+	if h == nil {
+		return
+	}
+	ag.events.dispatch(0, func() { h(reachable, peak) })
+}
+
+// mergePartition() joins some of the nodes lost, which are likely on
+// the other side of the partition, and some of the seed peers, so the
+// overlays of the sides merge through the forward joins.
+func (ag *agent) mergePartition(lost []string) {
+	defer func() {
+		ag.partition.Lock()
+		ag.partition.merging = false
+		ag.partition.Unlock()
+	}()
+
+	ag.rng.Shuffle(len(lost), func(i, j int) { lost[i], lost[j] = lost[j], lost[i] })
+	if len(lost) > maxMergePeers {
+		lost = lost[:maxMergePeers]
+	}
+	seeds := ag.config().ShufflePeers(ag.rng)
+	if len(seeds) > maxMergePeers {
+		seeds = seeds[:maxMergePeers]
+	}
+
+	ag.viewMu.RLock()
+	active := make(map[string]bool, ag.aView.Len())
+	for _, nd := range ag.aView.Values() {
+		active[nd.Addr] = true
+	}
+	ag.viewMu.RUnlock()
+	var peers []string
+	for _, addr := range append(lost, seeds...) {
+		if !active[addr] {
+			peers = append(peers, addr)
+		}
+	}
+	if len(peers) == 0 {
+		return
+	}
+
+	atomic.AddUint64(&ag.stats.mergeAttempts, 1)
+	nd := ag.joinAny(peers)
+	if nd == nil {
+		ag.logger.Warningf("Agent.mergePartition(): No peer accepted the join\n")
+		return
+	}
+	ag.logger.Infof("Agent.mergePartition(): Joined node %s\n", nd.Addr)
+	ag.viewMu.Lock()
+	ag.addNodeActiveView(nd)
+	ag.viewMu.Unlock()
+}
@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lilymona/gog/message"
+	"github.com/lilymona/gog/node"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// pinger is the state of the liveness probes of the neighbors. Each
+// neighbor that advertises node.CapPing is pinged periodically, and
+// removed from the active view after missing the pongs too many times,
+// as a hung but open connection would keep it there indefinitely.
+type pinger struct {
+	sync.Mutex
+	// The last sequence number.
+	seq uint64
+	// The probes of the neighbors, keyed by the node,
+	// as a new connection of the same id is a new node.
+	probes map[*node.Node]*probe
+}
+
+// probe is the liveness probe of a neighbor.
+type probe struct {
+	// The sequence number of the ping waiting for the pong,
+	// 0 if none, and the time it was sent.
+	seq  uint64
+	sent time.Time
+	// The number of the pongs missed in a row.
+	misses int
+}
+
+func newPinger() *pinger {
+	return &pinger{probes: make(map[*node.Node]*probe)}
+}
+
+// pingLoop() periodically pings the neighbors.
+func (ag *agent) pingLoop() {
+	if ag.config().PingInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(ag.config().PingInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ag.pingOnce(time.Now())
+		case <-ag.stopc:
+			return
+		}
+	}
+}
+
+// pingOnce() counts the pings not answered since the last time as missed,
+// replaces the neighbors that missed too many, and pings the others. The
+// replacements run in their own goroutines, as they dial the passive
+// nodes, so they do not delay the pings.
+func (ag *agent) pingOnce(now time.Time) {
+	ag.viewMu.RLock()
+	nodes := make(map[*node.Node]bool, ag.aView.Len())
+	for _, nd := range ag.aView.Values() {
+		if nd.Caps&node.CapPing != 0 {
+			nodes[nd] = true
+		}
+	}
+	ag.viewMu.RUnlock()
+
+	var lost, alive []*node.Node
+	var seqs []uint64
+	ag.pinger.Lock()
+	for nd := range ag.pinger.probes {
+		if !nodes[nd] {
+			delete(ag.pinger.probes, nd)
+		}
+	}
+	for nd := range nodes {
+		p, ok := ag.pinger.probes[nd]
+		if !ok {
+			p = &probe{}
+			ag.pinger.probes[nd] = p
+		}
+		if p.seq != 0 {
+			p.misses++
+		}
+		if p.misses >= ag.config().PingMisses {
+			delete(ag.pinger.probes, nd)
+			lost = append(lost, nd)
+			continue
+		}
+		ag.pinger.seq++
+		p.seq, p.sent = ag.pinger.seq, now
+		alive = append(alive, nd)
+		seqs = append(seqs, p.seq)
+	}
+	ag.pinger.Unlock()
+
+	for _, nd := range lost {
+		ag.logger.Warningf("Agent.pingOnce(): Node %v missed %d pongs, replace it\n", nd, ag.config().PingMisses)
+		atomic.AddUint64(&ag.stats.pingFailures, 1)
+		go ag.replaceActiveNode(nd)
+	}
+	for i, nd := range alive {
+		ag.ping(nd, seqs[i])
+	}
+}
+
+// ping() sends a Ping message to the node.
+func (ag *agent) ping(node *node.Node, seq uint64) {
+	msg := &message.Ping{
+		Id:  proto.Uint64(ag.id),
+		Seq: proto.Uint64(seq),
+	}
+	ag.writeControl(msg, node)
+}
+
+// handlePing() answers the Ping with a Pong of the same sequence number.
+func (ag *agent) handlePing(from *node.Node, msg *message.Ping) {
+	reply := &message.Pong{
+		Id:  proto.Uint64(ag.id),
+		Seq: proto.Uint64(msg.GetSeq()),
+	}
+	ag.controlMessage(from, reply)
+}
+
+// handlePong() marks the neighbor alive if the
+// Pong answers the last Ping in time.
+func (ag *agent) handlePong(from *node.Node, msg *message.Pong) {
+	ag.pinger.Lock()
+	defer ag.pinger.Unlock()
+	p, ok := ag.pinger.probes[from]
+	if !ok || p.seq == 0 || p.seq != msg.GetSeq() {
+		return
+	}
+	if timeout := time.Duration(ag.config().PingTimeout) * time.Millisecond; timeout > 0 && time.Since(p.sent) > timeout {
+		return
+	}
+	p.seq, p.misses = 0, 0
+}
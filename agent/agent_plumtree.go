@@ -0,0 +1,320 @@
+package agent
+
+import (
+	"crypto/sha1"
+	"sync"
+	"time"
+
+	"github.com/lilymona/gog/message"
+	"github.com/lilymona/gog/node"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// lazyPushInterval is the interval to flush the lazy queue.
+const lazyPushInterval = 100 * time.Millisecond
+
+// plumtree is the state of the epidemic broadcast trees. The user
+// messages are pushed to the eager peers, and only announced to the
+// lazy peers with IHave. A peer that is announced a message it has not
+// received grafts the announcer after a timeout, which makes the link
+// eager again. A peer that receives a duplicate prunes the sender, which
+// makes the link lazy. The neighbors are eager by default.
+type plumtree struct {
+	sync.Mutex
+	// The lazy peers.
+	lazy map[uint64]bool
+	// The message ids to announce to each lazy peer.
+	queue map[uint64][][]byte
+	// The received messages, to reply the grafts.
+	messages map[[sha1.Size]byte]*cachedMessage
+	// The announced messages that have not been received.
+	missing map[[sha1.Size]byte]*announcement
+}
+
+// cachedMessage is a received message, kept until the purge deadline.
+type cachedMessage struct {
+	msg           *message.UserMessage
+	purgeDeadline int64
+}
+
+// announcement records the peers that announced a missing message,
+// which are grafted in order when the timer fires.
+type announcement struct {
+	sources []uint64
+	timer   *time.Timer
+}
+
+func newPlumtree() *plumtree {
+	return &plumtree{
+		lazy:     make(map[uint64]bool),
+		queue:    make(map[uint64][][]byte),
+		messages: make(map[[sha1.Size]byte]*cachedMessage),
+		missing:  make(map[[sha1.Size]byte]*announcement),
+	}
+}
+
+// isLazy() returns true if the peer is lazy.
+func (pt *plumtree) isLazy(id uint64) bool {
+	pt.Lock()
+	defer pt.Unlock()
+	return pt.lazy[id]
+}
+
+// setLazy() makes the peer lazy or eager.
+func (pt *plumtree) setLazy(id uint64, lazy bool) {
+	pt.Lock()
+	defer pt.Unlock()
+	if lazy {
+		pt.lazy[id] = true
+	} else {
+		delete(pt.lazy, id)
+	}
+}
+
+// remove() forgets the peer, which has left the active view.
+func (pt *plumtree) remove(id uint64) {
+	pt.Lock()
+	defer pt.Unlock()
+	delete(pt.lazy, id)
+	delete(pt.queue, id)
+}
+
+// enqueue() queues the announcement of the message to the lazy peer.
+func (pt *plumtree) enqueue(id uint64, hash [sha1.Size]byte) {
+	pt.Lock()
+	defer pt.Unlock()
+	pt.queue[id] = append(pt.queue[id], hash[:])
+}
+
+// takeQueue() returns and clears the lazy queue.
+func (pt *plumtree) takeQueue() map[uint64][][]byte {
+	pt.Lock()
+	defer pt.Unlock()
+	queue := pt.queue
+	pt.queue = make(map[uint64][][]byte)
+	return queue
+}
+
+// received() caches the received message, and cancels the
+// grafts of the message.
+func (pt *plumtree) received(hash [sha1.Size]byte, msg *message.UserMessage, purgeDeadline int64) {
+	pt.Lock()
+	defer pt.Unlock()
+	pt.messages[hash] = &cachedMessage{msg, purgeDeadline}
+	if ann, ok := pt.missing[hash]; ok {
+		ann.timer.Stop()
+		delete(pt.missing, hash)
+	}
+}
+
+// message() returns the cached message, or nil if it is unknown.
+func (pt *plumtree) message(hash [sha1.Size]byte) *message.UserMessage {
+	pt.Lock()
+	defer pt.Unlock()
+	if cm, ok := pt.messages[hash]; ok {
+		return cm.msg
+	}
+	return nil
+}
+
+// purge() removes the cached messages whose purge deadlines are before now.
+func (pt *plumtree) purge(now int64) {
+	pt.Lock()
+	defer pt.Unlock()
+	for hash, cm := range pt.messages {
+		if cm.purgeDeadline < now {
+			delete(pt.messages, hash)
+		}
+	}
+}
+
+// announced() records that the peer announced the missing message, and
+// calls graft after the timeout if it is the first announcement.
+func (pt *plumtree) announced(hash [sha1.Size]byte, id uint64, timeout time.Duration, graft func()) {
+	pt.Lock()
+	defer pt.Unlock()
+	if _, ok := pt.messages[hash]; ok {
+		return
+	}
+	if ann, ok := pt.missing[hash]; ok {
+		ann.sources = append(ann.sources, id)
+		return
+	}
+	pt.missing[hash] = &announcement{
+		sources: []uint64{id},
+		timer:   time.AfterFunc(timeout, graft),
+	}
+}
+
+// nextSource() returns the next announcer of the missing message to
+// graft, and restarts the timer if there are more announcers.
+func (pt *plumtree) nextSource(hash [sha1.Size]byte, timeout time.Duration) (uint64, bool) {
+	pt.Lock()
+	defer pt.Unlock()
+	ann, ok := pt.missing[hash]
+	if !ok {
+		return 0, false
+	}
+	id := ann.sources[0]
+	ann.sources = ann.sources[1:]
+	if len(ann.sources) > 0 {
+		ann.timer.Reset(timeout)
+	} else {
+		delete(pt.missing, hash)
+	}
+	return id, true
+}
+
+// graftTimeout() returns the time to wait for a missing message
+// before grafting the announcer.
+func (ag *agent) graftTimeout() time.Duration {
+	return time.Duration(ag.config().GraftTimeout) * time.Millisecond
+}
+
+// lazyLoop() periodically sends the queued announcements to the lazy peers.
+func (ag *agent) lazyLoop() {
+	if !ag.config().Plumtree {
+		return
+	}
+	ticker := time.NewTicker(lazyPushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ag.flushLazyQueue()
+		case <-ag.stopc:
+			return
+		}
+	}
+}
+
+// flushLazyQueue() sends the queued announcements to the lazy peers.
+func (ag *agent) flushLazyQueue() {
+	queue := ag.plumtree.takeQueue()
+	if len(queue) == 0 {
+		return
+	}
+
+	ag.viewMu.RLock()
+	defer ag.viewMu.RUnlock()
+	for id, msgIds := range queue {
+		if !ag.aView.Has(id) {
+			continue
+		}
+		go ag.ihave(ag.aView.GetValueOf(id), msgIds)
+	}
+}
+
+// pushMessage() pushes the message to the eager peers in the active view
+// and queues the announcements to the lazy peers, except the sender.
+// The view lock must not be held, see activeNodes().
+func (ag *agent) pushMessage(from *node.Node, hash [sha1.Size]byte, msg *message.UserMessage) {
+	for _, nd := range ag.activeNodes() {
+		if from != nil && nd.Id == from.Id {
+			continue
+		}
+		if ag.plumtree.isLazy(nd.Id) {
+			ag.plumtree.enqueue(nd.Id, hash)
+		} else {
+			ag.userMessage(nd, msg)
+		}
+	}
+}
+
+// broadcastPlumtree() records the message, so the message coming back
+// prunes the redundant links, and pushes it to the peers.
+func (ag *agent) broadcastPlumtree(msg *message.UserMessage) {
+	hash := hashUserMessage(msg)
+	purgeDeadline := ag.purgeDeadline(msg, time.Now().UnixNano())
+	ag.msgCache.add(hash, purgeDeadline)
+	ag.plumtree.received(hash, msg, purgeDeadline)
+	ag.pushMessage(nil, hash, msg)
+}
+
+// handleIHave() handles IHave message. It schedules the grafts of the
+// announced messages that have not been received.
+func (ag *agent) handleIHave(from *node.Node, msg *message.IHave) {
+	now := time.Now().UnixNano()
+	for _, id := range msg.GetMsgIds() {
+		var hash [sha1.Size]byte
+		if len(id) != len(hash) {
+			ag.sampledLogger.Warningf("Agent.handleIHave(): Invalid message id %x\n", id)
+			continue
+		}
+		copy(hash[:], id)
+
+		if !ag.msgCache.has(hash, now) {
+			ag.plumtree.announced(hash, from.Id, ag.graftTimeout(), func() { ag.graftMissing(hash) })
+		}
+	}
+}
+
+// graftMissing() grafts the next announcer of the missing message,
+// asking it for the message.
+func (ag *agent) graftMissing(hash [sha1.Size]byte) {
+	id, ok := ag.plumtree.nextSource(hash, ag.graftTimeout()/2)
+	if !ok {
+		return
+	}
+	ag.viewMu.RLock()
+	defer ag.viewMu.RUnlock()
+	if !ag.aView.Has(id) {
+		// The next announcer, if any, is grafted on the next timeout.
+		return
+	}
+	ag.plumtree.setLazy(id, false)
+	go ag.graft(ag.aView.GetValueOf(id), hash)
+}
+
+// handleGraft() handles Graft message. It makes the link eager,
+// and sends the message if it is still cached.
+func (ag *agent) handleGraft(from *node.Node, msg *message.Graft) {
+	ag.plumtree.setLazy(from.Id, false)
+
+	var hash [sha1.Size]byte
+	copy(hash[:], msg.GetMsgId())
+	if umsg := ag.plumtree.message(hash); umsg != nil {
+		ag.userMessage(from, umsg)
+	}
+}
+
+// handlePrune() handles Prune message. It makes the link lazy.
+func (ag *agent) handlePrune(from *node.Node) {
+	ag.plumtree.setLazy(from.Id, true)
+}
+
+// ihave() sends an IHave message to the node.
+func (ag *agent) ihave(node *node.Node, msgIds [][]byte) {
+	msg := &message.IHave{
+		Id:     proto.Uint64(ag.id),
+		MsgIds: msgIds,
+	}
+	if err := ag.codec.WriteMsg(msg, node); err != nil {
+		ag.logger.Errorf("Agent.ihave(): Write msg error: %v\n", err)
+		node.Close()
+	}
+}
+
+// graft() sends a Graft message to the node.
+func (ag *agent) graft(node *node.Node, hash [sha1.Size]byte) {
+	msg := &message.Graft{
+		Id:    proto.Uint64(ag.id),
+		MsgId: hash[:],
+	}
+	if err := ag.codec.WriteMsg(msg, node); err != nil {
+		ag.logger.Errorf("Agent.graft(): Write msg error: %v\n", err)
+		node.Close()
+	}
+}
+
+// prune() sends a Prune message to the node.
+func (ag *agent) prune(node *node.Node) {
+	msg := &message.Prune{
+		Id: proto.Uint64(ag.id),
+	}
+	if err := ag.codec.WriteMsg(msg, node); err != nil {
+		ag.logger.Errorf("Agent.prune(): Write msg error: %v\n", err)
+		node.Close()
+	}
+}
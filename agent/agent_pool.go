@@ -0,0 +1,170 @@
+package agent
+
+import (
+	"container/list"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// connPool caches the idle connections of the control messages, i.e.
+// the shuffle replies and the neighbor requests, keyed by the address
+// of the peer, so they are reused instead of dialing the peer each time.
+type connPool struct {
+	mu sync.Mutex
+	// The maximum number of the idle connections, 0 disables the pool,
+	// and the time after which an idle connection is closed.
+	size    int
+	timeout time.Duration
+	// idle maps the addresses to the elements of lru, which
+	// has the most recently used connections at the front.
+	idle   map[string]*list.Element
+	lru    *list.List
+	closed bool
+}
+
+// idleConn is an idle connection in the pool.
+type idleConn struct {
+	addr  string
+	conn  net.Conn
+	since time.Time
+}
+
+// probeTimeout is the time to wait for the EOF of an idle
+// connection closed by the peer.
+const probeTimeout = time.Millisecond
+
+func newConnPool(size int, timeout time.Duration) *connPool {
+	return &connPool{
+		size:    size,
+		timeout: timeout,
+		idle:    make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// get() takes the idle connection to the address out of the pool,
+// or returns nil if there is none alive.
+func (p *connPool) get(addr string) net.Conn {
+	p.mu.Lock()
+	e, ok := p.idle[addr]
+	if ok {
+		p.removeLocked(e)
+	}
+	p.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	ic := e.Value.(*idleConn)
+	if p.expired(ic, time.Now()) || !alive(ic.conn) {
+		ic.conn.Close()
+		return nil
+	}
+	return ic.conn
+}
+
+// put() returns the connection to the address to the pool. The least
+// recently used connection is closed if the pool is full, and the
+// connection itself if the pool is disabled or closed, or already
+// has a connection to the address.
+func (p *connPool) put(addr string, conn net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.idle[addr]; ok || p.size <= 0 || p.closed {
+		conn.Close()
+		return
+	}
+	for p.lru.Len() >= p.size {
+		e := p.lru.Back()
+		p.removeLocked(e)
+		e.Value.(*idleConn).conn.Close()
+	}
+	p.idle[addr] = p.lru.PushFront(&idleConn{addr: addr, conn: conn, since: time.Now()})
+}
+
+// expire() closes the connections idle for longer than the
+// timeout, and returns the number of closed connections.
+func (p *connPool) expire(now time.Time) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// The least recently used connections are at the back.
+	n := 0
+	for e := p.lru.Back(); e != nil && p.expired(e.Value.(*idleConn), now); e = p.lru.Back() {
+		p.removeLocked(e)
+		e.Value.(*idleConn).conn.Close()
+		n++
+	}
+	return n
+}
+
+// close() closes all the idle connections, and
+// the connections returned to the pool later.
+func (p *connPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for e := p.lru.Front(); e != nil; e = e.Next() {
+		e.Value.(*idleConn).conn.Close()
+	}
+	p.idle = make(map[string]*list.Element)
+	p.lru.Init()
+	p.closed = true
+}
+
+// len() returns the number of the idle connections.
+func (p *connPool) len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lru.Len()
+}
+
+func (p *connPool) removeLocked(e *list.Element) {
+	delete(p.idle, e.Value.(*idleConn).addr)
+	p.lru.Remove(e)
+}
+
+func (p *connPool) expired(ic *idleConn, now time.Time) bool {
+	return p.timeout > 0 && now.Sub(ic.since) >= p.timeout
+}
+
+// alive() returns false if the idle connection has been closed by the
+// peer. The peers never write to the connections of the control
+// messages unasked, so anything but a timeout means it is broken.
+func alive(conn net.Conn) bool {
+	var b [1]byte
+	conn.SetReadDeadline(time.Now().Add(probeTimeout))
+	_, err := conn.Read(b[:])
+	conn.SetReadDeadline(time.Time{})
+	return isTimeout(err)
+}
+
+// poolLoop() periodically closes the expired idle connections.
+func (ag *agent) poolLoop() {
+	if ag.config().ConnIdleTimeout <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(ag.config().ConnIdleTimeout) * time.Second / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if n := ag.pool.expire(time.Now()); n > 0 {
+				ag.logger.Debugf("Agent.poolLoop(): Closed %d idle connections\n", n)
+			}
+		case <-ag.stopc:
+			return
+		}
+	}
+}
+
+// dial() returns an idle connection to the peer
+// from the pool, or connects the peer.
+func (ag *agent) dial(peerAddr string) (net.Conn, error) {
+	if conn := ag.pool.get(peerAddr); conn != nil {
+		atomic.AddUint64(&ag.stats.reusedConns, 1)
+		return conn, nil
+	}
+	return ag.connect(peerAddr)
+}
@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"time"
+)
+
+// purgeLoop() periodically removes the expired entries from the
+// message cache, which are otherwise only removed when the same
+// message is received again, or evicted when the cache is full.
+func (ag *agent) purgeLoop() {
+	if ag.config().PurgeDuration <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(ag.config().PurgeDuration) * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if n := ag.purgeMessages(time.Now().UnixNano()); n > 0 {
+				ag.logger.Debugf("Agent.purgeLoop(): Purged %d messages\n", n)
+			}
+		case <-ag.stopc:
+			return
+		}
+	}
+}
+
+// purgeMessages() removes the entries whose purge deadlines are
+// before now, and returns the number of removed entries. The senders
+// whose messages received before are all stale are forgotten too.
+func (ag *agent) purgeMessages(now int64) int {
+	ag.plumtree.purge(now)
+	ag.replay.purge(now)
+	ag.purgeFIFO(now)
+	return ag.msgCache.purge(now)
+}
@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lilymona/gog/config"
+	"github.com/lilymona/gog/node"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// rateLimiter limits the rates of the outbound user messages, in bytes
+// and in messages per second, to all the neighbors and to each neighbor.
+// The send loop of a neighbor waits for the tokens before writing, so the
+// messages pile up in the send queue, which drops them or blocks the
+// senders when it is full.
+type rateLimiter struct {
+	mu sync.Mutex
+	// The limits of all the neighbors.
+	bytes *throttle
+	msgs  *throttle
+	// The limits of each neighbor, keyed by the node id,
+	// and their rates.
+	links        map[uint64]*linkLimit
+	linkByteRate float64
+	linkMsgRate  float64
+}
+
+// linkLimit is the limits of a neighbor.
+type linkLimit struct {
+	bytes *throttle
+	msgs  *throttle
+}
+
+// newRateThrottle() creates a throttle with the rate, and a burst
+// of a second worth of it, but at least one token.
+func newRateThrottle(rate float64) *throttle {
+	burst := int(rate)
+	if burst < 1 {
+		burst = 1
+	}
+	return newThrottle(rate, burst)
+}
+
+func newRateLimiter(cfg *config.Config) *rateLimiter {
+	return &rateLimiter{
+		bytes:        newRateThrottle(cfg.SendByteRate),
+		msgs:         newRateThrottle(cfg.SendMessageRate),
+		links:        make(map[uint64]*linkLimit),
+		linkByteRate: cfg.PeerSendByteRate,
+		linkMsgRate:  cfg.PeerSendMessageRate,
+	}
+}
+
+// link() returns the limits of the neighbor, or nil if there is none.
+func (rl *rateLimiter) link(id uint64) *linkLimit {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if rl.linkByteRate <= 0 && rl.linkMsgRate <= 0 {
+		return nil
+	}
+	l, ok := rl.links[id]
+	if !ok {
+		l = &linkLimit{
+			bytes: newRateThrottle(rl.linkByteRate),
+			msgs:  newRateThrottle(rl.linkMsgRate),
+		}
+		rl.links[id] = l
+	}
+	return l
+}
+
+// setRates() replaces the limits with the rates of the configuration,
+// if they change. The neighbors start again with a full burst.
+func (rl *rateLimiter) setRates(cfg *config.Config) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if rl.bytes.rate != cfg.SendByteRate || rl.msgs.rate != cfg.SendMessageRate {
+		rl.bytes = newRateThrottle(cfg.SendByteRate)
+		rl.msgs = newRateThrottle(cfg.SendMessageRate)
+	}
+	if rl.linkByteRate != cfg.PeerSendByteRate || rl.linkMsgRate != cfg.PeerSendMessageRate {
+		rl.links = make(map[uint64]*linkLimit)
+		rl.linkByteRate = cfg.PeerSendByteRate
+		rl.linkMsgRate = cfg.PeerSendMessageRate
+	}
+}
+
+// remove() forgets the limits of the neighbor.
+func (rl *rateLimiter) remove(id uint64) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	delete(rl.links, id)
+}
+
+// reserve() takes the tokens of the messages to the neighbor,
+// and returns the time to wait before writing them.
+func (rl *rateLimiter) reserve(id uint64, msgs []proto.Message) time.Duration {
+	var size int
+	for _, msg := range msgs {
+		size += proto.Size(msg)
+	}
+	rl.mu.Lock()
+	bytes, count := rl.bytes, rl.msgs
+	rl.mu.Unlock()
+	waits := []time.Duration{
+		bytes.reserve(float64(size)),
+		count.reserve(float64(len(msgs))),
+	}
+	if l := rl.link(id); l != nil {
+		waits = append(waits, l.bytes.reserve(float64(size)), l.msgs.reserve(float64(len(msgs))))
+	}
+	var wait time.Duration
+	for _, w := range waits {
+		if w > wait {
+			wait = w
+		}
+	}
+	return wait
+}
+
+// throttleSend() waits until the messages to the node are within the
+// rate limits, or the agent is closed. The control messages are not
+// throttled, and are written while it waits.
+func (ag *agent) throttleSend(nd *node.Node, msgs []proto.Message) {
+	wait := ag.limiter.reserve(nd.Id, msgs)
+	if wait <= 0 {
+		return
+	}
+	atomic.AddUint64(&ag.stats.throttled, uint64(len(msgs)))
+	ag.sendWait(nd, wait)
+}
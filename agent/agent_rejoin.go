@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// JoinAbandonedHandler is the callback of giving up joining the peers
+// again, after the number of failed rounds of rejoin attempts.
+type JoinAbandonedHandler func(peers []string, attempts int)
+
+// rejoiner is the state of joining the peers again after losing all of
+// them. The failed rounds are backed off exponentially with a random
+// jitter, instead of being tried on every heal tick, and the agent gives
+// up after the configured number of rounds.
+type rejoiner struct {
+	sync.Mutex
+	// The number of the failed rounds in a row.
+	failures int
+	// The time before which no round is started.
+	next time.Time
+	// Whether a round is in progress.
+	running bool
+	// Whether the agent gave up.
+	abandoned bool
+}
+
+// RegisterJoinAbandoned registers a user provided callback, which is
+// invoked when the agent gives up joining the peers again.
+func (ag *agent) RegisterJoinAbandoned(h JoinAbandonedHandler) {
+	ag.abandonHandler = h
+}
+
+// joinBackoff() returns the time to wait before the retry, which is the
+// join backoff doubled on each retry, up to the maximum backoff, with a
+// random jitter of up to a half of it, so the agents that lost the same
+// peers do not retry at once.
+func (ag *agent) joinBackoff(retry int) time.Duration {
+	d := time.Duration(ag.config().JoinBackoff) * time.Millisecond
+	max := time.Duration(ag.config().JoinMaxBackoff) * time.Millisecond
+	for i := 0; i < retry && (max <= 0 || d < max); i++ {
+		d *= 2
+	}
+	if max > 0 && d > max {
+		d = max
+	}
+	if half := int64(d / 2); half > 0 {
+		d = time.Duration(half + ag.rng.Int63n(half+1))
+	}
+	return d
+}
+
+// rejoinCluster() joins the seed peers again, unless a round is in
+// progress, or the backoff of the last failed round has not passed, or
+// the agent gave up. The peers are looked up again on each round, so the
+// hostnames are resolved again too.
+func (ag *agent) rejoinCluster() {
+	r := &ag.rejoiner
+	r.Lock()
+	if r.running || r.abandoned || time.Now().Before(r.next) {
+		r.Unlock()
+		return
+	}
+	r.running = true
+	r.Unlock()
+
+	atomic.AddUint64(&ag.stats.rejoins, 1)
+	peers := ag.bootstrapPeers()
+	err := ag.joinCluster(peers)
+
+	r.Lock()
+	r.running = false
+	if err == nil {
+		r.failures, r.next = 0, time.Time{}
+		r.Unlock()
+		return
+	}
+	r.failures++
+	failures := r.failures
+	if max := ag.config().RejoinAttempts; max > 0 && failures >= max {
+		r.abandoned = true
+		r.Unlock()
+		ag.joinAbandoned(peers, failures)
+		return
+	}
+	backoff := ag.joinBackoff(ag.config().JoinRetries + failures)
+	r.next = time.Now().Add(backoff)
+	r.Unlock()
+	ag.logger.Warningf("Agent.rejoinCluster(): No available peers, retry in %v\n", backoff)
+}
+
+// resetRejoin() clears the failed rounds, as the agent has peers again,
+// so it rejoins right away the next time it loses them.
+func (ag *agent) resetRejoin() {
+	r := &ag.rejoiner
+	r.Lock()
+	r.failures, r.next, r.abandoned = 0, time.Time{}, false
+	r.Unlock()
+}
+
+// joinAbandoned() reports giving up joining the peers again.
+func (ag *agent) joinAbandoned(peers []string, attempts int) {
+	ag.logger.Errorf("Agent.joinAbandoned(): Giving up joining the peers after %d attempts, need a new list!\n", attempts)
+	atomic.AddUint64(&ag.stats.joinsAbandoned, 1)
+	h := ag.abandonHandler
+	if h == nil {
+		return
+	}
+	ag.events.dispatch(0, func() { h(peers, attempts) })
+}
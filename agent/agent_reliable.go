@@ -0,0 +1,186 @@
+package agent
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lilymona/gog/message"
+	"github.com/lilymona/gog/node"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// DeliveryFailureHandler is the callback of the user messages that the
+// neighbor has not acknowledged after all the retries in the reliable mode.
+type DeliveryFailureHandler func(id uint64, addr string, msg Message)
+
+// reliable is the state of the reliable mode. Each user message sent to a
+// neighbor carries a sequence number, which the neighbor acknowledges. The
+// messages not acknowledged in time are sent again with exponential backoff,
+// and reported to the DeliveryFailureHandler after the last retry.
+type reliable struct {
+	sync.Mutex
+	// The last sequence number.
+	seq uint64
+	// The messages waiting for the acks.
+	pending map[pendingKey]*pendingMessage
+}
+
+// pendingKey is the neighbor and the sequence number of a pending message.
+type pendingKey struct {
+	id  uint64
+	seq uint64
+}
+
+// pendingMessage is a message sent to a neighbor, waiting for the ack.
+type pendingMessage struct {
+	msg      *message.UserMessage
+	addr     string
+	attempts int
+	timer    *time.Timer
+}
+
+func newReliable() *reliable {
+	return &reliable{pending: make(map[pendingKey]*pendingMessage)}
+}
+
+// remove() removes the pending message, and stops its timer.
+// It returns nil if the message is not pending.
+func (r *reliable) remove(key pendingKey) *pendingMessage {
+	r.Lock()
+	defer r.Unlock()
+	p, ok := r.pending[key]
+	if !ok {
+		return nil
+	}
+	p.timer.Stop()
+	delete(r.pending, key)
+	return p
+}
+
+// RegisterDeliveryFailure registers a user provided callback, which is
+// invoked for the messages that are not acknowledged in the reliable mode.
+func (ag *agent) RegisterDeliveryFailure(h DeliveryFailureHandler) {
+	ag.failureHandler = h
+}
+
+// ackTimeout() returns the time to wait for the ack after the attempt.
+func (ag *agent) ackTimeout(attempts int) time.Duration {
+	return time.Duration(ag.config().AckTimeout) * time.Millisecond << uint(attempts)
+}
+
+// sequence() returns a copy of the message with the next sequence number,
+// which is pending until the node acknowledges it.
+func (ag *agent) sequence(nd *node.Node, msg *message.UserMessage) (*message.UserMessage, pendingKey) {
+	ag.reliable.Lock()
+	defer ag.reliable.Unlock()
+	ag.reliable.seq++
+	key := pendingKey{nd.Id, ag.reliable.seq}
+
+	smsg := &message.UserMessage{
+		Id:      msg.Id,
+		Payload: msg.Payload,
+		Ts:      msg.Ts,
+		Trace:   msg.Trace,
+		Topic:   msg.Topic,
+		Seq:     proto.Uint64(key.seq),
+		Hops:    msg.Hops,
+		Life:    msg.Life,
+		Key:     msg.Key,
+		Sig:     msg.Sig,
+
+		ChunkId:     msg.ChunkId,
+		ChunkIndex:  msg.ChunkIndex,
+		ChunkCount:  msg.ChunkCount,
+		ChunkHash:   msg.ChunkHash,
+		OriginSeq:   msg.OriginSeq,
+		OriginEpoch: msg.OriginEpoch,
+	}
+	ag.reliable.pending[key] = &pendingMessage{
+		msg:   smsg,
+		addr:  nd.Addr,
+		timer: time.AfterFunc(ag.ackTimeout(0), func() { ag.retry(key) }),
+	}
+	return smsg, key
+}
+
+// retry() sends the pending message again if the node is still in the
+// active view, or reports the failure after the last retry.
+func (ag *agent) retry(key pendingKey) {
+	if ag.stopped() {
+		ag.reliable.remove(key)
+		return
+	}
+
+	ag.reliable.Lock()
+	p, ok := ag.reliable.pending[key]
+	if !ok {
+		ag.reliable.Unlock()
+		return
+	}
+	p.attempts++
+	if p.attempts > ag.config().AckRetries {
+		delete(ag.reliable.pending, key)
+		ag.reliable.Unlock()
+		ag.deliveryFailed(key.id, p)
+		return
+	}
+	p.timer.Reset(ag.ackTimeout(p.attempts))
+	ag.reliable.Unlock()
+
+	ag.viewMu.RLock()
+	var nd *node.Node
+	if ag.aView.Has(key.id) {
+		nd = ag.aView.GetValueOf(key.id)
+	}
+	ag.viewMu.RUnlock()
+	if nd == nil {
+		if p := ag.reliable.remove(key); p != nil {
+			ag.deliveryFailed(key.id, p)
+		}
+		return
+	}
+
+	ag.logger.Debugf("Agent.retry(): Resending message %d to %v\n", key.seq, nd)
+	atomic.AddUint64(&ag.stats.retransmits, 1)
+	if err := ag.codec.WriteMsg(p.msg, nd); err != nil {
+		ag.logger.Errorf("Agent.retry(): Write msg error: %v\n", err)
+		nd.Close()
+	}
+}
+
+// deliveryFailed() reports the message not acknowledged by the node.
+func (ag *agent) deliveryFailed(id uint64, p *pendingMessage) {
+	ag.sampledLogger.Warningf("Agent.deliveryFailed(): Message %d is not acknowledged by %d\n", p.msg.GetSeq(), id)
+	atomic.AddUint64(&ag.stats.undelivered, 1)
+	h := ag.failureHandler
+	if h == nil {
+		return
+	}
+	addr := p.addr
+	msg := Message{
+		SenderID:  p.msg.GetId(),
+		Payload:   p.msg.GetPayload(),
+		Timestamp: p.msg.GetTs(),
+		Topic:     p.msg.GetTopic(),
+	}
+	ag.events.dispatch(0, func() { h(id, addr, msg) })
+}
+
+// handleAck() handles Ack message. The message is not pending anymore.
+func (ag *agent) handleAck(from *node.Node, msg *message.Ack) {
+	ag.reliable.remove(pendingKey{from.Id, msg.GetSeq()})
+}
+
+// ack() sends an Ack message to the node.
+func (ag *agent) ack(node *node.Node, seq uint64) {
+	msg := &message.Ack{
+		Id:  proto.Uint64(ag.id),
+		Seq: proto.Uint64(seq),
+	}
+	if err := ag.codec.WriteMsg(msg, node); err != nil {
+		ag.logger.Errorf("Agent.ack(): Write msg error: %v\n", err)
+		node.Close()
+	}
+}
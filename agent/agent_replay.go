@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// replay is the state of dropping the replayed user messages. Each
+// message carries the sender id, the epoch of the sender, which is the
+// time it started, and a sequence number, increasing from 1 for each
+// message the sender broadcasts since. The receivers track the highest
+// sequence number of the latest epoch of each sender, and which of the
+// ones in the window under it are received, so the messages arriving out
+// of order are accepted once, and the ones older than the window, or of
+// an earlier epoch, are dropped. A later epoch, i.e. a restart of the
+// sender, starts a new window.
+type replay struct {
+	// The epoch of the broadcast messages.
+	epoch uint64
+	// The last sequence number of the broadcast messages.
+	seq uint64
+
+	sync.Mutex
+	// The windows of the senders, keyed by the node id.
+	senders map[uint64]*replayWindow
+}
+
+// replayWindow is the sequence numbers received from a sender.
+type replayWindow struct {
+	// The epoch of the sender.
+	epoch uint64
+	// The highest sequence number received.
+	high uint64
+	// The bitmap of the sequence numbers received, the bit
+	// of a sequence number s at s modulo the window size.
+	seen []uint64
+	// The latest deadline in nanoseconds of the messages received,
+	// until which they could be replayed, see messageDeadline().
+	deadline int64
+}
+
+func newReplay() *replay {
+	return &replay{
+		epoch:   uint64(time.Now().UnixNano()),
+		senders: make(map[uint64]*replayWindow),
+	}
+}
+
+// next() returns the sequence number of the next broadcast message.
+func (r *replay) next() uint64 {
+	return atomic.AddUint64(&r.seq, 1)
+}
+
+// accept() records receiving the sequence number of the epoch from the
+// sender in a window of the size rounded up to a multiple of 64, and
+// returns false if it is received already, older than the window, or of
+// an earlier epoch, in which case the window is left as it is. The window
+// is kept until the deadline of the message at least.
+func (r *replay) accept(sender, epoch, seq uint64, size int, deadline int64) bool {
+	r.Lock()
+	defer r.Unlock()
+	w, ok := r.senders[sender]
+	switch {
+	case ok && epoch < w.epoch:
+		return false
+	case !ok || epoch > w.epoch:
+		w = &replayWindow{epoch: epoch, seen: make([]uint64, (size+63)/64)}
+		r.senders[sender] = w
+	}
+
+	bits := uint64(len(w.seen)) * 64
+	switch {
+	case seq > w.high:
+		// Forget the sequence numbers sliding out of the window.
+		if seq-w.high >= bits {
+			clear(w.seen)
+		} else {
+			for s := w.high + 1; s < seq; s++ {
+				w.seen[s%bits/64] &^= 1 << (s % 64)
+			}
+		}
+		w.high = seq
+	case w.high-seq >= bits:
+		return false
+	case w.seen[seq%bits/64]&(1<<(seq%64)) != 0:
+		return false
+	}
+	w.seen[seq%bits/64] |= 1 << (seq % 64)
+	if deadline > w.deadline {
+		w.deadline = deadline
+	}
+	return true
+}
+
+// purge() forgets the senders whose messages are all dead before now,
+// so they cannot be replayed, and returns the number of them.
+func (r *replay) purge(now int64) int {
+	r.Lock()
+	defer r.Unlock()
+	n := 0
+	for id, w := range r.senders {
+		if w.deadline < now {
+			delete(r.senders, id)
+			n++
+		}
+	}
+	return n
+}
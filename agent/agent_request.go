@@ -0,0 +1,259 @@
+package agent
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lilymona/gog/arraymap"
+	"github.com/lilymona/gog/message"
+	"github.com/lilymona/gog/node"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// RequestHandler is the request handler. It returns the reply
+// to the request, or nil if the node does not reply.
+type RequestHandler func([]byte) []byte
+
+// pendingRequests are the requests waiting for the replies,
+// keyed by the correlation ids.
+type pendingRequests struct {
+	sync.Mutex
+	m map[uint64]chan []byte
+}
+
+func newPendingRequests() *pendingRequests {
+	return &pendingRequests{m: make(map[uint64]chan []byte)}
+}
+
+// add() registers a request, the channel receives the first reply.
+func (pr *pendingRequests) add(reqId uint64) chan []byte {
+	pr.Lock()
+	defer pr.Unlock()
+	replyc := make(chan []byte, 1)
+	pr.m[reqId] = replyc
+	return replyc
+}
+
+// remove() deregisters a request.
+func (pr *pendingRequests) remove(reqId uint64) {
+	pr.Lock()
+	defer pr.Unlock()
+	delete(pr.m, reqId)
+}
+
+// deliver() delivers the reply to the request, and returns
+// false if the request is unknown or already replied.
+func (pr *pendingRequests) deliver(reqId uint64, payload []byte) bool {
+	pr.Lock()
+	defer pr.Unlock()
+	replyc, ok := pr.m[reqId]
+	if !ok {
+		return false
+	}
+	select {
+	case replyc <- payload:
+		return true
+	default:
+		return false
+	}
+}
+
+// Request broadcasts a request to the cluster, and returns the first
+// reply. It returns ErrRequestTimeout if no reply arrives in time.
+func (ag *agent) Request(payload []byte, timeout time.Duration) ([]byte, error) {
+	msg := &message.Request{
+		Id:      proto.Uint64(ag.id),
+		ReqId:   proto.Uint64(GenID()),
+		Addr:    proto.String(ag.addr),
+		Payload: payload,
+		Ts:      proto.Int64(time.Now().UnixNano()),
+	}
+	replyc := ag.requests.add(msg.GetReqId())
+	defer ag.requests.remove(msg.GetReqId())
+
+	// Do not handle the request when it comes back.
+	purgeDeadline := time.Now().UnixNano() + time.Millisecond.Nanoseconds()*int64(ag.config().PurgeDuration)
+	ag.msgCache.add(hashRequest(msg.GetReqId()), purgeDeadline)
+
+	ag.viewMu.RLock()
+	for _, nd := range ag.aView.Values() {
+		go ag.request(nd, msg)
+	}
+	ag.viewMu.RUnlock()
+
+	select {
+	case reply := <-replyc:
+		return reply, nil
+	case <-time.After(timeout):
+		return nil, ErrRequestTimeout
+	}
+}
+
+// RequestNode sends a request to the node of the id, and returns its
+// reply. The node is dialed directly at its address in the views, so
+// it returns ErrNodeNotFound if the node is in neither view, and
+// ErrNoReply if the node does not reply.
+func (ag *agent) RequestNode(id uint64, payload []byte, timeout time.Duration) ([]byte, error) {
+	var addr string
+	ag.viewMu.RLock()
+	for _, view := range []*arraymap.OrderedMap[uint64, *node.Node]{ag.aView, ag.pView} {
+		if view.Has(id) {
+			addr = view.GetValueOf(id).Addr
+			break
+		}
+	}
+	ag.viewMu.RUnlock()
+	if addr == "" {
+		return nil, ErrNodeNotFound
+	}
+
+	conn, err := ag.dial(addr)
+	if err != nil {
+		ag.logger.Errorf("Agent.RequestNode(): Failed to connect %s: %v\n", addr, err)
+		return nil, err
+	}
+	msg := &message.Request{
+		Id:      proto.Uint64(ag.id),
+		ReqId:   proto.Uint64(GenID()),
+		Addr:    proto.String(ag.addr),
+		Payload: payload,
+		Ts:      proto.Int64(time.Now().UnixNano()),
+		Target:  proto.Uint64(id),
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	if err := ag.codec.WriteMsg(msg, conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reply, err := ag.codec.ReadMsg(conn)
+	if err != nil {
+		conn.Close()
+		if isTimeout(err) {
+			return nil, ErrRequestTimeout
+		}
+		return nil, ErrNoReply
+	}
+	if r, ok := reply.(*message.Reply); !ok || r.GetReqId() != msg.GetReqId() {
+		conn.Close()
+		return nil, ErrInvalidMessageType
+	}
+	conn.SetDeadline(time.Time{})
+	ag.pool.put(addr, conn)
+	return reply.(*message.Reply).GetPayload(), nil
+}
+
+// RegisterRequestHandler registers a user provided callback
+// to reply the requests.
+func (ag *agent) RegisterRequestHandler(rh RequestHandler) {
+	ag.reqHandler = rh
+}
+
+// request() sends a Request message to the node.
+func (ag *agent) request(node *node.Node, msg *message.Request) {
+	if err := ag.codec.WriteMsg(msg, node); err != nil {
+		ag.logger.Errorf("Agent.request(): Write msg error: %v\n", err)
+		node.Close()
+	}
+}
+
+// reply() sends a Reply message to the origin of the request.
+func (ag *agent) reply(msg *message.Request, payload []byte) error {
+	conn, err := ag.connect(msg.GetAddr())
+	if err != nil {
+		ag.logger.Errorf("Agent.reply(): Failed to connect %s: %v\n", msg.GetAddr(), err)
+		return err
+	}
+	defer conn.Close()
+	reply := &message.Reply{
+		Id:      proto.Uint64(ag.id),
+		ReqId:   proto.Uint64(msg.GetReqId()),
+		Payload: payload,
+	}
+	return ag.codec.WriteMsg(reply, conn)
+}
+
+// handleRequest() handles Request message. It will invoke the request
+// handler, send back the reply if any, and forward the request to the
+// active view.
+func (ag *agent) handleRequest(from *node.Node, msg *message.Request) {
+	// Test if the request is stale.
+	deadline := msg.GetTs() + time.Millisecond.Nanoseconds()*int64(ag.config().MLife)
+	now := time.Now().UnixNano()
+	if now >= deadline {
+		ag.logger.Debugf("Request is too old, deadline: %v, now %v\n", deadline, now)
+		return
+	}
+
+	// Test if the request has been already received,
+	// unless the purge deadline of the entry has passed.
+	hash := hashRequest(msg.GetReqId())
+	purgeDeadline := now + time.Millisecond.Nanoseconds()*int64(ag.config().PurgeDuration)
+	if !ag.msgCache.addIfAbsent(hash, purgeDeadline, now) {
+		ag.logger.Debugf("Request is alread received, and with purge deadline, hash: %v\n", hash)
+		return
+	}
+
+	// Invoke user's request handler.
+	if rh := ag.reqHandler; rh != nil && !ag.stopped() {
+		atomic.AddInt32(&ag.stats.handlers, 1)
+		go func() {
+			defer atomic.AddInt32(&ag.stats.handlers, -1)
+			if payload := rh(msg.GetPayload()); payload != nil {
+				ag.reply(msg, payload)
+			}
+		}()
+	}
+
+	ag.viewMu.RLock()
+	defer ag.viewMu.RUnlock()
+
+	for _, nd := range ag.aView.Values() {
+		if nd.Id != from.Id {
+			go ag.request(nd, msg)
+		}
+	}
+}
+
+// handleDirectRequest() replies the Request sent to the agent over the
+// connection. It returns false if the request is not for the agent,
+// or the request handler does not reply.
+func (ag *agent) handleDirectRequest(conn net.Conn, msg *message.Request) bool {
+	rh := ag.reqHandler
+	if msg.GetTarget() != ag.id || rh == nil {
+		return false
+	}
+	payload := rh(msg.GetPayload())
+	if payload == nil {
+		return false
+	}
+	reply := &message.Reply{
+		Id:      proto.Uint64(ag.id),
+		ReqId:   proto.Uint64(msg.GetReqId()),
+		Payload: payload,
+	}
+	if err := ag.codec.WriteMsg(reply, conn); err != nil {
+		ag.logger.Errorf("Agent.handleDirectRequest(): Write msg error: %v\n", err)
+		return false
+	}
+	return true
+}
+
+// handleReply() handles Reply message.
+func (ag *agent) handleReply(msg *message.Reply) {
+	if !ag.requests.deliver(msg.GetReqId(), msg.GetPayload()) {
+		ag.logger.Debugf("Drop the reply to request %v\n", msg.GetReqId())
+	}
+}
+
+// hashRequest() returns the hash of a request, which is distinct
+// from the hashes of the user messages.
+func hashRequest(reqId uint64) [sha1.Size]byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, reqId)
+	return sha1.Sum(append([]byte("request:"), b...))
+}
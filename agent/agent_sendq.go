@@ -0,0 +1,235 @@
+package agent
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lilymona/gog/config"
+	"github.com/lilymona/gog/node"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// sendQueue queues the messages to each neighbor, which are written
+// by a single goroutine per connection, so a slow neighbor neither holds
+// the senders, nor makes the fan-out pile up goroutines.
+type sendQueue struct {
+	mu sync.Mutex
+	// room is signaled when a message is taken off a queue,
+	// or the agent is closed, for the senders blocked on it.
+	room *sync.Cond
+	// The pending messages of each node, keyed by the node, as a new
+	// connection of the same id is a new node. A node is in the map
+	// as long as a goroutine is writing its messages.
+	queues map[*node.Node]*peerQueue
+}
+
+// peerQueue is the pending messages to a node in two priority classes.
+// The control messages, which keep the overlay up, are written before
+// the user messages, so they are not starved behind the bulk payloads.
+// They are neither bounded by the queue size nor throttled, as they
+// are few and small.
+type peerQueue struct {
+	control []*controlItem
+	user    []proto.Message
+	// wake is signaled when a control message is queued, for the
+	// goroutine waiting to batch or to throttle the user messages.
+	wake chan struct{}
+}
+
+// controlItem is a queued control message, and the
+// channel of the result of its write.
+type controlItem struct {
+	msg  proto.Message
+	done chan error
+}
+
+func newSendQueue() *sendQueue {
+	q := &sendQueue{queues: make(map[*node.Node]*peerQueue)}
+	q.room = sync.NewCond(&q.mu)
+	return q
+}
+
+// queue() returns the queue of the node, and whether a goroutine
+// is writing it. It creates the queue if there is none.
+func (q *sendQueue) queue(node *node.Node) (*peerQueue, bool) {
+	pq, running := q.queues[node]
+	if !running {
+		pq = &peerQueue{wake: make(chan struct{}, 1)}
+		q.queues[node] = pq
+	}
+	return pq, running
+}
+
+// wakeup() wakes up the blocked senders.
+func (q *sendQueue) wakeup() {
+	q.mu.Lock()
+	q.room.Broadcast()
+	q.mu.Unlock()
+}
+
+// userMessage() queues a user message to the node. If the queue is full,
+// the message is dropped, or it waits for the room if the policy is block,
+// so the view lock must not be held.
+func (ag *agent) userMessage(node *node.Node, msg proto.Message) {
+	sq := ag.sendq
+	sq.mu.Lock()
+	pq := sq.queues[node]
+	for ag.config().SendQueueSize > 0 && pq != nil && len(pq.user) >= ag.config().SendQueueSize {
+		if ag.config().SendQueuePolicy != config.SendQueueBlock || ag.stopped() {
+			sq.mu.Unlock()
+			ag.sampledLogger.Warningf("Agent.userMessage(): Send queue of node %v is full, drop the message\n", node)
+			atomic.AddUint64(&ag.stats.sendDrops, 1)
+			return
+		}
+		sq.room.Wait()
+		pq = sq.queues[node]
+	}
+	pq, running := sq.queue(node)
+	pq.user = append(pq.user, msg)
+	sq.mu.Unlock()
+
+	if !running {
+		go ag.sendLoop(node)
+	}
+}
+
+// controlMessage() queues a control message to the node ahead of the
+// user messages, and returns the channel of the result of the write,
+// for the senders which wait for it.
+func (ag *agent) controlMessage(node *node.Node, msg proto.Message) <-chan error {
+	c := &controlItem{msg: msg, done: make(chan error, 1)}
+	sq := ag.sendq
+	sq.mu.Lock()
+	pq, running := sq.queue(node)
+	pq.control = append(pq.control, c)
+	sq.mu.Unlock()
+
+	select {
+	case pq.wake <- struct{}{}:
+	default:
+	}
+	if !running {
+		go ag.sendLoop(node)
+	}
+	return c.done
+}
+
+// len() returns the number of the user messages queued to the node.
+func (q *sendQueue) len(node *node.Node) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if pq := q.queues[node]; pq != nil {
+		return len(pq.user)
+	}
+	return 0
+}
+
+// wakec() returns the channel signaled when a control
+// message is queued to the node, nil if there is no queue.
+func (q *sendQueue) wakec(node *node.Node) chan struct{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if pq := q.queues[node]; pq != nil {
+		return pq.wake
+	}
+	return nil
+}
+
+// takeControl() takes the control messages queued to the node.
+func (q *sendQueue) takeControl(node *node.Node) []*controlItem {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	pq := q.queues[node]
+	if pq == nil {
+		return nil
+	}
+	cs := pq.control
+	pq.control = nil
+	return cs
+}
+
+// take() takes the control messages, and up to max user messages queued
+// to the node, or returns nil and forgets the node if there is none left.
+func (q *sendQueue) take(node *node.Node, max int) ([]*controlItem, []proto.Message) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	pq := q.queues[node]
+	if pq == nil || len(pq.control)+len(pq.user) == 0 {
+		delete(q.queues, node)
+		return nil, nil
+	}
+	cs := pq.control
+	pq.control = nil
+	if len(pq.user) < max {
+		max = len(pq.user)
+	}
+	msgs := make([]proto.Message, max)
+	copy(msgs, pq.user)
+	for i := range msgs {
+		pq.user[i] = nil
+	}
+	pq.user = pq.user[max:]
+	q.room.Broadcast()
+	return cs, msgs
+}
+
+// writeControls() writes the control messages to the node, and closes
+// the connection if a write fails, as the callers of the direct writes did.
+func (ag *agent) writeControls(nd *node.Node, cs []*controlItem) {
+	for _, c := range cs {
+		atomic.AddUint64(&ag.stats.controlSends, 1)
+		err := ag.codec.WriteMsg(c.msg, nd)
+		if err != nil {
+			ag.logger.Errorf("Agent.writeControls(): Write %T error: %v\n", c.msg, err)
+			nd.Close()
+		}
+		c.done <- err
+	}
+}
+
+// sendWait() waits for the duration, or the agent to close, and
+// writes the control messages queued to the node meanwhile.
+func (ag *agent) sendWait(nd *node.Node, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	wake := ag.sendq.wakec(nd)
+	for {
+		select {
+		case <-timer.C:
+			return
+		case <-ag.stopc:
+			return
+		case <-wake:
+			ag.writeControls(nd, ag.sendq.takeControl(nd))
+		}
+	}
+}
+
+// sendLoop() writes the queued messages to the node until there is none
+// left, the control messages first. If the batching is enabled, and the
+// node reads the batches, the user messages queued within the batch
+// interval are written in one Batch.
+func (ag *agent) sendLoop(nd *node.Node) {
+	size := 1
+	if ag.config().BatchSize > 1 && nd.Caps&node.CapBatch != 0 {
+		size = ag.config().BatchSize
+	}
+	interval := time.Duration(ag.config().BatchInterval) * time.Millisecond
+	for {
+		if n := ag.sendq.len(nd); size > 1 && n > 0 && n < size && interval > 0 {
+			ag.sendWait(nd, interval)
+		}
+		cs, msgs := ag.sendq.take(nd, size)
+		if len(cs) == 0 && len(msgs) == 0 {
+			return
+		}
+		ag.writeControls(nd, cs)
+		if len(msgs) == 0 {
+			continue
+		}
+		ag.throttleSend(nd, msgs)
+		ag.writeBatches(nd, msgs)
+	}
+}
@@ -0,0 +1,302 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lilymona/gog/config"
+	log "github.com/lilymona/gog/logging"
+	"github.com/lilymona/gog/message"
+	"github.com/lilymona/gog/node"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+var (
+	ErrTooManySettings = errors.New("Too many settings")
+	ErrSettingTooLarge = errors.New("Setting too large")
+)
+
+const (
+	// maxSettings bounds the keys of the cluster settings.
+	maxSettings = 256
+	// maxSettingSize bounds the size of the key and the value of a setting.
+	maxSettingSize = 4096
+	// logLevelKey is the setting of the log level, e.g. "info".
+	logLevelKey = "log_level"
+)
+
+// SettingsHandler is the callback of the cluster settings that change,
+// set on this agent or learned from the peers, keyed by the keys.
+type SettingsHandler func(changed map[string]string)
+
+// settings is the store of the cluster settings. Each setting has the
+// time and the origin of its last write, and the writes are merged by
+// the last writer wins, so the stores converge as the changes gossip
+// through the overlay.
+type settings struct {
+	sync.Mutex
+	m map[string]*message.Setting
+	// The callbacks of the changes.
+	handlers []SettingsHandler
+	// applyMu serializes applying the settings to the config,
+	// so the last write is applied last.
+	applyMu sync.Mutex
+}
+
+func newSettings() *settings {
+	return &settings{m: make(map[string]*message.Setting)}
+}
+
+// newer() returns true if the write of a is after the write of b,
+// by the time, and by the origin if the times are the same.
+func newer(a, b *message.Setting) bool {
+	if a.GetTs() != b.GetTs() {
+		return a.GetTs() > b.GetTs()
+	}
+	return a.GetOrigin() > b.GetOrigin()
+}
+
+// all() returns all the settings.
+func (s *settings) all() []*message.Setting {
+	s.Lock()
+	defer s.Unlock()
+	all := make([]*message.Setting, 0, len(s.m))
+	for _, st := range s.m {
+		all = append(all, st)
+	}
+	return all
+}
+
+// Settings returns the cluster settings.
+func (ag *agent) Settings() map[string]string {
+	m := make(map[string]string)
+	for _, st := range ag.settings.all() {
+		m[st.GetKey()] = st.GetValue()
+	}
+	return m
+}
+
+// SetSettings changes the cluster settings on this agent, and gossips
+// them to the rest of the cluster. The tunables of the config and the
+// "log_level" are applied, so the settings are rejected if this agent
+// cannot apply them, and so are the other fields of the config. The
+// other keys are only stored.
+func (ag *agent) SetSettings(values map[string]string) error {
+	for k, v := range values {
+		if len(k)+len(v) > maxSettingSize {
+			return fmt.Errorf("%w: %.64s", ErrSettingTooLarge, k)
+		}
+		if config.Fixed(k) {
+			return fmt.Errorf("%w: %s", config.ErrNotReloadable, k)
+		}
+	}
+	if _, _, err := ag.settingsConfig(values); err != nil {
+		return err
+	}
+
+	now := time.Now().UnixNano()
+	var changes []*message.Setting
+	ag.settings.Lock()
+	added := 0
+	for k := range values {
+		if _, ok := ag.settings.m[k]; !ok {
+			added++
+		}
+	}
+	if len(ag.settings.m)+added > maxSettings {
+		ag.settings.Unlock()
+		return ErrTooManySettings
+	}
+	for k, v := range values {
+		ts := now
+		// The change is after the write it replaces, even
+		// if the clock of its origin is ahead.
+		if st, ok := ag.settings.m[k]; ok && st.GetTs() >= ts {
+			ts = st.GetTs() + 1
+		}
+		changes = append(changes, &message.Setting{
+			Key:    proto.String(k),
+			Value:  proto.String(v),
+			Ts:     proto.Int64(ts),
+			Origin: proto.Uint64(ag.id),
+		})
+	}
+	ag.settings.Unlock()
+	ag.mergeSettings(nil, changes)
+	return nil
+}
+
+// RegisterSettingsHandler registers a user provided callback, which is
+// invoked when the cluster settings change. The callbacks are invoked
+// in the order they are registered.
+func (ag *agent) RegisterSettingsHandler(h SettingsHandler) {
+	ag.settings.Lock()
+	defer ag.settings.Unlock()
+	ag.settings.handlers = append(ag.settings.handlers, h)
+}
+
+// handleSettings() handles Settings message.
+func (ag *agent) handleSettings(from *node.Node, msg *message.Settings) {
+	ag.mergeSettings(from, msg.GetSettings())
+}
+
+// mergeSettings() merges the settings written by the node, nil for this
+// agent, applies and gossips the ones that change to the other neighbors.
+// The callbacks are invoked with the changes in the order they are merged.
+func (ag *agent) mergeSettings(from *node.Node, in []*message.Setting) {
+	changed := ag.storeSettings(from, in)
+	if len(changed) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(changed))
+	for _, st := range changed {
+		keys = append(keys, st.GetKey())
+	}
+	ag.applySettings(keys)
+}
+
+// storeSettings() stores the settings written by the node, and gossips
+// the ones that change, which it returns. The fields of the config that
+// cannot be changed at runtime are dropped, and so are the settings too
+// large, or the new keys beyond maxSettings, so a peer cannot fill the
+// store.
+func (ag *agent) storeSettings(from *node.Node, in []*message.Setting) []*message.Setting {
+	// The view lock is taken first, as addNodeActiveView() reads the
+	// settings with the view lock held.
+	ag.viewMu.RLock()
+	defer ag.viewMu.RUnlock()
+	ag.settings.Lock()
+	defer ag.settings.Unlock()
+	var changed []*message.Setting
+	for _, st := range in {
+		k := st.GetKey()
+		if k == "" {
+			continue
+		}
+		if len(k)+len(st.GetValue()) > maxSettingSize || config.Fixed(k) {
+			ag.sampledLogger.Warningf("Agent.storeSettings(): Drop the setting %.64q from %v\n", k, from)
+			continue
+		}
+		old, ok := ag.settings.m[k]
+		if ok && !newer(st, old) {
+			continue
+		}
+		if !ok && len(ag.settings.m) >= maxSettings {
+			ag.sampledLogger.Warningf("Agent.storeSettings(): Too many settings, drop %.64q from %v\n", k, from)
+			continue
+		}
+		ag.settings.m[k] = st
+		changed = append(changed, st)
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	msg := &message.Settings{Id: proto.Uint64(ag.id), Settings: changed}
+	for _, nd := range ag.aView.Values() {
+		if nd != from && nd.Caps&node.CapSettings != 0 {
+			ag.controlMessage(nd, msg)
+		}
+	}
+
+	for _, h := range ag.settings.handlers {
+		h := h
+		m := make(map[string]string, len(changed))
+		for _, st := range changed {
+			m[st.GetKey()] = st.GetValue()
+		}
+		ag.events.dispatch(0, func() { h(m) })
+	}
+	return changed
+}
+
+// applySettings() applies the tunables and the log level in the current
+// values of the settings of the keys.
+func (ag *agent) applySettings(keys []string) {
+	ag.settings.applyMu.Lock()
+	defer ag.settings.applyMu.Unlock()
+	values := make(map[string]string, len(keys))
+	ag.settings.Lock()
+	for _, k := range keys {
+		if st, ok := ag.settings.m[k]; ok {
+			values[k] = st.GetValue()
+		}
+	}
+	ag.settings.Unlock()
+
+	cfg, level, err := ag.settingsConfig(values)
+	if err == nil && cfg != nil {
+		err = ag.Reconfigure(cfg)
+	}
+	if err != nil {
+		ag.logger.Errorf("Agent.applySettings(): Failed to apply the settings: %v\n", err)
+		return
+	}
+	if level != nil {
+		log.SetLevel(*level)
+	}
+}
+
+// settingsConfig() returns the config updated with the tunables in the
+// settings, and the log level in "log_level", nil if they are not set.
+// The values which are not JSON are strings.
+func (ag *agent) settingsConfig(values map[string]string) (*config.Config, *int, error) {
+	obj := make(map[string]json.RawMessage)
+	for k, v := range values {
+		if k != logLevelKey && !config.Tunable(k) {
+			continue
+		}
+		raw := json.RawMessage(v)
+		if !json.Valid(raw) {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, nil, err
+			}
+			raw = b
+		}
+		obj[k] = raw
+	}
+	if len(obj) == 0 {
+		return nil, nil, nil
+	}
+
+	var level *int
+	if raw, ok := obj[logLevelKey]; ok {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, nil, err
+		}
+		l, err := log.ParseLevel(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		level = &l
+	}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, err := ag.config().Update(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, level, nil
+}
+
+// syncSettings() sends all the settings to a new neighbor,
+// which keeps the ones it has not seen. It is called with
+// the view lock held.
+func (ag *agent) syncSettings(nd *node.Node) {
+	if nd.Caps&node.CapSettings == 0 {
+		return
+	}
+	all := ag.settings.all()
+	if len(all) == 0 {
+		return
+	}
+	ag.controlMessage(nd, &message.Settings{Id: proto.Uint64(ag.id), Settings: all})
+}
@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/lilymona/gog/config"
+	"github.com/lilymona/gog/message"
+)
+
+// SignatureStatus is the result of verifying the signature of a user message.
+type SignatureStatus int
+
+const (
+	// Unsigned is a message without a signature.
+	Unsigned SignatureStatus = iota
+	// Verified is a message signed with a trusted key.
+	Verified
+	// UnknownKey is a message signed with a key that is not trusted.
+	UnknownKey
+	// BadSignature is a message whose signature does not match the key.
+	BadSignature
+)
+
+func (s SignatureStatus) String() string {
+	switch s {
+	case Unsigned:
+		return "unsigned"
+	case Verified:
+		return "verified"
+	case UnknownKey:
+		return "unknown key"
+	case BadSignature:
+		return "bad signature"
+	}
+	return "unknown"
+}
+
+// DeliveredMessage is a delivered user message, with the result of
+// verifying its signature.
+type DeliveredMessage struct {
+	Message
+	// The fingerprint of the key that signed the message, nil if unsigned.
+	KeyFingerprint []byte
+	// The result of verifying the signature.
+	Signature SignatureStatus
+}
+
+// DeliveredMessageHandler is the handler of the delivered messages.
+type DeliveredMessageHandler func(DeliveredMessage)
+
+// keyring is the trusted keys by their fingerprints.
+type keyring map[string]ed25519.PublicKey
+
+// newKeyring() creates the keyring of the trusted keys,
+// and the key of the agent itself.
+func newKeyring(cfg *config.Config) keyring {
+	kr := make(keyring)
+	for _, key := range cfg.TrustedKeys {
+		kr[string(fingerprint(key))] = key
+	}
+	if cfg.SigningKey != nil {
+		key := cfg.SigningKey.Public().(ed25519.PublicKey)
+		kr[string(fingerprint(key))] = key
+	}
+	return kr
+}
+
+// fingerprint() returns the fingerprint of the key, which is the
+// first 8 bytes of its SHA-256 hash.
+func fingerprint(key ed25519.PublicKey) []byte {
+	h := sha256.Sum256(key)
+	return h[:8]
+}
+
+// signedData() returns the signed bytes of the message, which are the
+// payload followed by the timestamp, the sender, the topic and the life,
+// and the chunk fields if any. The relays do not change any of them, as
+// opposed to the hops, the trace and the seq, which are not signed.
+func signedData(msg *message.UserMessage) []byte {
+	b := make([]byte, len(msg.GetPayload())+8, len(msg.GetPayload())+64+len(msg.GetTopic()))
+	copy(b, msg.GetPayload())
+	binary.BigEndian.PutUint64(b[len(msg.GetPayload()):], uint64(msg.GetTs()))
+	b = binary.BigEndian.AppendUint64(b, msg.GetId())
+	b = binary.BigEndian.AppendUint32(b, uint32(len(msg.GetTopic())))
+	b = append(b, msg.GetTopic()...)
+	// The life is signed with whether it is set, as
+	// the receivers use their MLife if it is not.
+	if msg.Life != nil {
+		b = append(b, 1)
+		b = binary.BigEndian.AppendUint64(b, uint64(msg.GetLife()))
+	} else {
+		b = append(b, 0)
+	}
+	// The chunk is signed with its place in the payload, and the hash
+	// of the payload, so the chunks cannot be reordered or mixed.
+	if msg.ChunkCount != nil {
+		b = binary.BigEndian.AppendUint64(b, msg.GetChunkId())
+		b = binary.BigEndian.AppendUint32(b, msg.GetChunkIndex())
+		b = binary.BigEndian.AppendUint32(b, msg.GetChunkCount())
+		b = append(b, msg.GetChunkHash()...)
+	}
+	// The sequence number is signed with the sender and its
+	// epoch, so a replayed message cannot be renumbered.
+	if msg.OriginSeq != nil {
+		b = binary.BigEndian.AppendUint64(b, msg.GetOriginSeq())
+		b = binary.BigEndian.AppendUint64(b, msg.GetOriginEpoch())
+	}
+	return b
+}
+
+// sign() signs the message with the key of the agent, if it has one.
+func (ag *agent) sign(msg *message.UserMessage) {
+	key := ag.config().SigningKey
+	if key == nil {
+		return
+	}
+	msg.Key = fingerprint(key.Public().(ed25519.PublicKey))
+	msg.Sig = ed25519.Sign(key, signedData(msg))
+}
+
+// verify() verifies the signature of the message.
+func (ag *agent) verify(msg *message.UserMessage) SignatureStatus {
+	if msg.Key == nil && msg.Sig == nil {
+		return Unsigned
+	}
+	key, ok := ag.keys[string(msg.GetKey())]
+	if !ok {
+		return UnknownKey
+	}
+	if !ed25519.Verify(key, signedData(msg), msg.GetSig()) {
+		return BadSignature
+	}
+	return Verified
+}
+
+// RegisterDeliveredMessageHandler registers a user provided callback to
+// handle the messages with the results of verifying their signatures,
+// which is invoked instead of the message and the topic handlers.
+func (ag *agent) RegisterDeliveredMessageHandler(dh DeliveredMessageHandler) {
+	ag.deliveredHandler = dh
+}
@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/lilymona/gog/message"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+var ErrSpoolRecordTooLarge = errors.New("Spool record too large")
+
+// spoolLoop() saves the failed messages to the spool file when they
+// change. The changes made while saving are saved together next time.
+func (ag *agent) spoolLoop() {
+	if ag.config().SpoolFile == "" {
+		return
+	}
+	for {
+		select {
+		case <-ag.spoolc:
+			if err := ag.saveSpool(); err != nil {
+				ag.logger.Errorf("Agent.spoolLoop(): Failed to save spool: %v\n", err)
+			}
+		case <-ag.stopc:
+			return
+		}
+	}
+}
+
+// spoolChanged() wakes up the spool loop to save the failed messages.
+func (ag *agent) spoolChanged() {
+	if ag.config().SpoolFile == "" {
+		return
+	}
+	select {
+	case ag.spoolc <- struct{}{}:
+	default:
+	}
+}
+
+// saveSpool() writes the failed messages to the spool file, oldest first,
+// each prefixed with its length as a uvarint. The oldest messages beyond
+// the size of the spool are dropped from the buffer too. The file is
+// written to a temporary file first, and then renamed, as the state file,
+// and removed when there is no failed message.
+func (ag *agent) saveSpool() error {
+	cfg := ag.config()
+	if cfg.SpoolFile == "" {
+		return nil
+	}
+	ag.spoolMu.Lock()
+	defer ag.spoolMu.Unlock()
+
+	msgs := ag.failmsgBuffer.Snapshot()
+	if len(msgs) == 0 {
+		if err := os.Remove(cfg.SpoolFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	sort.SliceStable(msgs, func(i, j int) bool { return msgs[i].GetTs() < msgs[j].GetTs() })
+
+	// Keep the newest messages that fit.
+	records := make([][]byte, len(msgs))
+	size, first := 0, len(msgs)
+	for i := len(msgs) - 1; i >= 0; i-- {
+		b, err := proto.Marshal(msgs[i])
+		if err != nil {
+			return err
+		}
+		record := binary.AppendUvarint(nil, uint64(len(b)))
+		record = append(record, b...)
+		if size+len(record) > cfg.SpoolSize {
+			break
+		}
+		records[i], size, first = record, size+len(record), i
+	}
+	for _, msg := range msgs[:first] {
+		ag.failmsgBuffer.Remove(hashUserMessage(msg))
+	}
+	if first > 0 {
+		ag.logger.Warningf("Agent.saveSpool(): Drop %d failed messages beyond the spool size\n", first)
+		atomic.AddUint64(&ag.stats.spoolDropped, uint64(first))
+	}
+
+	dir, base := filepath.Split(cfg.SpoolFile)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := ioutil.TempFile(dir, base+".tmp")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, record := range records[first:] {
+		w.Write(record)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), cfg.SpoolFile)
+}
+
+// loadSpool() buffers the unexpired messages in the spool file to resend
+// them once the agent has a neighbor. A corrupt record and the ones after
+// it are ignored. The messages of this agent are numbered and signed again
+// in the current epoch, as the receivers drop the earlier epochs.
+func (ag *agent) loadSpool() {
+	cfg := ag.config()
+	if cfg.SpoolFile == "" {
+		return
+	}
+	f, err := os.Open(cfg.SpoolFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			ag.logger.Warningf("Agent.loadSpool(): Failed to read spool: %v\n", err)
+		}
+		return
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	now := time.Now().UnixNano()
+	for {
+		msg, err := readSpoolRecord(r, cfg.MaxMessageSize)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			ag.logger.Warningf("Agent.loadSpool(): Ignore corrupt spool file %s: %v\n", cfg.SpoolFile, err)
+			break
+		}
+		if now >= ag.messageDeadline(msg) {
+			continue
+		}
+		if msg.GetId() == ag.id {
+			msg.OriginSeq = proto.Uint64(ag.replay.next())
+			msg.OriginEpoch = proto.Uint64(ag.replay.epoch)
+			ag.sign(msg)
+		}
+		ag.bufferFailedMessage(msg)
+	}
+	ag.logger.Infof("Agent.loadSpool(): Recovered %d failed messages\n", ag.failmsgBuffer.Len())
+}
+
+// readSpoolRecord() reads a message prefixed with its length, of at most
+// max bytes if max is positive.
+func readSpoolRecord(r *bufio.Reader, max int) (*message.UserMessage, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if max > 0 && n > uint64(max) {
+		return nil, ErrSpoolRecordTooLarge
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	msg := &message.UserMessage{}
+	if err := proto.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
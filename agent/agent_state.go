@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lilymona/gog/node"
+)
+
+// stateLoop() periodically saves the views to the state file.
+func (ag *agent) stateLoop() {
+	if ag.config().StateFile == "" || ag.config().ShuffleDuration <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(ag.config().ShuffleDuration) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := ag.saveState(); err != nil {
+				ag.logger.Errorf("Agent.stateLoop(): Failed to save state: %v\n", err)
+			}
+		case <-ag.stopc:
+			return
+		}
+	}
+}
+
+// saveState() writes the nodes in the active view and the passive view to
+// the state file. The file is written to a temporary file first, and then
+// renamed, so a crash will not leave a partial file behind. The empty
+// views are not written, so the last known nodes survive losing the peers.
+func (ag *agent) saveState() error {
+	if ag.config().StateFile == "" {
+		return nil
+	}
+	ag.viewMu.RLock()
+	nodes := make([]*node.Node, 0, ag.aView.Len()+ag.pView.Len())
+	for _, nd := range append(ag.aView.Snapshot(), ag.pView.Values()...) {
+		nodes = append(nodes, &node.Node{Id: nd.Id, Addr: nd.Addr, Labels: nd.Labels})
+	}
+	ag.viewMu.RUnlock()
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	b, err := json.Marshal(nodes)
+	if err != nil {
+		return err
+	}
+	dir, base := filepath.Split(ag.config().StateFile)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := ioutil.TempFile(dir, base+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), ag.config().StateFile)
+}
+
+// loadState() adds the nodes in the state file to the passive view.
+// A missing or corrupt state file is ignored.
+func (ag *agent) loadState() {
+	if ag.config().StateFile == "" {
+		return
+	}
+	b, err := ioutil.ReadFile(ag.config().StateFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			ag.logger.Warningf("Agent.loadState(): Failed to read state: %v\n", err)
+		}
+		return
+	}
+	var nodes []*node.Node
+	if err := json.Unmarshal(b, &nodes); err != nil {
+		ag.logger.Warningf("Agent.loadState(): Ignore corrupt state file %s: %v\n", ag.config().StateFile, err)
+		return
+	}
+
+	ag.viewMu.Lock()
+	defer ag.viewMu.Unlock()
+	for _, nd := range nodes {
+		if nd.Id == 0 || nd.Addr == "" || nd.Addr == ag.addr {
+			continue
+		}
+		ag.addNodePassiveView(&node.Node{Id: nd.Id, Addr: nd.Addr, Labels: nd.Labels})
+	}
+	ag.logger.Infof("Agent.loadState(): Recovered %d nodes in passive view\n", ag.pView.Len())
+}
+
+// restore() joins through the nodes recovered from the state file right
+// away, instead of waiting for the heal loop. The configured peers are
+// only tried if none of them accepts.
+func (ag *agent) restore() {
+	ag.viewMu.RLock()
+	recovered := ag.pView.Len() > 0 && ag.aView.Len() == 0
+	ag.viewMu.RUnlock()
+	if !recovered {
+		return
+	}
+	if err := ag.joinCluster(ag.bootstrapPeers()); err != nil {
+		ag.logger.Warningf("Agent.restore(): Failed to rejoin: %v\n", err)
+	}
+}
+
+// bootstrapPeers() returns the addresses of the nodes in the passive
+// view, followed by the configured peers in random order.
+func (ag *agent) bootstrapPeers() []string {
+	var peers []string
+	ag.viewMu.RLock()
+	for _, nd := range ag.pView.Values() {
+		peers = append(peers, nd.Addr)
+	}
+	ag.viewMu.RUnlock()
+	return append(peers, ag.config().ShufflePeers(ag.rng)...)
+}
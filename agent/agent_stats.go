@@ -0,0 +1,233 @@
+package agent
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// stats are the runtime counters and gauges of the agent,
+// updated atomically. The 64-bit counters come first to be
+// 64-bit aligned, as the atomic operations require.
+type stats struct {
+	// The number of messages broadcast by the agent.
+	broadcasts uint64
+	// The numbers of the bytes read from and written to the
+	// connections and the datagrams of the peers.
+	bytesIn  uint64
+	bytesOut uint64
+	// The number of the shuffles started by the agent.
+	shuffles uint64
+	// The number of the rounds of joining the peers
+	// again after losing all of them.
+	rejoins uint64
+	// The number of user messages received from the peers.
+	received uint64
+	// The number of received messages dropped as duplicates.
+	duplicates uint64
+	// The number of received messages dropped as stale.
+	stale uint64
+	// The number of attempts to join the peers.
+	joins uint64
+	// The number of the user messages sent to the peers.
+	payloadSends uint64
+	// The number of messages failed to send to a peer.
+	failedSends uint64
+	// The number of messages dropped as the send queue was full.
+	sendDrops uint64
+	// The number of the control messages sent through the send
+	// queues, ahead of the user messages.
+	controlSends uint64
+	// The number of the batches of user messages sent.
+	batches uint64
+	// The number of failed messages resent.
+	resent uint64
+	// The number of messages sent again as they were not
+	// acknowledged, and given up, in the reliable mode.
+	retransmits uint64
+	undelivered uint64
+	// The number of the idle connections reused from the pool.
+	reusedConns uint64
+	// The number of the neighbors removed as they missed the pongs.
+	pingFailures uint64
+	// The number of the connections rejected as the peers are
+	// not authenticated with the cluster secret.
+	authFailures uint64
+	// The number of the Join, Neighbor and Shuffle messages
+	// rejected as they are from other clusters.
+	clusterMismatches uint64
+	// The number of the user messages dropped as they are
+	// not signed with a trusted key.
+	unverified uint64
+	// The number of the user messages dropped as their
+	// sequence numbers are received already.
+	replayed uint64
+	// The number of the user messages buffered to deliver after the
+	// earlier ones of the sender, the sequence numbers skipped as
+	// they are not received in time, and the messages dropped as
+	// they arrived after they are skipped.
+	fifoBuffered uint64
+	fifoSkipped  uint64
+	fifoLate     uint64
+	// The number of the failed messages dropped
+	// as they do not fit in the spool file.
+	spoolDropped uint64
+	// The number of times the agent gave up joining the peers again.
+	joinsAbandoned uint64
+	// The number of the Join and Neighbor requests rejected as
+	// the id is of another node.
+	idCollisions uint64
+	// The number of the chunks dropped before their payloads
+	// are reassembled.
+	chunksDropped uint64
+	// The number of the user messages delayed by the rate limits.
+	throttled uint64
+	// The numbers of the datagrams sent and received.
+	packetsSent     uint64
+	packetsReceived uint64
+	// The number of the partitions suspected, and of
+	// the rounds of joins to merge them.
+	partitionsSuspected uint64
+	mergeAttempts       uint64
+	// The time in unix nanoseconds when the agent started serving,
+	// of the last shuffle reply, and of the last user message delivered.
+	started      int64
+	lastShuffle  int64
+	lastDelivery int64
+	// The number of accepted connections waiting for a handler.
+	queuedConns int32
+	// The number of connections being handled.
+	handlingConns int32
+	// The number of the user callbacks being run.
+	handlers int32
+}
+
+// Stats is the snapshot of the runtime counters and gauges of the agent.
+// The user messages sent to the peers are counted by PayloadSends, the
+// ones received by Received, and the duplicates of them by Duplicates.
+type Stats struct {
+	Broadcasts        uint64 `json:"broadcasts"`
+	BytesIn           uint64 `json:"bytes_in"`
+	BytesOut          uint64 `json:"bytes_out"`
+	Shuffles          uint64 `json:"shuffles"`
+	Rejoins           uint64 `json:"rejoins"`
+	Received          uint64 `json:"received"`
+	Duplicates        uint64 `json:"duplicates"`
+	Stale             uint64 `json:"stale"`
+	Joins             uint64 `json:"joins"`
+	PayloadSends      uint64 `json:"payload_sends"`
+	FailedSends       uint64 `json:"failed_sends"`
+	SendDrops         uint64 `json:"send_drops"`
+	ControlSends      uint64 `json:"control_sends"`
+	Batches           uint64 `json:"batches"`
+	Resent            uint64 `json:"resent"`
+	Retransmits       uint64 `json:"retransmits"`
+	Undelivered       uint64 `json:"undelivered"`
+	ReusedConns       uint64 `json:"reused_conns"`
+	PingFailures      uint64 `json:"ping_failures"`
+	AuthFailures      uint64 `json:"auth_failures"`
+	ClusterMismatches uint64 `json:"cluster_mismatches"`
+	Unverified        uint64 `json:"unverified"`
+	Replayed          uint64 `json:"replayed"`
+	FIFOBuffered      uint64 `json:"fifo_buffered"`
+	FIFOSkipped       uint64 `json:"fifo_skipped"`
+	FIFOLate          uint64 `json:"fifo_late"`
+	SpoolDropped      uint64 `json:"spool_dropped"`
+	JoinsAbandoned    uint64 `json:"joins_abandoned"`
+	IDCollisions      uint64 `json:"id_collisions"`
+	ChunksDropped     uint64 `json:"chunks_dropped"`
+	Throttled         uint64 `json:"throttled"`
+	PacketsSent       uint64 `json:"udp_sent"`
+	PacketsRecv       uint64 `json:"udp_received"`
+	CacheHits         uint64 `json:"cache_hits"`
+	CacheMisses       uint64 `json:"cache_misses"`
+	CacheEvictions    uint64 `json:"cache_evictions"`
+	Partitions        uint64 `json:"partitions_suspected"`
+	MergeAttempts     uint64 `json:"merge_attempts"`
+	IdleConns         int    `json:"idle_conns"`
+	QueuedConns       int32  `json:"queued_conns"`
+	HandlingConns     int32  `json:"handling_conns"`
+	Handlers          int32  `json:"handlers"`
+	ActiveView        int    `json:"active_view"`
+	PassiveView       int    `json:"passive_view"`
+	// The milliseconds since the agent started serving, 0 if it has not.
+	Uptime int64 `json:"uptime_ms"`
+}
+
+// Stats returns the runtime counters and gauges of the agent.
+func (ag *agent) Stats() *Stats {
+	m := &Stats{
+		Broadcasts:        atomic.LoadUint64(&ag.stats.broadcasts),
+		BytesIn:           atomic.LoadUint64(&ag.stats.bytesIn),
+		BytesOut:          atomic.LoadUint64(&ag.stats.bytesOut),
+		Shuffles:          atomic.LoadUint64(&ag.stats.shuffles),
+		Rejoins:           atomic.LoadUint64(&ag.stats.rejoins),
+		Received:          atomic.LoadUint64(&ag.stats.received),
+		Duplicates:        atomic.LoadUint64(&ag.stats.duplicates),
+		Stale:             atomic.LoadUint64(&ag.stats.stale),
+		Joins:             atomic.LoadUint64(&ag.stats.joins),
+		PayloadSends:      atomic.LoadUint64(&ag.stats.payloadSends),
+		FailedSends:       atomic.LoadUint64(&ag.stats.failedSends),
+		SendDrops:         atomic.LoadUint64(&ag.stats.sendDrops),
+		ControlSends:      atomic.LoadUint64(&ag.stats.controlSends),
+		Batches:           atomic.LoadUint64(&ag.stats.batches),
+		Resent:            atomic.LoadUint64(&ag.stats.resent),
+		Retransmits:       atomic.LoadUint64(&ag.stats.retransmits),
+		Undelivered:       atomic.LoadUint64(&ag.stats.undelivered),
+		ReusedConns:       atomic.LoadUint64(&ag.stats.reusedConns),
+		PingFailures:      atomic.LoadUint64(&ag.stats.pingFailures),
+		AuthFailures:      atomic.LoadUint64(&ag.stats.authFailures),
+		ClusterMismatches: atomic.LoadUint64(&ag.stats.clusterMismatches),
+		Unverified:        atomic.LoadUint64(&ag.stats.unverified),
+		Replayed:          atomic.LoadUint64(&ag.stats.replayed),
+		FIFOBuffered:      atomic.LoadUint64(&ag.stats.fifoBuffered),
+		FIFOSkipped:       atomic.LoadUint64(&ag.stats.fifoSkipped),
+		FIFOLate:          atomic.LoadUint64(&ag.stats.fifoLate),
+		SpoolDropped:      atomic.LoadUint64(&ag.stats.spoolDropped),
+		JoinsAbandoned:    atomic.LoadUint64(&ag.stats.joinsAbandoned),
+		IDCollisions:      atomic.LoadUint64(&ag.stats.idCollisions),
+		ChunksDropped:     atomic.LoadUint64(&ag.stats.chunksDropped),
+		Throttled:         atomic.LoadUint64(&ag.stats.throttled),
+		PacketsSent:       atomic.LoadUint64(&ag.stats.packetsSent),
+		PacketsRecv:       atomic.LoadUint64(&ag.stats.packetsReceived),
+		CacheHits:         atomic.LoadUint64(&ag.msgCache.hits),
+		CacheMisses:       atomic.LoadUint64(&ag.msgCache.misses),
+		CacheEvictions:    atomic.LoadUint64(&ag.msgCache.evictions),
+		Partitions:        atomic.LoadUint64(&ag.stats.partitionsSuspected),
+		MergeAttempts:     atomic.LoadUint64(&ag.stats.mergeAttempts),
+		IdleConns:         ag.pool.len(),
+		QueuedConns:       atomic.LoadInt32(&ag.stats.queuedConns),
+		HandlingConns:     atomic.LoadInt32(&ag.stats.handlingConns),
+		Handlers:          atomic.LoadInt32(&ag.stats.handlers),
+	}
+
+	// The view sizes are read under the read locks,
+	// which are only taken when the metrics are asked.
+	ag.viewMu.RLock()
+	m.ActiveView = ag.aView.Len()
+	m.PassiveView = ag.pView.Len()
+	ag.viewMu.RUnlock()
+
+	if started := atomic.LoadInt64(&ag.stats.started); started != 0 {
+		m.Uptime = int64(time.Since(time.Unix(0, started)) / time.Millisecond)
+	}
+	return m
+}
+
+// countingConn counts the bytes read from and written to the connection.
+type countingConn struct {
+	net.Conn
+	stats *stats
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	atomic.AddUint64(&c.stats.bytesIn, uint64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	atomic.AddUint64(&c.stats.bytesOut, uint64(n))
+	return n, err
+}
@@ -0,0 +1,4032 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/lilymona/gog/arraymap"
+	"github.com/lilymona/gog/codec"
+	"github.com/lilymona/gog/config"
+	log "github.com/lilymona/gog/logging"
+	"github.com/lilymona/gog/message"
+	"github.com/lilymona/gog/node"
+	"github.com/lilymona/gog/transport"
+	"github.com/lilymona/testify/assert"
+)
+
+// newTestConfig returns a config with an unused local address.
+func newTestConfig(t *testing.T) *config.Config {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().(*net.TCPAddr)
+	ln.Close()
+
+	return &config.Config{
+		Net:             "tcp",
+		AddrStr:         addr.String(),
+		LocalTCPAddr:    addr,
+		AViewMinSize:    3,
+		AViewMaxSize:    5,
+		PViewSize:       30,
+		Ka:              1,
+		Kp:              3,
+		ARWL:            5,
+		PRWL:            3,
+		SRWL:            5,
+		MLife:           5000,
+		ShuffleDuration: 5,
+		HealDuration:    1,
+		PurgeDuration:   5000,
+	}
+}
+
+// startTestAgent starts serving an agent, and waits until
+// it accepts connections.
+func startTestAgent(t *testing.T, cfg *config.Config) *agent {
+	ag := NewAgent(cfg).(*agent)
+	ag.RegisterMessageHandler(func(Message) {})
+	go ag.Serve()
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial(cfg.Net, cfg.AddrStr); err == nil {
+			conn.Close()
+			return ag
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Agent %s is not serving", cfg.AddrStr)
+	return nil
+}
+
+// hasNode returns true if the view of the agent has the node.
+func hasNode(ag *agent, view *arraymap.OrderedMap[uint64, *node.Node], id uint64) bool {
+	ag.viewMu.RLock()
+	defer ag.viewMu.RUnlock()
+	return view.Has(id)
+}
+
+// tcpPipe returns both ends of a local TCP connection.
+func tcpPipe(t *testing.T) (*net.TCPConn, *net.TCPConn) {
+	ln, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	local, err := net.DialTCP("tcp", nil, ln.Addr().(*net.TCPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := ln.AcceptTCP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return local, remote
+}
+
+// readMsgTimeout reads a message from the connection, giving up
+// after the timeout.
+func readMsgTimeout(c codec.Codec, conn *net.TCPConn, timeout time.Duration) (proto.Message, error) {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	defer conn.SetReadDeadline(time.Time{})
+	return c.ReadMsg(conn)
+}
+
+func TestHandleJoinObserve(t *testing.T) {
+	ag := NewAgent(newTestConfig(t)).(*agent)
+
+	// The neighbor should receive a ForwardJoin for every
+	// accepted join, except for the observers.
+	local, remote := tcpPipe(t)
+	defer remote.Close()
+	defer ag.Close()
+	ag.aView.Add(uint64(1), node.New(1, "neighbor", local))
+
+	conn, _ := tcpPipe(t)
+	assert.True(t, ag.handleJoin(conn, &message.Join{
+		Id:      proto.Uint64(2),
+		Addr:    proto.String("observer"),
+		Observe: proto.Bool(true),
+	}))
+	_, err := readMsgTimeout(ag.codec, remote, 200*time.Millisecond)
+	assert.Error(t, err)
+
+	conn, _ = tcpPipe(t)
+	assert.True(t, ag.handleJoin(conn, &message.Join{
+		Id:   proto.Uint64(3),
+		Addr: proto.String("joiner"),
+	}))
+	msg, err := readMsgTimeout(ag.codec, remote, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), msg.(*message.ForwardJoin).GetSourceId())
+}
+
+func TestIDCollision(t *testing.T) {
+	ag := NewAgent(newTestConfig(t)).(*agent)
+	defer ag.Close()
+	local, remote := tcpPipe(t)
+	defer remote.Close()
+	ag.aView.Add(uint64(1), node.New(1, "neighbor", local))
+
+	// The joins with the id of the neighbor or of
+	// the agent from another address are rejected.
+	for _, id := range []uint64{1, ag.id} {
+		conn, peer := tcpPipe(t)
+		assert.False(t, ag.handleJoin(conn, &message.Join{
+			Id:   proto.Uint64(id),
+			Addr: proto.String("impostor"),
+		}))
+		msg, err := readMsgTimeout(ag.codec, peer, time.Second)
+		if assert.NoError(t, err) {
+			assert.False(t, msg.(*message.JoinReply).GetAccept())
+		}
+		peer.Close()
+	}
+	assert.Equal(t, uint64(2), atomic.LoadUint64(&ag.stats.idCollisions))
+	assert.Equal(t, "neighbor", ag.aView.GetValueOf(1).Addr)
+}
+
+func TestNodeID(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.IDStrategy = config.IDAddr
+	assert.Equal(t, nodeID(cfg, "10.0.0.1:8424"), nodeID(cfg, "10.0.0.1:8424"))
+	assert.NotEqual(t, nodeID(cfg, "10.0.0.1:8424"), nodeID(cfg, "10.0.0.2:8424"))
+	assert.Equal(t, uint64(0), nodeID(cfg, "10.0.0.1:8424")>>63)
+
+	cfg.IDStrategy = config.IDRandom
+	assert.NotEqual(t, nodeID(cfg, ""), nodeID(cfg, ""))
+	cfg.ID = 42
+	assert.Equal(t, uint64(42), nodeID(cfg, ""))
+}
+
+func TestClusterName(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.ClusterName = "blue"
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+
+	// The joins from another cluster, or from the default one, are rejected.
+	for _, cluster := range []*string{proto.String("green"), nil} {
+		conn, peer := tcpPipe(t)
+		assert.False(t, ag.handleJoin(conn, &message.Join{
+			Id:      proto.Uint64(1),
+			Addr:    proto.String("joiner"),
+			Cluster: cluster,
+		}))
+		msg, err := readMsgTimeout(ag.codec, peer, time.Second)
+		if assert.NoError(t, err) {
+			assert.False(t, msg.(*message.JoinReply).GetAccept())
+		}
+		peer.Close()
+	}
+
+	// The candidates of the shuffles from another cluster are not learned.
+	ag.handleShuffle(&message.Shuffle{
+		Id:         proto.Uint64(2),
+		SourceId:   proto.Uint64(2),
+		Addr:       proto.String("shuffler"),
+		Ttl:        proto.Uint32(0),
+		Candidates: []*message.Candidate{{Id: proto.Uint64(3), Addr: proto.String("candidate")}},
+		Cluster:    proto.String("green"),
+	})
+	assert.Equal(t, 0, ag.pView.Len())
+	assert.Equal(t, uint64(3), atomic.LoadUint64(&ag.stats.clusterMismatches))
+
+	// The joins from the same cluster are accepted.
+	conn, peer := tcpPipe(t)
+	defer peer.Close()
+	assert.True(t, ag.handleJoin(conn, &message.Join{
+		Id:      proto.Uint64(1),
+		Addr:    proto.String("joiner"),
+		Cluster: proto.String("blue"),
+	}))
+	assert.Equal(t, "blue", *ag.cluster())
+}
+
+func TestNegotiateCompression(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.CompressThreshold = 100
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+
+	// The large messages are only compressed to
+	// the peers that advertise the compression.
+	for i, peerCaps := range []uint32{0, node.CapCompress} {
+		id := uint64(i + 1)
+		local, remote := tcpPipe(t)
+		defer remote.Close()
+		assert.True(t, ag.handleJoin(local, &message.Join{
+			Id:   proto.Uint64(id),
+			Addr: proto.String("joiner"),
+			Caps: proto.Uint32(peerCaps),
+		}))
+		reply, err := readMsgTimeout(ag.codec, remote, time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, caps, reply.(*message.JoinReply).GetCaps())
+
+		ag.viewMu.RLock()
+		nd := ag.aView.GetValueOf(id)
+		ag.viewMu.RUnlock()
+		ag.userMessage(nd, &message.UserMessage{
+			Id:      proto.Uint64(ag.id),
+			Payload: make([]byte, 1000),
+			Ts:      proto.Int64(time.Now().UnixNano()),
+		})
+
+		// The type index follows the magic and the length.
+		hdr := make([]byte, 7)
+		remote.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = io.ReadFull(remote, hdr)
+		assert.NoError(t, err)
+		assert.Equal(t, peerCaps != 0, hdr[6]&0x80 != 0)
+	}
+}
+
+func TestPing(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.PingTimeout = 1000
+	cfg.PingMisses = 2
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+
+	local, remote := tcpPipe(t)
+	defer remote.Close()
+	nd := node.New(1, "neighbor", local)
+	nd.Caps = node.CapPing
+	ag.aView.Add(nd.Id, nd)
+	// The old peers are not pinged.
+	oldLocal, oldRemote := tcpPipe(t)
+	defer oldRemote.Close()
+	ag.aView.Add(uint64(2), node.New(2, "old", oldLocal))
+
+	ping := func() *message.Ping {
+		ag.pingOnce(time.Now())
+		msg, err := readMsgTimeout(ag.codec, remote, time.Second)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return msg.(*message.Ping)
+	}
+
+	// The neighbor answering the pings stays.
+	for i := 0; i < 3; i++ {
+		msg := ping()
+		ag.handlePong(nd, &message.Pong{Id: proto.Uint64(1), Seq: msg.Seq})
+	}
+	_, err := readMsgTimeout(ag.codec, oldRemote, 100*time.Millisecond)
+	assert.Error(t, err)
+
+	// It is replaced after missing the pongs.
+	ping()
+	ping()
+	ag.pingOnce(time.Now())
+	for i := 0; i < 100 && hasNode(ag, ag.aView, 1); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.False(t, hasNode(ag, ag.aView, 1))
+	assert.True(t, hasNode(ag, ag.aView, 2))
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&ag.stats.pingFailures))
+}
+
+func TestEvict(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.BanDuration = 60
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+
+	local, remote := tcpPipe(t)
+	defer remote.Close()
+	ag.aView.Add(uint64(1), node.New(1, "neighbor", local))
+	ag.pView.Add(uint64(2), &node.Node{Id: 2, Addr: "passive"})
+
+	assert.NoError(t, ag.Evict(2))
+	assert.False(t, hasNode(ag, ag.pView, 2))
+	assert.NoError(t, ag.Evict(1))
+	assert.False(t, hasNode(ag, ag.aView, 1))
+	msg, err := readMsgTimeout(ag.codec, remote, time.Second)
+	assert.NoError(t, err)
+	assert.IsType(t, &message.Disconnect{}, msg)
+	assert.Equal(t, ErrNodeNotFound, ag.Evict(3))
+
+	// The banned nodes are refused, and not learned.
+	conn, _ := tcpPipe(t)
+	assert.False(t, ag.handleJoin(conn, &message.Join{
+		Id:   proto.Uint64(1),
+		Addr: proto.String("neighbor"),
+	}))
+	ag.handleShuffleReply(&message.ShuffleReply{
+		Id: proto.Uint64(3),
+		Candidates: []*message.Candidate{
+			{Id: proto.Uint64(2), Addr: proto.String("passive")},
+		},
+	})
+	assert.False(t, hasNode(ag, ag.pView, 2))
+
+	// Until the ban expires.
+	ag.viewMu.Lock()
+	ag.bans[1] = time.Now().Add(-time.Second)
+	ag.viewMu.Unlock()
+	conn, _ = tcpPipe(t)
+	assert.True(t, ag.handleJoin(conn, &message.Join{
+		Id:   proto.Uint64(1),
+		Addr: proto.String("neighbor"),
+	}))
+}
+
+func TestShuffleIntervalAdapts(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.MinShuffleDuration = 1
+	cfg.MaxShuffleDuration = 8
+	ag := NewAgent(cfg).(*agent)
+	ag.pView.Add(uint64(1), &node.Node{Id: 1, Addr: "known"})
+
+	// The shuffle brings no new candidates.
+	ag.handleShuffleReply(&message.ShuffleReply{
+		Id: proto.Uint64(1),
+		Candidates: []*message.Candidate{
+			{Id: proto.Uint64(1), Addr: proto.String("known")},
+		},
+	})
+	learned := int(atomic.SwapInt32(&ag.learned, 0))
+	assert.Equal(t, 0, learned)
+	interval := ag.nextShuffleInterval(2*time.Second, learned)
+	assert.Equal(t, 4*time.Second, interval)
+	interval = ag.nextShuffleInterval(interval, learned)
+	assert.Equal(t, 8*time.Second, interval)
+	interval = ag.nextShuffleInterval(interval, learned)
+	assert.Equal(t, 8*time.Second, interval)
+
+	// The shuffle brings only new candidates.
+	ag.handleShuffleReply(&message.ShuffleReply{
+		Id: proto.Uint64(1),
+		Candidates: []*message.Candidate{
+			{Id: proto.Uint64(2), Addr: proto.String("foo")},
+			{Id: proto.Uint64(3), Addr: proto.String("bar")},
+			{Id: proto.Uint64(4), Addr: proto.String("baz")},
+		},
+	})
+	learned = int(atomic.SwapInt32(&ag.learned, 0))
+	assert.Equal(t, 3, learned)
+	assert.Equal(t, 4*time.Second, ag.nextShuffleInterval(interval, learned))
+}
+
+func TestJoinDedupPeers(t *testing.T) {
+	peer := startTestAgent(t, newTestConfig(t))
+	defer peer.Close()
+
+	ag := NewAgent(newTestConfig(t)).(*agent)
+	defer ag.Close()
+
+	assert.NoError(t, ag.Join(peer.config().AddrStr))
+	assert.Equal(t, ErrNoAvailablePeers, ag.Join(peer.config().AddrStr))
+	assert.Equal(t, []string{peer.config().AddrStr}, ag.config().Peers)
+}
+
+func TestJoinConcurrently(t *testing.T) {
+	peer := startTestAgent(t, newTestConfig(t))
+	defer peer.Close()
+
+	// A peer that accepts the connection but never replies.
+	hung, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hung.Close()
+
+	ag := NewAgent(newTestConfig(t)).(*agent)
+	defer ag.Close()
+
+	start := time.Now()
+	assert.NoError(t, ag.Join(hung.Addr().String(), newTestConfig(t).AddrStr, newTestConfig(t).AddrStr, peer.config().AddrStr))
+	assert.True(t, time.Since(start) < time.Second)
+	assert.True(t, hasNode(ag, ag.aView, peer.id))
+}
+
+// fakePeer() listens for the agent, answers the handshake of each
+// connection, reads the Join, and calls serve with it.
+func fakePeer(t *testing.T, ag *agent, serve func(net.Conn, *message.Join)) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if _, err := readPreamble(conn); err != nil {
+					return
+				}
+				conn.Write(ag.localPreamble().marshal())
+				msg, err := ag.codec.ReadMsg(conn)
+				if err != nil {
+					return
+				}
+				serve(conn, msg.(*message.Join))
+			}()
+		}
+	}()
+	return ln
+}
+
+func TestJoinLateAcceptor(t *testing.T) {
+	peer := startTestAgent(t, newTestConfig(t))
+	defer peer.Close()
+	ag := NewAgent(newTestConfig(t)).(*agent)
+	defer ag.Close()
+
+	// The peer that accepts after the other one is disconnected.
+	disconnected := make(chan proto.Message, 1)
+	late := fakePeer(t, ag, func(conn net.Conn, join *message.Join) {
+		time.Sleep(200 * time.Millisecond)
+		ag.codec.WriteMsg(&message.JoinReply{Id: proto.Uint64(99), Accept: proto.Bool(true)}, conn)
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		msg, _ := ag.codec.ReadMsg(conn)
+		disconnected <- msg
+	})
+	defer late.Close()
+
+	start := time.Now()
+	assert.NoError(t, ag.Join(peer.config().AddrStr, late.Addr().String()))
+	assert.True(t, time.Since(start) < 200*time.Millisecond)
+	assert.IsType(t, &message.Disconnect{}, <-disconnected)
+	assert.False(t, hasNode(ag, ag.aView, 99))
+}
+
+func TestJoinTimeout(t *testing.T) {
+	defer func(d time.Duration) { joinTimeout = d }(joinTimeout)
+	joinTimeout = 100 * time.Millisecond
+
+	cfg := newTestConfig(t)
+	cfg.JoinRetries = 0
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+
+	// The peer that never replies the Join.
+	release := make(chan struct{})
+	defer close(release)
+	ln := fakePeer(t, ag, func(net.Conn, *message.Join) { <-release })
+	defer ln.Close()
+
+	start := time.Now()
+	assert.Equal(t, ErrNoAvailablePeers, ag.Join(ln.Addr().String()))
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestJoinRetries(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.JoinRetries = 2
+	cfg.JoinBackoff = 10
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+
+	assert.Equal(t, ErrNoAvailablePeers, ag.Join(newTestConfig(t).AddrStr))
+	assert.Equal(t, uint64(3), atomic.LoadUint64(&ag.stats.joins))
+}
+
+func TestJoinBackoff(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.JoinBackoff = 100
+	cfg.JoinMaxBackoff = 1000
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+
+	for retry, d := range []time.Duration{100, 200, 400, 800, 1000, 1000, 1000} {
+		d *= time.Millisecond
+		backoff := ag.joinBackoff(retry)
+		assert.True(t, backoff >= d/2 && backoff <= d, "retry %d: %v", retry, backoff)
+	}
+}
+
+func TestRandSource(t *testing.T) {
+	// The agents with the same seed make the same choices.
+	var choices [2][]int64
+	for i := range choices {
+		cfg := newTestConfig(t)
+		cfg.Rand = rand.NewSource(42)
+		ag := NewAgent(cfg).(*agent)
+		for j := 0; j < 3; j++ {
+			choices[i] = append(choices[i], ag.rng.Int63())
+		}
+		ag.Close()
+	}
+	assert.Equal(t, choices[0], choices[1])
+}
+
+func TestRejoinAbandoned(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.JoinRetries = 0
+	cfg.JoinBackoff = 1000
+	cfg.RejoinAttempts = 2
+	cfg.Peers = []string{newTestConfig(t).AddrStr}
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+
+	abandoned := make(chan int, 1)
+	ag.RegisterJoinAbandoned(func(peers []string, attempts int) {
+		assert.Equal(t, cfg.Peers, peers)
+		abandoned <- attempts
+	})
+
+	ag.rejoinCluster()
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&ag.stats.joins))
+
+	// The next round waits for the backoff.
+	ag.rejoinCluster()
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&ag.stats.joins))
+
+	ag.rejoiner.Lock()
+	ag.rejoiner.next = time.Now()
+	ag.rejoiner.Unlock()
+	ag.rejoinCluster()
+	assert.Equal(t, uint64(2), atomic.LoadUint64(&ag.stats.joins))
+	select {
+	case attempts := <-abandoned:
+		assert.Equal(t, 2, attempts)
+	case <-time.After(time.Second):
+		t.Fatal("The join is not abandoned")
+	}
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&ag.stats.joinsAbandoned))
+
+	// No more rounds after giving up, until the agent joins again.
+	ag.rejoinCluster()
+	assert.Equal(t, uint64(2), atomic.LoadUint64(&ag.stats.joins))
+
+	peer := startTestAgent(t, newTestConfig(t))
+	defer peer.Close()
+	assert.NoError(t, ag.Join(peer.config().AddrStr))
+	ag.rejoiner.Lock()
+	assert.False(t, ag.rejoiner.abandoned)
+	ag.rejoiner.Unlock()
+}
+
+func TestExtraAddrs(t *testing.T) {
+	cfg := newTestConfig(t)
+	extra := newTestConfig(t).LocalTCPAddr
+	cfg.ExtraAddrs = []string{extra.String()}
+	cfg.ExtraTCPAddrs = []*net.TCPAddr{extra}
+	ag := startTestAgent(t, cfg)
+	defer ag.Close()
+	ag.lnMu.Lock()
+	assert.Len(t, ag.lns, 2)
+	ag.lnMu.Unlock()
+	assert.True(t, ag.isSelf(extra))
+
+	// The peers join through the extra address.
+	peer := NewAgent(newTestConfig(t)).(*agent)
+	defer peer.Close()
+	assert.NoError(t, peer.Join(extra.String()))
+	assert.True(t, hasNode(peer, peer.aView, ag.id))
+
+	// Both listeners are closed.
+	ag.Close()
+	for _, addr := range []string{cfg.AddrStr, extra.String()} {
+		_, err := net.DialTimeout("tcp", addr, time.Second)
+		assert.Error(t, err)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	ag := startTestAgent(t, newTestConfig(t))
+	defer ag.Close()
+	h := ag.Health()
+	assert.True(t, h.Listening)
+	assert.Equal(t, 0, h.ActiveView)
+	assert.Equal(t, int64(-1), h.SinceShuffle)
+	assert.Equal(t, int64(-1), h.SinceDelivery)
+	assert.True(t, h.Degraded())
+	assert.False(t, h.Ready())
+
+	peer := startTestAgent(t, newTestConfig(t))
+	defer peer.Close()
+	assert.NoError(t, ag.Join(peer.config().AddrStr))
+	assert.NoError(t, peer.Broadcast([]byte("hello")))
+	time.Sleep(100 * time.Millisecond)
+	ag.handleShuffleReply(&message.ShuffleReply{Id: proto.Uint64(peer.id)})
+
+	h = ag.Health()
+	assert.Equal(t, 1, h.ActiveView)
+	assert.True(t, h.SinceShuffle >= 0)
+	assert.True(t, h.SinceDelivery >= 0)
+	assert.True(t, h.Ready())
+
+	ag.Close()
+	assert.False(t, ag.Health().Listening)
+}
+
+func TestShutdown(t *testing.T) {
+	ag := startTestAgent(t, newTestConfig(t))
+	defer ag.Close()
+	peer := startTestAgent(t, newTestConfig(t))
+	defer peer.Close()
+	assert.NoError(t, ag.Join(peer.config().AddrStr))
+	time.Sleep(100 * time.Millisecond)
+
+	received, release := make(chan struct{}), make(chan struct{})
+	ag.RegisterMessageHandler(func(Message) {
+		close(received)
+		<-release
+	})
+	assert.NoError(t, peer.Broadcast([]byte("hello")))
+	<-received
+
+	// The handler in flight is not waited for after the deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, ag.Shutdown(ctx))
+	assert.False(t, ag.Health().Listening)
+
+	other := startTestAgent(t, newTestConfig(t))
+	defer other.Close()
+	assert.NoError(t, other.Join(peer.config().AddrStr))
+	time.Sleep(100 * time.Millisecond)
+
+	delivered := make(chan struct{})
+	other.RegisterMessageHandler(func(Message) {
+		<-release
+		close(delivered)
+	})
+	assert.NoError(t, peer.Broadcast([]byte("world")))
+	time.Sleep(100 * time.Millisecond)
+
+	// The handler in flight returns before the peers are disconnected.
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+	}()
+	assert.NoError(t, other.Shutdown(context.Background()))
+	select {
+	case <-delivered:
+	default:
+		t.Fatal("The handler in flight is not waited for")
+	}
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, hasNode(peer, peer.aView, other.id))
+}
+
+func TestMsgpackCodec(t *testing.T) {
+	var cfgs [2]*config.Config
+	for i := range cfgs {
+		cfgs[i] = newTestConfig(t)
+		cfgs[i].Codec = config.CodecMsgpack
+	}
+	ag := startTestAgent(t, cfgs[0])
+	defer ag.Close()
+	peer := startTestAgent(t, cfgs[1])
+	defer peer.Close()
+
+	received := make(chan Message, 1)
+	peer.RegisterMessageHandler(func(msg Message) { received <- msg })
+	assert.NoError(t, ag.Join(cfgs[1].AddrStr))
+	assert.NoError(t, ag.BroadcastTopic("foo", []byte("hello")))
+	select {
+	case msg := <-received:
+		assert.Equal(t, ag.id, msg.SenderID)
+		assert.Equal(t, "foo", msg.Topic)
+		assert.Equal(t, []byte("hello"), msg.Payload)
+	case <-time.After(time.Second):
+		t.Fatal("The message is not received")
+	}
+}
+
+func TestChunkedBroadcast(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.ChunkSize = 4
+	a := NewAgent(cfg).(*agent)
+	defer a.Close()
+	cfg = newTestConfig(t)
+	cfg.ChunkBufferSize = 1024
+	b := NewAgent(cfg).(*agent)
+	defer b.Close()
+	delivered := make(chan Message, 2)
+	b.RegisterMessageHandler(func(msg Message) { delivered <- msg })
+
+	conn, ab := tcpPipe(t)
+	defer ab.Close()
+	a.aView.Add(b.id, node.New(b.id, "", conn))
+
+	payload := []byte("hello chunks!")
+	assert.NoError(t, a.Broadcast(payload))
+	var chunks []*message.UserMessage
+	for i := 0; i < 4; i++ {
+		msg, err := readMsgTimeout(b.codec, ab, time.Second)
+		if !assert.NoError(t, err) {
+			return
+		}
+		chunks = append(chunks, msg.(*message.UserMessage))
+	}
+	for i, msg := range chunks {
+		assert.Equal(t, uint32(i), msg.GetChunkIndex())
+		assert.Equal(t, uint32(4), msg.GetChunkCount())
+		assert.Equal(t, chunks[0].GetChunkId(), msg.GetChunkId())
+		assert.True(t, len(msg.GetPayload()) <= 4)
+		hash := sha256.Sum256(payload)
+		assert.Equal(t, hash[:], msg.GetChunkHash())
+	}
+	assert.NotEqual(t, hashUserMessage(chunks[0]), hashUserMessage(&message.UserMessage{Payload: chunks[0].Payload}))
+
+	// The chunks are reassembled in any order, and the
+	// payload is delivered only once all of them arrive.
+	for i := len(chunks) - 1; i > 0; i-- {
+		b.handleUserMessage(&node.Node{Id: a.id}, chunks[i])
+	}
+	b.handleUserMessage(&node.Node{Id: a.id}, chunks[2])
+	select {
+	case <-delivered:
+		t.Fatal("Delivered an incomplete payload")
+	case <-time.After(50 * time.Millisecond):
+	}
+	b.handleUserMessage(&node.Node{Id: a.id}, chunks[0])
+	select {
+	case msg := <-delivered:
+		assert.Equal(t, payload, msg.Payload)
+		assert.Equal(t, a.id, msg.SenderID)
+	case <-time.After(time.Second):
+		t.Fatal("Payload is not delivered")
+	}
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&b.stats.duplicates))
+	assert.Empty(t, b.chunks.partial)
+	assert.Equal(t, 0, b.chunks.size)
+}
+
+func TestChunksDropped(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.ChunkTimeout = 50
+	cfg.ChunkBufferSize = 2 * (partialCost(2) + 4)
+	b := NewAgent(cfg).(*agent)
+	defer b.Close()
+
+	chunk := func(id uint64, index uint32, payload string) *message.UserMessage {
+		return &message.UserMessage{
+			Id:         proto.Uint64(1),
+			Payload:    []byte(payload),
+			Ts:         proto.Int64(time.Now().UnixNano()),
+			ChunkId:    proto.Uint64(id),
+			ChunkIndex: proto.Uint32(index),
+			ChunkCount: proto.Uint32(2),
+		}
+	}
+
+	// The oldest payload is dropped when the buffer is full.
+	b.handleUserMessage(&node.Node{Id: 1}, chunk(1, 0, "abcd"))
+	b.handleUserMessage(&node.Node{Id: 1}, chunk(2, 0, "efgh"))
+	b.handleUserMessage(&node.Node{Id: 1}, chunk(3, 0, "ijkl"))
+	b.chunks.Lock()
+	assert.Len(t, b.chunks.partial, 2)
+	assert.NotContains(t, b.chunks.partial, chunkKey{1, 1})
+	assert.Equal(t, cfg.ChunkBufferSize, b.chunks.size)
+	b.chunks.Unlock()
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&b.stats.chunksDropped))
+
+	// A chunk larger than the buffer is dropped.
+	b.handleUserMessage(&node.Node{Id: 1}, chunk(4, 0, strings.Repeat("x", cfg.ChunkBufferSize)))
+	assert.Equal(t, uint64(2), atomic.LoadUint64(&b.stats.chunksDropped))
+
+	// The incomplete payloads are dropped after the timeout.
+	time.Sleep(150 * time.Millisecond)
+	b.chunks.Lock()
+	assert.Empty(t, b.chunks.partial)
+	assert.Equal(t, 0, b.chunks.size)
+	b.chunks.Unlock()
+	assert.Equal(t, uint64(4), atomic.LoadUint64(&b.stats.chunksDropped))
+
+	// The chunk of another payload is dropped, and the
+	// payload is not delivered if its hash differs.
+	hash := sha256.Sum256([]byte("abcdefgh"))
+	first, other, last := chunk(5, 0, "abcd"), chunk(5, 1, "efgh"), chunk(5, 1, "efgX")
+	first.ChunkHash, last.ChunkHash = hash[:], hash[:]
+	other.ChunkHash = []byte("other")
+	for _, msg := range []*message.UserMessage{first, other, last} {
+		b.handleUserMessage(&node.Node{Id: 1}, msg)
+	}
+	assert.Equal(t, uint64(6), atomic.LoadUint64(&b.stats.chunksDropped))
+	b.chunks.Lock()
+	assert.Empty(t, b.chunks.partial)
+	b.chunks.Unlock()
+}
+
+func TestChunksFlood(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.ChunkBufferSize = 64 << 20
+	b := NewAgent(cfg).(*agent)
+	defer b.Close()
+
+	chunk := func(sender, id uint64, count uint32, payload string) *message.UserMessage {
+		return &message.UserMessage{
+			Id:         proto.Uint64(sender),
+			Payload:    []byte(payload),
+			Ts:         proto.Int64(time.Now().UnixNano()),
+			ChunkId:    proto.Uint64(id),
+			ChunkIndex: proto.Uint32(0),
+			ChunkCount: proto.Uint32(count),
+		}
+	}
+	bounded := func() {
+		b.chunks.Lock()
+		defer b.chunks.Unlock()
+		assert.True(t, b.chunks.size <= cfg.ChunkBufferSize)
+		assert.True(t, len(b.chunks.partial) <= maxPartials)
+		for _, n := range b.chunks.senders {
+			assert.True(t, n <= maxSenderPartials)
+		}
+	}
+
+	// The empty chunks are dropped.
+	for i := uint64(1); i <= 100; i++ {
+		b.handleUserMessage(&node.Node{Id: 1}, chunk(1, i, maxChunks, ""))
+	}
+	b.chunks.Lock()
+	assert.Empty(t, b.chunks.partial)
+	b.chunks.Unlock()
+	assert.Equal(t, uint64(100), atomic.LoadUint64(&b.stats.chunksDropped))
+
+	// The payloads are charged for the chunks they announce.
+	for i := uint64(1); i <= 1000; i++ {
+		b.handleUserMessage(&node.Node{Id: 1}, chunk(1+i%100, i, maxChunks, "x"))
+		bounded()
+	}
+	b.chunks.Lock()
+	assert.Equal(t, cfg.ChunkBufferSize/(partialCost(maxChunks)+1), len(b.chunks.partial))
+	b.chunks.Unlock()
+
+	// The payloads of a sender, and all of them, are bounded.
+	for i := uint64(1); i <= 100; i++ {
+		b.handleUserMessage(&node.Node{Id: 1}, chunk(1, 1000+i, 2, "x"))
+	}
+	bounded()
+	b.chunks.Lock()
+	assert.Equal(t, maxSenderPartials, b.chunks.senders[1])
+	b.chunks.Unlock()
+	for i := uint64(1); i <= 2*maxPartials; i++ {
+		b.handleUserMessage(&node.Node{Id: 1}, chunk(1000+i, i, 2, "x"))
+	}
+	bounded()
+	b.chunks.Lock()
+	assert.Equal(t, maxPartials, len(b.chunks.partial))
+	b.chunks.Unlock()
+}
+
+func TestThrottleReserve(t *testing.T) {
+	clock := time.Now()
+	th := newThrottle(100, 100)
+	th.now = func() time.Time { return clock }
+	th.last = clock
+
+	assert.Equal(t, time.Duration(0), th.reserve(60))
+	assert.Equal(t, time.Duration(0), th.reserve(40))
+	// The bucket goes into debt for the tokens over the burst.
+	assert.Equal(t, 500*time.Millisecond, th.reserve(50))
+	assert.Equal(t, 1500*time.Millisecond, th.reserve(100))
+	clock = clock.Add(2 * time.Second)
+	assert.Equal(t, time.Duration(0), th.reserve(50))
+
+	th = newThrottle(0, 0)
+	assert.Equal(t, time.Duration(0), th.reserve(1e9))
+}
+
+func TestSendRate(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.PeerSendMessageRate = 20
+	a := NewAgent(cfg).(*agent)
+	defer a.Close()
+
+	conn, remote := tcpPipe(t)
+	defer remote.Close()
+	nd := node.New(1, "", conn)
+	a.aView.Add(nd.Id, nd)
+
+	// The burst is sent at once, and the rest at the rate.
+	start := time.Now()
+	for i := 0; i < 30; i++ {
+		assert.NoError(t, a.Broadcast([]byte{byte(i)}))
+	}
+	for i := 0; i < 30; i++ {
+		msg, err := readMsgTimeout(a.codec, remote, 2*time.Second)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, []byte{byte(i)}, msg.(*message.UserMessage).GetPayload())
+	}
+	assert.True(t, time.Since(start) >= 400*time.Millisecond, "sent in %v", time.Since(start))
+	assert.InDelta(t, 10, atomic.LoadUint64(&a.stats.throttled), 2)
+
+	// The limits of a neighbor are forgotten when it is removed.
+	a.limiter.mu.Lock()
+	assert.Contains(t, a.limiter.links, nd.Id)
+	a.limiter.mu.Unlock()
+	a.neighborDown(nd)
+	a.limiter.mu.Lock()
+	assert.NotContains(t, a.limiter.links, nd.Id)
+	a.limiter.mu.Unlock()
+
+	// The rates are changed by the reconfiguration.
+	c := *a.config()
+	c.SendMessageRate = 5
+	c.PeerSendMessageRate = 0
+	assert.NoError(t, a.Reconfigure(&c))
+	assert.Nil(t, a.limiter.link(nd.Id))
+	assert.Equal(t, float64(5), a.limiter.msgs.rate)
+
+	// Only the tunables are changed, and the peers added are kept.
+	a.addPeers("127.0.0.1:8001")
+	c = *a.config()
+	c.SendMessageRate, c.BatchSize, c.Peers = 6, 100, nil
+	assert.NoError(t, a.Reconfigure(&c))
+	assert.Equal(t, float64(6), a.config().SendMessageRate)
+	assert.NotEqual(t, 100, a.config().BatchSize)
+	assert.Contains(t, a.config().Peers, "127.0.0.1:8001")
+}
+
+func TestDebug(t *testing.T) {
+	ag := NewAgent(newTestConfig(t)).(*agent)
+	defer ag.Close()
+
+	// The messages pile up while the write to the pipe blocks.
+	local, remote := net.Pipe()
+	defer remote.Close()
+	nd := node.New(2, "", local)
+	ag.aView.Add(nd.Id, nd)
+	for i := 0; i < 3; i++ {
+		ag.userMessage(nd, &message.UserMessage{Payload: []byte{byte(i)}})
+	}
+	d := ag.Debug()
+	assert.True(t, d.Goroutines > 0)
+	assert.Equal(t, 1, d.ActiveView)
+	assert.True(t, d.SendQueues[nd.Id] >= 2, "queued %d", d.SendQueues[nd.Id])
+	assert.Equal(t, 0, d.PendingAcks)
+	if assert.Len(t, d.Neighbors, 1) {
+		assert.Equal(t, NeighborInfo{ID: 2, State: "connected"}, d.Neighbors[0])
+	}
+}
+
+func TestPartitionSuspected(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.PartitionInterval = 1
+	cfg.PartitionThreshold = 0.5
+	cfg.PartitionHistory = 600
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+	suspected := make(chan [2]int, 1)
+	ag.RegisterPartitionSuspected(func(reachable, peak int) { suspected <- [2]int{reachable, peak} })
+
+	conn, remote := tcpPipe(t)
+	defer remote.Close()
+	nd := node.New(100, "neighbor", conn)
+	nd.Caps = node.CapDigest
+	ag.aView.Add(nd.Id, nd)
+
+	// The neighbor reaches 9 other nodes.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := ln.Addr().String()
+	ln.Close()
+	digest := &message.Digest{Id: proto.Uint64(nd.Id), Reachable: proto.Uint32(10)}
+	for i := 1; i <= 9; i++ {
+		digest.Sample = append(digest.Sample, &message.DigestEntry{
+			Id:   proto.Uint64(uint64(i)),
+			Addr: proto.String(closed),
+			Age:  proto.Int64(100),
+		})
+	}
+	ag.handleDigest(nd, digest)
+	now := time.Now()
+	ag.checkPartition(now)
+	msg, err := readMsgTimeout(ag.codec, remote, time.Second)
+	if assert.NoError(t, err) {
+		sent := msg.(*message.Digest)
+		assert.Equal(t, ag.id, sent.GetId())
+		assert.Equal(t, uint32(11), sent.GetReachable())
+		assert.Len(t, sent.GetSample(), 10)
+	}
+	select {
+	case <-suspected:
+		t.Fatal("Partition suspected")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The agent loses the neighbor and the nodes behind it.
+	ag.aView.Remove(nd.Id)
+	ag.checkPartition(now.Add(10 * time.Second))
+	select {
+	case got := <-suspected:
+		assert.Equal(t, [2]int{1, 11}, got)
+	case <-time.After(time.Second):
+		t.Fatal("Partition not suspected")
+	}
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&ag.stats.partitionsSuspected))
+
+	// The lost nodes are joined to merge the partition.
+	for i := 0; i < 100 && atomic.LoadUint64(&ag.stats.mergeAttempts) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&ag.stats.mergeAttempts))
+
+	// The nodes are forgotten after the history.
+	ag.checkPartition(now.Add(time.Hour))
+	ag.partition.Lock()
+	assert.Empty(t, ag.partition.seen)
+	ag.partition.Unlock()
+}
+
+func TestDigestBounds(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.PartitionHistory = 600
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+	nd := &node.Node{Id: 100, Addr: "neighbor"}
+
+	ag.handleDigest(nd, &message.Digest{
+		Id:        proto.Uint64(nd.Id),
+		Reachable: proto.Uint32(1 << 31),
+		Sample: []*message.DigestEntry{
+			{Id: proto.Uint64(1), Addr: proto.String("future"), Age: proto.Int64(-time.Hour.Nanoseconds())},
+			{Id: proto.Uint64(2), Addr: proto.String("old"), Age: proto.Int64(601 * 1000)},
+			{Id: proto.Uint64(3), Addr: proto.String("huge"), Age: proto.Int64(math.MaxInt64)},
+		},
+	})
+	ag.partition.Lock()
+	defer ag.partition.Unlock()
+	assert.Equal(t, config.MaxPeers, ag.partition.reports[nd.Id].reachable)
+	if assert.Len(t, ag.partition.seen, 2) && assert.Contains(t, ag.partition.seen, uint64(1)) {
+		assert.False(t, ag.partition.seen[1].at.After(time.Now()))
+	}
+}
+
+func TestDeterministicChoice(t *testing.T) {
+	ag := newAgent(newTestConfig(t), rand.NewSource(42))
+	for i := uint64(1); i <= 10; i++ {
+		ag.pView.Add(i, &node.Node{Id: i})
+	}
+
+	// The same seed makes the same choices.
+	r := rand.New(rand.NewSource(42))
+	for i := 0; i < 5; i++ {
+		expected := ag.pView.GetValueAt(r.Intn(10))
+		assert.Equal(t, expected, chooseRandomNode(ag.rng, ag.pView, 0))
+	}
+}
+
+func TestAdvertiseAddr(t *testing.T) {
+	defer func(f func() ([]net.Addr, error)) { interfaceAddrs = f }(interfaceAddrs)
+	ipnet := func(s string) net.Addr {
+		ip, n, _ := net.ParseCIDR(s)
+		return &net.IPNet{IP: ip, Mask: n.Mask}
+	}
+	addrs := []net.Addr{ipnet("127.0.0.1/8"), ipnet("::1/128"), ipnet("fe80::1/64"), ipnet("fd00::2/64"), ipnet("192.0.2.2/24")}
+	interfaceAddrs = func() ([]net.Addr, error) { return addrs, nil }
+
+	for _, c := range []struct {
+		advertise, listen, expected string
+	}{
+		{"gog.example.com:8424", ":8424", "gog.example.com:8424"},
+		{"", "127.0.0.1:8424", "127.0.0.1:8424"},
+		{"", "localhost:8424", "localhost:8424"},
+		{"", ":8424", "192.0.2.2:8424"},
+		{"", "0.0.0.0:8424", "192.0.2.2:8424"},
+		{"", "[::]:8424", "[fd00::2]:8424"},
+	} {
+		cfg := &config.Config{AdvertiseAddr: c.advertise, AddrStr: c.listen}
+		assert.Equal(t, c.expected, advertiseAddr(cfg))
+	}
+
+	// Fall back to the loopback address.
+	addrs = addrs[:3]
+	assert.Equal(t, "127.0.0.1:8424", advertiseAddr(&config.Config{AddrStr: ":8424"}))
+}
+
+func TestAdvertiseAddrDialable(t *testing.T) {
+	cfg := newTestConfig(t)
+	_, port, _ := net.SplitHostPort(cfg.AddrStr)
+	cfg.AddrStr = "0.0.0.0:" + port
+	cfg.LocalTCPAddr = &net.TCPAddr{Port: cfg.LocalTCPAddr.Port}
+	ag := startTestAgent(t, cfg)
+	defer ag.Close()
+
+	self := ag.makeShuffleList()[0]
+	assert.NotEqual(t, cfg.AddrStr, self.GetAddr())
+	conn, err := net.Dial("tcp", self.GetAddr())
+	if assert.NoError(t, err) {
+		conn.Close()
+	}
+}
+
+func TestCheckViews(t *testing.T) {
+	ag := NewAgent(newTestConfig(t)).(*agent)
+	self := &node.Node{Id: ag.id, Addr: ag.config().AddrStr}
+
+	// A node in both views.
+	conn, remote := tcpPipe(t)
+	defer conn.Close()
+	defer remote.Close()
+	both := node.New(1, "both", conn)
+	ag.aView.Add(both.Id, both)
+	ag.pView.Add(both.Id, both)
+
+	// A node with a closed connection.
+	conn, _ = tcpPipe(t)
+	conn.Close()
+	closed := node.New(2, "closed", conn)
+	ag.aView.Add(closed.Id, closed)
+
+	ag.pView.Add(self.Id, self)
+
+	assert.Equal(t, 3, ag.checkViews())
+	assert.True(t, ag.aView.Has(both.Id))
+	assert.False(t, ag.pView.Has(both.Id))
+	assert.False(t, ag.aView.Has(closed.Id))
+	assert.True(t, ag.pView.Has(closed.Id))
+	assert.False(t, ag.pView.Has(self.Id))
+
+	assert.Equal(t, 0, ag.checkViews())
+}
+
+func TestSerializeHandler(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.SerializeHandler = true
+	ag := NewAgent(cfg).(*agent)
+
+	release := make(chan struct{})
+	delivered := make(chan string, 3)
+	ag.RegisterMessageHandler(func(msg Message) {
+		if string(msg.Payload) == "a1" {
+			<-release
+		}
+		delivered <- string(msg.Payload)
+	})
+
+	from := &node.Node{Id: 42}
+	for _, m := range []struct {
+		source  uint64
+		payload string
+	}{{1, "a1"}, {1, "a2"}, {2, "b1"}} {
+		ag.handleUserMessage(from, &message.UserMessage{
+			Id:      proto.Uint64(m.source),
+			Payload: []byte(m.payload),
+			Ts:      proto.Int64(time.Now().UnixNano()),
+		})
+	}
+
+	// The other source is not blocked by the first one.
+	assert.Equal(t, "b1", <-delivered)
+	select {
+	case p := <-delivered:
+		t.Fatalf("Unexpected delivery of %s", p)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	assert.Equal(t, "a1", <-delivered)
+	assert.Equal(t, "a2", <-delivered)
+}
+
+func TestReplaceActiveNodeRejoin(t *testing.T) {
+	seed := startTestAgent(t, newTestConfig(t))
+	defer seed.Close()
+
+	cfg := newTestConfig(t)
+	cfg.Peers = []string{seed.config().AddrStr}
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+
+	// Lose the last active node with an empty passive view.
+	conn, remote := tcpPipe(t)
+	defer remote.Close()
+	lost := node.New(1, "lost", conn)
+	ag.aView.Add(lost.Id, lost)
+
+	start := time.Now()
+	ag.replaceActiveNode(lost)
+	assert.True(t, time.Since(start) < time.Duration(cfg.HealDuration)*time.Second)
+	assert.True(t, ag.aView.Has(seed.id))
+	assert.True(t, ag.pView.Has(lost.Id))
+}
+
+func TestReplaceActiveNode(t *testing.T) {
+	peer := startTestAgent(t, newTestConfig(t))
+	defer peer.Close()
+
+	ag := NewAgent(newTestConfig(t)).(*agent)
+
+	conn1, remote1 := tcpPipe(t)
+	defer remote1.Close()
+	conn2, remote2 := tcpPipe(t)
+	defer remote2.Close()
+	defer ag.Close()
+
+	lost := node.New(1, "lost", conn1)
+	ag.aView.Add(lost.Id, lost)
+	ag.aView.Add(uint64(2), node.New(2, "alive", conn2))
+	ag.pView.Add(peer.id, &node.Node{Id: peer.id, Addr: peer.config().AddrStr})
+
+	ag.replaceActiveNode(lost)
+	assert.True(t, hasNode(ag, ag.aView, peer.id))
+	assert.False(t, hasNode(ag, ag.pView, peer.id))
+	assert.False(t, hasNode(ag, ag.aView, lost.Id))
+	assert.True(t, hasNode(ag, ag.pView, lost.Id))
+}
+
+func TestPromotePassiveNodes(t *testing.T) {
+	peers := []*agent{
+		startTestAgent(t, newTestConfig(t)),
+		startTestAgent(t, newTestConfig(t)),
+	}
+	for _, peer := range peers {
+		defer peer.Close()
+	}
+
+	cfg := newTestConfig(t)
+	cfg.AViewMinSize = 4
+	ag := NewAgent(cfg).(*agent)
+
+	// One active node, two live passive nodes and a dead one.
+	conn, remote := tcpPipe(t)
+	defer remote.Close()
+	defer ag.Close()
+	ag.aView.Add(uint64(1), node.New(1, "active", conn))
+	for _, peer := range peers {
+		ag.pView.Add(peer.id, &node.Node{Id: peer.id, Addr: peer.config().AddrStr})
+	}
+	dead := &node.Node{Id: 2, Addr: newTestConfig(t).AddrStr}
+	ag.pView.Add(dead.Id, dead)
+
+	// The minimum is out of reach, so all the passive nodes are tried.
+	ag.promotePassiveNodes(message.Neighbor_Low)
+
+	ag.viewMu.RLock()
+	assert.Equal(t, 3, ag.aView.Len())
+	ag.viewMu.RUnlock()
+	for _, peer := range peers {
+		assert.True(t, hasNode(ag, ag.aView, peer.id))
+		assert.False(t, hasNode(ag, ag.pView, peer.id))
+	}
+	assert.False(t, hasNode(ag, ag.pView, dead.Id))
+}
+
+func TestPromotePassiveNodesEscalation(t *testing.T) {
+	// The peer refuses the low priority requests with a full active view.
+	cfg := newTestConfig(t)
+	cfg.AViewMinSize, cfg.AViewMaxSize = 1, 1
+	peer := startTestAgent(t, cfg)
+	defer peer.Close()
+	conn, remote := tcpPipe(t)
+	defer remote.Close()
+	peer.viewMu.Lock()
+	peer.aView.Add(uint64(1), node.New(1, "full", conn))
+	peer.viewMu.Unlock()
+
+	ag := NewAgent(newTestConfig(t)).(*agent)
+	defer ag.Close()
+	ag.pView.Add(peer.id, &node.Node{Id: peer.id, Addr: peer.config().AddrStr})
+
+	ag.promotePassiveNodes(message.Neighbor_Low)
+	assert.False(t, hasNode(ag, ag.aView, peer.id))
+	assert.True(t, hasNode(ag, ag.pView, peer.id))
+
+	ag.promotePassiveNodes(message.Neighbor_High)
+	assert.True(t, hasNode(ag, ag.aView, peer.id))
+	assert.False(t, hasNode(ag, ag.pView, peer.id))
+}
+
+func TestHealRefill(t *testing.T) {
+	var peers []*agent
+	for i := 0; i < 3; i++ {
+		peer := startTestAgent(t, newTestConfig(t))
+		defer peer.Close()
+		peers = append(peers, peer)
+	}
+
+	// The active view is depleted to one node, below the minimum of 3.
+	cfg := newTestConfig(t)
+	cfg.AViewMinSize = 3
+	ag := startTestAgent(t, cfg)
+	defer ag.Close()
+	conn, remote := tcpPipe(t)
+	defer remote.Close()
+	ag.viewMu.Lock()
+	ag.aView.Add(uint64(1), node.New(1, "active", conn))
+	for _, peer := range peers {
+		ag.pView.Add(peer.id, &node.Node{Id: peer.id, Addr: peer.config().AddrStr})
+	}
+	ag.viewMu.Unlock()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ag.viewMu.RLock()
+		n := ag.aView.Len()
+		ag.viewMu.RUnlock()
+		if n >= cfg.AViewMinSize {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Active view has %d nodes, want %d", n, cfg.AViewMinSize)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMessageSender(t *testing.T) {
+	ag := NewAgent(newTestConfig(t)).(*agent)
+	received := make(chan Message, 1)
+	ag.RegisterMessageHandler(func(msg Message) { received <- msg })
+
+	// The message is broadcast by 7, and forwarded by 42.
+	ts := time.Now().UnixNano()
+	ag.handleUserMessage(&node.Node{Id: 42}, &message.UserMessage{
+		Id:      proto.Uint64(7),
+		Payload: []byte("hello"),
+		Ts:      proto.Int64(ts),
+	})
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, uint64(7), msg.SenderID)
+		assert.Equal(t, []byte("hello"), msg.Payload)
+		assert.Equal(t, ts, msg.Timestamp)
+	case <-time.After(time.Second):
+		t.Fatal("Message is not delivered")
+	}
+}
+
+// recordEvents records the active view events of the agent.
+func recordEvents(ag *agent) chan string {
+	events := make(chan string, 10)
+	ag.RegisterNeighborUp(func(id uint64, addr string) {
+		events <- fmt.Sprintf("up %d", id)
+	})
+	ag.RegisterNeighborDown(func(id uint64, addr string) {
+		events <- fmt.Sprintf("down %d", id)
+	})
+	return events
+}
+
+// nextEvent returns the next event, or "" if none arrives in time.
+func nextEvent(events chan string) string {
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(time.Second):
+		return ""
+	}
+}
+
+func TestNeighborEvents(t *testing.T) {
+	ag := startTestAgent(t, newTestConfig(t))
+	defer ag.Close()
+	events := recordEvents(ag)
+
+	peer := startTestAgent(t, newTestConfig(t))
+	defer peer.Close()
+	assert.NoError(t, peer.Join(ag.config().AddrStr))
+	assert.Equal(t, fmt.Sprintf("up %d", peer.id), nextEvent(events))
+
+	peer.Close()
+	assert.Equal(t, fmt.Sprintf("down %d", peer.id), nextEvent(events))
+}
+
+func TestNeighborEventsDedup(t *testing.T) {
+	ag := NewAgent(newTestConfig(t)).(*agent)
+	events := recordEvents(ag)
+
+	conn1, remote1 := tcpPipe(t)
+	defer remote1.Close()
+	conn2, remote2 := tcpPipe(t)
+	defer remote2.Close()
+	defer ag.Close()
+
+	// A new connection of the same node replaces the old one.
+	ag.viewMu.Lock()
+	ag.addNodeActiveView(node.New(1, "addr", conn1))
+	ag.addNodeActiveView(node.New(1, "addr", conn2))
+	ag.viewMu.Unlock()
+
+	assert.Equal(t, "up 1", nextEvent(events))
+	select {
+	case e := <-events:
+		t.Fatalf("Unexpected event %q", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.True(t, hasNode(ag, ag.aView, 1))
+}
+
+func TestShuffleReplyExistingConn(t *testing.T) {
+	ag := NewAgent(newTestConfig(t)).(*agent)
+
+	// The listener of the source, which should not be dialed.
+	ln, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	conn, remote := tcpPipe(t)
+	defer remote.Close()
+	defer ag.Close()
+	source := node.New(1, ln.Addr().String(), conn)
+	ag.aView.Add(source.Id, source)
+
+	ag.handleShuffle(&message.Shuffle{
+		Id:       proto.Uint64(source.Id),
+		SourceId: proto.Uint64(source.Id),
+		Addr:     proto.String(source.Addr),
+		Ttl:      proto.Uint32(0),
+	})
+
+	msg, err := readMsgTimeout(ag.codec, remote, time.Second)
+	assert.NoError(t, err)
+	assert.IsType(t, &message.ShuffleReply{}, msg)
+
+	ln.SetDeadline(time.Now().Add(100 * time.Millisecond))
+	if c, err := ln.Accept(); err == nil {
+		c.Close()
+		t.Fatal("Shuffle reply dialed a new connection")
+	}
+}
+
+func TestUDP(t *testing.T) {
+	cfgA, cfgB := newTestConfig(t), newTestConfig(t)
+	cfgA.UDP, cfgB.UDP = true, true
+	cfgA.PingTimeout, cfgA.PingMisses = 1000, 3
+	a := startTestAgent(t, cfgA)
+	defer a.Close()
+	b := startTestAgent(t, cfgB)
+	defer b.Close()
+	assert.Equal(t, caps|node.CapUDP, a.localCaps())
+
+	// The neighbors of each other, whose connections are not written.
+	connA, remoteA := tcpPipe(t)
+	defer remoteA.Close()
+	connB, remoteB := tcpPipe(t)
+	defer remoteB.Close()
+	ndB := node.New(b.id, cfgB.AddrStr, connA)
+	ndB.Caps = node.CapPing | node.CapUDP
+	a.aView.Add(ndB.Id, ndB)
+	ndA := node.New(a.id, cfgA.AddrStr, connB)
+	ndA.Caps = node.CapPing | node.CapUDP
+	b.aView.Add(a.id, ndA)
+
+	// The ping and the pong are datagrams.
+	a.pingOnce(time.Now())
+	answered := func() bool {
+		a.pinger.Lock()
+		defer a.pinger.Unlock()
+		return a.pinger.probes[ndB].seq == 0
+	}
+	for i := 0; i < 100 && !answered(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, answered())
+
+	// So is the reply to a shuffle from a node that reads them.
+	a.shuffle(ndB, a.makeShuffleList())
+	shuffleReply := func(nonce uint64, id uint64) {
+		b.shuffleReply(nil, &message.Shuffle{
+			Addr:  proto.String(cfgA.AddrStr),
+			Udp:   proto.Bool(true),
+			Nonce: proto.Uint64(nonce),
+		}, []*message.Candidate{
+			{Id: proto.Uint64(id), Addr: proto.String("candidate")},
+		})
+	}
+	for i := 0; i < 100 && !hasNode(a, a.pView, 42); i++ {
+		nonce, err := a.shuffles.add(time.Now())
+		assert.NoError(t, err)
+		shuffleReply(nonce, 42)
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, hasNode(a, a.pView, 42))
+
+	// The replies to the shuffles a did not send are dropped.
+	shuffleReply(12345, 43)
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, hasNode(a, a.pView, 43))
+
+	for _, conn := range []*net.TCPConn{remoteA, remoteB} {
+		_, err := readMsgTimeout(a.codec, conn, 50*time.Millisecond)
+		assert.True(t, isTimeout(err))
+	}
+	// The shuffle replies did not dial the connections to pool.
+	assert.Equal(t, 0, b.pool.len())
+	assert.True(t, atomic.LoadUint64(&a.stats.packetsSent) >= 2)
+	assert.True(t, atomic.LoadUint64(&b.stats.packetsReceived) >= 2)
+}
+
+func TestUDPClusterSecret(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.UDP = true
+	cfg.ClusterSecret = "secret"
+	ag := startTestAgent(t, cfg)
+	defer ag.Close()
+
+	conn, err := net.Dial("udp", cfg.AddrStr)
+	assert.NoError(t, err)
+	defer conn.Close()
+	send := func(id uint64, mac func([]byte) []byte) {
+		nonce, err := ag.shuffles.add(time.Now())
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		assert.NoError(t, ag.codec.WriteMsg(&message.ShuffleReply{
+			Id:         proto.Uint64(1),
+			Candidates: []*message.Candidate{{Id: proto.Uint64(id), Addr: proto.String("candidate")}},
+			Nonce:      proto.Uint64(nonce),
+		}, &buf))
+		buf.Write(mac(buf.Bytes()))
+		_, err = conn.Write(buf.Bytes())
+		assert.NoError(t, err)
+	}
+
+	// The datagrams without the HMAC of the secret are dropped.
+	send(42, func([]byte) []byte { return nil })
+	send(43, func(b []byte) []byte {
+		h := hmac.New(sha256.New, []byte("other"))
+		h.Write([]byte("packet"))
+		h.Write(b)
+		return h.Sum(nil)
+	})
+	for i := 0; i < 100 && atomic.LoadUint64(&ag.stats.authFailures) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, uint64(2), atomic.LoadUint64(&ag.stats.authFailures))
+
+	send(44, ag.packetMAC)
+	for i := 0; i < 100 && !hasNode(ag, ag.pView, 44); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, hasNode(ag, ag.pView, 44))
+	assert.False(t, hasNode(ag, ag.pView, 42))
+	assert.False(t, hasNode(ag, ag.pView, 43))
+}
+
+func TestPendingShuffles(t *testing.T) {
+	ps := newPendingShuffles()
+	now := time.Now()
+
+	// Each nonce is accepted once.
+	nonce, err := ps.add(now)
+	assert.NoError(t, err)
+	assert.False(t, ps.done(nonce+1, now))
+	assert.True(t, ps.done(nonce, now))
+	assert.False(t, ps.done(nonce, now))
+
+	// The expired ones are not.
+	nonce, err = ps.add(now)
+	assert.NoError(t, err)
+	assert.False(t, ps.done(nonce, now.Add(shuffleReplyTimeout+time.Second)))
+
+	// The oldest ones are dropped if there are too many.
+	var nonces []uint64
+	for i := 0; i < maxPendingShuffles+1; i++ {
+		nonce, err := ps.add(now.Add(time.Duration(i) * time.Millisecond))
+		assert.NoError(t, err)
+		nonces = append(nonces, nonce)
+	}
+	assert.Equal(t, maxPendingShuffles, len(ps.m))
+	assert.False(t, ps.done(nonces[0], now))
+	assert.True(t, ps.done(nonces[maxPendingShuffles], now))
+}
+
+func TestStats(t *testing.T) {
+	ag := NewAgent(newTestConfig(t)).(*agent)
+	ag.RegisterMessageHandler(func(Message) {})
+
+	from := &node.Node{Id: 1}
+	now := time.Now().UnixNano()
+	for _, m := range []struct {
+		payload string
+		ts      int64
+	}{{"a", now}, {"b", now}, {"a", now}, {"c", 0}} {
+		ag.handleUserMessage(from, &message.UserMessage{
+			Id:      proto.Uint64(1),
+			Payload: []byte(m.payload),
+			Ts:      proto.Int64(m.ts),
+		})
+	}
+	assert.NoError(t, ag.Broadcast([]byte("d")))
+
+	m := ag.Stats()
+	assert.Equal(t, uint64(1), m.Broadcasts)
+	assert.Equal(t, uint64(4), m.Received)
+	assert.Equal(t, uint64(1), m.Duplicates)
+	assert.Equal(t, uint64(1), m.Stale)
+	assert.Equal(t, int64(0), m.Uptime)
+
+	// The bytes of the connections are counted both ways.
+	local, remote := tcpPipe(t)
+	defer remote.Close()
+	conn := ag.wrapConn(local)
+	defer conn.Close()
+	_, err := conn.Write([]byte("hello"))
+	assert.NoError(t, err)
+	_, err = remote.Write([]byte("bye"))
+	assert.NoError(t, err)
+	_, err = io.ReadFull(conn, make([]byte, 3))
+	assert.NoError(t, err)
+	ag.aView.Add(uint64(1), node.New(1, "", conn))
+	ag.shuffleOnce()
+
+	m = ag.Stats()
+	assert.Equal(t, uint64(3), m.BytesIn)
+	assert.True(t, m.BytesOut >= 5)
+	assert.Equal(t, uint64(1), m.Shuffles)
+	assert.Equal(t, 1, m.ActiveView)
+	assert.False(t, isClosed(conn))
+}
+
+func TestPurgeMessages(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.PurgeDuration = 50
+	// The messages are kept until they are dead.
+	cfg.MLife = 50
+	ag := startTestAgent(t, cfg)
+	defer ag.Close()
+
+	from := &node.Node{Id: 1}
+	for i := 0; i < 100; i++ {
+		ag.handleUserMessage(from, &message.UserMessage{
+			Id:      proto.Uint64(1),
+			Payload: []byte(fmt.Sprintf("message %d", i)),
+			Ts:      proto.Int64(time.Now().UnixNano()),
+		})
+	}
+	assert.Equal(t, 100, ag.msgCache.len())
+
+	size := -1
+	for i := 0; i < 50 && size != 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		size = ag.msgCache.len()
+	}
+	assert.Equal(t, 0, size)
+}
+
+func TestMsgCache(t *testing.T) {
+	c := newMsgCache(3)
+	hash := func(i int) [sha1.Size]byte { return hashMessage([]byte{byte(i)}) }
+
+	assert.True(t, c.addIfAbsent(hash(1), 10, 0))
+	assert.True(t, c.addIfAbsent(hash(2), 20, 0))
+	assert.True(t, c.addIfAbsent(hash(3), 30, 0))
+	assert.False(t, c.addIfAbsent(hash(1), 40, 5))
+	// The least recently used entry is evicted when the cache is full.
+	assert.True(t, c.addIfAbsent(hash(4), 40, 5))
+	assert.Equal(t, 3, c.len())
+	assert.False(t, c.has(hash(2), 5))
+	assert.True(t, c.has(hash(1), 5))
+
+	// The expired entries are not hits, and are purged.
+	assert.False(t, c.has(hash(1), 15))
+	assert.True(t, c.addIfAbsent(hash(3), 50, 35))
+	assert.Equal(t, 1, c.purge(35))
+	assert.Equal(t, 2, c.len())
+	assert.True(t, c.has(hash(3), 35))
+	assert.True(t, c.has(hash(4), 35))
+
+	assert.Equal(t, uint64(1), c.hits)
+	assert.Equal(t, uint64(5), c.misses)
+	assert.Equal(t, uint64(1), c.evictions)
+}
+
+func TestReplayWindow(t *testing.T) {
+	r := newReplay()
+	assert.Equal(t, uint64(1), r.next())
+	assert.Equal(t, uint64(2), r.next())
+	assert.NotZero(t, r.epoch)
+
+	// The sequence numbers are accepted once, in any order.
+	assert.True(t, r.accept(1, 1, 3, 64, 0))
+	assert.True(t, r.accept(1, 1, 1, 64, 0))
+	assert.False(t, r.accept(1, 1, 3, 64, 0))
+	assert.False(t, r.accept(1, 1, 1, 64, 0))
+	assert.True(t, r.accept(1, 1, 2, 64, 0))
+	// The senders have their own windows.
+	assert.True(t, r.accept(2, 1, 3, 64, 0))
+
+	// The ones older than the window are dropped, and
+	// the ones sliding out of the window are forgotten.
+	assert.True(t, r.accept(1, 1, 66, 64, 0))
+	assert.False(t, r.accept(1, 1, 2, 64, 0))
+	assert.True(t, r.accept(1, 1, 4, 64, 0))
+	assert.True(t, r.accept(1, 1, 1000, 64, 0))
+	assert.False(t, r.accept(1, 1, 66, 64, 0))
+	assert.True(t, r.accept(1, 1, 999, 64, 0))
+	assert.False(t, r.accept(1, 1, 999, 64, 0))
+
+	// The idle senders are forgotten, and the
+	// dropped messages do not keep them.
+	assert.True(t, r.accept(2, 1, 4, 64, 10))
+	assert.False(t, r.accept(1, 1, 999, 64, 10))
+	assert.Equal(t, 1, r.purge(5))
+	assert.True(t, r.accept(1, 1, 1, 64, 10))
+
+	// The numbers restart with a later epoch, and
+	// the ones of the earlier epochs are dropped.
+	assert.True(t, r.accept(2, 2, 1, 64, 10))
+	assert.True(t, r.accept(2, 2, 2, 64, 10))
+	assert.False(t, r.accept(2, 1, 5, 64, 10))
+	assert.False(t, r.accept(2, 2, 1, 64, 10))
+}
+
+func TestReplayedMessage(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := newTestConfig(t)
+	cfg.ReplayWindow = 64
+	cfg.TrustedKeys = []ed25519.PublicKey{pub}
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+	delivered := make(chan Message, 3)
+	ag.RegisterMessageHandler(func(msg Message) { delivered <- msg })
+
+	cfg = newTestConfig(t)
+	cfg.SigningKey = key
+	signer := NewAgent(cfg).(*agent)
+	defer signer.Close()
+	msg := func(id, seq uint64) *message.UserMessage {
+		msg := &message.UserMessage{
+			Id:          proto.Uint64(id),
+			Payload:     []byte("hello"),
+			Ts:          proto.Int64(time.Now().UnixNano()),
+			OriginSeq:   proto.Uint64(seq),
+			OriginEpoch: proto.Uint64(1),
+		}
+		signer.sign(msg)
+		return msg
+	}
+
+	// The same payload from different senders is delivered from each.
+	ag.handleUserMessage(&node.Node{Id: 1}, msg(1, 1))
+	ag.handleUserMessage(&node.Node{Id: 2}, msg(2, 1))
+	for _, id := range []uint64{1, 2} {
+		select {
+		case m := <-delivered:
+			assert.Equal(t, []byte("hello"), m.Payload)
+		case <-time.After(time.Second):
+			t.Fatalf("Message of %d is not delivered", id)
+		}
+	}
+
+	// A replayed message is dropped, even after its hash is purged.
+	ag.msgCache.purge(time.Now().Add(time.Hour).UnixNano())
+	ag.handleUserMessage(&node.Node{Id: 2}, msg(1, 1))
+	select {
+	case <-delivered:
+		t.Fatal("Delivered a replayed message")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&ag.stats.replayed))
+	assert.Equal(t, uint64(0), atomic.LoadUint64(&ag.stats.duplicates))
+
+	// An unsigned message does not advance the window of the sender.
+	forged := &message.UserMessage{
+		Id:          proto.Uint64(1),
+		Payload:     []byte("forged"),
+		Ts:          proto.Int64(time.Now().UnixNano()),
+		OriginSeq:   proto.Uint64(1000),
+		OriginEpoch: proto.Uint64(1),
+	}
+	ag.handleUserMessage(&node.Node{Id: 2}, forged)
+	assert.Equal(t, []byte("forged"), (<-delivered).Payload)
+	ag.handleUserMessage(&node.Node{Id: 2}, msg(1, 2))
+	assert.Equal(t, []byte("hello"), (<-delivered).Payload)
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&ag.stats.replayed))
+
+	// The window is kept while the messages of the sender live,
+	// even if the sender is idle for longer than the message life.
+	long := msg(3, 1)
+	long.Life = proto.Int64(time.Minute.Milliseconds())
+	signer.sign(long)
+	ag.handleUserMessage(&node.Node{Id: 2}, long)
+	assert.Equal(t, []byte("hello"), (<-delivered).Payload)
+	now := time.Now().UnixNano()
+	ag.purgeMessages(now + int64(ag.config().MLife)*time.Millisecond.Nanoseconds() + 1)
+	ag.msgCache.purge(now + time.Hour.Nanoseconds())
+	ag.handleUserMessage(&node.Node{Id: 2}, long)
+	select {
+	case <-delivered:
+		t.Fatal("Delivered a replayed message")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, uint64(2), atomic.LoadUint64(&ag.stats.replayed))
+
+	// And forgotten once they are all dead.
+	ag.purgeMessages(now + 2*time.Minute.Nanoseconds())
+	ag.replay.Lock()
+	assert.Empty(t, ag.replay.senders)
+	ag.replay.Unlock()
+}
+
+func TestFIFO(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.FIFOWindow, cfg.FIFOGapTimeout = 4, 100
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+	delivered := make(chan string, 16)
+	ag.RegisterMessageHandler(func(msg Message) { delivered <- string(msg.Payload) })
+
+	epoch := uint64(1)
+	send := func(seqs ...uint64) {
+		for _, seq := range seqs {
+			ag.handleUserMessage(&node.Node{Id: 1}, &message.UserMessage{
+				Id:          proto.Uint64(1),
+				Payload:     []byte(strconv.FormatUint(seq, 10)),
+				Ts:          proto.Int64(time.Now().UnixNano()),
+				OriginSeq:   proto.Uint64(seq),
+				OriginEpoch: proto.Uint64(epoch),
+			})
+		}
+	}
+	expect := func(payloads ...string) {
+		for _, p := range payloads {
+			select {
+			case got := <-delivered:
+				assert.Equal(t, p, got)
+			case <-time.After(time.Second):
+				t.Fatalf("Message %s is not delivered", p)
+			}
+		}
+		select {
+		case got := <-delivered:
+			t.Fatalf("Delivered message %s", got)
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	// The messages are delivered in the order of the sequence numbers.
+	send(1, 3, 4)
+	expect("1")
+	send(2)
+	expect("2", "3", "4")
+
+	// The gap is skipped when the window is full.
+	send(6, 7, 8, 9)
+	expect("6", "7", "8", "9")
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&ag.stats.fifoSkipped))
+
+	// The gap is skipped when it is not filled in time,
+	// and the message filling it later is dropped.
+	send(11)
+	expect()
+	time.Sleep(150 * time.Millisecond)
+	expect("11")
+	send(10)
+	expect()
+	assert.Equal(t, uint64(2), atomic.LoadUint64(&ag.stats.fifoSkipped))
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&ag.stats.fifoLate))
+	assert.Equal(t, uint64(7), atomic.LoadUint64(&ag.stats.fifoBuffered))
+
+	// The restarted sender starts its sequence over.
+	epoch = 2
+	send(1, 3)
+	expect("1")
+	send(2)
+	expect("2", "3")
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&ag.stats.fifoLate))
+
+	// The late messages do not keep the idle queues.
+	ag.fifo.Lock()
+	deadline := ag.fifo.senders[fifoKey{sender: 1, epoch: 1}].deadline
+	ag.fifo.Unlock()
+	epoch = 1
+	send(5)
+	expect()
+	ag.purgeFIFO(deadline + 1)
+	ag.fifo.Lock()
+	_, ok := ag.fifo.senders[fifoKey{sender: 1, epoch: 1}]
+	_, restarted := ag.fifo.senders[fifoKey{sender: 1, epoch: 2}]
+	ag.fifo.Unlock()
+	assert.False(t, ok)
+	assert.True(t, restarted)
+}
+
+// testSpan is a span recorded by the testTracer.
+type testSpan struct {
+	name   string
+	id     uint64
+	parent uint64
+}
+
+func (s *testSpan) Context() []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, s.id)
+	return b
+}
+
+func (s *testSpan) End() {}
+
+// testTracer records the started spans.
+type testTracer struct {
+	sync.Mutex
+	spans []*testSpan
+}
+
+func (tr *testTracer) StartSpan(name string, parent []byte) Span {
+	tr.Lock()
+	defer tr.Unlock()
+	s := &testSpan{name: name, id: uint64(len(tr.spans) + 1)}
+	if parent != nil {
+		s.parent = binary.BigEndian.Uint64(parent)
+	}
+	tr.spans = append(tr.spans, s)
+	return s
+}
+
+// span returns the id and the parent of the last span with the name.
+func (tr *testTracer) span(name string) (uint64, uint64) {
+	tr.Lock()
+	defer tr.Unlock()
+	for i := len(tr.spans) - 1; i >= 0; i-- {
+		if tr.spans[i].name == name {
+			return tr.spans[i].id, tr.spans[i].parent
+		}
+	}
+	return 0, 0
+}
+
+func TestTraceUserMessage(t *testing.T) {
+	tracer := &testTracer{}
+	delivered := make(chan struct{}, 2)
+
+	// A broadcasts to B, which forwards to C.
+	var ags []*agent
+	for i := 0; i < 3; i++ {
+		ag := NewAgent(newTestConfig(t)).(*agent)
+		ag.RegisterTracer(tracer)
+		ag.RegisterMessageHandler(func(Message) { delivered <- struct{}{} })
+		ags = append(ags, ag)
+	}
+	a, b, c := ags[0], ags[1], ags[2]
+
+	conn, ab := tcpPipe(t)
+	defer ab.Close()
+	a.aView.Add(b.id, node.New(b.id, "", conn))
+	conn, bc := tcpPipe(t)
+	defer bc.Close()
+	b.aView.Add(c.id, node.New(c.id, "", conn))
+
+	assert.NoError(t, a.Broadcast([]byte("hello")))
+	broadcast, _ := tracer.span("broadcast")
+
+	// The first hop.
+	msg, err := readMsgTimeout(b.codec, ab, time.Second)
+	assert.NoError(t, err)
+	b.handleUserMessage(&node.Node{Id: a.id}, msg.(*message.UserMessage))
+	<-delivered
+	receive, parent := tracer.span("receive")
+	assert.Equal(t, broadcast, parent)
+	_, parent = tracer.span("deliver")
+	assert.Equal(t, receive, parent)
+	forward, parent := tracer.span("forward")
+	assert.Equal(t, receive, parent)
+
+	// The second hop.
+	msg, err = readMsgTimeout(c.codec, bc, time.Second)
+	assert.NoError(t, err)
+	c.handleUserMessage(&node.Node{Id: b.id}, msg.(*message.UserMessage))
+	<-delivered
+	receive, parent = tracer.span("receive")
+	assert.Equal(t, forward, parent)
+	_, parent = tracer.span("deliver")
+	assert.Equal(t, receive, parent)
+}
+
+func TestBroadcastWithOptions(t *testing.T) {
+	delivered := make(chan struct{}, 2)
+
+	// A broadcasts to B within 2 hops, which forwards to C,
+	// which does not forward to D.
+	var ags []*agent
+	for i := 0; i < 3; i++ {
+		ag := NewAgent(newTestConfig(t)).(*agent)
+		ag.RegisterMessageHandler(func(Message) { delivered <- struct{}{} })
+		defer ag.Close()
+		ags = append(ags, ag)
+	}
+	a, b, c := ags[0], ags[1], ags[2]
+
+	conn, ab := tcpPipe(t)
+	defer ab.Close()
+	a.aView.Add(b.id, node.New(b.id, "", conn))
+	conn, bc := tcpPipe(t)
+	defer bc.Close()
+	b.aView.Add(c.id, node.New(c.id, "", conn))
+	conn, cd := tcpPipe(t)
+	defer cd.Close()
+	c.aView.Add(4, node.New(4, "", conn))
+
+	assert.NoError(t, a.BroadcastWithOptions([]byte("hello"), BroadcastOptions{
+		Hops: 2,
+		Life: time.Second,
+	}))
+
+	msg, err := readMsgTimeout(b.codec, ab, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), msg.(*message.UserMessage).GetHops())
+	assert.Equal(t, int64(1000), msg.(*message.UserMessage).GetLife())
+	b.handleUserMessage(&node.Node{Id: a.id}, msg.(*message.UserMessage))
+	<-delivered
+
+	msg, err = readMsgTimeout(c.codec, bc, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), msg.(*message.UserMessage).GetHops())
+	assert.Equal(t, int64(1000), msg.(*message.UserMessage).GetLife())
+	c.handleUserMessage(&node.Node{Id: b.id}, msg.(*message.UserMessage))
+	<-delivered
+
+	_, err = readMsgTimeout(c.codec, cd, 200*time.Millisecond)
+	assert.Error(t, err)
+
+	// The message is stale after its own life, though not after the MLife.
+	c.handleUserMessage(&node.Node{Id: b.id}, &message.UserMessage{
+		Id:      proto.Uint64(a.id),
+		Payload: []byte("stale"),
+		Ts:      proto.Int64(time.Now().Add(-2 * time.Second).UnixNano()),
+		Life:    proto.Int64(1000),
+	})
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&c.stats.stale))
+
+	// The message is kept in the cache while it lives, even after the
+	// PurgeDuration, and its life is capped.
+	now := time.Now().UnixNano()
+	long := &message.UserMessage{Ts: proto.Int64(now), Life: proto.Int64(10000)}
+	assert.Equal(t, now+10*time.Second.Nanoseconds(), c.purgeDeadline(long, now))
+	long.Life = proto.Int64(math.MaxInt64)
+	assert.Equal(t, now+time.Hour.Nanoseconds(), c.messageDeadline(long))
+	long.Life = nil
+	assert.Equal(t, now+5*time.Second.Nanoseconds(), c.purgeDeadline(long, now))
+}
+
+func TestConnQueue(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.ConnQueueSize = 8
+	cfg.ConnHandlers = 1
+	ag := startTestAgent(t, cfg)
+	defer ag.Close()
+
+	gauges := func() (int32, int32) {
+		return atomic.LoadInt32(&ag.stats.queuedConns), atomic.LoadInt32(&ag.stats.handlingConns)
+	}
+	await := func(queued, handling int32) {
+		for i := 0; i < 100; i++ {
+			if q, h := gauges(); q == queued && h == handling {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		q, h := gauges()
+		t.Fatalf("Expect %d queued and %d handling, got %d and %d", queued, handling, q, h)
+	}
+
+	// The only handler is blocked by a silent connection,
+	// so the others are queued.
+	var conns []net.Conn
+	for i := 0; i < 4; i++ {
+		conn, err := net.Dial(cfg.Net, cfg.AddrStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, conn)
+	}
+	await(3, 1)
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+	await(0, 0)
+}
+
+func TestAcceptForwardJoinNeighbor(t *testing.T) {
+	peer := startTestAgent(t, newTestConfig(t))
+	defer peer.Close()
+	ag := NewAgent(newTestConfig(t)).(*agent)
+	defer ag.Close()
+
+	// The source becomes a neighbor while the agent talks to it.
+	conn, remote := tcpPipe(t)
+	defer remote.Close()
+	nd := node.New(peer.id, peer.config().AddrStr, conn)
+	ag.aView.Add(nd.Id, nd)
+
+	ag.acceptForwardJoin(&node.Node{Id: peer.id, Addr: peer.config().AddrStr})
+	assert.True(t, ag.aView.GetValueOf(peer.id) == nd)
+	assert.Equal(t, node.StateConnected, nd.State())
+
+	// The source is disconnected over the new connection.
+	for i := 0; i < 100 && hasNode(peer, peer.aView, ag.id); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.False(t, hasNode(peer, peer.aView, ag.id))
+}
+
+func TestForwardJoinThrottle(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.AViewMaxSize = 100
+	cfg.ForwardJoinRate = 4
+	cfg.ForwardJoinBurst = 5
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+	clock := time.Now()
+	ag.fjThrottle = newThrottle(cfg.ForwardJoinRate, cfg.ForwardJoinBurst)
+	ag.fjThrottle.now = func() time.Time { return clock }
+	ag.fjThrottle.last = clock
+
+	var forwarded int32
+	join := func(id uint64) {
+		conn, remote := tcpPipe(t)
+		go func() {
+			defer remote.Close()
+			for {
+				msg, err := ag.codec.ReadMsg(remote)
+				if err != nil {
+					return
+				}
+				if _, ok := msg.(*message.ForwardJoin); ok {
+					atomic.AddInt32(&forwarded, 1)
+				}
+			}
+		}()
+		ag.handleJoin(conn, &message.Join{
+			Id:   proto.Uint64(id),
+			Addr: proto.String("joiner"),
+		})
+	}
+	settled := func() int32 {
+		time.Sleep(200 * time.Millisecond)
+		return atomic.LoadInt32(&forwarded)
+	}
+
+	// A mass join only gets the burst forwarded.
+	for i := 1; i <= 20; i++ {
+		join(uint64(i))
+	}
+	assert.Equal(t, int32(5), settled())
+
+	// Then the joins are forwarded at the rate.
+	ag.fjThrottle.Lock()
+	clock = clock.Add(time.Second)
+	ag.fjThrottle.Unlock()
+	for i := 21; i <= 40; i++ {
+		join(uint64(i))
+	}
+	assert.Equal(t, int32(9), settled())
+}
+
+func TestRequestReply(t *testing.T) {
+	peer := startTestAgent(t, newTestConfig(t))
+	defer peer.Close()
+	peer.RegisterRequestHandler(func(payload []byte) []byte {
+		if string(payload) != "ping" {
+			return nil
+		}
+		return []byte("pong")
+	})
+
+	ag := startTestAgent(t, newTestConfig(t))
+	defer ag.Close()
+	assert.NoError(t, ag.Join(peer.config().AddrStr))
+
+	reply, err := ag.Request([]byte("ping"), time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("pong"), reply)
+
+	// Nobody replies.
+	_, err = ag.Request([]byte("hello"), 100*time.Millisecond)
+	assert.Equal(t, ErrRequestTimeout, err)
+}
+
+func TestTopology(t *testing.T) {
+	agents := make([]*agent, 3)
+	for i := range agents {
+		agents[i] = startTestAgent(t, newTestConfig(t))
+		defer agents[i].Close()
+		if i > 0 {
+			assert.NoError(t, agents[i].Join(agents[i-1].config().AddrStr))
+		}
+	}
+	ids := func(topo *Topology) []uint64 {
+		var ids []uint64
+		for _, nd := range topo.Nodes {
+			ids = append(ids, nd.ID)
+		}
+		return ids
+	}
+
+	// Without hops, only the agent itself is in the graph.
+	topo, err := agents[0].Topology(0, 100*time.Millisecond)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []uint64{agents[0].id}, ids(topo))
+		assert.Contains(t, topo.Edges, TopologyEdge{From: agents[0].id, To: agents[1].id})
+	}
+
+	// The crawl reaches every node, with their active views.
+	topo, err = agents[0].Topology(3, 500*time.Millisecond)
+	if assert.NoError(t, err) {
+		expected := []uint64{agents[0].id, agents[1].id, agents[2].id}
+		sort.Slice(expected, func(i, j int) bool { return expected[i] < expected[j] })
+		assert.Equal(t, expected, ids(topo))
+		assert.Contains(t, topo.Edges, TopologyEdge{From: agents[2].id, To: agents[1].id})
+		assert.Contains(t, topo.Nodes, TopologyNode{ID: agents[2].id, Addr: agents[2].addr})
+	}
+}
+
+func TestTopologyRoute(t *testing.T) {
+	ag := NewAgent(newTestConfig(t)).(*agent)
+	defer ag.Close()
+	conn, remoteA := tcpPipe(t)
+	defer remoteA.Close()
+	ndA := node.New(1, "", conn)
+	ag.aView.Add(ndA.Id, ndA)
+	conn, remoteB := tcpPipe(t)
+	defer remoteB.Close()
+	ndB := node.New(2, "", conn)
+	ag.aView.Add(ndB.Id, ndB)
+
+	// The reply goes back to the neighbor the request came from, and
+	// not to the claimed address, and the ttl is lowered to the maximum.
+	ag.handleTopologyRequest(ndA, &message.TopologyRequest{
+		Id:    proto.Uint64(1),
+		ReqId: proto.Uint64(7),
+		Addr:  proto.String("10.0.0.1:1"),
+		Ttl:   proto.Uint32(math.MaxUint32),
+		Ts:    proto.Int64(time.Now().UnixNano()),
+	})
+	msg, err := readMsgTimeout(ag.codec, remoteA, time.Second)
+	if assert.NoError(t, err) {
+		assert.Equal(t, ag.id, msg.(*message.TopologyReply).GetId())
+	}
+	msg, err = readMsgTimeout(ag.codec, remoteB, time.Second)
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint32(MaxTopologyTTL-1), msg.(*message.TopologyRequest).GetTtl())
+	}
+
+	// The replies from further are routed back the same way.
+	ag.handleTopologyReply(&message.TopologyReply{Id: proto.Uint64(3), ReqId: proto.Uint64(7), Addr: proto.String("")})
+	msg, err = readMsgTimeout(ag.codec, remoteA, time.Second)
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint64(3), msg.(*message.TopologyReply).GetId())
+	}
+	ag.handleTopologyReply(&message.TopologyReply{Id: proto.Uint64(3), ReqId: proto.Uint64(8), Addr: proto.String("")})
+	for _, conn := range []*net.TCPConn{remoteA, remoteB} {
+		_, err = readMsgTimeout(ag.codec, conn, 50*time.Millisecond)
+		assert.True(t, isTimeout(err))
+	}
+
+	// The routes expire, and are bounded.
+	pt := newPendingTopologies()
+	now := time.Now().UnixNano()
+	assert.True(t, pt.route(1, ndA, now+1, now))
+	assert.Equal(t, ndA, pt.next(1, now))
+	assert.Nil(t, pt.next(1, now+1))
+	for i := 0; i < maxTopologyRoutes; i++ {
+		assert.True(t, pt.route(uint64(i), ndA, now+1, now))
+	}
+	assert.False(t, pt.route(maxTopologyRoutes, ndA, now+1, now))
+	assert.True(t, pt.route(maxTopologyRoutes, ndA, now+2, now+1))
+}
+
+func TestSettings(t *testing.T) {
+	agents := make([]*agent, 3)
+	changes := make(chan map[string]string, 10)
+	for i := range agents {
+		agents[i] = startTestAgent(t, newTestConfig(t))
+		defer agents[i].Close()
+		if i > 0 {
+			assert.NoError(t, agents[i].Join(agents[i-1].config().AddrStr))
+		}
+	}
+	agents[2].RegisterSettingsHandler(func(changed map[string]string) { changes <- changed })
+	also := make(chan map[string]string, 10)
+	agents[2].RegisterSettingsHandler(func(changed map[string]string) { also <- changed })
+	converged := func(key, value string) bool {
+		for i := 0; i < 100; i++ {
+			ok := true
+			for _, ag := range agents {
+				ok = ok && ag.Settings()[key] == value
+			}
+			if ok {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+
+	// The setting gossips to every agent.
+	agents[0].SetSettings(map[string]string{"foo": "1"})
+	assert.True(t, converged("foo", "1"))
+	select {
+	case changed := <-changes:
+		assert.Equal(t, map[string]string{"foo": "1"}, changed)
+	case <-time.After(time.Second):
+		t.Fatal("Settings handler is not called")
+	}
+	select {
+	case changed := <-also:
+		assert.Equal(t, map[string]string{"foo": "1"}, changed)
+	case <-time.After(time.Second):
+		t.Fatal("Second settings handler is not called")
+	}
+
+	// The tunables are applied by every agent.
+	assert.NoError(t, agents[0].SetSettings(map[string]string{"passive_view": "40"}))
+	assert.True(t, converged("passive_view", "40"))
+	for _, ag := range agents {
+		assert.Equal(t, 40, ag.config().PViewSize)
+	}
+
+	// The settings this agent cannot apply are rejected.
+	for _, values := range []map[string]string{
+		{"address": "localhost:1"},
+		{"passive_view": "-1"},
+		{"log_level": "foo"},
+		{"foo": strings.Repeat("x", maxSettingSize)},
+	} {
+		assert.Error(t, agents[0].SetSettings(values), "%v", values)
+	}
+
+	// The peers cannot set the other fields of the config,
+	// the settings too large, nor too many keys.
+	peer := &node.Node{Id: 1}
+	setting := func(k, v string) *message.Setting {
+		return &message.Setting{
+			Key:    proto.String(k),
+			Value:  proto.String(v),
+			Ts:     proto.Int64(time.Now().UnixNano()),
+			Origin: proto.Uint64(1),
+		}
+	}
+	agents[1].mergeSettings(peer, []*message.Setting{
+		setting("cluster_secret", `"secret"`),
+		setting("bar", strings.Repeat("x", maxSettingSize)),
+	})
+	_, ok := agents[1].Settings()["cluster_secret"]
+	assert.False(t, ok)
+	_, ok = agents[1].Settings()["bar"]
+	assert.False(t, ok)
+	var many []*message.Setting
+	for i := 0; i < maxSettings; i++ {
+		many = append(many, setting(strconv.Itoa(i), "x"))
+	}
+	agents[1].mergeSettings(peer, many)
+	assert.Equal(t, maxSettings, len(agents[1].Settings()))
+	assert.Equal(t, ErrTooManySettings, agents[1].SetSettings(map[string]string{"baz": "1"}))
+
+	// The last write wins, wherever it is written.
+	agents[2].SetSettings(map[string]string{"foo": "2"})
+	assert.True(t, converged("foo", "2"))
+	agents[1].mergeSettings(nil, []*message.Setting{{
+		Key:    proto.String("foo"),
+		Value:  proto.String("stale"),
+		Ts:     proto.Int64(1),
+		Origin: proto.Uint64(agents[1].id),
+	}})
+	assert.Equal(t, "2", agents[1].Settings()["foo"])
+
+	// A new neighbor is sent all the settings.
+	late := startTestAgent(t, newTestConfig(t))
+	defer late.Close()
+	assert.NoError(t, late.Join(agents[0].config().AddrStr))
+	agents = append(agents, late)
+	assert.True(t, converged("foo", "2"))
+}
+
+func TestRequestNode(t *testing.T) {
+	peer := startTestAgent(t, newTestConfig(t))
+	defer peer.Close()
+	peer.RegisterRequestHandler(func(payload []byte) []byte {
+		switch string(payload) {
+		case "ping":
+			return []byte("pong")
+		case "slow":
+			time.Sleep(200 * time.Millisecond)
+			return []byte("late")
+		}
+		return nil
+	})
+
+	cfg := newTestConfig(t)
+	cfg.ConnPoolSize, cfg.ConnIdleTimeout = 4, 10
+	ag := startTestAgent(t, cfg)
+	defer ag.Close()
+	assert.NoError(t, ag.Join(peer.config().AddrStr))
+
+	_, err := ag.RequestNode(1, []byte("ping"), time.Second)
+	assert.Equal(t, ErrNodeNotFound, err)
+
+	// The second request reuses the connection of the first one.
+	for i := 0; i < 2; i++ {
+		reply, err := ag.RequestNode(peer.id, []byte("ping"), time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("pong"), reply)
+	}
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&ag.stats.reusedConns))
+
+	_, err = ag.RequestNode(peer.id, []byte("hello"), time.Second)
+	assert.Equal(t, ErrNoReply, err)
+	_, err = ag.RequestNode(peer.id, []byte("slow"), 50*time.Millisecond)
+	assert.Equal(t, ErrRequestTimeout, err)
+}
+
+func TestSignedMessages(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, other, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := newTestConfig(t)
+	cfg.SigningKey = key
+	a := NewAgent(cfg).(*agent)
+	defer a.Close()
+	cfg = newTestConfig(t)
+	cfg.SigningKey = other
+	c := NewAgent(cfg).(*agent)
+	defer c.Close()
+	cfg = newTestConfig(t)
+	cfg.TrustedKeys = []ed25519.PublicKey{pub}
+	b := NewAgent(cfg).(*agent)
+	defer b.Close()
+	delivered := make(chan DeliveredMessage, 1)
+	b.RegisterDeliveredMessageHandler(func(msg DeliveredMessage) { delivered <- msg })
+
+	conn, ab := tcpPipe(t)
+	defer ab.Close()
+	a.aView.Add(b.id, node.New(b.id, "", conn))
+	conn, cb := tcpPipe(t)
+	defer cb.Close()
+	c.aView.Add(b.id, node.New(b.id, "", conn))
+
+	assert.NoError(t, a.Broadcast([]byte("hello")))
+	msg, err := readMsgTimeout(b.codec, ab, time.Second)
+	if !assert.NoError(t, err) {
+		return
+	}
+	signed := msg.(*message.UserMessage)
+	b.handleUserMessage(&node.Node{Id: a.id}, signed)
+	dm := <-delivered
+	assert.Equal(t, Verified, dm.Signature)
+	assert.Equal(t, fingerprint(pub), dm.KeyFingerprint)
+	assert.Equal(t, []byte("hello"), dm.Payload)
+
+	assert.NoError(t, c.Broadcast([]byte("world")))
+	msg, err = readMsgTimeout(b.codec, cb, time.Second)
+	if !assert.NoError(t, err) {
+		return
+	}
+	b.handleUserMessage(&node.Node{Id: c.id}, msg.(*message.UserMessage))
+	assert.Equal(t, UnknownKey, (<-delivered).Signature)
+
+	forged := proto.Clone(signed).(*message.UserMessage)
+	forged.Payload = []byte("forged")
+	b.handleUserMessage(&node.Node{Id: a.id}, forged)
+	assert.Equal(t, BadSignature, (<-delivered).Signature)
+
+	unsigned := &message.UserMessage{
+		Id:      proto.Uint64(a.id),
+		Payload: []byte("unsigned"),
+		Ts:      proto.Int64(time.Now().UnixNano()),
+	}
+	b.handleUserMessage(&node.Node{Id: a.id}, unsigned)
+	assert.Equal(t, Unsigned, (<-delivered).Signature)
+
+	// Only the verified messages are delivered if required.
+	cfg = newTestConfig(t)
+	cfg.TrustedKeys = []ed25519.PublicKey{pub}
+	cfg.RequireSigned = true
+	d := NewAgent(cfg).(*agent)
+	defer d.Close()
+	d.RegisterDeliveredMessageHandler(func(msg DeliveredMessage) { delivered <- msg })
+	// The topic, the life and the sender are signed too, and a forged
+	// copy received first does not make the signed message a duplicate.
+	retopic := proto.Clone(signed).(*message.UserMessage)
+	retopic.Topic = proto.String("other")
+	relife := proto.Clone(signed).(*message.UserMessage)
+	relife.Life = proto.Int64(maxMessageLife)
+	resent := proto.Clone(signed).(*message.UserMessage)
+	resent.Id = proto.Uint64(c.id)
+	for _, msg := range []*message.UserMessage{unsigned, forged, retopic, relife, resent, signed} {
+		d.handleUserMessage(&node.Node{Id: a.id}, msg)
+	}
+	assert.Equal(t, []byte("hello"), (<-delivered).Payload)
+	assert.Equal(t, uint64(5), atomic.LoadUint64(&d.stats.unverified))
+}
+
+func TestClusterSecret(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.ClusterSecret = "secret"
+	peer := startTestAgent(t, cfg)
+	defer peer.Close()
+
+	// The agents without the secret cannot join.
+	for _, secret := range []string{"", "wrong"} {
+		cfg := newTestConfig(t)
+		cfg.ClusterSecret = secret
+		ag := startTestAgent(t, cfg)
+		assert.Equal(t, ErrNoAvailablePeers, ag.Join(peer.config().AddrStr))
+		assert.False(t, hasNode(peer, peer.aView, ag.id))
+		ag.Close()
+	}
+	assert.Equal(t, uint64(2), atomic.LoadUint64(&peer.stats.authFailures))
+
+	cfg = newTestConfig(t)
+	cfg.ClusterSecret = "secret"
+	ag := startTestAgent(t, cfg)
+	defer ag.Close()
+	assert.NoError(t, ag.Join(peer.config().AddrStr))
+	assert.True(t, hasNode(ag, ag.aView, peer.id))
+
+	// The peer that does not prove the secret cannot send any message,
+	// and a proof of another connection does not prove it.
+	for _, caps := range []uint32{0, capAuth} {
+		conn, err := net.Dial("tcp", peer.config().AddrStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := ag.localPreamble()
+		p.caps = caps
+		conn.Write(p.marshal())
+		_, err = readPreamble(conn)
+		assert.NoError(t, err)
+		if caps != 0 {
+			nonce := make([]byte, nonceSize)
+			conn.Write(nonce)
+			peerNonce := make([]byte, nonceSize)
+			_, err = io.ReadFull(conn, peerNonce)
+			assert.NoError(t, err)
+			conn.Write(ag.proof("dial", nonce, make([]byte, nonceSize)))
+		}
+		peer.codec.WriteMsg(&message.ShuffleReply{Id: proto.Uint64(1)}, conn)
+		// The connection is closed, or reset as the message is unread.
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = conn.Read(make([]byte, 1))
+		assert.True(t, err != nil && !isTimeout(err), "%v", err)
+		conn.Close()
+	}
+	assert.Equal(t, uint64(4), atomic.LoadUint64(&peer.stats.authFailures))
+}
+
+func TestNodesWithLabel(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Labels = map[string]string{"region": "eu"}
+	peer := startTestAgent(t, cfg)
+	defer peer.Close()
+
+	cfg = newTestConfig(t)
+	cfg.Labels = map[string]string{"region": "us", "role": "web"}
+	ag := startTestAgent(t, cfg)
+	defer ag.Close()
+	assert.NoError(t, ag.Join(peer.config().AddrStr))
+
+	nodes := ag.NodesWithLabel("region", "eu")
+	if assert.Equal(t, 1, len(nodes)) {
+		assert.Equal(t, peer.id, nodes[0].Id)
+	}
+	assert.Empty(t, ag.NodesWithLabel("region", "us"))
+
+	// The peer adds the joined node after replying.
+	for i := 0; i < 100 && len(peer.NodesWithLabel("role", "web")) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	nodes = peer.NodesWithLabel("role", "web")
+	if assert.Equal(t, 1, len(nodes)) {
+		assert.Equal(t, ag.id, nodes[0].Id)
+		assert.Equal(t, cfg.Labels, nodes[0].Labels)
+		// The nodes are copies, without the connections.
+		assert.Equal(t, node.StateIdle, nodes[0].State())
+		nodes[0].Labels["role"] = "db"
+		assert.Len(t, peer.NodesWithLabel("role", "web"), 1)
+	}
+}
+
+func TestSetLabels(t *testing.T) {
+	peer := startTestAgent(t, newTestConfig(t))
+	defer peer.Close()
+	ag := startTestAgent(t, newTestConfig(t))
+	defer ag.Close()
+
+	labels := map[string]string{"role": "db"}
+	ag.SetLabels(labels)
+	// The labels are copied.
+	labels["role"] = "web"
+	assert.NoError(t, ag.Join(peer.config().AddrStr))
+
+	for i := 0; i < 100 && len(peer.NodesWithLabel("role", "db")) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	nodes := peer.NodesWithLabel("role", "db")
+	if assert.Equal(t, 1, len(nodes)) {
+		assert.Equal(t, ag.id, nodes[0].Id)
+	}
+	assert.Empty(t, peer.NodesWithLabel("role", "web"))
+}
+
+func TestRejectTooLargeMessage(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.MaxMessageSize = 1024
+	ag := startTestAgent(t, cfg)
+	defer ag.Close()
+
+	conn, err := net.Dial(cfg.Net, cfg.AddrStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, err = conn.Write(ag.localPreamble().marshal())
+	assert.NoError(t, err)
+	_, err = readPreamble(conn)
+	assert.NoError(t, err)
+
+	// A frame header claiming 2GB.
+	buf := bytes.NewBuffer([]byte{0xab, 0xcd})
+	binary.Write(buf, binary.LittleEndian, uint32(2<<30))
+	_, err = buf.WriteTo(conn)
+	assert.NoError(t, err)
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestHandshake(t *testing.T) {
+	ag := startTestAgent(t, newTestConfig(t))
+	defer ag.Close()
+
+	// The agents of the same version and codec interoperate,
+	// and learn the capabilities of each other.
+	peer := NewAgent(newTestConfig(t)).(*agent)
+	defer peer.Close()
+	conn, err := peer.connect(ag.config().AddrStr)
+	if assert.NoError(t, err) {
+		assert.Equal(t, ag.localCaps(), peerCaps(conn))
+		assert.Equal(t, ag.localCaps(), nodeCaps(nil, conn))
+		assert.Equal(t, uint32(0), nodeCaps(proto.Uint32(0), conn))
+		conn.Close()
+	}
+
+	// A different codec.
+	cfg := newTestConfig(t)
+	cfg.Codec = config.CodecJSON
+	other := NewAgent(cfg).(*agent)
+	defer other.Close()
+	_, err = other.connect(ag.config().AddrStr)
+	assert.True(t, errors.Is(err, ErrIncompatibleCodec), "%v", err)
+
+	for _, b := range [][]byte{
+		// An older version.
+		(&preamble{version: minProtocolVersion - 1}).marshal(),
+		// An agent that predates the handshake.
+		{0xab, 0xcd, 0, 0, 0, 0, 0, 0, 0},
+	} {
+		conn, err := net.Dial("tcp", ag.config().AddrStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = conn.Write(b)
+		assert.NoError(t, err)
+		// The agent replies its preamble, and closes the connection.
+		p, err := readPreamble(conn)
+		if assert.NoError(t, err) {
+			assert.Equal(t, uint8(protocolVersion), p.version)
+		}
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = conn.Read(make([]byte, 1))
+		assert.Equal(t, io.EOF, err)
+		conn.Close()
+	}
+
+	// The peer that does not reply the handshake.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			conn.Write([]byte{0xab, 0xcd, 0, 0, 0, 0, 0, 0, 0})
+			conn.Close()
+		}
+	}()
+	_, err = ag.connect(ln.Addr().String())
+	assert.True(t, errors.Is(err, ErrIncompatibleProtocol), "%v", err)
+
+	// The peer that never replies is given up, without a read timeout.
+	defer func(timeout time.Duration) { handshakeTimeout = timeout }(handshakeTimeout)
+	handshakeTimeout = 100 * time.Millisecond
+	cfg = newTestConfig(t)
+	cfg.ReadTimeout = 0
+	dialer := NewAgent(cfg).(*agent)
+	defer dialer.Close()
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	go func() {
+		if conn, err := silent.Accept(); err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+	_, err = dialer.connect(silent.Addr().String())
+	assert.True(t, isTimeout(err), "%v", err)
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	ag := NewAgent(newTestConfig(t)).(*agent)
+	conn, remote := tcpPipe(t)
+	defer conn.Close()
+	defer remote.Close()
+	nd := node.New(1, "", conn)
+
+	const n = 100
+	for i := 0; i < n; i++ {
+		go ag.userMessage(nd, &message.UserMessage{
+			Id:      proto.Uint64(uint64(i)),
+			Payload: bytes.Repeat([]byte{byte(i)}, 64*1024),
+			Ts:      proto.Int64(0),
+		})
+	}
+
+	seen := make(map[uint64]bool)
+	for i := 0; i < n; i++ {
+		msg, err := readMsgTimeout(ag.codec, remote, 5*time.Second)
+		if !assert.NoError(t, err) {
+			return
+		}
+		umsg := msg.(*message.UserMessage)
+		assert.Equal(t, bytes.Repeat([]byte{byte(umsg.GetId())}, 64*1024), umsg.GetPayload())
+		seen[umsg.GetId()] = true
+	}
+	assert.Equal(t, n, len(seen))
+}
+
+func TestLeave(t *testing.T) {
+	peer := startTestAgent(t, newTestConfig(t))
+	defer peer.Close()
+
+	cfg := newTestConfig(t)
+	ag := NewAgent(cfg).(*agent)
+	ag.RegisterMessageHandler(func(Message) {})
+	served := make(chan error, 1)
+	go func() { served <- ag.Serve() }()
+	assert.NoError(t, ag.Join(peer.config().AddrStr))
+	for i := 0; i < 100 && !hasNode(peer, peer.aView, ag.id); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, hasNode(peer, peer.aView, ag.id))
+
+	assert.NoError(t, ag.Leave())
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Serve does not return after leaving")
+	}
+
+	// The peer is notified and drops the agent.
+	for i := 0; i < 100 && hasNode(peer, peer.aView, ag.id); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.False(t, hasNode(peer, peer.aView, ag.id))
+	assert.True(t, hasNode(peer, peer.pView, ag.id))
+	_, err := net.Dial(cfg.Net, cfg.AddrStr)
+	assert.Error(t, err)
+}
+
+func TestLeaveSlowNeighbor(t *testing.T) {
+	ag := NewAgent(newTestConfig(t)).(*agent)
+
+	// The write to the first neighbor blocks, as it does not read.
+	slow, slowRemote := net.Pipe()
+	defer slowRemote.Close()
+	ag.aView.Add(1, node.New(1, "slow", slow))
+	conn, remote := tcpPipe(t)
+	defer remote.Close()
+	ag.aView.Add(2, node.New(2, "fast", conn))
+
+	left := make(chan error, 1)
+	go func() { left <- ag.Leave() }()
+
+	// The other neighbor is disconnected meanwhile,
+	// and the view is not locked.
+	msg, err := readMsgTimeout(ag.codec, remote, time.Second)
+	if assert.NoError(t, err) {
+		assert.IsType(t, &message.Disconnect{}, msg)
+	}
+	ag.viewMu.Lock()
+	ag.viewMu.Unlock()
+
+	slowRemote.Close()
+	select {
+	case err := <-left:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Leave does not return")
+	}
+}
+
+func TestShutdownSlowNeighbor(t *testing.T) {
+	ag := NewAgent(newTestConfig(t)).(*agent)
+
+	slow, slowRemote := net.Pipe()
+	defer slowRemote.Close()
+	ag.aView.Add(1, node.New(1, "slow", slow))
+	conn, remote := tcpPipe(t)
+	defer remote.Close()
+	ag.aView.Add(2, node.New(2, "fast", conn))
+
+	// The other neighbor is disconnected, and the agent is
+	// closed without waiting for the slow one after the deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, ag.Shutdown(ctx))
+	msg, err := readMsgTimeout(ag.codec, remote, time.Second)
+	if assert.NoError(t, err) {
+		assert.IsType(t, &message.Disconnect{}, msg)
+	}
+	assert.Equal(t, 0, ag.aView.Len())
+}
+
+func TestReadTimeout(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.ReadTimeout = 1
+	ag := NewAgent(cfg).(*agent)
+
+	// The node goes silent after joining.
+	conn, remote := tcpPipe(t)
+	defer remote.Close()
+	defer ag.Close()
+	start := time.Now()
+	assert.True(t, ag.handleJoin(conn, &message.Join{
+		Id:      proto.Uint64(1),
+		Addr:    proto.String("silent"),
+		Observe: proto.Bool(true),
+	}))
+	for hasNode(ag, ag.aView, 1) && time.Since(start) < 3*time.Second {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.False(t, hasNode(ag, ag.aView, 1))
+	assert.True(t, hasNode(ag, ag.pView, 1))
+	assert.True(t, time.Since(start) >= time.Second)
+}
+
+func TestWriteTimeout(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.WriteTimeout = 1
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+
+	// The neighbor stops reading, so the write to the pipe blocks.
+	local, remote := net.Pipe()
+	defer remote.Close()
+	nd := node.New(1, "stuck", ag.wrapConn(local))
+	ag.aView.Add(nd.Id, nd)
+	go ag.serveNode(nd)
+
+	start := time.Now()
+	ag.userMessage(nd, &message.UserMessage{Id: proto.Uint64(ag.id), Payload: []byte("hello"), Ts: proto.Int64(time.Now().UnixNano())})
+	for hasNode(ag, ag.aView, 1) && time.Since(start) < 3*time.Second {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.False(t, hasNode(ag, ag.aView, 1))
+	assert.True(t, time.Since(start) >= time.Second)
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&ag.stats.failedSends))
+}
+
+// startTestCluster starts n agents, and joins each agent to the two
+// agents started before it, so there are redundant links. A join is
+// refused if the agents have become neighbors by a forward join.
+func startTestCluster(t *testing.T, n int, plumtree bool) ([]*agent, chan int) {
+	delivered := make(chan int, 1024)
+	agents := make([]*agent, n)
+	for i := range agents {
+		cfg := newTestConfig(t)
+		cfg.Plumtree = plumtree
+		cfg.GraftTimeout = 200
+		agents[i] = startTestAgent(t, cfg)
+		i := i
+		agents[i].RegisterMessageHandler(func(Message) { delivered <- i })
+		for j := i - 1; j >= 0 && j >= i-2; j-- {
+			if err := agents[i].Join(agents[j].config().AddrStr); err != nil && !hasNode(agents[i], agents[i].aView, agents[j].id) {
+				t.Fatal(err)
+			}
+		}
+	}
+	return agents, delivered
+}
+
+// payloadSends broadcasts the messages from the first agent, and
+// returns the number of the user messages sent by all the agents.
+func payloadSends(t *testing.T, plumtree bool) uint64 {
+	agents, delivered := startTestCluster(t, 8, plumtree)
+	defer func() {
+		for _, ag := range agents {
+			ag.Close()
+		}
+	}()
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, agents[0].Broadcast([]byte(fmt.Sprintf("message %d", i))))
+		received := make(map[int]bool)
+		timeout := time.After(5 * time.Second)
+		for len(received) < len(agents)-1 {
+			select {
+			case j := <-delivered:
+				received[j] = true
+			case <-timeout:
+				t.Fatalf("Message %d is received by %d agents", i, len(received))
+			}
+		}
+		// Let the prunes arrive.
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	var sends uint64
+	for _, ag := range agents {
+		sends += atomic.LoadUint64(&ag.stats.payloadSends)
+	}
+	return sends
+}
+
+func TestPlumtreeBroadcast(t *testing.T) {
+	flood := payloadSends(t, false)
+	plumtree := payloadSends(t, true)
+	t.Logf("Payload sends, flood: %d, plumtree: %d", flood, plumtree)
+	assert.True(t, plumtree < flood)
+}
+
+func TestPlumtreeGraft(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Plumtree = true
+	cfg.GraftTimeout = 50
+	ag := NewAgent(cfg).(*agent)
+
+	conn, remote := tcpPipe(t)
+	defer remote.Close()
+	defer ag.Close()
+	from := node.New(1, "lazy", conn)
+	ag.aView.Add(from.Id, from)
+	ag.plumtree.setLazy(from.Id, true)
+
+	// The announced message is missing, so the announcer is grafted.
+	hash := hashMessage([]byte("missing"))
+	ag.handleIHave(from, &message.IHave{Id: proto.Uint64(1), MsgIds: [][]byte{hash[:]}})
+	msg, err := readMsgTimeout(ag.codec, remote, time.Second)
+	if assert.NoError(t, err) {
+		assert.Equal(t, hash[:], msg.(*message.Graft).GetMsgId())
+	}
+	assert.False(t, ag.plumtree.isLazy(from.Id))
+
+	// The grafted message is sent back.
+	umsg := &message.UserMessage{Id: proto.Uint64(2), Payload: []byte("cached"), Ts: proto.Int64(1)}
+	hash = hashMessage(umsg.GetPayload())
+	ag.plumtree.received(hash, umsg, time.Now().Add(time.Minute).UnixNano())
+	ag.handleGraft(from, &message.Graft{Id: proto.Uint64(1), MsgId: hash[:]})
+	msg, err = readMsgTimeout(ag.codec, remote, time.Second)
+	if assert.NoError(t, err) {
+		assert.Equal(t, umsg, msg)
+	}
+}
+
+func TestStateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := newTestConfig(t)
+	cfg.StateFile = filepath.Join(dir, "state.json")
+	ag := NewAgent(cfg).(*agent)
+	for i := 1; i <= 3; i++ {
+		ag.pView.Append(uint64(i), &node.Node{Id: uint64(i), Addr: fmt.Sprintf("127.0.0.1:%d", 9000+i)})
+	}
+	assert.NoError(t, ag.saveState())
+
+	ag = NewAgent(cfg).(*agent)
+	assert.Equal(t, 3, ag.pView.Len())
+	for i := 1; i <= 3; i++ {
+		assert.True(t, hasNode(ag, ag.pView, uint64(i)))
+		assert.Equal(t, fmt.Sprintf("127.0.0.1:%d", 9000+i), ag.pView.GetValueOf(uint64(i)).Addr)
+	}
+	assert.Contains(t, ag.bootstrapPeers(), "127.0.0.1:9001")
+
+	// A corrupt state file is ignored.
+	assert.NoError(t, ioutil.WriteFile(cfg.StateFile, []byte("{corrupt"), 0644))
+	ag = NewAgent(cfg).(*agent)
+	assert.Equal(t, 0, ag.pView.Len())
+}
+
+func TestRestoreState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	peer := startTestAgent(t, newTestConfig(t))
+	defer peer.Close()
+
+	cfg := newTestConfig(t)
+	cfg.StateFile = filepath.Join(dir, "state.json")
+	ag := startTestAgent(t, cfg)
+	assert.NoError(t, ag.Join(peer.config().AddrStr))
+	// The active view is saved when the agent is closed.
+	ag.Close()
+
+	// The restarted agent rejoins through the saved nodes without
+	// any configured peers, before the heal loop would.
+	restarted := *cfg
+	restarted.Peers = nil
+	restarted.HealDuration = 60
+	ag = startTestAgent(t, &restarted)
+	defer ag.Close()
+	for i := 0; i < 100 && !hasNode(ag, ag.aView, peer.id); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, hasNode(ag, ag.aView, peer.id))
+}
+
+func TestJoinSelf(t *testing.T) {
+	cfg := newTestConfig(t)
+	ag := startTestAgent(t, cfg)
+	defer ag.Close()
+	peer := startTestAgent(t, newTestConfig(t))
+	defer peer.Close()
+
+	_, port, _ := net.SplitHostPort(cfg.AddrStr)
+	assert.Equal(t, ErrNoAvailablePeers, ag.Join(cfg.AddrStr))
+	assert.Equal(t, ErrNoAvailablePeers, ag.Join("localhost:"+port))
+	assert.NoError(t, ag.Join(cfg.AddrStr, peer.config().AddrStr))
+	time.Sleep(100 * time.Millisecond)
+
+	assert.False(t, hasNode(ag, ag.aView, ag.id))
+	assert.True(t, hasNode(ag, ag.aView, peer.id))
+	assert.Equal(t, 0, int(atomic.LoadInt32(&ag.stats.handlingConns)))
+
+	// The agent listening on all the interfaces.
+	cfg.LocalTCPAddr = &net.TCPAddr{Port: cfg.LocalTCPAddr.Port}
+	assert.True(t, ag.isSelf(&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: cfg.LocalTCPAddr.Port}))
+	assert.False(t, ag.isSelf(&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: peer.config().LocalTCPAddr.Port}))
+
+	// The forward join of self is skipped.
+	ag.handleForwardJoin(&message.ForwardJoin{
+		Id:         proto.Uint64(peer.id),
+		SourceId:   proto.Uint64(ag.id + 1),
+		SourceAddr: proto.String(cfg.AddrStr),
+		Ttl:        proto.Uint32(0),
+	})
+	assert.False(t, hasNode(ag, ag.aView, ag.id+1))
+}
+
+func TestResendFailedMessages(t *testing.T) {
+	ag := NewAgent(newTestConfig(t)).(*agent)
+	local, remote := tcpPipe(t)
+	defer remote.Close()
+	defer ag.Close()
+	ag.aView.Add(uint64(1), node.New(1, "neighbor", local))
+
+	now := time.Now().UnixNano()
+	expired := time.Duration(ag.config().MLife+1) * time.Millisecond
+	ag.bufferFailedMessage(&message.UserMessage{Id: proto.Uint64(2), Payload: []byte("expired"), Ts: proto.Int64(now - expired.Nanoseconds())})
+	ag.bufferFailedMessage(&message.UserMessage{Id: proto.Uint64(2), Payload: []byte("fresh"), Ts: proto.Int64(now)})
+
+	ag.resendFailedMessages()
+	assert.Equal(t, 0, ag.failmsgBuffer.Len())
+
+	msg, err := readMsgTimeout(ag.codec, remote, time.Second)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []byte("fresh"), msg.(*message.UserMessage).GetPayload())
+	}
+	_, err = readMsgTimeout(ag.codec, remote, 100*time.Millisecond)
+	assert.True(t, isTimeout(err))
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&ag.stats.resent))
+}
+
+func TestFailedMessageBufferSize(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.FailedMessageBufferSize = 3
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+
+	now := time.Now().UnixNano()
+	for i := 0; i < 5; i++ {
+		ag.bufferFailedMessage(&message.UserMessage{Id: proto.Uint64(2), Payload: []byte{byte(i)}, Ts: proto.Int64(now + int64(i))})
+		// A duplicate does not evict.
+		ag.bufferFailedMessage(&message.UserMessage{Id: proto.Uint64(2), Payload: []byte{byte(i)}, Ts: proto.Int64(now + int64(i))})
+	}
+	assert.Equal(t, 3, ag.failmsgBuffer.Len())
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, i >= 2, ag.failmsgBuffer.Has(hashMessage([]byte{byte(i)})), "message %d", i)
+	}
+}
+
+func TestSpool(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.SpoolFile = filepath.Join(t.TempDir(), "spool")
+	ag := NewAgent(cfg).(*agent)
+
+	now := time.Now().UnixNano()
+	expired := time.Duration(cfg.MLife+1) * time.Millisecond
+	msgs := []*message.UserMessage{
+		{Id: proto.Uint64(2), Payload: []byte("expired"), Ts: proto.Int64(now - expired.Nanoseconds())},
+		{Id: proto.Uint64(2), Payload: []byte("a"), Ts: proto.Int64(now)},
+		{Id: proto.Uint64(2), Payload: []byte("b"), Ts: proto.Int64(now + 1)},
+		{Id: proto.Uint64(2), Payload: []byte("c"), Ts: proto.Int64(now + 2)},
+	}
+	for _, msg := range msgs {
+		ag.bufferFailedMessage(msg)
+	}
+	// The messages are kept without a neighbor to resend them to.
+	ag.resendFailedMessages()
+	assert.Equal(t, 4, ag.failmsgBuffer.Len())
+
+	// The oldest messages beyond the size of the spool are dropped.
+	cfg.SpoolSize = 2 * (1 + proto.Size(msgs[3]))
+	assert.NoError(t, ag.saveSpool())
+	assert.Equal(t, 2, ag.failmsgBuffer.Len())
+	assert.Equal(t, uint64(2), atomic.LoadUint64(&ag.stats.spoolDropped))
+	ag.Close()
+
+	// The messages are recovered after restart, and
+	// resent once the agent has a neighbor.
+	ag = NewAgent(cfg).(*agent)
+	defer ag.Close()
+	assert.Equal(t, 2, ag.failmsgBuffer.Len())
+	local, remote := tcpPipe(t)
+	defer remote.Close()
+	ag.viewMu.Lock()
+	ag.addNodeActiveView(node.New(1, "neighbor", local))
+	ag.viewMu.Unlock()
+	for _, payload := range []string{"b", "c"} {
+		msg, err := readMsgTimeout(ag.codec, remote, time.Second)
+		if assert.NoError(t, err) {
+			assert.Equal(t, payload, string(msg.(*message.UserMessage).GetPayload()))
+		}
+	}
+	assert.Equal(t, 0, ag.failmsgBuffer.Len())
+
+	// The spool file is removed when there is no failed message.
+	assert.NoError(t, ag.saveSpool())
+	_, err := os.Stat(cfg.SpoolFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSpoolBroadcast(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := newTestConfig(t)
+	cfg.SpoolFile, cfg.SpoolSize = filepath.Join(t.TempDir(), "spool"), 1<<20
+	cfg.ID = 42
+	cfg.SigningKey = key
+	ag := NewAgent(cfg).(*agent)
+
+	// The broadcast without a neighbor is kept to resend.
+	assert.NoError(t, ag.Broadcast([]byte("hello")))
+	assert.Equal(t, 1, ag.failmsgBuffer.Len())
+	epoch := ag.replay.epoch
+	assert.NoError(t, ag.saveSpool())
+	ag.Close()
+
+	// The restarted agent numbers it in its new epoch, so the receivers
+	// that have its messages of the new epoch already do not drop it.
+	time.Sleep(time.Millisecond)
+	ag = startTestAgent(t, cfg)
+	defer ag.Close()
+	assert.NotEqual(t, epoch, ag.replay.epoch)
+	ag.failmsgBuffer.Range(func(_ [sha1.Size]byte, msg *message.UserMessage) bool {
+		assert.Equal(t, ag.replay.epoch, msg.GetOriginEpoch())
+		return true
+	})
+
+	cfg = newTestConfig(t)
+	cfg.ReplayWindow = 64
+	cfg.TrustedKeys = []ed25519.PublicKey{pub}
+	peer := startTestAgent(t, cfg)
+	defer peer.Close()
+	delivered := make(chan string, 2)
+	peer.RegisterMessageHandler(func(msg Message) { delivered <- string(msg.Payload) })
+	fresh := &message.UserMessage{
+		Id:          proto.Uint64(ag.id),
+		Payload:     []byte("fresh"),
+		Ts:          proto.Int64(time.Now().UnixNano()),
+		OriginSeq:   proto.Uint64(ag.replay.next()),
+		OriginEpoch: proto.Uint64(ag.replay.epoch),
+	}
+	ag.sign(fresh)
+	peer.handleUserMessage(&node.Node{Id: ag.id}, fresh)
+	assert.Equal(t, "fresh", <-delivered)
+
+	assert.NoError(t, ag.Join(cfg.AddrStr))
+	select {
+	case payload := <-delivered:
+		assert.Equal(t, "hello", payload)
+	case <-time.After(time.Second):
+		t.Fatal("Spooled broadcast is not delivered")
+	}
+	assert.Equal(t, uint64(0), atomic.LoadUint64(&peer.stats.replayed))
+}
+
+func TestStart(t *testing.T) {
+	cfg1 := newTestConfig(t)
+	ag1, err := Start(cfg1)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ag1.Close()
+	ag2, err := Start(newTestConfig(t))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ag2.Close()
+
+	received := make(chan []byte, 1)
+	ag1.RegisterMessageHandler(func(msg Message) { received <- msg.Payload })
+	assert.NoError(t, ag2.Join(cfg1.AddrStr))
+	for i := 0; i < 100 && !hasNode(ag1.(*agent), ag1.(*agent).aView, ag2.(*agent).id); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.NoError(t, ag2.Broadcast([]byte("hello")))
+	select {
+	case b := <-received:
+		assert.Equal(t, []byte("hello"), b)
+	case <-time.After(time.Second):
+		t.Fatal("Message is not delivered")
+	}
+	assert.NoError(t, ag2.Leave())
+
+	// The address is in use.
+	_, err = Start(cfg1)
+	assert.Error(t, err)
+}
+
+func TestTopics(t *testing.T) {
+	cfg := newTestConfig(t)
+	ag := startTestAgent(t, cfg)
+	defer ag.Close()
+	peer := startTestAgent(t, newTestConfig(t))
+	defer peer.Close()
+
+	received := make(chan Message, 10)
+	handler := func(msg Message) { received <- msg }
+	peer.RegisterMessageHandler(handler)
+	peer.RegisterTopicHandler("foo", handler)
+	peer.RegisterTopicHandler("bar", handler)
+	assert.NoError(t, peer.Join(cfg.AddrStr))
+	for i := 0; i < 100 && !hasNode(ag, ag.aView, peer.id); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The same payload of different topics are different messages,
+	// and so are the same payload broadcast twice.
+	assert.NoError(t, ag.BroadcastTopic("foo", []byte("hello")))
+	assert.NoError(t, ag.BroadcastTopic("bar", []byte("hello")))
+	assert.NoError(t, ag.Broadcast([]byte("hello")))
+	assert.NoError(t, ag.BroadcastTopic("baz", []byte("hello")))
+	assert.NoError(t, ag.BroadcastTopic("foo", []byte("hello")))
+
+	topics := make(map[string]int)
+	for i := 0; i < 5; i++ {
+		select {
+		case msg := <-received:
+			assert.Equal(t, []byte("hello"), msg.Payload)
+			topics[msg.Topic]++
+		case <-time.After(time.Second):
+			t.Fatal("Message is not delivered")
+		}
+	}
+	assert.Equal(t, map[string]int{"foo": 2, "bar": 1, "": 1, "baz": 1}, topics)
+	select {
+	case msg := <-received:
+		t.Fatalf("Duplicate message %v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestTopicHandlers(t *testing.T) {
+	ag := NewAgent(newTestConfig(t)).(*agent)
+	defer ag.Close()
+
+	received := make(chan string, 10)
+	ag.RegisterMessageHandler(func(msg Message) { received <- "default:" + string(msg.Payload) })
+	ag.RegisterTopicHandler("foo", func(msg Message) { received <- "foo:" + string(msg.Payload) })
+	ag.RegisterTopicHandler("bar", func(msg Message) { received <- "bar:" + string(msg.Payload) })
+
+	ts := time.Now().UnixNano()
+	for _, msg := range []*message.UserMessage{
+		{Id: proto.Uint64(1), Payload: []byte("a"), Ts: proto.Int64(ts), Topic: proto.String("foo")},
+		{Id: proto.Uint64(1), Payload: []byte("b"), Ts: proto.Int64(ts), Topic: proto.String("bar")},
+		{Id: proto.Uint64(1), Payload: []byte("c"), Ts: proto.Int64(ts)},
+	} {
+		ag.handleUserMessage(&node.Node{Id: 1}, msg)
+	}
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		select {
+		case s := <-received:
+			got = append(got, s)
+		case <-time.After(time.Second):
+			t.Fatal("Message is not delivered")
+		}
+	}
+	sort.Strings(got)
+	assert.Equal(t, []string{"bar:b", "default:c", "foo:a"}, got)
+}
+
+// testTLSConfig writes a self-signed certificate for 127.0.0.1, which
+// is also the CA, and loads the TLS config of the agents from it.
+func testTLSConfig(t *testing.T, dir string, requireClientCert bool) *tls.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gog"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(crand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		TLSCert:              filepath.Join(dir, "cert.pem"),
+		TLSKey:               filepath.Join(dir, "key.pem"),
+		TLSCA:                filepath.Join(dir, "cert.pem"),
+		TLSRequireClientCert: requireClientCert,
+	}
+	if err := ioutil.WriteFile(cfg.TLSCert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(cfg.TLSKey, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tlsConfig, err := config.LoadTLSConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tlsConfig
+}
+
+func TestTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tlsConfig := testTLSConfig(t, dir, true)
+
+	cfg := newTestConfig(t)
+	cfg.TLSConfig = tlsConfig
+	ag := startTestAgent(t, cfg)
+	defer ag.Close()
+	peerCfg := newTestConfig(t)
+	peerCfg.TLSConfig = tlsConfig
+	peer := startTestAgent(t, peerCfg)
+	defer peer.Close()
+
+	received := make(chan []byte, 1)
+	peer.RegisterMessageHandler(func(msg Message) { received <- msg.Payload })
+	assert.NoError(t, peer.Join(cfg.AddrStr))
+	for i := 0; i < 100 && !hasNode(ag, ag.aView, peer.id); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, hasNode(ag, ag.aView, peer.id))
+	assert.NoError(t, ag.Broadcast([]byte("hello")))
+	select {
+	case b := <-received:
+		assert.Equal(t, []byte("hello"), b)
+	case <-time.After(time.Second):
+		t.Fatal("Message is not delivered")
+	}
+	assert.False(t, isClosed(ag.aView.GetValueOf(peer.id).Conn()))
+
+	// A peer over plain TCP cannot join.
+	plain := startTestAgent(t, newTestConfig(t))
+	defer plain.Close()
+	assert.Error(t, plain.Join(cfg.AddrStr))
+
+	// A peer without a client certificate cannot join.
+	noCertCfg := newTestConfig(t)
+	noCertCfg.TLSConfig = &tls.Config{RootCAs: tlsConfig.RootCAs}
+	noCert := startTestAgent(t, noCertCfg)
+	defer noCert.Close()
+	assert.Error(t, noCert.Join(cfg.AddrStr))
+	assert.False(t, hasNode(ag, ag.aView, noCert.id))
+}
+
+func TestMemoryTransport(t *testing.T) {
+	m := transport.NewMemory()
+	newMemoryConfig := func(addr string) *config.Config {
+		cfg := newTestConfig(t)
+		cfg.AddrStr, cfg.LocalTCPAddr, cfg.Transport = addr, nil, m
+		return cfg
+	}
+	ag := NewAgent(newMemoryConfig("foo")).(*agent)
+	defer ag.Close()
+	peer := NewAgent(newMemoryConfig("bar")).(*agent)
+	defer peer.Close()
+	for _, a := range []*agent{ag, peer} {
+		assert.NoError(t, a.start())
+		go a.serve()
+	}
+
+	received := make(chan []byte, 1)
+	peer.RegisterMessageHandler(func(msg Message) { received <- msg.Payload })
+	assert.Equal(t, ErrNoAvailablePeers, peer.Join("bar"))
+	assert.NoError(t, peer.Join("foo"))
+	for i := 0; i < 100 && !hasNode(ag, ag.aView, peer.id); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, hasNode(ag, ag.aView, peer.id))
+
+	assert.NoError(t, ag.Broadcast([]byte("hello")))
+	select {
+	case b := <-received:
+		assert.Equal(t, []byte("hello"), b)
+	case <-time.After(time.Second):
+		t.Fatal("Message is not delivered")
+	}
+
+	// The peer replaces the closed agent.
+	ag.Close()
+	for i := 0; i < 100 && hasNode(peer, peer.aView, ag.id); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.False(t, hasNode(peer, peer.aView, ag.id))
+}
+
+// queued returns the number of the user messages queued to the node,
+// and whether a goroutine is writing them.
+func queued(ag *agent, nd *node.Node) (int, bool) {
+	ag.sendq.mu.Lock()
+	defer ag.sendq.mu.Unlock()
+	q, running := ag.sendq.queues[nd]
+	if !running {
+		return 0, false
+	}
+	return len(q.user), running
+}
+
+func TestSendQueue(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.SendQueueSize = 2
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+
+	// The writes to the pipe block until the other end reads.
+	local, remote := net.Pipe()
+	defer remote.Close()
+	nd := node.New(1, "neighbor", local)
+	newMsg := func(payload string) *message.UserMessage {
+		return &message.UserMessage{Id: proto.Uint64(ag.id), Payload: []byte(payload), Ts: proto.Int64(time.Now().UnixNano())}
+	}
+
+	// The first message is being written, the next two are queued,
+	// and the last one is dropped.
+	ag.userMessage(nd, newMsg("0"))
+	for i := 0; i < 100; i++ {
+		if n, _ := queued(ag, nd); n == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	ag.userMessage(nd, newMsg("1"))
+	ag.userMessage(nd, newMsg("2"))
+	ag.userMessage(nd, newMsg("3"))
+	n, running := queued(ag, nd)
+	assert.Equal(t, 2, n)
+	assert.True(t, running)
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&ag.stats.sendDrops))
+
+	// The sender blocks until the queue has room.
+	cfg.SendQueuePolicy = config.SendQueueBlock
+	sent := make(chan struct{})
+	go func() {
+		ag.userMessage(nd, newMsg("4"))
+		close(sent)
+	}()
+	select {
+	case <-sent:
+		t.Fatal("Sender is not blocked")
+	case <-time.After(100 * time.Millisecond):
+	}
+	for _, payload := range []string{"0", "1", "2", "4"} {
+		msg, err := ag.codec.ReadMsg(remote)
+		if assert.NoError(t, err) {
+			assert.Equal(t, []byte(payload), msg.(*message.UserMessage).GetPayload())
+		}
+	}
+	<-sent
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&ag.stats.sendDrops))
+
+	// The blocked sender gives up when the agent is closed.
+	for i := 0; i < 4; i++ {
+		go ag.userMessage(nd, newMsg("5"))
+	}
+	for i := 0; i < 100; i++ {
+		if n, _ := queued(ag, nd); n == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	ag.Close()
+	for i := 0; i < 100 && atomic.LoadUint64(&ag.stats.sendDrops) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, uint64(2), atomic.LoadUint64(&ag.stats.sendDrops))
+}
+
+func TestBlockedBroadcast(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.SendQueueSize = 1
+	cfg.SendQueuePolicy = config.SendQueueBlock
+	ag := NewAgent(cfg).(*agent)
+
+	// The neighbor does not read, so the queue fills up.
+	local, remote := net.Pipe()
+	defer remote.Close()
+	ag.aView.Add(1, node.New(1, "neighbor", local))
+	sent := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			ag.Broadcast([]byte{byte(i)})
+		}
+		close(sent)
+	}()
+	for i := 0; i < 100 && ag.sendq.len(ag.aView.GetValueOf(1)) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The blocked broadcast does not hold the view lock.
+	select {
+	case <-sent:
+		t.Fatal("Broadcast is not blocked")
+	case <-time.After(50 * time.Millisecond):
+	}
+	locked := make(chan struct{})
+	go func() {
+		ag.viewMu.Lock()
+		ag.viewMu.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("View lock is held by the blocked broadcast")
+	}
+	ag.Close()
+	<-sent
+}
+
+func TestControlPriority(t *testing.T) {
+	cfg := newTestConfig(t)
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+
+	local, remote := net.Pipe()
+	defer remote.Close()
+	nd := node.New(1, "neighbor", local)
+	newMsg := func(payload string) *message.UserMessage {
+		return &message.UserMessage{Id: proto.Uint64(ag.id), Payload: []byte(payload), Ts: proto.Int64(time.Now().UnixNano())}
+	}
+
+	// The first message is being written, the next two are queued.
+	ag.userMessage(nd, newMsg("0"))
+	for i := 0; i < 100; i++ {
+		if n, _ := queued(ag, nd); n == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	ag.userMessage(nd, newMsg("1"))
+	ag.userMessage(nd, newMsg("2"))
+
+	// The ping is written right after the message in flight.
+	done := ag.controlMessage(nd, &message.Ping{Id: proto.Uint64(ag.id), Seq: proto.Uint64(1)})
+	for _, want := range []string{"0", "ping", "1", "2"} {
+		msg, err := ag.codec.ReadMsg(remote)
+		if !assert.NoError(t, err) {
+			return
+		}
+		switch m := msg.(type) {
+		case *message.Ping:
+			assert.Equal(t, "ping", want)
+		case *message.UserMessage:
+			assert.Equal(t, want, string(m.GetPayload()))
+		default:
+			t.Fatalf("Unexpected message %T", msg)
+		}
+	}
+	assert.NoError(t, <-done)
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&ag.stats.controlSends))
+}
+
+func TestBatch(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.BatchSize, cfg.BatchInterval = 3, 50
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+	peer := NewAgent(newTestConfig(t)).(*agent)
+	defer peer.Close()
+	delivered := make(chan []byte, 5)
+	peer.RegisterMessageHandler(func(msg Message) { delivered <- msg.Payload })
+
+	local, remote := tcpPipe(t)
+	defer remote.Close()
+	nd := node.New(1, "neighbor", local)
+	nd.Caps = node.CapBatch
+	for i := 0; i < 5; i++ {
+		ag.userMessage(nd, &message.UserMessage{
+			Id:      proto.Uint64(ag.id),
+			Payload: []byte{byte(i)},
+			Ts:      proto.Int64(time.Now().UnixNano()),
+		})
+	}
+
+	// The messages are written in a full batch, and a batch of the rest.
+	var sizes []int
+	for i := 0; i < 2; i++ {
+		msg, err := readMsgTimeout(ag.codec, remote, time.Second)
+		if !assert.NoError(t, err) {
+			return
+		}
+		batch := msg.(*message.Batch)
+		sizes = append(sizes, len(batch.GetMessages()))
+		from := &node.Node{Id: ag.id}
+		for _, umsg := range batch.GetMessages() {
+			peer.handleUserMessage(from, umsg)
+		}
+	}
+	assert.Equal(t, []int{3, 2}, sizes)
+	received := make(map[byte]bool)
+	for i := 0; i < 5; i++ {
+		received[(<-delivered)[0]] = true
+	}
+	assert.Equal(t, 5, len(received))
+	assert.Equal(t, uint64(2), atomic.LoadUint64(&ag.stats.batches))
+	assert.Equal(t, uint64(5), atomic.LoadUint64(&ag.stats.payloadSends))
+
+	// The node that does not read the batches gets the messages one by one.
+	nd.Caps = 0
+	ag.userMessage(nd, &message.UserMessage{Id: proto.Uint64(ag.id), Payload: []byte("foo"), Ts: proto.Int64(time.Now().UnixNano())})
+	ag.userMessage(nd, &message.UserMessage{Id: proto.Uint64(ag.id), Payload: []byte("bar"), Ts: proto.Int64(time.Now().UnixNano())})
+	for _, payload := range []string{"foo", "bar"} {
+		msg, err := readMsgTimeout(ag.codec, remote, time.Second)
+		if assert.NoError(t, err) {
+			assert.Equal(t, []byte(payload), msg.(*message.UserMessage).GetPayload())
+		}
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.BatchSize, cfg.BatchInterval = 4, 50
+	cfg.MaxMessageSize = 1000
+	ag := NewAgent(cfg).(*agent)
+	defer ag.Close()
+
+	local, remote := tcpPipe(t)
+	defer remote.Close()
+	nd := node.New(1, "neighbor", local)
+	nd.Caps = node.CapBatch
+	for i := 0; i < 4; i++ {
+		ag.userMessage(nd, &message.UserMessage{
+			Id:      proto.Uint64(ag.id),
+			Payload: bytes.Repeat([]byte{byte(i)}, 300),
+			Ts:      proto.Int64(time.Now().UnixNano()),
+		})
+	}
+
+	// The batch is split to stay below the maximum message size.
+	var sizes []int
+	for i := 0; i < 2; i++ {
+		msg, err := readMsgTimeout(ag.codec, remote, time.Second)
+		if !assert.NoError(t, err) {
+			return
+		}
+		batch := msg.(*message.Batch)
+		assert.True(t, proto.Size(batch) < cfg.MaxMessageSize)
+		sizes = append(sizes, len(batch.GetMessages()))
+	}
+	assert.Equal(t, []int{2, 2}, sizes)
+}
+
+func TestReliableRetry(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Reliable, cfg.AckTimeout, cfg.AckRetries = true, 20, 2
+	ag := NewAgent(cfg).(*agent)
+	local, remote := tcpPipe(t)
+	defer remote.Close()
+	defer ag.Close()
+	nd := node.New(1, "neighbor", local)
+	ag.aView.Add(nd.Id, nd)
+
+	failed := make(chan Message, 1)
+	ag.RegisterDeliveryFailure(func(id uint64, addr string, msg Message) {
+		assert.Equal(t, nd.Id, id)
+		assert.Equal(t, nd.Addr, addr)
+		failed <- msg
+	})
+
+	// The acknowledged message is not sent again.
+	ag.userMessage(nd, &message.UserMessage{Id: proto.Uint64(ag.id), Payload: []byte("foo"), Ts: proto.Int64(time.Now().UnixNano())})
+	msg, err := readMsgTimeout(ag.codec, remote, time.Second)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ag.handleAck(nd, &message.Ack{Id: proto.Uint64(nd.Id), Seq: msg.(*message.UserMessage).Seq})
+	_, err = readMsgTimeout(ag.codec, remote, 100*time.Millisecond)
+	assert.True(t, isTimeout(err))
+
+	// The message is sent again until the retries run out.
+	ag.userMessage(nd, &message.UserMessage{Id: proto.Uint64(ag.id), Payload: []byte("bar"), Ts: proto.Int64(time.Now().UnixNano())})
+	var seqs []uint64
+	for i := 0; i < 3; i++ {
+		msg, err := readMsgTimeout(ag.codec, remote, time.Second)
+		if assert.NoError(t, err) {
+			assert.Equal(t, []byte("bar"), msg.(*message.UserMessage).GetPayload())
+			seqs = append(seqs, msg.(*message.UserMessage).GetSeq())
+		}
+	}
+	assert.Equal(t, []uint64{2, 2, 2}, seqs)
+	select {
+	case msg := <-failed:
+		assert.Equal(t, []byte("bar"), msg.Payload)
+	case <-time.After(time.Second):
+		t.Fatal("Failure is not reported")
+	}
+	assert.Equal(t, uint64(2), atomic.LoadUint64(&ag.stats.retransmits))
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&ag.stats.undelivered))
+}
+
+func TestReliableAck(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Reliable, cfg.AckTimeout, cfg.AckRetries = true, 50, 2
+	ag := startTestAgent(t, cfg)
+	defer ag.Close()
+	peerCfg := newTestConfig(t)
+	peerCfg.Reliable = true
+	peer := startTestAgent(t, peerCfg)
+	defer peer.Close()
+
+	received := make(chan []byte, 10)
+	peer.RegisterMessageHandler(func(msg Message) { received <- msg.Payload })
+	assert.NoError(t, peer.Join(cfg.AddrStr))
+	for i := 0; i < 100 && !hasNode(ag, ag.aView, peer.id); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.NoError(t, ag.Broadcast([]byte("hello")))
+	select {
+	case b := <-received:
+		assert.Equal(t, []byte("hello"), b)
+	case <-time.After(time.Second):
+		t.Fatal("Message is not delivered")
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	ag.reliable.Lock()
+	assert.Equal(t, 0, len(ag.reliable.pending))
+	ag.reliable.Unlock()
+	assert.Equal(t, uint64(0), atomic.LoadUint64(&ag.stats.retransmits))
+	assert.Equal(t, 0, len(received))
+}
+
+// testLogger captures the logs in tests.
+type testLogger struct {
+	mu     *sync.Mutex
+	logs   *[]string
+	fields log.Fields
+}
+
+func newTestLogger() *testLogger {
+	return &testLogger{mu: new(sync.Mutex), logs: new([]string)}
+}
+
+func (l *testLogger) printf(level, format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	*l.logs = append(*l.logs, fmt.Sprintf("%s %v ", level, l.fields)+fmt.Sprintf(format, args...))
+}
+
+func (l *testLogger) Debugf(format string, args ...interface{}) {
+	l.printf("DEBUG", format, args...)
+}
+
+func (l *testLogger) Infof(format string, args ...interface{}) {
+	l.printf("INFO", format, args...)
+}
+
+func (l *testLogger) Warningf(format string, args ...interface{}) {
+	l.printf("WARNING", format, args...)
+}
+
+func (l *testLogger) Errorf(format string, args ...interface{}) {
+	l.printf("ERROR", format, args...)
+}
+
+func (l *testLogger) WithFields(fields log.Fields) log.Logger {
+	return &testLogger{mu: l.mu, logs: l.logs, fields: fields}
+}
+
+func (l *testLogger) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(*l.logs, "")
+}
+
+func TestLogger(t *testing.T) {
+	logger := newTestLogger()
+	cfg := newTestConfig(t)
+	cfg.Logger = logger
+	ag := NewAgent(cfg).(*agent)
+
+	assert.Equal(t, ErrNoAvailablePeers, ag.Join(cfg.AddrStr))
+	assert.Contains(t, logger.String(), fmt.Sprintf("INFO map[agent:%d] Agent.Join(): Trying to join %s", ag.id, cfg.AddrStr))
+	assert.Contains(t, logger.String(), fmt.Sprintf("DEBUG map[agent:%d] Agent.Join(): Skip self address", ag.id))
+}
+
+func TestConnPool(t *testing.T) {
+	p := newConnPool(2, time.Minute)
+	a, remoteA := tcpPipe(t)
+	defer remoteA.Close()
+	b, remoteB := tcpPipe(t)
+	defer remoteB.Close()
+	c, remoteC := tcpPipe(t)
+	defer remoteC.Close()
+
+	assert.Nil(t, p.get("a"))
+	p.put("a", a)
+	assert.Equal(t, a, p.get("a"))
+	assert.Nil(t, p.get("a"))
+
+	// The least recently used connection is closed if the pool is full.
+	p.put("a", a)
+	p.put("b", b)
+	p.put("c", c)
+	assert.Equal(t, 2, p.len())
+	assert.True(t, isClosed(a))
+	assert.Nil(t, p.get("a"))
+
+	// The connections closed by the peers are not reused.
+	remoteB.Close()
+	assert.Nil(t, p.get("b"))
+	assert.True(t, isClosed(b))
+
+	// The expired connections are closed.
+	assert.Equal(t, 0, p.expire(time.Now()))
+	assert.Equal(t, 1, p.expire(time.Now().Add(time.Minute)))
+	assert.True(t, isClosed(c))
+
+	// The connections returned after close are closed.
+	d, remoteD := tcpPipe(t)
+	defer remoteD.Close()
+	p.close()
+	p.put("d", d)
+	assert.Equal(t, 0, p.len())
+	assert.True(t, isClosed(d))
+}
+
+func TestShuffleReplyReuse(t *testing.T) {
+	ag := newAgent(newTestConfig(t), rand.NewSource(1))
+	ag.pool = newConnPool(1, 0)
+	defer ag.Close()
+	peer := startTestAgent(t, newTestConfig(t))
+	defer peer.Close()
+
+	shuffle := &message.Shuffle{Addr: proto.String(peer.addr)}
+	for i := uint64(1); i <= 2; i++ {
+		candidates := []*message.Candidate{{Id: proto.Uint64(100 + i), Addr: proto.String(fmt.Sprintf("127.0.0.1:%d", 10000+i))}}
+		assert.NoError(t, ag.shuffleReply(nil, shuffle, candidates))
+	}
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&ag.stats.reusedConns))
+	assert.Equal(t, 1, ag.pool.len())
+
+	// Both the replies are received on the same connection.
+	for i := 0; i < 100 && !(hasNode(peer, peer.pView, 101) && hasNode(peer, peer.pView, 102)); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, hasNode(peer, peer.pView, 101))
+	assert.True(t, hasNode(peer, peer.pView, 102))
+}
+
+func TestMembershipStress(t *testing.T) {
+	const n = 8
+	agents := make([]*agent, n)
+	for i := range agents {
+		agents[i] = startTestAgent(t, newTestConfig(t))
+	}
+	defer func() {
+		for _, ag := range agents {
+			ag.Close()
+		}
+	}()
+
+	// Join, shuffle, broadcast and leave concurrently on all the
+	// agents, which deadlocks if the views are locked inconsistently.
+	var wg sync.WaitGroup
+	for i, ag := range agents {
+		wg.Add(3)
+		go func(i int, ag *agent) {
+			defer wg.Done()
+			for j := 1; j < n; j++ {
+				ag.Join(agents[(i+j)%n].config().AddrStr)
+			}
+		}(i, ag)
+		go func(ag *agent) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				ag.shuffleOnce()
+				ag.checkViews()
+				time.Sleep(time.Millisecond)
+			}
+		}(ag)
+		go func(ag *agent) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				ag.Broadcast([]byte(fmt.Sprintf("message %d", j)))
+				ag.Stats()
+				time.Sleep(time.Millisecond)
+			}
+		}(ag)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		time.Sleep(20 * time.Millisecond)
+		agents[n-1].Leave()
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Agents are deadlocked")
+	}
+
+	for _, ag := range agents[:n-1] {
+		ag.viewMu.RLock()
+		assert.False(t, ag.aView.Has(ag.id))
+		assert.False(t, ag.pView.Has(ag.id))
+		for _, nd := range ag.aView.Values() {
+			assert.False(t, ag.pView.Has(nd.Id))
+		}
+		assert.True(t, ag.aView.Len() <= ag.config().AViewMaxSize)
+		ag.viewMu.RUnlock()
+	}
+}
@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"sync"
+	"time"
+)
+
+// throttle is a token bucket that limits the rate of the outbound
+// messages. Zero rate disables the throttle.
+type throttle struct {
+	sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+// newThrottle() creates a throttle with the rate (messages per
+// second) and the burst.
+func newThrottle(rate float64, burst int) *throttle {
+	return &throttle{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// allow() takes a token from the bucket, and returns
+// false if there is none left.
+func (th *throttle) allow() bool {
+	th.Lock()
+	defer th.Unlock()
+
+	if th.rate <= 0 {
+		return true
+	}
+	th.refill()
+	if th.tokens < 1 {
+		return false
+	}
+	th.tokens--
+	return true
+}
+
+// reserve() takes n tokens from the bucket, and returns the time to wait
+// until they are refilled. The bucket goes into debt instead of refusing
+// the tokens, so n might be larger than the burst.
+func (th *throttle) reserve(n float64) time.Duration {
+	th.Lock()
+	defer th.Unlock()
+
+	if th.rate <= 0 {
+		return 0
+	}
+	th.refill()
+	th.tokens -= n
+	if th.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-th.tokens / th.rate * float64(time.Second))
+}
+
+// refill() adds the tokens for the time since the last refill,
+// up to the burst. The throttle must be locked.
+func (th *throttle) refill() {
+	t := th.now()
+	th.tokens += t.Sub(th.last).Seconds() * th.rate
+	if th.tokens > th.burst {
+		th.tokens = th.burst
+	}
+	th.last = t
+}
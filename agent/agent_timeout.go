@@ -0,0 +1,29 @@
+package agent
+
+import (
+	"net"
+	"time"
+)
+
+// timeoutConn is a connection whose writes time out, so a peer that
+// stops reading fails the writes, which close the connection, instead
+// of blocking the writer forever.
+type timeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *timeoutConn) Write(b []byte) (int, error) {
+	c.Conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	return c.Conn.Write(b)
+}
+
+// wrapConn() counts the bytes of the connection, and makes the
+// writes to it time out after the write timeout, if it is set.
+func (ag *agent) wrapConn(conn net.Conn) net.Conn {
+	conn = &countingConn{Conn: conn, stats: ag.stats}
+	if ag.config().WriteTimeout <= 0 {
+		return conn
+	}
+	return &timeoutConn{Conn: conn, timeout: time.Duration(ag.config().WriteTimeout) * time.Second}
+}
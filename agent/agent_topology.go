@@ -0,0 +1,282 @@
+package agent
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lilymona/gog/message"
+	"github.com/lilymona/gog/node"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// Topology is the graph of the overlay crawled by Topology.
+type Topology struct {
+	Nodes []TopologyNode `json:"nodes"`
+	Edges []TopologyEdge `json:"edges"`
+}
+
+// TopologyNode is a node that answered the crawl.
+type TopologyNode struct {
+	ID   uint64 `json:"id"`
+	Addr string `json:"address"`
+}
+
+// TopologyEdge is a neighbor in the active view of a node.
+type TopologyEdge struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+}
+
+// MaxTopologyTTL is the most hops a topology crawl goes,
+// the larger ttls are lowered to it.
+const MaxTopologyTTL = 16
+
+// maxTopologyRoutes bounds the crawls of the others routed back at once.
+const maxTopologyRoutes = 1024
+
+// pendingTopologies are the crawls collecting the replies, keyed by the
+// correlation ids. The replies are keyed by the node id, so a node that
+// answers twice is counted once.
+type pendingTopologies struct {
+	sync.Mutex
+	m map[uint64]map[uint64]*message.TopologyReply
+	// The neighbors the crawls of the others came from, keyed by the
+	// correlation ids, so the replies go back along the same path,
+	// instead of to the address the origin claims.
+	routes map[uint64]topologyRoute
+}
+
+// topologyRoute is the neighbor to send the replies of a crawl to,
+// until the deadline in nanoseconds.
+type topologyRoute struct {
+	node     *node.Node
+	deadline int64
+}
+
+func newPendingTopologies() *pendingTopologies {
+	return &pendingTopologies{
+		m:      make(map[uint64]map[uint64]*message.TopologyReply),
+		routes: make(map[uint64]topologyRoute),
+	}
+}
+
+// add() registers a crawl.
+func (pt *pendingTopologies) add(reqId uint64) {
+	pt.Lock()
+	defer pt.Unlock()
+	pt.m[reqId] = make(map[uint64]*message.TopologyReply)
+}
+
+// remove() deregisters a crawl, and returns its replies.
+func (pt *pendingTopologies) remove(reqId uint64) map[uint64]*message.TopologyReply {
+	pt.Lock()
+	defer pt.Unlock()
+	replies := pt.m[reqId]
+	delete(pt.m, reqId)
+	return replies
+}
+
+// deliver() records the reply to the crawl, and returns
+// false if the crawl is unknown.
+func (pt *pendingTopologies) deliver(msg *message.TopologyReply) bool {
+	pt.Lock()
+	defer pt.Unlock()
+	replies, ok := pt.m[msg.GetReqId()]
+	if !ok {
+		return false
+	}
+	replies[msg.GetId()] = msg
+	return true
+}
+
+// route() records the neighbor the crawl came from, and returns false
+// if there are too many crawls routed already. The expired routes are
+// dropped first.
+func (pt *pendingTopologies) route(reqId uint64, from *node.Node, deadline, now int64) bool {
+	pt.Lock()
+	defer pt.Unlock()
+	if len(pt.routes) >= maxTopologyRoutes {
+		for id, r := range pt.routes {
+			if now >= r.deadline {
+				delete(pt.routes, id)
+			}
+		}
+		if len(pt.routes) >= maxTopologyRoutes {
+			return false
+		}
+	}
+	pt.routes[reqId] = topologyRoute{node: from, deadline: deadline}
+	return true
+}
+
+// next() returns the neighbor to send the replies of the crawl to,
+// or nil if the crawl is unknown or expired.
+func (pt *pendingTopologies) next(reqId uint64, now int64) *node.Node {
+	pt.Lock()
+	defer pt.Unlock()
+	r, ok := pt.routes[reqId]
+	if !ok {
+		return nil
+	}
+	if now >= r.deadline {
+		delete(pt.routes, reqId)
+		return nil
+	}
+	return r.node
+}
+
+// Topology crawls the overlay within ttl hops, up to MaxTopologyTTL, and
+// returns the graph of the nodes that answered before the timeout, and
+// their active views. The nodes that do not answer in time are only in
+// the edges.
+func (ag *agent) Topology(ttl int, timeout time.Duration) (*Topology, error) {
+	if ttl > MaxTopologyTTL {
+		ttl = MaxTopologyTTL
+	}
+	msg := &message.TopologyRequest{
+		Id:    proto.Uint64(ag.id),
+		ReqId: proto.Uint64(ag.rng.Uint64()),
+		Addr:  proto.String(ag.addr),
+		Ttl:   proto.Uint32(uint32(ttl)),
+		Ts:    proto.Int64(time.Now().UnixNano()),
+	}
+	ag.topologies.add(msg.GetReqId())
+
+	// Do not handle the request when it comes back.
+	purgeDeadline := time.Now().UnixNano() + time.Millisecond.Nanoseconds()*int64(ag.config().PurgeDuration)
+	ag.msgCache.add(hashTopologyRequest(msg.GetReqId()), purgeDeadline)
+
+	ag.topologies.deliver(ag.topologyReply(msg.GetReqId()))
+	if ttl > 0 {
+		ag.viewMu.RLock()
+		for _, nd := range ag.aView.Values() {
+			ag.controlMessage(nd, msg)
+		}
+		ag.viewMu.RUnlock()
+	}
+
+	select {
+	case <-time.After(timeout):
+	case <-ag.stopc:
+		ag.topologies.remove(msg.GetReqId())
+		return nil, ErrAgentClosed
+	}
+	return newTopology(ag.topologies.remove(msg.GetReqId())), nil
+}
+
+// newTopology() returns the graph of the replies, sorted by the ids.
+func newTopology(replies map[uint64]*message.TopologyReply) *Topology {
+	t := &Topology{Nodes: []TopologyNode{}, Edges: []TopologyEdge{}}
+	for _, r := range replies {
+		t.Nodes = append(t.Nodes, TopologyNode{ID: r.GetId(), Addr: r.GetAddr()})
+		for _, nd := range r.GetNeighbors() {
+			t.Edges = append(t.Edges, TopologyEdge{From: r.GetId(), To: nd.GetId()})
+		}
+	}
+	sort.Slice(t.Nodes, func(i, j int) bool { return t.Nodes[i].ID < t.Nodes[j].ID })
+	sort.Slice(t.Edges, func(i, j int) bool {
+		if t.Edges[i].From != t.Edges[j].From {
+			return t.Edges[i].From < t.Edges[j].From
+		}
+		return t.Edges[i].To < t.Edges[j].To
+	})
+	return t
+}
+
+// topologyReply() returns the TopologyReply of the active view.
+func (ag *agent) topologyReply(reqId uint64) *message.TopologyReply {
+	reply := &message.TopologyReply{
+		Id:    proto.Uint64(ag.id),
+		ReqId: proto.Uint64(reqId),
+		Addr:  proto.String(ag.addr),
+	}
+	ag.viewMu.RLock()
+	for _, nd := range ag.aView.Values() {
+		reply.Neighbors = append(reply.Neighbors, &message.Candidate{
+			Id:   proto.Uint64(nd.Id),
+			Addr: proto.String(nd.Addr),
+		})
+	}
+	ag.viewMu.RUnlock()
+	return reply
+}
+
+// handleTopologyRequest() handles TopologyRequest message. It will send
+// back the active view to the neighbor the request came from, which
+// routes it to the origin, and forward the request to the active view
+// if the ttl is not exhausted.
+func (ag *agent) handleTopologyRequest(from *node.Node, msg *message.TopologyRequest) {
+	// Test if the request is stale.
+	deadline := msg.GetTs() + time.Millisecond.Nanoseconds()*int64(ag.config().MLife)
+	now := time.Now().UnixNano()
+	if now >= deadline {
+		ag.logger.Debugf("Topology request is too old, deadline: %v, now %v\n", deadline, now)
+		return
+	}
+
+	// Test if the request has been already received,
+	// unless the purge deadline of the entry has passed.
+	hash := hashTopologyRequest(msg.GetReqId())
+	purgeDeadline := now + time.Millisecond.Nanoseconds()*int64(ag.config().PurgeDuration)
+	if !ag.msgCache.addIfAbsent(hash, purgeDeadline, now) {
+		ag.logger.Debugf("Topology request is already received, hash: %v\n", hash)
+		return
+	}
+
+	ag.controlMessage(from, ag.topologyReply(msg.GetReqId()))
+
+	// The ttl counts the hops from the origin, including this one.
+	// The replies from further are only routed back while the
+	// request is not stale.
+	ttl := msg.GetTtl()
+	if ttl > MaxTopologyTTL {
+		ttl = MaxTopologyTTL
+	}
+	if ttl <= 1 {
+		return
+	}
+	if !ag.topologies.route(msg.GetReqId(), from, deadline, now) {
+		ag.sampledLogger.Warningf("Agent.handleTopologyRequest(): Too many topology requests, do not forward %v\n", msg.GetReqId())
+		return
+	}
+	fmsg := &message.TopologyRequest{
+		Id:    proto.Uint64(ag.id),
+		ReqId: msg.ReqId,
+		Addr:  msg.Addr,
+		Ttl:   proto.Uint32(ttl - 1),
+		Ts:    msg.Ts,
+	}
+	ag.viewMu.RLock()
+	defer ag.viewMu.RUnlock()
+	for _, nd := range ag.aView.Values() {
+		if nd.Id != from.Id {
+			ag.controlMessage(nd, fmsg)
+		}
+	}
+}
+
+// handleTopologyReply() handles TopologyReply message. The replies to the
+// crawls of the others are sent on to the neighbors the crawls came from.
+func (ag *agent) handleTopologyReply(msg *message.TopologyReply) {
+	if ag.topologies.deliver(msg) {
+		return
+	}
+	nd := ag.topologies.next(msg.GetReqId(), time.Now().UnixNano())
+	if nd == nil {
+		ag.logger.Debugf("Drop the topology reply to request %v\n", msg.GetReqId())
+		return
+	}
+	ag.controlMessage(nd, msg)
+}
+
+// hashTopologyRequest() returns the hash of a topology request,
+// which is distinct from the hashes of the other messages.
+func hashTopologyRequest(reqId uint64) [sha1.Size]byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, reqId)
+	return sha1.Sum(append([]byte("topology:"), b...))
+}
@@ -0,0 +1,34 @@
+package agent
+
+// Tracer creates the spans around the handling of the user messages.
+// The trace context of a span is carried in the forwarded messages, so
+// the fan-out of a broadcast shows up as one trace. An OpenTelemetry
+// tracer can be plugged in by encoding its span context, e.g. as the
+// W3C traceparent, into the trace context.
+type Tracer interface {
+	// StartSpan starts a span as a child of the parent trace
+	// context, which is nil if the message carries none.
+	StartSpan(name string, parent []byte) Span
+}
+
+// Span is a span started by the Tracer.
+type Span interface {
+	// Context returns the trace context to propagate.
+	Context() []byte
+	// End ends the span.
+	End()
+}
+
+// noopTracer is the default Tracer, it propagates
+// the parent trace context as is.
+type noopTracer struct{}
+
+func (noopTracer) StartSpan(name string, parent []byte) Span {
+	return noopSpan(parent)
+}
+
+type noopSpan []byte
+
+func (s noopSpan) Context() []byte { return s }
+
+func (noopSpan) End() {}
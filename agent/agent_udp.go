@@ -0,0 +1,260 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lilymona/gog/message"
+	"github.com/lilymona/gog/node"
+	"github.com/lilymona/gog/transport"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+var (
+	ErrNoPacketTransport = errors.New("Transport does not support datagrams")
+	ErrPacketTooLarge    = errors.New("Message too large for a datagram")
+)
+
+// maxPacketSize is the maximum size of the datagrams, which keeps them
+// under the usual MTU, so they are not fragmented. The larger messages
+// are sent over TCP.
+const maxPacketSize = 1400
+
+const (
+	// shuffleReplyTimeout is how long the ShuffleReplies
+	// of a shuffle are accepted from the datagrams.
+	shuffleReplyTimeout = time.Minute
+	// maxPendingShuffles bounds the shuffles waiting for the replies.
+	maxPendingShuffles = 16
+)
+
+// pendingShuffles are the nonces of the shuffles sent, with the times
+// they expire. Anyone can send a datagram, so the ShuffleReplies read
+// from them are only accepted for these, and each only once.
+type pendingShuffles struct {
+	sync.Mutex
+	m map[uint64]time.Time
+}
+
+func newPendingShuffles() *pendingShuffles {
+	return &pendingShuffles{m: make(map[uint64]time.Time)}
+}
+
+// add() registers a new shuffle and returns its nonce. The expired
+// shuffles are dropped, and the oldest one if there are too many.
+func (ps *pendingShuffles) add(now time.Time) (uint64, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, err
+	}
+	nonce := binary.BigEndian.Uint64(b[:])
+
+	ps.Lock()
+	defer ps.Unlock()
+	var oldest uint64
+	for n, expiry := range ps.m {
+		if now.After(expiry) {
+			delete(ps.m, n)
+		} else if oldest == 0 || expiry.Before(ps.m[oldest]) {
+			oldest = n
+		}
+	}
+	if len(ps.m) >= maxPendingShuffles {
+		delete(ps.m, oldest)
+	}
+	ps.m[nonce] = now.Add(shuffleReplyTimeout)
+	return nonce, nil
+}
+
+// done() returns true and forgets the shuffle
+// if the nonce is of one waiting for the reply.
+func (ps *pendingShuffles) done(nonce uint64, now time.Time) bool {
+	ps.Lock()
+	defer ps.Unlock()
+	expiry, ok := ps.m[nonce]
+	if !ok {
+		return false
+	}
+	delete(ps.m, nonce)
+	return !now.After(expiry)
+}
+
+// packetMAC() returns the HMAC of the datagram with the cluster secret,
+// or nil if there is none.
+func (ag *agent) packetMAC(b []byte) []byte {
+	secret := ag.config().ClusterSecret
+	if secret == "" {
+		return nil
+	}
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte("packet"))
+	h.Write(b)
+	return h.Sum(nil)
+}
+
+// localCaps() returns the capabilities advertised to the peers.
+func (ag *agent) localCaps() uint32 {
+	if ag.packetConn() != nil {
+		return caps | node.CapUDP
+	}
+	return caps
+}
+
+// listenPacket() listens for the datagrams on the address,
+// if the transport supports them.
+func (ag *agent) listenPacket(addr string) (net.PacketConn, error) {
+	pt, ok := ag.transport.(transport.PacketTransport)
+	if !ok {
+		return nil, ErrNoPacketTransport
+	}
+	return pt.ListenPacket(addr)
+}
+
+// packetConn() returns the connection of the datagrams,
+// nil if the agent does not listen for them.
+func (ag *agent) packetConn() net.PacketConn {
+	ag.lnMu.Lock()
+	defer ag.lnMu.Unlock()
+	return ag.pc
+}
+
+// servePackets() reads the datagrams until the connection is closed.
+// The ones that cannot be decoded are dropped, as they might be lost
+// anyway, and so are the ones without the HMAC of the cluster secret
+// if there is one.
+func (ag *agent) servePackets(pc net.PacketConn) {
+	b := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := pc.ReadFrom(b)
+		if err != nil {
+			if ag.stopped() || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			ag.logger.Errorf("Agent.servePackets(): Failed to read: %v\n", err)
+			continue
+		}
+		data := b[:n]
+		if ag.config().ClusterSecret != "" {
+			if n < sha256.Size || !hmac.Equal(data[n-sha256.Size:], ag.packetMAC(data[:n-sha256.Size])) {
+				atomic.AddUint64(&ag.stats.authFailures, 1)
+				ag.sampledLogger.Warningf("Agent.servePackets(): Drop the unauthenticated datagram from %v\n", addr)
+				continue
+			}
+			data = data[:n-sha256.Size]
+		}
+		msg, err := ag.codec.ReadMsg(bytes.NewReader(data))
+		if err != nil {
+			ag.sampledLogger.Errorf("Agent.servePackets(): Failed to decode message from %v: %v\n", addr, err)
+			continue
+		}
+		atomic.AddUint64(&ag.stats.packetsReceived, 1)
+		atomic.AddUint64(&ag.stats.bytesIn, uint64(n))
+		ag.handlePacket(msg, addr)
+	}
+}
+
+// handlePacket() dispatches a message read from a datagram. The shuffles
+// and the pings are only accepted from the neighbors, as they are over
+// TCP, so the agent does not reflect them to the spoofed addresses, and
+// the shuffle replies only for the shuffles the agent sent.
+func (ag *agent) handlePacket(msg proto.Message, addr net.Addr) {
+	switch t := msg.(type) {
+	case *message.Shuffle:
+		if ag.neighborOf(t.GetId()) != nil {
+			ag.handleShuffle(t)
+		}
+	case *message.ShuffleReply:
+		if !ag.shuffles.done(t.GetNonce(), time.Now()) {
+			ag.sampledLogger.Warningf("Agent.handlePacket(): Drop the unsolicited shuffle reply from %v\n", addr)
+			return
+		}
+		ag.handleShuffleReply(t)
+	case *message.Ping:
+		if ag.neighborOf(t.GetId()) == nil {
+			return
+		}
+		reply := &message.Pong{
+			Id:  proto.Uint64(ag.id),
+			Seq: proto.Uint64(t.GetSeq()),
+		}
+		if err := ag.writePacket(reply, addr); err != nil {
+			ag.logger.Errorf("Agent.handlePacket(): Write msg error: %v\n", err)
+		}
+	case *message.Pong:
+		if nd := ag.neighborOf(t.GetId()); nd != nil {
+			ag.handlePong(nd, t)
+		}
+	default:
+		ag.logger.Errorf("Agent.handlePacket(): Unexpected message type: %T\n", t)
+	}
+}
+
+// neighborOf() returns the node of the id in the active view, or nil.
+func (ag *agent) neighborOf(id uint64) *node.Node {
+	ag.viewMu.RLock()
+	defer ag.viewMu.RUnlock()
+	if !ag.aView.Has(id) {
+		return nil
+	}
+	return ag.aView.GetValueOf(id)
+}
+
+// writePacket() writes the message to the address in a datagram.
+func (ag *agent) writePacket(msg proto.Message, addr net.Addr) error {
+	pc := ag.packetConn()
+	if pc == nil {
+		return ErrNoPacketTransport
+	}
+	var buf bytes.Buffer
+	if err := ag.codec.WriteMsg(msg, &buf); err != nil {
+		return err
+	}
+	buf.Write(ag.packetMAC(buf.Bytes()))
+	if buf.Len() > maxPacketSize {
+		return ErrPacketTooLarge
+	}
+	if _, err := pc.WriteTo(buf.Bytes(), addr); err != nil {
+		return err
+	}
+	atomic.AddUint64(&ag.stats.packetsSent, 1)
+	atomic.AddUint64(&ag.stats.bytesOut, uint64(buf.Len()))
+	return nil
+}
+
+// writePacketTo() writes the message in a datagram
+// to the agent listening on the address.
+func (ag *agent) writePacketTo(msg proto.Message, addr string) error {
+	pt, ok := ag.transport.(transport.PacketTransport)
+	if !ok {
+		return ErrNoPacketTransport
+	}
+	raddr, err := pt.ResolvePacketAddr(addr)
+	if err != nil {
+		return err
+	}
+	return ag.writePacket(msg, raddr)
+}
+
+// writeControl() writes the control message to the node in a datagram if
+// both of them read the datagrams, or queues it to the connection of the
+// node ahead of the user messages if not, or if the datagram cannot be
+// written.
+func (ag *agent) writeControl(msg proto.Message, nd *node.Node) {
+	if nd.Caps&node.CapUDP != 0 && ag.packetConn() != nil {
+		err := ag.writePacketTo(msg, nd.Addr)
+		if err == nil {
+			return
+		}
+		ag.logger.Debugf("Agent.writeControl(): Write %T to %v over TCP: %v\n", msg, nd, err)
+	}
+	ag.controlMessage(nd, msg)
+}
@@ -0,0 +1,17 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package agent
+
+import (
+	"syscall"
+)
+
+// control() fails if any socket option is configured, as they
+// are not supported on this platform.
+func (ag *agent) control(network, address string, c syscall.RawConn) error {
+	if ag.config().ReuseAddr || ag.config().ReusePort {
+		return ErrSocketOptionUnsupported
+	}
+	return nil
+}
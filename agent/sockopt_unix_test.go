@@ -0,0 +1,29 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package agent
+
+import (
+	"testing"
+
+	"github.com/lilymona/testify/assert"
+)
+
+func TestListenReusePort(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.ReusePort = true
+	ag1 := NewAgent(cfg).(*agent)
+	ag2 := NewAgent(cfg).(*agent)
+
+	ln1, err := ag1.listen()
+	assert.NoError(t, err)
+	defer closeListeners(ln1)
+	ln2, err := ag2.listen()
+	assert.NoError(t, err)
+	defer closeListeners(ln2)
+
+	// Without the option, the port is taken.
+	cfg.ReusePort = false
+	_, err = NewAgent(cfg).(*agent).listen()
+	assert.Error(t, err)
+}
@@ -2,73 +2,124 @@ package arraymap
 
 import (
 	"encoding/json"
+	"math/rand"
 	"sync"
 )
 
-type ArrayMap struct {
-	positions map[interface{}]int
-	keys      []interface{}
-	values    []interface{}
+// OrderedMap is a map that keeps its values in an array, so they can be
+// iterated and chosen at random without allocation. Removing a key moves
+// the last value to its place, so the order is not the insertion order.
+// It is safe for concurrent use, every method locks the map, and the
+// compound updates are done atomically by CompareAndRemove and
+// AddIfAbsent.
+type OrderedMap[K comparable, V any] struct {
+	positions map[K]int
+	keys      []K
+	values    []V
 	rwl       sync.RWMutex
 }
 
-func NewArrayMap() *ArrayMap {
-	return &ArrayMap{
-		positions: make(map[interface{}]int),
-		keys:      make([]interface{}, 0),
-		values:    make([]interface{}, 0),
+// NewOrderedMap creates an empty map.
+func NewOrderedMap[K comparable, V any]() *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{
+		positions: make(map[K]int),
+		keys:      make([]K, 0),
+		values:    make([]V, 0),
 	}
 }
 
-func (a *ArrayMap) Len() int {
+// ArrayMap is the untyped map of the older code.
+//
+// Deprecated: Use OrderedMap with the types of the keys and the values.
+type ArrayMap = OrderedMap[interface{}, interface{}]
+
+// NewArrayMap creates an empty untyped map.
+//
+// Deprecated: Use NewOrderedMap.
+func NewArrayMap() *ArrayMap {
+	return NewOrderedMap[interface{}, interface{}]()
+}
+
+func (a *OrderedMap[K, V]) Len() int {
+	a.rwl.RLock()
+	defer a.rwl.RUnlock()
 	return len(a.keys)
 }
 
-func (a *ArrayMap) Add(key, value interface{}) (oldValue interface{}) {
+// Add sets the value of the key, and returns the old value,
+// or the zero value if the key is new.
+func (a *OrderedMap[K, V]) Add(key K, value V) (oldValue V) {
+	a.rwl.Lock()
+	defer a.rwl.Unlock()
 	if p, existed := a.positions[key]; existed {
 		oldValue = a.values[p]
 		a.values[p] = value
 		return
 	}
-	a.keys = append(a.keys, key)
-	a.values = append(a.values, value)
-	a.positions[key] = len(a.keys) - 1
+	a.add(key, value)
 	return
 }
 
-func (a *ArrayMap) GetKeyAt(i int) interface{} {
+// AddIfAbsent adds the value of the key, unless the key exists,
+// in which case it returns the current value and false.
+func (a *OrderedMap[K, V]) AddIfAbsent(key K, value V) (V, bool) {
+	a.rwl.Lock()
+	defer a.rwl.Unlock()
+	if p, existed := a.positions[key]; existed {
+		return a.values[p], false
+	}
+	a.add(key, value)
+	return value, true
+}
+
+func (a *OrderedMap[K, V]) Append(key K, value V) {
+	a.Add(key, value)
+}
+
+func (a *OrderedMap[K, V]) GetKeyAt(i int) K {
+	a.rwl.RLock()
+	defer a.rwl.RUnlock()
 	return a.keys[i]
 }
 
-func (a *ArrayMap) GetValueAt(i int) interface{} {
+func (a *OrderedMap[K, V]) GetValueAt(i int) V {
+	a.rwl.RLock()
+	defer a.rwl.RUnlock()
 	return a.values[i]
 }
 
-func (a *ArrayMap) GetValueOf(key interface{}) interface{} {
+func (a *OrderedMap[K, V]) GetValueOf(key K) V {
+	a.rwl.RLock()
+	defer a.rwl.RUnlock()
 	return a.values[a.positions[key]]
 }
 
-func (a *ArrayMap) Has(key interface{}) bool {
+// Get returns the value of the key, or false if the key does not exist.
+func (a *OrderedMap[K, V]) Get(key K) (value V, ok bool) {
+	a.rwl.RLock()
+	defer a.rwl.RUnlock()
+	if p, existed := a.positions[key]; existed {
+		return a.values[p], true
+	}
+	return
+}
+
+func (a *OrderedMap[K, V]) Has(key K) bool {
+	a.rwl.RLock()
+	defer a.rwl.RUnlock()
 	_, existed := a.positions[key]
 	return existed
 }
 
-func (a *ArrayMap) removeAt(i int) {
-	removingKey, lastKey := a.keys[i], a.keys[len(a.keys)-1]
-	// Swap the removing item and the last.
-	a.keys[i], a.keys[len(a.keys)-1] = a.keys[len(a.keys)-1], a.keys[i]
-	a.values[i], a.values[len(a.values)-1] = a.values[len(a.values)-1], a.values[i]
-
-	// Update the position.
-	a.positions[lastKey] = i
-
-	// Removing.
-	a.keys = a.keys[:len(a.keys)-1]
-	a.values = a.values[:len(a.values)-1]
-	delete(a.positions, removingKey)
+func (a *OrderedMap[K, V]) RemoveAt(i int) {
+	a.rwl.Lock()
+	defer a.rwl.Unlock()
+	a.removeAt(i)
 }
 
-func (a *ArrayMap) Remove(key interface{}) bool {
+func (a *OrderedMap[K, V]) Remove(key K) bool {
+	a.rwl.Lock()
+	defer a.rwl.Unlock()
 	if p, exisited := a.positions[key]; exisited {
 		a.removeAt(p)
 		return true
@@ -76,38 +127,116 @@ func (a *ArrayMap) Remove(key interface{}) bool {
 	return false
 }
 
-func (a *ArrayMap) RemoveAll() {
+// CompareAndRemove removes the key if the function returns
+// true for its value, and returns true if it is removed.
+func (a *OrderedMap[K, V]) CompareAndRemove(key K, f func(V) bool) bool {
+	a.rwl.Lock()
+	defer a.rwl.Unlock()
+	if p, existed := a.positions[key]; existed && f(a.values[p]) {
+		a.removeAt(p)
+		return true
+	}
+	return false
+}
+
+// RemoveAll removes all the keys, and returns the removed values.
+func (a *OrderedMap[K, V]) RemoveAll() []V {
+	a.rwl.Lock()
+	defer a.rwl.Unlock()
+	values := make([]V, len(a.values))
+	copy(values, a.values)
 	for k := range a.positions {
 		delete(a.positions, k)
 	}
 	a.keys = a.keys[:0]
 	a.values = a.values[:0]
+	return values
 }
 
-func (a *ArrayMap) Lock() {
-	a.rwl.Lock()
-	return
+// Values returns the values, which must not be modified, and are
+// only valid until the map is modified, see Snapshot and Range.
+func (a *OrderedMap[K, V]) Values() []V {
+	a.rwl.RLock()
+	defer a.rwl.RUnlock()
+	return a.values
 }
 
-func (a *ArrayMap) Unlock() {
-	a.rwl.Unlock()
-	return
+// Snapshot returns a copy of the values, which stays
+// valid after the map is modified.
+func (a *OrderedMap[K, V]) Snapshot() []V {
+	a.rwl.RLock()
+	defer a.rwl.RUnlock()
+	values := make([]V, len(a.values))
+	copy(values, a.values)
+	return values
 }
 
-func (a *ArrayMap) RLock() {
+// Range calls the function for each key and value, until it returns
+// false. The map is locked meanwhile, so the function must not call
+// the methods of the map.
+func (a *OrderedMap[K, V]) Range(f func(K, V) bool) {
 	a.rwl.RLock()
-	return
+	defer a.rwl.RUnlock()
+	for i, key := range a.keys {
+		if !f(key, a.values[i]) {
+			return
+		}
+	}
 }
 
-func (a *ArrayMap) RUnlock() {
-	a.rwl.RUnlock()
-	return
+// GetRandom returns a value chosen by r, or false if the map is empty.
+func (a *OrderedMap[K, V]) GetRandom(r *rand.Rand) (value V, ok bool) {
+	a.rwl.RLock()
+	defer a.rwl.RUnlock()
+	if len(a.keys) == 0 {
+		return
+	}
+	return a.values[r.Intn(len(a.keys))], true
 }
 
-func (a *ArrayMap) Values() []interface{} {
-	return a.values
+// RandomValue returns a value chosen by r, other than the value of the
+// excluded key, or false if there is no other value.
+func (a *OrderedMap[K, V]) RandomValue(r *rand.Rand, exclude K) (value V, ok bool) {
+	a.rwl.RLock()
+	defer a.rwl.RUnlock()
+	if len(a.keys) == 0 {
+		return
+	}
+	i := r.Intn(len(a.keys))
+	if a.keys[i] == exclude {
+		if len(a.keys) == 1 {
+			return
+		}
+		i = (i + 1) % len(a.keys)
+	}
+	return a.values[i], true
 }
 
-func (a *ArrayMap) MarshalJSON() ([]byte, error) {
+func (a *OrderedMap[K, V]) MarshalJSON() ([]byte, error) {
+	a.rwl.RLock()
+	defer a.rwl.RUnlock()
 	return json.Marshal(a.values)
 }
+
+// add() appends the new key and value. It must be called with rwl held.
+func (a *OrderedMap[K, V]) add(key K, value V) {
+	a.keys = append(a.keys, key)
+	a.values = append(a.values, value)
+	a.positions[key] = len(a.keys) - 1
+}
+
+// removeAt() removes the i-th key and value. It must be called with rwl held.
+func (a *OrderedMap[K, V]) removeAt(i int) {
+	removingKey, lastKey := a.keys[i], a.keys[len(a.keys)-1]
+	// Swap the removing item and the last.
+	a.keys[i], a.keys[len(a.keys)-1] = a.keys[len(a.keys)-1], a.keys[i]
+	a.values[i], a.values[len(a.values)-1] = a.values[len(a.values)-1], a.values[i]
+
+	// Update the position.
+	a.positions[lastKey] = i
+
+	// Removing.
+	a.keys = a.keys[:len(a.keys)-1]
+	a.values = a.values[:len(a.values)-1]
+	delete(a.positions, removingKey)
+}
@@ -1,6 +1,9 @@
 package arraymap
 
 import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/lilymona/testify/assert"
@@ -63,3 +66,77 @@ func TestSimple(t *testing.T) {
 	assert.Equal(t, 0, len(am.keys))
 	assert.Equal(t, 0, len(am.values))
 }
+
+func TestOrderedMap(t *testing.T) {
+	om := NewOrderedMap[uint64, string]()
+	assert.Equal(t, "", om.Add(1, "foo"))
+	assert.Equal(t, "foo", om.Add(1, "bar"))
+	om.Add(2, "baz")
+	assert.Equal(t, "bar", om.GetValueOf(1))
+
+	// The snapshot is not changed with the map.
+	values := om.Snapshot()
+	om.RemoveAll()
+	assert.Equal(t, []string{"bar", "baz"}, values)
+	assert.Equal(t, 0, om.Len())
+
+	r := rand.New(rand.NewSource(1))
+	_, ok := om.RandomValue(r, 1)
+	assert.False(t, ok)
+	om.Add(1, "foo")
+	_, ok = om.RandomValue(r, 1)
+	assert.False(t, ok)
+	om.Add(2, "bar")
+	for i := 0; i < 10; i++ {
+		v, ok := om.RandomValue(r, 1)
+		assert.True(t, ok)
+		assert.Equal(t, "bar", v)
+	}
+}
+
+func TestAtomicUpdates(t *testing.T) {
+	om := NewOrderedMap[string, int]()
+	v, added := om.AddIfAbsent("foo", 1)
+	assert.True(t, added)
+	assert.Equal(t, 1, v)
+	v, added = om.AddIfAbsent("foo", 2)
+	assert.False(t, added)
+	assert.Equal(t, 1, v)
+
+	assert.False(t, om.CompareAndRemove("foo", func(v int) bool { return v > 1 }))
+	assert.False(t, om.CompareAndRemove("bar", func(int) bool { return true }))
+	assert.True(t, om.CompareAndRemove("foo", func(v int) bool { return v == 1 }))
+	assert.False(t, om.Has("foo"))
+
+	om.Add("foo", 1)
+	om.Add("bar", 2)
+	om.Add("baz", 3)
+	sum := 0
+	om.Range(func(k string, v int) bool {
+		sum += v
+		return k != "bar"
+	})
+	assert.Equal(t, 3, sum)
+
+	v, ok := om.GetRandom(rand.New(rand.NewSource(1)))
+	assert.True(t, ok)
+	assert.True(t, v >= 1 && v <= 3)
+	assert.Equal(t, []int{1, 2, 3}, om.RemoveAll())
+	_, ok = om.GetRandom(rand.New(rand.NewSource(1)))
+	assert.False(t, ok)
+
+	// Only one of the concurrent adds succeeds.
+	var wg sync.WaitGroup
+	var n int32
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, added := om.AddIfAbsent("foo", i); added {
+				atomic.AddInt32(&n, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), n)
+}
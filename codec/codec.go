@@ -2,28 +2,38 @@ package codec
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"reflect"
 	"runtime/debug"
 
 	"github.com/gogo/protobuf/proto"
 
-	log "github.com/lilymona/gog/logging" // DEBUG
+	log "github.com/lilymona/gog/logging"
 )
 
 const (
 	sizeOfUint8 = 1
 	sizeOfInt32 = 4
+
+	// The high bit of the type index marks a compressed message.
+	compressedFlag = 0x80
+	maxMessages    = compressedFlag
+
+	// DefaultMaxMessageSize is the default maximum size of a frame.
+	DefaultMaxMessageSize = 10 << 20
 )
 
 var (
 	ErrMessageAlreadyRegistered = errors.New("Message already registered")
 	ErrMessageNotRegistered     = errors.New("Message not registered")
 	ErrCannotWriteMessage       = errors.New("Cannot write message")
+	ErrMessageTooLarge          = errors.New("Message too large")
 )
 
 // Codec describes the codec interface,
@@ -42,21 +52,76 @@ type Codec interface {
 	ReadMsg(r io.Reader) (proto.Message, error)
 }
 
+// CompressionAccepter is implemented by the writers that know whether
+// the peer reads the compressed messages, e.g. the nodes that negotiated
+// the compression. The messages to the other writers are compressed
+// as long as they are above the threshold.
+type CompressionAccepter interface {
+	AcceptsCompression() bool
+}
+
+// Marshaler describes the interface of the library
+// that encodes/decodes the protobuf messages.
+type Marshaler interface {
+	// Marshal encodes a message to bytes.
+	Marshal(msg proto.Message) ([]byte, error)
+	// Unmarshal decodes the bytes to a message.
+	Unmarshal(b []byte, msg proto.Message) error
+}
+
+// GogoMarshaler implements the Marshaler interface
+// with gogo-protobuf.
+type GogoMarshaler struct{}
+
+// Marshal encodes a message to bytes.
+func (GogoMarshaler) Marshal(msg proto.Message) ([]byte, error) {
+	return proto.Marshal(msg)
+}
+
+// Unmarshal decodes the bytes to a message.
+func (GogoMarshaler) Unmarshal(b []byte, msg proto.Message) error {
+	return proto.Unmarshal(b, msg)
+}
+
 // ProtobufCodec implements the codec interface.
 type ProtobufCodec struct {
+	// marshaler encodes/decodes the messages.
+	marshaler Marshaler
 	// registeredMessages is a map from message indices
 	// to message types. The indices increase monotonically.
 	registeredMessages map[uint8]reflect.Type
 	// messageIndices is a map from message types
 	// to message indices.
 	messageIndices map[reflect.Type]uint8
+	// compressible is the set of message types that
+	// can be compressed.
+	compressible map[reflect.Type]bool
+	// The messages larger than compressThreshold bytes are
+	// compressed, zero disables the compression.
+	compressThreshold int
+	// MaxMessageSize is the maximum size in bytes of the frames
+	// and the decompressed messages to read.
+	MaxMessageSize int
+	// logger writes the logs of the messages.
+	logger log.Logger
 }
 
-// NewProtobufCodec creates and returns a ProtobufCodec.
+// NewProtobufCodec creates and returns a ProtobufCodec
+// backed by gogo-protobuf.
 func NewProtobufCodec() *ProtobufCodec {
+	return NewProtobufCodecWithMarshaler(GogoMarshaler{})
+}
+
+// NewProtobufCodecWithMarshaler creates and returns a ProtobufCodec
+// backed by the given marshaler.
+func NewProtobufCodecWithMarshaler(m Marshaler) *ProtobufCodec {
 	return &ProtobufCodec{
+		marshaler:          m,
 		registeredMessages: make(map[uint8]reflect.Type),
 		messageIndices:     make(map[reflect.Type]uint8),
+		compressible:       make(map[reflect.Type]bool),
+		MaxMessageSize:     DefaultMaxMessageSize,
+		logger:             log.Default(),
 	}
 }
 
@@ -66,15 +131,46 @@ func (pc *ProtobufCodec) Register(msg proto.Message) {
 	if _, existed := pc.messageIndices[mtype]; existed {
 		panic("Message already registered")
 	}
+	if len(pc.messageIndices) >= maxMessages {
+		panic("Too many messages registered")
+	}
 	index := uint8(len(pc.messageIndices))
 	pc.messageIndices[mtype] = index
 	pc.registeredMessages[index] = mtype
 	return
 }
 
+// RegisterCompressible registers a message that will be compressed
+// if it is larger than the compress threshold. The control messages
+// should not be registered, as they are small and latency-sensitive.
+// Note this is not concurrent-safe.
+func (pc *ProtobufCodec) RegisterCompressible(msg proto.Message) {
+	pc.Register(msg)
+	pc.compressible[reflect.TypeOf(msg)] = true
+}
+
+// SetMaxMessageSize sets the maximum size in bytes of the messages to read.
+// Note this is not concurrent-safe.
+func (pc *ProtobufCodec) SetMaxMessageSize(size int) {
+	pc.MaxMessageSize = size
+}
+
+// SetCompressThreshold sets the size in bytes above which the
+// compressible messages are compressed, zero disables the compression.
+// Note this is not concurrent-safe.
+func (pc *ProtobufCodec) SetCompressThreshold(threshold int) {
+	pc.compressThreshold = threshold
+}
+
+// SetLogger sets the logger of the messages.
+// Note this is not concurrent-safe.
+func (pc *ProtobufCodec) SetLogger(logger log.Logger) {
+	pc.logger = logger
+}
+
 // WriteMsg encodes a message to bytes and writes it to the io.Writer.
 func (pc *ProtobufCodec) WriteMsg(msg proto.Message, w io.Writer) error {
-	log.Debugf("Send:%v, to:%v\n", msg, w.(*net.TCPConn).RemoteAddr())
+	pc.logger.Debugf("Send:%v, to:%v\n", msg, remoteAddr(w))
 	index, existed := pc.messageIndices[reflect.TypeOf(msg)]
 	if !existed {
 		return ErrMessageNotRegistered
@@ -82,10 +178,16 @@ func (pc *ProtobufCodec) WriteMsg(msg proto.Message, w io.Writer) error {
 	buf := bytes.NewBuffer([]byte{0xab, 0xcd})
 
 	// Encode.
-	b, err := proto.Marshal(msg)
+	b, err := pc.marshaler.Marshal(msg)
 	if err != nil {
 		return err
 	}
+	if pc.shouldCompress(msg, b, w) {
+		if b, err = compress(b); err != nil {
+			return err
+		}
+		index |= compressedFlag
+	}
 	// Write the length.
 	if err := binary.Write(buf, binary.LittleEndian, int32(len(b)+sizeOfUint8)); err != nil {
 		return err
@@ -96,6 +198,8 @@ func (pc *ProtobufCodec) WriteMsg(msg proto.Message, w io.Writer) error {
 	}
 	// Write the bytes.
 	buf.Write(b)
+	// Write the whole frame at once, so it will not be
+	// interleaved by a writer that serializes the writes.
 	if _, err = buf.WriteTo(w); err != nil {
 		return err
 	}
@@ -109,13 +213,14 @@ func (pc *ProtobufCodec) ReadMsg(r io.Reader) (msg proto.Message, err error) {
 	defer func() {
 		if fatal := recover(); fatal != nil {
 			err = fmt.Errorf("Recovery from panic: %v", fatal)
-			log.Errorf(err.Error())
+			pc.logger.Errorf("%v\n", err)
 			debug.PrintStack()
 		}
 	}()
 
+	// A clean EOF between the frames is returned as io.EOF.
 	magic := make([]byte, 2)
-	if _, err = r.Read(magic); err != nil {
+	if _, err = io.ReadFull(r, magic); err != nil {
 		return nil, err
 	} else if !(magic[0] == 0xab && magic[1] == 0xcd) {
 		return nil, fmt.Errorf("magic number unmatch")
@@ -125,6 +230,9 @@ func (pc *ProtobufCodec) ReadMsg(r io.Reader) (msg proto.Message, err error) {
 	if err = binary.Read(r, binary.LittleEndian, &length); err != nil {
 		return nil, err
 	}
+	if int64(length) > int64(pc.MaxMessageSize) {
+		return nil, fmt.Errorf("%w: %d bytes", ErrMessageTooLarge, length)
+	}
 	b := make([]byte, length)
 	// Read the type and bytes.
 	if _, err = io.ReadFull(r, b); err != nil {
@@ -132,15 +240,77 @@ func (pc *ProtobufCodec) ReadMsg(r io.Reader) (msg proto.Message, err error) {
 	}
 	// Get the index.
 	index := uint8(b[0])
+	body := b[1:]
+	if index&compressedFlag != 0 {
+		index &^= compressedFlag
+		if body, err = decompress(body, pc.MaxMessageSize); err != nil {
+			return nil, err
+		}
+	}
 	// Decode.
 	mtype, existed := pc.registeredMessages[index]
 	if !existed {
 		return nil, ErrMessageNotRegistered
 	}
 	msg = reflect.New(mtype.Elem()).Interface().(proto.Message)
-	if err := proto.Unmarshal(b[1:], msg); err != nil {
+	if err := pc.marshaler.Unmarshal(body, msg); err != nil {
 		return nil, err
 	}
-	log.Debugf("Recv:%v, from:%v\n", msg, r.(*net.TCPConn).RemoteAddr())
+	pc.logger.Debugf("Recv:%v, from:%v\n", msg, remoteAddr(r))
 	return msg, nil
 }
+
+// shouldCompress() returns true if the encoded message
+// should be compressed to the writer.
+func (pc *ProtobufCodec) shouldCompress(msg proto.Message, b []byte, w io.Writer) bool {
+	if pc.compressThreshold <= 0 ||
+		len(b) <= pc.compressThreshold ||
+		!pc.compressible[reflect.TypeOf(msg)] {
+		return false
+	}
+	if ca, ok := w.(CompressionAccepter); ok {
+		return ca.AcceptsCompression()
+	}
+	return true
+}
+
+// compress() compresses the bytes with gzip.
+func compress(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress() decompresses the gzipped bytes, which
+// should be no larger than max bytes.
+func decompress(b []byte, max int) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(zr, int64(max)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > max {
+		return nil, fmt.Errorf("%w: more than %d bytes decompressed", ErrMessageTooLarge, max)
+	}
+	return body, nil
+}
+
+// remoteAddr() returns the remote address if v is a connection.
+func remoteAddr(v interface{}) net.Addr {
+	if conn, ok := v.(interface {
+		RemoteAddr() net.Addr
+	}); ok {
+		return conn.RemoteAddr()
+	}
+	return nil
+}
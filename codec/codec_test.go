@@ -2,8 +2,12 @@ package codec
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
+	"runtime"
 	"testing"
 
 	"github.com/gogo/protobuf/proto"
@@ -24,7 +28,7 @@ func genRandomMessage(length int) []byte {
 
 func TestRegister(t *testing.T) {
 	umsg := &message.UserMessage{
-		Id:      proto.String("localhost:8080"),
+		Id:      proto.Uint64(8080),
 		Payload: []byte("hello world"),
 	}
 	pc := NewProtobufCodec()
@@ -34,12 +38,14 @@ func TestRegister(t *testing.T) {
 
 func TestWriteMsgReadMsg(t *testing.T) {
 	umsg1 := &message.UserMessage{
-		Id:      proto.String("localhost:8080"),
+		Id:      proto.Uint64(8080),
 		Payload: []byte("hello"),
+		Ts:      proto.Int64(0),
 	}
 	umsg2 := &message.UserMessage{
-		Id:      proto.String("localhost:8080"),
+		Id:      proto.Uint64(8080),
 		Payload: []byte("world"),
+		Ts:      proto.Int64(0),
 	}
 	pc := NewProtobufCodec()
 	pc.Register(umsg1)
@@ -53,10 +59,242 @@ func TestWriteMsgReadMsg(t *testing.T) {
 	assert.Equal(t, umsg2, msg2)
 }
 
+func TestMarshaler(t *testing.T) {
+	umsg := &message.UserMessage{
+		Id:      proto.Uint64(8080),
+		Payload: []byte("hello"),
+		Ts:      proto.Int64(0),
+	}
+	pc := NewProtobufCodecWithMarshaler(JSONMarshaler{})
+	pc.Register(umsg)
+	rw := new(bytes.Buffer)
+	assert.NoError(t, pc.WriteMsg(umsg, rw))
+	assert.Contains(t, rw.String(), `"payload":"aGVsbG8="`)
+	msg, err := pc.ReadMsg(rw)
+	assert.NoError(t, err)
+	assert.Equal(t, umsg, msg)
+}
+
+func TestJSONCodec(t *testing.T) {
+	labels := []*message.Label{{Key: proto.String("role"), Value: proto.String("web")}}
+	candidates := []*message.Candidate{{Id: proto.Uint64(3), Addr: proto.String("c"), Labels: labels}}
+	msgs := []proto.Message{
+		&message.UserMessage{Id: proto.Uint64(1), Payload: []byte("hello"), Ts: proto.Int64(2), Trace: []byte("trace")},
+		&message.Join{Id: proto.Uint64(1), Addr: proto.String("a"), Observe: proto.Bool(true), Labels: labels},
+		&message.JoinReply{Id: proto.Uint64(1), Accept: proto.Bool(true), Labels: labels},
+		&message.ForwardJoin{Id: proto.Uint64(1), SourceId: proto.Uint64(2), SourceAddr: proto.String("a"), Ttl: proto.Uint32(3), SourceLabels: labels},
+		&message.Neighbor{Id: proto.Uint64(1), Addr: proto.String("a"), Priority: message.Neighbor_High.Enum(), Labels: labels},
+		&message.NeighborReply{Id: proto.Uint64(1), Accept: proto.Bool(false), Labels: labels},
+		&message.Disconnect{Id: proto.Uint64(1)},
+		&message.Shuffle{Id: proto.Uint64(1), SourceId: proto.Uint64(2), Addr: proto.String("a"), Candidates: candidates, Ttl: proto.Uint32(3)},
+		&message.ShuffleReply{Id: proto.Uint64(1), Candidates: candidates},
+		&message.Request{Id: proto.Uint64(1), ReqId: proto.Uint64(2), Addr: proto.String("a"), Payload: []byte("req"), Ts: proto.Int64(3)},
+		&message.Reply{Id: proto.Uint64(1), ReqId: proto.Uint64(2), Payload: []byte("rep")},
+	}
+	jc := NewJSONCodec()
+	for _, msg := range msgs {
+		jc.Register(msg)
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		for _, msg := range msgs {
+			if err := jc.WriteMsg(msg, w); err != nil {
+				w.CloseWithError(err)
+				return
+			}
+		}
+		w.Close()
+	}()
+	for _, msg := range msgs {
+		got, err := jc.ReadMsg(r)
+		assert.NoError(t, err)
+		assert.Equal(t, msg, got)
+	}
+	_, err := jc.ReadMsg(r)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestMsgpackCodec(t *testing.T) {
+	labels := []*message.Label{{Key: proto.String("role"), Value: proto.String("web")}}
+	candidates := []*message.Candidate{{Id: proto.Uint64(3), Addr: proto.String("c"), Labels: labels}}
+	msgs := []proto.Message{
+		&message.UserMessage{Id: proto.Uint64(1<<64 - 1), Payload: genRandomMessage(1000), Ts: proto.Int64(-1 << 40), Trace: []byte("trace")},
+		&message.Join{Id: proto.Uint64(1), Addr: proto.String("a"), Observe: proto.Bool(true), Labels: labels},
+		&message.Neighbor{Id: proto.Uint64(1), Addr: proto.String("a"), Priority: message.Neighbor_High.Enum(), Labels: labels},
+		&message.NeighborReply{Id: proto.Uint64(1), Accept: proto.Bool(false), Labels: labels},
+		&message.Shuffle{Id: proto.Uint64(1), SourceId: proto.Uint64(2), Addr: proto.String("a"), Candidates: candidates, Ttl: proto.Uint32(3)},
+		&message.Reply{Id: proto.Uint64(1), ReqId: proto.Uint64(2), Payload: []byte{}},
+	}
+	mc := NewMsgpackCodec()
+	for _, msg := range msgs {
+		mc.Register(msg)
+	}
+	rw := new(bytes.Buffer)
+	for _, msg := range msgs {
+		assert.NoError(t, mc.WriteMsg(msg, rw))
+	}
+	for _, msg := range msgs {
+		got, err := mc.ReadMsg(rw)
+		assert.NoError(t, err)
+		assert.Equal(t, msg, got)
+	}
+
+	// The fields are keyed by their JSON names.
+	var m MsgpackMarshaler
+	b, err := m.Marshal(&message.Disconnect{Id: proto.Uint64(1)})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x81, 0xa2, 'i', 'd', 0x01}, b)
+
+	// The unknown fields are skipped.
+	var msg message.Disconnect
+	b = []byte{0x82, 0xa3, 'f', 'o', 'o', 0x92, 0xc3, 0xa1, 'x', 0xa2, 'i', 'd', 0xcd, 0x01, 0x00}
+	assert.NoError(t, m.Unmarshal(b, &msg))
+	assert.Equal(t, uint64(256), msg.GetId())
+
+	for _, b := range [][]byte{
+		{0x81, 0xa2, 'i', 'd'},
+		{0x81, 0xa2, 'i', 'd', 0xff},
+		{0x81, 0xa2, 'i', 'd', 0xa1, 'x'},
+		{0x81, 0xa2, 'i', 'd', 0x01, 0x01},
+		{0xdf, 0xff, 0xff, 0xff, 0xff},
+	} {
+		err := m.Unmarshal(b, &msg)
+		assert.True(t, errors.Is(err, ErrInvalidMsgpack), "%x: %v", b, err)
+	}
+
+	// The deeply nested unknown field is rejected
+	// before it exhausts the stack.
+	nested := func(depth int) []byte {
+		b := []byte{0x81, 0xa3, 'f', 'o', 'o'}
+		b = append(b, bytes.Repeat([]byte{0x91}, depth)...)
+		return append(b, 0xc0)
+	}
+	assert.NoError(t, m.Unmarshal(nested(maxMsgpackDepth-1), &msg))
+	err = m.Unmarshal(nested(1<<20), &msg)
+	assert.True(t, errors.Is(err, ErrInvalidMsgpack), "%v", err)
+}
+
+func TestCompression(t *testing.T) {
+	pc := NewProtobufCodec()
+	pc.Register(&message.Join{})
+	pc.RegisterCompressible(&message.UserMessage{})
+	pc.SetCompressThreshold(100)
+
+	for _, c := range []struct {
+		msg        proto.Message
+		compressed bool
+	}{
+		{&message.Join{Id: proto.Uint64(1), Addr: proto.String("localhost:8080")}, false},
+		{&message.Join{Id: proto.Uint64(1), Addr: proto.String(string(make([]byte, 1000)))}, false},
+		{&message.UserMessage{Id: proto.Uint64(1), Payload: []byte("hello"), Ts: proto.Int64(0)}, false},
+		{&message.UserMessage{Id: proto.Uint64(1), Payload: make([]byte, 1000), Ts: proto.Int64(0)}, true},
+	} {
+		rw := new(bytes.Buffer)
+		assert.NoError(t, pc.WriteMsg(c.msg, rw))
+		// The type index follows the magic and the length.
+		index := rw.Bytes()[2+sizeOfInt32]
+		assert.Equal(t, c.compressed, index&compressedFlag != 0)
+		if c.compressed {
+			assert.True(t, rw.Len() < proto.Size(c.msg))
+		}
+		msg, err := pc.ReadMsg(rw)
+		assert.NoError(t, err)
+		assert.Equal(t, c.msg, msg)
+	}
+}
+
+// accepter is a writer that tells whether the peer accepts the compression.
+type accepter struct {
+	bytes.Buffer
+	accepts bool
+}
+
+func (a *accepter) AcceptsCompression() bool { return a.accepts }
+
+func TestCompressionAccepter(t *testing.T) {
+	pc := NewProtobufCodec()
+	pc.RegisterCompressible(&message.UserMessage{})
+	pc.SetCompressThreshold(100)
+
+	msg := &message.UserMessage{Id: proto.Uint64(1), Payload: make([]byte, 1000), Ts: proto.Int64(0)}
+	for _, accepts := range []bool{false, true} {
+		w := &accepter{accepts: accepts}
+		assert.NoError(t, pc.WriteMsg(msg, w))
+		index := w.Bytes()[2+sizeOfInt32]
+		assert.Equal(t, accepts, index&compressedFlag != 0)
+		got, err := pc.ReadMsg(w)
+		assert.NoError(t, err)
+		assert.Equal(t, msg, got)
+	}
+}
+
+func TestReadMsgShortRead(t *testing.T) {
+	umsg := &message.UserMessage{
+		Id:      proto.Uint64(8080),
+		Payload: []byte("hello"),
+		Ts:      proto.Int64(0),
+	}
+	pc := NewProtobufCodec()
+	pc.Register(umsg)
+	buf := new(bytes.Buffer)
+	assert.NoError(t, pc.WriteMsg(umsg, buf))
+	assert.NoError(t, pc.WriteMsg(umsg, buf))
+
+	// Feed the frames one byte at a time.
+	r, w := io.Pipe()
+	go func() {
+		for _, b := range buf.Bytes() {
+			w.Write([]byte{b})
+		}
+		w.Close()
+	}()
+	for i := 0; i < 2; i++ {
+		msg, err := pc.ReadMsg(r)
+		assert.NoError(t, err)
+		assert.Equal(t, umsg, msg)
+	}
+	_, err := pc.ReadMsg(r)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReadMsgTooLarge(t *testing.T) {
+	pc := NewProtobufCodec()
+	pc.RegisterCompressible(&message.UserMessage{})
+	pc.SetMaxMessageSize(1024)
+
+	// A frame header claiming 2GB.
+	buf := bytes.NewBuffer([]byte{0xab, 0xcd})
+	binary.Write(buf, binary.LittleEndian, uint32(2<<30))
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := pc.ReadMsg(buf)
+	runtime.ReadMemStats(&after)
+	assert.True(t, errors.Is(err, ErrMessageTooLarge))
+	assert.Contains(t, err.Error(), "2147483648")
+	assert.True(t, after.TotalAlloc-before.TotalAlloc < 1<<20)
+
+	// A compressed message that is too large when decompressed.
+	pc.SetMaxMessageSize(DefaultMaxMessageSize)
+	pc.SetCompressThreshold(1)
+	umsg := &message.UserMessage{
+		Id:      proto.Uint64(8080),
+		Payload: make([]byte, 4096),
+		Ts:      proto.Int64(0),
+	}
+	rw := new(bytes.Buffer)
+	assert.NoError(t, pc.WriteMsg(umsg, rw))
+	pc.SetMaxMessageSize(1024)
+	_, err = pc.ReadMsg(rw)
+	assert.True(t, errors.Is(err, ErrMessageTooLarge))
+}
+
 func BenchmarkWriteMsgReadMsg(b *testing.B) {
 	umsg := &message.UserMessage{
-		Id:      proto.String("localhost:8080"),
+		Id:      proto.Uint64(8080),
 		Payload: payload,
+		Ts:      proto.Int64(0),
 	}
 	pc := NewProtobufCodec()
 	pc.Register(umsg)
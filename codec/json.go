@@ -0,0 +1,35 @@
+package codec
+
+import (
+	"encoding/json"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// JSONMarshaler implements the Marshaler interface
+// with encoding/json.
+type JSONMarshaler struct{}
+
+// Marshal encodes a message to JSON.
+func (JSONMarshaler) Marshal(msg proto.Message) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+// Unmarshal decodes the JSON to a message.
+func (JSONMarshaler) Unmarshal(b []byte, msg proto.Message) error {
+	return json.Unmarshal(b, msg)
+}
+
+// JSONCodec implements the codec interface, it encodes the messages
+// as JSON, which is handy to debug the wire. It shares the framing and
+// the message registration with ProtobufCodec, so the type indices of
+// both codecs are the same if the messages are registered in the same
+// order.
+type JSONCodec struct {
+	*ProtobufCodec
+}
+
+// NewJSONCodec creates and returns a JSONCodec.
+func NewJSONCodec() *JSONCodec {
+	return &JSONCodec{NewProtobufCodecWithMarshaler(JSONMarshaler{})}
+}
@@ -0,0 +1,537 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// ErrInvalidMsgpack is returned when the bytes are not a valid
+// msgpack encoding of the message.
+var ErrInvalidMsgpack = errors.New("Invalid msgpack")
+
+// The msgpack formats, see https://github.com/msgpack/msgpack/blob/master/spec.md.
+const (
+	mpNil      = 0xc0
+	mpFalse    = 0xc2
+	mpTrue     = 0xc3
+	mpBin8     = 0xc4
+	mpBin16    = 0xc5
+	mpBin32    = 0xc6
+	mpFloat32  = 0xca
+	mpFloat64  = 0xcb
+	mpUint8    = 0xcc
+	mpUint16   = 0xcd
+	mpUint32   = 0xce
+	mpUint64   = 0xcf
+	mpInt8     = 0xd0
+	mpInt16    = 0xd1
+	mpInt32    = 0xd2
+	mpInt64    = 0xd3
+	mpStr8     = 0xd9
+	mpStr16    = 0xda
+	mpStr32    = 0xdb
+	mpArray16  = 0xdc
+	mpArray32  = 0xdd
+	mpMap16    = 0xde
+	mpMap32    = 0xdf
+	mpFixMap   = 0x80
+	mpFixArray = 0x90
+	mpFixStr   = 0xa0
+	mpNegFix   = 0xe0
+)
+
+// MsgpackMarshaler implements the Marshaler interface with msgpack. A
+// message is encoded as a map from the JSON names of the fields that are
+// set to their values, so the non-Go agents can decode the messages with
+// any msgpack library, by the same names as the JSON codec.
+type MsgpackMarshaler struct{}
+
+// Marshal encodes a message to msgpack.
+func (MsgpackMarshaler) Marshal(msg proto.Message) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeMsgpack(&buf, reflect.ValueOf(msg)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the msgpack to a message.
+func (MsgpackMarshaler) Unmarshal(b []byte, msg proto.Message) error {
+	d := &msgpackDecoder{r: bytes.NewReader(b)}
+	if err := d.decode(reflect.ValueOf(msg), 0); err != nil {
+		return err
+	}
+	if d.r.Len() > 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrInvalidMsgpack, d.r.Len())
+	}
+	return nil
+}
+
+// MsgpackCodec implements the codec interface, it encodes the messages
+// with msgpack, for the agents written in other languages. Like JSONCodec,
+// it shares the framing and the message registration with ProtobufCodec.
+type MsgpackCodec struct {
+	*ProtobufCodec
+}
+
+// NewMsgpackCodec creates and returns a MsgpackCodec.
+func NewMsgpackCodec() *MsgpackCodec {
+	return &MsgpackCodec{NewProtobufCodecWithMarshaler(MsgpackMarshaler{})}
+}
+
+// msgpackField is an encoded field of a message.
+type msgpackField struct {
+	name  string
+	index int
+}
+
+// msgpackFields() returns the fields of the message type to encode, which
+// are the exported fields with their JSON names, except the XXX_ fields.
+func msgpackFields(t reflect.Type) []msgpackField {
+	var fields []msgpackField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || strings.HasPrefix(f.Name, "XXX_") {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, msgpackField{name, i})
+	}
+	return fields
+}
+
+// encodeMsgpack() writes the value in msgpack to the buffer.
+func encodeMsgpack(buf *bytes.Buffer, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			buf.WriteByte(mpNil)
+			return nil
+		}
+		return encodeMsgpack(buf, v.Elem())
+	case reflect.Struct:
+		var set []msgpackField
+		for _, f := range msgpackFields(v.Type()) {
+			fv := v.Field(f.index)
+			if (fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Slice) && fv.IsNil() {
+				continue
+			}
+			set = append(set, f)
+		}
+		writeMsgpackHeader(buf, len(set), mpFixMap, 15, mpMap16, mpMap32)
+		for _, f := range set {
+			writeMsgpackString(buf, f.name)
+			if err := encodeMsgpack(buf, v.Field(f.index)); err != nil {
+				return err
+			}
+		}
+	case reflect.Bool:
+		if v.Bool() {
+			buf.WriteByte(mpTrue)
+		} else {
+			buf.WriteByte(mpFalse)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeMsgpackInt(buf, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		writeMsgpackUint(buf, v.Uint())
+	case reflect.Float32:
+		buf.WriteByte(mpFloat32)
+		binary.Write(buf, binary.BigEndian, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		buf.WriteByte(mpFloat64)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(v.Float()))
+	case reflect.String:
+		writeMsgpackString(buf, v.String())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			writeMsgpackHeader(buf, v.Len(), 0, -1, mpBin16, mpBin32)
+			buf.Write(v.Bytes())
+			return nil
+		}
+		writeMsgpackHeader(buf, v.Len(), mpFixArray, 15, mpArray16, mpArray32)
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeMsgpack(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported type %v", v.Type())
+	}
+	return nil
+}
+
+// writeMsgpackHeader() writes the format and the length n, in the fixed
+// format if n is no more than maxFix, or 8 bits if it is a bin.
+func writeMsgpackHeader(buf *bytes.Buffer, n int, fix byte, maxFix int, f16, f32 byte) {
+	switch {
+	case n <= maxFix:
+		buf.WriteByte(fix | byte(n))
+	case f16 == mpBin16 && n <= math.MaxUint8:
+		buf.Write([]byte{mpBin8, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(f16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(f32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+// writeMsgpackString() writes the string in the shortest format.
+func writeMsgpackString(buf *bytes.Buffer, s string) {
+	if n := len(s); n > 31 && n <= math.MaxUint8 {
+		buf.Write([]byte{mpStr8, byte(n)})
+	} else {
+		writeMsgpackHeader(buf, n, mpFixStr, 31, mpStr16, mpStr32)
+	}
+	buf.WriteString(s)
+}
+
+// writeMsgpackUint() writes the unsigned integer in the shortest format.
+func writeMsgpackUint(buf *bytes.Buffer, u uint64) {
+	switch {
+	case u < 128:
+		buf.WriteByte(byte(u))
+	case u <= math.MaxUint8:
+		buf.Write([]byte{mpUint8, byte(u)})
+	case u <= math.MaxUint16:
+		buf.WriteByte(mpUint16)
+		binary.Write(buf, binary.BigEndian, uint16(u))
+	case u <= math.MaxUint32:
+		buf.WriteByte(mpUint32)
+		binary.Write(buf, binary.BigEndian, uint32(u))
+	default:
+		buf.WriteByte(mpUint64)
+		binary.Write(buf, binary.BigEndian, u)
+	}
+}
+
+// writeMsgpackInt() writes the signed integer in the shortest format.
+func writeMsgpackInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0:
+		writeMsgpackUint(buf, uint64(i))
+	case i >= -32:
+		buf.WriteByte(byte(i))
+	case i >= math.MinInt8:
+		buf.Write([]byte{mpInt8, byte(i)})
+	case i >= math.MinInt16:
+		buf.WriteByte(mpInt16)
+		binary.Write(buf, binary.BigEndian, int16(i))
+	case i >= math.MinInt32:
+		buf.WriteByte(mpInt32)
+		binary.Write(buf, binary.BigEndian, int32(i))
+	default:
+		buf.WriteByte(mpInt64)
+		binary.Write(buf, binary.BigEndian, i)
+	}
+}
+
+// maxMsgpackDepth is the deepest nesting of the maps and the arrays
+// decoded, far beyond the messages, so a crafted message cannot
+// exhaust the stack.
+const maxMsgpackDepth = 64
+
+// msgpackDecoder decodes the msgpack from the reader.
+type msgpackDecoder struct {
+	r *bytes.Reader
+}
+
+// decode() decodes the next value into v, which is settable or a pointer,
+// at the depth of the maps and the arrays the value is nested in.
+func (d *msgpackDecoder) decode(v reflect.Value, depth int) error {
+	if depth > maxMsgpackDepth {
+		return fmt.Errorf("%w: nested deeper than %d", ErrInvalidMsgpack, maxMsgpackDepth)
+	}
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return ErrInvalidMsgpack
+	}
+	if v.Kind() == reflect.Ptr {
+		if c == mpNil {
+			if v.CanSet() {
+				v.Set(reflect.Zero(v.Type()))
+			}
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		d.r.UnreadByte()
+		return d.decode(v.Elem(), depth)
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		n, err := d.length(c, mpFixMap, 15, mpMap16, mpMap32)
+		if err != nil {
+			return err
+		}
+		fields := make(map[string]int)
+		for _, f := range msgpackFields(v.Type()) {
+			fields[f.name] = f.index
+		}
+		for i := 0; i < n; i++ {
+			var name string
+			if err := d.decode(reflect.ValueOf(&name), depth+1); err != nil {
+				return err
+			}
+			index, ok := fields[name]
+			if !ok {
+				// Skip the unknown field, e.g. of a newer agent.
+				if err := d.skip(depth + 1); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.decode(v.Field(index), depth+1); err != nil {
+				return err
+			}
+		}
+	case reflect.Bool:
+		if c != mpTrue && c != mpFalse {
+			return fmt.Errorf("%w: 0x%x is not a bool", ErrInvalidMsgpack, c)
+		}
+		v.SetBool(c == mpTrue)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := d.integer(c)
+		if err != nil {
+			return err
+		}
+		if i > math.MaxInt64 && c == mpUint64 || v.OverflowInt(int64(i)) {
+			return fmt.Errorf("%w: %d overflows %v", ErrInvalidMsgpack, int64(i), v.Type())
+		}
+		v.SetInt(int64(i))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := d.integer(c)
+		if err != nil {
+			return err
+		}
+		if int64(i) < 0 && c != mpUint64 || v.OverflowUint(i) {
+			return fmt.Errorf("%w: %d overflows %v", ErrInvalidMsgpack, i, v.Type())
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		switch c {
+		case mpFloat32:
+			var f uint32
+			if err := binary.Read(d.r, binary.BigEndian, &f); err != nil {
+				return ErrInvalidMsgpack
+			}
+			v.SetFloat(float64(math.Float32frombits(f)))
+		case mpFloat64:
+			var f uint64
+			if err := binary.Read(d.r, binary.BigEndian, &f); err != nil {
+				return ErrInvalidMsgpack
+			}
+			v.SetFloat(math.Float64frombits(f))
+		default:
+			return fmt.Errorf("%w: 0x%x is not a float", ErrInvalidMsgpack, c)
+		}
+	case reflect.String:
+		b, err := d.str(c)
+		if err != nil {
+			return err
+		}
+		v.SetString(string(b))
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b, err := d.bin(c)
+			if err != nil {
+				return err
+			}
+			v.SetBytes(b)
+			return nil
+		}
+		n, err := d.length(c, mpFixArray, 15, mpArray16, mpArray32)
+		if err != nil {
+			return err
+		}
+		if n > d.r.Len() {
+			return fmt.Errorf("%w: %d elements in %d bytes", ErrInvalidMsgpack, n, d.r.Len())
+		}
+		s := reflect.MakeSlice(v.Type(), n, n)
+		for i := 0; i < n; i++ {
+			if err := d.decode(s.Index(i), depth+1); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	default:
+		return fmt.Errorf("msgpack: unsupported type %v", v.Type())
+	}
+	return nil
+}
+
+// skip() decodes the next value of any type, e.g. of an unknown field,
+// at the depth of the maps and the arrays the value is nested in.
+func (d *msgpackDecoder) skip(depth int) error {
+	if depth > maxMsgpackDepth {
+		return fmt.Errorf("%w: nested deeper than %d", ErrInvalidMsgpack, maxMsgpackDepth)
+	}
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return ErrInvalidMsgpack
+	}
+	switch {
+	case c == mpNil, c == mpTrue, c == mpFalse:
+	case c < 0x80, c >= mpNegFix, c >= mpUint8 && c <= mpInt64:
+		_, err = d.integer(c)
+	case c == mpFloat32:
+		_, err = d.r.Seek(4, io.SeekCurrent)
+	case c == mpFloat64:
+		_, err = d.r.Seek(8, io.SeekCurrent)
+	case c&0xe0 == mpFixStr, c >= mpStr8 && c <= mpStr32:
+		_, err = d.str(c)
+	case c >= mpBin8 && c <= mpBin32:
+		_, err = d.bin(c)
+	case c&0xf0 == mpFixArray, c == mpArray16, c == mpArray32:
+		var n int
+		if n, err = d.length(c, mpFixArray, 15, mpArray16, mpArray32); err != nil {
+			return err
+		}
+		for i := 0; i < n && err == nil; i++ {
+			err = d.skip(depth + 1)
+		}
+	case c&0xf0 == mpFixMap, c == mpMap16, c == mpMap32:
+		var n int
+		if n, err = d.length(c, mpFixMap, 15, mpMap16, mpMap32); err != nil {
+			return err
+		}
+		for i := 0; i < 2*n && err == nil; i++ {
+			err = d.skip(depth + 1)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported format 0x%x", ErrInvalidMsgpack, c)
+	}
+	return err
+}
+
+// integer() decodes the integer of the format c, the negative
+// integers are returned in two's complement.
+func (d *msgpackDecoder) integer(c byte) (uint64, error) {
+	if c < 0x80 {
+		return uint64(c), nil
+	}
+	if c >= mpNegFix {
+		return uint64(int64(int8(c))), nil
+	}
+	var err error
+	switch c {
+	case mpUint8:
+		var i uint8
+		err = binary.Read(d.r, binary.BigEndian, &i)
+		return uint64(i), err
+	case mpUint16:
+		var i uint16
+		err = binary.Read(d.r, binary.BigEndian, &i)
+		return uint64(i), err
+	case mpUint32:
+		var i uint32
+		err = binary.Read(d.r, binary.BigEndian, &i)
+		return uint64(i), err
+	case mpUint64:
+		var i uint64
+		err = binary.Read(d.r, binary.BigEndian, &i)
+		return i, err
+	case mpInt8:
+		var i int8
+		err = binary.Read(d.r, binary.BigEndian, &i)
+		return uint64(int64(i)), err
+	case mpInt16:
+		var i int16
+		err = binary.Read(d.r, binary.BigEndian, &i)
+		return uint64(int64(i)), err
+	case mpInt32:
+		var i int32
+		err = binary.Read(d.r, binary.BigEndian, &i)
+		return uint64(int64(i)), err
+	case mpInt64:
+		var i int64
+		err = binary.Read(d.r, binary.BigEndian, &i)
+		return uint64(i), err
+	}
+	return 0, fmt.Errorf("%w: 0x%x is not an integer", ErrInvalidMsgpack, c)
+}
+
+// length() decodes the length of the format c, which is in the fixed
+// format if c is fix with the length in the bits of maxFix.
+func (d *msgpackDecoder) length(c, fix byte, maxFix int, f16, f32 byte) (int, error) {
+	if maxFix >= 0 && c&^byte(maxFix) == fix {
+		return int(c & byte(maxFix)), nil
+	}
+	var n uint32
+	switch c {
+	case f16:
+		var n16 uint16
+		if err := binary.Read(d.r, binary.BigEndian, &n16); err != nil {
+			return 0, ErrInvalidMsgpack
+		}
+		n = uint32(n16)
+	case f32:
+		if err := binary.Read(d.r, binary.BigEndian, &n); err != nil {
+			return 0, ErrInvalidMsgpack
+		}
+	default:
+		return 0, fmt.Errorf("%w: unexpected format 0x%x", ErrInvalidMsgpack, c)
+	}
+	return int(n), nil
+}
+
+// str() decodes the bytes of the string of the format c.
+func (d *msgpackDecoder) str(c byte) ([]byte, error) {
+	if c == mpStr8 {
+		return d.bytes8()
+	}
+	n, err := d.length(c, mpFixStr, 31, mpStr16, mpStr32)
+	if err != nil {
+		return nil, err
+	}
+	return d.read(n)
+}
+
+// bin() decodes the bytes of the bin of the format c.
+func (d *msgpackDecoder) bin(c byte) ([]byte, error) {
+	if c == mpBin8 {
+		return d.bytes8()
+	}
+	n, err := d.length(c, 0, -1, mpBin16, mpBin32)
+	if err != nil {
+		return nil, err
+	}
+	return d.read(n)
+}
+
+// bytes8() decodes the bytes with the 8-bit length.
+func (d *msgpackDecoder) bytes8() ([]byte, error) {
+	n, err := d.r.ReadByte()
+	if err != nil {
+		return nil, ErrInvalidMsgpack
+	}
+	return d.read(int(n))
+}
+
+// read() reads n bytes.
+func (d *msgpackDecoder) read(n int) ([]byte, error) {
+	if n > d.r.Len() {
+		return nil, fmt.Errorf("%w: %d bytes expected, %d left", ErrInvalidMsgpack, n, d.r.Len())
+	}
+	b := make([]byte, n)
+	d.r.Read(b)
+	return b, nil
+}
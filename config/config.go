@@ -1,27 +1,141 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"math/rand"
 	"net"
 	"os"
 	"os/exec"
+	"reflect"
 	"strings"
+
+	"github.com/lilymona/gog/logging"
+	"github.com/lilymona/gog/transport"
+)
+
+var (
+	ErrInvalidLabel           = errors.New("Invalid label, should be key=value")
+	ErrInvalidCodec           = errors.New("Invalid codec, should be protobuf, json or msgpack")
+	ErrInvalidSendQueuePolicy = errors.New("Invalid send queue policy, should be drop or block")
+	ErrInvalidIDStrategy      = errors.New("Invalid id strategy, should be random, file or addr")
+	ErrNotReloadable          = errors.New("Field cannot be changed at runtime")
+)
+
+// The codecs of the messages.
+const (
+	CodecProtobuf = "protobuf"
+	CodecJSON     = "json"
+	CodecMsgpack  = "msgpack"
+)
+
+// The policies when the send queue of a neighbor is full.
+const (
+	// SendQueueDrop drops the new message.
+	SendQueueDrop = "drop"
+	// SendQueueBlock blocks the sender until the queue has room.
+	SendQueueBlock = "block"
 )
 
+// The strategies of getting the node id.
+const (
+	// IDRandom generates a random id on every start.
+	IDRandom = "random"
+	// IDFile reads the id from the id file, which is
+	// created with a random id if it does not exist.
+	IDFile = "file"
+	// IDAddr derives the id from the advertised address.
+	IDAddr = "addr"
+)
+
+// reloadable are the JSON names of the fields that can be changed
+// at runtime by Update.
+var reloadable = map[string]bool{
+	"active_view_min":        true,
+	"active_view_max":        true,
+	"passive_view":           true,
+	"ka":                     true,
+	"kb":                     true,
+	"arwl":                   true,
+	"prwl":                   true,
+	"srwl":                   true,
+	"message_life":           true,
+	"shuffle_duration":       true,
+	"min_shuffle_duration":   true,
+	"max_shuffle_duration":   true,
+	"send_byte_rate":         true,
+	"send_message_rate":      true,
+	"peer_send_byte_rate":    true,
+	"peer_send_message_rate": true,
+}
+
+// Tunable returns true if the key is the JSON name of
+// a field that can be changed at runtime by Update.
+func Tunable(key string) bool {
+	return reloadable[key]
+}
+
+// Fixed returns true if the key is the JSON name of
+// a field that cannot be changed at runtime by Update.
+func Fixed(key string) bool {
+	return fieldNames[key] && !reloadable[key]
+}
+
+// fieldNames are the JSON names of the fields.
+var fieldNames = func() map[string]bool {
+	names := make(map[string]bool)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}()
+
+// EnvPrefix is the prefix of the environment variables overriding the
+// flags, e.g. GOG_ADDR overrides -addr, and GOG_MAX_AVIEW_SIZE overrides
+// -max-aview-size.
+const EnvPrefix = "GOG_"
+
+// MaxPeers is the maximum size of the peer list.
+const MaxPeers = 1024
+
 // Config describes the config of the system.
 type Config struct {
 	// Net should be tcp4 or tcp6.
 	Net string `json:"net"`
 	// AddrStr is the local address string.
 	AddrStr string `json:"address"`
+	// AdvertiseAddr is the address advertised to the peers. If it is
+	// not set, the address is resolved from AddrStr and the addresses
+	// of the interfaces.
+	AdvertiseAddr string `json:"advertise_address"`
+	// ExtraAddrs are the additional addresses the agent listens on,
+	// e.g. an IPv6 address besides an IPv4 one, or the addresses of
+	// other interfaces. The peers only learn the advertised address.
+	ExtraAddrs []string `json:"extra_addresses"`
 	// Peers is peer list.
 	Peers []string `json:"-"`
+	// File is the JSON config file, empty if none.
+	File string `json:"-"`
+	// flags are the flags set in the arguments, set again
+	// over the config file and the environment on a reload.
+	flags map[string]string
 	// LocalTCPAddr is TCP address parsed from
 	// Net and AddrStr.
 	LocalTCPAddr *net.TCPAddr `json:"-"`
+	// ExtraTCPAddrs are the TCP addresses parsed
+	// from Net and ExtraAddrs.
+	ExtraTCPAddrs []*net.TCPAddr `json:"-"`
 	// AViewMinSize is the minimum size of the active view.
 	AViewMinSize int `json:"active_view_min"`
 	// AViewMaxSize is the maximum size of the active view.
@@ -44,81 +158,939 @@ type Config struct {
 	MLife int `json:"message_life"`
 	// Shuffle Duration in seconds.
 	ShuffleDuration int `json:"shuffle_duration"`
+	// The bounds of the shuffle duration in seconds. If set, the
+	// shuffle duration adapts to the churn of the views.
+	MinShuffleDuration int `json:"min_shuffle_duration"`
+	MaxShuffleDuration int `json:"max_shuffle_duration"`
 	// Heal Duration in seconds.
 	HealDuration int `json:"heal_duration"`
+	// Duration in seconds to check the view invariants,
+	// 0 to disable the check.
+	CheckDuration int `json:"check_duration"`
 	// The REST server address.
 	RESTAddrStr string `json:"rest_addr"`
-	// The path to user message handler(script).
-	UserMsgHandler string `json:"user_message_handler"`
+	// The certificate and the key files to serve the REST API
+	// over TLS. Both or neither must be set.
+	RESTTLSCert string `json:"rest_tls_cert"`
+	RESTTLSKey  string `json:"rest_tls_key"`
+	// Debug serves net/http/pprof and the internal state of the agent
+	// on the REST server, or on DebugAddrStr if it is set, which is
+	// usually bound to localhost.
+	Debug        bool   `json:"debug"`
+	DebugAddrStr string `json:"debug_addr"`
+	// The certificate, the key and the CA bundle files to connect
+	// the agents over TLS. The certificate is presented both to the
+	// peers that connect, and to the peers connected, so it should
+	// be valid for the advertised address and for client auth.
+	// If the CA bundle is not set, the system roots are used.
+	TLSCert string `json:"tls_cert"`
+	TLSKey  string `json:"tls_key"`
+	TLSCA   string `json:"tls_ca"`
+	// TLSRequireClientCert makes the agent reject the peers
+	// that connect without a certificate signed by the CA.
+	TLSRequireClientCert bool `json:"tls_require_client_cert"`
+	// TLSConfig is the TLS config loaded from the files above,
+	// nil if the agents connect over plain TCP.
+	TLSConfig *tls.Config `json:"-"`
+	// Transport connects the agents, nil for TCP, or TLS if
+	// TLSConfig is set. It is set by the programs embedding
+	// the agent, e.g. to an in-memory transport in tests.
+	Transport transport.Transport `json:"-"`
+	// Rand is the source of the random choices of the agent, e.g. the
+	// nodes to shuffle with, nil for a source seeded with the time. It
+	// is set by the programs embedding the agent, e.g. to a seeded
+	// source to replay a simulation.
+	Rand rand.Source `json:"-"`
+	// Logger writes the logs of the agent, the codec and the REST
+	// server, nil for the default logger of the logging package.
+	Logger logging.Logger `json:"-"`
+	// RESTAuthToken is the bearer token required by the REST API,
+	// empty to disable the authentication.
+	RESTAuthToken string `json:"rest_auth_token"`
+	// RESTAuthProbes requires the auth token on /api/health and
+	// /api/ready too, which are open to the probes by default.
+	RESTAuthProbes bool `json:"rest_auth_probes"`
+	// RESTCORSOrigins are the origins of the web pages allowed to call
+	// the REST API, "*" for any, empty to not send the CORS headers.
+	RESTCORSOrigins []string `json:"rest_cors_origins"`
+	// ClusterSecret is the shared secret that the agents authenticate
+	// each other with on every connection, empty to disable.
+	ClusterSecret string `json:"cluster_secret"`
+	// ClusterName is the name of the cluster of the agent. The Join,
+	// Neighbor and Shuffle messages from the agents of other clusters
+	// are rejected, so the overlays on the same network do not merge
+	// through the overlapping seeds. Empty for the default cluster.
+	ClusterName string `json:"cluster_name"`
+	// SigningKeyFile is the PEM file of the Ed25519 private key that
+	// the agent signs its user messages with, empty to not sign them.
+	SigningKeyFile string `json:"signing_key_file"`
+	// TrustedKeysFile is the PEM file of the Ed25519 public keys of
+	// the senders whose signatures are verified.
+	TrustedKeysFile string `json:"trusted_keys_file"`
+	// RequireSigned drops the user messages that are not signed
+	// with a trusted key.
+	RequireSigned bool `json:"require_signed"`
+	// SigningKey and TrustedKeys are loaded from the files above,
+	// or set by the programs embedding the agent.
+	SigningKey  ed25519.PrivateKey  `json:"-"`
+	TrustedKeys []ed25519.PublicKey `json:"-"`
+	// The path to user message handler(script), which reads the payload
+	// from stdin. It is killed after UserMsgHandlerTimeout milliseconds,
+	// 0 for no timeout, and at most UserMsgHandlerConcurrency of them run
+	// at once, 0 for unlimited, while the other messages wait.
+	UserMsgHandler            string `json:"user_message_handler"`
+	UserMsgHandlerTimeout     int    `json:"user_message_handler_timeout"`
+	UserMsgHandlerConcurrency int    `json:"user_message_handler_concurrency"`
 	// The duration to purge message buffer.
 	PurgeDuration int `json:"purge_duration"`
+	// The maximum number of the hashes of the received messages kept
+	// to suppress the duplicates, 0 for unbounded. The least recently
+	// used ones are evicted before their purge deadlines when it is full.
+	MessageCacheSize int `json:"message_cache_size"`
+	// The number of the latest sequence numbers of each sender tracked
+	// to drop the replayed user messages, 0 to disable. The messages
+	// older than the window are dropped too. Only the messages signed
+	// with a trusted key are tracked.
+	ReplayWindow int `json:"replay_window"`
+	// FIFOWindow is the maximum number of the user messages of each
+	// sender buffered to deliver them in the order they are sent, 0
+	// to deliver them as they arrive. The missing messages are skipped
+	// when the window is full, or after FIFOGapTimeout (milliseconds),
+	// 0 to only skip them when the window is full.
+	FIFOWindow     int `json:"fifo_window"`
+	FIFOGapTimeout int `json:"fifo_gap_timeout"`
+	// The maximum number of the failed messages buffered to resend,
+	// 0 for unlimited. The oldest messages are dropped when it is full.
+	FailedMessageBufferSize int `json:"failed_message_buffer"`
+	// The rate (messages per second) and the burst of the outbound
+	// forward joins. Zero rate disables the throttle.
+	ForwardJoinRate  float64 `json:"forward_join_rate"`
+	ForwardJoinBurst int     `json:"forward_join_burst"`
+	// The rates of the outbound user messages, in bytes and in messages
+	// per second, to all the neighbors and to each neighbor, 0 for
+	// unlimited. The bursts are a second worth of the rates. The messages
+	// over the rates wait in the send queues.
+	SendByteRate        float64 `json:"send_byte_rate"`
+	SendMessageRate     float64 `json:"send_message_rate"`
+	PeerSendByteRate    float64 `json:"peer_send_byte_rate"`
+	PeerSendMessageRate float64 `json:"peer_send_message_rate"`
+	// The user messages larger than CompressThreshold bytes are
+	// compressed, 0 to disable the compression. They are only
+	// compressed to the peers that advertise the compression.
+	CompressThreshold int `json:"compress_threshold"`
+	// The maximum size of the messages to read in bytes,
+	// 0 for the codec default.
+	MaxMessageSize int `json:"max_message_size"`
+	// The timeout in seconds to read from the peers, 0 to disable.
+	// As the idle connections time out as well, it should be larger
+	// than the interval of the messages, e.g. the PingInterval. It also
+	// bounds the wait for the first message of an accepted connection,
+	// which holds one of the ConnHandlers meanwhile.
+	ReadTimeout int `json:"read_timeout"`
+	// The timeout in seconds to write to the peers, 0 to disable, so
+	// a peer that stops reading does not block the writer forever.
+	WriteTimeout int `json:"write_timeout"`
+	// The size of the queue of the accepted connections.
+	ConnQueueSize int `json:"conn_queue_size"`
+	// The user messages to each neighbor are queued, and written by a
+	// goroutine per connection. SendQueueSize is the size of the queue,
+	// 0 for unbounded, and SendQueuePolicy is drop or block when full.
+	SendQueueSize   int    `json:"send_queue_size"`
+	SendQueuePolicy string `json:"send_queue_policy"`
+	// The user messages queued to a neighbor are written in batches
+	// of up to BatchSize messages, 0 or 1 to disable. BatchInterval is
+	// the time in milliseconds to wait for more messages to batch.
+	BatchSize     int `json:"batch_size"`
+	BatchInterval int `json:"batch_interval"`
+	// The payloads larger than ChunkSize bytes are broadcast in chunks
+	// of ChunkSize bytes, 0 to disable. The receivers reassemble the
+	// chunks for ChunkTimeout milliseconds, 0 for no timeout, in a buffer
+	// of up to ChunkBufferSize bytes, 0 to drop the chunks, which counts
+	// the bookkeeping of the incomplete payloads too. They are dropped
+	// after the timeout, or the oldest ones when the buffer is full.
+	ChunkSize       int `json:"chunk_size"`
+	ChunkTimeout    int `json:"chunk_timeout"`
+	ChunkBufferSize int `json:"chunk_buffer_size"`
+	// The number of the connection handlers, 0 to serve
+	// every accepted connection in its own goroutine.
+	ConnHandlers int `json:"conn_handlers"`
+	// The maximum number of the idle connections kept to send the
+	// shuffle replies and the neighbor requests again, 0 to disable,
+	// and the time in seconds after which they are closed. As the
+	// peers serve each idle connection by one of their ConnHandlers,
+	// the pool is disabled by default, and the idle timeout must be
+	// short, and shorter than the ReadTimeout of the peers.
+	ConnPoolSize    int `json:"conn_pool_size"`
+	ConnIdleTimeout int `json:"conn_idle_timeout"`
+	// ReuseAddr sets SO_REUSEADDR on the agent listener.
+	ReuseAddr bool `json:"reuse_addr"`
+	// ReusePort sets SO_REUSEPORT on the agent listener.
+	ReusePort bool `json:"reuse_port"`
+	// SerializeHandler makes the user message handler be invoked
+	// in order for the messages from the same source.
+	SerializeHandler bool `json:"serialize_handler"`
+	// Observe makes the agent join as an observer, so
+	// the peers will not forward its join to the cluster.
+	Observe bool `json:"observe"`
+	// Labels are the key/value metadata advertised to the peers.
+	Labels map[string]string `json:"labels"`
+	// The number of times to retry joining the peers, and the
+	// backoff in milliseconds before the first retry, which
+	// doubles on each retry, with a random jitter, up to
+	// JoinMaxBackoff.
+	JoinRetries    int `json:"join_retries"`
+	JoinBackoff    int `json:"join_backoff"`
+	JoinMaxBackoff int `json:"join_max_backoff"`
+	// The number of failed rounds of joining the peers again, after
+	// losing all of them, before the agent gives up. 0 never gives up.
+	RejoinAttempts int `json:"rejoin_attempts"`
+	// Plumtree makes the user messages be pushed along the epidemic
+	// broadcast trees, and only announced on the other links, instead
+	// of being flooded. GraftTimeout is the time in milliseconds to wait
+	// for an announced message before asking for it.
+	Plumtree     bool `json:"plumtree"`
+	GraftTimeout int  `json:"graft_timeout"`
+	// UDP makes the agent send the shuffles, the shuffle replies and the
+	// pings over UDP, on the port of its address, to the peers that read
+	// them, instead of TCP. The datagrams are not encrypted, so it is not
+	// supported over TLS.
+	UDP bool `json:"udp"`
+	// Reliable makes the neighbors acknowledge the user messages. The
+	// messages not acknowledged in AckTimeout milliseconds are sent again,
+	// with the timeout doubled on each retry, up to AckRetries times.
+	Reliable   bool `json:"reliable"`
+	AckTimeout int  `json:"ack_timeout"`
+	AckRetries int  `json:"ack_retries"`
+	// The neighbors are pinged every PingInterval seconds, 0 to disable,
+	// and removed from the active view after missing PingMisses pongs,
+	// at least 1 if enabled, in a row. A pong is missed if it does not arrive in PingTimeout
+	// milliseconds, which should be shorter than the interval, or
+	// before the next ping if 0.
+	PingInterval int `json:"ping_interval"`
+	PingTimeout  int `json:"ping_timeout"`
+	PingMisses   int `json:"ping_misses"`
+	// The neighbors exchange the digests of the nodes they reach every
+	// PartitionInterval seconds, 0 to disable. A partition is suspected
+	// when the nodes reached recently are fewer than PartitionThreshold
+	// of the most reached in the last PartitionHistory seconds, and the
+	// agent joins the seeds and the nodes it lost meanwhile to merge it.
+	PartitionInterval  int     `json:"partition_interval"`
+	PartitionThreshold float64 `json:"partition_threshold"`
+	PartitionHistory   int     `json:"partition_history"`
+	// The evicted nodes are banned for BanDuration seconds, 0 to
+	// only remove them from the views.
+	BanDuration int `json:"ban_duration"`
+	// StateFile is the file to persist the views, so the agent rejoins
+	// through their nodes after restart, before the configured peers.
+	// Empty to disable.
+	StateFile string `json:"state_file"`
+	// SpoolFile is the file to persist the failed messages, so they are
+	// resent after restart, empty to disable. SpoolSize is the maximum
+	// size of the file (bytes), the oldest messages are dropped beyond it,
+	// which must be positive with a SpoolFile.
+	SpoolFile string `json:"spool_file"`
+	SpoolSize int    `json:"spool_size"`
+	// Codec is the codec of the messages, protobuf, json or msgpack.
+	// All the agents of a cluster must use the same codec.
+	Codec string `json:"codec"`
+	// IDStrategy is how the agent gets its node id, random, file or
+	// addr. With file or addr, the agent keeps its id across the
+	// restarts. IDFile is the file of the id of the file strategy.
+	IDStrategy string `json:"id_strategy"`
+	IDFile     string `json:"id_file"`
+	// ID is the node id loaded from IDFile, or set by the programs
+	// embedding the agent, 0 to get the id by IDStrategy.
+	ID uint64 `json:"-"`
 }
 
+// DefaultConfig returns the built-in default configuration.
+func DefaultConfig() *Config {
+	return &Config{
+		Net:                       "tcp",
+		AddrStr:                   ":8424",
+		AViewMinSize:              3,
+		AViewMaxSize:              5,
+		PViewSize:                 30,
+		Ka:                        1,
+		Kp:                        3,
+		ARWL:                      5,
+		PRWL:                      3,
+		SRWL:                      5,
+		MLife:                     5000,
+		ShuffleDuration:           5,
+		HealDuration:              1,
+		CheckDuration:             10,
+		RESTAddrStr:               ":9424",
+		UserMsgHandlerTimeout:     10000,
+		UserMsgHandlerConcurrency: 8,
+		PurgeDuration:             5000,
+		MessageCacheSize:          1 << 16,
+		ReplayWindow:              1024,
+		FIFOGapTimeout:            1000,
+		ForwardJoinBurst:          10,
+		MaxMessageSize:            10 << 20,
+		SpoolSize:                 16 << 20,
+		ReadTimeout:               30,
+		WriteTimeout:              10,
+		ConnQueueSize:             64,
+		SendQueueSize:             256,
+		SendQueuePolicy:           SendQueueDrop,
+		BatchInterval:             5,
+		ChunkSize:                 1 << 20,
+		ChunkTimeout:              30000,
+		ChunkBufferSize:           64 << 20,
+		ConnHandlers:              16,
+		ConnIdleTimeout:           10,
+		FailedMessageBufferSize:   1024,
+		GraftTimeout:              500,
+		JoinRetries:               3,
+		AckTimeout:                500,
+		AckRetries:                3,
+		PingInterval:              5,
+		PingTimeout:               2000,
+		PingMisses:                3,
+		PartitionInterval:         10,
+		PartitionThreshold:        0.5,
+		PartitionHistory:          600,
+		BanDuration:               60,
+		JoinBackoff:               500,
+		JoinMaxBackoff:            30000,
+		Codec:                     CodecProtobuf,
+		IDStrategy:                IDRandom,
+	}
+}
+
+// ParseConfig parses the configuration from the command line flags of
+// the gog binary. The programs embedding the agent should use New.
 func ParseConfig() (*Config, error) {
-	var peerStr string
-	var peerFile string
+	return parseConfig(flag.CommandLine, os.Args[1:])
+}
 
-	cfg := new(Config)
+// parseConfig() parses the configuration from the arguments. The
+// defaults are overridden by the config file, then the environment
+// variables, see EnvPrefix, and then the flags set in the arguments.
+func parseConfig(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := DefaultConfig()
+	v := registerFlags(fs, cfg)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
 
-	flag.StringVar(&cfg.Net, "net", "tcp", "The network protocol")
-	flag.StringVar(&cfg.AddrStr, "addr", ":8424", "The address the agent listens on")
+	// The flags set in the arguments, to set them again over the
+	// config file and the environment.
+	set := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = f.Value.String() })
 
-	flag.StringVar(&peerFile, "peer-file", "", "Peer list file")
-	flag.StringVar(&peerStr, "peers", "", "Comma-separated list of peers")
+	// The file is loaded before the environment, so its
+	// path is looked up in both variables of its flags.
+	for _, name := range []string{"config", "config-file"} {
+		if v.cfgFile == "" {
+			v.cfgFile = os.Getenv(envName(name))
+		}
+	}
+	return layerConfig(fs, cfg, v, set)
+}
 
-	flag.IntVar(&cfg.AViewMinSize, "min-aview-size", 3, "The minimum size of the active view")
-	flag.IntVar(&cfg.AViewMaxSize, "max-aview-size", 5, "The maximum size of the active view")
-	flag.IntVar(&cfg.PViewSize, "pview-size", 30, "The size of the passive view")
+// ReloadFile parses the configuration again from the defaults, the
+// config file at path, the environment and the flags the config was
+// parsed from, in this order as ParseConfig, e.g. to reload the file on
+// SIGHUP. The flags of another package, e.g. the logging, are ignored.
+func (cfg *Config) ReloadFile(path string) (*Config, error) {
+	fs := flag.NewFlagSet("reload", flag.ContinueOnError)
+	c := DefaultConfig()
+	v := registerFlags(fs, c)
+	v.cfgFile = path
+	set := make(map[string]string)
+	for name, value := range cfg.flags {
+		if fs.Lookup(name) != nil && name != "config" && name != "config-file" {
+			set[name] = value
+		}
+	}
+	return layerConfig(fs, c, v, set)
+}
+
+// flagValues are the values of the flags that are not
+// fields of the config, and are parsed into them.
+type flagValues struct {
+	peerStr  string
+	peerFile string
+	labelStr string
+	extraStr string
+	corsStr  string
+	cfgFile  string
+}
 
-	flag.IntVar(&cfg.Ka, "ka", 1, "The number of active nodes to shuffle")
-	flag.IntVar(&cfg.Kp, "kp", 3, "The number of passive nodes to shuffle")
+// registerFlags() defines the flags of the config on the flag set.
+func registerFlags(fs *flag.FlagSet, cfg *Config) *flagValues {
+	v := new(flagValues)
+	fs.StringVar(&v.cfgFile, "config", "", "The JSON config file, overridden by the environment and the flags")
+	fs.StringVar(&v.cfgFile, "config-file", "", "Same as -config")
+	fs.StringVar(&cfg.Net, "net", cfg.Net, "The network protocol")
+	fs.StringVar(&cfg.AddrStr, "addr", cfg.AddrStr, "The address the agent listens on")
+	fs.StringVar(&v.extraStr, "extra-addrs", "", "Comma-separated list of additional addresses the agent listens on")
 
-	flag.IntVar(&cfg.ARWL, "arwl", 5, "The active random walk length")
-	flag.IntVar(&cfg.PRWL, "prwl", 3, "The passive random walk length")
-	flag.IntVar(&cfg.SRWL, "srwl", 5, "The shuffle random walk length")
+	fs.StringVar(&cfg.AdvertiseAddr, "advertise-addr", cfg.AdvertiseAddr, "The address advertised to the peers, detected if empty")
 
-	flag.IntVar(&cfg.MLife, "msg-life", 5000, "The default message life (milliseconds)")
-	flag.IntVar(&cfg.ShuffleDuration, "shuffle-duration", 5, "The default shuffle duration (seconds)")
-	flag.IntVar(&cfg.HealDuration, "heal", 1, "The default heal duration (seconds)")
-	flag.StringVar(&cfg.RESTAddrStr, "rest-addr", ":9424", "The address of the REST server")
-	flag.StringVar(&cfg.UserMsgHandler, "user-message-handler", "", "The path to the user message handler script")
-	flag.IntVar(&cfg.PurgeDuration, "purge-duration", 5000, "The default purge duration (milliseconds)")
+	fs.StringVar(&v.peerFile, "peer-file", "", "Peer list file")
+	fs.StringVar(&v.peerStr, "peers", "", "Comma-separated list of peers")
 
-	flag.Parse()
+	fs.IntVar(&cfg.AViewMinSize, "min-aview-size", cfg.AViewMinSize, "The minimum size of the active view")
+	fs.IntVar(&cfg.AViewMaxSize, "max-aview-size", cfg.AViewMaxSize, "The maximum size of the active view")
+	fs.IntVar(&cfg.PViewSize, "pview-size", cfg.PViewSize, "The size of the passive view")
+
+	fs.IntVar(&cfg.Ka, "ka", cfg.Ka, "The number of active nodes to shuffle")
+	fs.IntVar(&cfg.Kp, "kp", cfg.Kp, "The number of passive nodes to shuffle")
+
+	fs.IntVar(&cfg.ARWL, "arwl", cfg.ARWL, "The active random walk length")
+	fs.IntVar(&cfg.PRWL, "prwl", cfg.PRWL, "The passive random walk length")
+	fs.IntVar(&cfg.SRWL, "srwl", cfg.SRWL, "The shuffle random walk length")
+
+	fs.IntVar(&cfg.MLife, "msg-life", cfg.MLife, "The default message life (milliseconds)")
+	fs.IntVar(&cfg.ShuffleDuration, "shuffle-duration", cfg.ShuffleDuration, "The default shuffle duration (seconds)")
+	fs.IntVar(&cfg.MinShuffleDuration, "min-shuffle-duration", cfg.MinShuffleDuration, "The minimum adaptive shuffle duration (seconds), 0 to disable")
+	fs.IntVar(&cfg.MaxShuffleDuration, "max-shuffle-duration", cfg.MaxShuffleDuration, "The maximum adaptive shuffle duration (seconds), 0 to disable")
+	fs.IntVar(&cfg.HealDuration, "heal", cfg.HealDuration, "The interval to heal the active view (seconds), 0 to disable")
+	fs.IntVar(&cfg.CheckDuration, "check-duration", cfg.CheckDuration, "The duration to check the view invariants (seconds), 0 to disable")
+	fs.StringVar(&cfg.RESTAddrStr, "rest-addr", cfg.RESTAddrStr, "The address of the REST server")
+	fs.StringVar(&cfg.RESTTLSCert, "rest-tls-cert", cfg.RESTTLSCert, "The certificate file to serve the REST API over TLS")
+	fs.StringVar(&cfg.RESTTLSKey, "rest-tls-key", cfg.RESTTLSKey, "The key file to serve the REST API over TLS")
+	fs.BoolVar(&cfg.Debug, "debug", cfg.Debug, "Serve pprof and the internal state of the agent on the REST server")
+	fs.StringVar(&cfg.DebugAddrStr, "debug-addr", cfg.DebugAddrStr, "The address to serve the debug endpoints on instead of the REST server")
+	fs.StringVar(&cfg.TLSCert, "tls-cert", cfg.TLSCert, "The certificate file to connect the agents over TLS")
+	fs.StringVar(&cfg.TLSKey, "tls-key", cfg.TLSKey, "The key file to connect the agents over TLS")
+	fs.StringVar(&cfg.TLSCA, "tls-ca", cfg.TLSCA, "The CA bundle file to verify the peers, empty for the system roots")
+	fs.BoolVar(&cfg.TLSRequireClientCert, "tls-require-client-cert", cfg.TLSRequireClientCert, "Reject the peers that connect without a verified certificate")
+	fs.StringVar(&cfg.RESTAuthToken, "rest-auth-token", cfg.RESTAuthToken, "The bearer token required by the REST API, empty to disable")
+	fs.StringVar(&v.corsStr, "rest-cors-origins", "", "Comma-separated list of the origins allowed to call the REST API, * for any")
+	fs.BoolVar(&cfg.RESTAuthProbes, "rest-auth-probes", cfg.RESTAuthProbes, "Require the auth token on the health checks too")
+	fs.StringVar(&cfg.ClusterSecret, "cluster-secret", cfg.ClusterSecret, "The shared secret to authenticate the agents, empty to disable")
+	fs.StringVar(&cfg.ClusterName, "cluster-name", cfg.ClusterName, "The name of the cluster, the agents of other clusters are rejected")
+	fs.StringVar(&cfg.SigningKeyFile, "signing-key", cfg.SigningKeyFile, "The PEM file of the Ed25519 key to sign the user messages, empty to disable")
+	fs.StringVar(&cfg.TrustedKeysFile, "trusted-keys", cfg.TrustedKeysFile, "The PEM file of the Ed25519 public keys to verify the user messages")
+	fs.BoolVar(&cfg.RequireSigned, "require-signed", cfg.RequireSigned, "Drop the user messages not signed with a trusted key")
+	fs.StringVar(&cfg.UserMsgHandler, "user-message-handler", cfg.UserMsgHandler, "The path to the user message handler script, which reads the payload from stdin")
+	fs.IntVar(&cfg.UserMsgHandlerTimeout, "user-message-handler-timeout", cfg.UserMsgHandlerTimeout, "The time after which the user message handler is killed (milliseconds), 0 for no timeout")
+	fs.IntVar(&cfg.UserMsgHandlerConcurrency, "user-message-handler-concurrency", cfg.UserMsgHandlerConcurrency, "The maximum number of the user message handlers run at once, 0 for unlimited")
+	fs.IntVar(&cfg.PurgeDuration, "purge-duration", cfg.PurgeDuration, "The default purge duration (milliseconds)")
+	fs.IntVar(&cfg.MessageCacheSize, "message-cache-size", cfg.MessageCacheSize, "The maximum number of the received messages remembered to drop the duplicates, 0 for unbounded")
+	fs.IntVar(&cfg.ReplayWindow, "replay-window", cfg.ReplayWindow, "The number of the latest sequence numbers of each sender tracked to drop the replayed messages, 0 to disable")
+	fs.IntVar(&cfg.FIFOWindow, "fifo-window", cfg.FIFOWindow, "The maximum number of the messages of each sender buffered to deliver them in order, 0 to disable")
+	fs.IntVar(&cfg.FIFOGapTimeout, "fifo-gap-timeout", cfg.FIFOGapTimeout, "The time to wait for the missing messages of a sender before skipping them (milliseconds), 0 to wait until the window is full")
+	fs.IntVar(&cfg.FailedMessageBufferSize, "failed-message-buffer", cfg.FailedMessageBufferSize, "The maximum number of the failed messages to resend, 0 for unlimited")
+	fs.Float64Var(&cfg.ForwardJoinRate, "forward-join-rate", cfg.ForwardJoinRate, "The rate of the outbound forward joins (per second), 0 for unlimited")
+	fs.IntVar(&cfg.ForwardJoinBurst, "forward-join-burst", cfg.ForwardJoinBurst, "The burst of the outbound forward joins")
+	fs.Float64Var(&cfg.SendByteRate, "send-byte-rate", cfg.SendByteRate, "The rate of the outbound user messages to all the neighbors (bytes per second), 0 for unlimited")
+	fs.Float64Var(&cfg.SendMessageRate, "send-message-rate", cfg.SendMessageRate, "The rate of the outbound user messages to all the neighbors (per second), 0 for unlimited")
+	fs.Float64Var(&cfg.PeerSendByteRate, "peer-send-byte-rate", cfg.PeerSendByteRate, "The rate of the outbound user messages to each neighbor (bytes per second), 0 for unlimited")
+	fs.Float64Var(&cfg.PeerSendMessageRate, "peer-send-message-rate", cfg.PeerSendMessageRate, "The rate of the outbound user messages to each neighbor (per second), 0 for unlimited")
+	fs.IntVar(&cfg.CompressThreshold, "compress-threshold", cfg.CompressThreshold, "The minimum size of the user messages to compress (bytes), 0 to disable")
+	fs.IntVar(&cfg.MaxMessageSize, "max-message-size", cfg.MaxMessageSize, "The maximum size of the messages to read (bytes)")
+	fs.IntVar(&cfg.ReadTimeout, "read-timeout", cfg.ReadTimeout, "The timeout to read from the peers (seconds), 0 to disable")
+	fs.IntVar(&cfg.WriteTimeout, "write-timeout", cfg.WriteTimeout, "The timeout to write to the peers (seconds), 0 to disable")
+	fs.IntVar(&cfg.ConnQueueSize, "conn-queue-size", cfg.ConnQueueSize, "The size of the queue of the accepted connections")
+	fs.IntVar(&cfg.SendQueueSize, "send-queue-size", cfg.SendQueueSize, "The size of the queue of the user messages to each neighbor, 0 for unbounded")
+	fs.StringVar(&cfg.SendQueuePolicy, "send-queue-policy", cfg.SendQueuePolicy, "Drop the user messages or block when the send queue is full, drop or block")
+	fs.IntVar(&cfg.BatchSize, "batch-size", cfg.BatchSize, "The maximum number of the user messages written in a batch, 0 to disable")
+	fs.IntVar(&cfg.BatchInterval, "batch-interval", cfg.BatchInterval, "The time to wait for more user messages to batch (milliseconds)")
+	fs.IntVar(&cfg.ChunkSize, "chunk-size", cfg.ChunkSize, "The size of the chunks of the larger payloads (bytes), 0 to disable")
+	fs.IntVar(&cfg.ChunkTimeout, "chunk-timeout", cfg.ChunkTimeout, "The time to reassemble the chunks of a payload (milliseconds)")
+	fs.IntVar(&cfg.ChunkBufferSize, "chunk-buffer-size", cfg.ChunkBufferSize, "The maximum size of the chunks being reassembled (bytes)")
+	fs.IntVar(&cfg.ConnHandlers, "conn-handlers", cfg.ConnHandlers, "The number of the connection handlers, 0 for unbounded")
+	fs.IntVar(&cfg.ConnPoolSize, "conn-pool-size", cfg.ConnPoolSize, "The maximum number of the idle connections to reuse, 0 to disable")
+	fs.IntVar(&cfg.ConnIdleTimeout, "conn-idle-timeout", cfg.ConnIdleTimeout, "The time to keep the idle connections (seconds), 0 to keep them")
+	fs.BoolVar(&cfg.ReuseAddr, "reuse-addr", cfg.ReuseAddr, "Set SO_REUSEADDR on the agent listener")
+	fs.BoolVar(&cfg.ReusePort, "reuse-port", cfg.ReusePort, "Set SO_REUSEPORT on the agent listener")
+	fs.BoolVar(&cfg.SerializeHandler, "serialize-handler", cfg.SerializeHandler, "Invoke the message handler in order for each source")
+	fs.BoolVar(&cfg.Observe, "observe", cfg.Observe, "Join the cluster as an observer")
+	fs.StringVar(&v.labelStr, "labels", "", "Comma-separated list of key=value labels")
+	fs.BoolVar(&cfg.Plumtree, "plumtree", cfg.Plumtree, "Push the user messages along the broadcast trees instead of flooding")
+	fs.BoolVar(&cfg.UDP, "udp", cfg.UDP, "Send the shuffles and the pings over UDP to the peers that read them")
+	fs.IntVar(&cfg.GraftTimeout, "graft-timeout", cfg.GraftTimeout, "The time to wait for an announced message before asking for it (milliseconds)")
+	fs.BoolVar(&cfg.Reliable, "reliable", cfg.Reliable, "Make the neighbors acknowledge the user messages, and retry the unacknowledged")
+	fs.IntVar(&cfg.AckTimeout, "ack-timeout", cfg.AckTimeout, "The time to wait for an ack before the first retry (milliseconds)")
+	fs.IntVar(&cfg.AckRetries, "ack-retries", cfg.AckRetries, "The number of times to retry the unacknowledged messages")
+	fs.IntVar(&cfg.PingInterval, "ping-interval", cfg.PingInterval, "The interval to ping the neighbors (seconds), 0 to disable")
+	fs.IntVar(&cfg.PingTimeout, "ping-timeout", cfg.PingTimeout, "The time to wait for a pong (milliseconds)")
+	fs.IntVar(&cfg.PingMisses, "ping-misses", cfg.PingMisses, "The number of missed pongs after which a neighbor is removed")
+	fs.IntVar(&cfg.PartitionInterval, "partition-interval", cfg.PartitionInterval, "The interval to exchange the digests of the reached nodes (seconds), 0 to disable the partition detection")
+	fs.Float64Var(&cfg.PartitionThreshold, "partition-threshold", cfg.PartitionThreshold, "The fraction of the most nodes reached under which a partition is suspected")
+	fs.IntVar(&cfg.PartitionHistory, "partition-history", cfg.PartitionHistory, "The time to remember the nodes reached (seconds)")
+	fs.IntVar(&cfg.BanDuration, "ban-duration", cfg.BanDuration, "The time to ban the evicted nodes (seconds), 0 to disable")
+	fs.StringVar(&cfg.StateFile, "state-file", cfg.StateFile, "The file to persist the views, empty to disable")
+	fs.StringVar(&cfg.SpoolFile, "spool-file", cfg.SpoolFile, "The file to persist the failed messages to resend, empty to disable")
+	fs.IntVar(&cfg.SpoolSize, "spool-size", cfg.SpoolSize, "The maximum size of the spool file (bytes)")
+	fs.IntVar(&cfg.JoinRetries, "join-retries", cfg.JoinRetries, "The number of times to retry joining the peers")
+	fs.IntVar(&cfg.JoinBackoff, "join-backoff", cfg.JoinBackoff, "The backoff before the first join retry (milliseconds)")
+	fs.IntVar(&cfg.JoinMaxBackoff, "join-max-backoff", cfg.JoinMaxBackoff, "The maximum backoff between the join retries (milliseconds)")
+	fs.IntVar(&cfg.RejoinAttempts, "rejoin-attempts", cfg.RejoinAttempts, "The number of failed rejoins before giving up, 0 to never give up")
+	fs.StringVar(&cfg.Codec, "codec", cfg.Codec, "The codec of the messages, protobuf, json or msgpack")
+	fs.StringVar(&cfg.IDStrategy, "id-strategy", cfg.IDStrategy, "How to get the node id, random, file or addr")
+	fs.StringVar(&cfg.IDFile, "id-file", cfg.IDFile, "The file of the node id with the file strategy, created if it does not exist")
+	return v
+}
+
+// layerConfig() overrides the defaults of the config with the config
+// file, then the environment variables, and then the flags set.
+func layerConfig(fs *flag.FlagSet, cfg *Config, v *flagValues, set map[string]string) (*Config, error) {
+	if v.cfgFile != "" {
+		if err := loadConfigFile(cfg, v.cfgFile); err != nil {
+			return nil, err
+		}
+		cfg.File = v.cfgFile
+	}
+	if err := loadEnv(fs); err != nil {
+		return nil, err
+	}
+	for name, value := range set {
+		if err := fs.Set(name, value); err != nil {
+			return nil, err
+		}
+	}
+	cfg.flags = set
 
 	// Check configuration.
-	if peerStr != "" {
-		cfg.Peers = strings.Split(peerStr, ",")
+	if v.peerStr != "" {
+		cfg.Peers = strings.Split(v.peerStr, ",")
 	}
-	if peerFile != "" {
-		peers, err := parsePeerFile(peerFile)
+	if v.peerFile != "" {
+		peers, err := parsePeerFile(v.peerFile)
 		if err != nil {
 			return nil, err
 		}
 		cfg.Peers = peers
 	}
 
-	// Check agent server address.
-	tcpAddr, err := net.ResolveTCPAddr(cfg.Net, cfg.AddrStr)
-	if err != nil {
+	if v.extraStr != "" {
+		cfg.ExtraAddrs = strings.Split(v.extraStr, ",")
+	}
+
+	if v.corsStr != "" {
+		cfg.RESTCORSOrigins = strings.Split(v.corsStr, ",")
+	}
+
+	if v.labelStr != "" {
+		labels, err := parseLabels(v.labelStr)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Labels = labels
+	}
+
+	if err := cfg.check(); err != nil {
 		return nil, err
 	}
-	cfg.LocalTCPAddr = tcpAddr
+	return cfg, nil
+}
+
+// check() checks the configuration, and resolves the local
+// addresses and loads the TLS config.
+func (cfg *Config) check() error {
+	if cfg.Codec != CodecProtobuf && cfg.Codec != CodecJSON && cfg.Codec != CodecMsgpack {
+		return ErrInvalidCodec
+	}
+	if cfg.SendQueuePolicy != SendQueueDrop && cfg.SendQueuePolicy != SendQueueBlock {
+		return ErrInvalidSendQueuePolicy
+	}
+	if cfg.IDStrategy != IDRandom && cfg.IDStrategy != IDFile && cfg.IDStrategy != IDAddr {
+		return ErrInvalidIDStrategy
+	}
+
+	// Check agent server address, which is not a TCP
+	// address with a transport other than TCP.
+	if cfg.Transport == nil {
+		tcpAddr, err := net.ResolveTCPAddr(cfg.Net, cfg.AddrStr)
+		if err != nil {
+			return err
+		}
+		cfg.LocalTCPAddr = tcpAddr
+
+		cfg.ExtraTCPAddrs = nil
+		for _, addr := range cfg.ExtraAddrs {
+			tcpAddr, err := net.ResolveTCPAddr(cfg.Net, addr)
+			if err != nil {
+				return err
+			}
+			cfg.ExtraTCPAddrs = append(cfg.ExtraTCPAddrs, tcpAddr)
+		}
+	}
 
 	// Check REST API address.
-	_, err = net.ResolveTCPAddr(cfg.Net, cfg.RESTAddrStr)
-	if err != nil {
-		return nil, err
+	if _, err := net.ResolveTCPAddr(cfg.Net, cfg.RESTAddrStr); err != nil {
+		return err
+	}
+	if cfg.DebugAddrStr != "" {
+		if _, err := net.ResolveTCPAddr(cfg.Net, cfg.DebugAddrStr); err != nil {
+			return err
+		}
 	}
 
 	// Check User Message Handler.
 	if cfg.UserMsgHandler != "" {
-		_, err = exec.LookPath(cfg.UserMsgHandler)
+		if _, err := exec.LookPath(cfg.UserMsgHandler); err != nil {
+			return err
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	if cfg.TLSCert != "" {
+		tlsConfig, err := LoadTLSConfig(cfg)
+		if err != nil {
+			return err
+		}
+		cfg.TLSConfig = tlsConfig
+	}
+	if cfg.SigningKeyFile != "" {
+		key, err := LoadSigningKey(cfg.SigningKeyFile)
+		if err != nil {
+			return err
+		}
+		cfg.SigningKey = key
+	}
+	if cfg.TrustedKeysFile != "" {
+		keys, err := LoadTrustedKeys(cfg.TrustedKeysFile)
+		if err != nil {
+			return err
+		}
+		cfg.TrustedKeys = keys
+	}
+	if cfg.IDStrategy == IDFile && cfg.ID == 0 {
+		id, err := LoadNodeID(cfg.IDFile)
+		if err != nil {
+			return err
+		}
+		cfg.ID = id
+	}
+	return nil
+}
+
+// LoadSigningKey loads the Ed25519 private key from the PKCS #8
+// PEM file, e.g. generated by "openssl genpkey -algorithm ed25519".
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read signing key: %v", err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("Cannot parse signing key %s", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot parse signing key %s: %v", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("Signing key %s is not an Ed25519 key", path)
+	}
+	return priv, nil
+}
+
+// LoadTrustedKeys loads the Ed25519 public keys from the PEM file
+// of the PKIX public keys, e.g. generated by "openssl pkey -pubout".
+func LoadTrustedKeys(path string) ([]ed25519.PublicKey, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read trusted keys: %v", err)
+	}
+	var keys []ed25519.PublicKey
+	for {
+		var block *pem.Block
+		if block, b = pem.Decode(b); block == nil {
+			break
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
 		if err != nil {
+			return nil, fmt.Errorf("Cannot parse trusted keys %s: %v", path, err)
+		}
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("Trusted key in %s is not an Ed25519 key", path)
+		}
+		keys = append(keys, pub)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("No trusted keys in %s", path)
+	}
+	return keys, nil
+}
+
+// LoadTLSConfig loads the TLS config of the agent connections
+// from the certificate, the key and the CA bundle files.
+func LoadTLSConfig(cfg *Config) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot load TLS key pair: %v", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+	}
+	if cfg.TLSRequireClientCert {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if cfg.TLSCA != "" {
+		b, err := ioutil.ReadFile(cfg.TLSCA)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read TLS CA bundle: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("Cannot parse TLS CA bundle %s", cfg.TLSCA)
+		}
+		tlsConfig.RootCAs = pool
+		tlsConfig.ClientCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// Validate checks the invariants of the view sizes,
+// and that the walk lengths and durations are not negative.
+// All the violations are reported in the error.
+func (cfg *Config) Validate() error {
+	var errs []string
+	invalid := func(format string, a ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, a...))
+	}
+	if cfg.AViewMaxSize < 1 {
+		invalid("AViewMaxSize %d < 1", cfg.AViewMaxSize)
+	}
+	if cfg.AViewMinSize > cfg.AViewMaxSize {
+		invalid("AViewMinSize %d > AViewMaxSize %d", cfg.AViewMinSize, cfg.AViewMaxSize)
+	}
+	if cfg.PViewSize < 1 {
+		invalid("PViewSize %d < 1", cfg.PViewSize)
+	}
+	if cfg.Ka > cfg.AViewMaxSize {
+		invalid("Ka %d > AViewMaxSize %d", cfg.Ka, cfg.AViewMaxSize)
+	}
+	if cfg.Kp > cfg.PViewSize {
+		invalid("Kp %d > PViewSize %d", cfg.Kp, cfg.PViewSize)
+	}
+	if (cfg.RESTTLSCert == "") != (cfg.RESTTLSKey == "") {
+		invalid("RESTTLSCert and RESTTLSKey must be set together")
+	}
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		invalid("TLSCert and TLSKey must be set together")
+	}
+	if (cfg.TLSCA != "" || cfg.TLSRequireClientCert) && cfg.TLSCert == "" {
+		invalid("TLSCA and TLSRequireClientCert need TLSCert")
+	}
+	if cfg.IDStrategy == IDFile && cfg.IDFile == "" {
+		invalid("IDStrategy file needs IDFile")
+	}
+	if cfg.UDP && cfg.TLSCert != "" {
+		invalid("UDP is not supported over TLS")
+	}
+	for _, f := range []struct {
+		name  string
+		value int
+	}{
+		{"ARWL", cfg.ARWL},
+		{"PRWL", cfg.PRWL},
+		{"SRWL", cfg.SRWL},
+		{"MLife", cfg.MLife},
+		{"ShuffleDuration", cfg.ShuffleDuration},
+		{"MinShuffleDuration", cfg.MinShuffleDuration},
+		{"MaxShuffleDuration", cfg.MaxShuffleDuration},
+		{"HealDuration", cfg.HealDuration},
+		{"CheckDuration", cfg.CheckDuration},
+		{"UserMsgHandlerTimeout", cfg.UserMsgHandlerTimeout},
+		{"UserMsgHandlerConcurrency", cfg.UserMsgHandlerConcurrency},
+		{"PurgeDuration", cfg.PurgeDuration},
+		{"MessageCacheSize", cfg.MessageCacheSize},
+		{"ReplayWindow", cfg.ReplayWindow},
+		{"FIFOWindow", cfg.FIFOWindow},
+		{"FIFOGapTimeout", cfg.FIFOGapTimeout},
+		{"ReadTimeout", cfg.ReadTimeout},
+		{"WriteTimeout", cfg.WriteTimeout},
+		{"ConnPoolSize", cfg.ConnPoolSize},
+		{"ConnIdleTimeout", cfg.ConnIdleTimeout},
+		{"JoinBackoff", cfg.JoinBackoff},
+		{"JoinMaxBackoff", cfg.JoinMaxBackoff},
+		{"RejoinAttempts", cfg.RejoinAttempts},
+		{"GraftTimeout", cfg.GraftTimeout},
+		{"FailedMessageBufferSize", cfg.FailedMessageBufferSize},
+		{"AckTimeout", cfg.AckTimeout},
+		{"AckRetries", cfg.AckRetries},
+		{"PingInterval", cfg.PingInterval},
+		{"PingTimeout", cfg.PingTimeout},
+		{"PartitionInterval", cfg.PartitionInterval},
+		{"PartitionHistory", cfg.PartitionHistory},
+		{"BanDuration", cfg.BanDuration},
+		{"SpoolSize", cfg.SpoolSize},
+		{"SendQueueSize", cfg.SendQueueSize},
+		{"BatchSize", cfg.BatchSize},
+		{"BatchInterval", cfg.BatchInterval},
+		{"ChunkSize", cfg.ChunkSize},
+		{"ChunkTimeout", cfg.ChunkTimeout},
+		{"ChunkBufferSize", cfg.ChunkBufferSize},
+	} {
+		if f.value < 0 {
+			invalid("%s %d < 0", f.name, f.value)
+		}
+	}
+	if cfg.PingMisses < 0 || (cfg.PingInterval > 0 && cfg.PingMisses < 1) {
+		invalid("PingMisses %d < 1", cfg.PingMisses)
+	}
+	if cfg.PingInterval > 0 && cfg.PingTimeout >= cfg.PingInterval*1000 {
+		invalid("PingTimeout %dms >= PingInterval %ds", cfg.PingTimeout, cfg.PingInterval)
+	}
+	if cfg.PartitionThreshold < 0 || cfg.PartitionThreshold > 1 {
+		invalid("PartitionThreshold %v not in [0, 1]", cfg.PartitionThreshold)
+	}
+	if cfg.ConnPoolSize > 0 && cfg.ReadTimeout > 0 && (cfg.ConnIdleTimeout == 0 || cfg.ConnIdleTimeout >= cfg.ReadTimeout) {
+		invalid("ConnIdleTimeout %ds not in (0, ReadTimeout %ds)", cfg.ConnIdleTimeout, cfg.ReadTimeout)
+	}
+	if cfg.SpoolFile != "" && cfg.SpoolSize < 1 {
+		invalid("SpoolSize %d < 1 with SpoolFile %q", cfg.SpoolSize, cfg.SpoolFile)
+	}
+	if cfg.ChunkSize > 0 && cfg.MaxMessageSize > 0 && cfg.ChunkSize >= cfg.MaxMessageSize {
+		invalid("ChunkSize %d >= MaxMessageSize %d", cfg.ChunkSize, cfg.MaxMessageSize)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("Invalid config: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Diff returns the fields whose values differ from the base config,
+// keyed by their JSON names.
+func (cfg *Config) Diff(base *Config) (map[string]interface{}, error) {
+	fields, err := jsonFields(cfg)
+	if err != nil {
+		return nil, err
+	}
+	baseFields, err := jsonFields(base)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range fields {
+		if reflect.DeepEqual(v, baseFields[k]) {
+			delete(fields, k)
+		}
+	}
+	return fields, nil
+}
+
+// Update returns a copy of the config updated with the fields of the
+// JSON object, e.g. the body of a PUT or the reloaded config file. Only
+// the tunables, e.g. the view sizes and the message life, can be changed
+// at runtime, so it returns ErrNotReloadable if another field changes,
+// or the validation error if the updated config is invalid. The unknown
+// fields are ignored.
+func (cfg *Config) Update(b []byte) (*Config, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	current, err := jsonFields(cfg)
+	if err != nil {
+		return nil, err
+	}
+	updates := make(map[string]json.RawMessage)
+	for k, raw := range fields {
+		value, ok := current[k]
+		if !ok {
+			continue
+		}
+		if reloadable[k] {
+			updates[k] = raw
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
 			return nil, err
 		}
+		if !reflect.DeepEqual(v, value) {
+			return nil, fmt.Errorf("%w: %s", ErrNotReloadable, k)
+		}
 	}
-	return cfg, nil
+
+	if b, err = json.Marshal(updates); err != nil {
+		return nil, err
+	}
+	c := *cfg
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// WithTunables returns a copy of the config with the tunables, e.g. the
+// view sizes and the message life, of the other config. The other fields
+// are kept, as they cannot be changed at runtime.
+func (cfg *Config) WithTunables(other *Config) (*Config, error) {
+	fields, err := jsonFields(other)
+	if err != nil {
+		return nil, err
+	}
+	updates := make(map[string]interface{})
+	for k, v := range fields {
+		if reloadable[k] {
+			updates[k] = v
+		}
+	}
+	b, err := json.Marshal(updates)
+	if err != nil {
+		return nil, err
+	}
+	c := *cfg
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// jsonFields() returns the JSON fields of the config.
+func jsonFields(cfg *Config) (map[string]interface{}, error) {
+	var fields map[string]interface{}
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// loadConfigFile() loads the JSON config file into the config.
+// Unknown keys are ignored.
+func loadConfigFile(cfg *Config, path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Cannot read config file: %v", err)
+	}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return fmt.Errorf("Cannot parse config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// loadEnv() sets the flags from their environment variables, if set.
+func loadEnv(fs *flag.FlagSet) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok || err != nil {
+			return
+		}
+		if e := fs.Set(f.Name, value); e != nil {
+			err = fmt.Errorf("Invalid %s: %v", envName(f.Name), e)
+		}
+	})
+	return err
+}
+
+// envName() returns the environment variable of the flag.
+func envName(flag string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(flag, "-", "_", -1))
 }
 
 func parsePeerFile(path string) ([]string, error) {
@@ -139,14 +1111,48 @@ func parsePeerFile(path string) ([]string, error) {
 	return peers, nil
 }
 
-func (cfg *Config) ShufflePeers() []string {
+// parseLabels parses the labels in the form of "k1=v1,k2=v2".
+func parseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, kv := range strings.Split(s, ",") {
+		i := strings.Index(kv, "=")
+		if i <= 0 {
+			return nil, ErrInvalidLabel
+		}
+		labels[kv[:i]] = kv[i+1:]
+	}
+	return labels, nil
+}
+
+// AddPeers appends the peers that are not in the peer list yet.
+// If the list grows beyond MaxPeers, the oldest peers are dropped.
+func (cfg *Config) AddPeers(peers ...string) {
+	for _, peer := range peers {
+		existed := false
+		for _, p := range cfg.Peers {
+			if p == peer {
+				existed = true
+				break
+			}
+		}
+		if !existed {
+			cfg.Peers = append(cfg.Peers, peer)
+		}
+	}
+	if len(cfg.Peers) > MaxPeers {
+		cfg.Peers = cfg.Peers[len(cfg.Peers)-MaxPeers:]
+	}
+}
+
+// ShufflePeers returns the peers in a random order chosen by r.
+func (cfg *Config) ShufflePeers(r *rand.Rand) []string {
 	shuffledPeers := make([]string, len(cfg.Peers))
 	copy(shuffledPeers, cfg.Peers)
 	for i := range shuffledPeers {
 		if i == 0 {
 			continue
 		}
-		swapIndex := rand.Intn(i)
+		swapIndex := r.Intn(i)
 		shuffledPeers[i], shuffledPeers[swapIndex] = shuffledPeers[swapIndex], shuffledPeers[i]
 	}
 	return shuffledPeers
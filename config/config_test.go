@@ -0,0 +1,357 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lilymona/gog/transport"
+	"github.com/lilymona/testify/assert"
+)
+
+func TestAddPeers(t *testing.T) {
+	cfg := new(Config)
+	cfg.AddPeers("foo", "bar")
+	cfg.AddPeers("bar", "baz", "foo")
+	assert.Equal(t, []string{"foo", "bar", "baz"}, cfg.Peers)
+
+	for i := 0; i < MaxPeers; i++ {
+		cfg.AddPeers(strconv.Itoa(i))
+	}
+	assert.Equal(t, MaxPeers, len(cfg.Peers))
+	assert.Equal(t, "0", cfg.Peers[0])
+	assert.Equal(t, strconv.Itoa(MaxPeers-1), cfg.Peers[MaxPeers-1])
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := parseLabels("region=eu,role=web,empty=")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"region": "eu", "role": "web", "empty": ""}, labels)
+
+	_, err = parseLabels("region")
+	assert.Equal(t, ErrInvalidLabel, err)
+	_, err = parseLabels("=eu")
+	assert.Equal(t, ErrInvalidLabel, err)
+}
+
+func TestParseConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "gog.json")
+	b := []byte(`{"passive_view": 50, "active_view_max": 7, "unknown": true}`)
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The flag overrides the file, wherever it is.
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg, err := parseConfig(fs, []string{"-pview-size", "60", "-config", path})
+	assert.NoError(t, err)
+	assert.Equal(t, 60, cfg.PViewSize)
+	assert.Equal(t, 7, cfg.AViewMaxSize)
+	assert.Equal(t, DefaultConfig().AViewMinSize, cfg.AViewMinSize)
+	assert.NotNil(t, cfg.LocalTCPAddr)
+
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	_, err = parseConfig(fs, []string{"-config", filepath.Join(dir, "missing.json")})
+	assert.Error(t, err)
+
+	// The environment overrides the file, and the flags the environment.
+	t.Setenv("GOG_CONFIG", path)
+	t.Setenv("GOG_PVIEW_SIZE", "70")
+	t.Setenv("GOG_MAX_AVIEW_SIZE", "8")
+	t.Setenv("GOG_PEERS", "127.0.0.1:8001,127.0.0.1:8002")
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg, err = parseConfig(fs, []string{"-max-aview-size", "6"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, path, cfg.File)
+		assert.Equal(t, 70, cfg.PViewSize)
+		assert.Equal(t, 6, cfg.AViewMaxSize)
+		assert.Equal(t, []string{"127.0.0.1:8001", "127.0.0.1:8002"}, cfg.Peers)
+	}
+
+	// The file is also found from the variable of -config-file.
+	os.Unsetenv("GOG_CONFIG")
+	t.Setenv("GOG_CONFIG_FILE", path)
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg, err = parseConfig(fs, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, path, cfg.File)
+	}
+
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg, err = parseConfig(fs, []string{"-config-file", path})
+	if assert.NoError(t, err) {
+		assert.Equal(t, path, cfg.File)
+	}
+
+	t.Setenv("GOG_PVIEW_SIZE", "many")
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	_, err = parseConfig(fs, nil)
+	assert.Error(t, err)
+}
+
+func TestReloadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "gog.json")
+	if err := ioutil.WriteFile(path, []byte(`{"passive_view": 50, "message_life": 1000}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOG_MAX_AVIEW_SIZE", "8")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Bool("other", false, "A flag of another package")
+	cfg, err := parseConfig(fs, []string{"-pview-size", "60", "-other", "-config", path})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// The file is layered under the environment and the flags again.
+	if err := ioutil.WriteFile(path, []byte(`{"passive_view": 70, "message_life": 2000}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := cfg.ReloadFile(path)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2000, reloaded.MLife)
+		assert.Equal(t, 60, reloaded.PViewSize)
+		assert.Equal(t, 8, reloaded.AViewMaxSize)
+		assert.Equal(t, path, reloaded.File)
+	}
+
+	_, err = cfg.ReloadFile(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestWithTunables(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Peers = []string{"127.0.0.1:8001"}
+	other := DefaultConfig()
+	other.MLife, other.AddrStr, other.Peers = 1000, ":1234", nil
+	c, err := cfg.WithTunables(other)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1000, c.MLife)
+		assert.Equal(t, cfg.AddrStr, c.AddrStr)
+		assert.Equal(t, cfg.Peers, c.Peers)
+	}
+	assert.Equal(t, DefaultConfig().MLife, cfg.MLife)
+}
+
+func TestLoadKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var keys, pubs []byte
+	for i := 0; i < 2; i++ {
+		pub, key, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: b})
+		if b, err = x509.MarshalPKIXPublicKey(pub); err != nil {
+			t.Fatal(err)
+		}
+		pubs = append(pubs, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b})...)
+	}
+	keyFile := filepath.Join(dir, "key.pem")
+	pubFile := filepath.Join(dir, "trusted.pem")
+	if err := ioutil.WriteFile(keyFile, keys, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(pubFile, pubs, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := New(func(cfg *Config) {
+		cfg.SigningKeyFile = keyFile
+		cfg.TrustedKeysFile = pubFile
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, ed25519.PrivateKeySize, len(cfg.SigningKey))
+		assert.Equal(t, 2, len(cfg.TrustedKeys))
+	}
+
+	// The public keys are not a private key, and vice versa.
+	_, err = LoadSigningKey(pubFile)
+	assert.Error(t, err)
+	_, err = LoadTrustedKeys(keyFile)
+	assert.Error(t, err)
+}
+
+func TestLoadNodeID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The id file is created on the first start, and read after.
+	path := filepath.Join(dir, "id")
+	cfg, err := New(WithIdentity(IDFile, path))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NotZero(t, cfg.ID)
+	id, err := LoadNodeID(path)
+	assert.NoError(t, err)
+	assert.Equal(t, cfg.ID, id)
+
+	if err := ioutil.WriteFile(path, []byte("0\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadNodeID(path)
+	assert.Error(t, err)
+	_, err = LoadNodeID(filepath.Join(dir, "missing", "id"))
+	assert.Error(t, err)
+
+	_, err = New(WithIdentity("uuid", ""))
+	assert.Equal(t, ErrInvalidIDStrategy, err)
+}
+
+func TestFixed(t *testing.T) {
+	assert.True(t, Fixed("address"))
+	assert.True(t, Fixed("cluster_secret"))
+	assert.False(t, Fixed("passive_view"))
+	assert.True(t, Tunable("passive_view"))
+	assert.False(t, Fixed("log_level"))
+	assert.False(t, Fixed("foo"))
+	assert.False(t, Tunable("foo"))
+}
+
+func TestUpdate(t *testing.T) {
+	cfg := DefaultConfig()
+	updated, err := cfg.Update([]byte(`{"message_life": 1000, "active_view_max": 8, "codec": "protobuf", "unknown": 1}`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1000, updated.MLife)
+		assert.Equal(t, 8, updated.AViewMaxSize)
+	}
+	// The config itself is not changed.
+	assert.Equal(t, DefaultConfig().MLife, cfg.MLife)
+
+	_, err = cfg.Update([]byte(`{"address": ":8000"}`))
+	assert.True(t, errors.Is(err, ErrNotReloadable))
+	_, err = cfg.Update([]byte(`{"active_view_min": 10}`))
+	assert.Error(t, err)
+	_, err = cfg.Update([]byte(`[]`))
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	for _, c := range []struct {
+		modify func(cfg *Config)
+		err    string
+	}{
+		{func(cfg *Config) {}, ""},
+		{func(cfg *Config) { cfg.AViewMinSize, cfg.AViewMaxSize = 10, 5 }, "AViewMinSize 10 > AViewMaxSize 5"},
+		{func(cfg *Config) { cfg.AViewMinSize, cfg.AViewMaxSize = 0, 0 }, "AViewMaxSize 0 < 1; Ka 1 > AViewMaxSize 0"},
+		{func(cfg *Config) { cfg.PViewSize, cfg.Kp = 0, 0 }, "PViewSize 0 < 1"},
+		{func(cfg *Config) { cfg.Ka = 50 }, "Ka 50 > AViewMaxSize 5"},
+		{func(cfg *Config) { cfg.Kp = 31 }, "Kp 31 > PViewSize 30"},
+		{func(cfg *Config) { cfg.RESTTLSCert = "cert.pem" }, "RESTTLSCert and RESTTLSKey must be set together"},
+		{func(cfg *Config) { cfg.TLSKey = "key.pem" }, "TLSCert and TLSKey must be set together"},
+		{func(cfg *Config) { cfg.TLSRequireClientCert = true }, "TLSCA and TLSRequireClientCert need TLSCert"},
+		{func(cfg *Config) { cfg.IDStrategy = IDFile }, "IDStrategy file needs IDFile"},
+		{func(cfg *Config) { cfg.UDP, cfg.TLSCert, cfg.TLSKey = true, "cert.pem", "key.pem" }, "UDP is not supported over TLS"},
+		{func(cfg *Config) { cfg.ARWL = -1 }, "ARWL -1 < 0"},
+		{func(cfg *Config) { cfg.PRWL = -1 }, "PRWL -1 < 0"},
+		{func(cfg *Config) { cfg.SRWL = -1 }, "SRWL -1 < 0"},
+		{func(cfg *Config) { cfg.MLife = -1 }, "MLife -1 < 0"},
+		{func(cfg *Config) { cfg.ShuffleDuration = -1 }, "ShuffleDuration -1 < 0"},
+		{func(cfg *Config) { cfg.HealDuration = -2 }, "HealDuration -2 < 0"},
+		{func(cfg *Config) { cfg.PurgeDuration = -1 }, "PurgeDuration -1 < 0"},
+		{func(cfg *Config) { cfg.AckRetries = -1 }, "AckRetries -1 < 0"},
+		{func(cfg *Config) { cfg.PingMisses = -1 }, "PingMisses -1 < 1"},
+		{func(cfg *Config) { cfg.PingMisses = 0 }, "PingMisses 0 < 1"},
+		{func(cfg *Config) { cfg.PingInterval, cfg.PingMisses = 0, 0 }, ""},
+		{func(cfg *Config) { cfg.PingTimeout = 5000 }, "PingTimeout 5000ms >= PingInterval 5s"},
+		{func(cfg *Config) { cfg.PingInterval, cfg.PingTimeout = 0, 5000 }, ""},
+		{func(cfg *Config) { cfg.PartitionThreshold = 1.5 }, "PartitionThreshold 1.5 not in [0, 1]"},
+		{func(cfg *Config) { cfg.ConnPoolSize = 16 }, ""},
+		{func(cfg *Config) { cfg.ConnPoolSize, cfg.ConnIdleTimeout = 16, 30 }, "ConnIdleTimeout 30s not in (0, ReadTimeout 30s)"},
+		{func(cfg *Config) { cfg.ConnPoolSize, cfg.ConnIdleTimeout, cfg.ReadTimeout = 16, 0, 0 }, ""},
+		{func(cfg *Config) { cfg.ChunkSize = cfg.MaxMessageSize }, "ChunkSize 10485760 >= MaxMessageSize 10485760"},
+		{func(cfg *Config) { cfg.SpoolFile, cfg.SpoolSize = "spool", 0 }, `SpoolSize 0 < 1 with SpoolFile "spool"`},
+		{func(cfg *Config) { cfg.SpoolSize = 0 }, ""},
+		{func(cfg *Config) { cfg.ChunkSize, cfg.MaxMessageSize = 1<<30, 0 }, ""},
+		{func(cfg *Config) { cfg.ARWL, cfg.MLife, cfg.TLSKey = -1, -2, "key.pem" }, "TLSCert and TLSKey must be set together; ARWL -1 < 0; MLife -2 < 0"},
+	} {
+		cfg := DefaultConfig()
+		c.modify(cfg)
+		err := cfg.Validate()
+		if c.err == "" {
+			assert.NoError(t, err)
+		} else if assert.Error(t, err) {
+			assert.Equal(t, "Invalid config: "+c.err, err.Error())
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	cfg, err := New(
+		WithAddr("127.0.0.1:8000"),
+		WithExtraAddrs("127.0.0.1:8003", "[::1]:8000"),
+		WithPeers("127.0.0.1:8001", "127.0.0.1:8002"),
+		WithViewSizes(2, 4, 20),
+		WithShuffleInterval(10*time.Second),
+		WithHealInterval(1500*time.Millisecond),
+		WithReliable(time.Second, 5),
+		WithPing(10*time.Second, time.Second, 2),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "127.0.0.1:8000", cfg.LocalTCPAddr.String())
+	if assert.Len(t, cfg.ExtraTCPAddrs, 2) {
+		assert.Equal(t, "127.0.0.1:8003", cfg.ExtraTCPAddrs[0].String())
+		assert.Equal(t, "[::1]:8000", cfg.ExtraTCPAddrs[1].String())
+	}
+	assert.Equal(t, []string{"127.0.0.1:8001", "127.0.0.1:8002"}, cfg.Peers)
+	assert.Equal(t, 2, cfg.AViewMinSize)
+	assert.Equal(t, 4, cfg.AViewMaxSize)
+	assert.Equal(t, 20, cfg.PViewSize)
+	assert.Equal(t, 10, cfg.ShuffleDuration)
+	assert.Equal(t, 1, cfg.HealDuration)
+	assert.True(t, cfg.Reliable)
+	assert.Equal(t, 1000, cfg.AckTimeout)
+	assert.Equal(t, 5, cfg.AckRetries)
+	assert.Equal(t, 10, cfg.PingInterval)
+	assert.Equal(t, 1000, cfg.PingTimeout)
+	assert.Equal(t, 2, cfg.PingMisses)
+	// The other fields are the defaults.
+	assert.Equal(t, DefaultConfig().MLife, cfg.MLife)
+
+	_, err = New(WithViewSizes(5, 4, 20))
+	assert.Error(t, err)
+	_, err = New(WithCodec(CodecMsgpack))
+	assert.NoError(t, err)
+	_, err = New(WithCodec("xml"))
+	assert.Equal(t, ErrInvalidCodec, err)
+	_, err = New(WithSendQueue(16, "wait"))
+	assert.Equal(t, ErrInvalidSendQueuePolicy, err)
+
+	// The address is not resolved with another transport.
+	cfg, err = New(WithAddr("foo"), WithTransport(transport.NewMemory()))
+	if assert.NoError(t, err) {
+		assert.Nil(t, cfg.LocalTCPAddr)
+	}
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RandomNodeID returns a random non-zero node id from crypto/rand, of
+// 63 bits as the ids generated by the older agents.
+func RandomNodeID() uint64 {
+	var b [8]byte
+	for {
+		rand.Read(b[:])
+		if id := binary.BigEndian.Uint64(b[:]) &^ (1 << 63); id != 0 {
+			return id
+		}
+	}
+}
+
+// LoadNodeID loads the node id from the file, which has the id in
+// decimal. If the file does not exist, it is created with a random id,
+// so the agent keeps the id across the restarts.
+func LoadNodeID(path string) (uint64, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		id := RandomNodeID()
+		if err := writeNodeID(path, id); err != nil {
+			return 0, fmt.Errorf("Cannot write node id: %v", err)
+		}
+		return id, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("Cannot read node id: %v", err)
+	}
+	id, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("Cannot parse node id %s", path)
+	}
+	return id, nil
+}
+
+// writeNodeID() writes the id to a temporary file, and renames it
+// to the path, so a crash does not leave a partial id behind.
+func writeNodeID(path string, id uint64) error {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := ioutil.TempFile(dir, base+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(strconv.FormatUint(id, 10) + "\n"); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
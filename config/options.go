@@ -0,0 +1,292 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"math/rand"
+	"time"
+
+	"github.com/lilymona/gog/logging"
+	"github.com/lilymona/gog/transport"
+)
+
+// Option sets an option of the configuration created by New.
+type Option func(cfg *Config)
+
+// New creates a configuration from the default configuration and
+// the options, without parsing the command line, so the agent can be
+// embedded in the programs that have their own flags.
+func New(opts ...Option) (*Config, error) {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if err := cfg.check(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// WithAddr sets the listen address of the agent.
+func WithAddr(addr string) Option {
+	return func(cfg *Config) { cfg.AddrStr = addr }
+}
+
+// WithExtraAddrs sets the additional addresses the agent listens on.
+func WithExtraAddrs(addrs ...string) Option {
+	return func(cfg *Config) { cfg.ExtraAddrs = addrs }
+}
+
+// WithAdvertiseAddr sets the address advertised to the peers.
+func WithAdvertiseAddr(addr string) Option {
+	return func(cfg *Config) { cfg.AdvertiseAddr = addr }
+}
+
+// WithPeers sets the peers to join.
+func WithPeers(peers ...string) Option {
+	return func(cfg *Config) { cfg.Peers = peers }
+}
+
+// WithViewSizes sets the minimum and the maximum sizes of the
+// active view, and the size of the passive view.
+func WithViewSizes(activeMin, activeMax, passive int) Option {
+	return func(cfg *Config) {
+		cfg.AViewMinSize, cfg.AViewMaxSize, cfg.PViewSize = activeMin, activeMax, passive
+	}
+}
+
+// WithShuffleInterval sets the interval of the shuffles,
+// which is rounded down to seconds.
+func WithShuffleInterval(d time.Duration) Option {
+	return func(cfg *Config) { cfg.ShuffleDuration = int(d / time.Second) }
+}
+
+// WithHealInterval sets the interval to heal the active view,
+// which is rounded down to seconds.
+func WithHealInterval(d time.Duration) Option {
+	return func(cfg *Config) { cfg.HealDuration = int(d / time.Second) }
+}
+
+// WithMessageLife sets the time for which the user messages live,
+// which is rounded down to milliseconds.
+func WithMessageLife(d time.Duration) Option {
+	return func(cfg *Config) { cfg.MLife = int(d / time.Millisecond) }
+}
+
+// WithMessageCache sets the time for which the received messages are
+// remembered to drop the duplicates, which is rounded down to milliseconds,
+// and the maximum number of them, 0 for unbounded.
+func WithMessageCache(purge time.Duration, size int) Option {
+	return func(cfg *Config) {
+		cfg.PurgeDuration = int(purge / time.Millisecond)
+		cfg.MessageCacheSize = size
+	}
+}
+
+// WithReplayWindow sets the number of the latest sequence numbers of
+// each sender tracked to drop the replayed messages, 0 to disable.
+func WithReplayWindow(n int) Option {
+	return func(cfg *Config) { cfg.ReplayWindow = n }
+}
+
+// WithFIFO makes the agent deliver the messages of each sender in the
+// order they are sent, buffering up to window messages of a sender, and
+// skipping the missing ones after the gap timeout (milliseconds).
+func WithFIFO(window, gapTimeout int) Option {
+	return func(cfg *Config) {
+		cfg.FIFOWindow = window
+		cfg.FIFOGapTimeout = gapTimeout
+	}
+}
+
+// WithIdentity sets how the agent gets its node id, and
+// the file of the id with the file strategy.
+func WithIdentity(strategy, file string) Option {
+	return func(cfg *Config) {
+		cfg.IDStrategy = strategy
+		cfg.IDFile = file
+	}
+}
+
+// WithLabels sets the labels advertised to the peers.
+func WithLabels(labels map[string]string) Option {
+	return func(cfg *Config) { cfg.Labels = labels }
+}
+
+// WithRESTAuth requires the bearer token on the REST API,
+// and on the health checks too if probes is true.
+func WithRESTAuth(token string, probes bool) Option {
+	return func(cfg *Config) {
+		cfg.RESTAuthToken = token
+		cfg.RESTAuthProbes = probes
+	}
+}
+
+// WithCORS allows the web pages of the origins, "*" for any,
+// to call the REST API.
+func WithCORS(origins ...string) Option {
+	return func(cfg *Config) { cfg.RESTCORSOrigins = origins }
+}
+
+// WithDebug serves the debug endpoints on the REST server,
+// or on the address if it is not empty.
+func WithDebug(addr string) Option {
+	return func(cfg *Config) {
+		cfg.Debug = true
+		cfg.DebugAddrStr = addr
+	}
+}
+
+// WithCodec sets the codec of the messages, protobuf, json or msgpack.
+func WithCodec(name string) Option {
+	return func(cfg *Config) { cfg.Codec = name }
+}
+
+// WithPlumtree makes the user messages be pushed along
+// the broadcast trees instead of being flooded.
+func WithPlumtree() Option {
+	return func(cfg *Config) { cfg.Plumtree = true }
+}
+
+// WithUDP makes the agent send the shuffles and
+// the pings over UDP to the peers that read them.
+func WithUDP() Option {
+	return func(cfg *Config) { cfg.UDP = true }
+}
+
+// WithReliable makes the neighbors acknowledge the user messages, which
+// are sent again after the timeout (doubled on each retry) up to the retries.
+func WithReliable(timeout time.Duration, retries int) Option {
+	return func(cfg *Config) {
+		cfg.Reliable = true
+		cfg.AckTimeout = int(timeout / time.Millisecond)
+		cfg.AckRetries = retries
+	}
+}
+
+// WithPing makes the neighbors be pinged at the interval, which is rounded
+// down to seconds, and removed after missing the pongs in the timeout
+// the number of times in a row. A zero interval disables the pings.
+func WithPing(interval, timeout time.Duration, misses int) Option {
+	return func(cfg *Config) {
+		cfg.PingInterval = int(interval / time.Second)
+		cfg.PingTimeout = int(timeout / time.Millisecond)
+		cfg.PingMisses = misses
+	}
+}
+
+// WithPartitionDetection makes the neighbors exchange the digests of the
+// nodes they reach at the interval, which is rounded down to seconds, 0 to
+// disable, and a partition be suspected when the nodes reached are fewer
+// than the threshold of the most reached in the history.
+func WithPartitionDetection(interval time.Duration, threshold float64, history time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.PartitionInterval = int(interval / time.Second)
+		cfg.PartitionThreshold = threshold
+		cfg.PartitionHistory = int(history / time.Second)
+	}
+}
+
+// WithSendQueue sets the size of the queue of the user messages to each
+// neighbor, 0 for unbounded, and the policy when it is full, drop or block.
+func WithSendQueue(size int, policy string) Option {
+	return func(cfg *Config) {
+		cfg.SendQueueSize = size
+		cfg.SendQueuePolicy = policy
+	}
+}
+
+// WithSendRate limits the rates of the outbound user messages, in bytes
+// and in messages per second, to all the neighbors and to each neighbor,
+// 0 for unlimited.
+func WithSendRate(bytes, msgs, peerBytes, peerMsgs float64) Option {
+	return func(cfg *Config) {
+		cfg.SendByteRate = bytes
+		cfg.SendMessageRate = msgs
+		cfg.PeerSendByteRate = peerBytes
+		cfg.PeerSendMessageRate = peerMsgs
+	}
+}
+
+// WithBatching makes the user messages to each neighbor be written in
+// batches of up to size messages, waiting for the interval, which is
+// rounded down to milliseconds, for more messages to batch.
+func WithBatching(size int, interval time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.BatchSize = size
+		cfg.BatchInterval = int(interval / time.Millisecond)
+	}
+}
+
+// WithChunking makes the payloads larger than size bytes be broadcast in
+// chunks of size bytes, 0 to disable, which the receivers reassemble for
+// the timeout, rounded down to milliseconds, in a buffer of up to buffer
+// bytes.
+func WithChunking(size int, timeout time.Duration, buffer int) Option {
+	return func(cfg *Config) {
+		cfg.ChunkSize = size
+		cfg.ChunkTimeout = int(timeout / time.Millisecond)
+		cfg.ChunkBufferSize = buffer
+	}
+}
+
+// WithJoinRetry sets the retries of joining the peers, and the backoff,
+// rounded down to milliseconds, before the first retry, which doubles on
+// each retry up to max. After losing all the peers, the agent gives up
+// after the number of failed rejoins, 0 to never give up.
+func WithJoinRetry(retries int, backoff, max time.Duration, rejoins int) Option {
+	return func(cfg *Config) {
+		cfg.JoinRetries = retries
+		cfg.JoinBackoff = int(backoff / time.Millisecond)
+		cfg.JoinMaxBackoff = int(max / time.Millisecond)
+		cfg.RejoinAttempts = rejoins
+	}
+}
+
+// WithClusterSecret sets the shared secret that the agents
+// authenticate the joining agents with.
+func WithClusterSecret(secret string) Option {
+	return func(cfg *Config) { cfg.ClusterSecret = secret }
+}
+
+// WithClusterName sets the name of the cluster,
+// so the agents of other clusters are rejected.
+func WithClusterName(name string) Option {
+	return func(cfg *Config) { cfg.ClusterName = name }
+}
+
+// WithSpool persists the failed messages to the file of at most size
+// bytes, so they are resent after restart.
+func WithSpool(file string, size int) Option {
+	return func(cfg *Config) {
+		cfg.SpoolFile = file
+		cfg.SpoolSize = size
+	}
+}
+
+// WithSigning makes the agent sign its user messages with the key, and
+// verify the signatures of the senders of the trusted keys. If required,
+// the user messages not signed with a trusted key are dropped.
+func WithSigning(key ed25519.PrivateKey, trusted []ed25519.PublicKey, required bool) Option {
+	return func(cfg *Config) {
+		cfg.SigningKey = key
+		cfg.TrustedKeys = trusted
+		cfg.RequireSigned = required
+	}
+}
+
+// WithTransport sets the transport that connects the agents,
+// e.g. an in-memory transport in tests.
+func WithTransport(t transport.Transport) Option {
+	return func(cfg *Config) { cfg.Transport = t }
+}
+
+// WithRand sets the source of the random choices of the agent,
+// e.g. a seeded source in simulations.
+func WithRand(src rand.Source) Option {
+	return func(cfg *Config) { cfg.Rand = src }
+}
+
+// WithLogger sets the logger of the agent, the codec and the REST server.
+func WithLogger(l logging.Logger) Option {
+	return func(cfg *Config) { cfg.Logger = l }
+}
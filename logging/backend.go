@@ -0,0 +1,176 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sync"
+)
+
+// Fields are the structured fields of the logs, e.g. the id of the agent.
+type Fields map[string]interface{}
+
+// Logger is the interface of the logs of the agent, the codec and the
+// REST server. The package provides a text and a JSON backend, and the
+// programs embedding the agent can plug their own, e.g. an adapter of
+// zap or logrus.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warningf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	// WithFields returns a logger that adds the fields to the logs.
+	WithFields(fields Fields) Logger
+}
+
+// std is the default logger, which writes with
+// the level, the output and the format of the package.
+type std struct {
+	fields Fields
+}
+
+// Default returns the default logger, which follows SetLevel,
+// SetOutput and SetJSON.
+func Default() Logger {
+	return std{}
+}
+
+func (l std) Debugf(format string, args ...interface{}) {
+	if enabled(LevelDebug) {
+		printf("DEBUG", l.fields, format, args...)
+	}
+}
+
+func (l std) Infof(format string, args ...interface{}) {
+	if enabled(LevelInfo) {
+		printf("INFO", l.fields, format, args...)
+	}
+}
+
+func (l std) Warningf(format string, args ...interface{}) {
+	if enabled(LevelWarning) {
+		printf("WARNING", l.fields, format, args...)
+	}
+}
+
+func (l std) Errorf(format string, args ...interface{}) {
+	if enabled(LevelError) {
+		printf("ERROR", l.fields, format, args...)
+	}
+}
+
+func (l std) WithFields(fields Fields) Logger {
+	return std{mergeFields(l.fields, fields)}
+}
+
+// writer is the text or the JSON backend writing to an io.Writer.
+type writer struct {
+	*sink
+	fields Fields
+}
+
+// sink is the writer shared by a backend and its WithFields loggers.
+type sink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	logger *log.Logger
+	level  int
+	json   bool
+}
+
+// NewText returns a backend writing the logs up
+// to the level in text to w.
+func NewText(w io.Writer, level int) Logger {
+	return writer{sink: &sink{w: w, logger: log.New(w, "", log.LstdFlags), level: level}}
+}
+
+// NewJSON returns a backend writing the logs up
+// to the level in JSON to w, one object per line.
+func NewJSON(w io.Writer, level int) Logger {
+	return writer{sink: &sink{w: w, level: level, json: true}}
+}
+
+func (l writer) Debugf(format string, args ...interface{}) {
+	l.printf(LevelDebug, "DEBUG", format, args...)
+}
+
+func (l writer) Infof(format string, args ...interface{}) {
+	l.printf(LevelInfo, "INFO", format, args...)
+}
+
+func (l writer) Warningf(format string, args ...interface{}) {
+	l.printf(LevelWarning, "WARNING", format, args...)
+}
+
+func (l writer) Errorf(format string, args ...interface{}) {
+	l.printf(LevelError, "ERROR", format, args...)
+}
+
+func (l writer) WithFields(fields Fields) Logger {
+	return writer{l.sink, mergeFields(l.fields, fields)}
+}
+
+func (l writer) printf(level int, name string, format string, args ...interface{}) {
+	if l.level < level {
+		return
+	}
+	code := caller()
+	msg := fmt.Sprintf(format, args...)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.json {
+		if b := formatJSON(name, code, msg, l.fields); b != nil {
+			l.w.Write(b)
+		}
+		return
+	}
+	l.logger.Print(formatText(name, code, msg, l.fields))
+}
+
+// sampled is a logger whose errors, warnings
+// and infos are sampled per call site.
+type sampled struct {
+	Logger
+}
+
+// Sampled returns a logger that samples the errors, the warnings and the
+// infos of each call site like SampledErrorf, e.g. for the logs of the
+// messages from the peers. The debug logs are not sampled.
+func Sampled(l Logger) Logger {
+	return sampled{l}
+}
+
+func (l sampled) Infof(format string, args ...interface{}) {
+	if sample() {
+		l.Logger.Infof(format, args...)
+	}
+}
+
+func (l sampled) Warningf(format string, args ...interface{}) {
+	if sample() {
+		l.Logger.Warningf(format, args...)
+	}
+}
+
+func (l sampled) Errorf(format string, args ...interface{}) {
+	if sample() {
+		l.Logger.Errorf(format, args...)
+	}
+}
+
+func (l sampled) WithFields(fields Fields) Logger {
+	return sampled{l.Logger.WithFields(fields)}
+}
+
+// mergeFields() returns a copy of the fields with the new ones.
+func mergeFields(fields, more Fields) Fields {
+	merged := make(Fields, len(fields)+len(more))
+	for k, v := range fields {
+		merged[k] = v
+	}
+	for k, v := range more {
+		merged[k] = v
+	}
+	return merged
+}
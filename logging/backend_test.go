@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lilymona/testify/assert"
+)
+
+func TestText(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewText(&buf, LevelInfo)
+
+	l.Debugf("foo\n")
+	assert.Empty(t, buf.String())
+
+	l.WithFields(Fields{"b": 2, "a": 1}).Infof("foo %d\n", 42)
+	assert.Contains(t, buf.String(), "[INFO]")
+	assert.Contains(t, buf.String(), "logging.TestText")
+	assert.True(t, strings.HasSuffix(buf.String(), "foo 42 a=1 b=2\n"))
+
+	// The fields are not added to the parent logger.
+	buf.Reset()
+	l.Errorf("bar\n")
+	assert.True(t, strings.HasSuffix(buf.String(), "bar\n"))
+	assert.NotContains(t, buf.String(), "a=1")
+}
+
+func TestJSONBackend(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewJSON(&buf, LevelDebug).WithFields(Fields{"agent": 42})
+
+	l.Warningf("foo %s\n", "bar")
+	var e map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &e))
+	assert.Equal(t, "WARNING", e["level"])
+	assert.Equal(t, "foo bar", e["message"])
+	assert.Contains(t, e["caller"], "logging.TestJSONBackend")
+	assert.Equal(t, map[string]interface{}{"agent": float64(42)}, e["fields"])
+}
+
+func TestDefaultFields(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(nil)
+
+	Default().WithFields(Fields{"agent": 42}).Errorf("foo\n")
+	assert.Contains(t, buf.String(), "[ERROR]")
+	assert.True(t, strings.HasSuffix(buf.String(), "foo agent=42\n"))
+}
+
+func TestSampled(t *testing.T) {
+	var buf bytes.Buffer
+	l := Sampled(NewText(&buf, LevelDebug))
+
+	clock := time.Unix(0, 0)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	SetSampling(10, 5)
+	defer SetSampling(0, 0)
+
+	for i := 0; i < 100; i++ {
+		l.Errorf("foo\n")
+	}
+	assert.Equal(t, 5, strings.Count(buf.String(), "foo"))
+
+	// The debug logs are not sampled.
+	buf.Reset()
+	for i := 0; i < 100; i++ {
+		l.Debugf("bar\n")
+	}
+	assert.Equal(t, 100, strings.Count(buf.String(), "bar"))
+}
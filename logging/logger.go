@@ -1,39 +1,136 @@
 package logging
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/huandu/goroutine"
 )
 
 const (
-	verboseError = iota
-	verboseWarning
-	verboseInfo
-	verboseDebug
+	LevelError = iota
+	LevelWarning
+	LevelInfo
+	LevelDebug
+)
+
+var (
+	// mu guards the verboseness, the output and the format.
+	mu      sync.RWMutex
+	verbose = LevelDebug
+	// output is the writer of the logs, and logger formats the text
+	// logs to it. Both are nil for the standard logger.
+	output   io.Writer
+	logger   *log.Logger
+	jsonMode bool
 )
 
-var verbose int
 var pid = os.Getpid()
 
-func init() {
-	flag.IntVar(&verbose, "v", verboseDebug, "The log veboseness")
+var ErrInvalidLevel = errors.New("Invalid log level")
+
+// levelNames are the names of the levels.
+var levelNames = []string{"error", "warning", "info", "debug"}
+
+// RegisterFlags registers the flags of the logs, e.g. on the
+// flag.CommandLine of the gog binary, and OpenFlags opens the output
+// they select once they are parsed. The programs embedding the agent
+// can set the logs with the functions instead.
+func RegisterFlags(fs *flag.FlagSet) {
+	fs.IntVar(&verbose, "v", verbose, "The log veboseness")
+	fs.BoolVar(&jsonMode, "log-json", jsonMode, "Log in JSON format")
+	fs.Float64Var(&sampleRate, "log-sample-rate", sampleRate, "The rate of the sampled logs per call site (per second), 0 to log all")
+	fs.IntVar(&sampleBurst, "log-sample-burst", sampleBurst, "The burst of the sampled logs per call site")
+	fs.StringVar(&flagOutput.Output, "log-output", flagOutput.Output, "The output of the logs: stderr, file, syslog or journald")
+	fs.StringVar(&flagOutput.File, "log-file", flagOutput.File, "The path of the log file, with -log-output=file")
+	fs.IntVar(&flagOutput.MaxSize, "log-file-max-size", flagOutput.MaxSize, "The size of the log file to rotate it (megabytes), 0 to never rotate")
+	fs.IntVar(&flagOutput.MaxAge, "log-file-max-age", flagOutput.MaxAge, "The age of the rotated log files to remove them (days), 0 to keep them")
+	fs.IntVar(&flagOutput.MaxBackups, "log-file-max-backups", flagOutput.MaxBackups, "The number of the rotated log files to keep, 0 to keep them all")
+}
+
+// SetLevel sets the log verboseness, from LevelError to LevelDebug.
+func SetLevel(level int) {
+	mu.Lock()
+	defer mu.Unlock()
+	verbose = level
+}
+
+// GetLevel returns the log verboseness.
+func GetLevel() int {
+	mu.RLock()
+	defer mu.RUnlock()
+	return verbose
+}
+
+// ParseLevel parses the name or the numeric constant of a level.
+func ParseLevel(s string) (int, error) {
+	for level, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return level, nil
+		}
+	}
+	level, err := strconv.Atoi(s)
+	if err != nil || level < LevelError || level > LevelDebug {
+		return 0, ErrInvalidLevel
+	}
+	return level, nil
+}
+
+// LevelName returns the name of the level.
+func LevelName(level int) string {
+	if level < LevelError || level > LevelDebug {
+		return strconv.Itoa(level)
+	}
+	return levelNames[level]
+}
+
+// SetOutput sets the writer of the logs. Nil restores the standard logger.
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	output = w
+	if w == nil {
+		logger = nil
+	} else {
+		logger = log.New(w, "", log.LstdFlags)
+	}
+}
+
+// SetJSON enables or disables the JSON format of the logs.
+func SetJSON(enabled bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	jsonMode = enabled
+}
+
+// enabled() returns true if the logs of the level are written.
+func enabled(level int) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return verbose >= level
 }
 
 func Errorf(format string, args ...interface{}) {
-	if verbose < verboseError {
+	if !enabled(LevelError) {
 		return
 	}
 	Printf("ERROR", format, args...)
 }
 
 func Fatalf(format string, args ...interface{}) {
-	if verbose < verboseError {
+	if !enabled(LevelError) {
 		return
 	}
 	Printf("FATAL", format, args...)
@@ -41,32 +138,129 @@ func Fatalf(format string, args ...interface{}) {
 }
 
 func Warningf(format string, args ...interface{}) {
-	if verbose < verboseWarning {
+	if !enabled(LevelWarning) {
 		return
 	}
 	Printf("WARNING", format, args...)
 }
 
 func Infof(format string, args ...interface{}) {
-	if verbose < verboseInfo {
+	if !enabled(LevelInfo) {
 		return
 	}
 	Printf("INFO", format, args...)
 }
 
 func Debugf(format string, args ...interface{}) {
-	if verbose < verboseDebug {
+	if !enabled(LevelDebug) {
 		return
 	}
 	Printf("DEBUG", format, args...)
 }
 
+// entry is a log in JSON format.
+type entry struct {
+	Time      string `json:"time"`
+	Level     string `json:"level"`
+	Pid       int    `json:"pid"`
+	Goroutine int64  `json:"goroutine"`
+	Caller    string `json:"caller"`
+	Message   string `json:"message"`
+	Fields    Fields `json:"fields,omitempty"`
+}
+
 func Printf(level string, format string, args ...interface{}) {
-	var code string
-	// source code, function and line num
-	pc, _, line, ok := runtime.Caller(2)
-	if ok {
-		code = runtime.FuncForPC(pc).Name() + ":" + strconv.Itoa(line)
+	printf(level, nil, format, args...)
+}
+
+// printf() writes the log with the fields to the output of the package.
+func printf(level string, fields Fields, format string, args ...interface{}) {
+	code := caller()
+	msg := fmt.Sprintf(format, args...)
+
+	// Hold the lock while writing, so the lines are not interleaved.
+	mu.Lock()
+	defer mu.Unlock()
+	if jsonMode {
+		b := formatJSON(level, code, msg, fields)
+		if b == nil {
+			return
+		}
+		if lw, ok := output.(levelWriter); ok {
+			lw.writeLevel(level, b)
+		} else if output == nil {
+			log.Writer().Write(b)
+		} else {
+			output.Write(b)
+		}
+		return
+	}
+	text := formatText(level, code, msg, fields)
+	if lw, ok := output.(levelWriter); ok {
+		lw.writeLevel(level, []byte(text))
+	} else if logger == nil {
+		log.Print(text)
+	} else {
+		logger.Print(text)
+	}
+}
+
+// formatText() formats the log in text, with the fields sorted
+// by key at the end of the line.
+func formatText(level, code, msg string, fields Fields) string {
+	text := fmt.Sprintf("[%s] #%d.%d %s %s", level, pid, goroutine.GoroutineId(), code, msg)
+	if len(fields) == 0 {
+		return text
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	text = strings.TrimSuffix(text, "\n")
+	for _, k := range keys {
+		text += fmt.Sprintf(" %s=%v", k, fields[k])
+	}
+	return text + "\n"
+}
+
+// formatJSON() formats the log in JSON, or returns nil
+// if the fields cannot be marshaled.
+func formatJSON(level, code, msg string, fields Fields) []byte {
+	b, err := json.Marshal(&entry{
+		Time:      time.Now().Format(time.RFC3339Nano),
+		Level:     level,
+		Pid:       pid,
+		Goroutine: goroutine.GoroutineId(),
+		Caller:    code,
+		Message:   strings.TrimSuffix(msg, "\n"),
+		Fields:    fields,
+	})
+	if err != nil {
+		return nil
+	}
+	return append(b, '\n')
+}
+
+// dir is the directory of the package, whose frames are skipped
+// by caller().
+var dir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// caller() returns the function and the line of the first caller
+// out of the package, so the loggers can be wrapped.
+func caller() string {
+	var pcs [16]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	for {
+		frame, more := frames.Next()
+		if filepath.Dir(frame.File) != dir || strings.HasSuffix(frame.File, "_test.go") {
+			return frame.Function + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
 	}
-	log.Printf("[%s] #%d.%d %s %s", level, pid, goroutine.GoroutineId(), code, fmt.Sprintf(format, args...))
 }
@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lilymona/testify/assert"
+)
+
+func TestSetOutput(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(nil)
+
+	Errorf("foo %d\n", 42)
+	assert.Contains(t, buf.String(), "[ERROR]")
+	assert.Contains(t, buf.String(), "foo 42")
+	assert.True(t, strings.HasSuffix(buf.String(), "foo 42\n"))
+}
+
+func TestSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(nil)
+	SetLevel(LevelInfo)
+	defer SetLevel(LevelDebug)
+
+	Debugf("foo\n")
+	assert.Empty(t, buf.String())
+	Infof("bar\n")
+	assert.Contains(t, buf.String(), "bar")
+
+	buf.Reset()
+	SetLevel(LevelError)
+	Warningf("foo\n")
+	assert.Empty(t, buf.String())
+}
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(nil)
+	SetJSON(true)
+	defer SetJSON(false)
+
+	Warningf("foo %s\n", "bar")
+	var e map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &e))
+	assert.Equal(t, "WARNING", e["level"])
+	assert.Equal(t, float64(os.Getpid()), e["pid"])
+	assert.NotZero(t, e["goroutine"])
+	assert.Contains(t, e["caller"], "logging.TestJSON")
+	assert.Equal(t, "foo bar", e["message"])
+	assert.NotEmpty(t, e["time"])
+}
+
+func TestRegisterFlags(t *testing.T) {
+	// The flags are not registered on the command line by the package.
+	assert.Nil(t, flag.Lookup("v"))
+
+	fs := flag.NewFlagSet("gog", flag.ContinueOnError)
+	RegisterFlags(fs)
+	defer SetLevel(GetLevel())
+	assert.NoError(t, fs.Parse([]string{"-v", "1"}))
+	assert.Equal(t, LevelWarning, GetLevel())
+}
@@ -0,0 +1,268 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The outputs of the logs.
+const (
+	OutputStderr   = "stderr"
+	OutputFile     = "file"
+	OutputSyslog   = "syslog"
+	OutputJournald = "journald"
+)
+
+var (
+	ErrInvalidOutput     = errors.New("Invalid log output")
+	ErrNoLogFile         = errors.New("No log file")
+	ErrUnsupportedOutput = errors.New("Log output not supported on this platform")
+)
+
+// OutputConfig selects the output of the logs.
+type OutputConfig struct {
+	// Output is stderr, file, syslog or journald.
+	Output string
+	// File is the path of the log file, which is rotated when it would
+	// exceed MaxSize megabytes, 0 to never rotate. The rotated files
+	// are removed after MaxAge days, and the oldest ones over
+	// MaxBackups, 0 to keep them.
+	File       string
+	MaxSize    int
+	MaxAge     int
+	MaxBackups int
+}
+
+// flagOutput is the output selected by the flags.
+var flagOutput = OutputConfig{Output: OutputStderr, MaxSize: 100}
+
+// closer is the output opened by Open, which is closed
+// when the output is changed.
+var closer io.Closer
+
+// Open opens the output, and sets it as the output of the logs.
+func Open(cfg OutputConfig) error {
+	var w io.WriteCloser
+	var err error
+	switch cfg.Output {
+	case "", OutputStderr:
+	case OutputFile:
+		if cfg.File == "" {
+			return ErrNoLogFile
+		}
+		w, err = NewRotatingFile(cfg.File, int64(cfg.MaxSize)<<20,
+			time.Duration(cfg.MaxAge)*24*time.Hour, cfg.MaxBackups)
+	case OutputSyslog:
+		w, err = newSyslog()
+	case OutputJournald:
+		w, err = newJournal()
+	default:
+		return ErrInvalidOutput
+	}
+	if err != nil {
+		return err
+	}
+	if w == nil {
+		SetOutput(nil)
+	} else {
+		SetOutput(w)
+	}
+
+	mu.Lock()
+	prev := closer
+	closer = w
+	mu.Unlock()
+	if prev != nil {
+		prev.Close()
+	}
+	return nil
+}
+
+// OpenFlags opens the output selected by the flags registered
+// by RegisterFlags, after they are parsed.
+func OpenFlags() error {
+	return Open(flagOutput)
+}
+
+// levelWriter is an output which records the level of each log on its
+// own, e.g. syslog and journald. The text logs are written to it with
+// no timestamp, as it adds its own.
+type levelWriter interface {
+	writeLevel(level string, b []byte) error
+}
+
+// RotatingFile is a log file which is renamed with the time of the
+// rotation, and started again, when it would exceed the max size.
+type RotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	f          *os.File
+	size       int64
+}
+
+// backupTimeFormat is the format of the time in the
+// names of the rotated files, which sorts by time.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// NewRotatingFile opens the log file for appending. It is rotated when it
+// would exceed maxSize bytes, 0 to never rotate, and the rotated files are
+// removed after maxAge, and the oldest ones over maxBackups, 0 to keep them.
+func NewRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*RotatingFile, error) {
+	rf := &RotatingFile{path: path, maxSize: maxSize, maxAge: maxAge, maxBackups: maxBackups}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// open() opens the file for appending.
+func (rf *RotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.f, rf.size = f, fi.Size()
+	return nil
+}
+
+// Write writes the log to the file, after rotating it if it would
+// exceed the max size. A log larger than the max size is written to
+// a file of its own.
+func (rf *RotatingFile) Write(b []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		return 0, os.ErrClosed
+	}
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(b)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.f.Write(b)
+	rf.size += int64(n)
+	return n, err
+}
+
+// Close closes the file.
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		return nil
+	}
+	err := rf.f.Close()
+	rf.f = nil
+	return err
+}
+
+// rotate() renames the file with the time, opens a new one,
+// and removes the rotated files that are too old or too many.
+func (rf *RotatingFile) rotate() error {
+	if err := rf.f.Close(); err != nil {
+		return err
+	}
+	rf.f = nil
+	backup := rf.path + "." + time.Now().Format(backupTimeFormat)
+	if err := os.Rename(rf.path, backup); err != nil {
+		return err
+	}
+	if err := rf.open(); err != nil {
+		return err
+	}
+	rf.prune()
+	return nil
+}
+
+// prune() removes the rotated files older than the max age,
+// and the oldest ones over the max backups.
+func (rf *RotatingFile) prune() {
+	if rf.maxAge <= 0 && rf.maxBackups <= 0 {
+		return
+	}
+	matches, err := filepath.Glob(rf.path + ".*")
+	if err != nil {
+		return
+	}
+	// The names sort by the time of the rotation, the newest first.
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	now := time.Now()
+	i := 0
+	for _, m := range matches {
+		t, err := time.ParseInLocation(backupTimeFormat, strings.TrimPrefix(m, rf.path+"."), time.Local)
+		if err != nil {
+			continue
+		}
+		if (rf.maxBackups > 0 && i >= rf.maxBackups) || (rf.maxAge > 0 && now.Sub(t) > rf.maxAge) {
+			os.Remove(m)
+		}
+		i++
+	}
+}
+
+// journalSocket is the socket of journald, replaced in tests.
+var journalSocket = "/run/systemd/journal/socket"
+
+// journal writes the logs to journald in its native protocol.
+type journal struct {
+	conn  net.Conn
+	ident string
+}
+
+func newJournal() (*journal, error) {
+	conn, err := net.Dial("unixgram", journalSocket)
+	if err != nil {
+		return nil, err
+	}
+	return &journal{conn: conn, ident: filepath.Base(os.Args[0])}, nil
+}
+
+// journalPriorities are the syslog priorities of the levels.
+var journalPriorities = map[string]int{
+	"FATAL":   2,
+	"ERROR":   3,
+	"WARNING": 4,
+	"INFO":    6,
+	"DEBUG":   7,
+}
+
+// writeLevel() sends the log in a datagram. The message is in the binary
+// form of the protocol, as it might have newlines.
+func (j *journal) writeLevel(level string, b []byte) error {
+	b = bytes.TrimSuffix(b, []byte("\n"))
+	msg := []byte(fmt.Sprintf("PRIORITY=%d\nSYSLOG_IDENTIFIER=%s\nMESSAGE\n", journalPriorities[level], j.ident))
+	msg = binary.LittleEndian.AppendUint64(msg, uint64(len(b)))
+	msg = append(append(msg, b...), '\n')
+	_, err := j.conn.Write(msg)
+	return err
+}
+
+// Write writes the log at the info priority.
+func (j *journal) Write(b []byte) (int, error) {
+	if err := j.writeLevel("INFO", b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (j *journal) Close() error {
+	return j.conn.Close()
+}
@@ -0,0 +1,107 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lilymona/testify/assert"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gog.log")
+	rf, err := NewRotatingFile(path, 10, 0, 2)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer rf.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := rf.Write([]byte("12345678\n"))
+		assert.NoError(t, err)
+		// The rotated files are named by the milliseconds.
+		time.Sleep(2 * time.Millisecond)
+	}
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "12345678\n", string(b))
+	backups, _ := filepath.Glob(path + ".*")
+	assert.Len(t, backups, 2)
+
+	// The rotated files older than the max age are removed.
+	rf.maxAge = 50 * time.Millisecond
+	time.Sleep(60 * time.Millisecond)
+	rf.Write([]byte("12345678\n"))
+	backups, _ = filepath.Glob(path + ".*")
+	assert.Len(t, backups, 1)
+
+	// The file is appended to when it is opened again.
+	assert.NoError(t, rf.Close())
+	_, err = rf.Write([]byte("foo\n"))
+	assert.Equal(t, os.ErrClosed, err)
+	rf, err = NewRotatingFile(path, 0, 0, 0)
+	if assert.NoError(t, err) {
+		rf.Write([]byte(strings.Repeat("x", 100)))
+		rf.Close()
+	}
+	fi, err := os.Stat(path)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(109), fi.Size())
+	}
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gog.log")
+	fs := flag.NewFlagSet("gog", flag.ContinueOnError)
+	RegisterFlags(fs)
+	defer func() { flagOutput = OutputConfig{Output: OutputStderr, MaxSize: 100} }()
+	assert.NoError(t, fs.Parse([]string{"-log-output", "file", "-log-file", path, "-log-file-max-size", "1"}))
+	assert.NoError(t, OpenFlags())
+	defer Open(OutputConfig{})
+
+	Errorf("foo %d\n", 42)
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "[ERROR]")
+	assert.True(t, strings.HasSuffix(string(b), "foo 42\n"))
+
+	assert.Equal(t, ErrNoLogFile, Open(OutputConfig{Output: OutputFile}))
+	assert.Equal(t, ErrInvalidOutput, Open(OutputConfig{Output: "foo"}))
+}
+
+func TestJournal(t *testing.T) {
+	journalSocket = filepath.Join(t.TempDir(), "journal.sock")
+	defer func() { journalSocket = "/run/systemd/journal/socket" }()
+	conn, err := net.ListenPacket("unixgram", journalSocket)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	assert.NoError(t, Open(OutputConfig{Output: OutputJournald}))
+	defer Open(OutputConfig{})
+	Warningf("foo\nbar\n")
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if !assert.NoError(t, err) {
+		return
+	}
+	b := buf[:n]
+	assert.True(t, bytes.HasPrefix(b, []byte("PRIORITY=4\n")))
+	i := bytes.Index(b, []byte("MESSAGE\n")) + len("MESSAGE\n")
+	if !assert.True(t, i > len("MESSAGE\n")) {
+		return
+	}
+	size := binary.LittleEndian.Uint64(b[i:])
+	msg := string(b[i+8 : i+8+int(size)])
+	assert.Contains(t, msg, "[WARNING]")
+	assert.True(t, strings.HasSuffix(msg, "foo\nbar"))
+}
@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+var (
+	// The sustained rate (messages per second) and burst of the
+	// sampled logs of each call site. Zero rate disables the sampling.
+	sampleRate  float64
+	sampleBurst = 10
+
+	bucketsMu sync.Mutex
+	buckets   = make(map[site]*tokenBucket)
+
+	// now is replaced in tests.
+	now = time.Now
+)
+
+// site is a call site of the sampled logs.
+type site struct {
+	file string
+	line int
+}
+
+// tokenBucket limits the log rate of one call site.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// SetSampling sets the rate (messages per second) and the burst of the
+// sampled logs of each call site. Zero rate disables the sampling.
+func SetSampling(rate float64, burst int) {
+	bucketsMu.Lock()
+	defer bucketsMu.Unlock()
+	sampleRate, sampleBurst = rate, burst
+	buckets = make(map[site]*tokenBucket)
+}
+
+func SampledErrorf(format string, args ...interface{}) {
+	if !enabled(LevelError) || !sample() {
+		return
+	}
+	Printf("ERROR", format, args...)
+}
+
+func SampledWarningf(format string, args ...interface{}) {
+	if !enabled(LevelWarning) || !sample() {
+		return
+	}
+	Printf("WARNING", format, args...)
+}
+
+func SampledInfof(format string, args ...interface{}) {
+	if !enabled(LevelInfo) || !sample() {
+		return
+	}
+	Printf("INFO", format, args...)
+}
+
+// sample() takes a token from the bucket of the call site,
+// and returns false if there is none left.
+func sample() bool {
+	bucketsMu.Lock()
+	defer bucketsMu.Unlock()
+
+	if sampleRate <= 0 {
+		return true
+	}
+	_, file, line, _ := runtime.Caller(2)
+	t := now()
+	b, ok := buckets[site{file, line}]
+	if !ok {
+		b = &tokenBucket{tokens: float64(sampleBurst), last: t}
+		buckets[site{file, line}] = b
+	}
+
+	b.tokens += t.Sub(b.last).Seconds() * sampleRate
+	if b.tokens > float64(sampleBurst) {
+		b.tokens = float64(sampleBurst)
+	}
+	b.last = t
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
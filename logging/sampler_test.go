@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lilymona/testify/assert"
+)
+
+func TestSampledErrorf(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	clock := time.Unix(0, 0)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	SetSampling(10, 5)
+	defer SetSampling(0, 0)
+
+	// Log from a single call site.
+	foo := func(n int) {
+		for i := 0; i < n; i++ {
+			SampledErrorf("foo\n")
+		}
+	}
+
+	// Only the burst is logged at once.
+	foo(100)
+	assert.Equal(t, 5, strings.Count(buf.String(), "foo"))
+
+	// The bucket refills at the rate, up to the burst.
+	buf.Reset()
+	clock = clock.Add(100 * time.Millisecond)
+	foo(100)
+	assert.Equal(t, 1, strings.Count(buf.String(), "foo"))
+
+	buf.Reset()
+	clock = clock.Add(time.Minute)
+	foo(100)
+	assert.Equal(t, 5, strings.Count(buf.String(), "foo"))
+
+	// Call sites are sampled independently.
+	buf.Reset()
+	SampledErrorf("bar\n")
+	assert.Equal(t, 1, strings.Count(buf.String(), "bar"))
+
+	// Zero rate disables the sampling.
+	SetSampling(0, 0)
+	buf.Reset()
+	foo(100)
+	assert.Equal(t, 100, strings.Count(buf.String(), "foo"))
+}
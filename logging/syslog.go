@@ -0,0 +1,36 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package logging
+
+import (
+	"log/syslog"
+)
+
+// syslogWriter writes the logs to the local syslog
+// at the priorities of their levels.
+type syslogWriter struct {
+	*syslog.Writer
+}
+
+func newSyslog() (*syslogWriter, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, "")
+	if err != nil {
+		return nil, err
+	}
+	return &syslogWriter{w}, nil
+}
+
+func (w *syslogWriter) writeLevel(level string, b []byte) error {
+	switch level {
+	case "FATAL":
+		return w.Crit(string(b))
+	case "ERROR":
+		return w.Err(string(b))
+	case "WARNING":
+		return w.Warning(string(b))
+	case "DEBUG":
+		return w.Debug(string(b))
+	}
+	return w.Info(string(b))
+}
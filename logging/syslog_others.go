@@ -0,0 +1,12 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package logging
+
+import (
+	"io"
+)
+
+func newSyslog() (io.WriteCloser, error) {
+	return nil, ErrUnsupportedOutput
+}
@@ -1,21 +1,80 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/lilymona/gog/config"
 	log "github.com/lilymona/gog/logging"
 	"github.com/lilymona/gog/rest"
 )
 
 func main() {
+	log.RegisterFlags(flag.CommandLine)
 	cfg, err := config.ParseConfig()
 	if err != nil {
 		log.Fatalf("Failed to parse configuration: %v\n", err)
 	}
+	if err := log.OpenFlags(); err != nil {
+		log.Fatalf("Failed to open the log output: %v\n", err)
+	}
 
 	srv := rest.NewServer(cfg)
+	if cfg.File != "" {
+		go reloadOnHangup(srv.Handler.(*rest.RESTServer), cfg.File)
+	}
+	if dbg := rest.NewDebugServer(srv, cfg); dbg != nil {
+		go func() {
+			if err := dbg.ListenAndServe(); err != nil {
+				log.Errorf("Failed to serve debug endpoints: %v\n", err)
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go shutdownOnSignal(srv, done)
 	log.Infof("Starting server...\n")
-	if err := srv.ListenAndServe(); err != nil {
+	if err := rest.ListenAndServe(srv, cfg); err != http.ErrServerClosed {
 		log.Fatalf("Failed to start server: %v\n", err)
 	}
+	<-done
 	return
 }
+
+// shutdownTimeout is the time to wait for the
+// graceful shutdown on SIGINT or SIGTERM.
+const shutdownTimeout = 10 * time.Second
+
+// shutdownOnSignal gracefully shuts down the server and the
+// agent on SIGINT or SIGTERM, and closes done when finished.
+func shutdownOnSignal(srv *http.Server, done chan<- struct{}) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-c
+	log.Infof("Received %v, shutting down...\n", sig)
+	signal.Stop(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := rest.Shutdown(ctx, srv); err != nil {
+		log.Errorf("Failed to shut down gracefully: %v\n", err)
+	}
+	close(done)
+}
+
+// reloadOnHangup reloads the config file on SIGHUP.
+func reloadOnHangup(rh *rest.RESTServer, path string) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	for range c {
+		if err := rh.ReloadFile(path); err != nil {
+			log.Errorf("Failed to reload configuration: %v\n", err)
+			continue
+		}
+		log.Infof("Reloaded configuration from %s\n", path)
+	}
+}
@@ -10,6 +10,7 @@
 
 	It has these top-level messages:
 		UserMessage
+		Label
 		Join
 		JoinReply
 		Neighbor
@@ -19,6 +20,21 @@
 		Candidate
 		Shuffle
 		ShuffleReply
+		Request
+		Reply
+		IHave
+		Graft
+		Prune
+		Ack
+		Ping
+		Pong
+		Batch
+		Digest
+		DigestEntry
+		TopologyRequest
+		TopologyReply
+		Settings
+		Setting
 */
 package message
 
@@ -79,13 +95,26 @@ func (x *Neighbor_Priority) UnmarshalJSON(data []byte) error {
 	*x = Neighbor_Priority(value)
 	return nil
 }
-func (Neighbor_Priority) EnumDescriptor() ([]byte, []int) { return fileDescriptorMessage, []int{3, 0} }
+func (Neighbor_Priority) EnumDescriptor() ([]byte, []int) { return fileDescriptorMessage, []int{4, 0} }
 
 // User defined messages.
 type UserMessage struct {
 	Id               *uint64 `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
 	Payload          []byte  `protobuf:"bytes,2,opt,name=payload" json:"payload,omitempty"`
 	Ts               *int64  `protobuf:"varint,3,req,name=ts" json:"ts,omitempty"`
+	Trace            []byte  `protobuf:"bytes,4,opt,name=trace" json:"trace,omitempty"`
+	Topic            *string `protobuf:"bytes,5,opt,name=topic" json:"topic,omitempty"`
+	Seq              *uint64 `protobuf:"varint,6,opt,name=seq" json:"seq,omitempty"`
+	Hops             *uint32 `protobuf:"varint,7,opt,name=hops" json:"hops,omitempty"`
+	Life             *int64  `protobuf:"varint,8,opt,name=life" json:"life,omitempty"`
+	Key              []byte  `protobuf:"bytes,9,opt,name=key" json:"key,omitempty"`
+	Sig              []byte  `protobuf:"bytes,10,opt,name=sig" json:"sig,omitempty"`
+	ChunkId          *uint64 `protobuf:"varint,11,opt,name=chunk_id,json=chunkId" json:"chunk_id,omitempty"`
+	ChunkIndex       *uint32 `protobuf:"varint,12,opt,name=chunk_index,json=chunkIndex" json:"chunk_index,omitempty"`
+	ChunkCount       *uint32 `protobuf:"varint,13,opt,name=chunk_count,json=chunkCount" json:"chunk_count,omitempty"`
+	OriginSeq        *uint64 `protobuf:"varint,14,opt,name=origin_seq,json=originSeq" json:"origin_seq,omitempty"`
+	ChunkHash        []byte  `protobuf:"bytes,15,opt,name=chunk_hash,json=chunkHash" json:"chunk_hash,omitempty"`
+	OriginEpoch      *uint64 `protobuf:"varint,16,opt,name=origin_epoch,json=originEpoch" json:"origin_epoch,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -114,16 +143,137 @@ func (m *UserMessage) GetTs() int64 {
 	return 0
 }
 
+func (m *UserMessage) GetTrace() []byte {
+	if m != nil {
+		return m.Trace
+	}
+	return nil
+}
+
+func (m *UserMessage) GetTopic() string {
+	if m != nil && m.Topic != nil {
+		return *m.Topic
+	}
+	return ""
+}
+
+func (m *UserMessage) GetSeq() uint64 {
+	if m != nil && m.Seq != nil {
+		return *m.Seq
+	}
+	return 0
+}
+
+func (m *UserMessage) GetHops() uint32 {
+	if m != nil && m.Hops != nil {
+		return *m.Hops
+	}
+	return 0
+}
+
+func (m *UserMessage) GetLife() int64 {
+	if m != nil && m.Life != nil {
+		return *m.Life
+	}
+	return 0
+}
+
+func (m *UserMessage) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *UserMessage) GetSig() []byte {
+	if m != nil {
+		return m.Sig
+	}
+	return nil
+}
+
+func (m *UserMessage) GetChunkId() uint64 {
+	if m != nil && m.ChunkId != nil {
+		return *m.ChunkId
+	}
+	return 0
+}
+
+func (m *UserMessage) GetChunkIndex() uint32 {
+	if m != nil && m.ChunkIndex != nil {
+		return *m.ChunkIndex
+	}
+	return 0
+}
+
+func (m *UserMessage) GetChunkCount() uint32 {
+	if m != nil && m.ChunkCount != nil {
+		return *m.ChunkCount
+	}
+	return 0
+}
+
+func (m *UserMessage) GetOriginSeq() uint64 {
+	if m != nil && m.OriginSeq != nil {
+		return *m.OriginSeq
+	}
+	return 0
+}
+
+func (m *UserMessage) GetChunkHash() []byte {
+	if m != nil {
+		return m.ChunkHash
+	}
+	return nil
+}
+
+func (m *UserMessage) GetOriginEpoch() uint64 {
+	if m != nil && m.OriginEpoch != nil {
+		return *m.OriginEpoch
+	}
+	return 0
+}
+
+// The label of a node.
+type Label struct {
+	Key              *string `protobuf:"bytes,1,req,name=key" json:"key,omitempty"`
+	Value            *string `protobuf:"bytes,2,req,name=value" json:"value,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *Label) Reset()                    { *m = Label{} }
+func (*Label) ProtoMessage()               {}
+func (*Label) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{1} }
+
+func (m *Label) GetKey() string {
+	if m != nil && m.Key != nil {
+		return *m.Key
+	}
+	return ""
+}
+
+func (m *Label) GetValue() string {
+	if m != nil && m.Value != nil {
+		return *m.Value
+	}
+	return ""
+}
+
 // The Join request.
 type Join struct {
-	Id               *uint64 `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
-	Addr             *string `protobuf:"bytes,2,req,name=addr" json:"addr,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+	Id               *uint64  `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
+	Addr             *string  `protobuf:"bytes,2,req,name=addr" json:"addr,omitempty"`
+	Observe          *bool    `protobuf:"varint,3,opt,name=observe" json:"observe,omitempty"`
+	Labels           []*Label `protobuf:"bytes,4,rep,name=labels" json:"labels,omitempty"`
+	Caps             *uint32  `protobuf:"varint,5,opt,name=caps" json:"caps,omitempty"`
+	Mac              []byte   `protobuf:"bytes,6,opt,name=mac" json:"mac,omitempty"`
+	Cluster          *string  `protobuf:"bytes,7,opt,name=cluster" json:"cluster,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
 }
 
 func (m *Join) Reset()                    { *m = Join{} }
 func (*Join) ProtoMessage()               {}
-func (*Join) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{1} }
+func (*Join) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{2} }
 
 func (m *Join) GetId() uint64 {
 	if m != nil && m.Id != nil {
@@ -139,16 +289,53 @@ func (m *Join) GetAddr() string {
 	return ""
 }
 
+func (m *Join) GetObserve() bool {
+	if m != nil && m.Observe != nil {
+		return *m.Observe
+	}
+	return false
+}
+
+func (m *Join) GetLabels() []*Label {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *Join) GetCaps() uint32 {
+	if m != nil && m.Caps != nil {
+		return *m.Caps
+	}
+	return 0
+}
+
+func (m *Join) GetMac() []byte {
+	if m != nil {
+		return m.Mac
+	}
+	return nil
+}
+
+func (m *Join) GetCluster() string {
+	if m != nil && m.Cluster != nil {
+		return *m.Cluster
+	}
+	return ""
+}
+
 // The Join reply.
 type JoinReply struct {
-	Id               *uint64 `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
-	Accept           *bool   `protobuf:"varint,2,req,name=accept" json:"accept,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+	Id               *uint64  `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
+	Accept           *bool    `protobuf:"varint,2,req,name=accept" json:"accept,omitempty"`
+	Labels           []*Label `protobuf:"bytes,3,rep,name=labels" json:"labels,omitempty"`
+	Caps             *uint32  `protobuf:"varint,4,opt,name=caps" json:"caps,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
 }
 
 func (m *JoinReply) Reset()                    { *m = JoinReply{} }
 func (*JoinReply) ProtoMessage()               {}
-func (*JoinReply) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{2} }
+func (*JoinReply) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{3} }
 
 func (m *JoinReply) GetId() uint64 {
 	if m != nil && m.Id != nil {
@@ -164,17 +351,35 @@ func (m *JoinReply) GetAccept() bool {
 	return false
 }
 
+func (m *JoinReply) GetLabels() []*Label {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *JoinReply) GetCaps() uint32 {
+	if m != nil && m.Caps != nil {
+		return *m.Caps
+	}
+	return 0
+}
+
 // The Neighbor request.
 type Neighbor struct {
 	Id               *uint64            `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
 	Addr             *string            `protobuf:"bytes,2,req,name=addr" json:"addr,omitempty"`
 	Priority         *Neighbor_Priority `protobuf:"varint,3,req,name=priority,enum=message.Neighbor_Priority" json:"priority,omitempty"`
+	Labels           []*Label           `protobuf:"bytes,4,rep,name=labels" json:"labels,omitempty"`
+	Caps             *uint32            `protobuf:"varint,5,opt,name=caps" json:"caps,omitempty"`
+	Mac              []byte             `protobuf:"bytes,6,opt,name=mac" json:"mac,omitempty"`
+	Cluster          *string            `protobuf:"bytes,7,opt,name=cluster" json:"cluster,omitempty"`
 	XXX_unrecognized []byte             `json:"-"`
 }
 
 func (m *Neighbor) Reset()                    { *m = Neighbor{} }
 func (*Neighbor) ProtoMessage()               {}
-func (*Neighbor) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{3} }
+func (*Neighbor) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{4} }
 
 func (m *Neighbor) GetId() uint64 {
 	if m != nil && m.Id != nil {
@@ -197,16 +402,46 @@ func (m *Neighbor) GetPriority() Neighbor_Priority {
 	return Neighbor_Low
 }
 
+func (m *Neighbor) GetLabels() []*Label {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *Neighbor) GetCaps() uint32 {
+	if m != nil && m.Caps != nil {
+		return *m.Caps
+	}
+	return 0
+}
+
+func (m *Neighbor) GetMac() []byte {
+	if m != nil {
+		return m.Mac
+	}
+	return nil
+}
+
+func (m *Neighbor) GetCluster() string {
+	if m != nil && m.Cluster != nil {
+		return *m.Cluster
+	}
+	return ""
+}
+
 // The reply to Neighbor request.
 type NeighborReply struct {
-	Id               *uint64 `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
-	Accept           *bool   `protobuf:"varint,2,req,name=accept" json:"accept,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+	Id               *uint64  `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
+	Accept           *bool    `protobuf:"varint,2,req,name=accept" json:"accept,omitempty"`
+	Labels           []*Label `protobuf:"bytes,3,rep,name=labels" json:"labels,omitempty"`
+	Caps             *uint32  `protobuf:"varint,4,opt,name=caps" json:"caps,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
 }
 
 func (m *NeighborReply) Reset()                    { *m = NeighborReply{} }
 func (*NeighborReply) ProtoMessage()               {}
-func (*NeighborReply) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{4} }
+func (*NeighborReply) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{5} }
 
 func (m *NeighborReply) GetId() uint64 {
 	if m != nil && m.Id != nil {
@@ -222,18 +457,33 @@ func (m *NeighborReply) GetAccept() bool {
 	return false
 }
 
+func (m *NeighborReply) GetLabels() []*Label {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *NeighborReply) GetCaps() uint32 {
+	if m != nil && m.Caps != nil {
+		return *m.Caps
+	}
+	return 0
+}
+
 // The ForwardJoin request.
 type ForwardJoin struct {
-	Id               *uint64 `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
-	SourceId         *uint64 `protobuf:"varint,2,req,name=sourceId" json:"sourceId,omitempty"`
-	SourceAddr       *string `protobuf:"bytes,3,req,name=sourceAddr" json:"sourceAddr,omitempty"`
-	Ttl              *uint32 `protobuf:"varint,4,req,name=ttl" json:"ttl,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+	Id               *uint64  `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
+	SourceId         *uint64  `protobuf:"varint,2,req,name=sourceId" json:"sourceId,omitempty"`
+	SourceAddr       *string  `protobuf:"bytes,3,req,name=sourceAddr" json:"sourceAddr,omitempty"`
+	Ttl              *uint32  `protobuf:"varint,4,req,name=ttl" json:"ttl,omitempty"`
+	SourceLabels     []*Label `protobuf:"bytes,5,rep,name=sourceLabels" json:"sourceLabels,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
 }
 
 func (m *ForwardJoin) Reset()                    { *m = ForwardJoin{} }
 func (*ForwardJoin) ProtoMessage()               {}
-func (*ForwardJoin) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{5} }
+func (*ForwardJoin) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{6} }
 
 func (m *ForwardJoin) GetId() uint64 {
 	if m != nil && m.Id != nil {
@@ -263,6 +513,13 @@ func (m *ForwardJoin) GetTtl() uint32 {
 	return 0
 }
 
+func (m *ForwardJoin) GetSourceLabels() []*Label {
+	if m != nil {
+		return m.SourceLabels
+	}
+	return nil
+}
+
 // The Disconnect request.
 type Disconnect struct {
 	Id               *uint64 `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
@@ -271,7 +528,7 @@ type Disconnect struct {
 
 func (m *Disconnect) Reset()                    { *m = Disconnect{} }
 func (*Disconnect) ProtoMessage()               {}
-func (*Disconnect) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{6} }
+func (*Disconnect) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{7} }
 
 func (m *Disconnect) GetId() uint64 {
 	if m != nil && m.Id != nil {
@@ -282,14 +539,15 @@ func (m *Disconnect) GetId() uint64 {
 
 // The Candidate.
 type Candidate struct {
-	Id               *uint64 `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
-	Addr             *string `protobuf:"bytes,2,req,name=addr" json:"addr,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+	Id               *uint64  `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
+	Addr             *string  `protobuf:"bytes,2,req,name=addr" json:"addr,omitempty"`
+	Labels           []*Label `protobuf:"bytes,3,rep,name=labels" json:"labels,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
 }
 
 func (m *Candidate) Reset()                    { *m = Candidate{} }
 func (*Candidate) ProtoMessage()               {}
-func (*Candidate) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{7} }
+func (*Candidate) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{8} }
 
 func (m *Candidate) GetId() uint64 {
 	if m != nil && m.Id != nil {
@@ -305,6 +563,13 @@ func (m *Candidate) GetAddr() string {
 	return ""
 }
 
+func (m *Candidate) GetLabels() []*Label {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
 // The Shuffle request.
 type Shuffle struct {
 	Id               *uint64      `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
@@ -312,12 +577,15 @@ type Shuffle struct {
 	Addr             *string      `protobuf:"bytes,3,req,name=addr" json:"addr,omitempty"`
 	Candidates       []*Candidate `protobuf:"bytes,4,rep,name=candidates" json:"candidates,omitempty"`
 	Ttl              *uint32      `protobuf:"varint,5,req,name=ttl" json:"ttl,omitempty"`
+	Udp              *bool        `protobuf:"varint,6,opt,name=udp" json:"udp,omitempty"`
+	Cluster          *string      `protobuf:"bytes,7,opt,name=cluster" json:"cluster,omitempty"`
+	Nonce            *uint64      `protobuf:"varint,8,opt,name=nonce" json:"nonce,omitempty"`
 	XXX_unrecognized []byte       `json:"-"`
 }
 
 func (m *Shuffle) Reset()                    { *m = Shuffle{} }
 func (*Shuffle) ProtoMessage()               {}
-func (*Shuffle) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{8} }
+func (*Shuffle) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{9} }
 
 func (m *Shuffle) GetId() uint64 {
 	if m != nil && m.Id != nil {
@@ -354,16 +622,38 @@ func (m *Shuffle) GetTtl() uint32 {
 	return 0
 }
 
+func (m *Shuffle) GetUdp() bool {
+	if m != nil && m.Udp != nil {
+		return *m.Udp
+	}
+	return false
+}
+
+func (m *Shuffle) GetCluster() string {
+	if m != nil && m.Cluster != nil {
+		return *m.Cluster
+	}
+	return ""
+}
+
+func (m *Shuffle) GetNonce() uint64 {
+	if m != nil && m.Nonce != nil {
+		return *m.Nonce
+	}
+	return 0
+}
+
 // The ShuffleReply.
 type ShuffleReply struct {
 	Id               *uint64      `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
 	Candidates       []*Candidate `protobuf:"bytes,2,rep,name=candidates" json:"candidates,omitempty"`
+	Nonce            *uint64      `protobuf:"varint,3,opt,name=nonce" json:"nonce,omitempty"`
 	XXX_unrecognized []byte       `json:"-"`
 }
 
 func (m *ShuffleReply) Reset()                    { *m = ShuffleReply{} }
 func (*ShuffleReply) ProtoMessage()               {}
-func (*ShuffleReply) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{9} }
+func (*ShuffleReply) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{10} }
 
 func (m *ShuffleReply) GetId() uint64 {
 	if m != nil && m.Id != nil {
@@ -379,314 +669,521 @@ func (m *ShuffleReply) GetCandidates() []*Candidate {
 	return nil
 }
 
-func init() {
-	proto.RegisterType((*UserMessage)(nil), "message.UserMessage")
-	proto.RegisterType((*Join)(nil), "message.Join")
-	proto.RegisterType((*JoinReply)(nil), "message.JoinReply")
-	proto.RegisterType((*Neighbor)(nil), "message.Neighbor")
-	proto.RegisterType((*NeighborReply)(nil), "message.NeighborReply")
-	proto.RegisterType((*ForwardJoin)(nil), "message.ForwardJoin")
-	proto.RegisterType((*Disconnect)(nil), "message.Disconnect")
-	proto.RegisterType((*Candidate)(nil), "message.Candidate")
-	proto.RegisterType((*Shuffle)(nil), "message.Shuffle")
-	proto.RegisterType((*ShuffleReply)(nil), "message.ShuffleReply")
-	proto.RegisterEnum("message.Neighbor_Priority", Neighbor_Priority_name, Neighbor_Priority_value)
-}
-func (this *UserMessage) VerboseEqual(that interface{}) error {
-	if that == nil {
-		if this == nil {
-			return nil
-		}
-		return fmt.Errorf("that == nil && this != nil")
+func (m *ShuffleReply) GetNonce() uint64 {
+	if m != nil && m.Nonce != nil {
+		return *m.Nonce
 	}
+	return 0
+}
 
-	that1, ok := that.(*UserMessage)
-	if !ok {
-		that2, ok := that.(UserMessage)
-		if ok {
-			that1 = &that2
-		} else {
-			return fmt.Errorf("that is not of type *UserMessage")
-		}
-	}
-	if that1 == nil {
-		if this == nil {
-			return nil
-		}
-		return fmt.Errorf("that is type *UserMessage but is nil && this != nil")
-	} else if this == nil {
-		return fmt.Errorf("that is type *UserMessage but is not nil && this == nil")
-	}
-	if this.Id != nil && that1.Id != nil {
-		if *this.Id != *that1.Id {
-			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
-		}
-	} else if this.Id != nil {
-		return fmt.Errorf("this.Id == nil && that.Id != nil")
-	} else if that1.Id != nil {
-		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
+// The Request, which is broadcast like the user messages.
+type Request struct {
+	Id               *uint64 `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
+	ReqId            *uint64 `protobuf:"varint,2,req,name=reqId" json:"reqId,omitempty"`
+	Addr             *string `protobuf:"bytes,3,req,name=addr" json:"addr,omitempty"`
+	Payload          []byte  `protobuf:"bytes,4,opt,name=payload" json:"payload,omitempty"`
+	Ts               *int64  `protobuf:"varint,5,req,name=ts" json:"ts,omitempty"`
+	Target           *uint64 `protobuf:"varint,6,opt,name=target" json:"target,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *Request) Reset()                    { *m = Request{} }
+func (*Request) ProtoMessage()               {}
+func (*Request) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{11} }
+
+func (m *Request) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
 	}
-	if !bytes.Equal(this.Payload, that1.Payload) {
-		return fmt.Errorf("Payload this(%v) Not Equal that(%v)", this.Payload, that1.Payload)
+	return 0
+}
+
+func (m *Request) GetReqId() uint64 {
+	if m != nil && m.ReqId != nil {
+		return *m.ReqId
 	}
-	if this.Ts != nil && that1.Ts != nil {
-		if *this.Ts != *that1.Ts {
-			return fmt.Errorf("Ts this(%v) Not Equal that(%v)", *this.Ts, *that1.Ts)
-		}
-	} else if this.Ts != nil {
-		return fmt.Errorf("this.Ts == nil && that.Ts != nil")
-	} else if that1.Ts != nil {
-		return fmt.Errorf("Ts this(%v) Not Equal that(%v)", this.Ts, that1.Ts)
+	return 0
+}
+
+func (m *Request) GetAddr() string {
+	if m != nil && m.Addr != nil {
+		return *m.Addr
 	}
-	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
-		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
+	return ""
+}
+
+func (m *Request) GetPayload() []byte {
+	if m != nil {
+		return m.Payload
 	}
 	return nil
 }
-func (this *UserMessage) Equal(that interface{}) bool {
-	if that == nil {
-		if this == nil {
-			return true
-		}
-		return false
-	}
 
-	that1, ok := that.(*UserMessage)
-	if !ok {
-		that2, ok := that.(UserMessage)
-		if ok {
-			that1 = &that2
-		} else {
-			return false
-		}
+func (m *Request) GetTs() int64 {
+	if m != nil && m.Ts != nil {
+		return *m.Ts
 	}
-	if that1 == nil {
-		if this == nil {
-			return true
-		}
-		return false
-	} else if this == nil {
-		return false
-	}
-	if this.Id != nil && that1.Id != nil {
-		if *this.Id != *that1.Id {
-			return false
-		}
-	} else if this.Id != nil {
-		return false
-	} else if that1.Id != nil {
-		return false
-	}
-	if !bytes.Equal(this.Payload, that1.Payload) {
-		return false
-	}
-	if this.Ts != nil && that1.Ts != nil {
-		if *this.Ts != *that1.Ts {
-			return false
-		}
-	} else if this.Ts != nil {
-		return false
-	} else if that1.Ts != nil {
-		return false
-	}
-	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
-		return false
-	}
-	return true
+	return 0
 }
-func (this *Join) VerboseEqual(that interface{}) error {
-	if that == nil {
-		if this == nil {
-			return nil
-		}
-		return fmt.Errorf("that == nil && this != nil")
-	}
 
-	that1, ok := that.(*Join)
-	if !ok {
-		that2, ok := that.(Join)
-		if ok {
-			that1 = &that2
-		} else {
-			return fmt.Errorf("that is not of type *Join")
-		}
-	}
-	if that1 == nil {
-		if this == nil {
-			return nil
-		}
-		return fmt.Errorf("that is type *Join but is nil && this != nil")
-	} else if this == nil {
-		return fmt.Errorf("that is type *Join but is not nil && this == nil")
+func (m *Request) GetTarget() uint64 {
+	if m != nil && m.Target != nil {
+		return *m.Target
 	}
-	if this.Id != nil && that1.Id != nil {
-		if *this.Id != *that1.Id {
-			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
-		}
-	} else if this.Id != nil {
-		return fmt.Errorf("this.Id == nil && that.Id != nil")
-	} else if that1.Id != nil {
-		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
+	return 0
+}
+
+// The Reply to Request, which is sent to the origin.
+type Reply struct {
+	Id               *uint64 `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
+	ReqId            *uint64 `protobuf:"varint,2,req,name=reqId" json:"reqId,omitempty"`
+	Payload          []byte  `protobuf:"bytes,3,opt,name=payload" json:"payload,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *Reply) Reset()                    { *m = Reply{} }
+func (*Reply) ProtoMessage()               {}
+func (*Reply) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{12} }
+
+func (m *Reply) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
 	}
-	if this.Addr != nil && that1.Addr != nil {
-		if *this.Addr != *that1.Addr {
-			return fmt.Errorf("Addr this(%v) Not Equal that(%v)", *this.Addr, *that1.Addr)
-		}
-	} else if this.Addr != nil {
-		return fmt.Errorf("this.Addr == nil && that.Addr != nil")
-	} else if that1.Addr != nil {
-		return fmt.Errorf("Addr this(%v) Not Equal that(%v)", this.Addr, that1.Addr)
+	return 0
+}
+
+func (m *Reply) GetReqId() uint64 {
+	if m != nil && m.ReqId != nil {
+		return *m.ReqId
 	}
-	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
-		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
+	return 0
+}
+
+func (m *Reply) GetPayload() []byte {
+	if m != nil {
+		return m.Payload
 	}
 	return nil
 }
-func (this *Join) Equal(that interface{}) bool {
-	if that == nil {
-		if this == nil {
-			return true
-		}
-		return false
+
+// The IHave, which announces the ids of the messages
+// to the lazy peers in the plumtree mode.
+type IHave struct {
+	Id               *uint64  `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
+	MsgIds           [][]byte `protobuf:"bytes,2,rep,name=msgIds" json:"msgIds,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *IHave) Reset()                    { *m = IHave{} }
+func (*IHave) ProtoMessage()               {}
+func (*IHave) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{13} }
+
+func (m *IHave) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
 	}
+	return 0
+}
 
-	that1, ok := that.(*Join)
-	if !ok {
-		that2, ok := that.(Join)
-		if ok {
-			that1 = &that2
-		} else {
-			return false
-		}
+func (m *IHave) GetMsgIds() [][]byte {
+	if m != nil {
+		return m.MsgIds
 	}
-	if that1 == nil {
-		if this == nil {
-			return true
-		}
-		return false
-	} else if this == nil {
-		return false
+	return nil
+}
+
+// The Graft, which asks for a missing message, and
+// makes the link eager in the plumtree mode.
+type Graft struct {
+	Id               *uint64 `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
+	MsgId            []byte  `protobuf:"bytes,2,req,name=msgId" json:"msgId,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *Graft) Reset()                    { *m = Graft{} }
+func (*Graft) ProtoMessage()               {}
+func (*Graft) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{14} }
+
+func (m *Graft) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
 	}
-	if this.Id != nil && that1.Id != nil {
-		if *this.Id != *that1.Id {
-			return false
-		}
-	} else if this.Id != nil {
-		return false
-	} else if that1.Id != nil {
-		return false
+	return 0
+}
+
+func (m *Graft) GetMsgId() []byte {
+	if m != nil {
+		return m.MsgId
 	}
-	if this.Addr != nil && that1.Addr != nil {
-		if *this.Addr != *that1.Addr {
-			return false
-		}
-	} else if this.Addr != nil {
-		return false
-	} else if that1.Addr != nil {
-		return false
+	return nil
+}
+
+// The Prune, which makes the link lazy in the plumtree mode.
+type Prune struct {
+	Id               *uint64 `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *Prune) Reset()                    { *m = Prune{} }
+func (*Prune) ProtoMessage()               {}
+func (*Prune) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{15} }
+
+func (m *Prune) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
 	}
-	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
-		return false
+	return 0
+}
+
+// The Ack, which acknowledges a user message in the reliable mode.
+type Ack struct {
+	Id               *uint64 `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
+	Seq              *uint64 `protobuf:"varint,2,req,name=seq" json:"seq,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *Ack) Reset()                    { *m = Ack{} }
+func (*Ack) ProtoMessage()               {}
+func (*Ack) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{16} }
+
+func (m *Ack) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
 	}
-	return true
+	return 0
 }
-func (this *JoinReply) VerboseEqual(that interface{}) error {
-	if that == nil {
-		if this == nil {
-			return nil
-		}
-		return fmt.Errorf("that == nil && this != nil")
+
+func (m *Ack) GetSeq() uint64 {
+	if m != nil && m.Seq != nil {
+		return *m.Seq
 	}
+	return 0
+}
 
-	that1, ok := that.(*JoinReply)
-	if !ok {
-		that2, ok := that.(JoinReply)
-		if ok {
-			that1 = &that2
-		} else {
-			return fmt.Errorf("that is not of type *JoinReply")
-		}
+// The Ping, which probes the liveness of a neighbor.
+type Ping struct {
+	Id               *uint64 `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
+	Seq              *uint64 `protobuf:"varint,2,req,name=seq" json:"seq,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *Ping) Reset()                    { *m = Ping{} }
+func (*Ping) ProtoMessage()               {}
+func (*Ping) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{17} }
+
+func (m *Ping) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
 	}
-	if that1 == nil {
-		if this == nil {
-			return nil
-		}
-		return fmt.Errorf("that is type *JoinReply but is nil && this != nil")
-	} else if this == nil {
-		return fmt.Errorf("that is type *JoinReply but is not nil && this == nil")
+	return 0
+}
+
+func (m *Ping) GetSeq() uint64 {
+	if m != nil && m.Seq != nil {
+		return *m.Seq
 	}
-	if this.Id != nil && that1.Id != nil {
-		if *this.Id != *that1.Id {
-			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
-		}
-	} else if this.Id != nil {
-		return fmt.Errorf("this.Id == nil && that.Id != nil")
-	} else if that1.Id != nil {
-		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
+	return 0
+}
+
+// The Pong, which answers the Ping with the same sequence number.
+type Pong struct {
+	Id               *uint64 `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
+	Seq              *uint64 `protobuf:"varint,2,req,name=seq" json:"seq,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *Pong) Reset()                    { *m = Pong{} }
+func (*Pong) ProtoMessage()               {}
+func (*Pong) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{18} }
+
+func (m *Pong) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
 	}
-	if this.Accept != nil && that1.Accept != nil {
-		if *this.Accept != *that1.Accept {
-			return fmt.Errorf("Accept this(%v) Not Equal that(%v)", *this.Accept, *that1.Accept)
-		}
-	} else if this.Accept != nil {
-		return fmt.Errorf("this.Accept == nil && that.Accept != nil")
-	} else if that1.Accept != nil {
-		return fmt.Errorf("Accept this(%v) Not Equal that(%v)", this.Accept, that1.Accept)
+	return 0
+}
+
+func (m *Pong) GetSeq() uint64 {
+	if m != nil && m.Seq != nil {
+		return *m.Seq
 	}
-	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
-		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
+	return 0
+}
+
+// The Batch, which carries the user messages to a neighbor in one frame.
+type Batch struct {
+	Messages         []*UserMessage `protobuf:"bytes,1,rep,name=messages" json:"messages,omitempty"`
+	XXX_unrecognized []byte         `json:"-"`
+}
+
+func (m *Batch) Reset()                    { *m = Batch{} }
+func (*Batch) ProtoMessage()               {}
+func (*Batch) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{19} }
+
+func (m *Batch) GetMessages() []*UserMessage {
+	if m != nil {
+		return m.Messages
 	}
 	return nil
 }
-func (this *JoinReply) Equal(that interface{}) bool {
-	if that == nil {
-		if this == nil {
-			return true
-		}
-		return false
-	}
 
-	that1, ok := that.(*JoinReply)
-	if !ok {
-		that2, ok := that.(JoinReply)
-		if ok {
-			that1 = &that2
-		} else {
-			return false
-		}
+// The Digest, which gossips the nodes reachable by the sender
+// to the neighbors, to detect the partitions of the overlay.
+type Digest struct {
+	Id               *uint64        `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
+	Reachable        *uint32        `protobuf:"varint,2,req,name=reachable" json:"reachable,omitempty"`
+	Sample           []*DigestEntry `protobuf:"bytes,3,rep,name=sample" json:"sample,omitempty"`
+	XXX_unrecognized []byte         `json:"-"`
+}
+
+func (m *Digest) Reset()                    { *m = Digest{} }
+func (*Digest) ProtoMessage()               {}
+func (*Digest) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{20} }
+
+func (m *Digest) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
 	}
-	if that1 == nil {
-		if this == nil {
-			return true
-		}
-		return false
-	} else if this == nil {
-		return false
+	return 0
+}
+
+func (m *Digest) GetReachable() uint32 {
+	if m != nil && m.Reachable != nil {
+		return *m.Reachable
 	}
-	if this.Id != nil && that1.Id != nil {
-		if *this.Id != *that1.Id {
-			return false
-		}
-	} else if this.Id != nil {
-		return false
-	} else if that1.Id != nil {
-		return false
+	return 0
+}
+
+func (m *Digest) GetSample() []*DigestEntry {
+	if m != nil {
+		return m.Sample
 	}
-	if this.Accept != nil && that1.Accept != nil {
-		if *this.Accept != *that1.Accept {
-			return false
-		}
-	} else if this.Accept != nil {
-		return false
-	} else if that1.Accept != nil {
-		return false
+	return nil
+}
+
+// A node in the Digest.
+type DigestEntry struct {
+	Id               *uint64 `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
+	Addr             *string `protobuf:"bytes,2,req,name=addr" json:"addr,omitempty"`
+	Age              *int64  `protobuf:"varint,3,req,name=age" json:"age,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DigestEntry) Reset()                    { *m = DigestEntry{} }
+func (*DigestEntry) ProtoMessage()               {}
+func (*DigestEntry) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{21} }
+
+func (m *DigestEntry) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
 	}
-	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
-		return false
+	return 0
+}
+
+func (m *DigestEntry) GetAddr() string {
+	if m != nil && m.Addr != nil {
+		return *m.Addr
 	}
-	return true
+	return ""
 }
-func (this *Neighbor) VerboseEqual(that interface{}) error {
+
+func (m *DigestEntry) GetAge() int64 {
+	if m != nil && m.Age != nil {
+		return *m.Age
+	}
+	return 0
+}
+
+// The TopologyRequest, which is flooded to the active views
+// within ttl hops, and answered by each node with a
+// TopologyReply, which is routed back along the path
+// the request came, to crawl the overlay.
+type TopologyRequest struct {
+	Id               *uint64 `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
+	ReqId            *uint64 `protobuf:"varint,2,req,name=reqId" json:"reqId,omitempty"`
+	Addr             *string `protobuf:"bytes,3,req,name=addr" json:"addr,omitempty"`
+	Ttl              *uint32 `protobuf:"varint,4,req,name=ttl" json:"ttl,omitempty"`
+	Ts               *int64  `protobuf:"varint,5,req,name=ts" json:"ts,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *TopologyRequest) Reset()                    { *m = TopologyRequest{} }
+func (*TopologyRequest) ProtoMessage()               {}
+func (*TopologyRequest) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{22} }
+
+func (m *TopologyRequest) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
+	}
+	return 0
+}
+
+func (m *TopologyRequest) GetReqId() uint64 {
+	if m != nil && m.ReqId != nil {
+		return *m.ReqId
+	}
+	return 0
+}
+
+func (m *TopologyRequest) GetAddr() string {
+	if m != nil && m.Addr != nil {
+		return *m.Addr
+	}
+	return ""
+}
+
+func (m *TopologyRequest) GetTtl() uint32 {
+	if m != nil && m.Ttl != nil {
+		return *m.Ttl
+	}
+	return 0
+}
+
+func (m *TopologyRequest) GetTs() int64 {
+	if m != nil && m.Ts != nil {
+		return *m.Ts
+	}
+	return 0
+}
+
+// The TopologyReply, which has the active view of the node.
+type TopologyReply struct {
+	Id               *uint64      `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
+	ReqId            *uint64      `protobuf:"varint,2,req,name=reqId" json:"reqId,omitempty"`
+	Addr             *string      `protobuf:"bytes,3,req,name=addr" json:"addr,omitempty"`
+	Neighbors        []*Candidate `protobuf:"bytes,4,rep,name=neighbors" json:"neighbors,omitempty"`
+	XXX_unrecognized []byte       `json:"-"`
+}
+
+func (m *TopologyReply) Reset()                    { *m = TopologyReply{} }
+func (*TopologyReply) ProtoMessage()               {}
+func (*TopologyReply) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{23} }
+
+func (m *TopologyReply) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
+	}
+	return 0
+}
+
+func (m *TopologyReply) GetReqId() uint64 {
+	if m != nil && m.ReqId != nil {
+		return *m.ReqId
+	}
+	return 0
+}
+
+func (m *TopologyReply) GetAddr() string {
+	if m != nil && m.Addr != nil {
+		return *m.Addr
+	}
+	return ""
+}
+
+func (m *TopologyReply) GetNeighbors() []*Candidate {
+	if m != nil {
+		return m.Neighbors
+	}
+	return nil
+}
+
+// The Settings, which gossips the cluster settings that changed, or all
+// of them to a new neighbor. The receivers keep the last write of each.
+type Settings struct {
+	Id               *uint64    `protobuf:"varint,1,req,name=id" json:"id,omitempty"`
+	Settings         []*Setting `protobuf:"bytes,2,rep,name=settings" json:"settings,omitempty"`
+	XXX_unrecognized []byte     `json:"-"`
+}
+
+func (m *Settings) Reset()                    { *m = Settings{} }
+func (*Settings) ProtoMessage()               {}
+func (*Settings) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{24} }
+
+func (m *Settings) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
+	}
+	return 0
+}
+
+func (m *Settings) GetSettings() []*Setting {
+	if m != nil {
+		return m.Settings
+	}
+	return nil
+}
+
+// A cluster setting, and the time and the origin of its write.
+type Setting struct {
+	Key              *string `protobuf:"bytes,1,req,name=key" json:"key,omitempty"`
+	Value            *string `protobuf:"bytes,2,req,name=value" json:"value,omitempty"`
+	Ts               *int64  `protobuf:"varint,3,req,name=ts" json:"ts,omitempty"`
+	Origin           *uint64 `protobuf:"varint,4,req,name=origin" json:"origin,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *Setting) Reset()                    { *m = Setting{} }
+func (*Setting) ProtoMessage()               {}
+func (*Setting) Descriptor() ([]byte, []int) { return fileDescriptorMessage, []int{25} }
+
+func (m *Setting) GetKey() string {
+	if m != nil && m.Key != nil {
+		return *m.Key
+	}
+	return ""
+}
+
+func (m *Setting) GetValue() string {
+	if m != nil && m.Value != nil {
+		return *m.Value
+	}
+	return ""
+}
+
+func (m *Setting) GetTs() int64 {
+	if m != nil && m.Ts != nil {
+		return *m.Ts
+	}
+	return 0
+}
+
+func (m *Setting) GetOrigin() uint64 {
+	if m != nil && m.Origin != nil {
+		return *m.Origin
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*UserMessage)(nil), "message.UserMessage")
+	proto.RegisterType((*Label)(nil), "message.Label")
+	proto.RegisterType((*Join)(nil), "message.Join")
+	proto.RegisterType((*JoinReply)(nil), "message.JoinReply")
+	proto.RegisterType((*Neighbor)(nil), "message.Neighbor")
+	proto.RegisterType((*NeighborReply)(nil), "message.NeighborReply")
+	proto.RegisterType((*ForwardJoin)(nil), "message.ForwardJoin")
+	proto.RegisterType((*Disconnect)(nil), "message.Disconnect")
+	proto.RegisterType((*Candidate)(nil), "message.Candidate")
+	proto.RegisterType((*Shuffle)(nil), "message.Shuffle")
+	proto.RegisterType((*ShuffleReply)(nil), "message.ShuffleReply")
+	proto.RegisterType((*Request)(nil), "message.Request")
+	proto.RegisterType((*Reply)(nil), "message.Reply")
+	proto.RegisterType((*IHave)(nil), "message.IHave")
+	proto.RegisterType((*Graft)(nil), "message.Graft")
+	proto.RegisterType((*Prune)(nil), "message.Prune")
+	proto.RegisterType((*Ack)(nil), "message.Ack")
+	proto.RegisterType((*Ping)(nil), "message.Ping")
+	proto.RegisterType((*Pong)(nil), "message.Pong")
+	proto.RegisterType((*Batch)(nil), "message.Batch")
+	proto.RegisterType((*Digest)(nil), "message.Digest")
+	proto.RegisterType((*DigestEntry)(nil), "message.DigestEntry")
+	proto.RegisterType((*TopologyRequest)(nil), "message.TopologyRequest")
+	proto.RegisterType((*TopologyReply)(nil), "message.TopologyReply")
+	proto.RegisterType((*Settings)(nil), "message.Settings")
+	proto.RegisterType((*Setting)(nil), "message.Setting")
+	proto.RegisterEnum("message.Neighbor_Priority", Neighbor_Priority_name, Neighbor_Priority_value)
+}
+func (this *UserMessage) VerboseEqual(that interface{}) error {
 	if that == nil {
 		if this == nil {
 			return nil
@@ -694,22 +1191,22 @@ func (this *Neighbor) VerboseEqual(that interface{}) error {
 		return fmt.Errorf("that == nil && this != nil")
 	}
 
-	that1, ok := that.(*Neighbor)
+	that1, ok := that.(*UserMessage)
 	if !ok {
-		that2, ok := that.(Neighbor)
+		that2, ok := that.(UserMessage)
 		if ok {
 			that1 = &that2
 		} else {
-			return fmt.Errorf("that is not of type *Neighbor")
+			return fmt.Errorf("that is not of type *UserMessage")
 		}
 	}
 	if that1 == nil {
 		if this == nil {
 			return nil
 		}
-		return fmt.Errorf("that is type *Neighbor but is nil && this != nil")
+		return fmt.Errorf("that is type *UserMessage but is nil && this != nil")
 	} else if this == nil {
-		return fmt.Errorf("that is type *Neighbor but is not nil && this == nil")
+		return fmt.Errorf("that is type *UserMessage but is not nil && this == nil")
 	}
 	if this.Id != nil && that1.Id != nil {
 		if *this.Id != *that1.Id {
@@ -720,30 +1217,117 @@ func (this *Neighbor) VerboseEqual(that interface{}) error {
 	} else if that1.Id != nil {
 		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
 	}
-	if this.Addr != nil && that1.Addr != nil {
-		if *this.Addr != *that1.Addr {
-			return fmt.Errorf("Addr this(%v) Not Equal that(%v)", *this.Addr, *that1.Addr)
+	if !bytes.Equal(this.Payload, that1.Payload) {
+		return fmt.Errorf("Payload this(%v) Not Equal that(%v)", this.Payload, that1.Payload)
+	}
+	if this.Ts != nil && that1.Ts != nil {
+		if *this.Ts != *that1.Ts {
+			return fmt.Errorf("Ts this(%v) Not Equal that(%v)", *this.Ts, *that1.Ts)
 		}
-	} else if this.Addr != nil {
-		return fmt.Errorf("this.Addr == nil && that.Addr != nil")
-	} else if that1.Addr != nil {
-		return fmt.Errorf("Addr this(%v) Not Equal that(%v)", this.Addr, that1.Addr)
+	} else if this.Ts != nil {
+		return fmt.Errorf("this.Ts == nil && that.Ts != nil")
+	} else if that1.Ts != nil {
+		return fmt.Errorf("Ts this(%v) Not Equal that(%v)", this.Ts, that1.Ts)
 	}
-	if this.Priority != nil && that1.Priority != nil {
-		if *this.Priority != *that1.Priority {
-			return fmt.Errorf("Priority this(%v) Not Equal that(%v)", *this.Priority, *that1.Priority)
+	if !bytes.Equal(this.Trace, that1.Trace) {
+		return fmt.Errorf("Trace this(%v) Not Equal that(%v)", this.Trace, that1.Trace)
+	}
+	if this.Topic != nil && that1.Topic != nil {
+		if *this.Topic != *that1.Topic {
+			return fmt.Errorf("Topic this(%v) Not Equal that(%v)", *this.Topic, *that1.Topic)
 		}
-	} else if this.Priority != nil {
-		return fmt.Errorf("this.Priority == nil && that.Priority != nil")
-	} else if that1.Priority != nil {
-		return fmt.Errorf("Priority this(%v) Not Equal that(%v)", this.Priority, that1.Priority)
+	} else if this.Topic != nil {
+		return fmt.Errorf("this.Topic == nil && that.Topic != nil")
+	} else if that1.Topic != nil {
+		return fmt.Errorf("Topic this(%v) Not Equal that(%v)", this.Topic, that1.Topic)
+	}
+	if this.Seq != nil && that1.Seq != nil {
+		if *this.Seq != *that1.Seq {
+			return fmt.Errorf("Seq this(%v) Not Equal that(%v)", *this.Seq, *that1.Seq)
+		}
+	} else if this.Seq != nil {
+		return fmt.Errorf("this.Seq == nil && that.Seq != nil")
+	} else if that1.Seq != nil {
+		return fmt.Errorf("Seq this(%v) Not Equal that(%v)", this.Seq, that1.Seq)
+	}
+	if this.Hops != nil && that1.Hops != nil {
+		if *this.Hops != *that1.Hops {
+			return fmt.Errorf("Hops this(%v) Not Equal that(%v)", *this.Hops, *that1.Hops)
+		}
+	} else if this.Hops != nil {
+		return fmt.Errorf("this.Hops == nil && that.Hops != nil")
+	} else if that1.Hops != nil {
+		return fmt.Errorf("Hops this(%v) Not Equal that(%v)", this.Hops, that1.Hops)
+	}
+	if this.Life != nil && that1.Life != nil {
+		if *this.Life != *that1.Life {
+			return fmt.Errorf("Life this(%v) Not Equal that(%v)", *this.Life, *that1.Life)
+		}
+	} else if this.Life != nil {
+		return fmt.Errorf("this.Life == nil && that.Life != nil")
+	} else if that1.Life != nil {
+		return fmt.Errorf("Life this(%v) Not Equal that(%v)", this.Life, that1.Life)
+	}
+	if !bytes.Equal(this.Key, that1.Key) {
+		return fmt.Errorf("Key this(%v) Not Equal that(%v)", this.Key, that1.Key)
+	}
+	if !bytes.Equal(this.Sig, that1.Sig) {
+		return fmt.Errorf("Sig this(%v) Not Equal that(%v)", this.Sig, that1.Sig)
+	}
+	if this.ChunkId != nil && that1.ChunkId != nil {
+		if *this.ChunkId != *that1.ChunkId {
+			return fmt.Errorf("ChunkId this(%v) Not Equal that(%v)", *this.ChunkId, *that1.ChunkId)
+		}
+	} else if this.ChunkId != nil {
+		return fmt.Errorf("this.ChunkId == nil && that.ChunkId != nil")
+	} else if that1.ChunkId != nil {
+		return fmt.Errorf("ChunkId this(%v) Not Equal that(%v)", this.ChunkId, that1.ChunkId)
+	}
+	if this.ChunkIndex != nil && that1.ChunkIndex != nil {
+		if *this.ChunkIndex != *that1.ChunkIndex {
+			return fmt.Errorf("ChunkIndex this(%v) Not Equal that(%v)", *this.ChunkIndex, *that1.ChunkIndex)
+		}
+	} else if this.ChunkIndex != nil {
+		return fmt.Errorf("this.ChunkIndex == nil && that.ChunkIndex != nil")
+	} else if that1.ChunkIndex != nil {
+		return fmt.Errorf("ChunkIndex this(%v) Not Equal that(%v)", this.ChunkIndex, that1.ChunkIndex)
+	}
+	if this.ChunkCount != nil && that1.ChunkCount != nil {
+		if *this.ChunkCount != *that1.ChunkCount {
+			return fmt.Errorf("ChunkCount this(%v) Not Equal that(%v)", *this.ChunkCount, *that1.ChunkCount)
+		}
+	} else if this.ChunkCount != nil {
+		return fmt.Errorf("this.ChunkCount == nil && that.ChunkCount != nil")
+	} else if that1.ChunkCount != nil {
+		return fmt.Errorf("ChunkCount this(%v) Not Equal that(%v)", this.ChunkCount, that1.ChunkCount)
+	}
+	if this.OriginSeq != nil && that1.OriginSeq != nil {
+		if *this.OriginSeq != *that1.OriginSeq {
+			return fmt.Errorf("OriginSeq this(%v) Not Equal that(%v)", *this.OriginSeq, *that1.OriginSeq)
+		}
+	} else if this.OriginSeq != nil {
+		return fmt.Errorf("this.OriginSeq == nil && that.OriginSeq != nil")
+	} else if that1.OriginSeq != nil {
+		return fmt.Errorf("OriginSeq this(%v) Not Equal that(%v)", this.OriginSeq, that1.OriginSeq)
+	}
+	if !bytes.Equal(this.ChunkHash, that1.ChunkHash) {
+		return fmt.Errorf("ChunkHash this(%v) Not Equal that(%v)", this.ChunkHash, that1.ChunkHash)
+	}
+	if this.OriginEpoch != nil && that1.OriginEpoch != nil {
+		if *this.OriginEpoch != *that1.OriginEpoch {
+			return fmt.Errorf("OriginEpoch this(%v) Not Equal that(%v)", *this.OriginEpoch, *that1.OriginEpoch)
+		}
+	} else if this.OriginEpoch != nil {
+		return fmt.Errorf("this.OriginEpoch == nil && that.OriginEpoch != nil")
+	} else if that1.OriginEpoch != nil {
+		return fmt.Errorf("OriginEpoch this(%v) Not Equal that(%v)", this.OriginEpoch, that1.OriginEpoch)
 	}
 	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
 		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
 	}
 	return nil
 }
-func (this *Neighbor) Equal(that interface{}) bool {
+func (this *UserMessage) Equal(that interface{}) bool {
 	if that == nil {
 		if this == nil {
 			return true
@@ -751,9 +1335,9 @@ func (this *Neighbor) Equal(that interface{}) bool {
 		return false
 	}
 
-	that1, ok := that.(*Neighbor)
+	that1, ok := that.(*UserMessage)
 	if !ok {
-		that2, ok := that.(Neighbor)
+		that2, ok := that.(UserMessage)
 		if ok {
 			that1 = &that2
 		} else {
@@ -777,22 +1361,109 @@ func (this *Neighbor) Equal(that interface{}) bool {
 	} else if that1.Id != nil {
 		return false
 	}
-	if this.Addr != nil && that1.Addr != nil {
-		if *this.Addr != *that1.Addr {
+	if !bytes.Equal(this.Payload, that1.Payload) {
+		return false
+	}
+	if this.Ts != nil && that1.Ts != nil {
+		if *this.Ts != *that1.Ts {
 			return false
 		}
-	} else if this.Addr != nil {
+	} else if this.Ts != nil {
 		return false
-	} else if that1.Addr != nil {
+	} else if that1.Ts != nil {
 		return false
 	}
-	if this.Priority != nil && that1.Priority != nil {
-		if *this.Priority != *that1.Priority {
+	if !bytes.Equal(this.Trace, that1.Trace) {
+		return false
+	}
+	if this.Topic != nil && that1.Topic != nil {
+		if *this.Topic != *that1.Topic {
 			return false
 		}
-	} else if this.Priority != nil {
+	} else if this.Topic != nil {
 		return false
-	} else if that1.Priority != nil {
+	} else if that1.Topic != nil {
+		return false
+	}
+	if this.Seq != nil && that1.Seq != nil {
+		if *this.Seq != *that1.Seq {
+			return false
+		}
+	} else if this.Seq != nil {
+		return false
+	} else if that1.Seq != nil {
+		return false
+	}
+	if this.Hops != nil && that1.Hops != nil {
+		if *this.Hops != *that1.Hops {
+			return false
+		}
+	} else if this.Hops != nil {
+		return false
+	} else if that1.Hops != nil {
+		return false
+	}
+	if this.Life != nil && that1.Life != nil {
+		if *this.Life != *that1.Life {
+			return false
+		}
+	} else if this.Life != nil {
+		return false
+	} else if that1.Life != nil {
+		return false
+	}
+	if !bytes.Equal(this.Key, that1.Key) {
+		return false
+	}
+	if !bytes.Equal(this.Sig, that1.Sig) {
+		return false
+	}
+	if this.ChunkId != nil && that1.ChunkId != nil {
+		if *this.ChunkId != *that1.ChunkId {
+			return false
+		}
+	} else if this.ChunkId != nil {
+		return false
+	} else if that1.ChunkId != nil {
+		return false
+	}
+	if this.ChunkIndex != nil && that1.ChunkIndex != nil {
+		if *this.ChunkIndex != *that1.ChunkIndex {
+			return false
+		}
+	} else if this.ChunkIndex != nil {
+		return false
+	} else if that1.ChunkIndex != nil {
+		return false
+	}
+	if this.ChunkCount != nil && that1.ChunkCount != nil {
+		if *this.ChunkCount != *that1.ChunkCount {
+			return false
+		}
+	} else if this.ChunkCount != nil {
+		return false
+	} else if that1.ChunkCount != nil {
+		return false
+	}
+	if this.OriginSeq != nil && that1.OriginSeq != nil {
+		if *this.OriginSeq != *that1.OriginSeq {
+			return false
+		}
+	} else if this.OriginSeq != nil {
+		return false
+	} else if that1.OriginSeq != nil {
+		return false
+	}
+	if !bytes.Equal(this.ChunkHash, that1.ChunkHash) {
+		return false
+	}
+	if this.OriginEpoch != nil && that1.OriginEpoch != nil {
+		if *this.OriginEpoch != *that1.OriginEpoch {
+			return false
+		}
+	} else if this.OriginEpoch != nil {
+		return false
+	} else if that1.OriginEpoch != nil {
 		return false
 	}
 	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
@@ -800,7 +1471,7 @@ func (this *Neighbor) Equal(that interface{}) bool {
 	}
 	return true
 }
-func (this *NeighborReply) VerboseEqual(that interface{}) error {
+func (this *Label) VerboseEqual(that interface{}) error {
 	if that == nil {
 		if this == nil {
 			return nil
@@ -808,47 +1479,47 @@ func (this *NeighborReply) VerboseEqual(that interface{}) error {
 		return fmt.Errorf("that == nil && this != nil")
 	}
 
-	that1, ok := that.(*NeighborReply)
+	that1, ok := that.(*Label)
 	if !ok {
-		that2, ok := that.(NeighborReply)
+		that2, ok := that.(Label)
 		if ok {
 			that1 = &that2
 		} else {
-			return fmt.Errorf("that is not of type *NeighborReply")
+			return fmt.Errorf("that is not of type *Label")
 		}
 	}
 	if that1 == nil {
 		if this == nil {
 			return nil
 		}
-		return fmt.Errorf("that is type *NeighborReply but is nil && this != nil")
+		return fmt.Errorf("that is type *Label but is nil && this != nil")
 	} else if this == nil {
-		return fmt.Errorf("that is type *NeighborReply but is not nil && this == nil")
+		return fmt.Errorf("that is type *Label but is not nil && this == nil")
 	}
-	if this.Id != nil && that1.Id != nil {
-		if *this.Id != *that1.Id {
-			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
+	if this.Key != nil && that1.Key != nil {
+		if *this.Key != *that1.Key {
+			return fmt.Errorf("Key this(%v) Not Equal that(%v)", *this.Key, *that1.Key)
 		}
-	} else if this.Id != nil {
-		return fmt.Errorf("this.Id == nil && that.Id != nil")
-	} else if that1.Id != nil {
-		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
+	} else if this.Key != nil {
+		return fmt.Errorf("this.Key == nil && that.Key != nil")
+	} else if that1.Key != nil {
+		return fmt.Errorf("Key this(%v) Not Equal that(%v)", this.Key, that1.Key)
 	}
-	if this.Accept != nil && that1.Accept != nil {
-		if *this.Accept != *that1.Accept {
-			return fmt.Errorf("Accept this(%v) Not Equal that(%v)", *this.Accept, *that1.Accept)
+	if this.Value != nil && that1.Value != nil {
+		if *this.Value != *that1.Value {
+			return fmt.Errorf("Value this(%v) Not Equal that(%v)", *this.Value, *that1.Value)
 		}
-	} else if this.Accept != nil {
-		return fmt.Errorf("this.Accept == nil && that.Accept != nil")
-	} else if that1.Accept != nil {
-		return fmt.Errorf("Accept this(%v) Not Equal that(%v)", this.Accept, that1.Accept)
+	} else if this.Value != nil {
+		return fmt.Errorf("this.Value == nil && that.Value != nil")
+	} else if that1.Value != nil {
+		return fmt.Errorf("Value this(%v) Not Equal that(%v)", this.Value, that1.Value)
 	}
 	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
 		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
 	}
 	return nil
 }
-func (this *NeighborReply) Equal(that interface{}) bool {
+func (this *Label) Equal(that interface{}) bool {
 	if that == nil {
 		if this == nil {
 			return true
@@ -856,9 +1527,9 @@ func (this *NeighborReply) Equal(that interface{}) bool {
 		return false
 	}
 
-	that1, ok := that.(*NeighborReply)
+	that1, ok := that.(*Label)
 	if !ok {
-		that2, ok := that.(NeighborReply)
+		that2, ok := that.(Label)
 		if ok {
 			that1 = &that2
 		} else {
@@ -873,22 +1544,22 @@ func (this *NeighborReply) Equal(that interface{}) bool {
 	} else if this == nil {
 		return false
 	}
-	if this.Id != nil && that1.Id != nil {
-		if *this.Id != *that1.Id {
+	if this.Key != nil && that1.Key != nil {
+		if *this.Key != *that1.Key {
 			return false
 		}
-	} else if this.Id != nil {
+	} else if this.Key != nil {
 		return false
-	} else if that1.Id != nil {
+	} else if that1.Key != nil {
 		return false
 	}
-	if this.Accept != nil && that1.Accept != nil {
-		if *this.Accept != *that1.Accept {
+	if this.Value != nil && that1.Value != nil {
+		if *this.Value != *that1.Value {
 			return false
 		}
-	} else if this.Accept != nil {
+	} else if this.Value != nil {
 		return false
-	} else if that1.Accept != nil {
+	} else if that1.Value != nil {
 		return false
 	}
 	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
@@ -896,7 +1567,7 @@ func (this *NeighborReply) Equal(that interface{}) bool {
 	}
 	return true
 }
-func (this *ForwardJoin) VerboseEqual(that interface{}) error {
+func (this *Join) VerboseEqual(that interface{}) error {
 	if that == nil {
 		if this == nil {
 			return nil
@@ -904,22 +1575,22 @@ func (this *ForwardJoin) VerboseEqual(that interface{}) error {
 		return fmt.Errorf("that == nil && this != nil")
 	}
 
-	that1, ok := that.(*ForwardJoin)
+	that1, ok := that.(*Join)
 	if !ok {
-		that2, ok := that.(ForwardJoin)
+		that2, ok := that.(Join)
 		if ok {
 			that1 = &that2
 		} else {
-			return fmt.Errorf("that is not of type *ForwardJoin")
+			return fmt.Errorf("that is not of type *Join")
 		}
 	}
 	if that1 == nil {
 		if this == nil {
 			return nil
 		}
-		return fmt.Errorf("that is type *ForwardJoin but is nil && this != nil")
+		return fmt.Errorf("that is type *Join but is nil && this != nil")
 	} else if this == nil {
-		return fmt.Errorf("that is type *ForwardJoin but is not nil && this == nil")
+		return fmt.Errorf("that is type *Join but is not nil && this == nil")
 	}
 	if this.Id != nil && that1.Id != nil {
 		if *this.Id != *that1.Id {
@@ -930,39 +1601,59 @@ func (this *ForwardJoin) VerboseEqual(that interface{}) error {
 	} else if that1.Id != nil {
 		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
 	}
-	if this.SourceId != nil && that1.SourceId != nil {
-		if *this.SourceId != *that1.SourceId {
-			return fmt.Errorf("SourceId this(%v) Not Equal that(%v)", *this.SourceId, *that1.SourceId)
+	if this.Addr != nil && that1.Addr != nil {
+		if *this.Addr != *that1.Addr {
+			return fmt.Errorf("Addr this(%v) Not Equal that(%v)", *this.Addr, *that1.Addr)
 		}
-	} else if this.SourceId != nil {
-		return fmt.Errorf("this.SourceId == nil && that.SourceId != nil")
-	} else if that1.SourceId != nil {
-		return fmt.Errorf("SourceId this(%v) Not Equal that(%v)", this.SourceId, that1.SourceId)
+	} else if this.Addr != nil {
+		return fmt.Errorf("this.Addr == nil && that.Addr != nil")
+	} else if that1.Addr != nil {
+		return fmt.Errorf("Addr this(%v) Not Equal that(%v)", this.Addr, that1.Addr)
 	}
-	if this.SourceAddr != nil && that1.SourceAddr != nil {
-		if *this.SourceAddr != *that1.SourceAddr {
-			return fmt.Errorf("SourceAddr this(%v) Not Equal that(%v)", *this.SourceAddr, *that1.SourceAddr)
+	if this.Observe != nil && that1.Observe != nil {
+		if *this.Observe != *that1.Observe {
+			return fmt.Errorf("Observe this(%v) Not Equal that(%v)", *this.Observe, *that1.Observe)
 		}
-	} else if this.SourceAddr != nil {
-		return fmt.Errorf("this.SourceAddr == nil && that.SourceAddr != nil")
-	} else if that1.SourceAddr != nil {
-		return fmt.Errorf("SourceAddr this(%v) Not Equal that(%v)", this.SourceAddr, that1.SourceAddr)
+	} else if this.Observe != nil {
+		return fmt.Errorf("this.Observe == nil && that.Observe != nil")
+	} else if that1.Observe != nil {
+		return fmt.Errorf("Observe this(%v) Not Equal that(%v)", this.Observe, that1.Observe)
 	}
-	if this.Ttl != nil && that1.Ttl != nil {
-		if *this.Ttl != *that1.Ttl {
-			return fmt.Errorf("Ttl this(%v) Not Equal that(%v)", *this.Ttl, *that1.Ttl)
+	if len(this.Labels) != len(that1.Labels) {
+		return fmt.Errorf("Labels this(%v) Not Equal that(%v)", len(this.Labels), len(that1.Labels))
+	}
+	for i := range this.Labels {
+		if !this.Labels[i].Equal(that1.Labels[i]) {
+			return fmt.Errorf("Labels this[%v](%v) Not Equal that[%v](%v)", i, this.Labels[i], i, that1.Labels[i])
 		}
-	} else if this.Ttl != nil {
-		return fmt.Errorf("this.Ttl == nil && that.Ttl != nil")
-	} else if that1.Ttl != nil {
-		return fmt.Errorf("Ttl this(%v) Not Equal that(%v)", this.Ttl, that1.Ttl)
+	}
+	if this.Caps != nil && that1.Caps != nil {
+		if *this.Caps != *that1.Caps {
+			return fmt.Errorf("Caps this(%v) Not Equal that(%v)", *this.Caps, *that1.Caps)
+		}
+	} else if this.Caps != nil {
+		return fmt.Errorf("this.Caps == nil && that.Caps != nil")
+	} else if that1.Caps != nil {
+		return fmt.Errorf("Caps this(%v) Not Equal that(%v)", this.Caps, that1.Caps)
+	}
+	if !bytes.Equal(this.Mac, that1.Mac) {
+		return fmt.Errorf("Mac this(%v) Not Equal that(%v)", this.Mac, that1.Mac)
+	}
+	if this.Cluster != nil && that1.Cluster != nil {
+		if *this.Cluster != *that1.Cluster {
+			return fmt.Errorf("Cluster this(%v) Not Equal that(%v)", *this.Cluster, *that1.Cluster)
+		}
+	} else if this.Cluster != nil {
+		return fmt.Errorf("this.Cluster == nil && that.Cluster != nil")
+	} else if that1.Cluster != nil {
+		return fmt.Errorf("Cluster this(%v) Not Equal that(%v)", this.Cluster, that1.Cluster)
 	}
 	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
 		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
 	}
 	return nil
 }
-func (this *ForwardJoin) Equal(that interface{}) bool {
+func (this *Join) Equal(that interface{}) bool {
 	if that == nil {
 		if this == nil {
 			return true
@@ -970,9 +1661,9 @@ func (this *ForwardJoin) Equal(that interface{}) bool {
 		return false
 	}
 
-	that1, ok := that.(*ForwardJoin)
+	that1, ok := that.(*Join)
 	if !ok {
-		that2, ok := that.(ForwardJoin)
+		that2, ok := that.(Join)
 		if ok {
 			that1 = &that2
 		} else {
@@ -996,31 +1687,51 @@ func (this *ForwardJoin) Equal(that interface{}) bool {
 	} else if that1.Id != nil {
 		return false
 	}
-	if this.SourceId != nil && that1.SourceId != nil {
-		if *this.SourceId != *that1.SourceId {
+	if this.Addr != nil && that1.Addr != nil {
+		if *this.Addr != *that1.Addr {
 			return false
 		}
-	} else if this.SourceId != nil {
+	} else if this.Addr != nil {
 		return false
-	} else if that1.SourceId != nil {
+	} else if that1.Addr != nil {
 		return false
 	}
-	if this.SourceAddr != nil && that1.SourceAddr != nil {
-		if *this.SourceAddr != *that1.SourceAddr {
+	if this.Observe != nil && that1.Observe != nil {
+		if *this.Observe != *that1.Observe {
 			return false
 		}
-	} else if this.SourceAddr != nil {
+	} else if this.Observe != nil {
 		return false
-	} else if that1.SourceAddr != nil {
+	} else if that1.Observe != nil {
 		return false
 	}
-	if this.Ttl != nil && that1.Ttl != nil {
-		if *this.Ttl != *that1.Ttl {
+	if len(this.Labels) != len(that1.Labels) {
+		return false
+	}
+	for i := range this.Labels {
+		if !this.Labels[i].Equal(that1.Labels[i]) {
 			return false
 		}
-	} else if this.Ttl != nil {
+	}
+	if this.Caps != nil && that1.Caps != nil {
+		if *this.Caps != *that1.Caps {
+			return false
+		}
+	} else if this.Caps != nil {
 		return false
-	} else if that1.Ttl != nil {
+	} else if that1.Caps != nil {
+		return false
+	}
+	if !bytes.Equal(this.Mac, that1.Mac) {
+		return false
+	}
+	if this.Cluster != nil && that1.Cluster != nil {
+		if *this.Cluster != *that1.Cluster {
+			return false
+		}
+	} else if this.Cluster != nil {
+		return false
+	} else if that1.Cluster != nil {
 		return false
 	}
 	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
@@ -1028,7 +1739,7 @@ func (this *ForwardJoin) Equal(that interface{}) bool {
 	}
 	return true
 }
-func (this *Disconnect) VerboseEqual(that interface{}) error {
+func (this *JoinReply) VerboseEqual(that interface{}) error {
 	if that == nil {
 		if this == nil {
 			return nil
@@ -1036,22 +1747,22 @@ func (this *Disconnect) VerboseEqual(that interface{}) error {
 		return fmt.Errorf("that == nil && this != nil")
 	}
 
-	that1, ok := that.(*Disconnect)
+	that1, ok := that.(*JoinReply)
 	if !ok {
-		that2, ok := that.(Disconnect)
+		that2, ok := that.(JoinReply)
 		if ok {
 			that1 = &that2
 		} else {
-			return fmt.Errorf("that is not of type *Disconnect")
+			return fmt.Errorf("that is not of type *JoinReply")
 		}
 	}
 	if that1 == nil {
 		if this == nil {
 			return nil
 		}
-		return fmt.Errorf("that is type *Disconnect but is nil && this != nil")
+		return fmt.Errorf("that is type *JoinReply but is nil && this != nil")
 	} else if this == nil {
-		return fmt.Errorf("that is type *Disconnect but is not nil && this == nil")
+		return fmt.Errorf("that is type *JoinReply but is not nil && this == nil")
 	}
 	if this.Id != nil && that1.Id != nil {
 		if *this.Id != *that1.Id {
@@ -1062,12 +1773,38 @@ func (this *Disconnect) VerboseEqual(that interface{}) error {
 	} else if that1.Id != nil {
 		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
 	}
+	if this.Accept != nil && that1.Accept != nil {
+		if *this.Accept != *that1.Accept {
+			return fmt.Errorf("Accept this(%v) Not Equal that(%v)", *this.Accept, *that1.Accept)
+		}
+	} else if this.Accept != nil {
+		return fmt.Errorf("this.Accept == nil && that.Accept != nil")
+	} else if that1.Accept != nil {
+		return fmt.Errorf("Accept this(%v) Not Equal that(%v)", this.Accept, that1.Accept)
+	}
+	if len(this.Labels) != len(that1.Labels) {
+		return fmt.Errorf("Labels this(%v) Not Equal that(%v)", len(this.Labels), len(that1.Labels))
+	}
+	for i := range this.Labels {
+		if !this.Labels[i].Equal(that1.Labels[i]) {
+			return fmt.Errorf("Labels this[%v](%v) Not Equal that[%v](%v)", i, this.Labels[i], i, that1.Labels[i])
+		}
+	}
+	if this.Caps != nil && that1.Caps != nil {
+		if *this.Caps != *that1.Caps {
+			return fmt.Errorf("Caps this(%v) Not Equal that(%v)", *this.Caps, *that1.Caps)
+		}
+	} else if this.Caps != nil {
+		return fmt.Errorf("this.Caps == nil && that.Caps != nil")
+	} else if that1.Caps != nil {
+		return fmt.Errorf("Caps this(%v) Not Equal that(%v)", this.Caps, that1.Caps)
+	}
 	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
 		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
 	}
 	return nil
 }
-func (this *Disconnect) Equal(that interface{}) bool {
+func (this *JoinReply) Equal(that interface{}) bool {
 	if that == nil {
 		if this == nil {
 			return true
@@ -1075,9 +1812,9 @@ func (this *Disconnect) Equal(that interface{}) bool {
 		return false
 	}
 
-	that1, ok := that.(*Disconnect)
+	that1, ok := that.(*JoinReply)
 	if !ok {
-		that2, ok := that.(Disconnect)
+		that2, ok := that.(JoinReply)
 		if ok {
 			that1 = &that2
 		} else {
@@ -1101,12 +1838,38 @@ func (this *Disconnect) Equal(that interface{}) bool {
 	} else if that1.Id != nil {
 		return false
 	}
+	if this.Accept != nil && that1.Accept != nil {
+		if *this.Accept != *that1.Accept {
+			return false
+		}
+	} else if this.Accept != nil {
+		return false
+	} else if that1.Accept != nil {
+		return false
+	}
+	if len(this.Labels) != len(that1.Labels) {
+		return false
+	}
+	for i := range this.Labels {
+		if !this.Labels[i].Equal(that1.Labels[i]) {
+			return false
+		}
+	}
+	if this.Caps != nil && that1.Caps != nil {
+		if *this.Caps != *that1.Caps {
+			return false
+		}
+	} else if this.Caps != nil {
+		return false
+	} else if that1.Caps != nil {
+		return false
+	}
 	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
 		return false
 	}
 	return true
 }
-func (this *Candidate) VerboseEqual(that interface{}) error {
+func (this *Neighbor) VerboseEqual(that interface{}) error {
 	if that == nil {
 		if this == nil {
 			return nil
@@ -1114,22 +1877,22 @@ func (this *Candidate) VerboseEqual(that interface{}) error {
 		return fmt.Errorf("that == nil && this != nil")
 	}
 
-	that1, ok := that.(*Candidate)
+	that1, ok := that.(*Neighbor)
 	if !ok {
-		that2, ok := that.(Candidate)
+		that2, ok := that.(Neighbor)
 		if ok {
 			that1 = &that2
 		} else {
-			return fmt.Errorf("that is not of type *Candidate")
+			return fmt.Errorf("that is not of type *Neighbor")
 		}
 	}
 	if that1 == nil {
 		if this == nil {
 			return nil
 		}
-		return fmt.Errorf("that is type *Candidate but is nil && this != nil")
+		return fmt.Errorf("that is type *Neighbor but is nil && this != nil")
 	} else if this == nil {
-		return fmt.Errorf("that is type *Candidate but is not nil && this == nil")
+		return fmt.Errorf("that is type *Neighbor but is not nil && this == nil")
 	}
 	if this.Id != nil && that1.Id != nil {
 		if *this.Id != *that1.Id {
@@ -1149,12 +1912,50 @@ func (this *Candidate) VerboseEqual(that interface{}) error {
 	} else if that1.Addr != nil {
 		return fmt.Errorf("Addr this(%v) Not Equal that(%v)", this.Addr, that1.Addr)
 	}
+	if this.Priority != nil && that1.Priority != nil {
+		if *this.Priority != *that1.Priority {
+			return fmt.Errorf("Priority this(%v) Not Equal that(%v)", *this.Priority, *that1.Priority)
+		}
+	} else if this.Priority != nil {
+		return fmt.Errorf("this.Priority == nil && that.Priority != nil")
+	} else if that1.Priority != nil {
+		return fmt.Errorf("Priority this(%v) Not Equal that(%v)", this.Priority, that1.Priority)
+	}
+	if len(this.Labels) != len(that1.Labels) {
+		return fmt.Errorf("Labels this(%v) Not Equal that(%v)", len(this.Labels), len(that1.Labels))
+	}
+	for i := range this.Labels {
+		if !this.Labels[i].Equal(that1.Labels[i]) {
+			return fmt.Errorf("Labels this[%v](%v) Not Equal that[%v](%v)", i, this.Labels[i], i, that1.Labels[i])
+		}
+	}
+	if this.Caps != nil && that1.Caps != nil {
+		if *this.Caps != *that1.Caps {
+			return fmt.Errorf("Caps this(%v) Not Equal that(%v)", *this.Caps, *that1.Caps)
+		}
+	} else if this.Caps != nil {
+		return fmt.Errorf("this.Caps == nil && that.Caps != nil")
+	} else if that1.Caps != nil {
+		return fmt.Errorf("Caps this(%v) Not Equal that(%v)", this.Caps, that1.Caps)
+	}
+	if !bytes.Equal(this.Mac, that1.Mac) {
+		return fmt.Errorf("Mac this(%v) Not Equal that(%v)", this.Mac, that1.Mac)
+	}
+	if this.Cluster != nil && that1.Cluster != nil {
+		if *this.Cluster != *that1.Cluster {
+			return fmt.Errorf("Cluster this(%v) Not Equal that(%v)", *this.Cluster, *that1.Cluster)
+		}
+	} else if this.Cluster != nil {
+		return fmt.Errorf("this.Cluster == nil && that.Cluster != nil")
+	} else if that1.Cluster != nil {
+		return fmt.Errorf("Cluster this(%v) Not Equal that(%v)", this.Cluster, that1.Cluster)
+	}
 	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
 		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
 	}
 	return nil
 }
-func (this *Candidate) Equal(that interface{}) bool {
+func (this *Neighbor) Equal(that interface{}) bool {
 	if that == nil {
 		if this == nil {
 			return true
@@ -1162,9 +1963,9 @@ func (this *Candidate) Equal(that interface{}) bool {
 		return false
 	}
 
-	that1, ok := that.(*Candidate)
+	that1, ok := that.(*Neighbor)
 	if !ok {
-		that2, ok := that.(Candidate)
+		that2, ok := that.(Neighbor)
 		if ok {
 			that1 = &that2
 		} else {
@@ -1197,12 +1998,50 @@ func (this *Candidate) Equal(that interface{}) bool {
 	} else if that1.Addr != nil {
 		return false
 	}
-	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+	if this.Priority != nil && that1.Priority != nil {
+		if *this.Priority != *that1.Priority {
+			return false
+		}
+	} else if this.Priority != nil {
+		return false
+	} else if that1.Priority != nil {
 		return false
 	}
-	return true
-}
-func (this *Shuffle) VerboseEqual(that interface{}) error {
+	if len(this.Labels) != len(that1.Labels) {
+		return false
+	}
+	for i := range this.Labels {
+		if !this.Labels[i].Equal(that1.Labels[i]) {
+			return false
+		}
+	}
+	if this.Caps != nil && that1.Caps != nil {
+		if *this.Caps != *that1.Caps {
+			return false
+		}
+	} else if this.Caps != nil {
+		return false
+	} else if that1.Caps != nil {
+		return false
+	}
+	if !bytes.Equal(this.Mac, that1.Mac) {
+		return false
+	}
+	if this.Cluster != nil && that1.Cluster != nil {
+		if *this.Cluster != *that1.Cluster {
+			return false
+		}
+	} else if this.Cluster != nil {
+		return false
+	} else if that1.Cluster != nil {
+		return false
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
+	}
+	return true
+}
+func (this *NeighborReply) VerboseEqual(that interface{}) error {
 	if that == nil {
 		if this == nil {
 			return nil
@@ -1210,22 +2049,152 @@ func (this *Shuffle) VerboseEqual(that interface{}) error {
 		return fmt.Errorf("that == nil && this != nil")
 	}
 
-	that1, ok := that.(*Shuffle)
+	that1, ok := that.(*NeighborReply)
 	if !ok {
-		that2, ok := that.(Shuffle)
+		that2, ok := that.(NeighborReply)
 		if ok {
 			that1 = &that2
 		} else {
-			return fmt.Errorf("that is not of type *Shuffle")
+			return fmt.Errorf("that is not of type *NeighborReply")
 		}
 	}
 	if that1 == nil {
 		if this == nil {
 			return nil
 		}
-		return fmt.Errorf("that is type *Shuffle but is nil && this != nil")
+		return fmt.Errorf("that is type *NeighborReply but is nil && this != nil")
 	} else if this == nil {
-		return fmt.Errorf("that is type *Shuffle but is not nil && this == nil")
+		return fmt.Errorf("that is type *NeighborReply but is not nil && this == nil")
+	}
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
+		}
+	} else if this.Id != nil {
+		return fmt.Errorf("this.Id == nil && that.Id != nil")
+	} else if that1.Id != nil {
+		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
+	}
+	if this.Accept != nil && that1.Accept != nil {
+		if *this.Accept != *that1.Accept {
+			return fmt.Errorf("Accept this(%v) Not Equal that(%v)", *this.Accept, *that1.Accept)
+		}
+	} else if this.Accept != nil {
+		return fmt.Errorf("this.Accept == nil && that.Accept != nil")
+	} else if that1.Accept != nil {
+		return fmt.Errorf("Accept this(%v) Not Equal that(%v)", this.Accept, that1.Accept)
+	}
+	if len(this.Labels) != len(that1.Labels) {
+		return fmt.Errorf("Labels this(%v) Not Equal that(%v)", len(this.Labels), len(that1.Labels))
+	}
+	for i := range this.Labels {
+		if !this.Labels[i].Equal(that1.Labels[i]) {
+			return fmt.Errorf("Labels this[%v](%v) Not Equal that[%v](%v)", i, this.Labels[i], i, that1.Labels[i])
+		}
+	}
+	if this.Caps != nil && that1.Caps != nil {
+		if *this.Caps != *that1.Caps {
+			return fmt.Errorf("Caps this(%v) Not Equal that(%v)", *this.Caps, *that1.Caps)
+		}
+	} else if this.Caps != nil {
+		return fmt.Errorf("this.Caps == nil && that.Caps != nil")
+	} else if that1.Caps != nil {
+		return fmt.Errorf("Caps this(%v) Not Equal that(%v)", this.Caps, that1.Caps)
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
+	}
+	return nil
+}
+func (this *NeighborReply) Equal(that interface{}) bool {
+	if that == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	}
+
+	that1, ok := that.(*NeighborReply)
+	if !ok {
+		that2, ok := that.(NeighborReply)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	} else if this == nil {
+		return false
+	}
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return false
+		}
+	} else if this.Id != nil {
+		return false
+	} else if that1.Id != nil {
+		return false
+	}
+	if this.Accept != nil && that1.Accept != nil {
+		if *this.Accept != *that1.Accept {
+			return false
+		}
+	} else if this.Accept != nil {
+		return false
+	} else if that1.Accept != nil {
+		return false
+	}
+	if len(this.Labels) != len(that1.Labels) {
+		return false
+	}
+	for i := range this.Labels {
+		if !this.Labels[i].Equal(that1.Labels[i]) {
+			return false
+		}
+	}
+	if this.Caps != nil && that1.Caps != nil {
+		if *this.Caps != *that1.Caps {
+			return false
+		}
+	} else if this.Caps != nil {
+		return false
+	} else if that1.Caps != nil {
+		return false
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
+	}
+	return true
+}
+func (this *ForwardJoin) VerboseEqual(that interface{}) error {
+	if that == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that == nil && this != nil")
+	}
+
+	that1, ok := that.(*ForwardJoin)
+	if !ok {
+		that2, ok := that.(ForwardJoin)
+		if ok {
+			that1 = &that2
+		} else {
+			return fmt.Errorf("that is not of type *ForwardJoin")
+		}
+	}
+	if that1 == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that is type *ForwardJoin but is nil && this != nil")
+	} else if this == nil {
+		return fmt.Errorf("that is type *ForwardJoin but is not nil && this == nil")
 	}
 	if this.Id != nil && that1.Id != nil {
 		if *this.Id != *that1.Id {
@@ -1245,22 +2214,14 @@ func (this *Shuffle) VerboseEqual(that interface{}) error {
 	} else if that1.SourceId != nil {
 		return fmt.Errorf("SourceId this(%v) Not Equal that(%v)", this.SourceId, that1.SourceId)
 	}
-	if this.Addr != nil && that1.Addr != nil {
-		if *this.Addr != *that1.Addr {
-			return fmt.Errorf("Addr this(%v) Not Equal that(%v)", *this.Addr, *that1.Addr)
-		}
-	} else if this.Addr != nil {
-		return fmt.Errorf("this.Addr == nil && that.Addr != nil")
-	} else if that1.Addr != nil {
-		return fmt.Errorf("Addr this(%v) Not Equal that(%v)", this.Addr, that1.Addr)
-	}
-	if len(this.Candidates) != len(that1.Candidates) {
-		return fmt.Errorf("Candidates this(%v) Not Equal that(%v)", len(this.Candidates), len(that1.Candidates))
-	}
-	for i := range this.Candidates {
-		if !this.Candidates[i].Equal(that1.Candidates[i]) {
-			return fmt.Errorf("Candidates this[%v](%v) Not Equal that[%v](%v)", i, this.Candidates[i], i, that1.Candidates[i])
+	if this.SourceAddr != nil && that1.SourceAddr != nil {
+		if *this.SourceAddr != *that1.SourceAddr {
+			return fmt.Errorf("SourceAddr this(%v) Not Equal that(%v)", *this.SourceAddr, *that1.SourceAddr)
 		}
+	} else if this.SourceAddr != nil {
+		return fmt.Errorf("this.SourceAddr == nil && that.SourceAddr != nil")
+	} else if that1.SourceAddr != nil {
+		return fmt.Errorf("SourceAddr this(%v) Not Equal that(%v)", this.SourceAddr, that1.SourceAddr)
 	}
 	if this.Ttl != nil && that1.Ttl != nil {
 		if *this.Ttl != *that1.Ttl {
@@ -1271,12 +2232,20 @@ func (this *Shuffle) VerboseEqual(that interface{}) error {
 	} else if that1.Ttl != nil {
 		return fmt.Errorf("Ttl this(%v) Not Equal that(%v)", this.Ttl, that1.Ttl)
 	}
+	if len(this.SourceLabels) != len(that1.SourceLabels) {
+		return fmt.Errorf("SourceLabels this(%v) Not Equal that(%v)", len(this.SourceLabels), len(that1.SourceLabels))
+	}
+	for i := range this.SourceLabels {
+		if !this.SourceLabels[i].Equal(that1.SourceLabels[i]) {
+			return fmt.Errorf("SourceLabels this[%v](%v) Not Equal that[%v](%v)", i, this.SourceLabels[i], i, that1.SourceLabels[i])
+		}
+	}
 	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
 		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
 	}
 	return nil
 }
-func (this *Shuffle) Equal(that interface{}) bool {
+func (this *ForwardJoin) Equal(that interface{}) bool {
 	if that == nil {
 		if this == nil {
 			return true
@@ -1284,9 +2253,9 @@ func (this *Shuffle) Equal(that interface{}) bool {
 		return false
 	}
 
-	that1, ok := that.(*Shuffle)
+	that1, ok := that.(*ForwardJoin)
 	if !ok {
-		that2, ok := that.(Shuffle)
+		that2, ok := that.(ForwardJoin)
 		if ok {
 			that1 = &that2
 		} else {
@@ -1319,23 +2288,15 @@ func (this *Shuffle) Equal(that interface{}) bool {
 	} else if that1.SourceId != nil {
 		return false
 	}
-	if this.Addr != nil && that1.Addr != nil {
-		if *this.Addr != *that1.Addr {
+	if this.SourceAddr != nil && that1.SourceAddr != nil {
+		if *this.SourceAddr != *that1.SourceAddr {
 			return false
 		}
-	} else if this.Addr != nil {
-		return false
-	} else if that1.Addr != nil {
+	} else if this.SourceAddr != nil {
 		return false
-	}
-	if len(this.Candidates) != len(that1.Candidates) {
+	} else if that1.SourceAddr != nil {
 		return false
 	}
-	for i := range this.Candidates {
-		if !this.Candidates[i].Equal(that1.Candidates[i]) {
-			return false
-		}
-	}
 	if this.Ttl != nil && that1.Ttl != nil {
 		if *this.Ttl != *that1.Ttl {
 			return false
@@ -1345,12 +2306,20 @@ func (this *Shuffle) Equal(that interface{}) bool {
 	} else if that1.Ttl != nil {
 		return false
 	}
+	if len(this.SourceLabels) != len(that1.SourceLabels) {
+		return false
+	}
+	for i := range this.SourceLabels {
+		if !this.SourceLabels[i].Equal(that1.SourceLabels[i]) {
+			return false
+		}
+	}
 	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
 		return false
 	}
 	return true
 }
-func (this *ShuffleReply) VerboseEqual(that interface{}) error {
+func (this *Disconnect) VerboseEqual(that interface{}) error {
 	if that == nil {
 		if this == nil {
 			return nil
@@ -1358,22 +2327,22 @@ func (this *ShuffleReply) VerboseEqual(that interface{}) error {
 		return fmt.Errorf("that == nil && this != nil")
 	}
 
-	that1, ok := that.(*ShuffleReply)
+	that1, ok := that.(*Disconnect)
 	if !ok {
-		that2, ok := that.(ShuffleReply)
+		that2, ok := that.(Disconnect)
 		if ok {
 			that1 = &that2
 		} else {
-			return fmt.Errorf("that is not of type *ShuffleReply")
+			return fmt.Errorf("that is not of type *Disconnect")
 		}
 	}
 	if that1 == nil {
 		if this == nil {
 			return nil
 		}
-		return fmt.Errorf("that is type *ShuffleReply but is nil && this != nil")
+		return fmt.Errorf("that is type *Disconnect but is nil && this != nil")
 	} else if this == nil {
-		return fmt.Errorf("that is type *ShuffleReply but is not nil && this == nil")
+		return fmt.Errorf("that is type *Disconnect but is not nil && this == nil")
 	}
 	if this.Id != nil && that1.Id != nil {
 		if *this.Id != *that1.Id {
@@ -1384,20 +2353,12 @@ func (this *ShuffleReply) VerboseEqual(that interface{}) error {
 	} else if that1.Id != nil {
 		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
 	}
-	if len(this.Candidates) != len(that1.Candidates) {
-		return fmt.Errorf("Candidates this(%v) Not Equal that(%v)", len(this.Candidates), len(that1.Candidates))
-	}
-	for i := range this.Candidates {
-		if !this.Candidates[i].Equal(that1.Candidates[i]) {
-			return fmt.Errorf("Candidates this[%v](%v) Not Equal that[%v](%v)", i, this.Candidates[i], i, that1.Candidates[i])
-		}
-	}
 	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
 		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
 	}
 	return nil
 }
-func (this *ShuffleReply) Equal(that interface{}) bool {
+func (this *Disconnect) Equal(that interface{}) bool {
 	if that == nil {
 		if this == nil {
 			return true
@@ -1405,9 +2366,9 @@ func (this *ShuffleReply) Equal(that interface{}) bool {
 		return false
 	}
 
-	that1, ok := that.(*ShuffleReply)
+	that1, ok := that.(*Disconnect)
 	if !ok {
-		that2, ok := that.(ShuffleReply)
+		that2, ok := that.(Disconnect)
 		if ok {
 			that1 = &that2
 		} else {
@@ -1431,1218 +2392,8444 @@ func (this *ShuffleReply) Equal(that interface{}) bool {
 	} else if that1.Id != nil {
 		return false
 	}
-	if len(this.Candidates) != len(that1.Candidates) {
-		return false
-	}
-	for i := range this.Candidates {
-		if !this.Candidates[i].Equal(that1.Candidates[i]) {
-			return false
-		}
-	}
 	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
 		return false
 	}
 	return true
 }
-func (this *UserMessage) GoString() string {
-	if this == nil {
-		return "nil"
-	}
-	s := make([]string, 0, 7)
-	s = append(s, "&message.UserMessage{")
-	if this.Id != nil {
-		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+func (this *Candidate) VerboseEqual(that interface{}) error {
+	if that == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that == nil && this != nil")
 	}
-	if this.Payload != nil {
-		s = append(s, "Payload: "+valueToGoStringMessage(this.Payload, "byte")+",\n")
+
+	that1, ok := that.(*Candidate)
+	if !ok {
+		that2, ok := that.(Candidate)
+		if ok {
+			that1 = &that2
+		} else {
+			return fmt.Errorf("that is not of type *Candidate")
+		}
 	}
-	if this.Ts != nil {
-		s = append(s, "Ts: "+valueToGoStringMessage(this.Ts, "int64")+",\n")
+	if that1 == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that is type *Candidate but is nil && this != nil")
+	} else if this == nil {
+		return fmt.Errorf("that is type *Candidate but is not nil && this == nil")
 	}
-	if this.XXX_unrecognized != nil {
-		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
+		}
+	} else if this.Id != nil {
+		return fmt.Errorf("this.Id == nil && that.Id != nil")
+	} else if that1.Id != nil {
+		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
 	}
-	s = append(s, "}")
-	return strings.Join(s, "")
-}
-func (this *Join) GoString() string {
-	if this == nil {
-		return "nil"
+	if this.Addr != nil && that1.Addr != nil {
+		if *this.Addr != *that1.Addr {
+			return fmt.Errorf("Addr this(%v) Not Equal that(%v)", *this.Addr, *that1.Addr)
+		}
+	} else if this.Addr != nil {
+		return fmt.Errorf("this.Addr == nil && that.Addr != nil")
+	} else if that1.Addr != nil {
+		return fmt.Errorf("Addr this(%v) Not Equal that(%v)", this.Addr, that1.Addr)
 	}
-	s := make([]string, 0, 6)
-	s = append(s, "&message.Join{")
-	if this.Id != nil {
-		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	if len(this.Labels) != len(that1.Labels) {
+		return fmt.Errorf("Labels this(%v) Not Equal that(%v)", len(this.Labels), len(that1.Labels))
 	}
-	if this.Addr != nil {
-		s = append(s, "Addr: "+valueToGoStringMessage(this.Addr, "string")+",\n")
+	for i := range this.Labels {
+		if !this.Labels[i].Equal(that1.Labels[i]) {
+			return fmt.Errorf("Labels this[%v](%v) Not Equal that[%v](%v)", i, this.Labels[i], i, that1.Labels[i])
+		}
 	}
-	if this.XXX_unrecognized != nil {
-		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
 	}
-	s = append(s, "}")
-	return strings.Join(s, "")
+	return nil
 }
-func (this *JoinReply) GoString() string {
-	if this == nil {
-		return "nil"
-	}
-	s := make([]string, 0, 6)
-	s = append(s, "&message.JoinReply{")
-	if this.Id != nil {
-		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+func (this *Candidate) Equal(that interface{}) bool {
+	if that == nil {
+		if this == nil {
+			return true
+		}
+		return false
 	}
-	if this.Accept != nil {
-		s = append(s, "Accept: "+valueToGoStringMessage(this.Accept, "bool")+",\n")
+
+	that1, ok := that.(*Candidate)
+	if !ok {
+		that2, ok := that.(Candidate)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
 	}
-	if this.XXX_unrecognized != nil {
-		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	if that1 == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	} else if this == nil {
+		return false
 	}
-	s = append(s, "}")
-	return strings.Join(s, "")
-}
-func (this *Neighbor) GoString() string {
-	if this == nil {
-		return "nil"
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return false
+		}
+	} else if this.Id != nil {
+		return false
+	} else if that1.Id != nil {
+		return false
 	}
-	s := make([]string, 0, 7)
-	s = append(s, "&message.Neighbor{")
-	if this.Id != nil {
-		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	if this.Addr != nil && that1.Addr != nil {
+		if *this.Addr != *that1.Addr {
+			return false
+		}
+	} else if this.Addr != nil {
+		return false
+	} else if that1.Addr != nil {
+		return false
 	}
-	if this.Addr != nil {
-		s = append(s, "Addr: "+valueToGoStringMessage(this.Addr, "string")+",\n")
+	if len(this.Labels) != len(that1.Labels) {
+		return false
 	}
-	if this.Priority != nil {
-		s = append(s, "Priority: "+valueToGoStringMessage(this.Priority, "message.Neighbor_Priority")+",\n")
+	for i := range this.Labels {
+		if !this.Labels[i].Equal(that1.Labels[i]) {
+			return false
+		}
 	}
-	if this.XXX_unrecognized != nil {
-		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
 	}
-	s = append(s, "}")
-	return strings.Join(s, "")
+	return true
 }
-func (this *NeighborReply) GoString() string {
-	if this == nil {
-		return "nil"
+func (this *Shuffle) VerboseEqual(that interface{}) error {
+	if that == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that == nil && this != nil")
 	}
-	s := make([]string, 0, 6)
-	s = append(s, "&message.NeighborReply{")
-	if this.Id != nil {
-		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+
+	that1, ok := that.(*Shuffle)
+	if !ok {
+		that2, ok := that.(Shuffle)
+		if ok {
+			that1 = &that2
+		} else {
+			return fmt.Errorf("that is not of type *Shuffle")
+		}
 	}
-	if this.Accept != nil {
-		s = append(s, "Accept: "+valueToGoStringMessage(this.Accept, "bool")+",\n")
+	if that1 == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that is type *Shuffle but is nil && this != nil")
+	} else if this == nil {
+		return fmt.Errorf("that is type *Shuffle but is not nil && this == nil")
 	}
-	if this.XXX_unrecognized != nil {
-		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
+		}
+	} else if this.Id != nil {
+		return fmt.Errorf("this.Id == nil && that.Id != nil")
+	} else if that1.Id != nil {
+		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
 	}
-	s = append(s, "}")
-	return strings.Join(s, "")
-}
-func (this *ForwardJoin) GoString() string {
-	if this == nil {
-		return "nil"
+	if this.SourceId != nil && that1.SourceId != nil {
+		if *this.SourceId != *that1.SourceId {
+			return fmt.Errorf("SourceId this(%v) Not Equal that(%v)", *this.SourceId, *that1.SourceId)
+		}
+	} else if this.SourceId != nil {
+		return fmt.Errorf("this.SourceId == nil && that.SourceId != nil")
+	} else if that1.SourceId != nil {
+		return fmt.Errorf("SourceId this(%v) Not Equal that(%v)", this.SourceId, that1.SourceId)
 	}
-	s := make([]string, 0, 8)
-	s = append(s, "&message.ForwardJoin{")
-	if this.Id != nil {
-		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	if this.Addr != nil && that1.Addr != nil {
+		if *this.Addr != *that1.Addr {
+			return fmt.Errorf("Addr this(%v) Not Equal that(%v)", *this.Addr, *that1.Addr)
+		}
+	} else if this.Addr != nil {
+		return fmt.Errorf("this.Addr == nil && that.Addr != nil")
+	} else if that1.Addr != nil {
+		return fmt.Errorf("Addr this(%v) Not Equal that(%v)", this.Addr, that1.Addr)
 	}
-	if this.SourceId != nil {
-		s = append(s, "SourceId: "+valueToGoStringMessage(this.SourceId, "uint64")+",\n")
+	if len(this.Candidates) != len(that1.Candidates) {
+		return fmt.Errorf("Candidates this(%v) Not Equal that(%v)", len(this.Candidates), len(that1.Candidates))
 	}
-	if this.SourceAddr != nil {
-		s = append(s, "SourceAddr: "+valueToGoStringMessage(this.SourceAddr, "string")+",\n")
+	for i := range this.Candidates {
+		if !this.Candidates[i].Equal(that1.Candidates[i]) {
+			return fmt.Errorf("Candidates this[%v](%v) Not Equal that[%v](%v)", i, this.Candidates[i], i, that1.Candidates[i])
+		}
 	}
-	if this.Ttl != nil {
-		s = append(s, "Ttl: "+valueToGoStringMessage(this.Ttl, "uint32")+",\n")
+	if this.Ttl != nil && that1.Ttl != nil {
+		if *this.Ttl != *that1.Ttl {
+			return fmt.Errorf("Ttl this(%v) Not Equal that(%v)", *this.Ttl, *that1.Ttl)
+		}
+	} else if this.Ttl != nil {
+		return fmt.Errorf("this.Ttl == nil && that.Ttl != nil")
+	} else if that1.Ttl != nil {
+		return fmt.Errorf("Ttl this(%v) Not Equal that(%v)", this.Ttl, that1.Ttl)
 	}
-	if this.XXX_unrecognized != nil {
-		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	if this.Udp != nil && that1.Udp != nil {
+		if *this.Udp != *that1.Udp {
+			return fmt.Errorf("Udp this(%v) Not Equal that(%v)", *this.Udp, *that1.Udp)
+		}
+	} else if this.Udp != nil {
+		return fmt.Errorf("this.Udp == nil && that.Udp != nil")
+	} else if that1.Udp != nil {
+		return fmt.Errorf("Udp this(%v) Not Equal that(%v)", this.Udp, that1.Udp)
 	}
-	s = append(s, "}")
-	return strings.Join(s, "")
-}
-func (this *Disconnect) GoString() string {
-	if this == nil {
-		return "nil"
+	if this.Cluster != nil && that1.Cluster != nil {
+		if *this.Cluster != *that1.Cluster {
+			return fmt.Errorf("Cluster this(%v) Not Equal that(%v)", *this.Cluster, *that1.Cluster)
+		}
+	} else if this.Cluster != nil {
+		return fmt.Errorf("this.Cluster == nil && that.Cluster != nil")
+	} else if that1.Cluster != nil {
+		return fmt.Errorf("Cluster this(%v) Not Equal that(%v)", this.Cluster, that1.Cluster)
 	}
-	s := make([]string, 0, 5)
-	s = append(s, "&message.Disconnect{")
-	if this.Id != nil {
-		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	if this.Nonce != nil && that1.Nonce != nil {
+		if *this.Nonce != *that1.Nonce {
+			return fmt.Errorf("Nonce this(%v) Not Equal that(%v)", *this.Nonce, *that1.Nonce)
+		}
+	} else if this.Nonce != nil {
+		return fmt.Errorf("this.Nonce == nil && that.Nonce != nil")
+	} else if that1.Nonce != nil {
+		return fmt.Errorf("Nonce this(%v) Not Equal that(%v)", this.Nonce, that1.Nonce)
 	}
-	if this.XXX_unrecognized != nil {
-		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
 	}
-	s = append(s, "}")
-	return strings.Join(s, "")
+	return nil
 }
-func (this *Candidate) GoString() string {
-	if this == nil {
-		return "nil"
-	}
-	s := make([]string, 0, 6)
-	s = append(s, "&message.Candidate{")
-	if this.Id != nil {
-		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+func (this *Shuffle) Equal(that interface{}) bool {
+	if that == nil {
+		if this == nil {
+			return true
+		}
+		return false
 	}
-	if this.Addr != nil {
-		s = append(s, "Addr: "+valueToGoStringMessage(this.Addr, "string")+",\n")
+
+	that1, ok := that.(*Shuffle)
+	if !ok {
+		that2, ok := that.(Shuffle)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
 	}
-	if this.XXX_unrecognized != nil {
-		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
-	}
-	s = append(s, "}")
-	return strings.Join(s, "")
-}
-func (this *Shuffle) GoString() string {
-	if this == nil {
-		return "nil"
-	}
-	s := make([]string, 0, 9)
-	s = append(s, "&message.Shuffle{")
-	if this.Id != nil {
-		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	if that1 == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	} else if this == nil {
+		return false
 	}
-	if this.SourceId != nil {
-		s = append(s, "SourceId: "+valueToGoStringMessage(this.SourceId, "uint64")+",\n")
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return false
+		}
+	} else if this.Id != nil {
+		return false
+	} else if that1.Id != nil {
+		return false
 	}
-	if this.Addr != nil {
-		s = append(s, "Addr: "+valueToGoStringMessage(this.Addr, "string")+",\n")
+	if this.SourceId != nil && that1.SourceId != nil {
+		if *this.SourceId != *that1.SourceId {
+			return false
+		}
+	} else if this.SourceId != nil {
+		return false
+	} else if that1.SourceId != nil {
+		return false
 	}
-	if this.Candidates != nil {
-		s = append(s, "Candidates: "+fmt.Sprintf("%#v", this.Candidates)+",\n")
+	if this.Addr != nil && that1.Addr != nil {
+		if *this.Addr != *that1.Addr {
+			return false
+		}
+	} else if this.Addr != nil {
+		return false
+	} else if that1.Addr != nil {
+		return false
 	}
-	if this.Ttl != nil {
-		s = append(s, "Ttl: "+valueToGoStringMessage(this.Ttl, "uint32")+",\n")
+	if len(this.Candidates) != len(that1.Candidates) {
+		return false
 	}
-	if this.XXX_unrecognized != nil {
-		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	for i := range this.Candidates {
+		if !this.Candidates[i].Equal(that1.Candidates[i]) {
+			return false
+		}
 	}
-	s = append(s, "}")
-	return strings.Join(s, "")
-}
-func (this *ShuffleReply) GoString() string {
-	if this == nil {
-		return "nil"
+	if this.Ttl != nil && that1.Ttl != nil {
+		if *this.Ttl != *that1.Ttl {
+			return false
+		}
+	} else if this.Ttl != nil {
+		return false
+	} else if that1.Ttl != nil {
+		return false
 	}
-	s := make([]string, 0, 6)
-	s = append(s, "&message.ShuffleReply{")
-	if this.Id != nil {
-		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	if this.Udp != nil && that1.Udp != nil {
+		if *this.Udp != *that1.Udp {
+			return false
+		}
+	} else if this.Udp != nil {
+		return false
+	} else if that1.Udp != nil {
+		return false
 	}
-	if this.Candidates != nil {
-		s = append(s, "Candidates: "+fmt.Sprintf("%#v", this.Candidates)+",\n")
+	if this.Cluster != nil && that1.Cluster != nil {
+		if *this.Cluster != *that1.Cluster {
+			return false
+		}
+	} else if this.Cluster != nil {
+		return false
+	} else if that1.Cluster != nil {
+		return false
 	}
-	if this.XXX_unrecognized != nil {
-		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	if this.Nonce != nil && that1.Nonce != nil {
+		if *this.Nonce != *that1.Nonce {
+			return false
+		}
+	} else if this.Nonce != nil {
+		return false
+	} else if that1.Nonce != nil {
+		return false
 	}
-	s = append(s, "}")
-	return strings.Join(s, "")
-}
-func valueToGoStringMessage(v interface{}, typ string) string {
-	rv := reflect.ValueOf(v)
-	if rv.IsNil() {
-		return "nil"
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
 	}
-	pv := reflect.Indirect(rv).Interface()
-	return fmt.Sprintf("func(v %v) *%v { return &v } ( %#v )", typ, typ, pv)
+	return true
 }
-func (m *UserMessage) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+func (this *ShuffleReply) VerboseEqual(that interface{}) error {
+	if that == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that == nil && this != nil")
 	}
-	return dAtA[:n], nil
-}
 
-func (m *UserMessage) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if m.Id == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
-	} else {
-		dAtA[i] = 0x8
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
-	}
-	if m.Payload != nil {
-		dAtA[i] = 0x12
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(len(m.Payload)))
-		i += copy(dAtA[i:], m.Payload)
+	that1, ok := that.(*ShuffleReply)
+	if !ok {
+		that2, ok := that.(ShuffleReply)
+		if ok {
+			that1 = &that2
+		} else {
+			return fmt.Errorf("that is not of type *ShuffleReply")
+		}
 	}
-	if m.Ts == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("ts")
-	} else {
-		dAtA[i] = 0x18
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(*m.Ts))
+	if that1 == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that is type *ShuffleReply but is nil && this != nil")
+	} else if this == nil {
+		return fmt.Errorf("that is type *ShuffleReply but is not nil && this == nil")
 	}
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
+		}
+	} else if this.Id != nil {
+		return fmt.Errorf("this.Id == nil && that.Id != nil")
+	} else if that1.Id != nil {
+		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
 	}
-	return i, nil
-}
-
-func (m *Join) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+	if len(this.Candidates) != len(that1.Candidates) {
+		return fmt.Errorf("Candidates this(%v) Not Equal that(%v)", len(this.Candidates), len(that1.Candidates))
 	}
-	return dAtA[:n], nil
-}
-
-func (m *Join) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if m.Id == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
-	} else {
-		dAtA[i] = 0x8
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	for i := range this.Candidates {
+		if !this.Candidates[i].Equal(that1.Candidates[i]) {
+			return fmt.Errorf("Candidates this[%v](%v) Not Equal that[%v](%v)", i, this.Candidates[i], i, that1.Candidates[i])
+		}
 	}
-	if m.Addr == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
-	} else {
-		dAtA[i] = 0x12
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Addr)))
-		i += copy(dAtA[i:], *m.Addr)
+	if this.Nonce != nil && that1.Nonce != nil {
+		if *this.Nonce != *that1.Nonce {
+			return fmt.Errorf("Nonce this(%v) Not Equal that(%v)", *this.Nonce, *that1.Nonce)
+		}
+	} else if this.Nonce != nil {
+		return fmt.Errorf("this.Nonce == nil && that.Nonce != nil")
+	} else if that1.Nonce != nil {
+		return fmt.Errorf("Nonce this(%v) Not Equal that(%v)", this.Nonce, that1.Nonce)
 	}
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
 	}
-	return i, nil
+	return nil
 }
-
-func (m *JoinReply) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+func (this *ShuffleReply) Equal(that interface{}) bool {
+	if that == nil {
+		if this == nil {
+			return true
+		}
+		return false
 	}
-	return dAtA[:n], nil
-}
 
-func (m *JoinReply) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if m.Id == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
-	} else {
-		dAtA[i] = 0x8
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
-	}
-	if m.Accept == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("accept")
-	} else {
-		dAtA[i] = 0x10
-		i++
-		if *m.Accept {
-			dAtA[i] = 1
+	that1, ok := that.(*ShuffleReply)
+	if !ok {
+		that2, ok := that.(ShuffleReply)
+		if ok {
+			that1 = &that2
 		} else {
-			dAtA[i] = 0
+			return false
 		}
-		i++
 	}
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
-	}
-	return i, nil
-}
-
-func (m *Neighbor) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+	if that1 == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	} else if this == nil {
+		return false
 	}
-	return dAtA[:n], nil
-}
-
-func (m *Neighbor) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if m.Id == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
-	} else {
-		dAtA[i] = 0x8
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return false
+		}
+	} else if this.Id != nil {
+		return false
+	} else if that1.Id != nil {
+		return false
 	}
-	if m.Addr == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
-	} else {
-		dAtA[i] = 0x12
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Addr)))
-		i += copy(dAtA[i:], *m.Addr)
+	if len(this.Candidates) != len(that1.Candidates) {
+		return false
 	}
-	if m.Priority == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("priority")
-	} else {
-		dAtA[i] = 0x18
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(*m.Priority))
+	for i := range this.Candidates {
+		if !this.Candidates[i].Equal(that1.Candidates[i]) {
+			return false
+		}
 	}
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+	if this.Nonce != nil && that1.Nonce != nil {
+		if *this.Nonce != *that1.Nonce {
+			return false
+		}
+	} else if this.Nonce != nil {
+		return false
+	} else if that1.Nonce != nil {
+		return false
 	}
-	return i, nil
-}
-
-func (m *NeighborReply) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
 	}
-	return dAtA[:n], nil
+	return true
 }
-
-func (m *NeighborReply) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if m.Id == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
-	} else {
-		dAtA[i] = 0x8
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+func (this *Request) VerboseEqual(that interface{}) error {
+	if that == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that == nil && this != nil")
 	}
-	if m.Accept == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("accept")
-	} else {
-		dAtA[i] = 0x10
-		i++
-		if *m.Accept {
-			dAtA[i] = 1
+
+	that1, ok := that.(*Request)
+	if !ok {
+		that2, ok := that.(Request)
+		if ok {
+			that1 = &that2
 		} else {
-			dAtA[i] = 0
+			return fmt.Errorf("that is not of type *Request")
 		}
-		i++
 	}
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+	if that1 == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that is type *Request but is nil && this != nil")
+	} else if this == nil {
+		return fmt.Errorf("that is type *Request but is not nil && this == nil")
 	}
-	return i, nil
-}
-
-func (m *ForwardJoin) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
+		}
+	} else if this.Id != nil {
+		return fmt.Errorf("this.Id == nil && that.Id != nil")
+	} else if that1.Id != nil {
+		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
 	}
-	return dAtA[:n], nil
-}
-
-func (m *ForwardJoin) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if m.Id == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
-	} else {
-		dAtA[i] = 0x8
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	if this.ReqId != nil && that1.ReqId != nil {
+		if *this.ReqId != *that1.ReqId {
+			return fmt.Errorf("ReqId this(%v) Not Equal that(%v)", *this.ReqId, *that1.ReqId)
+		}
+	} else if this.ReqId != nil {
+		return fmt.Errorf("this.ReqId == nil && that.ReqId != nil")
+	} else if that1.ReqId != nil {
+		return fmt.Errorf("ReqId this(%v) Not Equal that(%v)", this.ReqId, that1.ReqId)
 	}
-	if m.SourceId == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("sourceId")
-	} else {
-		dAtA[i] = 0x10
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(*m.SourceId))
+	if this.Addr != nil && that1.Addr != nil {
+		if *this.Addr != *that1.Addr {
+			return fmt.Errorf("Addr this(%v) Not Equal that(%v)", *this.Addr, *that1.Addr)
+		}
+	} else if this.Addr != nil {
+		return fmt.Errorf("this.Addr == nil && that.Addr != nil")
+	} else if that1.Addr != nil {
+		return fmt.Errorf("Addr this(%v) Not Equal that(%v)", this.Addr, that1.Addr)
 	}
-	if m.SourceAddr == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("sourceAddr")
-	} else {
-		dAtA[i] = 0x1a
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(len(*m.SourceAddr)))
-		i += copy(dAtA[i:], *m.SourceAddr)
+	if !bytes.Equal(this.Payload, that1.Payload) {
+		return fmt.Errorf("Payload this(%v) Not Equal that(%v)", this.Payload, that1.Payload)
 	}
-	if m.Ttl == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("ttl")
-	} else {
-		dAtA[i] = 0x20
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(*m.Ttl))
+	if this.Ts != nil && that1.Ts != nil {
+		if *this.Ts != *that1.Ts {
+			return fmt.Errorf("Ts this(%v) Not Equal that(%v)", *this.Ts, *that1.Ts)
+		}
+	} else if this.Ts != nil {
+		return fmt.Errorf("this.Ts == nil && that.Ts != nil")
+	} else if that1.Ts != nil {
+		return fmt.Errorf("Ts this(%v) Not Equal that(%v)", this.Ts, that1.Ts)
 	}
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+	if this.Target != nil && that1.Target != nil {
+		if *this.Target != *that1.Target {
+			return fmt.Errorf("Target this(%v) Not Equal that(%v)", *this.Target, *that1.Target)
+		}
+	} else if this.Target != nil {
+		return fmt.Errorf("this.Target == nil && that.Target != nil")
+	} else if that1.Target != nil {
+		return fmt.Errorf("Target this(%v) Not Equal that(%v)", this.Target, that1.Target)
 	}
-	return i, nil
-}
-
-func (m *Disconnect) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
 	}
-	return dAtA[:n], nil
+	return nil
 }
-
-func (m *Disconnect) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if m.Id == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
-	} else {
-		dAtA[i] = 0x8
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
-	}
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+func (this *Request) Equal(that interface{}) bool {
+	if that == nil {
+		if this == nil {
+			return true
+		}
+		return false
 	}
-	return i, nil
-}
 
-func (m *Candidate) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+	that1, ok := that.(*Request)
+	if !ok {
+		that2, ok := that.(Request)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
 	}
-	return dAtA[:n], nil
-}
-
-func (m *Candidate) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if m.Id == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
-	} else {
-		dAtA[i] = 0x8
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	if that1 == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	} else if this == nil {
+		return false
 	}
-	if m.Addr == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
-	} else {
-		dAtA[i] = 0x12
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Addr)))
-		i += copy(dAtA[i:], *m.Addr)
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return false
+		}
+	} else if this.Id != nil {
+		return false
+	} else if that1.Id != nil {
+		return false
 	}
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+	if this.ReqId != nil && that1.ReqId != nil {
+		if *this.ReqId != *that1.ReqId {
+			return false
+		}
+	} else if this.ReqId != nil {
+		return false
+	} else if that1.ReqId != nil {
+		return false
 	}
-	return i, nil
-}
-
-func (m *Shuffle) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+	if this.Addr != nil && that1.Addr != nil {
+		if *this.Addr != *that1.Addr {
+			return false
+		}
+	} else if this.Addr != nil {
+		return false
+	} else if that1.Addr != nil {
+		return false
 	}
-	return dAtA[:n], nil
+	if !bytes.Equal(this.Payload, that1.Payload) {
+		return false
+	}
+	if this.Ts != nil && that1.Ts != nil {
+		if *this.Ts != *that1.Ts {
+			return false
+		}
+	} else if this.Ts != nil {
+		return false
+	} else if that1.Ts != nil {
+		return false
+	}
+	if this.Target != nil && that1.Target != nil {
+		if *this.Target != *that1.Target {
+			return false
+		}
+	} else if this.Target != nil {
+		return false
+	} else if that1.Target != nil {
+		return false
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
+	}
+	return true
 }
+func (this *Reply) VerboseEqual(that interface{}) error {
+	if that == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that == nil && this != nil")
+	}
 
-func (m *Shuffle) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if m.Id == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
-	} else {
-		dAtA[i] = 0x8
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	that1, ok := that.(*Reply)
+	if !ok {
+		that2, ok := that.(Reply)
+		if ok {
+			that1 = &that2
+		} else {
+			return fmt.Errorf("that is not of type *Reply")
+		}
 	}
-	if m.SourceId == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("sourceId")
-	} else {
-		dAtA[i] = 0x10
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(*m.SourceId))
+	if that1 == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that is type *Reply but is nil && this != nil")
+	} else if this == nil {
+		return fmt.Errorf("that is type *Reply but is not nil && this == nil")
 	}
-	if m.Addr == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
-	} else {
-		dAtA[i] = 0x1a
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Addr)))
-		i += copy(dAtA[i:], *m.Addr)
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
+		}
+	} else if this.Id != nil {
+		return fmt.Errorf("this.Id == nil && that.Id != nil")
+	} else if that1.Id != nil {
+		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
 	}
-	if len(m.Candidates) > 0 {
-		for _, msg := range m.Candidates {
-			dAtA[i] = 0x22
-			i++
-			i = encodeVarintMessage(dAtA, i, uint64(msg.Size()))
-			n, err := msg.MarshalTo(dAtA[i:])
-			if err != nil {
-				return 0, err
-			}
-			i += n
+	if this.ReqId != nil && that1.ReqId != nil {
+		if *this.ReqId != *that1.ReqId {
+			return fmt.Errorf("ReqId this(%v) Not Equal that(%v)", *this.ReqId, *that1.ReqId)
 		}
+	} else if this.ReqId != nil {
+		return fmt.Errorf("this.ReqId == nil && that.ReqId != nil")
+	} else if that1.ReqId != nil {
+		return fmt.Errorf("ReqId this(%v) Not Equal that(%v)", this.ReqId, that1.ReqId)
 	}
-	if m.Ttl == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("ttl")
-	} else {
-		dAtA[i] = 0x28
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(*m.Ttl))
+	if !bytes.Equal(this.Payload, that1.Payload) {
+		return fmt.Errorf("Payload this(%v) Not Equal that(%v)", this.Payload, that1.Payload)
 	}
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
 	}
-	return i, nil
+	return nil
 }
-
-func (m *ShuffleReply) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+func (this *Reply) Equal(that interface{}) bool {
+	if that == nil {
+		if this == nil {
+			return true
+		}
+		return false
 	}
-	return dAtA[:n], nil
-}
 
-func (m *ShuffleReply) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if m.Id == nil {
-		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
-	} else {
-		dAtA[i] = 0x8
-		i++
-		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
-	}
-	if len(m.Candidates) > 0 {
-		for _, msg := range m.Candidates {
-			dAtA[i] = 0x12
-			i++
-			i = encodeVarintMessage(dAtA, i, uint64(msg.Size()))
-			n, err := msg.MarshalTo(dAtA[i:])
-			if err != nil {
-				return 0, err
-			}
-			i += n
+	that1, ok := that.(*Reply)
+	if !ok {
+		that2, ok := that.(Reply)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
 		}
 	}
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+	if that1 == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	} else if this == nil {
+		return false
 	}
-	return i, nil
-}
-
-func encodeFixed64Message(dAtA []byte, offset int, v uint64) int {
-	dAtA[offset] = uint8(v)
-	dAtA[offset+1] = uint8(v >> 8)
-	dAtA[offset+2] = uint8(v >> 16)
-	dAtA[offset+3] = uint8(v >> 24)
-	dAtA[offset+4] = uint8(v >> 32)
-	dAtA[offset+5] = uint8(v >> 40)
-	dAtA[offset+6] = uint8(v >> 48)
-	dAtA[offset+7] = uint8(v >> 56)
-	return offset + 8
-}
-func encodeFixed32Message(dAtA []byte, offset int, v uint32) int {
-	dAtA[offset] = uint8(v)
-	dAtA[offset+1] = uint8(v >> 8)
-	dAtA[offset+2] = uint8(v >> 16)
-	dAtA[offset+3] = uint8(v >> 24)
-	return offset + 4
-}
-func encodeVarintMessage(dAtA []byte, offset int, v uint64) int {
-	for v >= 1<<7 {
-		dAtA[offset] = uint8(v&0x7f | 0x80)
-		v >>= 7
-		offset++
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return false
+		}
+	} else if this.Id != nil {
+		return false
+	} else if that1.Id != nil {
+		return false
 	}
-	dAtA[offset] = uint8(v)
-	return offset + 1
-}
-func NewPopulatedUserMessage(r randyMessage, easy bool) *UserMessage {
-	this := &UserMessage{}
-	v1 := uint64(uint64(r.Uint32()))
-	this.Id = &v1
-	if r.Intn(10) != 0 {
-		v2 := r.Intn(100)
-		this.Payload = make([]byte, v2)
-		for i := 0; i < v2; i++ {
-			this.Payload[i] = byte(r.Intn(256))
+	if this.ReqId != nil && that1.ReqId != nil {
+		if *this.ReqId != *that1.ReqId {
+			return false
 		}
+	} else if this.ReqId != nil {
+		return false
+	} else if that1.ReqId != nil {
+		return false
 	}
-	v3 := int64(r.Int63())
-	if r.Intn(2) == 0 {
-		v3 *= -1
+	if !bytes.Equal(this.Payload, that1.Payload) {
+		return false
 	}
-	this.Ts = &v3
-	if !easy && r.Intn(10) != 0 {
-		this.XXX_unrecognized = randUnrecognizedMessage(r, 4)
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
 	}
-	return this
+	return true
 }
-
-func NewPopulatedJoin(r randyMessage, easy bool) *Join {
-	this := &Join{}
-	v4 := uint64(uint64(r.Uint32()))
-	this.Id = &v4
-	v5 := string(randStringMessage(r))
-	this.Addr = &v5
-	if !easy && r.Intn(10) != 0 {
-		this.XXX_unrecognized = randUnrecognizedMessage(r, 3)
+func (this *IHave) VerboseEqual(that interface{}) error {
+	if that == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that == nil && this != nil")
 	}
-	return this
-}
 
-func NewPopulatedJoinReply(r randyMessage, easy bool) *JoinReply {
-	this := &JoinReply{}
-	v6 := uint64(uint64(r.Uint32()))
-	this.Id = &v6
-	v7 := bool(bool(r.Intn(2) == 0))
-	this.Accept = &v7
-	if !easy && r.Intn(10) != 0 {
-		this.XXX_unrecognized = randUnrecognizedMessage(r, 3)
-	}
-	return this
-}
-
-func NewPopulatedNeighbor(r randyMessage, easy bool) *Neighbor {
-	this := &Neighbor{}
-	v8 := uint64(uint64(r.Uint32()))
-	this.Id = &v8
-	v9 := string(randStringMessage(r))
-	this.Addr = &v9
-	v10 := Neighbor_Priority([]int32{0, 1}[r.Intn(2)])
-	this.Priority = &v10
-	if !easy && r.Intn(10) != 0 {
-		this.XXX_unrecognized = randUnrecognizedMessage(r, 4)
-	}
-	return this
-}
-
-func NewPopulatedNeighborReply(r randyMessage, easy bool) *NeighborReply {
-	this := &NeighborReply{}
-	v11 := uint64(uint64(r.Uint32()))
-	this.Id = &v11
-	v12 := bool(bool(r.Intn(2) == 0))
-	this.Accept = &v12
-	if !easy && r.Intn(10) != 0 {
-		this.XXX_unrecognized = randUnrecognizedMessage(r, 3)
+	that1, ok := that.(*IHave)
+	if !ok {
+		that2, ok := that.(IHave)
+		if ok {
+			that1 = &that2
+		} else {
+			return fmt.Errorf("that is not of type *IHave")
+		}
 	}
-	return this
-}
-
-func NewPopulatedForwardJoin(r randyMessage, easy bool) *ForwardJoin {
-	this := &ForwardJoin{}
-	v13 := uint64(uint64(r.Uint32()))
-	this.Id = &v13
-	v14 := uint64(uint64(r.Uint32()))
-	this.SourceId = &v14
-	v15 := string(randStringMessage(r))
-	this.SourceAddr = &v15
-	v16 := uint32(r.Uint32())
-	this.Ttl = &v16
-	if !easy && r.Intn(10) != 0 {
-		this.XXX_unrecognized = randUnrecognizedMessage(r, 5)
+	if that1 == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that is type *IHave but is nil && this != nil")
+	} else if this == nil {
+		return fmt.Errorf("that is type *IHave but is not nil && this == nil")
 	}
-	return this
-}
-
-func NewPopulatedDisconnect(r randyMessage, easy bool) *Disconnect {
-	this := &Disconnect{}
-	v17 := uint64(uint64(r.Uint32()))
-	this.Id = &v17
-	if !easy && r.Intn(10) != 0 {
-		this.XXX_unrecognized = randUnrecognizedMessage(r, 2)
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
+		}
+	} else if this.Id != nil {
+		return fmt.Errorf("this.Id == nil && that.Id != nil")
+	} else if that1.Id != nil {
+		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
 	}
-	return this
-}
-
-func NewPopulatedCandidate(r randyMessage, easy bool) *Candidate {
-	this := &Candidate{}
-	v18 := uint64(uint64(r.Uint32()))
-	this.Id = &v18
-	v19 := string(randStringMessage(r))
-	this.Addr = &v19
-	if !easy && r.Intn(10) != 0 {
-		this.XXX_unrecognized = randUnrecognizedMessage(r, 3)
+	if len(this.MsgIds) != len(that1.MsgIds) {
+		return fmt.Errorf("MsgIds this(%v) Not Equal that(%v)", len(this.MsgIds), len(that1.MsgIds))
 	}
-	return this
-}
-
-func NewPopulatedShuffle(r randyMessage, easy bool) *Shuffle {
-	this := &Shuffle{}
-	v20 := uint64(uint64(r.Uint32()))
-	this.Id = &v20
-	v21 := uint64(uint64(r.Uint32()))
-	this.SourceId = &v21
-	v22 := string(randStringMessage(r))
-	this.Addr = &v22
-	if r.Intn(10) != 0 {
-		v23 := r.Intn(5)
-		this.Candidates = make([]*Candidate, v23)
-		for i := 0; i < v23; i++ {
-			this.Candidates[i] = NewPopulatedCandidate(r, easy)
+	for i := range this.MsgIds {
+		if !bytes.Equal(this.MsgIds[i], that1.MsgIds[i]) {
+			return fmt.Errorf("MsgIds this[%v](%v) Not Equal that[%v](%v)", i, this.MsgIds[i], i, that1.MsgIds[i])
 		}
 	}
-	v24 := uint32(r.Uint32())
-	this.Ttl = &v24
-	if !easy && r.Intn(10) != 0 {
-		this.XXX_unrecognized = randUnrecognizedMessage(r, 6)
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
 	}
-	return this
+	return nil
 }
+func (this *IHave) Equal(that interface{}) bool {
+	if that == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	}
 
-func NewPopulatedShuffleReply(r randyMessage, easy bool) *ShuffleReply {
-	this := &ShuffleReply{}
-	v25 := uint64(uint64(r.Uint32()))
-	this.Id = &v25
-	if r.Intn(10) != 0 {
-		v26 := r.Intn(5)
-		this.Candidates = make([]*Candidate, v26)
-		for i := 0; i < v26; i++ {
-			this.Candidates[i] = NewPopulatedCandidate(r, easy)
+	that1, ok := that.(*IHave)
+	if !ok {
+		that2, ok := that.(IHave)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
 		}
 	}
-	if !easy && r.Intn(10) != 0 {
-		this.XXX_unrecognized = randUnrecognizedMessage(r, 3)
+	if that1 == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	} else if this == nil {
+		return false
 	}
-	return this
-}
-
-type randyMessage interface {
-	Float32() float32
-	Float64() float64
-	Int63() int64
-	Int31() int32
-	Uint32() uint32
-	Intn(n int) int
-}
-
-func randUTF8RuneMessage(r randyMessage) rune {
-	ru := r.Intn(62)
-	if ru < 10 {
-		return rune(ru + 48)
-	} else if ru < 36 {
-		return rune(ru + 55)
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return false
+		}
+	} else if this.Id != nil {
+		return false
+	} else if that1.Id != nil {
+		return false
 	}
-	return rune(ru + 61)
-}
-func randStringMessage(r randyMessage) string {
-	v27 := r.Intn(100)
-	tmps := make([]rune, v27)
-	for i := 0; i < v27; i++ {
-		tmps[i] = randUTF8RuneMessage(r)
+	if len(this.MsgIds) != len(that1.MsgIds) {
+		return false
 	}
-	return string(tmps)
-}
-func randUnrecognizedMessage(r randyMessage, maxFieldNumber int) (dAtA []byte) {
-	l := r.Intn(5)
-	for i := 0; i < l; i++ {
-		wire := r.Intn(4)
-		if wire == 3 {
-			wire = 5
+	for i := range this.MsgIds {
+		if !bytes.Equal(this.MsgIds[i], that1.MsgIds[i]) {
+			return false
 		}
-		fieldNumber := maxFieldNumber + r.Intn(100)
-		dAtA = randFieldMessage(dAtA, r, fieldNumber, wire)
 	}
-	return dAtA
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
+	}
+	return true
 }
-func randFieldMessage(dAtA []byte, r randyMessage, fieldNumber int, wire int) []byte {
-	key := uint32(fieldNumber)<<3 | uint32(wire)
-	switch wire {
-	case 0:
-		dAtA = encodeVarintPopulateMessage(dAtA, uint64(key))
-		v28 := r.Int63()
-		if r.Intn(2) == 0 {
-			v28 *= -1
-		}
-		dAtA = encodeVarintPopulateMessage(dAtA, uint64(v28))
-	case 1:
-		dAtA = encodeVarintPopulateMessage(dAtA, uint64(key))
-		dAtA = append(dAtA, byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)))
-	case 2:
-		dAtA = encodeVarintPopulateMessage(dAtA, uint64(key))
-		ll := r.Intn(100)
-		dAtA = encodeVarintPopulateMessage(dAtA, uint64(ll))
-		for j := 0; j < ll; j++ {
-			dAtA = append(dAtA, byte(r.Intn(256)))
+func (this *Graft) VerboseEqual(that interface{}) error {
+	if that == nil {
+		if this == nil {
+			return nil
 		}
-	default:
-		dAtA = encodeVarintPopulateMessage(dAtA, uint64(key))
-		dAtA = append(dAtA, byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)))
+		return fmt.Errorf("that == nil && this != nil")
 	}
-	return dAtA
-}
-func encodeVarintPopulateMessage(dAtA []byte, v uint64) []byte {
-	for v >= 1<<7 {
-		dAtA = append(dAtA, uint8(uint64(v)&0x7f|0x80))
-		v >>= 7
+
+	that1, ok := that.(*Graft)
+	if !ok {
+		that2, ok := that.(Graft)
+		if ok {
+			that1 = &that2
+		} else {
+			return fmt.Errorf("that is not of type *Graft")
+		}
 	}
-	dAtA = append(dAtA, uint8(v))
-	return dAtA
-}
-func (m *UserMessage) Size() (n int) {
-	var l int
-	_ = l
-	if m.Id != nil {
-		n += 1 + sovMessage(uint64(*m.Id))
+	if that1 == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that is type *Graft but is nil && this != nil")
+	} else if this == nil {
+		return fmt.Errorf("that is type *Graft but is not nil && this == nil")
 	}
-	if m.Payload != nil {
-		l = len(m.Payload)
-		n += 1 + l + sovMessage(uint64(l))
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
+		}
+	} else if this.Id != nil {
+		return fmt.Errorf("this.Id == nil && that.Id != nil")
+	} else if that1.Id != nil {
+		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
 	}
-	if m.Ts != nil {
-		n += 1 + sovMessage(uint64(*m.Ts))
+	if !bytes.Equal(this.MsgId, that1.MsgId) {
+		return fmt.Errorf("MsgId this(%v) Not Equal that(%v)", this.MsgId, that1.MsgId)
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
 	}
-	return n
+	return nil
 }
+func (this *Graft) Equal(that interface{}) bool {
+	if that == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	}
 
-func (m *Join) Size() (n int) {
-	var l int
-	_ = l
-	if m.Id != nil {
-		n += 1 + sovMessage(uint64(*m.Id))
+	that1, ok := that.(*Graft)
+	if !ok {
+		that2, ok := that.(Graft)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
 	}
-	if m.Addr != nil {
-		l = len(*m.Addr)
-		n += 1 + l + sovMessage(uint64(l))
+	if that1 == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	} else if this == nil {
+		return false
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return false
+		}
+	} else if this.Id != nil {
+		return false
+	} else if that1.Id != nil {
+		return false
 	}
-	return n
-}
-
-func (m *JoinReply) Size() (n int) {
-	var l int
-	_ = l
-	if m.Id != nil {
-		n += 1 + sovMessage(uint64(*m.Id))
+	if !bytes.Equal(this.MsgId, that1.MsgId) {
+		return false
 	}
-	if m.Accept != nil {
-		n += 2
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
+	return true
 }
+func (this *Prune) VerboseEqual(that interface{}) error {
+	if that == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that == nil && this != nil")
+	}
 
-func (m *Neighbor) Size() (n int) {
-	var l int
-	_ = l
-	if m.Id != nil {
-		n += 1 + sovMessage(uint64(*m.Id))
+	that1, ok := that.(*Prune)
+	if !ok {
+		that2, ok := that.(Prune)
+		if ok {
+			that1 = &that2
+		} else {
+			return fmt.Errorf("that is not of type *Prune")
+		}
 	}
-	if m.Addr != nil {
-		l = len(*m.Addr)
-		n += 1 + l + sovMessage(uint64(l))
+	if that1 == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that is type *Prune but is nil && this != nil")
+	} else if this == nil {
+		return fmt.Errorf("that is type *Prune but is not nil && this == nil")
 	}
-	if m.Priority != nil {
-		n += 1 + sovMessage(uint64(*m.Priority))
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
+		}
+	} else if this.Id != nil {
+		return fmt.Errorf("this.Id == nil && that.Id != nil")
+	} else if that1.Id != nil {
+		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
 	}
-	return n
+	return nil
 }
-
-func (m *NeighborReply) Size() (n int) {
-	var l int
-	_ = l
-	if m.Id != nil {
-		n += 1 + sovMessage(uint64(*m.Id))
-	}
-	if m.Accept != nil {
-		n += 2
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+func (this *Prune) Equal(that interface{}) bool {
+	if that == nil {
+		if this == nil {
+			return true
+		}
+		return false
 	}
-	return n
-}
 
-func (m *ForwardJoin) Size() (n int) {
-	var l int
-	_ = l
-	if m.Id != nil {
-		n += 1 + sovMessage(uint64(*m.Id))
-	}
-	if m.SourceId != nil {
-		n += 1 + sovMessage(uint64(*m.SourceId))
+	that1, ok := that.(*Prune)
+	if !ok {
+		that2, ok := that.(Prune)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
 	}
-	if m.SourceAddr != nil {
-		l = len(*m.SourceAddr)
-		n += 1 + l + sovMessage(uint64(l))
+	if that1 == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	} else if this == nil {
+		return false
 	}
-	if m.Ttl != nil {
-		n += 1 + sovMessage(uint64(*m.Ttl))
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return false
+		}
+	} else if this.Id != nil {
+		return false
+	} else if that1.Id != nil {
+		return false
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
 	}
-	return n
+	return true
 }
+func (this *Ack) VerboseEqual(that interface{}) error {
+	if that == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that == nil && this != nil")
+	}
 
-func (m *Disconnect) Size() (n int) {
-	var l int
-	_ = l
-	if m.Id != nil {
-		n += 1 + sovMessage(uint64(*m.Id))
+	that1, ok := that.(*Ack)
+	if !ok {
+		that2, ok := that.(Ack)
+		if ok {
+			that1 = &that2
+		} else {
+			return fmt.Errorf("that is not of type *Ack")
+		}
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if that1 == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that is type *Ack but is nil && this != nil")
+	} else if this == nil {
+		return fmt.Errorf("that is type *Ack but is not nil && this == nil")
 	}
-	return n
-}
-
-func (m *Candidate) Size() (n int) {
-	var l int
-	_ = l
-	if m.Id != nil {
-		n += 1 + sovMessage(uint64(*m.Id))
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
+		}
+	} else if this.Id != nil {
+		return fmt.Errorf("this.Id == nil && that.Id != nil")
+	} else if that1.Id != nil {
+		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
 	}
-	if m.Addr != nil {
-		l = len(*m.Addr)
-		n += 1 + l + sovMessage(uint64(l))
+	if this.Seq != nil && that1.Seq != nil {
+		if *this.Seq != *that1.Seq {
+			return fmt.Errorf("Seq this(%v) Not Equal that(%v)", *this.Seq, *that1.Seq)
+		}
+	} else if this.Seq != nil {
+		return fmt.Errorf("this.Seq == nil && that.Seq != nil")
+	} else if that1.Seq != nil {
+		return fmt.Errorf("Seq this(%v) Not Equal that(%v)", this.Seq, that1.Seq)
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
 	}
-	return n
+	return nil
 }
-
-func (m *Shuffle) Size() (n int) {
-	var l int
-	_ = l
-	if m.Id != nil {
-		n += 1 + sovMessage(uint64(*m.Id))
+func (this *Ack) Equal(that interface{}) bool {
+	if that == nil {
+		if this == nil {
+			return true
+		}
+		return false
 	}
-	if m.SourceId != nil {
-		n += 1 + sovMessage(uint64(*m.SourceId))
+
+	that1, ok := that.(*Ack)
+	if !ok {
+		that2, ok := that.(Ack)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
 	}
-	if m.Addr != nil {
-		l = len(*m.Addr)
-		n += 1 + l + sovMessage(uint64(l))
+	if that1 == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	} else if this == nil {
+		return false
 	}
-	if len(m.Candidates) > 0 {
-		for _, e := range m.Candidates {
-			l = e.Size()
-			n += 1 + l + sovMessage(uint64(l))
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return false
 		}
+	} else if this.Id != nil {
+		return false
+	} else if that1.Id != nil {
+		return false
 	}
-	if m.Ttl != nil {
-		n += 1 + sovMessage(uint64(*m.Ttl))
+	if this.Seq != nil && that1.Seq != nil {
+		if *this.Seq != *that1.Seq {
+			return false
+		}
+	} else if this.Seq != nil {
+		return false
+	} else if that1.Seq != nil {
+		return false
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
 	}
-	return n
+	return true
 }
+func (this *Ping) VerboseEqual(that interface{}) error {
+	if that == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that == nil && this != nil")
+	}
 
-func (m *ShuffleReply) Size() (n int) {
-	var l int
-	_ = l
-	if m.Id != nil {
-		n += 1 + sovMessage(uint64(*m.Id))
+	that1, ok := that.(*Ping)
+	if !ok {
+		that2, ok := that.(Ping)
+		if ok {
+			that1 = &that2
+		} else {
+			return fmt.Errorf("that is not of type *Ping")
+		}
 	}
-	if len(m.Candidates) > 0 {
-		for _, e := range m.Candidates {
-			l = e.Size()
-			n += 1 + l + sovMessage(uint64(l))
+	if that1 == nil {
+		if this == nil {
+			return nil
 		}
+		return fmt.Errorf("that is type *Ping but is nil && this != nil")
+	} else if this == nil {
+		return fmt.Errorf("that is type *Ping but is not nil && this == nil")
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
+		}
+	} else if this.Id != nil {
+		return fmt.Errorf("this.Id == nil && that.Id != nil")
+	} else if that1.Id != nil {
+		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
 	}
-	return n
-}
-
-func sovMessage(x uint64) (n int) {
-	for {
-		n++
-		x >>= 7
-		if x == 0 {
-			break
+	if this.Seq != nil && that1.Seq != nil {
+		if *this.Seq != *that1.Seq {
+			return fmt.Errorf("Seq this(%v) Not Equal that(%v)", *this.Seq, *that1.Seq)
 		}
+	} else if this.Seq != nil {
+		return fmt.Errorf("this.Seq == nil && that.Seq != nil")
+	} else if that1.Seq != nil {
+		return fmt.Errorf("Seq this(%v) Not Equal that(%v)", this.Seq, that1.Seq)
 	}
-	return n
-}
-func sozMessage(x uint64) (n int) {
-	return sovMessage(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
+	}
+	return nil
 }
-func (this *UserMessage) String() string {
-	if this == nil {
-		return "nil"
+func (this *Ping) Equal(that interface{}) bool {
+	if that == nil {
+		if this == nil {
+			return true
+		}
+		return false
 	}
-	s := strings.Join([]string{`&UserMessage{`,
-		`Id:` + valueToStringMessage(this.Id) + `,`,
-		`Payload:` + valueToStringMessage(this.Payload) + `,`,
-		`Ts:` + valueToStringMessage(this.Ts) + `,`,
-		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
-		`}`,
-	}, "")
-	return s
+
+	that1, ok := that.(*Ping)
+	if !ok {
+		that2, ok := that.(Ping)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	} else if this == nil {
+		return false
+	}
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return false
+		}
+	} else if this.Id != nil {
+		return false
+	} else if that1.Id != nil {
+		return false
+	}
+	if this.Seq != nil && that1.Seq != nil {
+		if *this.Seq != *that1.Seq {
+			return false
+		}
+	} else if this.Seq != nil {
+		return false
+	} else if that1.Seq != nil {
+		return false
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
+	}
+	return true
 }
-func (this *Join) String() string {
-	if this == nil {
-		return "nil"
+func (this *Pong) VerboseEqual(that interface{}) error {
+	if that == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that == nil && this != nil")
 	}
-	s := strings.Join([]string{`&Join{`,
-		`Id:` + valueToStringMessage(this.Id) + `,`,
-		`Addr:` + valueToStringMessage(this.Addr) + `,`,
-		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
-		`}`,
-	}, "")
-	return s
+
+	that1, ok := that.(*Pong)
+	if !ok {
+		that2, ok := that.(Pong)
+		if ok {
+			that1 = &that2
+		} else {
+			return fmt.Errorf("that is not of type *Pong")
+		}
+	}
+	if that1 == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that is type *Pong but is nil && this != nil")
+	} else if this == nil {
+		return fmt.Errorf("that is type *Pong but is not nil && this == nil")
+	}
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
+		}
+	} else if this.Id != nil {
+		return fmt.Errorf("this.Id == nil && that.Id != nil")
+	} else if that1.Id != nil {
+		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
+	}
+	if this.Seq != nil && that1.Seq != nil {
+		if *this.Seq != *that1.Seq {
+			return fmt.Errorf("Seq this(%v) Not Equal that(%v)", *this.Seq, *that1.Seq)
+		}
+	} else if this.Seq != nil {
+		return fmt.Errorf("this.Seq == nil && that.Seq != nil")
+	} else if that1.Seq != nil {
+		return fmt.Errorf("Seq this(%v) Not Equal that(%v)", this.Seq, that1.Seq)
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
+	}
+	return nil
 }
-func (this *JoinReply) String() string {
-	if this == nil {
-		return "nil"
+func (this *Pong) Equal(that interface{}) bool {
+	if that == nil {
+		if this == nil {
+			return true
+		}
+		return false
 	}
-	s := strings.Join([]string{`&JoinReply{`,
-		`Id:` + valueToStringMessage(this.Id) + `,`,
-		`Accept:` + valueToStringMessage(this.Accept) + `,`,
-		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
-		`}`,
-	}, "")
-	return s
+
+	that1, ok := that.(*Pong)
+	if !ok {
+		that2, ok := that.(Pong)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	} else if this == nil {
+		return false
+	}
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return false
+		}
+	} else if this.Id != nil {
+		return false
+	} else if that1.Id != nil {
+		return false
+	}
+	if this.Seq != nil && that1.Seq != nil {
+		if *this.Seq != *that1.Seq {
+			return false
+		}
+	} else if this.Seq != nil {
+		return false
+	} else if that1.Seq != nil {
+		return false
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
+	}
+	return true
 }
-func (this *Neighbor) String() string {
-	if this == nil {
-		return "nil"
+func (this *Batch) VerboseEqual(that interface{}) error {
+	if that == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that == nil && this != nil")
 	}
-	s := strings.Join([]string{`&Neighbor{`,
-		`Id:` + valueToStringMessage(this.Id) + `,`,
-		`Addr:` + valueToStringMessage(this.Addr) + `,`,
-		`Priority:` + valueToStringMessage(this.Priority) + `,`,
-		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
-		`}`,
-	}, "")
-	return s
+
+	that1, ok := that.(*Batch)
+	if !ok {
+		that2, ok := that.(Batch)
+		if ok {
+			that1 = &that2
+		} else {
+			return fmt.Errorf("that is not of type *Batch")
+		}
+	}
+	if that1 == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that is type *Batch but is nil && this != nil")
+	} else if this == nil {
+		return fmt.Errorf("that is type *Batch but is not nil && this == nil")
+	}
+	if len(this.Messages) != len(that1.Messages) {
+		return fmt.Errorf("Messages this(%v) Not Equal that(%v)", len(this.Messages), len(that1.Messages))
+	}
+	for i := range this.Messages {
+		if !this.Messages[i].Equal(that1.Messages[i]) {
+			return fmt.Errorf("Messages this[%v](%v) Not Equal that[%v](%v)", i, this.Messages[i], i, that1.Messages[i])
+		}
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
+	}
+	return nil
 }
-func (this *NeighborReply) String() string {
-	if this == nil {
-		return "nil"
+func (this *Batch) Equal(that interface{}) bool {
+	if that == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	}
+
+	that1, ok := that.(*Batch)
+	if !ok {
+		that2, ok := that.(Batch)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	} else if this == nil {
+		return false
+	}
+	if len(this.Messages) != len(that1.Messages) {
+		return false
+	}
+	for i := range this.Messages {
+		if !this.Messages[i].Equal(that1.Messages[i]) {
+			return false
+		}
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
+	}
+	return true
+}
+func (this *Digest) VerboseEqual(that interface{}) error {
+	if that == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that == nil && this != nil")
+	}
+
+	that1, ok := that.(*Digest)
+	if !ok {
+		that2, ok := that.(Digest)
+		if ok {
+			that1 = &that2
+		} else {
+			return fmt.Errorf("that is not of type *Digest")
+		}
+	}
+	if that1 == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that is type *Digest but is nil && this != nil")
+	} else if this == nil {
+		return fmt.Errorf("that is type *Digest but is not nil && this == nil")
+	}
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
+		}
+	} else if this.Id != nil {
+		return fmt.Errorf("this.Id == nil && that.Id != nil")
+	} else if that1.Id != nil {
+		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
+	}
+	if this.Reachable != nil && that1.Reachable != nil {
+		if *this.Reachable != *that1.Reachable {
+			return fmt.Errorf("Reachable this(%v) Not Equal that(%v)", *this.Reachable, *that1.Reachable)
+		}
+	} else if this.Reachable != nil {
+		return fmt.Errorf("this.Reachable == nil && that.Reachable != nil")
+	} else if that1.Reachable != nil {
+		return fmt.Errorf("Reachable this(%v) Not Equal that(%v)", this.Reachable, that1.Reachable)
+	}
+	if len(this.Sample) != len(that1.Sample) {
+		return fmt.Errorf("Sample this(%v) Not Equal that(%v)", len(this.Sample), len(that1.Sample))
+	}
+	for i := range this.Sample {
+		if !this.Sample[i].Equal(that1.Sample[i]) {
+			return fmt.Errorf("Sample this[%v](%v) Not Equal that[%v](%v)", i, this.Sample[i], i, that1.Sample[i])
+		}
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
+	}
+	return nil
+}
+func (this *Digest) Equal(that interface{}) bool {
+	if that == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	}
+
+	that1, ok := that.(*Digest)
+	if !ok {
+		that2, ok := that.(Digest)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	} else if this == nil {
+		return false
+	}
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return false
+		}
+	} else if this.Id != nil {
+		return false
+	} else if that1.Id != nil {
+		return false
+	}
+	if this.Reachable != nil && that1.Reachable != nil {
+		if *this.Reachable != *that1.Reachable {
+			return false
+		}
+	} else if this.Reachable != nil {
+		return false
+	} else if that1.Reachable != nil {
+		return false
+	}
+	if len(this.Sample) != len(that1.Sample) {
+		return false
+	}
+	for i := range this.Sample {
+		if !this.Sample[i].Equal(that1.Sample[i]) {
+			return false
+		}
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
+	}
+	return true
+}
+func (this *DigestEntry) VerboseEqual(that interface{}) error {
+	if that == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that == nil && this != nil")
+	}
+
+	that1, ok := that.(*DigestEntry)
+	if !ok {
+		that2, ok := that.(DigestEntry)
+		if ok {
+			that1 = &that2
+		} else {
+			return fmt.Errorf("that is not of type *DigestEntry")
+		}
+	}
+	if that1 == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that is type *DigestEntry but is nil && this != nil")
+	} else if this == nil {
+		return fmt.Errorf("that is type *DigestEntry but is not nil && this == nil")
+	}
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
+		}
+	} else if this.Id != nil {
+		return fmt.Errorf("this.Id == nil && that.Id != nil")
+	} else if that1.Id != nil {
+		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
+	}
+	if this.Addr != nil && that1.Addr != nil {
+		if *this.Addr != *that1.Addr {
+			return fmt.Errorf("Addr this(%v) Not Equal that(%v)", *this.Addr, *that1.Addr)
+		}
+	} else if this.Addr != nil {
+		return fmt.Errorf("this.Addr == nil && that.Addr != nil")
+	} else if that1.Addr != nil {
+		return fmt.Errorf("Addr this(%v) Not Equal that(%v)", this.Addr, that1.Addr)
+	}
+	if this.Age != nil && that1.Age != nil {
+		if *this.Age != *that1.Age {
+			return fmt.Errorf("Age this(%v) Not Equal that(%v)", *this.Age, *that1.Age)
+		}
+	} else if this.Age != nil {
+		return fmt.Errorf("this.Age == nil && that.Age != nil")
+	} else if that1.Age != nil {
+		return fmt.Errorf("Age this(%v) Not Equal that(%v)", this.Age, that1.Age)
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
+	}
+	return nil
+}
+func (this *DigestEntry) Equal(that interface{}) bool {
+	if that == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	}
+
+	that1, ok := that.(*DigestEntry)
+	if !ok {
+		that2, ok := that.(DigestEntry)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	} else if this == nil {
+		return false
+	}
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return false
+		}
+	} else if this.Id != nil {
+		return false
+	} else if that1.Id != nil {
+		return false
+	}
+	if this.Addr != nil && that1.Addr != nil {
+		if *this.Addr != *that1.Addr {
+			return false
+		}
+	} else if this.Addr != nil {
+		return false
+	} else if that1.Addr != nil {
+		return false
+	}
+	if this.Age != nil && that1.Age != nil {
+		if *this.Age != *that1.Age {
+			return false
+		}
+	} else if this.Age != nil {
+		return false
+	} else if that1.Age != nil {
+		return false
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
+	}
+	return true
+}
+func (this *TopologyRequest) VerboseEqual(that interface{}) error {
+	if that == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that == nil && this != nil")
+	}
+
+	that1, ok := that.(*TopologyRequest)
+	if !ok {
+		that2, ok := that.(TopologyRequest)
+		if ok {
+			that1 = &that2
+		} else {
+			return fmt.Errorf("that is not of type *TopologyRequest")
+		}
+	}
+	if that1 == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that is type *TopologyRequest but is nil && this != nil")
+	} else if this == nil {
+		return fmt.Errorf("that is type *TopologyRequest but is not nil && this == nil")
+	}
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
+		}
+	} else if this.Id != nil {
+		return fmt.Errorf("this.Id == nil && that.Id != nil")
+	} else if that1.Id != nil {
+		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
+	}
+	if this.ReqId != nil && that1.ReqId != nil {
+		if *this.ReqId != *that1.ReqId {
+			return fmt.Errorf("ReqId this(%v) Not Equal that(%v)", *this.ReqId, *that1.ReqId)
+		}
+	} else if this.ReqId != nil {
+		return fmt.Errorf("this.ReqId == nil && that.ReqId != nil")
+	} else if that1.ReqId != nil {
+		return fmt.Errorf("ReqId this(%v) Not Equal that(%v)", this.ReqId, that1.ReqId)
+	}
+	if this.Addr != nil && that1.Addr != nil {
+		if *this.Addr != *that1.Addr {
+			return fmt.Errorf("Addr this(%v) Not Equal that(%v)", *this.Addr, *that1.Addr)
+		}
+	} else if this.Addr != nil {
+		return fmt.Errorf("this.Addr == nil && that.Addr != nil")
+	} else if that1.Addr != nil {
+		return fmt.Errorf("Addr this(%v) Not Equal that(%v)", this.Addr, that1.Addr)
+	}
+	if this.Ttl != nil && that1.Ttl != nil {
+		if *this.Ttl != *that1.Ttl {
+			return fmt.Errorf("Ttl this(%v) Not Equal that(%v)", *this.Ttl, *that1.Ttl)
+		}
+	} else if this.Ttl != nil {
+		return fmt.Errorf("this.Ttl == nil && that.Ttl != nil")
+	} else if that1.Ttl != nil {
+		return fmt.Errorf("Ttl this(%v) Not Equal that(%v)", this.Ttl, that1.Ttl)
+	}
+	if this.Ts != nil && that1.Ts != nil {
+		if *this.Ts != *that1.Ts {
+			return fmt.Errorf("Ts this(%v) Not Equal that(%v)", *this.Ts, *that1.Ts)
+		}
+	} else if this.Ts != nil {
+		return fmt.Errorf("this.Ts == nil && that.Ts != nil")
+	} else if that1.Ts != nil {
+		return fmt.Errorf("Ts this(%v) Not Equal that(%v)", this.Ts, that1.Ts)
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
+	}
+	return nil
+}
+func (this *TopologyRequest) Equal(that interface{}) bool {
+	if that == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	}
+
+	that1, ok := that.(*TopologyRequest)
+	if !ok {
+		that2, ok := that.(TopologyRequest)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	} else if this == nil {
+		return false
+	}
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return false
+		}
+	} else if this.Id != nil {
+		return false
+	} else if that1.Id != nil {
+		return false
+	}
+	if this.ReqId != nil && that1.ReqId != nil {
+		if *this.ReqId != *that1.ReqId {
+			return false
+		}
+	} else if this.ReqId != nil {
+		return false
+	} else if that1.ReqId != nil {
+		return false
+	}
+	if this.Addr != nil && that1.Addr != nil {
+		if *this.Addr != *that1.Addr {
+			return false
+		}
+	} else if this.Addr != nil {
+		return false
+	} else if that1.Addr != nil {
+		return false
+	}
+	if this.Ttl != nil && that1.Ttl != nil {
+		if *this.Ttl != *that1.Ttl {
+			return false
+		}
+	} else if this.Ttl != nil {
+		return false
+	} else if that1.Ttl != nil {
+		return false
+	}
+	if this.Ts != nil && that1.Ts != nil {
+		if *this.Ts != *that1.Ts {
+			return false
+		}
+	} else if this.Ts != nil {
+		return false
+	} else if that1.Ts != nil {
+		return false
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
+	}
+	return true
+}
+func (this *TopologyReply) VerboseEqual(that interface{}) error {
+	if that == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that == nil && this != nil")
+	}
+
+	that1, ok := that.(*TopologyReply)
+	if !ok {
+		that2, ok := that.(TopologyReply)
+		if ok {
+			that1 = &that2
+		} else {
+			return fmt.Errorf("that is not of type *TopologyReply")
+		}
+	}
+	if that1 == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that is type *TopologyReply but is nil && this != nil")
+	} else if this == nil {
+		return fmt.Errorf("that is type *TopologyReply but is not nil && this == nil")
+	}
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
+		}
+	} else if this.Id != nil {
+		return fmt.Errorf("this.Id == nil && that.Id != nil")
+	} else if that1.Id != nil {
+		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
+	}
+	if this.ReqId != nil && that1.ReqId != nil {
+		if *this.ReqId != *that1.ReqId {
+			return fmt.Errorf("ReqId this(%v) Not Equal that(%v)", *this.ReqId, *that1.ReqId)
+		}
+	} else if this.ReqId != nil {
+		return fmt.Errorf("this.ReqId == nil && that.ReqId != nil")
+	} else if that1.ReqId != nil {
+		return fmt.Errorf("ReqId this(%v) Not Equal that(%v)", this.ReqId, that1.ReqId)
+	}
+	if this.Addr != nil && that1.Addr != nil {
+		if *this.Addr != *that1.Addr {
+			return fmt.Errorf("Addr this(%v) Not Equal that(%v)", *this.Addr, *that1.Addr)
+		}
+	} else if this.Addr != nil {
+		return fmt.Errorf("this.Addr == nil && that.Addr != nil")
+	} else if that1.Addr != nil {
+		return fmt.Errorf("Addr this(%v) Not Equal that(%v)", this.Addr, that1.Addr)
+	}
+	if len(this.Neighbors) != len(that1.Neighbors) {
+		return fmt.Errorf("Neighbors this(%v) Not Equal that(%v)", len(this.Neighbors), len(that1.Neighbors))
+	}
+	for i := range this.Neighbors {
+		if !this.Neighbors[i].Equal(that1.Neighbors[i]) {
+			return fmt.Errorf("Neighbors this[%v](%v) Not Equal that[%v](%v)", i, this.Neighbors[i], i, that1.Neighbors[i])
+		}
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
+	}
+	return nil
+}
+func (this *TopologyReply) Equal(that interface{}) bool {
+	if that == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	}
+
+	that1, ok := that.(*TopologyReply)
+	if !ok {
+		that2, ok := that.(TopologyReply)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	} else if this == nil {
+		return false
+	}
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return false
+		}
+	} else if this.Id != nil {
+		return false
+	} else if that1.Id != nil {
+		return false
+	}
+	if this.ReqId != nil && that1.ReqId != nil {
+		if *this.ReqId != *that1.ReqId {
+			return false
+		}
+	} else if this.ReqId != nil {
+		return false
+	} else if that1.ReqId != nil {
+		return false
+	}
+	if this.Addr != nil && that1.Addr != nil {
+		if *this.Addr != *that1.Addr {
+			return false
+		}
+	} else if this.Addr != nil {
+		return false
+	} else if that1.Addr != nil {
+		return false
+	}
+	if len(this.Neighbors) != len(that1.Neighbors) {
+		return false
+	}
+	for i := range this.Neighbors {
+		if !this.Neighbors[i].Equal(that1.Neighbors[i]) {
+			return false
+		}
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
+	}
+	return true
+}
+func (this *Settings) VerboseEqual(that interface{}) error {
+	if that == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that == nil && this != nil")
+	}
+
+	that1, ok := that.(*Settings)
+	if !ok {
+		that2, ok := that.(Settings)
+		if ok {
+			that1 = &that2
+		} else {
+			return fmt.Errorf("that is not of type *Settings")
+		}
+	}
+	if that1 == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that is type *Settings but is nil && this != nil")
+	} else if this == nil {
+		return fmt.Errorf("that is type *Settings but is not nil && this == nil")
+	}
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return fmt.Errorf("Id this(%v) Not Equal that(%v)", *this.Id, *that1.Id)
+		}
+	} else if this.Id != nil {
+		return fmt.Errorf("this.Id == nil && that.Id != nil")
+	} else if that1.Id != nil {
+		return fmt.Errorf("Id this(%v) Not Equal that(%v)", this.Id, that1.Id)
+	}
+	if len(this.Settings) != len(that1.Settings) {
+		return fmt.Errorf("Settings this(%v) Not Equal that(%v)", len(this.Settings), len(that1.Settings))
+	}
+	for i := range this.Settings {
+		if !this.Settings[i].Equal(that1.Settings[i]) {
+			return fmt.Errorf("Settings this[%v](%v) Not Equal that[%v](%v)", i, this.Settings[i], i, that1.Settings[i])
+		}
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
+	}
+	return nil
+}
+func (this *Settings) Equal(that interface{}) bool {
+	if that == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	}
+
+	that1, ok := that.(*Settings)
+	if !ok {
+		that2, ok := that.(Settings)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	} else if this == nil {
+		return false
+	}
+	if this.Id != nil && that1.Id != nil {
+		if *this.Id != *that1.Id {
+			return false
+		}
+	} else if this.Id != nil {
+		return false
+	} else if that1.Id != nil {
+		return false
+	}
+	if len(this.Settings) != len(that1.Settings) {
+		return false
+	}
+	for i := range this.Settings {
+		if !this.Settings[i].Equal(that1.Settings[i]) {
+			return false
+		}
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
+	}
+	return true
+}
+func (this *Setting) VerboseEqual(that interface{}) error {
+	if that == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that == nil && this != nil")
+	}
+
+	that1, ok := that.(*Setting)
+	if !ok {
+		that2, ok := that.(Setting)
+		if ok {
+			that1 = &that2
+		} else {
+			return fmt.Errorf("that is not of type *Setting")
+		}
+	}
+	if that1 == nil {
+		if this == nil {
+			return nil
+		}
+		return fmt.Errorf("that is type *Setting but is nil && this != nil")
+	} else if this == nil {
+		return fmt.Errorf("that is type *Setting but is not nil && this == nil")
+	}
+	if this.Key != nil && that1.Key != nil {
+		if *this.Key != *that1.Key {
+			return fmt.Errorf("Key this(%v) Not Equal that(%v)", *this.Key, *that1.Key)
+		}
+	} else if this.Key != nil {
+		return fmt.Errorf("this.Key == nil && that.Key != nil")
+	} else if that1.Key != nil {
+		return fmt.Errorf("Key this(%v) Not Equal that(%v)", this.Key, that1.Key)
+	}
+	if this.Value != nil && that1.Value != nil {
+		if *this.Value != *that1.Value {
+			return fmt.Errorf("Value this(%v) Not Equal that(%v)", *this.Value, *that1.Value)
+		}
+	} else if this.Value != nil {
+		return fmt.Errorf("this.Value == nil && that.Value != nil")
+	} else if that1.Value != nil {
+		return fmt.Errorf("Value this(%v) Not Equal that(%v)", this.Value, that1.Value)
+	}
+	if this.Ts != nil && that1.Ts != nil {
+		if *this.Ts != *that1.Ts {
+			return fmt.Errorf("Ts this(%v) Not Equal that(%v)", *this.Ts, *that1.Ts)
+		}
+	} else if this.Ts != nil {
+		return fmt.Errorf("this.Ts == nil && that.Ts != nil")
+	} else if that1.Ts != nil {
+		return fmt.Errorf("Ts this(%v) Not Equal that(%v)", this.Ts, that1.Ts)
+	}
+	if this.Origin != nil && that1.Origin != nil {
+		if *this.Origin != *that1.Origin {
+			return fmt.Errorf("Origin this(%v) Not Equal that(%v)", *this.Origin, *that1.Origin)
+		}
+	} else if this.Origin != nil {
+		return fmt.Errorf("this.Origin == nil && that.Origin != nil")
+	} else if that1.Origin != nil {
+		return fmt.Errorf("Origin this(%v) Not Equal that(%v)", this.Origin, that1.Origin)
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return fmt.Errorf("XXX_unrecognized this(%v) Not Equal that(%v)", this.XXX_unrecognized, that1.XXX_unrecognized)
+	}
+	return nil
+}
+func (this *Setting) Equal(that interface{}) bool {
+	if that == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	}
+
+	that1, ok := that.(*Setting)
+	if !ok {
+		that2, ok := that.(Setting)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		if this == nil {
+			return true
+		}
+		return false
+	} else if this == nil {
+		return false
+	}
+	if this.Key != nil && that1.Key != nil {
+		if *this.Key != *that1.Key {
+			return false
+		}
+	} else if this.Key != nil {
+		return false
+	} else if that1.Key != nil {
+		return false
+	}
+	if this.Value != nil && that1.Value != nil {
+		if *this.Value != *that1.Value {
+			return false
+		}
+	} else if this.Value != nil {
+		return false
+	} else if that1.Value != nil {
+		return false
+	}
+	if this.Ts != nil && that1.Ts != nil {
+		if *this.Ts != *that1.Ts {
+			return false
+		}
+	} else if this.Ts != nil {
+		return false
+	} else if that1.Ts != nil {
+		return false
+	}
+	if this.Origin != nil && that1.Origin != nil {
+		if *this.Origin != *that1.Origin {
+			return false
+		}
+	} else if this.Origin != nil {
+		return false
+	} else if that1.Origin != nil {
+		return false
+	}
+	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
+		return false
+	}
+	return true
+}
+func (this *UserMessage) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 20)
+	s = append(s, "&message.UserMessage{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.Payload != nil {
+		s = append(s, "Payload: "+valueToGoStringMessage(this.Payload, "byte")+",\n")
+	}
+	if this.Ts != nil {
+		s = append(s, "Ts: "+valueToGoStringMessage(this.Ts, "int64")+",\n")
+	}
+	if this.Trace != nil {
+		s = append(s, "Trace: "+valueToGoStringMessage(this.Trace, "byte")+",\n")
+	}
+	if this.Topic != nil {
+		s = append(s, "Topic: "+valueToGoStringMessage(this.Topic, "string")+",\n")
+	}
+	if this.Seq != nil {
+		s = append(s, "Seq: "+valueToGoStringMessage(this.Seq, "uint64")+",\n")
+	}
+	if this.Hops != nil {
+		s = append(s, "Hops: "+valueToGoStringMessage(this.Hops, "uint32")+",\n")
+	}
+	if this.Life != nil {
+		s = append(s, "Life: "+valueToGoStringMessage(this.Life, "int64")+",\n")
+	}
+	if this.Key != nil {
+		s = append(s, "Key: "+valueToGoStringMessage(this.Key, "byte")+",\n")
+	}
+	if this.Sig != nil {
+		s = append(s, "Sig: "+valueToGoStringMessage(this.Sig, "byte")+",\n")
+	}
+	if this.ChunkId != nil {
+		s = append(s, "ChunkId: "+valueToGoStringMessage(this.ChunkId, "uint64")+",\n")
+	}
+	if this.ChunkIndex != nil {
+		s = append(s, "ChunkIndex: "+valueToGoStringMessage(this.ChunkIndex, "uint32")+",\n")
+	}
+	if this.ChunkCount != nil {
+		s = append(s, "ChunkCount: "+valueToGoStringMessage(this.ChunkCount, "uint32")+",\n")
+	}
+	if this.OriginSeq != nil {
+		s = append(s, "OriginSeq: "+valueToGoStringMessage(this.OriginSeq, "uint64")+",\n")
+	}
+	if this.ChunkHash != nil {
+		s = append(s, "ChunkHash: "+valueToGoStringMessage(this.ChunkHash, "byte")+",\n")
+	}
+	if this.OriginEpoch != nil {
+		s = append(s, "OriginEpoch: "+valueToGoStringMessage(this.OriginEpoch, "uint64")+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *Label) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 6)
+	s = append(s, "&message.Label{")
+	if this.Key != nil {
+		s = append(s, "Key: "+valueToGoStringMessage(this.Key, "string")+",\n")
+	}
+	if this.Value != nil {
+		s = append(s, "Value: "+valueToGoStringMessage(this.Value, "string")+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *Join) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 11)
+	s = append(s, "&message.Join{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.Addr != nil {
+		s = append(s, "Addr: "+valueToGoStringMessage(this.Addr, "string")+",\n")
+	}
+	if this.Observe != nil {
+		s = append(s, "Observe: "+valueToGoStringMessage(this.Observe, "bool")+",\n")
+	}
+	if this.Labels != nil {
+		s = append(s, "Labels: "+fmt.Sprintf("%#v", this.Labels)+",\n")
+	}
+	if this.Caps != nil {
+		s = append(s, "Caps: "+valueToGoStringMessage(this.Caps, "uint32")+",\n")
+	}
+	if this.Mac != nil {
+		s = append(s, "Mac: "+valueToGoStringMessage(this.Mac, "byte")+",\n")
+	}
+	if this.Cluster != nil {
+		s = append(s, "Cluster: "+valueToGoStringMessage(this.Cluster, "string")+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *JoinReply) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 8)
+	s = append(s, "&message.JoinReply{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.Accept != nil {
+		s = append(s, "Accept: "+valueToGoStringMessage(this.Accept, "bool")+",\n")
+	}
+	if this.Labels != nil {
+		s = append(s, "Labels: "+fmt.Sprintf("%#v", this.Labels)+",\n")
+	}
+	if this.Caps != nil {
+		s = append(s, "Caps: "+valueToGoStringMessage(this.Caps, "uint32")+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *Neighbor) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 11)
+	s = append(s, "&message.Neighbor{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.Addr != nil {
+		s = append(s, "Addr: "+valueToGoStringMessage(this.Addr, "string")+",\n")
+	}
+	if this.Priority != nil {
+		s = append(s, "Priority: "+valueToGoStringMessage(this.Priority, "message.Neighbor_Priority")+",\n")
+	}
+	if this.Labels != nil {
+		s = append(s, "Labels: "+fmt.Sprintf("%#v", this.Labels)+",\n")
+	}
+	if this.Caps != nil {
+		s = append(s, "Caps: "+valueToGoStringMessage(this.Caps, "uint32")+",\n")
+	}
+	if this.Mac != nil {
+		s = append(s, "Mac: "+valueToGoStringMessage(this.Mac, "byte")+",\n")
+	}
+	if this.Cluster != nil {
+		s = append(s, "Cluster: "+valueToGoStringMessage(this.Cluster, "string")+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *NeighborReply) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 8)
+	s = append(s, "&message.NeighborReply{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.Accept != nil {
+		s = append(s, "Accept: "+valueToGoStringMessage(this.Accept, "bool")+",\n")
+	}
+	if this.Labels != nil {
+		s = append(s, "Labels: "+fmt.Sprintf("%#v", this.Labels)+",\n")
+	}
+	if this.Caps != nil {
+		s = append(s, "Caps: "+valueToGoStringMessage(this.Caps, "uint32")+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *ForwardJoin) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 9)
+	s = append(s, "&message.ForwardJoin{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.SourceId != nil {
+		s = append(s, "SourceId: "+valueToGoStringMessage(this.SourceId, "uint64")+",\n")
+	}
+	if this.SourceAddr != nil {
+		s = append(s, "SourceAddr: "+valueToGoStringMessage(this.SourceAddr, "string")+",\n")
+	}
+	if this.Ttl != nil {
+		s = append(s, "Ttl: "+valueToGoStringMessage(this.Ttl, "uint32")+",\n")
+	}
+	if this.SourceLabels != nil {
+		s = append(s, "SourceLabels: "+fmt.Sprintf("%#v", this.SourceLabels)+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *Disconnect) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 5)
+	s = append(s, "&message.Disconnect{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *Candidate) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 7)
+	s = append(s, "&message.Candidate{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.Addr != nil {
+		s = append(s, "Addr: "+valueToGoStringMessage(this.Addr, "string")+",\n")
+	}
+	if this.Labels != nil {
+		s = append(s, "Labels: "+fmt.Sprintf("%#v", this.Labels)+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *Shuffle) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 12)
+	s = append(s, "&message.Shuffle{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.SourceId != nil {
+		s = append(s, "SourceId: "+valueToGoStringMessage(this.SourceId, "uint64")+",\n")
+	}
+	if this.Addr != nil {
+		s = append(s, "Addr: "+valueToGoStringMessage(this.Addr, "string")+",\n")
+	}
+	if this.Candidates != nil {
+		s = append(s, "Candidates: "+fmt.Sprintf("%#v", this.Candidates)+",\n")
+	}
+	if this.Ttl != nil {
+		s = append(s, "Ttl: "+valueToGoStringMessage(this.Ttl, "uint32")+",\n")
+	}
+	if this.Udp != nil {
+		s = append(s, "Udp: "+valueToGoStringMessage(this.Udp, "bool")+",\n")
+	}
+	if this.Cluster != nil {
+		s = append(s, "Cluster: "+valueToGoStringMessage(this.Cluster, "string")+",\n")
+	}
+	if this.Nonce != nil {
+		s = append(s, "Nonce: "+valueToGoStringMessage(this.Nonce, "uint64")+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *ShuffleReply) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 7)
+	s = append(s, "&message.ShuffleReply{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.Candidates != nil {
+		s = append(s, "Candidates: "+fmt.Sprintf("%#v", this.Candidates)+",\n")
+	}
+	if this.Nonce != nil {
+		s = append(s, "Nonce: "+valueToGoStringMessage(this.Nonce, "uint64")+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *Request) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 10)
+	s = append(s, "&message.Request{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.ReqId != nil {
+		s = append(s, "ReqId: "+valueToGoStringMessage(this.ReqId, "uint64")+",\n")
+	}
+	if this.Addr != nil {
+		s = append(s, "Addr: "+valueToGoStringMessage(this.Addr, "string")+",\n")
+	}
+	if this.Payload != nil {
+		s = append(s, "Payload: "+valueToGoStringMessage(this.Payload, "byte")+",\n")
+	}
+	if this.Ts != nil {
+		s = append(s, "Ts: "+valueToGoStringMessage(this.Ts, "int64")+",\n")
+	}
+	if this.Target != nil {
+		s = append(s, "Target: "+valueToGoStringMessage(this.Target, "uint64")+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *Reply) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 7)
+	s = append(s, "&message.Reply{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.ReqId != nil {
+		s = append(s, "ReqId: "+valueToGoStringMessage(this.ReqId, "uint64")+",\n")
+	}
+	if this.Payload != nil {
+		s = append(s, "Payload: "+valueToGoStringMessage(this.Payload, "byte")+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *IHave) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 6)
+	s = append(s, "&message.IHave{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.MsgIds != nil {
+		s = append(s, "MsgIds: "+fmt.Sprintf("%#v", this.MsgIds)+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *Graft) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 6)
+	s = append(s, "&message.Graft{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.MsgId != nil {
+		s = append(s, "MsgId: "+valueToGoStringMessage(this.MsgId, "byte")+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *Prune) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 5)
+	s = append(s, "&message.Prune{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *Ack) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 6)
+	s = append(s, "&message.Ack{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.Seq != nil {
+		s = append(s, "Seq: "+valueToGoStringMessage(this.Seq, "uint64")+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *Ping) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 6)
+	s = append(s, "&message.Ping{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.Seq != nil {
+		s = append(s, "Seq: "+valueToGoStringMessage(this.Seq, "uint64")+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *Pong) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 6)
+	s = append(s, "&message.Pong{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.Seq != nil {
+		s = append(s, "Seq: "+valueToGoStringMessage(this.Seq, "uint64")+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *Batch) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 5)
+	s = append(s, "&message.Batch{")
+	if this.Messages != nil {
+		s = append(s, "Messages: "+fmt.Sprintf("%#v", this.Messages)+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *Digest) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 7)
+	s = append(s, "&message.Digest{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.Reachable != nil {
+		s = append(s, "Reachable: "+valueToGoStringMessage(this.Reachable, "uint32")+",\n")
+	}
+	if this.Sample != nil {
+		s = append(s, "Sample: "+fmt.Sprintf("%#v", this.Sample)+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *DigestEntry) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 7)
+	s = append(s, "&message.DigestEntry{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.Addr != nil {
+		s = append(s, "Addr: "+valueToGoStringMessage(this.Addr, "string")+",\n")
+	}
+	if this.Age != nil {
+		s = append(s, "Age: "+valueToGoStringMessage(this.Age, "int64")+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *TopologyRequest) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 9)
+	s = append(s, "&message.TopologyRequest{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.ReqId != nil {
+		s = append(s, "ReqId: "+valueToGoStringMessage(this.ReqId, "uint64")+",\n")
+	}
+	if this.Addr != nil {
+		s = append(s, "Addr: "+valueToGoStringMessage(this.Addr, "string")+",\n")
+	}
+	if this.Ttl != nil {
+		s = append(s, "Ttl: "+valueToGoStringMessage(this.Ttl, "uint32")+",\n")
+	}
+	if this.Ts != nil {
+		s = append(s, "Ts: "+valueToGoStringMessage(this.Ts, "int64")+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *TopologyReply) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 8)
+	s = append(s, "&message.TopologyReply{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.ReqId != nil {
+		s = append(s, "ReqId: "+valueToGoStringMessage(this.ReqId, "uint64")+",\n")
+	}
+	if this.Addr != nil {
+		s = append(s, "Addr: "+valueToGoStringMessage(this.Addr, "string")+",\n")
+	}
+	if this.Neighbors != nil {
+		s = append(s, "Neighbors: "+fmt.Sprintf("%#v", this.Neighbors)+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *Settings) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 6)
+	s = append(s, "&message.Settings{")
+	if this.Id != nil {
+		s = append(s, "Id: "+valueToGoStringMessage(this.Id, "uint64")+",\n")
+	}
+	if this.Settings != nil {
+		s = append(s, "Settings: "+fmt.Sprintf("%#v", this.Settings)+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *Setting) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 8)
+	s = append(s, "&message.Setting{")
+	if this.Key != nil {
+		s = append(s, "Key: "+valueToGoStringMessage(this.Key, "string")+",\n")
+	}
+	if this.Value != nil {
+		s = append(s, "Value: "+valueToGoStringMessage(this.Value, "string")+",\n")
+	}
+	if this.Ts != nil {
+		s = append(s, "Ts: "+valueToGoStringMessage(this.Ts, "int64")+",\n")
+	}
+	if this.Origin != nil {
+		s = append(s, "Origin: "+valueToGoStringMessage(this.Origin, "uint64")+",\n")
+	}
+	if this.XXX_unrecognized != nil {
+		s = append(s, "XXX_unrecognized:"+fmt.Sprintf("%#v", this.XXX_unrecognized)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func valueToGoStringMessage(v interface{}, typ string) string {
+	rv := reflect.ValueOf(v)
+	if rv.IsNil() {
+		return "nil"
+	}
+	pv := reflect.Indirect(rv).Interface()
+	return fmt.Sprintf("func(v %v) *%v { return &v } ( %#v )", typ, typ, pv)
+}
+func (m *UserMessage) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *UserMessage) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.Payload != nil {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Payload)))
+		i += copy(dAtA[i:], m.Payload)
+	}
+	if m.Ts == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("ts")
+	} else {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Ts))
+	}
+	if m.Trace != nil {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Trace)))
+		i += copy(dAtA[i:], m.Trace)
+	}
+	if m.Topic != nil {
+		dAtA[i] = 0x2a
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Topic)))
+		i += copy(dAtA[i:], *m.Topic)
+	}
+	if m.Seq != nil {
+		dAtA[i] = 0x30
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Seq))
+	}
+	if m.Hops != nil {
+		dAtA[i] = 0x38
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Hops))
+	}
+	if m.Life != nil {
+		dAtA[i] = 0x40
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Life))
+	}
+	if m.Key != nil {
+		dAtA[i] = 0x4a
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Key)))
+		i += copy(dAtA[i:], m.Key)
+	}
+	if m.Sig != nil {
+		dAtA[i] = 0x52
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Sig)))
+		i += copy(dAtA[i:], m.Sig)
+	}
+	if m.ChunkId != nil {
+		dAtA[i] = 0x58
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.ChunkId))
+	}
+	if m.ChunkIndex != nil {
+		dAtA[i] = 0x60
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.ChunkIndex))
+	}
+	if m.ChunkCount != nil {
+		dAtA[i] = 0x68
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.ChunkCount))
+	}
+	if m.OriginSeq != nil {
+		dAtA[i] = 0x70
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.OriginSeq))
+	}
+	if m.ChunkHash != nil {
+		dAtA[i] = 0x7a
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.ChunkHash)))
+		i += copy(dAtA[i:], m.ChunkHash)
+	}
+	if m.OriginEpoch != nil {
+		dAtA[i] = 0x80
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.OriginEpoch))
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *Label) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Label) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Key == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("key")
+	} else {
+		dAtA[i] = 0xa
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Key)))
+		i += copy(dAtA[i:], *m.Key)
+	}
+	if m.Value == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("value")
+	} else {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Value)))
+		i += copy(dAtA[i:], *m.Value)
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *Join) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Join) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.Addr == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
+	} else {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Addr)))
+		i += copy(dAtA[i:], *m.Addr)
+	}
+	if m.Observe != nil {
+		dAtA[i] = 0x18
+		i++
+		if *m.Observe {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if len(m.Labels) > 0 {
+		for _, msg := range m.Labels {
+			dAtA[i] = 0x22
+			i++
+			i = encodeVarintMessage(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.Caps != nil {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Caps))
+	}
+	if m.Mac != nil {
+		dAtA[i] = 0x32
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Mac)))
+		i += copy(dAtA[i:], m.Mac)
+	}
+	if m.Cluster != nil {
+		dAtA[i] = 0x3a
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Cluster)))
+		i += copy(dAtA[i:], *m.Cluster)
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *JoinReply) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *JoinReply) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.Accept == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("accept")
+	} else {
+		dAtA[i] = 0x10
+		i++
+		if *m.Accept {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if len(m.Labels) > 0 {
+		for _, msg := range m.Labels {
+			dAtA[i] = 0x1a
+			i++
+			i = encodeVarintMessage(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.Caps != nil {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Caps))
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *Neighbor) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Neighbor) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.Addr == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
+	} else {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Addr)))
+		i += copy(dAtA[i:], *m.Addr)
+	}
+	if m.Priority == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("priority")
+	} else {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Priority))
+	}
+	if len(m.Labels) > 0 {
+		for _, msg := range m.Labels {
+			dAtA[i] = 0x22
+			i++
+			i = encodeVarintMessage(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.Caps != nil {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Caps))
+	}
+	if m.Mac != nil {
+		dAtA[i] = 0x32
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Mac)))
+		i += copy(dAtA[i:], m.Mac)
+	}
+	if m.Cluster != nil {
+		dAtA[i] = 0x3a
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Cluster)))
+		i += copy(dAtA[i:], *m.Cluster)
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *NeighborReply) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *NeighborReply) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.Accept == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("accept")
+	} else {
+		dAtA[i] = 0x10
+		i++
+		if *m.Accept {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if len(m.Labels) > 0 {
+		for _, msg := range m.Labels {
+			dAtA[i] = 0x1a
+			i++
+			i = encodeVarintMessage(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.Caps != nil {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Caps))
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *ForwardJoin) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ForwardJoin) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.SourceId == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("sourceId")
+	} else {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.SourceId))
+	}
+	if m.SourceAddr == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("sourceAddr")
+	} else {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(*m.SourceAddr)))
+		i += copy(dAtA[i:], *m.SourceAddr)
+	}
+	if m.Ttl == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("ttl")
+	} else {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Ttl))
+	}
+	if len(m.SourceLabels) > 0 {
+		for _, msg := range m.SourceLabels {
+			dAtA[i] = 0x2a
+			i++
+			i = encodeVarintMessage(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *Disconnect) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Disconnect) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *Candidate) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Candidate) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.Addr == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
+	} else {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Addr)))
+		i += copy(dAtA[i:], *m.Addr)
+	}
+	if len(m.Labels) > 0 {
+		for _, msg := range m.Labels {
+			dAtA[i] = 0x1a
+			i++
+			i = encodeVarintMessage(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *Shuffle) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Shuffle) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.SourceId == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("sourceId")
+	} else {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.SourceId))
+	}
+	if m.Addr == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
+	} else {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Addr)))
+		i += copy(dAtA[i:], *m.Addr)
+	}
+	if len(m.Candidates) > 0 {
+		for _, msg := range m.Candidates {
+			dAtA[i] = 0x22
+			i++
+			i = encodeVarintMessage(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.Ttl == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("ttl")
+	} else {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Ttl))
+	}
+	if m.Udp != nil {
+		dAtA[i] = 0x30
+		i++
+		if *m.Udp {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if m.Cluster != nil {
+		dAtA[i] = 0x3a
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Cluster)))
+		i += copy(dAtA[i:], *m.Cluster)
+	}
+	if m.Nonce != nil {
+		dAtA[i] = 0x40
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Nonce))
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *ShuffleReply) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ShuffleReply) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if len(m.Candidates) > 0 {
+		for _, msg := range m.Candidates {
+			dAtA[i] = 0x12
+			i++
+			i = encodeVarintMessage(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.Nonce != nil {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Nonce))
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *Request) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Request) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.ReqId == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("reqId")
+	} else {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.ReqId))
+	}
+	if m.Addr == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
+	} else {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Addr)))
+		i += copy(dAtA[i:], *m.Addr)
+	}
+	if m.Payload != nil {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Payload)))
+		i += copy(dAtA[i:], m.Payload)
+	}
+	if m.Ts == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("ts")
+	} else {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Ts))
+	}
+	if m.Target != nil {
+		dAtA[i] = 0x30
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Target))
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *Reply) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Reply) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.ReqId == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("reqId")
+	} else {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.ReqId))
+	}
+	if m.Payload != nil {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.Payload)))
+		i += copy(dAtA[i:], m.Payload)
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *IHave) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *IHave) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if len(m.MsgIds) > 0 {
+		for _, b := range m.MsgIds {
+			dAtA[i] = 0x12
+			i++
+			i = encodeVarintMessage(dAtA, i, uint64(len(b)))
+			i += copy(dAtA[i:], b)
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *Graft) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Graft) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.MsgId == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("msgId")
+	} else {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(m.MsgId)))
+		i += copy(dAtA[i:], m.MsgId)
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *Prune) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Prune) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *Ack) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Ack) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.Seq == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("seq")
+	} else {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Seq))
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *Ping) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Ping) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.Seq == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("seq")
+	} else {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Seq))
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *Pong) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Pong) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.Seq == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("seq")
+	} else {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Seq))
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *Batch) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Batch) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Messages) > 0 {
+		for _, msg := range m.Messages {
+			dAtA[i] = 0xa
+			i++
+			i = encodeVarintMessage(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *Digest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Digest) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.Reachable == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("reachable")
+	} else {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Reachable))
+	}
+	if len(m.Sample) > 0 {
+		for _, msg := range m.Sample {
+			dAtA[i] = 0x1a
+			i++
+			i = encodeVarintMessage(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *DigestEntry) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *DigestEntry) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.Addr == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
+	} else {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Addr)))
+		i += copy(dAtA[i:], *m.Addr)
+	}
+	if m.Age == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("age")
+	} else {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Age))
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *TopologyRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *TopologyRequest) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.ReqId == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("reqId")
+	} else {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.ReqId))
+	}
+	if m.Addr == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
+	} else {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Addr)))
+		i += copy(dAtA[i:], *m.Addr)
+	}
+	if m.Ttl == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("ttl")
+	} else {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Ttl))
+	}
+	if m.Ts == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("ts")
+	} else {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Ts))
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *TopologyReply) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *TopologyReply) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if m.ReqId == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("reqId")
+	} else {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.ReqId))
+	}
+	if m.Addr == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
+	} else {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Addr)))
+		i += copy(dAtA[i:], *m.Addr)
+	}
+	if len(m.Neighbors) > 0 {
+		for _, msg := range m.Neighbors {
+			dAtA[i] = 0x22
+			i++
+			i = encodeVarintMessage(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *Settings) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Settings) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Id == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	} else {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Id))
+	}
+	if len(m.Settings) > 0 {
+		for _, msg := range m.Settings {
+			dAtA[i] = 0x12
+			i++
+			i = encodeVarintMessage(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *Setting) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Setting) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Key == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("key")
+	} else {
+		dAtA[i] = 0xa
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Key)))
+		i += copy(dAtA[i:], *m.Key)
+	}
+	if m.Value == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("value")
+	} else {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(len(*m.Value)))
+		i += copy(dAtA[i:], *m.Value)
+	}
+	if m.Ts == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("ts")
+	} else {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Ts))
+	}
+	if m.Origin == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("origin")
+	} else {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintMessage(dAtA, i, uint64(*m.Origin))
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func encodeFixed64Message(dAtA []byte, offset int, v uint64) int {
+	dAtA[offset] = uint8(v)
+	dAtA[offset+1] = uint8(v >> 8)
+	dAtA[offset+2] = uint8(v >> 16)
+	dAtA[offset+3] = uint8(v >> 24)
+	dAtA[offset+4] = uint8(v >> 32)
+	dAtA[offset+5] = uint8(v >> 40)
+	dAtA[offset+6] = uint8(v >> 48)
+	dAtA[offset+7] = uint8(v >> 56)
+	return offset + 8
+}
+func encodeFixed32Message(dAtA []byte, offset int, v uint32) int {
+	dAtA[offset] = uint8(v)
+	dAtA[offset+1] = uint8(v >> 8)
+	dAtA[offset+2] = uint8(v >> 16)
+	dAtA[offset+3] = uint8(v >> 24)
+	return offset + 4
+}
+func encodeVarintMessage(dAtA []byte, offset int, v uint64) int {
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return offset + 1
+}
+func NewPopulatedUserMessage(r randyMessage, easy bool) *UserMessage {
+	this := &UserMessage{}
+	v1 := uint64(uint64(r.Uint32()))
+	this.Id = &v1
+	if r.Intn(10) != 0 {
+		v2 := r.Intn(100)
+		this.Payload = make([]byte, v2)
+		for i := 0; i < v2; i++ {
+			this.Payload[i] = byte(r.Intn(256))
+		}
+	}
+	v3 := int64(r.Int63())
+	if r.Intn(2) == 0 {
+		v3 *= -1
+	}
+	this.Ts = &v3
+	if r.Intn(10) != 0 {
+		v4 := r.Intn(100)
+		this.Trace = make([]byte, v4)
+		for i := 0; i < v4; i++ {
+			this.Trace[i] = byte(r.Intn(256))
+		}
+	}
+	if r.Intn(10) != 0 {
+		v5 := string(randStringMessage(r))
+		this.Topic = &v5
+	}
+	if r.Intn(10) != 0 {
+		v6 := uint64(uint64(r.Uint32()))
+		this.Seq = &v6
+	}
+	if r.Intn(10) != 0 {
+		v7 := uint32(r.Uint32())
+		this.Hops = &v7
+	}
+	if r.Intn(10) != 0 {
+		v8 := int64(r.Int63())
+		if r.Intn(2) == 0 {
+			v8 *= -1
+		}
+		this.Life = &v8
+	}
+	if r.Intn(10) != 0 {
+		v9 := r.Intn(100)
+		this.Key = make([]byte, v9)
+		for i := 0; i < v9; i++ {
+			this.Key[i] = byte(r.Intn(256))
+		}
+	}
+	if r.Intn(10) != 0 {
+		v10 := r.Intn(100)
+		this.Sig = make([]byte, v10)
+		for i := 0; i < v10; i++ {
+			this.Sig[i] = byte(r.Intn(256))
+		}
+	}
+	if r.Intn(10) != 0 {
+		v11 := uint64(uint64(r.Uint32()))
+		this.ChunkId = &v11
+	}
+	if r.Intn(10) != 0 {
+		v12 := uint32(r.Uint32())
+		this.ChunkIndex = &v12
+	}
+	if r.Intn(10) != 0 {
+		v13 := uint32(r.Uint32())
+		this.ChunkCount = &v13
+	}
+	if r.Intn(10) != 0 {
+		v14 := uint64(uint64(r.Uint32()))
+		this.OriginSeq = &v14
+	}
+	if r.Intn(10) != 0 {
+		v15 := r.Intn(100)
+		this.ChunkHash = make([]byte, v15)
+		for i := 0; i < v15; i++ {
+			this.ChunkHash[i] = byte(r.Intn(256))
+		}
+	}
+	if r.Intn(10) != 0 {
+		v16 := uint64(uint64(r.Uint32()))
+		this.OriginEpoch = &v16
+	}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 17)
+	}
+	return this
+}
+
+func NewPopulatedLabel(r randyMessage, easy bool) *Label {
+	this := &Label{}
+	v17 := string(randStringMessage(r))
+	this.Key = &v17
+	v18 := string(randStringMessage(r))
+	this.Value = &v18
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 3)
+	}
+	return this
+}
+
+func NewPopulatedJoin(r randyMessage, easy bool) *Join {
+	this := &Join{}
+	v19 := uint64(uint64(r.Uint32()))
+	this.Id = &v19
+	v20 := string(randStringMessage(r))
+	this.Addr = &v20
+	if r.Intn(10) != 0 {
+		v21 := bool(bool(r.Intn(2) == 0))
+		this.Observe = &v21
+	}
+	if r.Intn(10) != 0 {
+		v22 := r.Intn(5)
+		this.Labels = make([]*Label, v22)
+		for i := 0; i < v22; i++ {
+			this.Labels[i] = NewPopulatedLabel(r, easy)
+		}
+	}
+	if r.Intn(10) != 0 {
+		v23 := uint32(r.Uint32())
+		this.Caps = &v23
+	}
+	if r.Intn(10) != 0 {
+		v24 := r.Intn(100)
+		this.Mac = make([]byte, v24)
+		for i := 0; i < v24; i++ {
+			this.Mac[i] = byte(r.Intn(256))
+		}
+	}
+	if r.Intn(10) != 0 {
+		v25 := string(randStringMessage(r))
+		this.Cluster = &v25
+	}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 8)
+	}
+	return this
+}
+
+func NewPopulatedJoinReply(r randyMessage, easy bool) *JoinReply {
+	this := &JoinReply{}
+	v26 := uint64(uint64(r.Uint32()))
+	this.Id = &v26
+	v27 := bool(bool(r.Intn(2) == 0))
+	this.Accept = &v27
+	if r.Intn(10) != 0 {
+		v28 := r.Intn(5)
+		this.Labels = make([]*Label, v28)
+		for i := 0; i < v28; i++ {
+			this.Labels[i] = NewPopulatedLabel(r, easy)
+		}
+	}
+	if r.Intn(10) != 0 {
+		v29 := uint32(r.Uint32())
+		this.Caps = &v29
+	}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 5)
+	}
+	return this
+}
+
+func NewPopulatedNeighbor(r randyMessage, easy bool) *Neighbor {
+	this := &Neighbor{}
+	v30 := uint64(uint64(r.Uint32()))
+	this.Id = &v30
+	v31 := string(randStringMessage(r))
+	this.Addr = &v31
+	v32 := Neighbor_Priority([]int32{0, 1}[r.Intn(2)])
+	this.Priority = &v32
+	if r.Intn(10) != 0 {
+		v33 := r.Intn(5)
+		this.Labels = make([]*Label, v33)
+		for i := 0; i < v33; i++ {
+			this.Labels[i] = NewPopulatedLabel(r, easy)
+		}
+	}
+	if r.Intn(10) != 0 {
+		v34 := uint32(r.Uint32())
+		this.Caps = &v34
+	}
+	if r.Intn(10) != 0 {
+		v35 := r.Intn(100)
+		this.Mac = make([]byte, v35)
+		for i := 0; i < v35; i++ {
+			this.Mac[i] = byte(r.Intn(256))
+		}
+	}
+	if r.Intn(10) != 0 {
+		v36 := string(randStringMessage(r))
+		this.Cluster = &v36
+	}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 8)
+	}
+	return this
+}
+
+func NewPopulatedNeighborReply(r randyMessage, easy bool) *NeighborReply {
+	this := &NeighborReply{}
+	v37 := uint64(uint64(r.Uint32()))
+	this.Id = &v37
+	v38 := bool(bool(r.Intn(2) == 0))
+	this.Accept = &v38
+	if r.Intn(10) != 0 {
+		v39 := r.Intn(5)
+		this.Labels = make([]*Label, v39)
+		for i := 0; i < v39; i++ {
+			this.Labels[i] = NewPopulatedLabel(r, easy)
+		}
+	}
+	if r.Intn(10) != 0 {
+		v40 := uint32(r.Uint32())
+		this.Caps = &v40
+	}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 5)
+	}
+	return this
+}
+
+func NewPopulatedForwardJoin(r randyMessage, easy bool) *ForwardJoin {
+	this := &ForwardJoin{}
+	v41 := uint64(uint64(r.Uint32()))
+	this.Id = &v41
+	v42 := uint64(uint64(r.Uint32()))
+	this.SourceId = &v42
+	v43 := string(randStringMessage(r))
+	this.SourceAddr = &v43
+	v44 := uint32(r.Uint32())
+	this.Ttl = &v44
+	if r.Intn(10) != 0 {
+		v45 := r.Intn(5)
+		this.SourceLabels = make([]*Label, v45)
+		for i := 0; i < v45; i++ {
+			this.SourceLabels[i] = NewPopulatedLabel(r, easy)
+		}
+	}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 6)
+	}
+	return this
+}
+
+func NewPopulatedDisconnect(r randyMessage, easy bool) *Disconnect {
+	this := &Disconnect{}
+	v46 := uint64(uint64(r.Uint32()))
+	this.Id = &v46
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 2)
+	}
+	return this
+}
+
+func NewPopulatedCandidate(r randyMessage, easy bool) *Candidate {
+	this := &Candidate{}
+	v47 := uint64(uint64(r.Uint32()))
+	this.Id = &v47
+	v48 := string(randStringMessage(r))
+	this.Addr = &v48
+	if r.Intn(10) != 0 {
+		v49 := r.Intn(5)
+		this.Labels = make([]*Label, v49)
+		for i := 0; i < v49; i++ {
+			this.Labels[i] = NewPopulatedLabel(r, easy)
+		}
+	}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 4)
+	}
+	return this
+}
+
+func NewPopulatedShuffle(r randyMessage, easy bool) *Shuffle {
+	this := &Shuffle{}
+	v50 := uint64(uint64(r.Uint32()))
+	this.Id = &v50
+	v51 := uint64(uint64(r.Uint32()))
+	this.SourceId = &v51
+	v52 := string(randStringMessage(r))
+	this.Addr = &v52
+	if r.Intn(10) != 0 {
+		v53 := r.Intn(5)
+		this.Candidates = make([]*Candidate, v53)
+		for i := 0; i < v53; i++ {
+			this.Candidates[i] = NewPopulatedCandidate(r, easy)
+		}
+	}
+	v54 := uint32(r.Uint32())
+	this.Ttl = &v54
+	if r.Intn(10) != 0 {
+		v55 := bool(bool(r.Intn(2) == 0))
+		this.Udp = &v55
+	}
+	if r.Intn(10) != 0 {
+		v56 := string(randStringMessage(r))
+		this.Cluster = &v56
+	}
+	if r.Intn(10) != 0 {
+		v57 := uint64(uint64(r.Uint32()))
+		this.Nonce = &v57
+	}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 9)
+	}
+	return this
+}
+
+func NewPopulatedShuffleReply(r randyMessage, easy bool) *ShuffleReply {
+	this := &ShuffleReply{}
+	v58 := uint64(uint64(r.Uint32()))
+	this.Id = &v58
+	if r.Intn(10) != 0 {
+		v59 := r.Intn(5)
+		this.Candidates = make([]*Candidate, v59)
+		for i := 0; i < v59; i++ {
+			this.Candidates[i] = NewPopulatedCandidate(r, easy)
+		}
+	}
+	if r.Intn(10) != 0 {
+		v60 := uint64(uint64(r.Uint32()))
+		this.Nonce = &v60
+	}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 4)
+	}
+	return this
+}
+
+func NewPopulatedRequest(r randyMessage, easy bool) *Request {
+	this := &Request{}
+	v61 := uint64(uint64(r.Uint32()))
+	this.Id = &v61
+	v62 := uint64(uint64(r.Uint32()))
+	this.ReqId = &v62
+	v63 := string(randStringMessage(r))
+	this.Addr = &v63
+	if r.Intn(10) != 0 {
+		v64 := r.Intn(100)
+		this.Payload = make([]byte, v64)
+		for i := 0; i < v64; i++ {
+			this.Payload[i] = byte(r.Intn(256))
+		}
+	}
+	v65 := int64(r.Int63())
+	if r.Intn(2) == 0 {
+		v65 *= -1
+	}
+	this.Ts = &v65
+	if r.Intn(10) != 0 {
+		v66 := uint64(uint64(r.Uint32()))
+		this.Target = &v66
+	}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 7)
+	}
+	return this
+}
+
+func NewPopulatedReply(r randyMessage, easy bool) *Reply {
+	this := &Reply{}
+	v67 := uint64(uint64(r.Uint32()))
+	this.Id = &v67
+	v68 := uint64(uint64(r.Uint32()))
+	this.ReqId = &v68
+	if r.Intn(10) != 0 {
+		v69 := r.Intn(100)
+		this.Payload = make([]byte, v69)
+		for i := 0; i < v69; i++ {
+			this.Payload[i] = byte(r.Intn(256))
+		}
+	}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 4)
+	}
+	return this
+}
+
+func NewPopulatedIHave(r randyMessage, easy bool) *IHave {
+	this := &IHave{}
+	v70 := uint64(uint64(r.Uint32()))
+	this.Id = &v70
+	if r.Intn(10) != 0 {
+		v71 := r.Intn(10)
+		this.MsgIds = make([][]byte, v71)
+		for i := 0; i < v71; i++ {
+			v72 := r.Intn(100)
+			this.MsgIds[i] = make([]byte, v72)
+			for j := 0; j < v72; j++ {
+				this.MsgIds[i][j] = byte(r.Intn(256))
+			}
+		}
+	}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 3)
+	}
+	return this
+}
+
+func NewPopulatedGraft(r randyMessage, easy bool) *Graft {
+	this := &Graft{}
+	v73 := uint64(uint64(r.Uint32()))
+	this.Id = &v73
+	v74 := r.Intn(100)
+	this.MsgId = make([]byte, v74)
+	for i := 0; i < v74; i++ {
+		this.MsgId[i] = byte(r.Intn(256))
+	}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 3)
+	}
+	return this
+}
+
+func NewPopulatedPrune(r randyMessage, easy bool) *Prune {
+	this := &Prune{}
+	v75 := uint64(uint64(r.Uint32()))
+	this.Id = &v75
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 2)
+	}
+	return this
+}
+
+func NewPopulatedAck(r randyMessage, easy bool) *Ack {
+	this := &Ack{}
+	v76 := uint64(uint64(r.Uint32()))
+	this.Id = &v76
+	v77 := uint64(uint64(r.Uint32()))
+	this.Seq = &v77
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 3)
+	}
+	return this
+}
+
+func NewPopulatedPing(r randyMessage, easy bool) *Ping {
+	this := &Ping{}
+	v78 := uint64(uint64(r.Uint32()))
+	this.Id = &v78
+	v79 := uint64(uint64(r.Uint32()))
+	this.Seq = &v79
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 3)
+	}
+	return this
+}
+
+func NewPopulatedPong(r randyMessage, easy bool) *Pong {
+	this := &Pong{}
+	v80 := uint64(uint64(r.Uint32()))
+	this.Id = &v80
+	v81 := uint64(uint64(r.Uint32()))
+	this.Seq = &v81
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 3)
+	}
+	return this
+}
+
+func NewPopulatedBatch(r randyMessage, easy bool) *Batch {
+	this := &Batch{}
+	if r.Intn(10) != 0 {
+		v82 := r.Intn(5)
+		this.Messages = make([]*UserMessage, v82)
+		for i := 0; i < v82; i++ {
+			this.Messages[i] = NewPopulatedUserMessage(r, easy)
+		}
+	}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 2)
+	}
+	return this
+}
+
+func NewPopulatedDigest(r randyMessage, easy bool) *Digest {
+	this := &Digest{}
+	v83 := uint64(uint64(r.Uint32()))
+	this.Id = &v83
+	v84 := uint32(r.Uint32())
+	this.Reachable = &v84
+	if r.Intn(10) != 0 {
+		v85 := r.Intn(5)
+		this.Sample = make([]*DigestEntry, v85)
+		for i := 0; i < v85; i++ {
+			this.Sample[i] = NewPopulatedDigestEntry(r, easy)
+		}
+	}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 4)
+	}
+	return this
+}
+
+func NewPopulatedDigestEntry(r randyMessage, easy bool) *DigestEntry {
+	this := &DigestEntry{}
+	v86 := uint64(uint64(r.Uint32()))
+	this.Id = &v86
+	v87 := string(randStringMessage(r))
+	this.Addr = &v87
+	v88 := int64(r.Int63())
+	if r.Intn(2) == 0 {
+		v88 *= -1
+	}
+	this.Age = &v88
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 4)
+	}
+	return this
+}
+
+func NewPopulatedTopologyRequest(r randyMessage, easy bool) *TopologyRequest {
+	this := &TopologyRequest{}
+	v89 := uint64(uint64(r.Uint32()))
+	this.Id = &v89
+	v90 := uint64(uint64(r.Uint32()))
+	this.ReqId = &v90
+	v91 := string(randStringMessage(r))
+	this.Addr = &v91
+	v92 := uint32(r.Uint32())
+	this.Ttl = &v92
+	v93 := int64(r.Int63())
+	if r.Intn(2) == 0 {
+		v93 *= -1
+	}
+	this.Ts = &v93
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 6)
+	}
+	return this
+}
+
+func NewPopulatedTopologyReply(r randyMessage, easy bool) *TopologyReply {
+	this := &TopologyReply{}
+	v94 := uint64(uint64(r.Uint32()))
+	this.Id = &v94
+	v95 := uint64(uint64(r.Uint32()))
+	this.ReqId = &v95
+	v96 := string(randStringMessage(r))
+	this.Addr = &v96
+	if r.Intn(10) != 0 {
+		v97 := r.Intn(5)
+		this.Neighbors = make([]*Candidate, v97)
+		for i := 0; i < v97; i++ {
+			this.Neighbors[i] = NewPopulatedCandidate(r, easy)
+		}
+	}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 5)
+	}
+	return this
+}
+
+func NewPopulatedSettings(r randyMessage, easy bool) *Settings {
+	this := &Settings{}
+	v98 := uint64(uint64(r.Uint32()))
+	this.Id = &v98
+	if r.Intn(10) != 0 {
+		v99 := r.Intn(5)
+		this.Settings = make([]*Setting, v99)
+		for i := 0; i < v99; i++ {
+			this.Settings[i] = NewPopulatedSetting(r, easy)
+		}
+	}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 3)
+	}
+	return this
+}
+
+func NewPopulatedSetting(r randyMessage, easy bool) *Setting {
+	this := &Setting{}
+	v100 := string(randStringMessage(r))
+	this.Key = &v100
+	v101 := string(randStringMessage(r))
+	this.Value = &v101
+	v102 := int64(r.Int63())
+	if r.Intn(2) == 0 {
+		v102 *= -1
+	}
+	this.Ts = &v102
+	v103 := uint64(uint64(r.Uint32()))
+	this.Origin = &v103
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedMessage(r, 5)
+	}
+	return this
+}
+
+type randyMessage interface {
+	Float32() float32
+	Float64() float64
+	Int63() int64
+	Int31() int32
+	Uint32() uint32
+	Intn(n int) int
+}
+
+func randUTF8RuneMessage(r randyMessage) rune {
+	ru := r.Intn(62)
+	if ru < 10 {
+		return rune(ru + 48)
+	} else if ru < 36 {
+		return rune(ru + 55)
+	}
+	return rune(ru + 61)
+}
+func randStringMessage(r randyMessage) string {
+	v104 := r.Intn(100)
+	tmps := make([]rune, v104)
+	for i := 0; i < v104; i++ {
+		tmps[i] = randUTF8RuneMessage(r)
+	}
+	return string(tmps)
+}
+func randUnrecognizedMessage(r randyMessage, maxFieldNumber int) (dAtA []byte) {
+	l := r.Intn(5)
+	for i := 0; i < l; i++ {
+		wire := r.Intn(4)
+		if wire == 3 {
+			wire = 5
+		}
+		fieldNumber := maxFieldNumber + r.Intn(100)
+		dAtA = randFieldMessage(dAtA, r, fieldNumber, wire)
+	}
+	return dAtA
+}
+func randFieldMessage(dAtA []byte, r randyMessage, fieldNumber int, wire int) []byte {
+	key := uint32(fieldNumber)<<3 | uint32(wire)
+	switch wire {
+	case 0:
+		dAtA = encodeVarintPopulateMessage(dAtA, uint64(key))
+		v105 := r.Int63()
+		if r.Intn(2) == 0 {
+			v105 *= -1
+		}
+		dAtA = encodeVarintPopulateMessage(dAtA, uint64(v105))
+	case 1:
+		dAtA = encodeVarintPopulateMessage(dAtA, uint64(key))
+		dAtA = append(dAtA, byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)))
+	case 2:
+		dAtA = encodeVarintPopulateMessage(dAtA, uint64(key))
+		ll := r.Intn(100)
+		dAtA = encodeVarintPopulateMessage(dAtA, uint64(ll))
+		for j := 0; j < ll; j++ {
+			dAtA = append(dAtA, byte(r.Intn(256)))
+		}
+	default:
+		dAtA = encodeVarintPopulateMessage(dAtA, uint64(key))
+		dAtA = append(dAtA, byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)))
+	}
+	return dAtA
+}
+func encodeVarintPopulateMessage(dAtA []byte, v uint64) []byte {
+	for v >= 1<<7 {
+		dAtA = append(dAtA, uint8(uint64(v)&0x7f|0x80))
+		v >>= 7
+	}
+	dAtA = append(dAtA, uint8(v))
+	return dAtA
+}
+func (m *UserMessage) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.Payload != nil {
+		l = len(m.Payload)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Ts != nil {
+		n += 1 + sovMessage(uint64(*m.Ts))
+	}
+	if m.Trace != nil {
+		l = len(m.Trace)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Topic != nil {
+		l = len(*m.Topic)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Seq != nil {
+		n += 1 + sovMessage(uint64(*m.Seq))
+	}
+	if m.Hops != nil {
+		n += 1 + sovMessage(uint64(*m.Hops))
+	}
+	if m.Life != nil {
+		n += 1 + sovMessage(uint64(*m.Life))
+	}
+	if m.Key != nil {
+		l = len(m.Key)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Sig != nil {
+		l = len(m.Sig)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.ChunkId != nil {
+		n += 1 + sovMessage(uint64(*m.ChunkId))
+	}
+	if m.ChunkIndex != nil {
+		n += 1 + sovMessage(uint64(*m.ChunkIndex))
+	}
+	if m.ChunkCount != nil {
+		n += 1 + sovMessage(uint64(*m.ChunkCount))
+	}
+	if m.OriginSeq != nil {
+		n += 1 + sovMessage(uint64(*m.OriginSeq))
+	}
+	if m.ChunkHash != nil {
+		l = len(m.ChunkHash)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.OriginEpoch != nil {
+		n += 2 + sovMessage(uint64(*m.OriginEpoch))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Label) Size() (n int) {
+	var l int
+	_ = l
+	if m.Key != nil {
+		l = len(*m.Key)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Value != nil {
+		l = len(*m.Value)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Join) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.Addr != nil {
+		l = len(*m.Addr)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Observe != nil {
+		n += 2
+	}
+	if len(m.Labels) > 0 {
+		for _, e := range m.Labels {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.Caps != nil {
+		n += 1 + sovMessage(uint64(*m.Caps))
+	}
+	if m.Mac != nil {
+		l = len(m.Mac)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Cluster != nil {
+		l = len(*m.Cluster)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *JoinReply) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.Accept != nil {
+		n += 2
+	}
+	if len(m.Labels) > 0 {
+		for _, e := range m.Labels {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.Caps != nil {
+		n += 1 + sovMessage(uint64(*m.Caps))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Neighbor) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.Addr != nil {
+		l = len(*m.Addr)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Priority != nil {
+		n += 1 + sovMessage(uint64(*m.Priority))
+	}
+	if len(m.Labels) > 0 {
+		for _, e := range m.Labels {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.Caps != nil {
+		n += 1 + sovMessage(uint64(*m.Caps))
+	}
+	if m.Mac != nil {
+		l = len(m.Mac)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Cluster != nil {
+		l = len(*m.Cluster)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *NeighborReply) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.Accept != nil {
+		n += 2
+	}
+	if len(m.Labels) > 0 {
+		for _, e := range m.Labels {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.Caps != nil {
+		n += 1 + sovMessage(uint64(*m.Caps))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *ForwardJoin) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.SourceId != nil {
+		n += 1 + sovMessage(uint64(*m.SourceId))
+	}
+	if m.SourceAddr != nil {
+		l = len(*m.SourceAddr)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Ttl != nil {
+		n += 1 + sovMessage(uint64(*m.Ttl))
+	}
+	if len(m.SourceLabels) > 0 {
+		for _, e := range m.SourceLabels {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Disconnect) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Candidate) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.Addr != nil {
+		l = len(*m.Addr)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if len(m.Labels) > 0 {
+		for _, e := range m.Labels {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Shuffle) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.SourceId != nil {
+		n += 1 + sovMessage(uint64(*m.SourceId))
+	}
+	if m.Addr != nil {
+		l = len(*m.Addr)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if len(m.Candidates) > 0 {
+		for _, e := range m.Candidates {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.Ttl != nil {
+		n += 1 + sovMessage(uint64(*m.Ttl))
+	}
+	if m.Udp != nil {
+		n += 2
+	}
+	if m.Cluster != nil {
+		l = len(*m.Cluster)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Nonce != nil {
+		n += 1 + sovMessage(uint64(*m.Nonce))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *ShuffleReply) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if len(m.Candidates) > 0 {
+		for _, e := range m.Candidates {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.Nonce != nil {
+		n += 1 + sovMessage(uint64(*m.Nonce))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Request) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.ReqId != nil {
+		n += 1 + sovMessage(uint64(*m.ReqId))
+	}
+	if m.Addr != nil {
+		l = len(*m.Addr)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Payload != nil {
+		l = len(m.Payload)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Ts != nil {
+		n += 1 + sovMessage(uint64(*m.Ts))
+	}
+	if m.Target != nil {
+		n += 1 + sovMessage(uint64(*m.Target))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Reply) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.ReqId != nil {
+		n += 1 + sovMessage(uint64(*m.ReqId))
+	}
+	if m.Payload != nil {
+		l = len(m.Payload)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *IHave) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if len(m.MsgIds) > 0 {
+		for _, b := range m.MsgIds {
+			l = len(b)
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Graft) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.MsgId != nil {
+		l = len(m.MsgId)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Prune) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Ack) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.Seq != nil {
+		n += 1 + sovMessage(uint64(*m.Seq))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Ping) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.Seq != nil {
+		n += 1 + sovMessage(uint64(*m.Seq))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Pong) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.Seq != nil {
+		n += 1 + sovMessage(uint64(*m.Seq))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Batch) Size() (n int) {
+	var l int
+	_ = l
+	if len(m.Messages) > 0 {
+		for _, e := range m.Messages {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Digest) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.Reachable != nil {
+		n += 1 + sovMessage(uint64(*m.Reachable))
+	}
+	if len(m.Sample) > 0 {
+		for _, e := range m.Sample {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *DigestEntry) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.Addr != nil {
+		l = len(*m.Addr)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Age != nil {
+		n += 1 + sovMessage(uint64(*m.Age))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *TopologyRequest) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.ReqId != nil {
+		n += 1 + sovMessage(uint64(*m.ReqId))
+	}
+	if m.Addr != nil {
+		l = len(*m.Addr)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Ttl != nil {
+		n += 1 + sovMessage(uint64(*m.Ttl))
+	}
+	if m.Ts != nil {
+		n += 1 + sovMessage(uint64(*m.Ts))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *TopologyReply) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if m.ReqId != nil {
+		n += 1 + sovMessage(uint64(*m.ReqId))
+	}
+	if m.Addr != nil {
+		l = len(*m.Addr)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if len(m.Neighbors) > 0 {
+		for _, e := range m.Neighbors {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Settings) Size() (n int) {
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovMessage(uint64(*m.Id))
+	}
+	if len(m.Settings) > 0 {
+		for _, e := range m.Settings {
+			l = e.Size()
+			n += 1 + l + sovMessage(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Setting) Size() (n int) {
+	var l int
+	_ = l
+	if m.Key != nil {
+		l = len(*m.Key)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Value != nil {
+		l = len(*m.Value)
+		n += 1 + l + sovMessage(uint64(l))
+	}
+	if m.Ts != nil {
+		n += 1 + sovMessage(uint64(*m.Ts))
+	}
+	if m.Origin != nil {
+		n += 1 + sovMessage(uint64(*m.Origin))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func sovMessage(x uint64) (n int) {
+	for {
+		n++
+		x >>= 7
+		if x == 0 {
+			break
+		}
+	}
+	return n
+}
+func sozMessage(x uint64) (n int) {
+	return sovMessage(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (this *UserMessage) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&UserMessage{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`Payload:` + valueToStringMessage(this.Payload) + `,`,
+		`Ts:` + valueToStringMessage(this.Ts) + `,`,
+		`Trace:` + valueToStringMessage(this.Trace) + `,`,
+		`Topic:` + valueToStringMessage(this.Topic) + `,`,
+		`Seq:` + valueToStringMessage(this.Seq) + `,`,
+		`Hops:` + valueToStringMessage(this.Hops) + `,`,
+		`Life:` + valueToStringMessage(this.Life) + `,`,
+		`Key:` + valueToStringMessage(this.Key) + `,`,
+		`Sig:` + valueToStringMessage(this.Sig) + `,`,
+		`ChunkId:` + valueToStringMessage(this.ChunkId) + `,`,
+		`ChunkIndex:` + valueToStringMessage(this.ChunkIndex) + `,`,
+		`ChunkCount:` + valueToStringMessage(this.ChunkCount) + `,`,
+		`OriginSeq:` + valueToStringMessage(this.OriginSeq) + `,`,
+		`ChunkHash:` + valueToStringMessage(this.ChunkHash) + `,`,
+		`OriginEpoch:` + valueToStringMessage(this.OriginEpoch) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *Label) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&Label{`,
+		`Key:` + valueToStringMessage(this.Key) + `,`,
+		`Value:` + valueToStringMessage(this.Value) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *Join) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&Join{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`Addr:` + valueToStringMessage(this.Addr) + `,`,
+		`Observe:` + valueToStringMessage(this.Observe) + `,`,
+		`Labels:` + strings.Replace(fmt.Sprintf("%v", this.Labels), "Label", "Label", 1) + `,`,
+		`Caps:` + valueToStringMessage(this.Caps) + `,`,
+		`Mac:` + valueToStringMessage(this.Mac) + `,`,
+		`Cluster:` + valueToStringMessage(this.Cluster) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *JoinReply) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&JoinReply{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`Accept:` + valueToStringMessage(this.Accept) + `,`,
+		`Labels:` + strings.Replace(fmt.Sprintf("%v", this.Labels), "Label", "Label", 1) + `,`,
+		`Caps:` + valueToStringMessage(this.Caps) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *Neighbor) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&Neighbor{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`Addr:` + valueToStringMessage(this.Addr) + `,`,
+		`Priority:` + valueToStringMessage(this.Priority) + `,`,
+		`Labels:` + strings.Replace(fmt.Sprintf("%v", this.Labels), "Label", "Label", 1) + `,`,
+		`Caps:` + valueToStringMessage(this.Caps) + `,`,
+		`Mac:` + valueToStringMessage(this.Mac) + `,`,
+		`Cluster:` + valueToStringMessage(this.Cluster) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *NeighborReply) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&NeighborReply{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`Accept:` + valueToStringMessage(this.Accept) + `,`,
+		`Labels:` + strings.Replace(fmt.Sprintf("%v", this.Labels), "Label", "Label", 1) + `,`,
+		`Caps:` + valueToStringMessage(this.Caps) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *ForwardJoin) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&ForwardJoin{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`SourceId:` + valueToStringMessage(this.SourceId) + `,`,
+		`SourceAddr:` + valueToStringMessage(this.SourceAddr) + `,`,
+		`Ttl:` + valueToStringMessage(this.Ttl) + `,`,
+		`SourceLabels:` + strings.Replace(fmt.Sprintf("%v", this.SourceLabels), "Label", "Label", 1) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *Disconnect) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&Disconnect{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *Candidate) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&Candidate{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`Addr:` + valueToStringMessage(this.Addr) + `,`,
+		`Labels:` + strings.Replace(fmt.Sprintf("%v", this.Labels), "Label", "Label", 1) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *Shuffle) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&Shuffle{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`SourceId:` + valueToStringMessage(this.SourceId) + `,`,
+		`Addr:` + valueToStringMessage(this.Addr) + `,`,
+		`Candidates:` + strings.Replace(fmt.Sprintf("%v", this.Candidates), "Candidate", "Candidate", 1) + `,`,
+		`Ttl:` + valueToStringMessage(this.Ttl) + `,`,
+		`Udp:` + valueToStringMessage(this.Udp) + `,`,
+		`Cluster:` + valueToStringMessage(this.Cluster) + `,`,
+		`Nonce:` + valueToStringMessage(this.Nonce) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *ShuffleReply) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&ShuffleReply{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`Candidates:` + strings.Replace(fmt.Sprintf("%v", this.Candidates), "Candidate", "Candidate", 1) + `,`,
+		`Nonce:` + valueToStringMessage(this.Nonce) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *Request) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&Request{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`ReqId:` + valueToStringMessage(this.ReqId) + `,`,
+		`Addr:` + valueToStringMessage(this.Addr) + `,`,
+		`Payload:` + valueToStringMessage(this.Payload) + `,`,
+		`Ts:` + valueToStringMessage(this.Ts) + `,`,
+		`Target:` + valueToStringMessage(this.Target) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *Reply) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&Reply{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`ReqId:` + valueToStringMessage(this.ReqId) + `,`,
+		`Payload:` + valueToStringMessage(this.Payload) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *IHave) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&IHave{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`MsgIds:` + fmt.Sprintf("%v", this.MsgIds) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *Graft) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&Graft{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`MsgId:` + valueToStringMessage(this.MsgId) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *Prune) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&Prune{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *Ack) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&Ack{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`Seq:` + valueToStringMessage(this.Seq) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *Ping) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&Ping{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`Seq:` + valueToStringMessage(this.Seq) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *Pong) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&Pong{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`Seq:` + valueToStringMessage(this.Seq) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *Batch) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&Batch{`,
+		`Messages:` + strings.Replace(fmt.Sprintf("%v", this.Messages), "UserMessage", "UserMessage", 1) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *Digest) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&Digest{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`Reachable:` + valueToStringMessage(this.Reachable) + `,`,
+		`Sample:` + strings.Replace(fmt.Sprintf("%v", this.Sample), "DigestEntry", "DigestEntry", 1) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *DigestEntry) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&DigestEntry{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`Addr:` + valueToStringMessage(this.Addr) + `,`,
+		`Age:` + valueToStringMessage(this.Age) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *TopologyRequest) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&TopologyRequest{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`ReqId:` + valueToStringMessage(this.ReqId) + `,`,
+		`Addr:` + valueToStringMessage(this.Addr) + `,`,
+		`Ttl:` + valueToStringMessage(this.Ttl) + `,`,
+		`Ts:` + valueToStringMessage(this.Ts) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *TopologyReply) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&TopologyReply{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`ReqId:` + valueToStringMessage(this.ReqId) + `,`,
+		`Addr:` + valueToStringMessage(this.Addr) + `,`,
+		`Neighbors:` + strings.Replace(fmt.Sprintf("%v", this.Neighbors), "Candidate", "Candidate", 1) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *Settings) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&Settings{`,
+		`Id:` + valueToStringMessage(this.Id) + `,`,
+		`Settings:` + strings.Replace(fmt.Sprintf("%v", this.Settings), "Setting", "Setting", 1) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *Setting) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&Setting{`,
+		`Key:` + valueToStringMessage(this.Key) + `,`,
+		`Value:` + valueToStringMessage(this.Value) + `,`,
+		`Ts:` + valueToStringMessage(this.Ts) + `,`,
+		`Origin:` + valueToStringMessage(this.Origin) + `,`,
+		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func valueToStringMessage(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if rv.IsNil() {
+		return "nil"
+	}
+	pv := reflect.Indirect(rv).Interface()
+	return fmt.Sprintf("*%v", pv)
+}
+func (m *UserMessage) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: UserMessage: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: UserMessage: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Payload", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Payload = append(m.Payload[:0], dAtA[iNdEx:postIndex]...)
+			if m.Payload == nil {
+				m.Payload = []byte{}
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ts", wireType)
+			}
+			var v int64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Ts = &v
+			hasFields[0] |= uint64(0x00000002)
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Trace", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Trace = append(m.Trace[:0], dAtA[iNdEx:postIndex]...)
+			if m.Trace == nil {
+				m.Trace = []byte{}
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Topic", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.Topic = &s
+			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Seq", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Seq = &v
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Hops", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Hops = &v
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Life", wireType)
+			}
+			var v int64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Life = &v
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = append(m.Key[:0], dAtA[iNdEx:postIndex]...)
+			if m.Key == nil {
+				m.Key = []byte{}
+			}
+			iNdEx = postIndex
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Sig", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Sig = append(m.Sig[:0], dAtA[iNdEx:postIndex]...)
+			if m.Sig == nil {
+				m.Sig = []byte{}
+			}
+			iNdEx = postIndex
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ChunkId", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ChunkId = &v
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ChunkIndex", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ChunkIndex = &v
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ChunkCount", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ChunkCount = &v
+		case 14:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field OriginSeq", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.OriginSeq = &v
+		case 15:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ChunkHash", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ChunkHash = append(m.ChunkHash[:0], dAtA[iNdEx:postIndex]...)
+			if m.ChunkHash == nil {
+				m.ChunkHash = []byte{}
+			}
+			iNdEx = postIndex
+		case 16:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field OriginEpoch", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.OriginEpoch = &v
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("ts")
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Label) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Label: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Label: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.Key = &s
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.Value = &s
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000002)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("key")
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("value")
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Join) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Join: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Join: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Addr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.Addr = &s
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Observe", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			b := bool(v != 0)
+			m.Observe = &b
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Labels", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Labels = append(m.Labels, &Label{})
+			if err := m.Labels[len(m.Labels)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Caps", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Caps = &v
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Mac", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Mac = append(m.Mac[:0], dAtA[iNdEx:postIndex]...)
+			if m.Mac == nil {
+				m.Mac = []byte{}
+			}
+			iNdEx = postIndex
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Cluster", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.Cluster = &s
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *JoinReply) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: JoinReply: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: JoinReply: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Accept", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			b := bool(v != 0)
+			m.Accept = &b
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Labels", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Labels = append(m.Labels, &Label{})
+			if err := m.Labels[len(m.Labels)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Caps", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Caps = &v
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("accept")
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Neighbor) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Neighbor: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Neighbor: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Addr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.Addr = &s
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Priority", wireType)
+			}
+			var v Neighbor_Priority
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (Neighbor_Priority(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Priority = &v
+			hasFields[0] |= uint64(0x00000004)
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Labels", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Labels = append(m.Labels, &Label{})
+			if err := m.Labels[len(m.Labels)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Caps", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Caps = &v
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Mac", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Mac = append(m.Mac[:0], dAtA[iNdEx:postIndex]...)
+			if m.Mac == nil {
+				m.Mac = []byte{}
+			}
+			iNdEx = postIndex
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Cluster", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.Cluster = &s
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
+	}
+	if hasFields[0]&uint64(0x00000004) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("priority")
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *NeighborReply) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: NeighborReply: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: NeighborReply: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Accept", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			b := bool(v != 0)
+			m.Accept = &b
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Labels", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Labels = append(m.Labels, &Label{})
+			if err := m.Labels[len(m.Labels)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Caps", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Caps = &v
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("accept")
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ForwardJoin) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ForwardJoin: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ForwardJoin: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SourceId", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.SourceId = &v
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SourceAddr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.SourceAddr = &s
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000004)
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ttl", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Ttl = &v
+			hasFields[0] |= uint64(0x00000008)
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SourceLabels", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.SourceLabels = append(m.SourceLabels, &Label{})
+			if err := m.SourceLabels[len(m.SourceLabels)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("sourceId")
+	}
+	if hasFields[0]&uint64(0x00000004) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("sourceAddr")
+	}
+	if hasFields[0]&uint64(0x00000008) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("ttl")
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Disconnect) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Disconnect: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Disconnect: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Candidate) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Candidate: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Candidate: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Addr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.Addr = &s
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Labels", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Labels = append(m.Labels, &Label{})
+			if err := m.Labels[len(m.Labels)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Shuffle) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Shuffle: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Shuffle: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SourceId", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.SourceId = &v
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Addr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.Addr = &s
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000004)
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Candidates", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Candidates = append(m.Candidates, &Candidate{})
+			if err := m.Candidates[len(m.Candidates)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ttl", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Ttl = &v
+			hasFields[0] |= uint64(0x00000008)
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Udp", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			b := bool(v != 0)
+			m.Udp = &b
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Cluster", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.Cluster = &s
+			iNdEx = postIndex
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Nonce", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Nonce = &v
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("sourceId")
+	}
+	if hasFields[0]&uint64(0x00000004) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
+	}
+	if hasFields[0]&uint64(0x00000008) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("ttl")
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ShuffleReply) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ShuffleReply: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ShuffleReply: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Candidates", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Candidates = append(m.Candidates, &Candidate{})
+			if err := m.Candidates[len(m.Candidates)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Nonce", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Nonce = &v
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Request) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Request: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Request: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReqId", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ReqId = &v
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Addr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.Addr = &s
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000004)
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Payload", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Payload = append(m.Payload[:0], dAtA[iNdEx:postIndex]...)
+			if m.Payload == nil {
+				m.Payload = []byte{}
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ts", wireType)
+			}
+			var v int64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Ts = &v
+			hasFields[0] |= uint64(0x00000008)
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Target", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Target = &v
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("reqId")
+	}
+	if hasFields[0]&uint64(0x00000004) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
+	}
+	if hasFields[0]&uint64(0x00000008) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("ts")
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Reply) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Reply: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Reply: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReqId", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ReqId = &v
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Payload", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Payload = append(m.Payload[:0], dAtA[iNdEx:postIndex]...)
+			if m.Payload == nil {
+				m.Payload = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("reqId")
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *IHave) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: IHave: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: IHave: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MsgIds", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.MsgIds = append(m.MsgIds, make([]byte, postIndex-iNdEx))
+			copy(m.MsgIds[len(m.MsgIds)-1], dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Graft) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Graft: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Graft: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MsgId", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.MsgId = append(m.MsgId[:0], dAtA[iNdEx:postIndex]...)
+			if m.MsgId == nil {
+				m.MsgId = []byte{}
+			}
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000002)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
 	}
-	s := strings.Join([]string{`&NeighborReply{`,
-		`Id:` + valueToStringMessage(this.Id) + `,`,
-		`Accept:` + valueToStringMessage(this.Accept) + `,`,
-		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
-		`}`,
-	}, "")
-	return s
-}
-func (this *ForwardJoin) String() string {
-	if this == nil {
-		return "nil"
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
 	}
-	s := strings.Join([]string{`&ForwardJoin{`,
-		`Id:` + valueToStringMessage(this.Id) + `,`,
-		`SourceId:` + valueToStringMessage(this.SourceId) + `,`,
-		`SourceAddr:` + valueToStringMessage(this.SourceAddr) + `,`,
-		`Ttl:` + valueToStringMessage(this.Ttl) + `,`,
-		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
-		`}`,
-	}, "")
-	return s
-}
-func (this *Disconnect) String() string {
-	if this == nil {
-		return "nil"
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("msgId")
 	}
-	s := strings.Join([]string{`&Disconnect{`,
-		`Id:` + valueToStringMessage(this.Id) + `,`,
-		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
-		`}`,
-	}, "")
-	return s
-}
-func (this *Candidate) String() string {
-	if this == nil {
-		return "nil"
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
 	}
-	s := strings.Join([]string{`&Candidate{`,
-		`Id:` + valueToStringMessage(this.Id) + `,`,
-		`Addr:` + valueToStringMessage(this.Addr) + `,`,
-		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
-		`}`,
-	}, "")
-	return s
+	return nil
 }
-func (this *Shuffle) String() string {
-	if this == nil {
-		return "nil"
+func (m *Prune) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Prune: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Prune: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthMessage
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
 	}
-	s := strings.Join([]string{`&Shuffle{`,
-		`Id:` + valueToStringMessage(this.Id) + `,`,
-		`SourceId:` + valueToStringMessage(this.SourceId) + `,`,
-		`Addr:` + valueToStringMessage(this.Addr) + `,`,
-		`Candidates:` + strings.Replace(fmt.Sprintf("%v", this.Candidates), "Candidate", "Candidate", 1) + `,`,
-		`Ttl:` + valueToStringMessage(this.Ttl) + `,`,
-		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
-		`}`,
-	}, "")
-	return s
-}
-func (this *ShuffleReply) String() string {
-	if this == nil {
-		return "nil"
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
 	}
-	s := strings.Join([]string{`&ShuffleReply{`,
-		`Id:` + valueToStringMessage(this.Id) + `,`,
-		`Candidates:` + strings.Replace(fmt.Sprintf("%v", this.Candidates), "Candidate", "Candidate", 1) + `,`,
-		`XXX_unrecognized:` + fmt.Sprintf("%v", this.XXX_unrecognized) + `,`,
-		`}`,
-	}, "")
-	return s
-}
-func valueToStringMessage(v interface{}) string {
-	rv := reflect.ValueOf(v)
-	if rv.IsNil() {
-		return "nil"
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
 	}
-	pv := reflect.Indirect(rv).Interface()
-	return fmt.Sprintf("*%v", pv)
+	return nil
 }
-func (m *UserMessage) Unmarshal(dAtA []byte) error {
+func (m *Ack) Unmarshal(dAtA []byte) error {
 	var hasFields [1]uint64
 	l := len(dAtA)
 	iNdEx := 0
@@ -2666,10 +10853,10 @@ func (m *UserMessage) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: UserMessage: wiretype end group for non-group")
+			return fmt.Errorf("proto: Ack: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: UserMessage: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Ack: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
@@ -2694,10 +10881,10 @@ func (m *UserMessage) Unmarshal(dAtA []byte) error {
 			m.Id = &v
 			hasFields[0] |= uint64(0x00000001)
 		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Payload", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Seq", wireType)
 			}
-			var byteLen int
+			var v uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -2707,28 +10894,97 @@ func (m *UserMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				v |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
+			m.Seq = &v
+			hasFields[0] |= uint64(0x00000002)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipMessage(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
 				return ErrInvalidLengthMessage
 			}
-			postIndex := iNdEx + byteLen
-			if postIndex > l {
+			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Payload = append(m.Payload[:0], dAtA[iNdEx:postIndex]...)
-			if m.Payload == nil {
-				m.Payload = []byte{}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("seq")
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Ping) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowMessage
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Ping: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Ping: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			iNdEx = postIndex
-		case 3:
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Ts", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Seq", wireType)
 			}
-			var v int64
+			var v uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -2738,12 +10994,12 @@ func (m *UserMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (int64(b) & 0x7F) << shift
+				v |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Ts = &v
+			m.Seq = &v
 			hasFields[0] |= uint64(0x00000002)
 		default:
 			iNdEx = preIndex
@@ -2765,7 +11021,7 @@ func (m *UserMessage) Unmarshal(dAtA []byte) error {
 		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
 	}
 	if hasFields[0]&uint64(0x00000002) == 0 {
-		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("ts")
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("seq")
 	}
 
 	if iNdEx > l {
@@ -2773,7 +11029,7 @@ func (m *UserMessage) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Join) Unmarshal(dAtA []byte) error {
+func (m *Pong) Unmarshal(dAtA []byte) error {
 	var hasFields [1]uint64
 	l := len(dAtA)
 	iNdEx := 0
@@ -2797,10 +11053,10 @@ func (m *Join) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Join: wiretype end group for non-group")
+			return fmt.Errorf("proto: Pong: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Join: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Pong: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
@@ -2825,10 +11081,10 @@ func (m *Join) Unmarshal(dAtA []byte) error {
 			m.Id = &v
 			hasFields[0] |= uint64(0x00000001)
 		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Addr", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Seq", wireType)
 			}
-			var stringLen uint64
+			var v uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -2838,22 +11094,12 @@ func (m *Join) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= (uint64(b) & 0x7F) << shift
+				v |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthMessage
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			s := string(dAtA[iNdEx:postIndex])
-			m.Addr = &s
-			iNdEx = postIndex
+			m.Seq = &v
 			hasFields[0] |= uint64(0x00000002)
 		default:
 			iNdEx = preIndex
@@ -2875,7 +11121,7 @@ func (m *Join) Unmarshal(dAtA []byte) error {
 		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
 	}
 	if hasFields[0]&uint64(0x00000002) == 0 {
-		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("seq")
 	}
 
 	if iNdEx > l {
@@ -2883,8 +11129,7 @@ func (m *Join) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *JoinReply) Unmarshal(dAtA []byte) error {
-	var hasFields [1]uint64
+func (m *Batch) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -2907,17 +11152,17 @@ func (m *JoinReply) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: JoinReply: wiretype end group for non-group")
+			return fmt.Errorf("proto: Batch: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: JoinReply: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Batch: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Messages", wireType)
 			}
-			var v uint64
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -2927,35 +11172,23 @@ func (m *JoinReply) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (uint64(b) & 0x7F) << shift
+				msglen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Id = &v
-			hasFields[0] |= uint64(0x00000001)
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Accept", wireType)
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
 			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowMessage
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= (int(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
 			}
-			b := bool(v != 0)
-			m.Accept = &b
-			hasFields[0] |= uint64(0x00000002)
+			m.Messages = append(m.Messages, &UserMessage{})
+			if err := m.Messages[len(m.Messages)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipMessage(dAtA[iNdEx:])
@@ -2972,19 +11205,13 @@ func (m *JoinReply) Unmarshal(dAtA []byte) error {
 			iNdEx += skippy
 		}
 	}
-	if hasFields[0]&uint64(0x00000001) == 0 {
-		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
-	}
-	if hasFields[0]&uint64(0x00000002) == 0 {
-		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("accept")
-	}
 
 	if iNdEx > l {
 		return io.ErrUnexpectedEOF
 	}
 	return nil
 }
-func (m *Neighbor) Unmarshal(dAtA []byte) error {
+func (m *Digest) Unmarshal(dAtA []byte) error {
 	var hasFields [1]uint64
 	l := len(dAtA)
 	iNdEx := 0
@@ -3008,10 +11235,10 @@ func (m *Neighbor) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Neighbor: wiretype end group for non-group")
+			return fmt.Errorf("proto: Digest: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Neighbor: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Digest: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
@@ -3036,10 +11263,10 @@ func (m *Neighbor) Unmarshal(dAtA []byte) error {
 			m.Id = &v
 			hasFields[0] |= uint64(0x00000001)
 		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Addr", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Reachable", wireType)
 			}
-			var stringLen uint64
+			var v uint32
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -3049,28 +11276,18 @@ func (m *Neighbor) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= (uint64(b) & 0x7F) << shift
+				v |= (uint32(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthMessage
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			s := string(dAtA[iNdEx:postIndex])
-			m.Addr = &s
-			iNdEx = postIndex
+			m.Reachable = &v
 			hasFields[0] |= uint64(0x00000002)
 		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Priority", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Sample", wireType)
 			}
-			var v Neighbor_Priority
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -3080,13 +11297,23 @@ func (m *Neighbor) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (Neighbor_Priority(b) & 0x7F) << shift
+				msglen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Priority = &v
-			hasFields[0] |= uint64(0x00000004)
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Sample = append(m.Sample, &DigestEntry{})
+			if err := m.Sample[len(m.Sample)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipMessage(dAtA[iNdEx:])
@@ -3107,10 +11334,7 @@ func (m *Neighbor) Unmarshal(dAtA []byte) error {
 		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
 	}
 	if hasFields[0]&uint64(0x00000002) == 0 {
-		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
-	}
-	if hasFields[0]&uint64(0x00000004) == 0 {
-		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("priority")
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("reachable")
 	}
 
 	if iNdEx > l {
@@ -3118,7 +11342,7 @@ func (m *Neighbor) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *NeighborReply) Unmarshal(dAtA []byte) error {
+func (m *DigestEntry) Unmarshal(dAtA []byte) error {
 	var hasFields [1]uint64
 	l := len(dAtA)
 	iNdEx := 0
@@ -3142,10 +11366,10 @@ func (m *NeighborReply) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: NeighborReply: wiretype end group for non-group")
+			return fmt.Errorf("proto: DigestEntry: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: NeighborReply: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: DigestEntry: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
@@ -3170,10 +11394,10 @@ func (m *NeighborReply) Unmarshal(dAtA []byte) error {
 			m.Id = &v
 			hasFields[0] |= uint64(0x00000001)
 		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Accept", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Addr", wireType)
 			}
-			var v int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -3183,14 +11407,44 @@ func (m *NeighborReply) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (int(b) & 0x7F) << shift
+				stringLen |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			b := bool(v != 0)
-			m.Accept = &b
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.Addr = &s
+			iNdEx = postIndex
 			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Age", wireType)
+			}
+			var v int64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Age = &v
+			hasFields[0] |= uint64(0x00000004)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipMessage(dAtA[iNdEx:])
@@ -3211,7 +11465,10 @@ func (m *NeighborReply) Unmarshal(dAtA []byte) error {
 		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
 	}
 	if hasFields[0]&uint64(0x00000002) == 0 {
-		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("accept")
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
+	}
+	if hasFields[0]&uint64(0x00000004) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("age")
 	}
 
 	if iNdEx > l {
@@ -3219,7 +11476,7 @@ func (m *NeighborReply) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *ForwardJoin) Unmarshal(dAtA []byte) error {
+func (m *TopologyRequest) Unmarshal(dAtA []byte) error {
 	var hasFields [1]uint64
 	l := len(dAtA)
 	iNdEx := 0
@@ -3243,10 +11500,10 @@ func (m *ForwardJoin) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: ForwardJoin: wiretype end group for non-group")
+			return fmt.Errorf("proto: TopologyRequest: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: ForwardJoin: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: TopologyRequest: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
@@ -3272,7 +11529,7 @@ func (m *ForwardJoin) Unmarshal(dAtA []byte) error {
 			hasFields[0] |= uint64(0x00000001)
 		case 2:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SourceId", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ReqId", wireType)
 			}
 			var v uint64
 			for shift := uint(0); ; shift += 7 {
@@ -3289,11 +11546,11 @@ func (m *ForwardJoin) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
-			m.SourceId = &v
+			m.ReqId = &v
 			hasFields[0] |= uint64(0x00000002)
 		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SourceAddr", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Addr", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -3319,7 +11576,7 @@ func (m *ForwardJoin) Unmarshal(dAtA []byte) error {
 				return io.ErrUnexpectedEOF
 			}
 			s := string(dAtA[iNdEx:postIndex])
-			m.SourceAddr = &s
+			m.Addr = &s
 			iNdEx = postIndex
 			hasFields[0] |= uint64(0x00000004)
 		case 4:
@@ -3334,84 +11591,20 @@ func (m *ForwardJoin) Unmarshal(dAtA []byte) error {
 				if iNdEx >= l {
 					return io.ErrUnexpectedEOF
 				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= (uint32(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			m.Ttl = &v
-			hasFields[0] |= uint64(0x00000008)
-		default:
-			iNdEx = preIndex
-			skippy, err := skipMessage(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthMessage
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-	if hasFields[0]&uint64(0x00000001) == 0 {
-		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
-	}
-	if hasFields[0]&uint64(0x00000002) == 0 {
-		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("sourceId")
-	}
-	if hasFields[0]&uint64(0x00000004) == 0 {
-		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("sourceAddr")
-	}
-	if hasFields[0]&uint64(0x00000008) == 0 {
-		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("ttl")
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *Disconnect) Unmarshal(dAtA []byte) error {
-	var hasFields [1]uint64
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowMessage
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: Disconnect: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Disconnect: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Ttl = &v
+			hasFields[0] |= uint64(0x00000008)
+		case 5:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Ts", wireType)
 			}
-			var v uint64
+			var v int64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -3421,13 +11614,13 @@ func (m *Disconnect) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (uint64(b) & 0x7F) << shift
+				v |= (int64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Id = &v
-			hasFields[0] |= uint64(0x00000001)
+			m.Ts = &v
+			hasFields[0] |= uint64(0x00000010)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipMessage(dAtA[iNdEx:])
@@ -3447,13 +11640,25 @@ func (m *Disconnect) Unmarshal(dAtA []byte) error {
 	if hasFields[0]&uint64(0x00000001) == 0 {
 		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
 	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("reqId")
+	}
+	if hasFields[0]&uint64(0x00000004) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
+	}
+	if hasFields[0]&uint64(0x00000008) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("ttl")
+	}
+	if hasFields[0]&uint64(0x00000010) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("ts")
+	}
 
 	if iNdEx > l {
 		return io.ErrUnexpectedEOF
 	}
 	return nil
 }
-func (m *Candidate) Unmarshal(dAtA []byte) error {
+func (m *TopologyReply) Unmarshal(dAtA []byte) error {
 	var hasFields [1]uint64
 	l := len(dAtA)
 	iNdEx := 0
@@ -3477,10 +11682,10 @@ func (m *Candidate) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Candidate: wiretype end group for non-group")
+			return fmt.Errorf("proto: TopologyReply: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Candidate: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: TopologyReply: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
@@ -3505,6 +11710,27 @@ func (m *Candidate) Unmarshal(dAtA []byte) error {
 			m.Id = &v
 			hasFields[0] |= uint64(0x00000001)
 		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReqId", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ReqId = &v
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
 			if wireType != 2 {
 				return fmt.Errorf("proto: wrong wireType = %d for field Addr", wireType)
 			}
@@ -3534,7 +11760,38 @@ func (m *Candidate) Unmarshal(dAtA []byte) error {
 			s := string(dAtA[iNdEx:postIndex])
 			m.Addr = &s
 			iNdEx = postIndex
-			hasFields[0] |= uint64(0x00000002)
+			hasFields[0] |= uint64(0x00000004)
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Neighbors", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Neighbors = append(m.Neighbors, &Candidate{})
+			if err := m.Neighbors[len(m.Neighbors)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipMessage(dAtA[iNdEx:])
@@ -3555,6 +11812,9 @@ func (m *Candidate) Unmarshal(dAtA []byte) error {
 		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
 	}
 	if hasFields[0]&uint64(0x00000002) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("reqId")
+	}
+	if hasFields[0]&uint64(0x00000004) == 0 {
 		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
 	}
 
@@ -3563,7 +11823,7 @@ func (m *Candidate) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Shuffle) Unmarshal(dAtA []byte) error {
+func (m *Settings) Unmarshal(dAtA []byte) error {
 	var hasFields [1]uint64
 	l := len(dAtA)
 	iNdEx := 0
@@ -3587,10 +11847,10 @@ func (m *Shuffle) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Shuffle: wiretype end group for non-group")
+			return fmt.Errorf("proto: Settings: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Shuffle: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Settings: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
@@ -3615,60 +11875,8 @@ func (m *Shuffle) Unmarshal(dAtA []byte) error {
 			m.Id = &v
 			hasFields[0] |= uint64(0x00000001)
 		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SourceId", wireType)
-			}
-			var v uint64
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowMessage
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= (uint64(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			m.SourceId = &v
-			hasFields[0] |= uint64(0x00000002)
-		case 3:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Addr", wireType)
-			}
-			var stringLen uint64
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowMessage
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				stringLen |= (uint64(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthMessage
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			s := string(dAtA[iNdEx:postIndex])
-			m.Addr = &s
-			iNdEx = postIndex
-			hasFields[0] |= uint64(0x00000004)
-		case 4:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Candidates", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Settings", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -3692,32 +11900,11 @@ func (m *Shuffle) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Candidates = append(m.Candidates, &Candidate{})
-			if err := m.Candidates[len(m.Candidates)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			m.Settings = append(m.Settings, &Setting{})
+			if err := m.Settings[len(m.Settings)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
-		case 5:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Ttl", wireType)
-			}
-			var v uint32
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowMessage
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= (uint32(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			m.Ttl = &v
-			hasFields[0] |= uint64(0x00000008)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipMessage(dAtA[iNdEx:])
@@ -3737,22 +11924,13 @@ func (m *Shuffle) Unmarshal(dAtA []byte) error {
 	if hasFields[0]&uint64(0x00000001) == 0 {
 		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
 	}
-	if hasFields[0]&uint64(0x00000002) == 0 {
-		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("sourceId")
-	}
-	if hasFields[0]&uint64(0x00000004) == 0 {
-		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("addr")
-	}
-	if hasFields[0]&uint64(0x00000008) == 0 {
-		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("ttl")
-	}
 
 	if iNdEx > l {
 		return io.ErrUnexpectedEOF
 	}
 	return nil
 }
-func (m *ShuffleReply) Unmarshal(dAtA []byte) error {
+func (m *Setting) Unmarshal(dAtA []byte) error {
 	var hasFields [1]uint64
 	l := len(dAtA)
 	iNdEx := 0
@@ -3776,17 +11954,17 @@ func (m *ShuffleReply) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: ShuffleReply: wiretype end group for non-group")
+			return fmt.Errorf("proto: Setting: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: ShuffleReply: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Setting: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
 			}
-			var v uint64
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -3796,18 +11974,28 @@ func (m *ShuffleReply) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (uint64(b) & 0x7F) << shift
+				stringLen |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Id = &v
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthMessage
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.Key = &s
+			iNdEx = postIndex
 			hasFields[0] |= uint64(0x00000001)
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Candidates", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowMessage
@@ -3817,23 +12005,65 @@ func (m *ShuffleReply) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				stringLen |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthMessage
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Candidates = append(m.Candidates, &Candidate{})
-			if err := m.Candidates[len(m.Candidates)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.Value = &s
 			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ts", wireType)
+			}
+			var v int64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Ts = &v
+			hasFields[0] |= uint64(0x00000004)
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Origin", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowMessage
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Origin = &v
+			hasFields[0] |= uint64(0x00000008)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipMessage(dAtA[iNdEx:])
@@ -3851,7 +12081,16 @@ func (m *ShuffleReply) Unmarshal(dAtA []byte) error {
 		}
 	}
 	if hasFields[0]&uint64(0x00000001) == 0 {
-		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("id")
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("key")
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("value")
+	}
+	if hasFields[0]&uint64(0x00000004) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("ts")
+	}
+	if hasFields[0]&uint64(0x00000008) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("origin")
 	}
 
 	if iNdEx > l {
@@ -3967,33 +12206,72 @@ var (
 func init() { proto.RegisterFile("message.proto", fileDescriptorMessage) }
 
 var fileDescriptorMessage = []byte{
-	// 445 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x94, 0x51, 0x3d, 0x8f, 0x13, 0x31,
-	0x10, 0x3d, 0x7b, 0x17, 0xb2, 0x99, 0x5c, 0x4e, 0x91, 0x0b, 0xb4, 0x8a, 0xc0, 0x5a, 0x6d, 0xb5,
-	0x42, 0x90, 0x48, 0x41, 0x82, 0x9a, 0x0f, 0xf1, 0x25, 0x40, 0xc8, 0x88, 0x1f, 0xe0, 0xac, 0x9d,
-	0x8d, 0x45, 0x2e, 0x5e, 0xd9, 0x8e, 0x4e, 0xe9, 0x68, 0xa8, 0xf9, 0x1b, 0xfc, 0x04, 0x4a, 0x4a,
-	0x4a, 0x4a, 0xca, 0xcb, 0xfe, 0x02, 0x4a, 0x4a, 0x14, 0x27, 0x0e, 0x81, 0xbb, 0x22, 0xd7, 0xcd,
-	0xb3, 0xdf, 0x9b, 0x37, 0x6f, 0x06, 0xba, 0xa7, 0xd2, 0x5a, 0x5e, 0xc9, 0x41, 0x6d, 0xb4, 0xd3,
-	0xa4, 0xb5, 0x85, 0xfd, 0xbb, 0x95, 0x72, 0xd3, 0xc5, 0x78, 0x50, 0xea, 0xd3, 0x61, 0xa5, 0x2b,
-	0x3d, 0xf4, 0xff, 0xe3, 0xc5, 0xc4, 0x23, 0x0f, 0x7c, 0xb5, 0xd1, 0xe5, 0xcf, 0xa0, 0xf3, 0xde,
-	0x4a, 0xf3, 0x7a, 0xa3, 0x26, 0x27, 0x80, 0x95, 0x48, 0x51, 0x86, 0x8b, 0x98, 0x61, 0x25, 0x48,
-	0x0a, 0xad, 0x9a, 0x2f, 0x67, 0x9a, 0x8b, 0x14, 0x67, 0xa8, 0x38, 0x66, 0x01, 0xae, 0x99, 0xce,
-	0xa6, 0x51, 0x86, 0x8b, 0x88, 0x61, 0x67, 0xf3, 0xdb, 0x10, 0xbf, 0xd4, 0x6a, 0x7e, 0xa1, 0x03,
-	0x81, 0x98, 0x0b, 0x61, 0x52, 0x9c, 0xe1, 0xa2, 0xcd, 0x7c, 0x9d, 0xdf, 0x83, 0xf6, 0x9a, 0xcb,
-	0x64, 0x3d, 0x5b, 0x5e, 0x10, 0xdc, 0x80, 0xeb, 0xbc, 0x2c, 0x65, 0xed, 0xbc, 0x24, 0x61, 0x5b,
-	0x94, 0x7f, 0x42, 0x90, 0xbc, 0x91, 0xaa, 0x9a, 0x8e, 0xb5, 0x39, 0xc4, 0x85, 0xdc, 0x87, 0xa4,
-	0x36, 0x4a, 0x1b, 0xe5, 0x96, 0x7e, 0xce, 0x93, 0x51, 0x7f, 0x10, 0x96, 0x16, 0x1a, 0x0d, 0xde,
-	0x6e, 0x19, 0x6c, 0xc7, 0xcd, 0x6f, 0x41, 0x12, 0x5e, 0x49, 0x0b, 0xa2, 0x57, 0xfa, 0xac, 0x77,
-	0x44, 0x12, 0x88, 0x9f, 0xab, 0x6a, 0xda, 0x43, 0xf9, 0x03, 0xe8, 0x06, 0xf5, 0xd5, 0x02, 0x7c,
-	0x80, 0xce, 0x53, 0x6d, 0xce, 0xb8, 0x11, 0x97, 0x2e, 0xaa, 0x0f, 0x89, 0xd5, 0x0b, 0x53, 0xca,
-	0x17, 0xc2, 0x0b, 0x63, 0xb6, 0xc3, 0x84, 0x02, 0x6c, 0xea, 0x87, 0xeb, 0x90, 0x91, 0x0f, 0xb9,
-	0xf7, 0x42, 0x7a, 0x10, 0x39, 0x37, 0x4b, 0xe3, 0x0c, 0x17, 0x5d, 0xb6, 0x2e, 0xf3, 0x9b, 0x00,
-	0x4f, 0x94, 0x2d, 0xf5, 0x7c, 0x2e, 0x4b, 0xf7, 0xbf, 0x57, 0x3e, 0x84, 0xf6, 0x63, 0x3e, 0x17,
-	0x4a, 0x70, 0x27, 0x0f, 0xba, 0xd8, 0x67, 0x04, 0xad, 0x77, 0xd3, 0xc5, 0x64, 0x32, 0x93, 0x57,
-	0x1a, 0x3c, 0xf4, 0x8a, 0xf6, 0xee, 0x32, 0x02, 0x28, 0x83, 0xb9, 0x4d, 0xe3, 0x2c, 0x2a, 0x3a,
-	0x23, 0xb2, 0xbb, 0xcc, 0x6e, 0x2e, 0xb6, 0xc7, 0x0a, 0x01, 0xaf, 0xfd, 0x0d, 0xc8, 0xe0, 0x78,
-	0x3b, 0xd0, 0xe5, 0x57, 0xf8, 0xd7, 0x05, 0x1f, 0xe2, 0xf2, 0xe8, 0xce, 0xcf, 0x15, 0x3d, 0x3a,
-	0x5f, 0x51, 0xf4, 0x6b, 0x45, 0xd1, 0xef, 0x15, 0x45, 0x1f, 0x1b, 0x8a, 0xbe, 0x34, 0x14, 0x7d,
-	0x6d, 0x28, 0xfa, 0xd6, 0x50, 0xf4, 0xbd, 0xa1, 0xe8, 0x47, 0x43, 0xd1, 0x79, 0x43, 0xd1, 0x9f,
-	0x00, 0x00, 0x00, 0xff, 0xff, 0xdb, 0x24, 0xfc, 0x93, 0x82, 0x03, 0x00, 0x00,
+	// 1059 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x56, 0x31, 0x6f, 0xe4, 0x44,
+	0x14, 0xbe, 0x59, 0x7b, 0x77, 0xbd, 0x6f, 0x77, 0x73, 0x2b, 0x2b, 0x0a, 0x43, 0x74, 0x67, 0x16,
+	0x17, 0xb0, 0x45, 0x2e, 0x39, 0xa5, 0x40, 0xa2, 0xbc, 0xcb, 0x1d, 0x97, 0xa0, 0x80, 0xa2, 0x09,
+	0x08, 0x51, 0x9d, 0x66, 0xed, 0x89, 0x6d, 0xc5, 0xeb, 0x71, 0x3c, 0x76, 0x8e, 0x15, 0x0d, 0x0d,
+	0x35, 0x3f, 0x81, 0x96, 0x82, 0x1f, 0x40, 0x49, 0x49, 0x09, 0x1d, 0xe5, 0x65, 0x7b, 0x24, 0x4a,
+	0x4a, 0x34, 0x33, 0xb6, 0x77, 0x93, 0x35, 0x68, 0x11, 0xd2, 0x75, 0xef, 0x7b, 0xf3, 0xe6, 0x7d,
+	0xdf, 0xbc, 0xf7, 0x3c, 0x63, 0x18, 0xce, 0x98, 0x10, 0x34, 0x60, 0xfb, 0x69, 0xc6, 0x73, 0x6e,
+	0x77, 0x4b, 0xb8, 0xfb, 0x28, 0x88, 0xf2, 0xb0, 0x98, 0xee, 0x7b, 0x7c, 0x76, 0x10, 0xf0, 0x80,
+	0x1f, 0xa8, 0xf5, 0x69, 0x71, 0xa1, 0x90, 0x02, 0xca, 0xd2, 0xfb, 0xdc, 0xef, 0x0c, 0xe8, 0x7f,
+	0x2e, 0x58, 0xf6, 0x89, 0xde, 0x6e, 0x6f, 0x41, 0x2b, 0xf2, 0x31, 0x1a, 0xb7, 0x26, 0x26, 0x69,
+	0x45, 0xbe, 0x8d, 0xa1, 0x9b, 0xd2, 0x79, 0xcc, 0xa9, 0x8f, 0x5b, 0x63, 0x34, 0x19, 0x90, 0x0a,
+	0xca, 0xc8, 0x5c, 0x60, 0x63, 0xdc, 0x9a, 0x18, 0xa4, 0x95, 0x0b, 0x7b, 0x1b, 0xda, 0x79, 0x46,
+	0x3d, 0x86, 0x4d, 0x15, 0xa7, 0x81, 0xf2, 0xf2, 0x34, 0xf2, 0x70, 0x7b, 0x8c, 0x26, 0x3d, 0xa2,
+	0x81, 0x3d, 0x02, 0x43, 0xb0, 0x2b, 0xdc, 0x19, 0xa3, 0x89, 0x49, 0xa4, 0x69, 0xdb, 0x60, 0x86,
+	0x3c, 0x15, 0xb8, 0x3b, 0x46, 0x93, 0x21, 0x51, 0xb6, 0xf4, 0xc5, 0xd1, 0x05, 0xc3, 0xd6, 0x18,
+	0x4d, 0x0c, 0xa2, 0x6c, 0xb9, 0xf3, 0x92, 0xcd, 0x71, 0x4f, 0x71, 0x48, 0x53, 0xe5, 0x8a, 0x02,
+	0x0c, 0xda, 0x23, 0xa2, 0xc0, 0x7e, 0x1b, 0x2c, 0x2f, 0x2c, 0x92, 0xcb, 0x97, 0x91, 0x8f, 0xfb,
+	0x8a, 0xa2, 0xab, 0xf0, 0x89, 0x6f, 0xbf, 0x03, 0xfd, 0x72, 0x29, 0xf1, 0xd9, 0x57, 0x78, 0xa0,
+	0xd8, 0x40, 0xaf, 0x4a, 0xcf, 0x32, 0xc0, 0xe3, 0x45, 0x92, 0xe3, 0xe1, 0x4a, 0xc0, 0x91, 0xf4,
+	0xd8, 0x0f, 0x01, 0x78, 0x16, 0x05, 0x51, 0xf2, 0x52, 0x9e, 0x60, 0x4b, 0xa5, 0xef, 0x69, 0xcf,
+	0x39, 0xbb, 0x92, 0xcb, 0x7a, 0x7f, 0x48, 0x45, 0x88, 0xef, 0x2b, 0x51, 0x3d, 0xe5, 0x39, 0xa6,
+	0x22, 0xb4, 0xdf, 0x85, 0x41, 0xb9, 0x9b, 0xa5, 0xdc, 0x0b, 0xf1, 0x48, 0xed, 0xef, 0x6b, 0xdf,
+	0x73, 0xe9, 0x72, 0x0f, 0xa0, 0x7d, 0x4a, 0xa7, 0x2c, 0xae, 0x8e, 0x2a, 0x7b, 0xd1, 0xd3, 0x47,
+	0xdd, 0x86, 0xf6, 0x35, 0x8d, 0x0b, 0x86, 0x5b, 0xca, 0xa7, 0x81, 0xfb, 0x23, 0x02, 0xf3, 0x63,
+	0x1e, 0x25, 0x6b, 0xbd, 0xb3, 0xc1, 0xa4, 0xbe, 0x9f, 0x95, 0xd1, 0xca, 0x96, 0xfd, 0xe4, 0x53,
+	0xc1, 0xb2, 0x6b, 0x86, 0x8d, 0x31, 0x9a, 0x58, 0xa4, 0x82, 0xf6, 0x7b, 0xd0, 0x89, 0x25, 0xaf,
+	0xc0, 0xe6, 0xd8, 0x98, 0xf4, 0x0f, 0xb7, 0xf6, 0xab, 0x09, 0x53, 0x72, 0x48, 0xb9, 0x2a, 0xb3,
+	0x7a, 0x34, 0x15, 0xaa, 0xa1, 0x43, 0xa2, 0x6c, 0x29, 0x75, 0x46, 0x3d, 0xd5, 0xcf, 0x01, 0x91,
+	0xa6, 0xe4, 0xf1, 0xe2, 0x42, 0xe4, 0x2c, 0x53, 0x2d, 0xed, 0x91, 0x0a, 0xba, 0x1c, 0x7a, 0x52,
+	0x2d, 0x61, 0x69, 0x3c, 0x5f, 0x93, 0xbc, 0x03, 0x1d, 0xea, 0x79, 0x2c, 0xcd, 0x95, 0x68, 0x8b,
+	0x94, 0x68, 0x45, 0x9c, 0xb1, 0x91, 0x38, 0x73, 0x29, 0xce, 0xfd, 0x03, 0x81, 0xf5, 0x29, 0x8b,
+	0x82, 0x70, 0xca, 0xb3, 0x8d, 0x6a, 0xf4, 0x01, 0x58, 0x69, 0x16, 0xf1, 0x2c, 0xca, 0xe7, 0x6a,
+	0xbe, 0xb7, 0x0e, 0x77, 0x6b, 0xba, 0x2a, 0xd1, 0xfe, 0x59, 0x19, 0x41, 0xea, 0xd8, 0x37, 0x56,
+	0xc1, 0x87, 0x60, 0x55, 0xec, 0x76, 0x17, 0x8c, 0x53, 0xfe, 0x6a, 0x74, 0xcf, 0xb6, 0xc0, 0x3c,
+	0x8e, 0x82, 0x70, 0x84, 0x5c, 0x01, 0xc3, 0x4a, 0xe5, 0x9b, 0x2b, 0xf2, 0xf7, 0x08, 0xfa, 0x1f,
+	0xf1, 0xec, 0x15, 0xcd, 0xfc, 0xc6, 0x59, 0xdc, 0x05, 0x4b, 0xf0, 0x22, 0xf3, 0xd8, 0x89, 0xaf,
+	0x58, 0x4d, 0x52, 0x63, 0xdb, 0x01, 0xd0, 0xf6, 0x13, 0xd9, 0x09, 0x43, 0x75, 0x62, 0xc5, 0x23,
+	0x6b, 0x93, 0xe7, 0x31, 0x36, 0xc7, 0xad, 0xc9, 0x90, 0x48, 0xd3, 0x3e, 0x84, 0x81, 0x5e, 0x3f,
+	0xd5, 0x7a, 0xdb, 0x8d, 0x7a, 0x6f, 0xc5, 0xb8, 0x0f, 0x00, 0x9e, 0x45, 0xc2, 0xe3, 0x49, 0xc2,
+	0xbc, 0xfc, 0xae, 0x3e, 0xf7, 0x0b, 0xe8, 0x1d, 0xd1, 0xc4, 0x8f, 0x7c, 0x9a, 0xb3, 0x8d, 0x86,
+	0x64, 0xc3, 0x62, 0xb9, 0xbf, 0x21, 0xe8, 0x9e, 0x87, 0xc5, 0xc5, 0x45, 0xcc, 0xfe, 0x53, 0x51,
+	0x2a, 0x4e, 0x63, 0x85, 0xf3, 0x10, 0xc0, 0xab, 0x44, 0x56, 0x43, 0x66, 0xd7, 0xbc, 0xb5, 0x7e,
+	0xb2, 0x12, 0x55, 0x15, 0xaf, 0xbd, 0x2c, 0xde, 0x08, 0x8c, 0xc2, 0x4f, 0xd5, 0xa8, 0x59, 0x44,
+	0x9a, 0xff, 0x3c, 0x6a, 0xf2, 0xc6, 0x49, 0x78, 0xe2, 0xe9, 0x3b, 0xd8, 0x24, 0x1a, 0xb8, 0x21,
+	0x0c, 0xca, 0x23, 0x35, 0x0f, 0xd8, 0x6d, 0x9d, 0xad, 0x8d, 0x74, 0xd6, 0x4c, 0xc6, 0x2a, 0xd3,
+	0xb7, 0x08, 0xba, 0x84, 0x5d, 0x15, 0x4c, 0xac, 0xb5, 0x4c, 0xee, 0xc8, 0xd8, 0x55, 0x5d, 0x3a,
+	0x0d, 0x1a, 0xeb, 0xb6, 0xf2, 0x88, 0x99, 0x4d, 0x8f, 0x58, 0xbb, 0x7e, 0xc4, 0x76, 0xa0, 0x93,
+	0xd3, 0x2c, 0x60, 0x79, 0xf9, 0x36, 0x95, 0xc8, 0x7d, 0x01, 0xed, 0xe6, 0xa3, 0x36, 0x8b, 0x58,
+	0x21, 0x34, 0x6e, 0x11, 0xca, 0xdb, 0xfd, 0xe4, 0x98, 0x5e, 0xb3, 0xa6, 0x8f, 0x72, 0x26, 0x82,
+	0x13, 0x5f, 0xd7, 0x6b, 0x40, 0x4a, 0xe4, 0x3e, 0x82, 0xf6, 0x8b, 0x8c, 0x5e, 0x34, 0x1e, 0x5f,
+	0x85, 0x28, 0xe6, 0x01, 0xd1, 0xc0, 0x7d, 0x0b, 0xda, 0x67, 0x59, 0x91, 0xac, 0xe5, 0x77, 0xdf,
+	0x07, 0xe3, 0x89, 0x77, 0xb9, 0x96, 0xa5, 0x7c, 0x89, 0xb5, 0x7a, 0x69, 0xba, 0x13, 0x30, 0xcf,
+	0xa2, 0x24, 0xd8, 0x30, 0x92, 0x6f, 0x14, 0xf9, 0x21, 0xb4, 0x9f, 0xd2, 0xdc, 0x0b, 0xed, 0xc7,
+	0x60, 0x95, 0x63, 0x20, 0x30, 0x52, 0x73, 0xb1, 0x5d, 0xcf, 0xc5, 0xca, 0x6f, 0x08, 0xa9, 0xa3,
+	0x5c, 0x1f, 0x3a, 0xcf, 0xa2, 0xa0, 0xa9, 0xff, 0x0f, 0xa0, 0x97, 0x31, 0xea, 0x85, 0x74, 0x1a,
+	0xeb, 0x17, 0x71, 0x48, 0x96, 0x0e, 0x7b, 0x0f, 0x3a, 0x82, 0xce, 0xd2, 0x98, 0x61, 0xe3, 0x0e,
+	0x8f, 0x4e, 0xf7, 0x3c, 0xc9, 0xb3, 0x39, 0x29, 0x63, 0xdc, 0x23, 0xe8, 0xaf, 0xb8, 0x37, 0xba,
+	0x00, 0x46, 0x60, 0xd0, 0x80, 0x95, 0x3f, 0x40, 0xd2, 0x74, 0x67, 0x70, 0xff, 0x33, 0x9e, 0xf2,
+	0x98, 0x07, 0xf3, 0xff, 0x3f, 0xb3, 0xeb, 0x97, 0xde, 0x9d, 0x59, 0x75, 0xbf, 0x86, 0xe1, 0x92,
+	0x6e, 0xf3, 0xd9, 0x6c, 0x22, 0x7b, 0x0c, 0xbd, 0xa4, 0x7c, 0x32, 0xfe, 0xed, 0x5e, 0x59, 0x06,
+	0xb9, 0xc7, 0x60, 0x9d, 0xb3, 0x3c, 0x8f, 0x92, 0x40, 0xac, 0xf1, 0xee, 0x81, 0x25, 0xca, 0xb5,
+	0xf2, 0xe3, 0x1f, 0xd5, 0xc9, 0xca, 0x4d, 0xa4, 0x8e, 0x70, 0xbf, 0x84, 0x6e, 0xe9, 0xdc, 0xf4,
+	0x8f, 0x67, 0xed, 0xd7, 0x73, 0x07, 0x3a, 0xfa, 0x0f, 0x4a, 0x95, 0xcb, 0x24, 0x25, 0x7a, 0xba,
+	0xf7, 0xfb, 0x8d, 0x73, 0xef, 0xf5, 0x8d, 0x83, 0xfe, 0xbc, 0x71, 0xd0, 0x5f, 0x37, 0x0e, 0xfa,
+	0x66, 0xe1, 0xa0, 0x1f, 0x16, 0x0e, 0xfa, 0x69, 0xe1, 0xa0, 0x9f, 0x17, 0x0e, 0xfa, 0x65, 0xe1,
+	0xa0, 0x5f, 0x17, 0x0e, 0x7a, 0xbd, 0x70, 0xd0, 0xdf, 0x03, 0x00, 0x22, 0x34, 0x30, 0x40, 0x52,
+	0x0b, 0x00, 0x00,
 }
@@ -1,7 +1,60 @@
 package node
 
 import (
+	"errors"
+	"fmt"
 	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The capabilities of the nodes, negotiated in the Join and Neighbor
+// messages, so the new features are only used with the peers that
+// support them.
+const (
+	// CapCompress marks a node that reads the compressed messages.
+	CapCompress uint32 = 1 << iota
+	// CapPing marks a node that answers the pings.
+	CapPing
+	// CapBatch marks a node that reads the batches of user messages.
+	CapBatch
+	// CapDigest marks a node that reads the digests of the nodes reached.
+	CapDigest
+	// CapUDP marks a node that reads the shuffles and the pings over UDP.
+	CapUDP
+	// CapSettings marks a node that reads the cluster settings.
+	CapSettings
+)
+
+// State is the state of the connection to a node.
+type State int32
+
+const (
+	// StateIdle is the state of a node with no connection,
+	// e.g. in the passive view.
+	StateIdle State = iota
+	// StateDialing is the state of a node being connected.
+	StateDialing
+	// StateConnected is the state of a node with an open connection.
+	StateConnected
+	// StateClosed is the state of a node whose connection is closed
+	// locally. A closed node is not connected again.
+	StateClosed
+)
+
+var stateNames = []string{"idle", "dialing", "connected", "closed"}
+
+func (s State) String() string {
+	if s < StateIdle || s > StateClosed {
+		return fmt.Sprintf("State(%d)", s)
+	}
+	return stateNames[s]
+}
+
+var (
+	ErrNotConnected = errors.New("Node is not connected")
+	ErrClosed       = errors.New("Node is closed")
 )
 
 // Node decribes a node in the overlay.
@@ -11,8 +64,201 @@ type Node struct {
 	// Addr is the network address of the node,
 	// in the form of "host:port".
 	Addr string `json:"address"`
-	// Conn is the (TCP) connection to the node.
-	// If the node is in the passive view, then the Conn could be
-	// nil.
-	Conn *net.TCPConn `json:"-"`
+	// Labels are the key/value metadata of the node,
+	// e.g. region or role.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Caps are the capabilities of the node, learned
+	// when it joins or becomes a neighbor.
+	Caps uint32 `json:"-"`
+
+	// mu guards the connection and its state.
+	mu sync.Mutex
+	// conn is the (TCP or TLS) connection to the node, nil
+	// if the node is in the passive view.
+	conn  net.Conn
+	state State
+	// wmu serializes the writes to the connection.
+	wmu sync.Mutex
+	// The times in nanoseconds of the last read and the last
+	// write, and the number of the failed dials and writes.
+	lastSeen int64
+	lastSent int64
+	failures uint64
+}
+
+// New returns a node connected by the connection.
+func New(id uint64, addr string, conn net.Conn) *Node {
+	return &Node{Id: id, Addr: addr, conn: conn, state: StateConnected}
+}
+
+// Dial connects the node with the dial function, and
+// returns ErrClosed if the node is closed meanwhile.
+func (n *Node) Dial(dial func(addr string) (net.Conn, error)) error {
+	n.mu.Lock()
+	switch n.state {
+	case StateClosed:
+		n.mu.Unlock()
+		return ErrClosed
+	case StateDialing, StateConnected:
+		n.mu.Unlock()
+		return fmt.Errorf("Node is %v", n.state)
+	}
+	n.state = StateDialing
+	n.mu.Unlock()
+
+	conn, err := dial(n.Addr)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if err != nil {
+		atomic.AddUint64(&n.failures, 1)
+		if n.state == StateDialing {
+			n.state = StateIdle
+		}
+		return err
+	}
+	if n.state == StateClosed {
+		conn.Close()
+		return ErrClosed
+	}
+	n.conn, n.state = conn, StateConnected
+	return nil
+}
+
+// connection() returns the connection, or the error
+// if the node is not connected.
+func (n *Node) connection() (net.Conn, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	switch {
+	case n.state == StateClosed:
+		return nil, ErrClosed
+	case n.conn == nil:
+		return nil, ErrNotConnected
+	}
+	return n.conn, nil
+}
+
+// Send writes the bytes to the connection. It is safe for concurrent
+// use, the bytes of one Send are never interleaved with the others.
+func (n *Node) Send(b []byte) error {
+	_, err := n.Write(b)
+	return err
+}
+
+// Write is Send as an io.Writer, for the codec.
+func (n *Node) Write(b []byte) (int, error) {
+	conn, err := n.connection()
+	if err != nil {
+		return 0, err
+	}
+	n.wmu.Lock()
+	defer n.wmu.Unlock()
+	m, err := conn.Write(b)
+	if err != nil {
+		atomic.AddUint64(&n.failures, 1)
+		return m, err
+	}
+	atomic.StoreInt64(&n.lastSent, time.Now().UnixNano())
+	return m, nil
+}
+
+// Read reads from the connection, and marks the node seen
+// if anything is read.
+func (n *Node) Read(b []byte) (int, error) {
+	conn, err := n.connection()
+	if err != nil {
+		return 0, err
+	}
+	m, err := conn.Read(b)
+	if m > 0 {
+		atomic.StoreInt64(&n.lastSeen, time.Now().UnixNano())
+	}
+	return m, err
+}
+
+// SetReadDeadline sets the deadline of the reads.
+func (n *Node) SetReadDeadline(t time.Time) error {
+	conn, err := n.connection()
+	if err != nil {
+		return err
+	}
+	return conn.SetReadDeadline(t)
+}
+
+// Close closes the connection, if any, and marks the node closed.
+// It is safe to call more than once.
+func (n *Node) Close() error {
+	n.mu.Lock()
+	if n.state == StateClosed {
+		n.mu.Unlock()
+		return nil
+	}
+	conn := n.conn
+	n.state = StateClosed
+	n.mu.Unlock()
+	if conn == nil {
+		return nil
+	}
+	return conn.Close()
+}
+
+// State returns the state of the connection.
+func (n *Node) State() State {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.state
+}
+
+// Conn returns the connection, nil if the node is not connected.
+// The node is read and written by its own methods, the connection
+// is only for inspection, e.g. whether the peer has closed it.
+func (n *Node) Conn() net.Conn {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.conn
+}
+
+// LastSeen returns the time of the last read from the
+// node, zero if nothing has been read.
+func (n *Node) LastSeen() time.Time {
+	return unixTime(atomic.LoadInt64(&n.lastSeen))
+}
+
+// LastSent returns the time of the last write to the
+// node, zero if nothing has been written.
+func (n *Node) LastSent() time.Time {
+	return unixTime(atomic.LoadInt64(&n.lastSent))
+}
+
+// Failures returns the number of the failed dials and writes.
+func (n *Node) Failures() uint64 {
+	return atomic.LoadUint64(&n.failures)
+}
+
+// unixTime() returns the time of the nanoseconds, zero if they are.
+func unixTime(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// AcceptsCompression returns true if the node reads the compressed
+// messages, so the codec compresses the large messages to it.
+func (n *Node) AcceptsCompression() bool {
+	return n.Caps&CapCompress != 0
+}
+
+// RemoteAddr returns the remote address of the connection,
+// nil if the node is not connected.
+func (n *Node) RemoteAddr() net.Addr {
+	if conn := n.Conn(); conn != nil {
+		return conn.RemoteAddr()
+	}
+	return nil
+}
+
+func (n *Node) String() string {
+	return fmt.Sprintf("{Id:%d Addr:%s Labels:%v}", n.Id, n.Addr, n.Labels)
 }
@@ -0,0 +1,77 @@
+package node
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lilymona/testify/assert"
+)
+
+func TestDial(t *testing.T) {
+	local, remote := net.Pipe()
+	defer remote.Close()
+
+	nd := &Node{Id: 1, Addr: "peer"}
+	assert.Equal(t, StateIdle, nd.State())
+	assert.Equal(t, ErrNotConnected, nd.Send([]byte("foo")))
+	assert.Nil(t, nd.RemoteAddr())
+
+	// A failed dial leaves the node idle.
+	errDial := errors.New("dial error")
+	assert.Equal(t, errDial, nd.Dial(func(string) (net.Conn, error) { return nil, errDial }))
+	assert.Equal(t, StateIdle, nd.State())
+	assert.Equal(t, uint64(1), nd.Failures())
+
+	assert.NoError(t, nd.Dial(func(addr string) (net.Conn, error) {
+		assert.Equal(t, "peer", addr)
+		assert.Equal(t, StateDialing, nd.State())
+		return local, nil
+	}))
+	assert.Equal(t, StateConnected, nd.State())
+	assert.Error(t, nd.Dial(func(string) (net.Conn, error) { return local, nil }))
+
+	// The node closed while dialing closes the new connection.
+	nd = &Node{Id: 2, Addr: "peer"}
+	c1, c2 := net.Pipe()
+	defer c2.Close()
+	assert.Equal(t, ErrClosed, nd.Dial(func(string) (net.Conn, error) {
+		nd.Close()
+		return c1, nil
+	}))
+	_, err := c1.Write([]byte("foo"))
+	assert.Error(t, err)
+}
+
+func TestSendRead(t *testing.T) {
+	local, remote := net.Pipe()
+	a, b := New(1, "a", local), New(2, "b", remote)
+	assert.True(t, a.LastSent().IsZero())
+	assert.True(t, b.LastSeen().IsZero())
+
+	start := time.Now()
+	go a.Send([]byte("foo"))
+	buf := make([]byte, 3)
+	n, err := b.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", string(buf[:n]))
+	assert.False(t, b.LastSeen().Before(start))
+	for i := 0; i < 100 && a.LastSent().IsZero(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.False(t, a.LastSent().Before(start))
+
+	// Close is idempotent, and the closed node is not written.
+	assert.NoError(t, a.Close())
+	assert.NoError(t, a.Close())
+	assert.Equal(t, StateClosed, a.State())
+	assert.Equal(t, ErrClosed, a.Send([]byte("foo")))
+	_, err = b.Read(buf)
+	assert.Error(t, err)
+
+	// The failed writes are counted.
+	assert.Error(t, b.Send([]byte("foo")))
+	assert.Equal(t, uint64(1), b.Failures())
+	b.Close()
+}
@@ -0,0 +1,45 @@
+package rest
+
+import (
+	"net/http"
+)
+
+const (
+	// corsMethods and corsHeaders are allowed in the preflights.
+	corsMethods = "GET, POST, PUT, DELETE"
+	corsHeaders = "Authorization, Content-Type"
+	// corsMaxAge is the seconds the browsers cache a preflight.
+	corsMaxAge = "600"
+)
+
+// cors() sets the CORS headers if the origin of the request is allowed,
+// and returns true if the request is a preflight, which it answers. The
+// preflights need no auth token, as the browsers do not send it.
+func (rh *RESTServer) cors(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || !rh.allowedOrigin(origin) {
+		return false
+	}
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Set("Access-Control-Allow-Origin", origin)
+	if r.Method != "OPTIONS" || r.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+	h.Set("Access-Control-Allow-Methods", corsMethods)
+	h.Set("Access-Control-Allow-Headers", corsHeaders)
+	h.Set("Access-Control-Max-Age", corsMaxAge)
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// allowedOrigin() returns true if the origin is
+// configured, or any origin is allowed.
+func (rh *RESTServer) allowedOrigin(origin string) bool {
+	for _, o := range rh.cfg.RESTCORSOrigins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/lilymona/gog/agent"
+	"github.com/lilymona/gog/config"
+)
+
+const (
+	debugURL = "/api/debug"
+	pprofURL = "/debug/pprof/"
+)
+
+// debugState is the response of the debug endpoint.
+type debugState struct {
+	Agent  *agent.DebugInfo `json:"agent"`
+	Config *config.Config   `json:"config"`
+}
+
+// NewDebugServer creates the server of the debug endpoints on the debug
+// address, or returns nil if they are disabled, or served by the REST
+// server. The requests need the auth token as the REST API does.
+func NewDebugServer(srv *http.Server, cfg *config.Config) *http.Server {
+	rh, ok := srv.Handler.(*RESTServer)
+	if !ok || !cfg.Debug || cfg.DebugAddrStr == "" {
+		return nil
+	}
+	mux := http.NewServeMux()
+	rh.registerDebug(mux)
+	return &http.Server{
+		Addr: cfg.DebugAddrStr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !rh.authorized(r) {
+				unauthorized(w)
+				return
+			}
+			mux.ServeHTTP(w, r)
+		}),
+	}
+}
+
+// registerDebug() registers the pprof handlers and the debug endpoint.
+func (rh *RESTServer) registerDebug(mux *http.ServeMux) {
+	mux.HandleFunc(pprofURL, pprof.Index)
+	mux.HandleFunc(pprofURL+"cmdline", pprof.Cmdline)
+	mux.HandleFunc(pprofURL+"profile", pprof.Profile)
+	mux.HandleFunc(pprofURL+"symbol", pprof.Symbol)
+	mux.HandleFunc(pprofURL+"trace", pprof.Trace)
+	mux.HandleFunc(debugURL, rh.Debug)
+}
+
+// Debug returns the internal state of the agent, e.g. the number of
+// the goroutines and the depths of the send queues, and its current
+// configuration.
+func (rh *RESTServer) Debug(w http.ResponseWriter, r *http.Request) {
+	b, err := json.Marshal(&debugState{
+		Agent:  rh.ag.Debug(),
+		Config: rh.redactedConfig(),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, string(b))
+}
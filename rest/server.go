@@ -1,17 +1,27 @@
 package rest
 
 import (
+	"bytes"
+	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"os"
 	"os/exec"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/lilymona/gog/agent"
+	"github.com/lilymona/gog/codec"
 	"github.com/lilymona/gog/config"
 	log "github.com/lilymona/gog/logging"
+	"github.com/lilymona/gog/node"
 )
 
 const (
@@ -20,17 +30,55 @@ const (
 	broadcastURL = "/api/broadcast"
 	configURL    = "/api/config"
 	leaveURL     = "/api/leave"
+	metricsURL   = "/api/metrics"
+	statsURL     = "/api/stats"
+	logLevelURL  = "/api/loglevel"
+	peersURL     = "/api/peers"
+	streamURL    = "/api/stream"
+	healthURL    = "/api/health"
+	readyURL     = "/api/ready"
+	topologyURL  = "/api/topology"
+)
+
+// The default hops and timeout of the topology crawls, and the longest
+// timeout. The larger ttls are lowered to agent.MaxTopologyTTL.
+const (
+	defaultTopologyTTL     = 8
+	defaultTopologyTimeout = 2 * time.Second
+	maxTopologyTimeout     = 30 * time.Second
 )
 
 var (
-	errInvalidMethod = errors.New("server: Invalid method")
+	errInvalidMethod   = errors.New("server: Invalid method")
+	errEmptyMessage    = errors.New("server: Empty message")
+	errMessageTooLarge = errors.New("server: Message too large")
+	errUnauthorized    = errors.New("server: Unauthorized")
+	errInvalidView     = errors.New("server: Invalid view, should be active, passive or all")
+	errInvalidLabel    = errors.New("server: Invalid label, should be key=value")
+	errInvalidID       = errors.New("server: Invalid node id")
+	errInvalidTTL      = errors.New("server: Invalid ttl")
+	errInvalidTimeout  = errors.New("server: Invalid timeout")
 )
 
+// redacted replaces the auth token and the cluster secret
+// in the returned configuration.
+const redacted = "<redacted>"
+
+// maxConfigSize is the maximum size in bytes of a configuration to reload.
+const maxConfigSize = 1 << 20
+
 // RESTServer handles RESTful requests for gog agent.
 type RESTServer struct {
 	cfg *config.Config
 	ag  agent.Agent
 	mux *http.ServeMux
+	// exit terminates the process after the agent leaves.
+	exit func(code int)
+	// streams are the clients of the message stream.
+	streams streamHub
+	// handlers limits the user message handlers run at once,
+	// nil for unlimited.
+	handlers chan struct{}
 }
 
 // NewServer creates a new RESTful server for gog agent.
@@ -43,11 +91,35 @@ func NewServer(cfg *config.Config) *http.Server {
 	}
 }
 
+// ListenAndServe serves the REST API on the configured address,
+// over TLS if the certificate and the key are configured.
+func ListenAndServe(srv *http.Server, cfg *config.Config) error {
+	if cfg.RESTTLSCert != "" && cfg.RESTTLSKey != "" {
+		return srv.ListenAndServeTLS(cfg.RESTTLSCert, cfg.RESTTLSKey)
+	}
+	return srv.ListenAndServe()
+}
+
+// Shutdown gracefully shuts down the REST server, waiting for the requests
+// in flight, and then the agent, until the context is done.
+func Shutdown(ctx context.Context, srv *http.Server) error {
+	err := srv.Shutdown(ctx)
+	if rh, ok := srv.Handler.(*RESTServer); ok {
+		if e := rh.Shutdown(ctx); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
 // NewRESTServer creates an http.Handler to handle HTTP requests.
 func NewRESTServer(cfg *config.Config) http.Handler {
 	mux := http.NewServeMux()
 	ag := agent.NewAgent(cfg)
-	rh := &RESTServer{cfg, ag, mux}
+	rh := &RESTServer{cfg: cfg, ag: ag, mux: mux, exit: os.Exit}
+	if cfg.UserMsgHandlerConcurrency > 0 {
+		rh.handlers = make(chan struct{}, cfg.UserMsgHandlerConcurrency)
+	}
 	rh.RegisterAPI(mux)
 
 	// Register a user message handler.
@@ -56,7 +128,8 @@ func NewRESTServer(cfg *config.Config) http.Handler {
 	// Start the agent server.
 	go func() {
 		if err := ag.Serve(); err != nil {
-			log.Fatalf("server.NewServer(): Agent failed to serve: %v\n", err)
+			rh.logger().Errorf("server.NewServer(): Agent failed to serve: %v\n", err)
+			rh.exit(1)
 		}
 	}()
 	return rh
@@ -69,20 +142,238 @@ func (rh *RESTServer) RegisterAPI(mux *http.ServeMux) {
 	mux.HandleFunc(broadcastURL, rh.Broadcast)
 	mux.HandleFunc(configURL, rh.Config)
 	mux.HandleFunc(leaveURL, rh.Leave)
+	mux.HandleFunc(metricsURL, rh.Stats)
+	mux.HandleFunc(statsURL, rh.Stats)
+	mux.HandleFunc(logLevelURL, rh.LogLevel)
+	mux.HandleFunc(peersURL, rh.Peers)
+	mux.HandleFunc(peersURL+"/", rh.Evict)
+	mux.HandleFunc(streamURL, rh.Stream)
+	mux.HandleFunc(healthURL, rh.Health)
+	mux.HandleFunc(readyURL, rh.Ready)
+	mux.HandleFunc(specURL, rh.Spec)
+	mux.HandleFunc(uiURL, rh.UI)
+	mux.HandleFunc(topologyURL, rh.Topology)
+	mux.HandleFunc(settingsURL, rh.Settings)
+	if rh.cfg.Debug && rh.cfg.DebugAddrStr == "" {
+		rh.registerDebug(mux)
+	}
 	return
 }
 
-// List lists the views.
+// List lists the views. If the "label" is given as key=value, it
+// lists the nodes in the views that have the label instead.
 func (rh *RESTServer) List(w http.ResponseWriter, r *http.Request) {
-	b, err := rh.ag.List()
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var b []byte
+	var err error
+	if label := r.Form.Get("label"); label != "" {
+		kv := strings.SplitN(label, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			http.Error(w, errInvalidLabel.Error(), http.StatusBadRequest)
+			return
+		}
+		nodes := rh.ag.NodesWithLabel(kv[0], kv[1])
+		if nodes == nil {
+			nodes = []*node.Node{}
+		}
+		b, err = json.Marshal(nodes)
+	} else {
+		b, err = rh.ag.List()
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	fmt.Fprintf(w, string(b))
+	fmt.Fprint(w, string(b))
 	return
 }
 
+// Peers returns the addresses of the peers as a JSON array. The "view"
+// is active (default), passive or all.
+func (rh *RESTServer) Peers(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var peers []string
+	switch r.Form.Get("view") {
+	case "", "active":
+		peers = rh.ag.Peers(true, false)
+	case "passive":
+		peers = rh.ag.Peers(false, true)
+	case "all":
+		peers = rh.ag.Peers(true, true)
+	default:
+		http.Error(w, errInvalidView.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b, err := json.Marshal(peers)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, string(b))
+}
+
+// Evict evicts the node of the id in the path /api/peers/{id}
+// from the views with a DELETE, and bans it for a while.
+func (rh *RESTServer) Evict(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		http.Error(w, errInvalidMethod.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(r.URL.Path, peersURL+"/"), 10, 64)
+	if err != nil {
+		http.Error(w, errInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := rh.ag.Evict(id); err == agent.ErrNodeNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Topology crawls the overlay within "ttl" hops, and returns the graph
+// of the nodes that answered in "timeout" milliseconds. Both of them
+// are lowered to their maximums.
+func (rh *RESTServer) Topology(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ttl, timeout := defaultTopologyTTL, defaultTopologyTimeout
+	if s := r.Form.Get("ttl"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			http.Error(w, errInvalidTTL.Error(), http.StatusBadRequest)
+			return
+		}
+		ttl = n
+		if ttl > agent.MaxTopologyTTL {
+			ttl = agent.MaxTopologyTTL
+		}
+	}
+	if s := r.Form.Get("timeout"); s != "" {
+		ms, err := strconv.Atoi(s)
+		if err != nil || ms <= 0 {
+			http.Error(w, errInvalidTimeout.Error(), http.StatusBadRequest)
+			return
+		}
+		timeout = time.Duration(ms) * time.Millisecond
+		if ms > int(maxTopologyTimeout/time.Millisecond) {
+			timeout = maxTopologyTimeout
+		}
+	}
+
+	topo, err := rh.ag.Topology(ttl, timeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	b, err := json.Marshal(topo)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, string(b))
+}
+
+// Stats returns the runtime counters and gauges of the agent. They
+// are served on the metrics endpoint too, for the older clients.
+func (rh *RESTServer) Stats(w http.ResponseWriter, r *http.Request) {
+	b, err := json.Marshal(rh.ag.Stats())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, string(b))
+}
+
+// Health returns the status of the agent in the overlay, with 503
+// Service Unavailable if it is degraded, i.e. it does not accept
+// connections or the active view is smaller than the minimum size.
+func (rh *RESTServer) Health(w http.ResponseWriter, r *http.Request) {
+	h := rh.ag.Health()
+	writeHealth(w, h, !h.Degraded())
+}
+
+// Ready returns the status of the agent in the overlay, with 503
+// Service Unavailable until it accepts connections and has joined
+// the overlay, for the load balancers and the readiness probes.
+func (rh *RESTServer) Ready(w http.ResponseWriter, r *http.Request) {
+	h := rh.ag.Health()
+	writeHealth(w, h, h.Ready())
+}
+
+// writeHealth() writes the status in JSON, with 503
+// Service Unavailable if it is not ok.
+func writeHealth(w http.ResponseWriter, h *agent.Health, ok bool) {
+	b, err := json.Marshal(h)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	fmt.Fprint(w, string(b))
+}
+
+// LogLevel get/set the log verboseness. The "level" of a POST form, or of
+// the JSON object of a PUT, is error, warning, info, debug or the numeric
+// constant.
+func (rh *RESTServer) LogLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+	case "POST", "PUT":
+		var name string
+		if r.Method == "PUT" {
+			var v map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			name = v["level"]
+		} else {
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			name = r.Form.Get("level")
+		}
+		level, err := log.ParseLevel(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.SetLevel(level)
+	default:
+		http.Error(w, errInvalidMethod.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+
+	b, err := json.Marshal(map[string]string{"level": log.LevelName(log.GetLevel())})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprint(w, string(b))
+}
+
 // Join joins the agent to a cluster.
 func (rh *RESTServer) Join(w http.ResponseWriter, r *http.Request) {
 	var peers []string
@@ -124,8 +415,14 @@ func (rh *RESTServer) Join(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
-// Broadcast broadcasts the message to the cluster
+// Broadcast broadcasts the message to the cluster. The message is the
+// "message" form field, or the raw request body if the content type is
+// not a form, e.g. application/octet-stream.
 func (rh *RESTServer) Broadcast(w http.ResponseWriter, r *http.Request) {
+	if !isForm(r) {
+		rh.broadcastBody(w, r)
+		return
+	}
 	if err := r.ParseForm(); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -133,7 +430,7 @@ func (rh *RESTServer) Broadcast(w http.ResponseWriter, r *http.Request) {
 
 	msg := r.Form.Get("message")
 	if msg != "" {
-		log.Infof("Broadcasting: %s\n", msg)
+		rh.logger().Infof("Broadcasting: %s\n", msg)
 		if err := rh.ag.Broadcast([]byte(msg)); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -142,9 +439,89 @@ func (rh *RESTServer) Broadcast(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
-// Config get/set the current configuration.
+// broadcastBody() broadcasts the raw request body.
+func (rh *RESTServer) broadcastBody(w http.ResponseWriter, r *http.Request) {
+	max := rh.cfg.MaxMessageSize
+	if max <= 0 {
+		max = codec.DefaultMaxMessageSize
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, int64(max)+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(b) == 0 {
+		http.Error(w, errEmptyMessage.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(b) > max {
+		http.Error(w, errMessageTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	rh.logger().Infof("Broadcasting %d bytes\n", len(b))
+	if err := rh.ag.Broadcast(b); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// isForm() returns true if the request has no body
+// or the body is a form.
+func isForm(r *http.Request) bool {
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		return true
+	}
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	return mt == "application/x-www-form-urlencoded" || mt == "multipart/form-data"
+}
+
+// Config get/set the current configuration. If "diff" is set, only
+// the fields that differ from the default configuration are returned.
+// A PUT changes the tunables in the JSON body, and the log level in
+// "log_level", and returns the new configuration.
 func (rh *RESTServer) Config(w http.ResponseWriter, r *http.Request) {
-	b, err := json.Marshal(rh.cfg)
+	switch r.Method {
+	case "GET":
+	case "PUT":
+		b, err := ioutil.ReadAll(io.LimitReader(r.Body, maxConfigSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := rh.reload(b); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, errInvalidMethod.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cfg := rh.redactedConfig()
+	var v interface{} = cfg
+	if d := r.Form.Get("diff"); d != "" {
+		diff, err := strconv.ParseBool(d)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if diff {
+			if v, err = cfg.Diff(config.DefaultConfig()); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	b, err := json.Marshal(v)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -152,28 +529,189 @@ func (rh *RESTServer) Config(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, string(b))
 }
 
-// Leave makes the agent to exit.
+// redactedConfig() returns a copy of the current configuration,
+// with the auth token and the cluster secret redacted.
+func (rh *RESTServer) redactedConfig() *config.Config {
+	cfg := *rh.ag.Config()
+	if cfg.RESTAuthToken != "" {
+		cfg.RESTAuthToken = redacted
+	}
+	if cfg.ClusterSecret != "" {
+		cfg.ClusterSecret = redacted
+	}
+	return &cfg
+}
+
+// ReloadFile reloads the tunables, and the log level in "log_level",
+// from the JSON config file, e.g. on SIGHUP. The file is layered under
+// the environment and the flags again, as when the agent started, and
+// the changes of the other fields are ignored until the restart.
+func (rh *RESTServer) ReloadFile(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	level, err := logLevel(b)
+	if err != nil {
+		return err
+	}
+	cfg, err := rh.ag.Config().ReloadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := rh.ag.Reconfigure(cfg); err != nil {
+		return err
+	}
+	if level != nil {
+		log.SetLevel(*level)
+	}
+	return nil
+}
+
+// reload() applies the tunables, and the log level in "log_level",
+// of the JSON object to the agent. Nothing is applied if any is invalid.
+func (rh *RESTServer) reload(b []byte) error {
+	level, err := logLevel(b)
+	if err != nil {
+		return err
+	}
+	cfg, err := rh.ag.Config().Update(b)
+	if err != nil {
+		return err
+	}
+	if err := rh.ag.Reconfigure(cfg); err != nil {
+		return err
+	}
+	if level != nil {
+		log.SetLevel(*level)
+	}
+	return nil
+}
+
+// logLevel() parses the log level in "log_level" of the JSON
+// object, which is nil if it is not set.
+func logLevel(b []byte) (*int, error) {
+	var v struct {
+		LogLevel *string `json:"log_level"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	if v.LogLevel == nil {
+		return nil, nil
+	}
+	level, err := log.ParseLevel(*v.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+	return &level, nil
+}
+
+// Leave makes the agent leave the cluster, and then exit.
 func (rh *RESTServer) Leave(w http.ResponseWriter, r *http.Request) {
-	rh.ag.Leave()
+	if err := rh.ag.Leave(); err != nil {
+		rh.logger().Errorf("server.Leave(): Failed to leave: %v\n", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	rh.exit(0)
+}
+
+// Shutdown ends the message streams, and gracefully shuts
+// down the agent, until the context is done.
+func (rh *RESTServer) Shutdown(ctx context.Context) error {
+	rh.streams.close()
+	return rh.ag.Shutdown(ctx)
 }
 
-// UserMessagHandler is the handler for user messages. It will run a script
-// specified by the configuration.
-func (rh *RESTServer) UserMessagHandler(msg []byte) {
+// UserMessagHandler is the handler for user messages. It will push the
+// message to the stream clients, and run a script specified by the
+// configuration, with the payload on stdin, and the sender id, the
+// timestamp and the topic in the GOG_SENDER_ID, GOG_TIMESTAMP and
+// GOG_TOPIC environment variables.
+func (rh *RESTServer) UserMessagHandler(msg agent.Message) {
+	if n := rh.streams.publish(msg); n > 0 {
+		rh.logger().Warningf("server.UserMessageHandler(): Dropped the message for %d slow stream clients\n", n)
+	}
 	if rh.cfg.UserMsgHandler == "" {
 		return
 	}
-	cmd := exec.Command(rh.cfg.UserMsgHandler, string(msg))
+	if rh.handlers != nil {
+		rh.handlers <- struct{}{}
+		defer func() { <-rh.handlers }()
+	}
+
+	ctx := context.Background()
+	if timeout := rh.cfg.UserMsgHandlerTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Millisecond)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, rh.cfg.UserMsgHandler)
+	cmd.Env = append(os.Environ(),
+		"GOG_SENDER_ID="+strconv.FormatUint(msg.SenderID, 10),
+		"GOG_TIMESTAMP="+strconv.FormatInt(msg.Timestamp, 10),
+		"GOG_TOPIC="+msg.Topic,
+	)
+	cmd.Stdin = bytes.NewReader(msg.Payload)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		log.Errorf("server.UserMessageHandler(): Failed to run command: %v\n", err)
+	if err := cmd.Run(); ctx.Err() == context.DeadlineExceeded {
+		rh.logger().Errorf("server.UserMessageHandler(): Command timed out after %dms\n", rh.cfg.UserMsgHandlerTimeout)
+	} else if err != nil {
+		rh.logger().Errorf("server.UserMessageHandler(): Failed to run command: %v\n", err)
+	}
+}
+
+// logger() returns the logger of the
+// config, or the default logger if none.
+func (rh *RESTServer) logger() log.Logger {
+	if rh.cfg.Logger != nil {
+		return rh.cfg.Logger
 	}
+	return log.Default()
 }
 
 // ServeHTTP implements the http.Handler for RESTServer.
-// It will get the handler from mux and invoke the handler.
+// It will get the handler from mux and invoke the handler,
+// if the request is authorized. The health checks are not
+// authorized unless RESTAuthProbes is set, as the probes
+// might not have the token, nor is the dashboard page. The
+// CORS preflights of the allowed origins are answered here.
 func (rh *RESTServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if rh.cors(w, r) {
+		return
+	}
+	probe := (r.URL.Path == healthURL || r.URL.Path == readyURL) && !rh.cfg.RESTAuthProbes
+	if !probe && r.URL.Path != uiURL && !rh.authorized(r) {
+		unauthorized(w)
+		return
+	}
 	h, _ := rh.mux.Handler(r)
 	h.ServeHTTP(w, r)
 }
+
+// unauthorized() writes 401 Unauthorized.
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	http.Error(w, errUnauthorized.Error(), http.StatusUnauthorized)
+}
+
+// authorized() returns true if the auth token is disabled, or the
+// request has the bearer token. The token is compared in constant time.
+func (rh *RESTServer) authorized(r *http.Request) bool {
+	if rh.cfg.RESTAuthToken == "" {
+		return true
+	}
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return false
+	}
+	token := strings.TrimPrefix(auth, prefix)
+	return subtle.ConstantTimeCompare([]byte(token), []byte(rh.cfg.RESTAuthToken)) == 1
+}
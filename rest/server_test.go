@@ -0,0 +1,893 @@
+package rest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lilymona/gog/agent"
+	"github.com/lilymona/gog/config"
+	log "github.com/lilymona/gog/logging"
+	"github.com/lilymona/testify/assert"
+)
+
+func TestConfigDiff(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.AddrStr = ":1234"
+	cfg.PViewSize = 100
+	cfg.Peers = []string{"localhost:8424"}
+	rh := &RESTServer{cfg: cfg, ag: agent.NewAgent(cfg)}
+
+	w := httptest.NewRecorder()
+	rh.Config(w, httptest.NewRequest("GET", configURL+"?diff=1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var diff map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &diff))
+	assert.Equal(t, map[string]interface{}{
+		"address":      ":1234",
+		"passive_view": float64(100),
+	}, diff)
+
+	w = httptest.NewRecorder()
+	rh.Config(w, httptest.NewRequest("GET", configURL+"?diff=foo", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestConfigReload(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.LocalTCPAddr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	rh := &RESTServer{cfg: cfg, ag: agent.NewAgent(cfg)}
+	defer log.SetLevel(log.GetLevel())
+
+	w := httptest.NewRecorder()
+	rh.Config(w, httptest.NewRequest("PUT", configURL, strings.NewReader(`{"message_life": 1000, "log_level": "debug"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1000, rh.ag.Config().MLife)
+	assert.Equal(t, log.LevelDebug, log.GetLevel())
+	var got map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, float64(1000), got["message_life"])
+
+	// Nothing is applied if a field is invalid.
+	for _, body := range []string{
+		`{"message_life": 2000, "address": ":1234"}`,
+		`{"message_life": 2000, "active_view_min": 100}`,
+		`{"message_life": 2000, "log_level": "foo"}`,
+		`foo`,
+	} {
+		w = httptest.NewRecorder()
+		rh.Config(w, httptest.NewRequest("PUT", configURL, strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+	assert.Equal(t, 1000, rh.ag.Config().MLife)
+	assert.Equal(t, log.LevelDebug, log.GetLevel())
+
+	w = httptest.NewRecorder()
+	rh.Config(w, httptest.NewRequest("DELETE", configURL, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestLeave(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.LocalTCPAddr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	code := -1
+	rh := &RESTServer{
+		cfg:  cfg,
+		ag:   agent.NewAgent(cfg),
+		exit: func(c int) { code = c },
+	}
+
+	w := httptest.NewRecorder()
+	rh.Leave(w, httptest.NewRequest("POST", leaveURL, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 0, code)
+}
+
+func TestMetrics(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.LocalTCPAddr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	mux := http.NewServeMux()
+	rh := &RESTServer{cfg: cfg, ag: agent.NewAgent(cfg), mux: mux}
+	rh.RegisterAPI(mux)
+
+	for _, msg := range []string{"a", "b", "c"} {
+		w := httptest.NewRecorder()
+		rh.ServeHTTP(w, httptest.NewRequest("POST", broadcastURL+"?message="+msg, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	rh.ServeHTTP(w, httptest.NewRequest("GET", metricsURL, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var metrics map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &metrics))
+	assert.Equal(t, float64(3), metrics["broadcasts"])
+	assert.Equal(t, float64(0), metrics["received"])
+	assert.Equal(t, float64(0), metrics["active_view"])
+
+	// The stats endpoint serves the same counters.
+	w = httptest.NewRecorder()
+	rh.ServeHTTP(w, httptest.NewRequest("GET", statsURL, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var stats map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, float64(3), stats["broadcasts"])
+	for _, key := range []string{"bytes_in", "bytes_out", "shuffles", "rejoins", "uptime_ms", "duplicates", "passive_view"} {
+		assert.Contains(t, stats, key)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.LocalTCPAddr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	cfg.RESTAuthToken = "s3cr3t"
+	mux := http.NewServeMux()
+	rh := &RESTServer{cfg: cfg, ag: agent.NewAgent(cfg), mux: mux}
+	rh.RegisterAPI(mux)
+
+	// The agent is not listening, and the probes need no token.
+	for _, url := range []string{healthURL, readyURL} {
+		w := httptest.NewRecorder()
+		rh.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		var h agent.Health
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &h))
+		assert.False(t, h.Listening)
+		assert.Equal(t, int64(-1), h.SinceShuffle)
+	}
+
+	ag, cfg := startTestAgent(t)
+	defer ag.Close()
+	cfg.AViewMinSize = 0
+	rh = &RESTServer{cfg: cfg, ag: ag, mux: http.NewServeMux()}
+	rh.RegisterAPI(rh.mux)
+
+	w := httptest.NewRecorder()
+	rh.ServeHTTP(w, httptest.NewRequest("GET", healthURL, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	// Not ready until it joins the overlay.
+	w = httptest.NewRecorder()
+	rh.ServeHTTP(w, httptest.NewRequest("GET", readyURL, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	peer, peerCfg := startTestAgent(t)
+	defer peer.Close()
+	assert.NoError(t, ag.Join(peerCfg.AddrStr))
+	w = httptest.NewRecorder()
+	rh.ServeHTTP(w, httptest.NewRequest("GET", readyURL, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestShutdown(t *testing.T) {
+	ag, cfg := startTestAgent(t)
+	defer ag.Close()
+	rh := &RESTServer{cfg: cfg, ag: ag, mux: http.NewServeMux()}
+	rh.RegisterAPI(rh.mux)
+	srv := &http.Server{Handler: rh}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ln) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, Shutdown(ctx, srv))
+	assert.Equal(t, http.ErrServerClosed, <-served)
+	assert.False(t, ag.Health().Listening)
+	_, err = net.DialTimeout("tcp", cfg.AddrStr, time.Second)
+	assert.Error(t, err)
+}
+
+func TestDebug(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.LocalTCPAddr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	cfg.RESTAuthToken = "s3cr3t"
+	rh := &RESTServer{cfg: cfg, ag: agent.NewAgent(cfg), mux: http.NewServeMux()}
+	rh.RegisterAPI(rh.mux)
+
+	// The debug endpoints are disabled by default.
+	r := httptest.NewRequest("GET", debugURL, nil)
+	r.Header.Set("Authorization", "Bearer s3cr3t")
+	w := httptest.NewRecorder()
+	rh.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	cfg.Debug = true
+	rh = &RESTServer{cfg: cfg, ag: rh.ag, mux: http.NewServeMux()}
+	rh.RegisterAPI(rh.mux)
+	for _, url := range []string{debugURL, pprofURL + "goroutine?debug=1"} {
+		w = httptest.NewRecorder()
+		rh.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		r = httptest.NewRequest("GET", url, nil)
+		r.Header.Set("Authorization", "Bearer s3cr3t")
+		w = httptest.NewRecorder()
+		rh.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+		if url == debugURL {
+			var state debugState
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+			assert.True(t, state.Agent.Goroutines > 0)
+			assert.Equal(t, 0, state.Agent.ActiveView)
+			assert.Equal(t, redacted, state.Config.RESTAuthToken)
+		}
+	}
+	srv := &http.Server{Handler: rh}
+	assert.Nil(t, NewDebugServer(srv, cfg))
+
+	// On the debug address, the REST server does not serve them.
+	cfg.DebugAddrStr = "127.0.0.1:0"
+	rh = &RESTServer{cfg: cfg, ag: rh.ag, mux: http.NewServeMux()}
+	rh.RegisterAPI(rh.mux)
+	srv.Handler = rh
+	r = httptest.NewRequest("GET", debugURL, nil)
+	r.Header.Set("Authorization", "Bearer s3cr3t")
+	w = httptest.NewRecorder()
+	rh.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	dbg := NewDebugServer(srv, cfg)
+	if !assert.NotNil(t, dbg) {
+		return
+	}
+	w = httptest.NewRecorder()
+	dbg.Handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = httptest.NewRecorder()
+	dbg.Handler.ServeHTTP(w, httptest.NewRequest("GET", debugURL, nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestUserMessageHandler(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "handler.sh")
+	out := filepath.Join(dir, "out")
+	err := ioutil.WriteFile(script, []byte(`#!/bin/sh
+[ "$GOG_TOPIC" = slow ] && exec sleep 5
+{ echo "$GOG_SENDER_ID $GOG_TIMESTAMP $GOG_TOPIC"; cat; } > `+out+`.$GOG_SENDER_ID
+`), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.UserMsgHandler = script
+	cfg.UserMsgHandlerTimeout = 100
+	rh := &RESTServer{cfg: cfg, handlers: make(chan struct{}, 1)}
+
+	// The binary payload is fed on stdin.
+	payload := []byte{'a', 0, '\n', 0xff}
+	rh.UserMessagHandler(agent.Message{SenderID: 42, Payload: payload, Timestamp: 7, Topic: "foo"})
+	b, err := ioutil.ReadFile(out + ".42")
+	assert.NoError(t, err)
+	assert.Equal(t, append([]byte("42 7 foo\n"), payload...), b)
+
+	// The handler is killed after the timeout, and the
+	// other handlers wait for it at the concurrency limit.
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rh.UserMessagHandler(agent.Message{SenderID: 43, Topic: "slow"})
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= 200*time.Millisecond, "ran in %v", elapsed)
+	assert.True(t, elapsed < 4*time.Second, "ran in %v", elapsed)
+	_, err = os.Stat(out + ".43")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLogLevel(t *testing.T) {
+	rh := &RESTServer{cfg: config.DefaultConfig()}
+	defer log.SetLevel(log.GetLevel())
+
+	w := httptest.NewRecorder()
+	rh.LogLevel(w, httptest.NewRequest("POST", logLevelURL+"?level=info", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, log.LevelInfo, log.GetLevel())
+
+	w = httptest.NewRecorder()
+	rh.LogLevel(w, httptest.NewRequest("POST", logLevelURL+"?level=debug", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	rh.LogLevel(w, httptest.NewRequest("GET", logLevelURL, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"level":"debug"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	rh.LogLevel(w, httptest.NewRequest("POST", logLevelURL+"?level=1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, log.LevelWarning, log.GetLevel())
+
+	for _, level := range []string{"", "foo", "4", "-1"} {
+		w = httptest.NewRecorder()
+		rh.LogLevel(w, httptest.NewRequest("POST", logLevelURL+"?level="+level, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+	assert.Equal(t, log.LevelWarning, log.GetLevel())
+
+	w = httptest.NewRecorder()
+	rh.LogLevel(w, httptest.NewRequest("PUT", logLevelURL, strings.NewReader(`{"level":"error"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"level":"error"}`, w.Body.String())
+	assert.Equal(t, log.LevelError, log.GetLevel())
+
+	for _, body := range []string{`{"level":"foo"}`, `"info"`, `{}`} {
+		w = httptest.NewRecorder()
+		rh.LogLevel(w, httptest.NewRequest("PUT", logLevelURL, strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+	assert.Equal(t, log.LevelError, log.GetLevel())
+}
+
+// startTestAgent starts an agent on an unused local address,
+// and waits until it accepts connections.
+func startTestAgent(t *testing.T) (agent.Agent, *config.Config) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.AddrStr = ln.Addr().String()
+	cfg.LocalTCPAddr = ln.Addr().(*net.TCPAddr)
+	ln.Close()
+
+	ag := agent.NewAgent(cfg)
+	ag.RegisterMessageHandler(func(agent.Message) {})
+	go ag.Serve()
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial(cfg.Net, cfg.AddrStr); err == nil {
+			conn.Close()
+			return ag, cfg
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Agent %s is not serving", cfg.AddrStr)
+	return nil, nil
+}
+
+func TestBroadcastBinary(t *testing.T) {
+	ag, cfg := startTestAgent(t)
+	defer ag.Close()
+	peer, _ := startTestAgent(t)
+	defer peer.Close()
+
+	received := make(chan []byte, 1)
+	peer.RegisterMessageHandler(func(msg agent.Message) { received <- msg.Payload })
+	assert.NoError(t, peer.Join(cfg.AddrStr))
+	rh := &RESTServer{cfg: cfg, ag: ag}
+
+	payload := []byte{0x00, 0xff, 'a', 0x00, 0x80, '&', '='}
+	r := httptest.NewRequest("POST", broadcastURL, bytes.NewReader(payload))
+	r.Header.Set("Content-Type", "application/octet-stream")
+	w := httptest.NewRecorder()
+	rh.Broadcast(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	select {
+	case b := <-received:
+		assert.Equal(t, payload, b)
+	case <-time.After(time.Second):
+		t.Fatal("Message is not delivered")
+	}
+
+	r = httptest.NewRequest("POST", broadcastURL, nil)
+	r.Header.Set("Content-Type", "application/octet-stream")
+	w = httptest.NewRecorder()
+	rh.Broadcast(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	cfg.MaxMessageSize = 4
+	r = httptest.NewRequest("POST", broadcastURL, bytes.NewReader(payload))
+	r.Header.Set("Content-Type", "application/octet-stream")
+	w = httptest.NewRecorder()
+	rh.Broadcast(w, r)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestAuthToken(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.RESTAuthToken = "secret"
+	cfg.ClusterSecret = "secret"
+	cfg.LocalTCPAddr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	mux := http.NewServeMux()
+	rh := &RESTServer{cfg: cfg, ag: agent.NewAgent(cfg), mux: mux}
+	rh.RegisterAPI(mux)
+
+	for _, auth := range []string{"", "secret", "Bearer", "Bearer wrong", "Bearer secret2"} {
+		r := httptest.NewRequest("GET", logLevelURL, nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		rh.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, auth)
+	}
+
+	r := httptest.NewRequest("GET", logLevelURL, nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	rh.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The token and the secret are not disclosed by the configuration.
+	r = httptest.NewRequest("GET", configURL, nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	rh.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), `"secret"`)
+
+	// The health checks need the token only if configured.
+	for _, probes := range []bool{false, true} {
+		cfg.RESTAuthProbes = probes
+		for _, url := range []string{healthURL, readyURL} {
+			w = httptest.NewRecorder()
+			rh.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+			assert.Equal(t, probes, w.Code == http.StatusUnauthorized, url)
+		}
+	}
+}
+
+func TestTopology(t *testing.T) {
+	peer, _ := startTestAgent(t)
+	defer peer.Close()
+	ag, cfg := startTestAgent(t)
+	defer ag.Close()
+	assert.NoError(t, ag.Join(peer.Config().AddrStr))
+	rh := &RESTServer{cfg: cfg, ag: ag, mux: http.NewServeMux()}
+	rh.RegisterAPI(rh.mux)
+
+	w := httptest.NewRecorder()
+	rh.ServeHTTP(w, httptest.NewRequest("GET", topologyURL+"?ttl=2&timeout=300", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var topo agent.Topology
+	if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &topo)) {
+		assert.Len(t, topo.Nodes, 2)
+		assert.Len(t, topo.Edges, 2)
+	}
+
+	// The ttl is lowered to its maximum.
+	w = httptest.NewRecorder()
+	rh.ServeHTTP(w, httptest.NewRequest("GET", topologyURL+"?ttl=4294967296&timeout=100", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	for _, query := range []string{"?ttl=-1", "?ttl=x", "?timeout=0"} {
+		w = httptest.NewRecorder()
+		rh.ServeHTTP(w, httptest.NewRequest("GET", topologyURL+query, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestSettings(t *testing.T) {
+	ag, cfg := startTestAgent(t)
+	defer ag.Close()
+	defer log.SetLevel(log.GetLevel())
+	rh := &RESTServer{cfg: cfg, ag: ag, mux: http.NewServeMux()}
+	rh.RegisterAPI(rh.mux)
+
+	w := httptest.NewRecorder()
+	rh.ServeHTTP(w, httptest.NewRequest("PUT", settingsURL, strings.NewReader(`{"log_level": "info", "passive_view": 40, "foo": {"bar": 1}}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"log_level": "info", "passive_view": 40, "foo": {"bar": 1}}`, w.Body.String())
+	assert.Equal(t, log.LevelInfo, log.GetLevel())
+	assert.Equal(t, 40, ag.Config().PViewSize)
+
+	// The settings this agent cannot apply are not gossiped.
+	for _, body := range []string{
+		`{"address": "localhost:1"}`,
+		`{"log_level": "foo"}`,
+		`{"passive_view": -1}`,
+		`{"foo": "` + strings.Repeat("x", 4096) + `"}`,
+		`[]`,
+	} {
+		w = httptest.NewRecorder()
+		rh.ServeHTTP(w, httptest.NewRequest("PUT", settingsURL, strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	w = httptest.NewRecorder()
+	rh.ServeHTTP(w, httptest.NewRequest("GET", settingsURL, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"log_level": "info", "passive_view": 40, "foo": {"bar": 1}}`, w.Body.String())
+}
+
+func TestCORS(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.RESTAuthToken = "secret"
+	cfg.RESTCORSOrigins = []string{"https://dashboard.example.com"}
+	cfg.LocalTCPAddr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	mux := http.NewServeMux()
+	rh := &RESTServer{cfg: cfg, ag: agent.NewAgent(cfg), mux: mux}
+	rh.RegisterAPI(mux)
+
+	// The preflight of an allowed origin needs no token.
+	r := httptest.NewRequest("OPTIONS", broadcastURL, nil)
+	r.Header.Set("Origin", "https://dashboard.example.com")
+	r.Header.Set("Access-Control-Request-Method", "POST")
+	w := httptest.NewRecorder()
+	rh.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://dashboard.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+
+	// The requests get the headers, and still need the token.
+	r = httptest.NewRequest("GET", statsURL, nil)
+	r.Header.Set("Origin", "https://dashboard.example.com")
+	w = httptest.NewRecorder()
+	rh.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "https://dashboard.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+
+	// The other origins get no headers.
+	r = httptest.NewRequest("OPTIONS", broadcastURL, nil)
+	r.Header.Set("Origin", "https://evil.example.com")
+	r.Header.Set("Access-Control-Request-Method", "POST")
+	w = httptest.NewRecorder()
+	rh.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestUI(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.RESTAuthToken = "secret"
+	cfg.LocalTCPAddr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	mux := http.NewServeMux()
+	rh := &RESTServer{cfg: cfg, ag: agent.NewAgent(cfg), mux: mux}
+	rh.RegisterAPI(mux)
+
+	// The page needs no token, while the API it polls does.
+	w := httptest.NewRecorder()
+	rh.ServeHTTP(w, httptest.NewRequest("GET", uiURL, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	for _, url := range []string{listURL, statsURL, configURL} {
+		assert.Contains(t, w.Body.String(), `"`+url, url)
+	}
+	w = httptest.NewRecorder()
+	rh.ServeHTTP(w, httptest.NewRequest("POST", uiURL, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestSpec(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.LocalTCPAddr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	mux := http.NewServeMux()
+	rh := &RESTServer{cfg: cfg, ag: agent.NewAgent(cfg), mux: mux}
+	rh.RegisterAPI(mux)
+
+	w := httptest.NewRecorder()
+	rh.ServeHTTP(w, httptest.NewRequest("GET", specURL, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			Responses map[string]struct {
+				Content map[string]struct {
+					Schema struct {
+						Properties map[string]interface{} `json:"properties"`
+					} `json:"schema"`
+				} `json:"content"`
+			} `json:"responses"`
+		} `json:"paths"`
+	}
+	if !assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec)) {
+		return
+	}
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+
+	// Every endpoint is described, with the schemas of the responses.
+	for _, ep := range rh.endpoints() {
+		assert.Contains(t, spec.Paths[ep.path], ep.method, ep.path)
+	}
+	stats := spec.Paths[statsURL]["get"].Responses["200"].Content["application/json"].Schema
+	assert.Contains(t, stats.Properties, "bytes_in")
+	cfgSchema := spec.Paths[configURL]["get"].Responses["200"].Content["application/json"].Schema
+	assert.Contains(t, cfgSchema.Properties, "rest_cors_origins")
+	assert.NotContains(t, cfgSchema.Properties, "Transport")
+}
+
+// writeTestCert writes a self-signed certificate for 127.0.0.1 and
+// its key to the directory, and returns the certificate.
+func writeTestCert(t *testing.T, dir string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	if err := ioutil.WriteFile(filepath.Join(dir, "cert.pem"), certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "key.pem"), keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestListenAndServeTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cert := writeTestCert(t, dir)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.RESTAddrStr = addr
+	cfg.RESTTLSCert = filepath.Join(dir, "cert.pem")
+	cfg.RESTTLSKey = filepath.Join(dir, "key.pem")
+	mux := http.NewServeMux()
+	rh := &RESTServer{cfg: cfg, mux: mux}
+	rh.RegisterAPI(mux)
+	srv := &http.Server{Addr: addr, Handler: rh}
+	go ListenAndServe(srv, cfg)
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	var resp *http.Response
+	for i := 0; i < 100; i++ {
+		if resp, err = client.Get("https://" + addr + logLevelURL); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotNil(t, resp.TLS)
+}
+
+func TestPeers(t *testing.T) {
+	ag, cfg := startTestAgent(t)
+	defer ag.Close()
+	peer1, cfg1 := startTestAgent(t)
+	defer peer1.Close()
+	peer2, cfg2 := startTestAgent(t)
+	defer peer2.Close()
+
+	assert.NoError(t, ag.Join(cfg1.AddrStr))
+	assert.NoError(t, ag.Join(cfg2.AddrStr))
+	rh := &RESTServer{cfg: cfg, ag: ag}
+
+	peers := func(query string) []string {
+		w := httptest.NewRecorder()
+		rh.Peers(w, httptest.NewRequest("GET", peersURL+query, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		var peers []string
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &peers))
+		sort.Strings(peers)
+		return peers
+	}
+	expected := []string{cfg1.AddrStr, cfg2.AddrStr}
+	sort.Strings(expected)
+	assert.Equal(t, expected, peers(""))
+	assert.Equal(t, expected, peers("?view=active"))
+	assert.Equal(t, expected, peers("?view=all"))
+	assert.Equal(t, []string{}, peers("?view=passive"))
+
+	w := httptest.NewRecorder()
+	rh.Peers(w, httptest.NewRequest("GET", peersURL+"?view=foo", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestListLabel(t *testing.T) {
+	ag, cfg := startTestAgent(t)
+	defer ag.Close()
+	db, dbCfg := startTestAgent(t)
+	defer db.Close()
+	db.SetLabels(map[string]string{"role": "db"})
+	web, webCfg := startTestAgent(t)
+	defer web.Close()
+	web.SetLabels(map[string]string{"role": "web"})
+
+	assert.NoError(t, ag.Join(dbCfg.AddrStr))
+	assert.NoError(t, ag.Join(webCfg.AddrStr))
+	rh := &RESTServer{cfg: cfg, ag: ag}
+
+	list := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		rh.List(w, httptest.NewRequest("GET", listURL+query, nil))
+		return w
+	}
+	w := list("?label=role=db")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var nodes []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &nodes))
+	if assert.Len(t, nodes, 1) {
+		assert.Equal(t, dbCfg.AddrStr, nodes[0]["address"])
+		assert.Equal(t, map[string]interface{}{"role": "db"}, nodes[0]["labels"])
+	}
+	assert.Equal(t, "[]", list("?label=role=cache").Body.String())
+	assert.Contains(t, list("").Body.String(), `"labels":{"role":"web"}`)
+	assert.Equal(t, http.StatusBadRequest, list("?label=role").Code)
+}
+
+func TestStream(t *testing.T) {
+	rh := &RESTServer{cfg: config.DefaultConfig(), mux: http.NewServeMux()}
+	rh.RegisterAPI(rh.mux)
+	srv := httptest.NewServer(rh)
+	defer srv.Close()
+
+	// Not a WebSocket handshake.
+	resp, err := http.Get(srv.URL + streamURL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// The pages of the other origins are refused, unless allowed.
+	handshake := func(origin string) int {
+		req, _ := http.NewRequest("GET", srv.URL+streamURL, nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusForbidden, handshake("https://evil.example.com"))
+	rh.cfg.RESTCORSOrigins = []string{"https://dashboard.example.com"}
+	assert.Equal(t, http.StatusForbidden, handshake("https://evil.example.com"))
+	assert.Equal(t, http.StatusSwitchingProtocols, handshake("https://dashboard.example.com"))
+	assert.Equal(t, http.StatusSwitchingProtocols, handshake(srv.URL))
+	for rh.streams.len() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	const key = "dGhlIHNhbXBsZSBub25jZQ=="
+	conn.Write([]byte("GET " + streamURL + " HTTP/1.1\r\nHost: localhost\r\n" +
+		"Origin: http://localhost\r\n" +
+		"Upgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+	br := bufio.NewReader(conn)
+	resp, err = http.ReadResponse(br, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	// The example of RFC 6455.
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+
+	for rh.streams.len() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	rh.UserMessagHandler(agent.Message{SenderID: 42, Payload: []byte("hello"), Timestamp: 1, Topic: "foo"})
+
+	readFrame := func() (byte, []byte) {
+		var hdr [2]byte
+		_, err := io.ReadFull(br, hdr[:])
+		assert.NoError(t, err)
+		payload := make([]byte, hdr[1]&0x7f)
+		_, err = io.ReadFull(br, payload)
+		assert.NoError(t, err)
+		return hdr[0] & 0x0f, payload
+	}
+	opcode, payload := readFrame()
+	assert.Equal(t, byte(wsText), opcode)
+	var msg map[string]interface{}
+	assert.NoError(t, json.Unmarshal(payload, &msg))
+	assert.Equal(t, map[string]interface{}{
+		"sender_id": float64(42),
+		"topic":     "foo",
+		"payload":   "aGVsbG8=",
+		"timestamp": float64(1),
+	}, msg)
+
+	// A masked close frame is echoed, and the client unsubscribed.
+	conn.Write([]byte{0x80 | wsClose, 0x80, 0, 0, 0, 0})
+	opcode, _ = readFrame()
+	assert.Equal(t, byte(wsClose), opcode)
+	for rh.streams.len() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStreamHubClose(t *testing.T) {
+	var h streamHub
+	c := h.subscribe()
+	h.close()
+	_, ok := <-c
+	assert.False(t, ok)
+	assert.Equal(t, 0, h.len())
+	assert.Equal(t, 0, h.publish(agent.Message{Payload: []byte("hello")}))
+
+	// The clients subscribing after the close end at once.
+	_, ok = <-h.subscribe()
+	assert.False(t, ok)
+}
+
+func TestEvict(t *testing.T) {
+	ag, cfg := startTestAgent(t)
+	defer ag.Close()
+	peer, peerCfg := startTestAgent(t)
+	defer peer.Close()
+	peer.SetLabels(map[string]string{"name": "peer"})
+
+	assert.NoError(t, ag.Join(peerCfg.AddrStr))
+	nodes := ag.NodesWithLabel("name", "peer")
+	if !assert.Len(t, nodes, 1) {
+		return
+	}
+	rh := &RESTServer{cfg: cfg, ag: ag, mux: http.NewServeMux()}
+	rh.RegisterAPI(rh.mux)
+
+	evict := func(method, path string) int {
+		w := httptest.NewRecorder()
+		rh.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w.Code
+	}
+	path := peersURL + "/" + strconv.FormatUint(nodes[0].Id, 10)
+	assert.Equal(t, http.StatusMethodNotAllowed, evict("GET", path))
+	assert.Equal(t, http.StatusOK, evict("DELETE", path))
+	assert.Equal(t, []string{}, ag.Peers(true, true))
+	assert.Equal(t, http.StatusNotFound, evict("DELETE", path))
+	assert.Equal(t, http.StatusBadRequest, evict("DELETE", peersURL+"/foo"))
+}
@@ -0,0 +1,67 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+const settingsURL = "/api/settings"
+
+// Settings get/set the cluster settings, a JSON object whose values are
+// JSON too, e.g. {"log_level": "info", "send_message_rate": 100}. The
+// agent applies the settings like a PUT of the config, so a PUT is
+// rejected if this agent cannot apply it, and then gossips them to the
+// cluster, where each agent applies the ones that change. The keys that
+// are neither a field of the config nor "log_level" are only stored.
+func (rh *RESTServer) Settings(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+	case "PUT":
+		b, err := ioutil.ReadAll(io.LimitReader(r.Body, maxConfigSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var v map[string]json.RawMessage
+		if err := json.Unmarshal(b, &v); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		settings := make(map[string]string, len(v))
+		for k, raw := range v {
+			settings[k] = string(raw)
+		}
+		if err := rh.ag.SetSettings(settings); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, errInvalidMethod.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+
+	b, err := json.Marshal(settingsObject(rh.ag.Settings()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprint(w, string(b))
+}
+
+// settingsObject() returns the JSON object of the settings. The values
+// which are not JSON, e.g. set by a program embedding the agent, are
+// strings.
+func settingsObject(settings map[string]string) map[string]interface{} {
+	obj := make(map[string]interface{}, len(settings))
+	for k, v := range settings {
+		if json.Valid([]byte(v)) {
+			obj[k] = json.RawMessage(v)
+		} else {
+			obj[k] = v
+		}
+	}
+	return obj
+}
@@ -0,0 +1,275 @@
+package rest
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/lilymona/gog/agent"
+	"github.com/lilymona/gog/config"
+	"github.com/lilymona/gog/node"
+)
+
+const specURL = "/api/spec"
+
+// endpoint describes an operation of the REST API in the spec.
+type endpoint struct {
+	path, method, summary string
+	params                []param
+	// The content types of the request body, and the value whose type
+	// is the schema of the JSON body, nil if it is not JSON.
+	body     []string
+	bodyType interface{}
+	// The value whose type is the schema of the JSON response,
+	// nil if the response has no JSON body.
+	response interface{}
+	// The status codes other than 200 and their descriptions.
+	statuses map[int]string
+}
+
+// param is a parameter of an endpoint, in the query or the path.
+type param struct {
+	name, in, description string
+	enum                  []string
+}
+
+// listResponse is the response of the list endpoint.
+type listResponse struct {
+	ActiveView  []*node.Node `json:"active_view"`
+	PassiveView []*node.Node `json:"passive_view"`
+}
+
+// endpoints() returns the endpoints registered by RegisterAPI.
+func (rh *RESTServer) endpoints() []endpoint {
+	form := []string{"application/x-www-form-urlencoded"}
+	level := map[string]string{}
+	eps := []endpoint{
+		{path: listURL, method: "get", summary: "List the active and the passive views",
+			params:   []param{{name: "label", in: "query", description: "List the nodes with the label, key=value"}},
+			response: &listResponse{}},
+		{path: peersURL, method: "get", summary: "List the addresses of the peers",
+			params:   []param{{name: "view", in: "query", description: "The view of the peers", enum: []string{"active", "passive", "all"}}},
+			response: []string{}},
+		{path: peersURL + "/{id}", method: "delete", summary: "Evict the node from the views, and ban it for a while",
+			params:   []param{{name: "id", in: "path", description: "The node id"}},
+			statuses: map[int]string{http.StatusNotFound: "The node is not in the views"}},
+		{path: joinURL, method: "post", summary: "Join the peer in the form, or the peers in the JSON array",
+			params: []param{{name: "peer", in: "query", description: "The address of the peer"}},
+			body:   []string{"application/json"}, bodyType: []string{}},
+		{path: broadcastURL, method: "post", summary: "Broadcast the message in the form, or the raw body",
+			params:   []param{{name: "message", in: "query", description: "The message"}},
+			body:     append(form, "application/octet-stream"),
+			statuses: map[int]string{http.StatusRequestEntityTooLarge: "The message is too large"}},
+		{path: configURL, method: "get", summary: "Get the configuration",
+			params:   []param{{name: "diff", in: "query", description: "Only return the fields that differ from the defaults"}},
+			response: &config.Config{}},
+		{path: configURL, method: "put", summary: "Change the tunables in the configuration",
+			body: []string{"application/json"}, bodyType: &config.Config{}, response: &config.Config{}},
+		{path: leaveURL, method: "post", summary: "Leave the cluster, and exit"},
+		{path: statsURL, method: "get", summary: "Get the runtime counters and gauges", response: &agent.Stats{}},
+		{path: metricsURL, method: "get", summary: "Get the runtime counters and gauges, for the older clients", response: &agent.Stats{}},
+		{path: logLevelURL, method: "get", summary: "Get the log level", response: level},
+		{path: logLevelURL, method: "post", summary: "Set the log level",
+			params:   []param{{name: "level", in: "query", description: "The log level", enum: []string{"error", "warning", "info", "debug"}}},
+			body:     form,
+			response: level},
+		{path: logLevelURL, method: "put", summary: "Set the log level in the JSON object",
+			body: []string{"application/json"}, bodyType: level, response: level},
+		{path: streamURL, method: "get", summary: "Stream the received messages over a WebSocket",
+			statuses: map[int]string{http.StatusSwitchingProtocols: "The WebSocket of the JSON messages"}},
+		{path: healthURL, method: "get", summary: "Get the health of the agent", response: &agent.Health{},
+			statuses: map[int]string{http.StatusServiceUnavailable: "The agent is degraded"}},
+		{path: readyURL, method: "get", summary: "Get the readiness of the agent", response: &agent.Health{},
+			statuses: map[int]string{http.StatusServiceUnavailable: "The agent is not ready"}},
+		{path: specURL, method: "get", summary: "Get the OpenAPI document of the REST API", response: map[string]interface{}{}},
+		{path: uiURL, method: "get", summary: "Get the page of the dashboard"},
+		{path: topologyURL, method: "get", summary: "Crawl the overlay, and get the graph of the nodes that answered",
+			params: []param{
+				{name: "ttl", in: "query", description: "The hops to crawl, 16 at most"},
+				{name: "timeout", in: "query", description: "The time to wait for the answers (milliseconds), 30000 at most"},
+			},
+			response: &agent.Topology{}},
+		{path: settingsURL, method: "get", summary: "Get the cluster settings", response: map[string]interface{}{}},
+		{path: settingsURL, method: "put", summary: "Change the cluster settings, and gossip them to the cluster",
+			body: []string{"application/json"}, bodyType: map[string]interface{}{}, response: map[string]interface{}{}},
+	}
+	if rh.cfg.Debug && rh.cfg.DebugAddrStr == "" {
+		eps = append(eps, endpoint{path: debugURL, method: "get", summary: "Get the internal state of the agent", response: &debugState{}})
+	}
+	return eps
+}
+
+// Spec returns the OpenAPI document of the REST API, generated from
+// the endpoints, and the types of their JSON bodies and responses.
+func (rh *RESTServer) Spec(w http.ResponseWriter, r *http.Request) {
+	b, err := json.Marshal(rh.spec())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, string(b))
+}
+
+// spec() generates the OpenAPI document.
+func (rh *RESTServer) spec() map[string]interface{} {
+	paths := map[string]map[string]interface{}{}
+	for _, ep := range rh.endpoints() {
+		op := map[string]interface{}{"summary": ep.summary}
+		var params []interface{}
+		for _, p := range ep.params {
+			schema := map[string]interface{}{"type": "string"}
+			if p.enum != nil {
+				schema["enum"] = p.enum
+			}
+			params = append(params, map[string]interface{}{
+				"name":        p.name,
+				"in":          p.in,
+				"description": p.description,
+				"required":    p.in == "path",
+				"schema":      schema,
+			})
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+		if ep.body != nil {
+			content := map[string]interface{}{}
+			for _, ct := range ep.body {
+				schema := map[string]interface{}{"type": "string"}
+				switch {
+				case ct == "application/json":
+					schema = schemaOf(reflect.TypeOf(ep.bodyType), nil)
+				case ct == "application/octet-stream":
+					schema["format"] = "binary"
+				default:
+					schema = map[string]interface{}{"type": "object"}
+				}
+				content[ct] = map[string]interface{}{"schema": schema}
+			}
+			op["requestBody"] = map[string]interface{}{"content": content}
+		}
+
+		ok := map[string]interface{}{"description": "OK"}
+		if ep.response != nil {
+			ok["content"] = map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": schemaOf(reflect.TypeOf(ep.response), nil)},
+			}
+		}
+		responses := map[string]interface{}{"200": ok}
+		for code, desc := range ep.statuses {
+			responses[fmt.Sprint(code)] = map[string]interface{}{"description": desc}
+		}
+		if rh.cfg.RESTAuthToken != "" {
+			responses["401"] = map[string]interface{}{"description": "The auth token is missing or wrong"}
+		}
+		op["responses"] = responses
+
+		if paths[ep.path] == nil {
+			paths[ep.path] = map[string]interface{}{}
+		}
+		paths[ep.path][ep.method] = op
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    map[string]interface{}{"title": "gog", "version": "1"},
+		"paths":   paths,
+	}
+	if rh.cfg.RESTAuthToken != "" {
+		doc["components"] = map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"bearer": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		}
+		doc["security"] = []interface{}{map[string]interface{}{"bearer": []string{}}}
+	}
+	return doc
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemaOf() returns the JSON schema of the type as encoding/json encodes
+// it. The types with their own encoding, and the recursive types, which
+// are seen already, are described as any value.
+func schemaOf(t reflect.Type, seen map[reflect.Type]bool) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(jsonMarshalerType) {
+		return map[string]interface{}{}
+	}
+	if reflect.PtrTo(t).Implements(textMarshalerType) {
+		return map[string]interface{}{"type": "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uintptr:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem(), seen)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaOf(t.Elem(), seen)}
+	case reflect.Struct:
+		if seen[t] {
+			return map[string]interface{}{}
+		}
+		s := make(map[reflect.Type]bool, len(seen)+1)
+		for k := range seen {
+			s[k] = true
+		}
+		s[t] = true
+		props := map[string]interface{}{}
+		addFields(t, s, props)
+		return map[string]interface{}{"type": "object", "properties": props}
+	}
+	return map[string]interface{}{}
+}
+
+// addFields() adds the schemas of the fields of the struct to the
+// properties, named as encoding/json names them, and the fields of
+// the embedded structs without a name as its own.
+func addFields(t reflect.Type, seen map[reflect.Type]bool, props map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addFields(ft, seen, props)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = schemaOf(f.Type, seen)
+	}
+}
@@ -0,0 +1,142 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/lilymona/gog/agent"
+)
+
+// streamBuffer is the number of the messages buffered for a
+// stream client, the messages are dropped if it is full.
+const streamBuffer = 64
+
+// streamMessage is a received user message pushed to the stream clients.
+// The payload is encoded in base64.
+type streamMessage struct {
+	SenderID  uint64 `json:"sender_id"`
+	Topic     string `json:"topic,omitempty"`
+	Payload   []byte `json:"payload"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// streamHub fans out the received user messages to the
+// stream clients. The zero value is an empty hub.
+type streamHub struct {
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+	closed  bool
+}
+
+// subscribe() returns the channel of the messages of a new client,
+// which is closed when the hub is.
+func (h *streamHub) subscribe() chan []byte {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c := make(chan []byte, streamBuffer)
+	if h.closed {
+		close(c)
+		return c
+	}
+	if h.clients == nil {
+		h.clients = make(map[chan []byte]struct{})
+	}
+	h.clients[c] = struct{}{}
+	return c
+}
+
+// close() closes the channels of all the clients, so their streams end,
+// as the server does not track the hijacked connections on shutdown.
+func (h *streamHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for c := range h.clients {
+		close(c)
+		delete(h.clients, c)
+	}
+}
+
+func (h *streamHub) unsubscribe(c chan []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, c)
+}
+
+// len() returns the number of the clients.
+func (h *streamHub) len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// publish() sends the message to all the clients, and returns the
+// number of the clients whose buffer is full, so the message is dropped.
+// It never blocks the delivery of the messages by the agent.
+func (h *streamHub) publish(msg agent.Message) (dropped int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.clients) == 0 {
+		return 0
+	}
+	b, err := json.Marshal(&streamMessage{
+		SenderID:  msg.SenderID,
+		Topic:     msg.Topic,
+		Payload:   msg.Payload,
+		Timestamp: msg.Timestamp,
+	})
+	if err != nil {
+		return 0
+	}
+	for c := range h.clients {
+		select {
+		case c <- b:
+		default:
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// Stream upgrades the request to a WebSocket, and pushes the received
+// user messages to it in JSON text frames until the client closes it,
+// or the server shuts down.
+func (rh *RESTServer) Stream(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgradeWebSocket(w, r, rh.allowedOrigin)
+	if err == errWSOrigin {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer ws.Close()
+
+	msgc := rh.streams.subscribe()
+	defer rh.streams.unsubscribe(msgc)
+
+	donec := make(chan struct{})
+	go func() {
+		if err := ws.readLoop(); err != nil {
+			rh.logger().Debugf("server.Stream(): Stream closed: %v\n", err)
+		}
+		close(donec)
+	}()
+
+	for {
+		select {
+		case b, ok := <-msgc:
+			if !ok {
+				return
+			}
+			if err := ws.writeText(b); err != nil {
+				rh.logger().Debugf("server.Stream(): Failed to write: %v\n", err)
+				return
+			}
+		case <-donec:
+			return
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package rest
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+const uiURL = "/ui"
+
+// uiPage is the single page of the dashboard, which polls the views and
+// the stats with the auth token entered in the page.
+//
+//go:embed ui/index.html
+var uiPage []byte
+
+// UI serves the dashboard. It needs no auth token, as it has
+// no data of its own, and the browsers do not send the token
+// when the page is opened.
+func (rh *RESTServer) UI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, errInvalidMethod.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(uiPage)
+}
@@ -0,0 +1,220 @@
+package rest
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The opcodes of the WebSocket frames.
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xa
+)
+
+// wsGUID is appended to the key of the handshake, as in RFC 6455.
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// wsMaxControlPayload is the maximum payload of the control frames.
+const wsMaxControlPayload = 125
+
+// wsWriteTimeout bounds the write of a frame, so a client that
+// stops reading does not hold the stream forever.
+const wsWriteTimeout = 10 * time.Second
+
+var (
+	errNotWebSocket  = errors.New("server: Not a WebSocket handshake")
+	errUnmaskedFrame = errors.New("server: Unmasked frame from the client")
+	errControlFrame  = errors.New("server: Control frame too large")
+	errWSOrigin      = errors.New("server: Origin not allowed")
+)
+
+// wsConn is the server side of a WebSocket connection. It only writes
+// the text frames, and reads the control frames of the client.
+type wsConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	// wmu serializes the writes of the frames.
+	wmu sync.Mutex
+}
+
+// upgradeWebSocket() completes the WebSocket handshake of the request,
+// and takes over the connection. As the browsers do not apply the same
+// origin policy to the WebSockets, the request is refused if it has an
+// origin which is neither its host, nor allowed.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request, allowed func(string) bool) (*wsConn, error) {
+	if r.Method != "GET" ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" ||
+		r.Header.Get("Sec-WebSocket-Key") == "" {
+		return nil, errNotWebSocket
+	}
+	if origin := r.Header.Get("Origin"); origin != "" && !sameHost(origin, r.Host) && !allowed(origin) {
+		return nil, errWSOrigin
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errNotWebSocket
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + wsAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, br: rw.Reader}, nil
+}
+
+// wsAccept() returns the accept key of the handshake key.
+func wsAccept(key string) string {
+	h := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// sameHost() returns true if the origin is on the host.
+func sameHost(origin, host string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, host)
+}
+
+// headerContains() returns true if the comma-separated
+// header has the token, ignoring the case.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h[name] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeFrame() writes an unfragmented frame.
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	hdr := make([]byte, 2, 10)
+	hdr[0] = 0x80 | opcode
+	switch n := len(payload); {
+	case n < 126:
+		hdr[1] = byte(n)
+	case n <= 0xffff:
+		hdr[1] = 126
+		hdr = hdr[:4]
+		binary.BigEndian.PutUint16(hdr[2:], uint16(n))
+	default:
+		hdr[1] = 127
+		hdr = hdr[:10]
+		binary.BigEndian.PutUint64(hdr[2:], uint64(n))
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if err := c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout)); err != nil {
+		return err
+	}
+	if _, err := c.conn.Write(hdr); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(payload)
+	return err
+}
+
+// writeText() writes a text frame.
+func (c *wsConn) writeText(b []byte) error {
+	return c.writeFrame(wsText, b)
+}
+
+// readLoop() reads the frames of the client until it closes the
+// connection. The pings are answered, and the data frames discarded.
+func (c *wsConn) readLoop() error {
+	for {
+		var hdr [2]byte
+		if _, err := io.ReadFull(c.br, hdr[:]); err != nil {
+			return err
+		}
+		opcode := hdr[0] & 0x0f
+		if hdr[1]&0x80 == 0 {
+			c.writeFrame(wsClose, closePayload(1002))
+			return errUnmaskedFrame
+		}
+
+		n := uint64(hdr[1] & 0x7f)
+		switch n {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+				return err
+			}
+			n = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+				return err
+			}
+			n = binary.BigEndian.Uint64(ext[:])
+		}
+		var mask [4]byte
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return err
+		}
+
+		if opcode < wsClose {
+			if _, err := io.CopyN(ioutil.Discard, c.br, int64(n)); err != nil {
+				return err
+			}
+			continue
+		}
+		if n > wsMaxControlPayload {
+			c.writeFrame(wsClose, closePayload(1002))
+			return errControlFrame
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(c.br, payload); err != nil {
+			return err
+		}
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+		switch opcode {
+		case wsClose:
+			c.writeFrame(wsClose, payload)
+			return nil
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// closePayload() returns the payload of a close frame with the status.
+func closePayload(status uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, status)
+	return b
+}
+
+// Close closes the connection.
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
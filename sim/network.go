@@ -0,0 +1,326 @@
+package sim
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lilymona/gog/transport"
+)
+
+// Network is a simulated network connecting the agents in-process. Every
+// message written to a connection is a packet, which is delivered after
+// the latency, or dropped by the loss or a partition, as if the peer
+// never received it, though the connection stays open. The random
+// choices are made with a seeded source, so a run can be replayed.
+type Network struct {
+	mu sync.Mutex
+	// The random source of the losses and the jitter.
+	rand *rand.Rand
+	// The latency of the packets, plus a random jitter up to jitter.
+	latency, jitter time.Duration
+	// The probability of a packet being lost.
+	loss float64
+	// The partition group of each address, nil if not partitioned.
+	groups map[string]int
+	// The listeners by their addresses.
+	listeners map[string]*listener
+}
+
+// NewNetwork creates a network, which makes the random choices with the seed.
+func NewNetwork(seed int64) *Network {
+	return &Network{
+		rand:      rand.New(rand.NewSource(seed)),
+		listeners: make(map[string]*listener),
+	}
+}
+
+// Transport returns the transport of the agent listening on the address.
+func (n *Network) Transport(addr string) transport.Transport {
+	return &endpoint{n: n, addr: addr}
+}
+
+// SetLatency sets the latency of the packets, and the maximum of the
+// random jitter added to it. The packets of a connection are delivered
+// in order, regardless of the jitter.
+func (n *Network) SetLatency(latency, jitter time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.latency, n.jitter = latency, jitter
+}
+
+// SetLoss sets the probability of a packet being lost, from 0 to 1.
+func (n *Network) SetLoss(p float64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.loss = p
+}
+
+// Partition splits the network into the groups of addresses, and the
+// group of the addresses not given. The nodes of different groups cannot
+// connect each other, and the packets between them are dropped.
+func (n *Network) Partition(groups ...[]string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.groups = make(map[string]int)
+	for i, group := range groups {
+		for _, addr := range group {
+			n.groups[addr] = i + 1
+		}
+	}
+}
+
+// Heal removes the partition.
+func (n *Network) Heal() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.groups = nil
+}
+
+// reachable() returns true if the nodes are not partitioned.
+// It must be called with mu held.
+func (n *Network) reachable(from, to string) bool {
+	return n.groups == nil || n.groups[from] == n.groups[to]
+}
+
+// send() returns the time to deliver a packet from a node to another,
+// or false if the packet is dropped.
+func (n *Network) send(from, to string) (time.Time, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if !n.reachable(from, to) {
+		return time.Time{}, false
+	}
+	if n.loss > 0 && n.rand.Float64() < n.loss {
+		return time.Time{}, false
+	}
+	d := n.latency
+	if n.jitter > 0 {
+		d += time.Duration(n.rand.Int63n(int64(n.jitter) + 1))
+	}
+	return time.Now().Add(d), true
+}
+
+// dial() connects a node to the listener of the address.
+func (n *Network) dial(from, to string) (net.Conn, error) {
+	n.mu.Lock()
+	ln, ok := n.listeners[to]
+	ok = ok && n.reachable(from, to)
+	n.mu.Unlock()
+	if !ok {
+		return nil, transport.ErrConnRefused
+	}
+
+	// A pipe for each direction, so a side can close its read end at
+	// once, while the packets it has written are still delivered.
+	cw, sr := net.Pipe()
+	sw, cr := net.Pipe()
+	client := newConn(n, from, to, cr, cw)
+	server := newConn(n, to, from, sr, sw)
+	select {
+	case ln.connc <- server:
+		return client, nil
+	case <-ln.done:
+		client.Close()
+		server.Close()
+		return nil, transport.ErrConnRefused
+	}
+}
+
+// listen() listens on the address.
+func (n *Network) listen(addr string) (net.Listener, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, ok := n.listeners[addr]; ok {
+		return nil, transport.ErrAddrInUse
+	}
+	ln := &listener{
+		n:     n,
+		addr:  simAddr(addr),
+		connc: make(chan *conn),
+		done:  make(chan struct{}),
+	}
+	n.listeners[addr] = ln
+	return ln, nil
+}
+
+// endpoint is the transport of an agent on the network.
+type endpoint struct {
+	n    *Network
+	addr string
+}
+
+func (e *endpoint) Dial(addr string) (net.Conn, error) {
+	return e.n.dial(e.addr, addr)
+}
+
+func (e *endpoint) Listen(addr string) (net.Listener, error) {
+	return e.n.listen(addr)
+}
+
+// simAddr is an address of the simulated network.
+type simAddr string
+
+func (a simAddr) Network() string { return "sim" }
+func (a simAddr) String() string  { return string(a) }
+
+// listener is a listener of the simulated network.
+type listener struct {
+	n         *Network
+	addr      simAddr
+	connc     chan *conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.connc:
+		return conn, nil
+	case <-l.done:
+		return nil, transport.ErrListenerClosed
+	}
+}
+
+func (l *listener) Close() error {
+	l.closeOnce.Do(func() {
+		l.n.mu.Lock()
+		delete(l.n.listeners, string(l.addr))
+		l.n.mu.Unlock()
+		close(l.done)
+	})
+	return nil
+}
+
+func (l *listener) Addr() net.Addr {
+	return l.addr
+}
+
+// packet is a packet waiting to be delivered.
+type packet struct {
+	b   []byte
+	due time.Time
+}
+
+// conn is a connection of the simulated network. The writes never block,
+// the packets are queued and delivered to the peer in the background.
+type conn struct {
+	n             *Network
+	local, remote simAddr
+	// The read end of the pipe from the peer,
+	// and the write end of the pipe to the peer.
+	r, w net.Conn
+
+	mu      sync.Mutex
+	packets []packet
+	// The due time of the last packet.
+	last   time.Time
+	closed bool
+	// notify wakes up the delivery.
+	notify chan struct{}
+}
+
+func newConn(n *Network, local, remote string, r, w net.Conn) *conn {
+	c := &conn{
+		n:      n,
+		local:  simAddr(local),
+		remote: simAddr(remote),
+		r:      r,
+		w:      w,
+		notify: make(chan struct{}, 1),
+	}
+	go c.deliver()
+	return c
+}
+
+func (c *conn) Read(b []byte) (int, error) { return c.r.Read(b) }
+
+// Write queues the bytes as a packet, unless the packet is dropped.
+func (c *conn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, io.ErrClosedPipe
+	}
+	c.mu.Unlock()
+
+	due, ok := c.n.send(string(c.local), string(c.remote))
+	if !ok {
+		return len(b), nil
+	}
+
+	c.mu.Lock()
+	if due.Before(c.last) {
+		due = c.last
+	}
+	c.last = due
+	c.packets = append(c.packets, packet{b: append([]byte(nil), b...), due: due})
+	c.mu.Unlock()
+	c.wakeup()
+	return len(b), nil
+}
+
+// Close closes the connection. The peer reads io.EOF
+// after the packets written before.
+func (c *conn) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	c.wakeup()
+	return c.r.Close()
+}
+
+func (c *conn) LocalAddr() net.Addr  { return c.local }
+func (c *conn) RemoteAddr() net.Addr { return c.remote }
+
+func (c *conn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *conn) SetReadDeadline(t time.Time) error {
+	return c.r.SetReadDeadline(t)
+}
+
+// SetWriteDeadline does nothing, as the writes never block.
+func (c *conn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// wakeup() wakes up the delivery, if it is waiting.
+func (c *conn) wakeup() {
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// deliver() writes the packets to the peer when they are due, until
+// the connection is closed and the queued packets are delivered,
+// or the peer closes the connection.
+func (c *conn) deliver() {
+	defer c.w.Close()
+	for {
+		c.mu.Lock()
+		if len(c.packets) == 0 {
+			closed := c.closed
+			c.mu.Unlock()
+			if closed {
+				return
+			}
+			<-c.notify
+			continue
+		}
+		p := c.packets[0]
+		c.packets = c.packets[1:]
+		c.mu.Unlock()
+
+		if d := time.Until(p.due); d > 0 {
+			time.Sleep(d)
+		}
+		if _, err := c.w.Write(p.b); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,284 @@
+// Package sim runs a cluster of agents in-process over a simulated
+// network with controllable latency, packet loss and partitions, to
+// test the behavior of the overlay without real sockets.
+package sim
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/lilymona/gog/agent"
+	"github.com/lilymona/gog/config"
+)
+
+var (
+	ErrNodeNotAlive = errors.New("Node is not alive")
+	ErrTimeout      = errors.New("Timeout")
+)
+
+// pollInterval is the interval to check the state of the simulation.
+const pollInterval = 10 * time.Millisecond
+
+// Sim is a cluster of agents on a simulated network.
+type Sim struct {
+	net *Network
+	// The seed of the network, from which the seeds
+	// of the agents are derived.
+	seed int64
+
+	mu sync.Mutex
+	// The addresses of the nodes.
+	addrs []string
+	// The running agents, nil if the node is killed.
+	agents []agent.Agent
+	// The hashes of the messages delivered to each node.
+	delivered []map[[sha1.Size]byte]bool
+	// The sender of each broadcast message.
+	senders map[[sha1.Size]byte]int
+}
+
+// New starts n agents on a network with the seed, configured with the
+// options, and joins them to the first one. The nodes listen on the
+// addresses "node-0" to "node-<n-1>", and take their ids from them. The
+// agents make their random choices with sources seeded from the seed,
+// so the runs with the same seed make the same choices, though the
+// timing of their goroutines still varies.
+func New(n int, seed int64, opts ...config.Option) (*Sim, error) {
+	s := &Sim{
+		net:       NewNetwork(seed),
+		seed:      seed,
+		addrs:     make([]string, n),
+		agents:    make([]agent.Agent, n),
+		delivered: make([]map[[sha1.Size]byte]bool, n),
+		senders:   make(map[[sha1.Size]byte]int),
+	}
+	for i := range s.addrs {
+		s.addrs[i] = fmt.Sprintf("node-%d", i)
+		if err := s.start(i, opts); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	for i := 1; i < n; i++ {
+		if err := s.agents[i].Join(s.addrs[0]); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Network returns the simulated network of the nodes.
+func (s *Sim) Network() *Network {
+	return s.net
+}
+
+// Len returns the number of nodes, including the killed ones.
+func (s *Sim) Len() int {
+	return len(s.addrs)
+}
+
+// Addr returns the address of the i-th node.
+func (s *Sim) Addr(i int) string {
+	return s.addrs[i]
+}
+
+// Agent returns the i-th agent, or nil if it is killed.
+func (s *Sim) Agent(i int) agent.Agent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.agents[i]
+}
+
+// Kill shuts down the i-th node.
+func (s *Sim) Kill(i int) error {
+	s.mu.Lock()
+	ag := s.agents[i]
+	s.agents[i] = nil
+	s.mu.Unlock()
+
+	if ag == nil {
+		return ErrNodeNotAlive
+	}
+	return ag.Close()
+}
+
+// Close kills all the nodes.
+func (s *Sim) Close() {
+	for i := range s.agents {
+		s.Kill(i)
+	}
+}
+
+// Broadcast broadcasts the payload from the i-th node, and returns
+// the hash of the payload for Reach and AwaitReach.
+func (s *Sim) Broadcast(i int, payload []byte) ([sha1.Size]byte, error) {
+	hash := sha1.Sum(payload)
+
+	s.mu.Lock()
+	ag := s.agents[i]
+	if ag == nil {
+		s.mu.Unlock()
+		return hash, ErrNodeNotAlive
+	}
+	s.senders[hash] = i
+	s.mu.Unlock()
+
+	return hash, ag.Broadcast(payload)
+}
+
+// Reach returns the fraction of the alive nodes, except the sender,
+// that have received the message with the payload hash.
+func (s *Sim) Reach(hash [sha1.Size]byte) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var total, received int
+	sender, sent := s.senders[hash]
+	for i, ag := range s.agents {
+		if ag == nil || (sent && i == sender) {
+			continue
+		}
+		total++
+		if s.delivered[i][hash] {
+			received++
+		}
+	}
+	if total == 0 {
+		return 1
+	}
+	return float64(received) / float64(total)
+}
+
+// Received returns true if the i-th node has received the message
+// with the payload hash.
+func (s *Sim) Received(i int, hash [sha1.Size]byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.delivered[i][hash]
+}
+
+// AwaitReach waits until the message with the payload hash
+// reaches at least the fraction of the nodes.
+func (s *Sim) AwaitReach(hash [sha1.Size]byte, fraction float64, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		reach := s.Reach(hash)
+		if reach >= fraction {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%v: the message reached %.2f of the nodes", ErrTimeout, reach)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Converged returns nil if the active views of the alive nodes are
+// not empty, symmetric, and connect all of them, or the violation.
+func (s *Sim) Converged() error {
+	views := make(map[string]map[string]bool)
+	for i := range s.agents {
+		ag := s.Agent(i)
+		if ag == nil {
+			continue
+		}
+		view := make(map[string]bool)
+		for _, addr := range ag.Peers(true, false) {
+			view[addr] = true
+		}
+		views[s.addrs[i]] = view
+	}
+
+	var first string
+	for addr, view := range views {
+		if len(view) == 0 {
+			return fmt.Errorf("node %s has no neighbors", addr)
+		}
+		for peer := range view {
+			if !views[peer][addr] {
+				return fmt.Errorf("node %s has neighbor %s, but not vice versa", addr, peer)
+			}
+		}
+		first = addr
+	}
+	if first == "" {
+		return nil
+	}
+
+	// Every node is reachable from any node.
+	visited := map[string]bool{first: true}
+	queue := []string{first}
+	for len(queue) > 0 {
+		addr := queue[0]
+		queue = queue[1:]
+		for peer := range views[addr] {
+			if !visited[peer] {
+				visited[peer] = true
+				queue = append(queue, peer)
+			}
+		}
+	}
+	if len(visited) != len(views) {
+		return fmt.Errorf("only %d of %d nodes are connected", len(visited), len(views))
+	}
+	return nil
+}
+
+// AwaitConvergence waits until the active views converge, see Converged.
+func (s *Sim) AwaitConvergence(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := s.Converged()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%v: %v", ErrTimeout, err)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// start() starts the i-th agent on the network.
+func (s *Sim) start(i int, opts []config.Option) error {
+	addr := s.addrs[i]
+	cfg, err := config.New(append([]config.Option{
+		config.WithAddr(addr),
+		config.WithTransport(s.net.Transport(addr)),
+		config.WithIdentity(config.IDAddr, ""),
+		config.WithRand(rand.NewSource(s.seed + int64(i) + 1)),
+	}, opts...)...)
+	if err != nil {
+		return err
+	}
+	ag, err := agent.Start(cfg)
+	if err != nil {
+		return err
+	}
+	ag.RegisterMessageHandler(func(msg agent.Message) {
+		s.deliver(ag, i, msg.Payload)
+	})
+
+	s.mu.Lock()
+	s.agents[i] = ag
+	s.delivered[i] = make(map[[sha1.Size]byte]bool)
+	s.mu.Unlock()
+	return nil
+}
+
+// deliver() records a message delivered to the i-th node.
+func (s *Sim) deliver(ag agent.Agent, i int, payload []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The message is delivered to an agent that has been killed.
+	if s.agents[i] != ag {
+		return
+	}
+	s.delivered[i][sha1.Sum(payload)] = true
+}
@@ -0,0 +1,137 @@
+package sim
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lilymona/gog/config"
+	"github.com/lilymona/gog/transport"
+	"github.com/lilymona/testify/assert"
+)
+
+// pair returns both ends of a connection between the addresses.
+func pair(t *testing.T, n *Network, from, to string) (net.Conn, net.Conn) {
+	ln, err := n.Transport(to).Listen(to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := ln.Accept()
+		accepted <- conn
+	}()
+	client, err := n.Transport(from).Dial(to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, <-accepted
+}
+
+func TestNetwork(t *testing.T) {
+	n := NewNetwork(1)
+	n.SetLatency(50*time.Millisecond, 0)
+	client, server := pair(t, n, "a", "b")
+
+	start := time.Now()
+	_, err := client.Write([]byte("hello"))
+	assert.NoError(t, err)
+	b := make([]byte, 5)
+	_, err = io.ReadFull(server, b)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), b)
+	assert.True(t, time.Since(start) >= 50*time.Millisecond)
+
+	// The lost packets are never delivered.
+	n.SetLatency(0, 0)
+	n.SetLoss(1)
+	_, err = server.Write([]byte("lost"))
+	assert.NoError(t, err)
+	client.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, err = client.Read(b)
+	assert.Error(t, err)
+
+	// The packets written before closing are delivered.
+	n.SetLoss(0)
+	_, err = server.Write([]byte("bye"))
+	assert.NoError(t, err)
+	server.Close()
+	client.SetReadDeadline(time.Time{})
+	b, err = ioutil.ReadAll(client)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bye"), b)
+
+	// The partitioned nodes cannot connect.
+	n.Partition([]string{"a"})
+	_, err = n.Transport("a").Dial("b")
+	assert.Equal(t, transport.ErrConnRefused, err)
+	n.Heal()
+	client, server = pair(t, n, "a", "b")
+	client.Close()
+	server.Close()
+}
+
+func TestConvergence(t *testing.T) {
+	s, err := New(10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	assert.NoError(t, s.AwaitConvergence(5*time.Second))
+
+	hash, err := s.Broadcast(3, []byte("hello"))
+	assert.NoError(t, err)
+	assert.NoError(t, s.AwaitReach(hash, 1, 5*time.Second))
+
+	// The overlay converges again without a node.
+	assert.NoError(t, s.Kill(0))
+	assert.NoError(t, s.AwaitConvergence(5*time.Second))
+	hash, err = s.Broadcast(5, []byte("world"))
+	assert.NoError(t, err)
+	assert.NoError(t, s.AwaitReach(hash, 1, 5*time.Second))
+}
+
+func TestSeed(t *testing.T) {
+	s, err := New(2, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// The agents take the ids from the addresses,
+	// and the choices from the seeded sources.
+	for i := 0; i < s.Len(); i++ {
+		cfg := s.Agent(i).Config()
+		assert.Equal(t, config.IDAddr, cfg.IDStrategy)
+		assert.NotNil(t, cfg.Rand)
+	}
+}
+
+func TestPartition(t *testing.T) {
+	s, err := New(6, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Network().SetLatency(time.Millisecond, time.Millisecond)
+	assert.NoError(t, s.AwaitConvergence(5*time.Second))
+
+	// The message does not cross the partition.
+	s.Network().Partition([]string{s.Addr(0), s.Addr(1), s.Addr(2)})
+	hash, err := s.Broadcast(0, []byte("hello"))
+	assert.NoError(t, err)
+	time.Sleep(200 * time.Millisecond)
+	for i := 3; i < s.Len(); i++ {
+		assert.False(t, s.Received(i, hash))
+	}
+
+	// The message reaches every node after the partition heals.
+	s.Network().Heal()
+	hash, err = s.Broadcast(0, []byte("world"))
+	assert.NoError(t, err)
+	assert.NoError(t, s.AwaitReach(hash, 1, 5*time.Second))
+}
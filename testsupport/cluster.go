@@ -0,0 +1,289 @@
+// Package testsupport provides helpers to integration-test
+// applications built on gog.
+package testsupport
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lilymona/gog/agent"
+	"github.com/lilymona/gog/config"
+)
+
+var (
+	ErrNodeNotAlive = errors.New("Node is not alive")
+	ErrTimeout      = errors.New("Timeout")
+)
+
+// pollInterval is the interval to check the state of the cluster.
+const pollInterval = 10 * time.Millisecond
+
+// Cluster is a cluster of agents running in the current process
+// over the loopback interface.
+type Cluster struct {
+	mu sync.Mutex
+	// The configurations of the nodes.
+	cfgs []*config.Config
+	// The running agents, nil if the node is killed.
+	agents []agent.Agent
+	// The hashes of the messages delivered to each node.
+	delivered []map[[sha1.Size]byte]bool
+	// The sender of each broadcast message.
+	senders map[[sha1.Size]byte]int
+}
+
+// NewCluster starts n agents, joins them together and waits until
+// every agent has at least one neighbor.
+func NewCluster(n int, timeout time.Duration) (*Cluster, error) {
+	c := &Cluster{
+		cfgs:      make([]*config.Config, n),
+		agents:    make([]agent.Agent, n),
+		delivered: make([]map[[sha1.Size]byte]bool, n),
+		senders:   make(map[[sha1.Size]byte]int),
+	}
+	for i := range c.cfgs {
+		cfg, err := newConfig()
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		c.cfgs[i] = cfg
+		if err := c.start(i); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	for i := 1; i < n; i++ {
+		if err := c.agents[i].Join(c.cfgs[0].AddrStr); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	if err := c.awaitNeighbors(timeout); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Len returns the number of nodes in the cluster, including
+// the killed ones.
+func (c *Cluster) Len() int {
+	return len(c.cfgs)
+}
+
+// Agent returns the i-th agent, or nil if it is killed.
+func (c *Cluster) Agent(i int) agent.Agent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.agents[i]
+}
+
+// Broadcast broadcasts the payload from the i-th node, and returns
+// the hash of the payload for AwaitConvergence.
+func (c *Cluster) Broadcast(i int, payload []byte) ([sha1.Size]byte, error) {
+	hash := sha1.Sum(payload)
+
+	c.mu.Lock()
+	ag := c.agents[i]
+	if ag == nil {
+		c.mu.Unlock()
+		return hash, ErrNodeNotAlive
+	}
+	c.senders[hash] = i
+	c.mu.Unlock()
+
+	return hash, ag.Broadcast(payload)
+}
+
+// AwaitConvergence waits until every alive node, except the sender,
+// has received the message with the given payload hash.
+func (c *Cluster) AwaitConvergence(hash [sha1.Size]byte, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		missing := c.missing(hash)
+		if len(missing) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%v: nodes %v did not receive the message", ErrTimeout, missing)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Kill shuts down the i-th node without notifying its peers,
+// as if it had crashed.
+func (c *Cluster) Kill(i int) error {
+	c.mu.Lock()
+	ag := c.agents[i]
+	c.agents[i] = nil
+	c.mu.Unlock()
+
+	if ag == nil {
+		return ErrNodeNotAlive
+	}
+	return ag.Close()
+}
+
+// Revive restarts the i-th node on its old address, and joins it
+// to the cluster through the other alive nodes.
+func (c *Cluster) Revive(i int) error {
+	c.mu.Lock()
+	if c.agents[i] != nil {
+		c.mu.Unlock()
+		return nil
+	}
+	var peers []string
+	for j, ag := range c.agents {
+		if ag != nil && j != i {
+			peers = append(peers, c.cfgs[j].AddrStr)
+		}
+	}
+	c.mu.Unlock()
+
+	if err := c.start(i); err != nil {
+		return err
+	}
+	if len(peers) == 0 {
+		return nil
+	}
+	return c.Agent(i).Join(peers...)
+}
+
+// Close kills all the nodes.
+func (c *Cluster) Close() {
+	for i := range c.agents {
+		c.Kill(i)
+	}
+}
+
+// start() starts the i-th agent, and waits until it accepts connections.
+func (c *Cluster) start(i int) error {
+	cfg := c.cfgs[i]
+	ag := agent.NewAgent(cfg)
+	ag.RegisterMessageHandler(func(msg agent.Message) {
+		c.deliver(ag, i, msg.Payload)
+	})
+
+	errc := make(chan error, 1)
+	go func() { errc <- ag.Serve() }()
+
+	for {
+		select {
+		case err := <-errc:
+			if err == nil {
+				err = ErrNodeNotAlive
+			}
+			return err
+		default:
+		}
+		if conn, err := net.Dial(cfg.Net, cfg.AddrStr); err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(pollInterval)
+	}
+
+	c.mu.Lock()
+	c.agents[i] = ag
+	c.delivered[i] = make(map[[sha1.Size]byte]bool)
+	c.mu.Unlock()
+	return nil
+}
+
+// deliver() records a message delivered to the i-th node.
+func (c *Cluster) deliver(ag agent.Agent, i int, payload []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The message is delivered to an agent that has been killed.
+	if c.agents[i] != ag {
+		return
+	}
+	c.delivered[i][sha1.Sum(payload)] = true
+}
+
+// missing() returns the alive nodes that have not received the message.
+func (c *Cluster) missing(hash [sha1.Size]byte) []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var missing []int
+	sender, sent := c.senders[hash]
+	for i, ag := range c.agents {
+		if ag == nil || (sent && i == sender) {
+			continue
+		}
+		if !c.delivered[i][hash] {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
+
+// awaitNeighbors() waits until every alive node has at least one
+// node in its active view.
+func (c *Cluster) awaitNeighbors(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for i := range c.agents {
+		for {
+			ag := c.Agent(i)
+			if ag == nil {
+				break
+			}
+			b, err := ag.List()
+			if err != nil {
+				return err
+			}
+			var v struct {
+				AView []json.RawMessage `json:"active_view"`
+			}
+			if err := json.Unmarshal(b, &v); err != nil {
+				return err
+			}
+			if len(v.AView) > 0 {
+				break
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("%v: node %d has no neighbors", ErrTimeout, i)
+			}
+			time.Sleep(pollInterval)
+		}
+	}
+	return nil
+}
+
+// newConfig() returns a configuration for an agent listening on
+// an unused loopback port.
+func newConfig() (*config.Config, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	addr := ln.Addr().(*net.TCPAddr)
+	ln.Close()
+
+	return &config.Config{
+		Net:             "tcp",
+		AddrStr:         addr.String(),
+		LocalTCPAddr:    addr,
+		AViewMinSize:    3,
+		AViewMaxSize:    5,
+		PViewSize:       30,
+		Ka:              1,
+		Kp:              3,
+		ARWL:            5,
+		PRWL:            3,
+		SRWL:            5,
+		MLife:           5000,
+		ShuffleDuration: 5,
+		HealDuration:    1,
+		PurgeDuration:   5000,
+	}, nil
+}
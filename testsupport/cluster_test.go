@@ -0,0 +1,26 @@
+package testsupport
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lilymona/testify/assert"
+)
+
+func TestClusterKillMidBroadcast(t *testing.T) {
+	c, err := NewCluster(5, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	hash, err := c.Broadcast(0, []byte("hello"))
+	assert.NoError(t, err)
+	assert.NoError(t, c.Kill(2))
+	assert.NoError(t, c.AwaitConvergence(hash, 5*time.Second))
+
+	assert.NoError(t, c.Revive(2))
+	hash, err = c.Broadcast(1, []byte("world"))
+	assert.NoError(t, err)
+	assert.NoError(t, c.AwaitConvergence(hash, 5*time.Second))
+}
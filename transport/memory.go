@@ -0,0 +1,231 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	ErrConnRefused    = errors.New("Connection refused")
+	ErrAddrInUse      = errors.New("Address already in use")
+	ErrListenerClosed = errors.New("Listener closed")
+)
+
+// Memory is an in-memory transport, e.g. for the tests. The agents on
+// the same Memory connect each other by their listen addresses, which
+// can be any strings. The writes never block, as over TCP the kernel
+// buffers them.
+type Memory struct {
+	mu        sync.Mutex
+	listeners map[string]*memoryListener
+}
+
+// NewMemory creates an in-memory transport.
+func NewMemory() *Memory {
+	return &Memory{listeners: make(map[string]*memoryListener)}
+}
+
+// Dial connects the listener of the address.
+func (m *Memory) Dial(addr string) (net.Conn, error) {
+	m.mu.Lock()
+	ln, ok := m.listeners[addr]
+	m.mu.Unlock()
+	if !ok {
+		return nil, ErrConnRefused
+	}
+
+	a, b := newPipe(), newPipe()
+	client := &memoryConn{in: a, out: b, local: memoryAddr("client:" + addr), remote: memoryAddr(addr)}
+	server := &memoryConn{in: b, out: a, local: memoryAddr(addr), remote: client.local}
+	select {
+	case ln.connc <- server:
+		return client, nil
+	case <-ln.done:
+		return nil, ErrConnRefused
+	}
+}
+
+// Listen listens on the address.
+func (m *Memory) Listen(addr string) (net.Listener, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.listeners[addr]; ok {
+		return nil, ErrAddrInUse
+	}
+	ln := &memoryListener{
+		m:     m,
+		addr:  memoryAddr(addr),
+		connc: make(chan *memoryConn),
+		done:  make(chan struct{}),
+	}
+	m.listeners[addr] = ln
+	return ln, nil
+}
+
+// memoryAddr is an address of the in-memory transport.
+type memoryAddr string
+
+func (a memoryAddr) Network() string { return "memory" }
+func (a memoryAddr) String() string  { return string(a) }
+
+// memoryListener is a listener of the in-memory transport.
+type memoryListener struct {
+	m         *Memory
+	addr      memoryAddr
+	connc     chan *memoryConn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *memoryListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.connc:
+		return conn, nil
+	case <-l.done:
+		return nil, ErrListenerClosed
+	}
+}
+
+func (l *memoryListener) Close() error {
+	l.closeOnce.Do(func() {
+		l.m.mu.Lock()
+		delete(l.m.listeners, string(l.addr))
+		l.m.mu.Unlock()
+		close(l.done)
+	})
+	return nil
+}
+
+func (l *memoryListener) Addr() net.Addr {
+	return l.addr
+}
+
+// memoryConn is a connection of the in-memory transport,
+// which reads from one pipe, and writes to the other.
+type memoryConn struct {
+	in, out       *pipe
+	local, remote memoryAddr
+}
+
+func (c *memoryConn) Read(b []byte) (int, error)  { return c.in.read(b) }
+func (c *memoryConn) Write(b []byte) (int, error) { return c.out.write(b) }
+
+// Close closes the connection. The reads of the peer
+// return io.EOF after the written bytes.
+func (c *memoryConn) Close() error {
+	c.in.close(io.ErrClosedPipe)
+	c.out.close(io.EOF)
+	return nil
+}
+
+func (c *memoryConn) LocalAddr() net.Addr  { return c.local }
+func (c *memoryConn) RemoteAddr() net.Addr { return c.remote }
+
+func (c *memoryConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *memoryConn) SetReadDeadline(t time.Time) error {
+	c.in.setDeadline(t)
+	return nil
+}
+
+// SetWriteDeadline does nothing, as the writes never block.
+func (c *memoryConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// pipe is an unbounded buffer of bytes in one direction.
+type pipe struct {
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	err      error
+	deadline time.Time
+	// notify wakes up the blocked read.
+	notify chan struct{}
+}
+
+func newPipe() *pipe {
+	return &pipe{notify: make(chan struct{}, 1)}
+}
+
+// wakeup() wakes up the blocked read, if any.
+func (p *pipe) wakeup() {
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (p *pipe) write(b []byte) (int, error) {
+	p.mu.Lock()
+	if p.err != nil {
+		p.mu.Unlock()
+		return 0, io.ErrClosedPipe
+	}
+	n, _ := p.buf.Write(b)
+	p.mu.Unlock()
+	p.wakeup()
+	return n, nil
+}
+
+// read() reads the buffered bytes, or blocks until there are
+// some, the pipe is closed or the deadline is exceeded.
+func (p *pipe) read(b []byte) (int, error) {
+	for {
+		p.mu.Lock()
+		if p.err == io.ErrClosedPipe {
+			p.mu.Unlock()
+			return 0, p.err
+		}
+		if p.buf.Len() > 0 {
+			n, _ := p.buf.Read(b)
+			p.mu.Unlock()
+			return n, nil
+		}
+		if p.err != nil {
+			p.mu.Unlock()
+			return 0, p.err
+		}
+		deadline := p.deadline
+		p.mu.Unlock()
+
+		if deadline.IsZero() {
+			<-p.notify
+			continue
+		}
+		d := time.Until(deadline)
+		if d <= 0 {
+			return 0, os.ErrDeadlineExceeded
+		}
+		timer := time.NewTimer(d)
+		select {
+		case <-p.notify:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// close() makes the reads return the error, once the buffered bytes are
+// read for io.EOF, or at once for io.ErrClosedPipe.
+func (p *pipe) close(err error) {
+	p.mu.Lock()
+	if p.err == nil || err == io.ErrClosedPipe {
+		p.err = err
+	}
+	p.mu.Unlock()
+	p.wakeup()
+}
+
+func (p *pipe) setDeadline(t time.Time) {
+	p.mu.Lock()
+	p.deadline = t
+	p.mu.Unlock()
+	p.wakeup()
+}
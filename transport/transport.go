@@ -0,0 +1,111 @@
+// Package transport provides the connections between the agents.
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var ErrPacketsOverTLS = errors.New("Datagrams are not supported over TLS")
+
+// Transport creates the connections between the agents.
+type Transport interface {
+	// Dial connects the agent listening on the address.
+	Dial(addr string) (net.Conn, error)
+	// Listen listens on the address, the returned listener
+	// accepts the connections of the other agents.
+	Listen(addr string) (net.Listener, error)
+}
+
+// PacketTransport is implemented by the transports that also carry
+// the datagrams between the agents, for the small control messages
+// that tolerate the loss.
+type PacketTransport interface {
+	// ListenPacket listens for the datagrams on the address.
+	ListenPacket(addr string) (net.PacketConn, error)
+	// ResolvePacketAddr resolves the address to send the datagrams
+	// to the agent listening on the address.
+	ResolvePacketAddr(addr string) (net.Addr, error)
+}
+
+// tlsHandshakeTimeout is the timeout of the TLS handshake with a peer.
+const tlsHandshakeTimeout = 10 * time.Second
+
+// TCP is the transport over TCP, or over TLS if TLSConfig is set.
+type TCP struct {
+	// Net should be tcp, tcp4 or tcp6.
+	Net string
+	// Control is called on the listener socket before it is bound,
+	// e.g. to set the socket options. It can be nil.
+	Control func(network, address string, c syscall.RawConn) error
+	// TLSConfig makes the connections use TLS if it is not nil.
+	TLSConfig *tls.Config
+}
+
+// Dial connects the address. Over TLS, it verifies the certificate
+// of the peer against the host of the address.
+func (t *TCP) Dial(addr string) (net.Conn, error) {
+	conn, err := net.Dial(t.Net, addr)
+	if err != nil || t.TLSConfig == nil {
+		return conn, err
+	}
+	return t.handshake(conn, addr)
+}
+
+// Listen listens on the address.
+func (t *TCP) Listen(addr string) (net.Listener, error) {
+	lc := &net.ListenConfig{Control: t.Control}
+	ln, err := lc.Listen(context.Background(), t.Net, addr)
+	if err != nil {
+		return nil, err
+	}
+	if t.TLSConfig != nil {
+		// The handshake is done by the first read.
+		return tls.NewListener(ln, t.TLSConfig), nil
+	}
+	return ln, nil
+}
+
+// ListenPacket listens for the UDP datagrams on the address. The
+// datagrams are not encrypted, so it fails if TLSConfig is set.
+func (t *TCP) ListenPacket(addr string) (net.PacketConn, error) {
+	if t.TLSConfig != nil {
+		return nil, ErrPacketsOverTLS
+	}
+	lc := &net.ListenConfig{Control: t.Control}
+	return lc.ListenPacket(context.Background(), t.packetNet(), addr)
+}
+
+// ResolvePacketAddr resolves the UDP address of the address.
+func (t *TCP) ResolvePacketAddr(addr string) (net.Addr, error) {
+	return net.ResolveUDPAddr(t.packetNet(), addr)
+}
+
+// packetNet() returns the UDP network of the TCP network.
+func (t *TCP) packetNet() string {
+	return strings.Replace(t.Net, "tcp", "udp", 1)
+}
+
+// handshake() starts TLS on the connection to the address.
+func (t *TCP) handshake(conn net.Conn, addr string) (net.Conn, error) {
+	cfg := t.TLSConfig
+	if cfg.ServerName == "" {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			cfg = cfg.Clone()
+			cfg.ServerName = host
+		}
+	}
+	tlsConn := tls.Client(conn, cfg)
+	tlsConn.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tlsConn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
@@ -0,0 +1,127 @@
+package transport
+
+import (
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lilymona/testify/assert"
+)
+
+// testTransport dials and accepts a connection, and checks
+// the bytes are delivered both ways.
+func testTransport(t *testing.T, tr Transport, addr string) {
+	ln, err := tr.Listen(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		assert.NoError(t, err)
+		accepted <- conn
+	}()
+	client, err := tr.Dial(ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	server := <-accepted
+	defer server.Close()
+
+	b := make([]byte, 5)
+	_, err = client.Write([]byte("hello"))
+	assert.NoError(t, err)
+	_, err = io.ReadFull(server, b)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+
+	_, err = server.Write([]byte("world"))
+	assert.NoError(t, err)
+	_, err = io.ReadFull(client, b)
+	assert.NoError(t, err)
+	assert.Equal(t, "world", string(b))
+
+	// The read times out.
+	server.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	_, err = server.Read(b)
+	ne, ok := err.(net.Error)
+	assert.True(t, ok && ne.Timeout())
+	server.SetReadDeadline(time.Time{})
+
+	// The bytes written before closing are read before io.EOF.
+	_, err = client.Write([]byte("bye"))
+	assert.NoError(t, err)
+	client.Close()
+	b, err = ioutil.ReadAll(server)
+	assert.NoError(t, err)
+	assert.Equal(t, "bye", string(b))
+}
+
+func TestTCP(t *testing.T) {
+	testTransport(t, &TCP{Net: "tcp"}, "127.0.0.1:0")
+}
+
+func TestTCPPackets(t *testing.T) {
+	tr := &TCP{Net: "tcp"}
+	pc, err := tr.ListenPacket("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	addr, err := tr.ResolvePacketAddr(pc.LocalAddr().String())
+	assert.NoError(t, err)
+	assert.Equal(t, "udp", addr.Network())
+
+	_, err = pc.WriteTo([]byte("hello"), addr)
+	assert.NoError(t, err)
+	b := make([]byte, 16)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(b)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(b[:n]))
+
+	// The datagrams are not encrypted.
+	_, err = (&TCP{Net: "tcp", TLSConfig: &tls.Config{}}).ListenPacket("127.0.0.1:0")
+	assert.Equal(t, ErrPacketsOverTLS, err)
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	testTransport(t, m, "foo")
+
+	_, err := m.Dial("foo")
+	assert.Equal(t, ErrConnRefused, err)
+
+	ln, err := m.Listen("foo")
+	assert.NoError(t, err)
+	_, err = m.Listen("foo")
+	assert.Equal(t, ErrAddrInUse, err)
+
+	// The listener is closed while accepting.
+	accepted := make(chan error, 1)
+	go func() {
+		_, err := ln.Accept()
+		accepted <- err
+	}()
+	ln.Close()
+	assert.Equal(t, ErrListenerClosed, <-accepted)
+
+	// The closed connection cannot be read or written.
+	ln, err = m.Listen("bar")
+	assert.NoError(t, err)
+	defer ln.Close()
+	go ln.Accept()
+	conn, err := m.Dial("bar")
+	assert.NoError(t, err)
+	conn.Close()
+	_, err = conn.Read(make([]byte, 1))
+	assert.Equal(t, io.ErrClosedPipe, err)
+	_, err = conn.Write([]byte("foo"))
+	assert.Equal(t, io.ErrClosedPipe, err)
+}